@@ -0,0 +1,398 @@
+package lessgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// AccessLogConfig defines the config for access log middleware.
+	AccessLogConfig struct {
+		// 日志格式："combined"(Apache combined)、"json"，或包含"${tag}"标签的自定义模板。
+		// 支持的标签：time, remote_ip, method, uri, path, route, proto, status,
+		// latency, latency_human, bytes_in, bytes_out, request_id, user,
+		// referer, user_agent, features, tenant, header:<NAME>, query:<NAME>
+		Format string
+
+		// 日志输出文件，为空时输出到系统日志Log
+		Output string
+
+		// 异步写入的缓冲队列长度，队列满时丢弃日志而不阻塞请求
+		BufferSize int
+
+		// 仅记录日志的路由，为空时记录全部路由；匹配注册的路由或请求路径前缀
+		OnlyPaths []string
+
+		// 不记录日志的路由，匹配注册的路由或请求路径前缀，优先于OnlyPaths
+		SkipPaths []string
+
+		// Context中存放当前用户的键名
+		UserKey string
+	}
+
+	// 异步日志写入器
+	accessLogWriter struct {
+		ch      chan []byte
+		w       io.Writer
+		dropped int64
+		done    chan struct{}
+		closed  bool
+		lock    sync.RWMutex
+	}
+
+	// 预编译的日志模板片段
+	accessLogSegment struct {
+		text string
+		tag  string
+	}
+)
+
+const (
+	ACCESSLOG_COMBINED = "combined"
+	ACCESSLOG_JSON     = "json"
+)
+
+var (
+	accessLogWriters    = map[string]*accessLogWriter{}
+	accessLogWritersMux sync.Mutex
+	accessLogBufPool    = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
+var AccessLog = ApiMiddleware{
+	Name: "访问日志",
+	Desc: "按Apache combined、JSON或自定义模板格式异步记录访问日志",
+	Config: AccessLogConfig{
+		Format:     ACCESSLOG_COMBINED,
+		Output:     "",
+		BufferSize: 4096,
+		OnlyPaths:  []string{},
+		SkipPaths:  []string{},
+		UserKey:    "user",
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(AccessLogConfig)
+		// Defaults
+		if config.Format == "" {
+			config.Format = ACCESSLOG_COMBINED
+		}
+		if config.BufferSize <= 0 {
+			config.BufferSize = 4096
+		}
+		var segments []accessLogSegment
+		switch config.Format {
+		case ACCESSLOG_COMBINED, ACCESSLOG_JSON:
+		default:
+			segments = parseAccessLogFormat(config.Format)
+		}
+		writer, err := getAccessLogWriter(config.Output, config.BufferSize)
+		if err != nil {
+			Log.Error("AccessLog: %v", err)
+		}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				start := time.Now()
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+				if !matchAccessLog(c, config.OnlyPaths, true) || matchAccessLog(c, config.SkipPaths, false) {
+					return nil
+				}
+				latency := time.Now().Sub(start)
+
+				buf := accessLogBufPool.Get().(*bytes.Buffer)
+				buf.Reset()
+				switch config.Format {
+				case ACCESSLOG_COMBINED:
+					writeCombinedLog(buf, c, start, config.UserKey)
+				case ACCESSLOG_JSON:
+					writeJSONLog(buf, c, start, latency, config.UserKey)
+				default:
+					for _, seg := range segments {
+						if seg.tag == "" {
+							buf.WriteString(seg.text)
+						} else {
+							buf.WriteString(accessLogTag(c, seg.tag, start, latency, config.UserKey))
+						}
+					}
+				}
+				if writer != nil {
+					writer.write(buf.Bytes())
+				} else {
+					Log.Info("%s", buf.String())
+				}
+				accessLogBufPool.Put(buf)
+				return nil
+			}
+		}
+	},
+}.Reg()
+
+// 返回指定输出文件的异步写入器，相同文件共享同一写入器
+func getAccessLogWriter(output string, size int) (*accessLogWriter, error) {
+	if output == "" {
+		return nil, nil
+	}
+	accessLogWritersMux.Lock()
+	defer accessLogWritersMux.Unlock()
+	if w, ok := accessLogWriters[output]; ok {
+		return w, nil
+	}
+	os.MkdirAll(filepath.Dir(output), 0777)
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, err
+	}
+	w := &accessLogWriter{
+		ch:   make(chan []byte, size),
+		w:    f,
+		done: make(chan struct{}),
+	}
+	go w.run()
+	if len(accessLogWriters) == 0 {
+		app.onShutdown(func() {
+			closeAccessLogWriters(5 * time.Second)
+		})
+	}
+	accessLogWriters[output] = w
+	return w, nil
+}
+
+// 关闭全部写入器，等待缓冲队列中的日志写完后关闭文件
+func closeAccessLogWriters(timeout time.Duration) {
+	accessLogWritersMux.Lock()
+	writers := accessLogWriters
+	accessLogWriters = map[string]*accessLogWriter{}
+	accessLogWritersMux.Unlock()
+	deadline := time.After(timeout)
+	for output, w := range writers {
+		if !w.close(deadline) {
+			fmt.Fprintf(os.Stderr, "AccessLog: timed out flushing %s\n", output)
+		}
+	}
+}
+
+func (w *accessLogWriter) write(line []byte) {
+	b := make([]byte, len(line)+1)
+	copy(b, line)
+	b[len(line)] = '\n'
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		atomic.AddInt64(&w.dropped, 1)
+		return
+	}
+	select {
+	case w.ch <- b:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+}
+
+// 停止接收新日志，返回是否在deadline前写完
+func (w *accessLogWriter) close(deadline <-chan time.Time) bool {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.ch)
+	}
+	w.lock.Unlock()
+	select {
+	case <-w.done:
+		return true
+	case <-deadline:
+		return false
+	}
+}
+
+func (w *accessLogWriter) run() {
+	defer func() {
+		if c, ok := w.w.(io.Closer); ok {
+			c.Close()
+		}
+		close(w.done)
+	}()
+	for b := range w.ch {
+		if _, err := w.w.Write(b); err != nil {
+			fmt.Fprintf(os.Stderr, "AccessLog: %v\n", err)
+		}
+		if n := atomic.SwapInt64(&w.dropped, 0); n > 0 {
+			fmt.Fprintf(w.w, "[AccessLog] %d lines dropped because the buffer is full\n", n)
+		}
+	}
+}
+
+// 判断当前请求是否匹配paths中的路由，paths为空时返回empty
+func matchAccessLog(c *Context, paths []string, empty bool) bool {
+	if len(paths) == 0 {
+		return empty
+	}
+	for _, p := range paths {
+		if c.path == p || strings.HasPrefix(c.request.URL.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析自定义日志模板
+func parseAccessLogFormat(format string) []accessLogSegment {
+	var segments []accessLogSegment
+	for {
+		i := strings.Index(format, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(format[i:], "}")
+		if j < 0 {
+			break
+		}
+		if i > 0 {
+			segments = append(segments, accessLogSegment{text: format[:i]})
+		}
+		segments = append(segments, accessLogSegment{tag: format[i+2 : i+j]})
+		format = format[i+j+1:]
+	}
+	if len(format) > 0 {
+		segments = append(segments, accessLogSegment{text: format})
+	}
+	return segments
+}
+
+func accessLogTag(c *Context, tag string, start time.Time, latency time.Duration, userKey string) string {
+	req := c.request
+	switch tag {
+	case "time":
+		return start.Format(time.RFC3339)
+	case "remote_ip":
+		return c.RealRemoteAddr()
+	case "method":
+		return req.Method
+	case "uri":
+		return req.RequestURI
+	case "path":
+		return req.URL.Path
+	case "route":
+		return c.path
+	case "proto":
+		return req.Proto
+	case "status":
+		return strconv.Itoa(c.response.Status())
+	case "latency":
+		return strconv.FormatInt(int64(latency), 10)
+	case "latency_human":
+		return latency.String()
+	case "bytes_in":
+		return strconv.FormatInt(requestBytesIn(c), 10)
+	case "bytes_out":
+		return strconv.FormatInt(c.response.Size(), 10)
+	case "request_id":
+		return requestID(c)
+	case "user":
+		return accessLogUser(c, userKey)
+	case "referer":
+		return req.Referer()
+	case "user_agent":
+		return req.UserAgent()
+	case "features":
+		return featureVariants(c.FeatureVariants()).String()
+	case "tenant":
+		return c.TenantID()
+	}
+	switch {
+	case strings.HasPrefix(tag, "header:"):
+		return req.Header.Get(tag[7:])
+	case strings.HasPrefix(tag, "query:"):
+		return c.QueryParam(tag[6:])
+	}
+	return ""
+}
+
+func writeCombinedLog(buf *bytes.Buffer, c *Context, start time.Time, userKey string) {
+	req := c.request
+	buf.WriteString(c.RealRemoteAddr())
+	buf.WriteString(" - ")
+	buf.WriteString(accessLogUser(c, userKey))
+	buf.WriteString(" [")
+	buf.WriteString(start.Format("02/Jan/2006:15:04:05 -0700"))
+	buf.WriteString(`] "`)
+	buf.WriteString(req.Method)
+	buf.WriteByte(' ')
+	buf.WriteString(req.RequestURI)
+	buf.WriteByte(' ')
+	buf.WriteString(req.Proto)
+	buf.WriteString(`" `)
+	buf.WriteString(strconv.Itoa(c.response.Status()))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(c.response.Size(), 10))
+	buf.WriteString(` "`)
+	buf.WriteString(req.Referer())
+	buf.WriteString(`" "`)
+	buf.WriteString(req.UserAgent())
+	buf.WriteByte('"')
+}
+
+func writeJSONLog(buf *bytes.Buffer, c *Context, start time.Time, latency time.Duration, userKey string) {
+	req := c.request
+	fields := map[string]interface{}{
+		"time":       start.Format(time.RFC3339),
+		"remote_ip":  c.RealRemoteAddr(),
+		"method":     req.Method,
+		"uri":        req.RequestURI,
+		"route":      c.path,
+		"status":     c.response.Status(),
+		"latency":    int64(latency),
+		"bytes_in":   requestBytesIn(c),
+		"bytes_out":  c.response.Size(),
+		"request_id": requestID(c),
+		"user":       accessLogUser(c, userKey),
+		"referer":    req.Referer(),
+		"user_agent": req.UserAgent(),
+	}
+	if tenant := c.TenantID(); tenant != "" {
+		fields["tenant"] = tenant
+	}
+	if features := c.FeatureVariants(); len(features) > 0 {
+		fields["features"] = features
+	}
+	json.NewEncoder(buf).Encode(fields)
+	// 去掉Encode追加的换行符
+	buf.Truncate(buf.Len() - 1)
+}
+
+func accessLogUser(c *Context, userKey string) string {
+	if userKey != "" {
+		if u := c.Get(userKey); u != nil {
+			return fmt.Sprint(u)
+		}
+	}
+	return "-"
+}
+
+func requestBytesIn(c *Context) int64 {
+	if c.request.ContentLength > 0 {
+		return c.request.ContentLength
+	}
+	return 0
+}
+
+// 获取请求ID，优先读取响应头中已设置的值
+func requestID(c *Context) string {
+	if id := c.response.Header().Get(HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.request.Header.Get(HeaderXRequestID)
+}
@@ -0,0 +1,101 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lessgo/lessgo/logs"
+)
+
+var accessLogFormatTests = []struct {
+	format   string
+	segments []accessLogSegment
+}{
+	{"", nil},
+	{"plain", []accessLogSegment{{text: "plain"}}},
+	{"${method}", []accessLogSegment{{tag: "method"}}},
+	{"${method} ${uri} ${status}", []accessLogSegment{
+		{tag: "method"}, {text: " "}, {tag: "uri"}, {text: " "}, {tag: "status"},
+	}},
+	{"[${header:X-Real-IP}] ok", []accessLogSegment{
+		{text: "["}, {tag: "header:X-Real-IP"}, {text: "] ok"},
+	}},
+	{"${unclosed", []accessLogSegment{{text: "${unclosed"}}},
+}
+
+func TestParseAccessLogFormat(t *testing.T) {
+	for _, test := range accessLogFormatTests {
+		if segments := parseAccessLogFormat(test.format); !reflect.DeepEqual(segments, test.segments) {
+			t.Errorf("parseAccessLogFormat(%q) = %+v, want %+v", test.format, segments, test.segments)
+		}
+	}
+}
+
+// 返回一个以rec为响应的Context
+func testContext(req *http.Request) (*Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	c := app.getContext()
+	c.init(rec, req)
+	return c, rec
+}
+
+func TestAccessLogOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "access.log")
+	h := AccessLog.Middleware.(Middleware).getMiddlewareFunc(AccessLogConfig{
+		Format:    "${method} ${path} ${status} ${bytes_out}",
+		Output:    output,
+		OnlyPaths: []string{"/api/"},
+		SkipPaths: []string{"/api/health"},
+	})(func(c *Context) error {
+		return c.String(http.StatusCreated, "hello")
+	})
+	for _, path := range []string{"/api/users", "/static/a.js", "/api/health"} {
+		req, _ := http.NewRequest("POST", path, nil)
+		c, _ := testContext(req)
+		h(c)
+		c.free()
+	}
+	closeAccessLogWriters(time.Second)
+	b, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "POST /api/users 201 5\n"; string(b) != want {
+		t.Errorf("access log = %q, want %q", b, want)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	l := logs.NewLogger(10)
+	l.AddAdapter("ring", `{"size":4}`)
+	old := Log
+	Log = l
+	defer func() { Log = old }()
+
+	req, _ := http.NewRequest("GET", "/users/7", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set(HeaderXRequestID, "req-1")
+	c, _ := testContext(req)
+	defer c.free()
+	c.path = "/users/:id"
+	c.Set("user", "alice")
+	c.Logger().Info("loaded")
+	l.Flush()
+
+	recent := logs.Recent()
+	want := "loaded remote_ip=10.0.0.1 request_id=req-1 route=/users/:id user=alice"
+	if len(recent) != 1 || recent[0].Msg != want {
+		t.Errorf("Recent() = %v, want %q", recent, want)
+	}
+}
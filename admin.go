@@ -0,0 +1,274 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// 访问后台管理面板所需的权限，由权限控制中间件校验
+const ADMIN_PERMISSION = "admin:dashboard"
+
+// 虚拟路由节点的概要，Middlewares为从根节点起的完整中间件链
+type AdminRouteNode struct {
+	Id          string   `json:"id"`
+	Type        int      `json:"type"`
+	Path        string   `json:"path"`
+	Desc        string   `json:"desc"`
+	Methods     []string `json:"methods,omitempty"`
+	Enable      bool     `json:"enable"`
+	Dynamic     bool     `json:"dynamic"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// 返回全部虚拟路由节点的概要，按树的先序排列
+func AdminRouteNodes() []AdminRouteNode {
+	var nodes []AdminRouteNode
+	for _, vr := range RootRouter().Progeny() {
+		node := AdminRouteNode{
+			Id:      vr.Id,
+			Type:    vr.Type,
+			Path:    vr.Path(),
+			Enable:  vr.Enable,
+			Dynamic: vr.Dynamic,
+		}
+		if vr.apiHandler != nil {
+			node.Desc = vr.apiHandler.Desc
+			if vr.Type == HANDLER {
+				node.Methods = vr.Methods()
+			}
+		}
+		node.Middlewares = virtRouterMiddlewares(vr)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func middlewareNames(ms []*MiddlewareConfig) []string {
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// 查询路由树、中间件链及真实路由(含统计)的操作，供后台管理路由使用
+var AdminRoutesHandler = ApiHandler{
+	Desc:        "查询路由与中间件链",
+	Method:      "GET",
+	Permissions: []string{ADMIN_PERMISSION},
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"before": middlewareNames(lessgo.virtBefore),
+			"after":  middlewareNames(lessgo.virtAfter),
+			"nodes":  AdminRouteNodes(),
+			"routes": RealRoutes(),
+		})
+	},
+}.Reg()
+
+// 查询当前系统配置的操作，会话驱动配置等可能含密码的项被隐去，供后台管理路由使用
+var AdminConfigHandler = ApiHandler{
+	Desc:        "查询系统配置",
+	Method:      "GET",
+	Permissions: []string{ADMIN_PERMISSION},
+	Handler: func(c *Context) error {
+		conf := *Config
+		if conf.Session.SessionProviderConfig != "" {
+			conf.Session.SessionProviderConfig = "******"
+		}
+		if conf.SecureKeys != "" {
+			conf.SecureKeys = "******"
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"config":      conf,
+			"maintenance": Maintenance(),
+			"debug":       Debug(),
+			"version":     VERSION,
+		})
+	},
+}.Reg()
+
+// 后台管理面板页面，通过同一分组下的api/*接口读取与修改状态，见AdminRoutes
+var AdminDashboardHandler = ApiHandler{
+	Desc:        "后台管理面板",
+	Method:      "GET",
+	Permissions: []string{ADMIN_PERMISSION},
+	Handler: func(c *Context) error {
+		base, _ := json.Marshal(strings.TrimRight(c.request.URL.Path, "/"))
+		c.response.CacheControl().NoStore()
+		return c.HTML(http.StatusOK, strings.Replace(adminDashboardHTML, "{{BASE}}", string(base), 1))
+	},
+}.Reg()
+
+// 返回后台管理面板的路由分组：面板页面位于prefix，其数据接口位于prefix/api/*；
+// 需显式挂载并配置认证，如：
+//
+//	lessgo.Root(lessgo.AdminRoutes("/admin", auth, lessgo.RBAC))
+//
+// 其中auth为在Context中设置当前用户的认证中间件，RBAC校验该用户拥有ADMIN_PERMISSION权限；
+// 日志级别与维护模式接口未要求该权限，应由中间件统一保护
+func AdminRoutes(prefix string, middlewares ...*ApiMiddleware) *VirtRouter {
+	return Branch(prefix, "后台管理",
+		Leaf("/", AdminDashboardHandler, middlewares...),
+		Branch("/api", "后台管理接口",
+			Leaf("/routes", AdminRoutesHandler, middlewares...),
+			Leaf("/config", AdminConfigHandler, middlewares...),
+			Leaf("/route_stats", RouteStatsHandler, middlewares...),
+			Leaf("/slow_requests", SlowRequestStatsHandler, middlewares...),
+			Leaf("/runtime", RuntimeStatsHandler, middlewares...),
+			Leaf("/loglevel", LogLevelHandler, middlewares...),
+			Leaf("/maintenance", MaintenanceHandler, middlewares...),
+		),
+	)
+}
+
+const adminDashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Lessgo Admin</title>
+<style>
+body{font-family:-apple-system,Helvetica,Arial,sans-serif;margin:0;color:#222;background:#f5f6f8}
+header{background:#2d3e50;color:#fff;padding:12px 20px}
+header h1{display:inline;font-size:18px;margin:0 20px 0 0}
+nav a{color:#cfd8e3;margin-right:14px;cursor:pointer;text-decoration:none}
+nav a.on{color:#fff;font-weight:bold}
+main{padding:16px 20px}
+table{border-collapse:collapse;width:100%;background:#fff;font-size:13px}
+th,td{border:1px solid #e1e4e8;padding:5px 8px;text-align:left;vertical-align:top}
+th{background:#eef1f4}
+td.n{text-align:right}
+.err{color:#c0392b}
+pre{background:#fff;border:1px solid #e1e4e8;padding:10px;overflow:auto;font-size:12px}
+button,select,input{font-size:13px;margin-right:6px}
+.card{background:#fff;border:1px solid #e1e4e8;padding:12px;margin-bottom:12px}
+</style>
+</head>
+<body>
+<header><h1>Lessgo Admin</h1><nav id="nav"></nav></header>
+<main id="main"></main>
+<script>
+var BASE = {{BASE}};
+var tabs = {routes: "路由", stats: "路由统计", slow: "慢请求", runtime: "运行时", config: "配置", ops: "日志级别与维护模式"};
+var current = "routes";
+
+function esc(s) {
+	return String(s == null ? "" : s).replace(/[&<>"]/g, function (ch) {
+		return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;"}[ch];
+	});
+}
+
+function api(method, path, form, done) {
+	var xhr = new XMLHttpRequest();
+	xhr.open(method, BASE + "/api/" + path);
+	xhr.setRequestHeader("Accept", "application/json");
+	if (form) {
+		xhr.setRequestHeader("Content-Type", "application/x-www-form-urlencoded");
+	}
+	xhr.onload = function () {
+		var data;
+		try { data = JSON.parse(xhr.responseText); } catch (e) { data = xhr.responseText; }
+		if (xhr.status >= 400) {
+			show('<p class="err">' + esc(method + " " + path + ": " + xhr.status + " " + xhr.responseText) + "</p>");
+			return;
+		}
+		done(data);
+	};
+	xhr.send(form || null);
+}
+
+function show(html) { document.getElementById("main").innerHTML = html; }
+
+function table(head, rows) {
+	var h = "<table><tr>";
+	for (var i = 0; i < head.length; i++) h += "<th>" + esc(head[i]) + "</th>";
+	h += "</tr>";
+	for (i = 0; i < rows.length; i++) h += "<tr>" + rows[i] + "</tr>";
+	return h + "</table>";
+}
+
+function td(v, num) { return (num ? '<td class="n">' : "<td>") + esc(v) + "</td>"; }
+
+var render = {
+	routes: function () {
+		api("GET", "routes", null, function (d) {
+			var rows = [];
+			for (var i = 0; i < d.nodes.length; i++) {
+				var n = d.nodes[i];
+				rows.push(td(["根", "分组", "操作"][n.type]) + td(n.path) + td((n.methods || []).join("|")) +
+					td(n.desc) + td(d.before.concat(n.middlewares, d.after).join(" → ")) + td(n.enable ? "是" : "否"));
+			}
+			show(table(["类型", "路径", "方法", "描述", "中间件链", "启用"], rows));
+		});
+	},
+	stats: function () {
+		api("GET", "route_stats", null, function (d) {
+			var rows = [];
+			for (var i = 0; i < d.length; i++) {
+				var s = d[i];
+				rows.push(td(s.method) + td(s.path) + td(s.count, 1) + td(s.in_flight, 1) +
+					td((s.error_rate * 100).toFixed(2) + "%", 1) + td(s.avg_ms.toFixed(2), 1) +
+					td(s.p50_ms.toFixed(2), 1) + td(s.p90_ms.toFixed(2), 1) + td(s.p99_ms.toFixed(2), 1) + td(s.max_ms.toFixed(2), 1));
+			}
+			show(table(["方法", "路径", "请求数", "进行中", "错误率", "平均ms", "P50", "P90", "P99", "最大"], rows));
+		});
+	},
+	slow: function () {
+		api("GET", "slow_requests", null, function (d) {
+			var rows = [];
+			for (var i = 0; i < d.length; i++) {
+				var s = d[i];
+				rows.push(td(s.route) + td(s.slow, 1) + td(s.large, 1) + td(s.max_ms, 1) + td(s.max_bytes, 1) + td(s.last_ms, 1) + td(s.last_bytes, 1));
+			}
+			show(table(["路由", "慢请求", "大响应", "最大ms", "最大字节", "最近ms", "最近字节"], rows));
+		});
+	},
+	runtime: function () {
+		api("GET", "runtime", null, function (d) { show("<pre>" + esc(JSON.stringify(d, null, 2)) + "</pre>"); });
+	},
+	config: function () {
+		api("GET", "config", null, function (d) { show("<pre>" + esc(JSON.stringify(d, null, 2)) + "</pre>"); });
+	},
+	ops: function () {
+		api("GET", "loglevel", null, function (lv) {
+			api("GET", "maintenance", null, function (m) {
+				var levels = ["inherit", "debug", "info", "warn", "error", "fatal", "off"];
+				var opts = "";
+				for (var i = 0; i < levels.length; i++) opts += "<option>" + levels[i] + "</option>";
+				var mods = "";
+				for (var k in lv.modules) mods += "<li>" + esc(k) + ": " + esc(lv.modules[k]) + "</li>";
+				show('<div class="card"><h3>维护模式</h3><p>当前：' + (m.maintenance ? "开启" : "关闭") + "</p>" +
+					'<button onclick="setMaintenance(' + !m.maintenance + ')">' + (m.maintenance ? "关闭" : "开启") + "维护模式</button></div>" +
+					'<div class="card"><h3>日志级别</h3><p>全局：' + esc(lv.level) + "</p><ul>" + mods + "</ul>" +
+					'<input id="module" placeholder="模块名称"><select id="level">' + opts + "</select>" +
+					'<button onclick="setLevel()">设置</button></div>');
+			});
+		});
+	}
+};
+
+function setMaintenance(on) {
+	api("PUT", "maintenance", "enable=" + on, function () { render.ops(); });
+}
+
+function setLevel() {
+	var module = document.getElementById("module").value;
+	var level = document.getElementById("level").value;
+	api("PUT", "loglevel", "module=" + encodeURIComponent(module) + "&level=" + encodeURIComponent(level), function () { render.ops(); });
+}
+
+function showTab(tab) {
+	current = tab;
+	var h = "";
+	for (var k in tabs) h += '<a class="' + (k == tab ? "on" : "") + '" onclick="showTab(\'' + k + '\')">' + tabs[k] + "</a>";
+	document.getElementById("nav").innerHTML = h;
+	render[tab]();
+}
+
+showTab(current);
+setInterval(function () { if (current == "stats" || current == "slow" || current == "runtime") render[current](); }, 5000);
+</script>
+</body>
+</html>
+`
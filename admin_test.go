@@ -0,0 +1,48 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	req, _ := http.NewRequest(GET, "/admin/", nil)
+	c, rec := testContext(req)
+	if err := AdminDashboardHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `var BASE = "/admin";`) {
+		t.Errorf("dashboard BASE not injected: %.300s", body)
+	}
+
+	old := Config.Session.SessionProviderConfig
+	Config.Session.SessionProviderConfig = "127.0.0.1:6379,100,secret"
+	defer func() { Config.Session.SessionProviderConfig = old }()
+	req, _ = http.NewRequest(GET, "/admin/api/config", nil)
+	c, rec = testContext(req)
+	if err := AdminConfigHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("config not redacted: %s", rec.Body.String())
+	}
+
+	req, _ = http.NewRequest(GET, "/admin/api/routes", nil)
+	c, rec = testContext(req)
+	if err := AdminRoutesHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	var routes struct {
+		Nodes []AdminRouteNode
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &routes); err != nil || len(routes.Nodes) == 0 || routes.Nodes[0].Type != ROOT {
+		t.Errorf("routes = %s, %v", rec.Body.String(), err)
+	}
+
+	vr := AdminRoutes("/admin", RBAC)
+	if len(vr.Children) != 2 || len(vr.Children[1].Children) != 7 {
+		t.Errorf("AdminRoutes() = %+v", vr.Children)
+	}
+}
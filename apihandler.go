@@ -12,11 +12,12 @@ import (
 
 type (
 	ApiHandler struct {
-		Desc    string               // (可选)本操作的描述
-		Method  string               // (必填)请求方法，"*"表示除"WS"外全部方法，多方法写法："GET|POST"或"GET POST"，冲突时优先级WS>GET>*
-		Params  []Param              // (必填)参数说明列表(应该只声明当前中间件用到的参数)，path参数类型的先后顺序与url中保持一致
-		HTTP200 []Result             // (可选)HTTP Status Code 为200时的响应结果
-		Handler func(*Context) error // (必填)操作
+		Desc        string               // (可选)本操作的描述
+		Method      string               // (必填)请求方法，"*"表示除"WS"外全部方法，多方法写法："GET|POST"或"GET POST"，冲突时优先级WS>GET>*
+		Params      []Param              // (必填)参数说明列表(应该只声明当前中间件用到的参数)，path参数类型的先后顺序与url中保持一致
+		HTTP200     []Result             // (可选)HTTP Status Code 为200时的响应结果
+		Permissions []string             // (可选)访问本操作所需的权限，由权限控制中间件校验
+		Handler     func(*Context) error // (必填)操作
 
 		id      string   // 操作的唯一标识符
 		methods []string // 真实的请求方法列表
@@ -64,24 +65,13 @@ type (
  *    自定义：目前仅支持自定义json格式，仅当"参数位置"为“body"有效
  */
 
-var (
-	apiHandlerMap  = map[string]*ApiHandler{}
-	apiHandlerLock sync.RWMutex
-)
-
 func NilApiHandler(desc string) *ApiHandler {
 	a := &ApiHandler{
 		Desc: desc,
 	}
 	a.initId()
 	a.inited = true
-	if getApiHandler(a.id) != nil {
-		return apiHandlerMap[a.id]
-	}
-	apiHandlerLock.Lock()
-	defer apiHandlerLock.Unlock()
-	apiHandlerMap[a.id] = a
-	return a
+	return apiHandlerRegistry.add(a, false)
 }
 
 // 注册操作
@@ -100,11 +90,7 @@ func (a *ApiHandler) init() *ApiHandler {
 	a.initParamsAndSuffix()
 	a.initId()
 	a.inited = true
-	if h := getApiHandler(a.id); h != nil {
-		return h
-	}
-	setApiHandler(a)
-	return a
+	return apiHandlerRegistry.add(a, true)
 }
 
 // 虚拟操作的id
@@ -123,26 +109,7 @@ func (a *ApiHandler) Methods() []string {
 }
 
 func getApiHandler(id string) *ApiHandler {
-	apiHandlerLock.RLock()
-	defer apiHandlerLock.RUnlock()
-	return apiHandlerMap[id]
-}
-
-func setApiHandler(vh *ApiHandler) {
-	apiHandlerLock.Lock()
-	defer apiHandlerLock.Unlock()
-	apiHandlerMap[vh.id] = vh
-	for i, vh2 := range lessgo.apiHandlers {
-		if vh.Id() < vh2.Id() {
-			list := make([]*ApiHandler, len(lessgo.apiHandlers)+1)
-			copy(list, lessgo.apiHandlers[:i])
-			list[i] = vh
-			copy(list[i+1:], lessgo.apiHandlers[i:])
-			lessgo.apiHandlers = list
-			return
-		}
-	}
-	lessgo.apiHandlers = append(lessgo.apiHandlers, vh)
+	return apiHandlerRegistry.get(id)
 }
 
 func (a *ApiHandler) initParamsAndSuffix() {
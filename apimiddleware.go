@@ -58,9 +58,7 @@ func (a *ApiMiddleware) SetConfig(confObject interface{}) *ApiMiddleware {
 	defer a.lock.Unlock()
 	a.Config = confObject
 	a.inited = false
-	apiMiddlewareLock.Lock()
-	delete(apiMiddlewareMap, a.Name)
-	apiMiddlewareLock.Unlock()
+	apiMiddlewareRegistry.del(a.Name)
 	return a.init()
 }
 
@@ -264,40 +262,19 @@ func (c ConfMiddlewareFunc) getMiddlewareFunc(confObject interface{}) Middleware
 	return c(confObject)
 }
 
-var (
-	apiMiddlewareMap  = map[string]*ApiMiddleware{}
-	apiMiddlewareLock sync.RWMutex
-)
-
 func getApiMiddleware(name string) *ApiMiddleware {
-	apiMiddlewareLock.RLock()
-	defer apiMiddlewareLock.RUnlock()
-	return apiMiddlewareMap[name]
+	return apiMiddlewareRegistry.get(name)
 }
 
 func setApiMiddleware(a *ApiMiddleware) {
-	apiMiddlewareLock.Lock()
-	defer apiMiddlewareLock.Unlock()
-	apiMiddlewareMap[a.Name] = a
-	for i, a2 := range lessgo.apiMiddlewares {
-		if a.Name < a2.Name {
-			list := make([]*ApiMiddleware, len(lessgo.apiMiddlewares)+1)
-			copy(list, lessgo.apiMiddlewares[:i])
-			list[i] = a
-			copy(list[i+1:], lessgo.apiMiddlewares[i:])
-			lessgo.apiMiddlewares = list
-			return
-		}
-	}
-	lessgo.apiMiddlewares = append(lessgo.apiMiddlewares, a)
+	apiMiddlewareRegistry.set(a)
 }
 
 // 检查中间件是否存在
 func isExistMiddlewares(middlewareConfigs ...*MiddlewareConfig) error {
 	var errstring string
 	for _, m := range middlewareConfigs {
-		_, ok := apiMiddlewareMap[m.Name]
-		if !ok {
+		if getApiMiddleware(m.Name) == nil {
 			errstring += " \"" + m.Name + "\""
 		}
 	}
@@ -408,7 +385,7 @@ type (
 
 var Recover = ApiMiddleware{
 	Name: "捕获运行时恐慌",
-	Desc: "Recover returns a middleware which recovers from panics anywhere in the chain and handles the control to the centralized HTTPErrorHandler.",
+	Desc: "Recover returns a middleware which recovers from panics anywhere in the chain and returns them as *PanicError to the centralized HTTPErrorHandler.",
 	Config: RecoverConfig{
 		StackSize:         4 << 10, // 4 KB
 		DisableStackAll:   false,
@@ -422,22 +399,15 @@ var Recover = ApiMiddleware{
 		}
 
 		return func(next HandlerFunc) HandlerFunc {
-			return func(c *Context) error {
+			return func(c *Context) (err error) {
 				defer func() {
 					if r := recover(); r != nil {
-						var err error
-						switch r := r.(type) {
-						case error:
-							err = r
-						default:
-							err = fmt.Errorf("%v", r)
-						}
-						stack := make([]byte, config.StackSize)
-						length := runtime.Stack(stack, !config.DisableStackAll)
-						if !config.DisablePrintStack {
-							Log.Error("[%s] %s %s", color.Red("PANIC RECOVER"), err, stack[:length])
+						pe := newPanicError(r, config.StackSize, !config.DisableStackAll)
+						if !config.DisablePrintStack && !pe.logged {
+							Log.Error("[%s] %v %s", color.Red("PANIC RECOVER"), pe.Value, pe.Stack)
 						}
-						c.Error(err)
+						pe.logged = true
+						err = pe
 					}
 				}()
 				return next(c)
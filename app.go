@@ -2,17 +2,23 @@ package lessgo
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"reflect"
 	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/lessgo/lessgo/grace"
@@ -25,9 +31,10 @@ import (
 type (
 	// App is the top-level framework instancthis.
 	App struct {
-		debug        bool
+		debug        int32 // 调试模式，原子读写，可由配置热加载切换
 		router       *Router
 		routes       map[string]Route
+		apiHandlers  map[string]*ApiHandler // 路由对应的操作，键为method+path
 		routerIndex  int
 		chainNodes   []MiddlewareFunc
 		chainHandler HandlerFunc
@@ -37,6 +44,10 @@ type (
 		memoryCache  *MemoryCache
 		ctxPool      sync.Pool
 		lock         sync.RWMutex
+
+		shutdownHooks []func()
+		shutdownOnce  sync.Once
+		hooksLock     sync.Mutex
 	}
 
 	// Route contains a handler and information for matching against requests.
@@ -44,6 +55,7 @@ type (
 		Method  string
 		Path    string
 		Handler string
+		Stats   *RouteStats `json:",omitempty"` // 路由的耗时与错误统计，由RealRoutes填充
 	}
 
 	// HandlerFunc defines a function to server HTTP requests.
@@ -90,6 +102,7 @@ const (
 	MIMEApplicationForm                  = "application/x-www-form-urlencoded"
 	MIMEApplicationProtobuf              = "application/protobuf"
 	MIMEApplicationMsgpack               = "application/msgpack"
+	MIMETextCSS                          = "text/css"
 	MIMETextHTML                         = "text/html"
 	MIMETextHTMLCharsetUTF8              = MIMETextHTML + "; " + charsetUTF8
 	MIMETextPlain                        = "text/plain"
@@ -105,15 +118,23 @@ const (
 // Headers
 const (
 	HeaderAcceptEncoding                = "Accept-Encoding"
+	HeaderAcceptLanguage                = "Accept-Language"
 	HeaderAuthorization                 = "Authorization"
+	HeaderCacheControl                  = "Cache-Control"
 	HeaderContentDisposition            = "Content-Disposition"
 	HeaderContentEncoding               = "Content-Encoding"
+	HeaderContentLanguage               = "Content-Language"
 	HeaderContentLength                 = "Content-Length"
 	HeaderContentType                   = "Content-Type"
 	HeaderCookie                        = "Cookie"
 	HeaderSetCookie                     = "Set-Cookie"
+	HeaderETag                          = "ETag"
+	HeaderIfMatch                       = "If-Match"
 	HeaderIfModifiedSince               = "If-Modified-Since"
+	HeaderIfNoneMatch                   = "If-None-Match"
+	HeaderIfUnmodifiedSince             = "If-Unmodified-Since"
 	HeaderLastModified                  = "Last-Modified"
+	HeaderLink                          = "Link"
 	HeaderLocation                      = "Location"
 	HeaderUpgrade                       = "Upgrade"
 	HeaderVary                          = "Vary"
@@ -122,6 +143,8 @@ const (
 	HeaderXHTTPMethodOverride           = "X-HTTP-Method-Override"
 	HeaderXForwardedFor                 = "X-Forwarded-For"
 	HeaderXRealIP                       = "X-Real-IP"
+	HeaderXRequestID                    = "X-Request-ID"
+	HeaderXTotalCount                   = "X-Total-Count"
 	HeaderServer                        = "Server"
 	HeaderOrigin                        = "Origin"
 	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
@@ -190,32 +213,49 @@ var (
 	// 请求的操作发生错误后的默认处理
 	// 500 Internal Server Error
 	defaultInternalServerErrorHandler = func(c *Context, err error, rcv interface{}) {
-		code := http.StatusInternalServerError
-		msg := http.StatusText(code)
-		if rcv != nil {
-			msg = fmt.Sprint(rcv)
-			stack := make([]byte, 4<<10) //4KB
-			length := runtime.Stack(stack, true)
-			Log.Error("[%s] %s %s", color.Red("PANIC RECOVER"), msg, stack[:length])
-
-		} else if err != nil {
-			switch e := err.(type) {
-			case *HTTPError:
-				code = e.Code
-				msg = e.Message
-			case error:
-				if Debug() {
-					msg = e.Error()
-				}
-			}
-			Log.Error("%v", err)
-		}
+		code, msg := errorStatus(err, rcv)
+		logRequestError(err, rcv)
 		if !c.response.Committed() {
 			c.String(code, msg)
 		}
 	}
 )
 
+// 返回请求的错误或恐慌对应的状态码与响应说明
+func errorStatus(err error, rcv interface{}) (int, string) {
+	code := http.StatusInternalServerError
+	msg := http.StatusText(code)
+	if rcv != nil {
+		msg = fmt.Sprint(rcv)
+	} else if err != nil {
+		switch e := err.(type) {
+		case *HTTPError:
+			code = e.Code
+			msg = e.Message
+		case error:
+			if Debug() {
+				msg = e.Error()
+			}
+		}
+	}
+	return code, msg
+}
+
+// 记录请求的错误，恐慌时附带堆栈
+func logRequestError(err error, rcv interface{}) {
+	if pe, ok := err.(*PanicError); ok {
+		if !pe.logged {
+			Log.Error("[%s] %v %s", color.Red("PANIC RECOVER"), pe.Value, pe.Stack)
+		}
+	} else if rcv != nil {
+		stack := make([]byte, 4<<10) //4KB
+		length := runtime.Stack(stack, true)
+		Log.Error("[%s] %s %s", color.Red("PANIC RECOVER"), fmt.Sprint(rcv), stack[:length])
+	} else if err != nil {
+		Log.Error("%v", err)
+	}
+}
+
 // New creates an instance of App.
 func newApp() (this *App) {
 	this = &App{
@@ -268,7 +308,11 @@ func (this *App) SetRenderer(r Renderer) {
 
 // SetDebug enable/disable debug modthis.
 func (this *App) SetDebug(on bool) {
-	this.debug = on
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&this.debug, v)
 	if this.memoryCache != nil {
 		this.memoryCache.SetEnable(!on)
 	}
@@ -282,7 +326,7 @@ func (this *App) SetDebug(on bool) {
 
 // Debug returns debug mode (enabled or disabled).
 func (this *App) Debug() bool {
-	return this.debug
+	return atomic.LoadInt32(&this.debug) == 1
 }
 
 // 获取文件缓存对象
@@ -309,6 +353,9 @@ func (this *App) RealRoutes() []Route {
 	sort.Strings(keys)
 	for i, k := range keys {
 		routes[i] = m[k]
+		if s, ok := readRouteStats(routes[i].Method, routes[i].Path); ok {
+			routes[i].Stats = &s
+		}
 	}
 	return routes
 }
@@ -320,51 +367,102 @@ func (this *App) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	var c = this.ctxPool.Get().(*Context)
 	defer func() {
 		rcv := recover()
-		if rcv != nil || err != nil {
-			this.router.ErrorPanicHandler(c, err, rcv)
+		if rcv != nil {
+			err = newPanicError(rcv, 4<<10, true)
+		}
+		if err != nil {
+			handleError(c, err, nil)
+		}
+		if PoolCheck() != POOL_CHECK_OFF {
+			c.release()
+		} else {
+			c.free()
+			this.ctxPool.Put(c)
 		}
-		c.free()
-		this.ctxPool.Put(c)
 		this.lock.RUnlock()
 	}()
 	if err = c.init(rw, req); err != nil {
 		return
 	}
+	if err = checkRequestLimits(req); err != nil {
+		return
+	}
 	// Execute chain
 	err = this.chainHandler(c)
 }
 
 // Run starts the HTTP server.
-func (this *App) run(address, tlsCertfile, tlsKeyfile string, readTimeout, writeTimeout time.Duration, graceful bool) {
+func (this *App) run(address, tlsCertfile, tlsKeyfile string, readTimeout, writeTimeout time.Duration, graceful, strict bool) {
 	server := &http.Server{
 		Addr:         address,
 		Handler:      this,
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 	}
+	lc := getListenerCounter(address)
+	server.ConnState = lc.connState
 
 	canHttps := tlsCertfile != "" && tlsKeyfile != ""
+	var wrapListener func(net.Listener) net.Listener
+	if strict {
+		wrapListener = newStrictListener
+		if canHttps {
+			server.ConnContext = strictConnContext
+			server.Handler = strictTLSHandler(this)
+		}
+	}
+
+	server.Handler = lc.countRequests(server.Handler)
+
+	// 绑定监听端口后执行预热钩子，完成前就绪检查失败
+	wrapListener = warmupOnListen(wrapListener)
+
+	if graceful && os.Getenv(DEV_LISTENER_ENV) != "" {
+		// 监听套接字由lessgo run持有，由其负责重启
+		Log.Sys("Graceful restart is disabled under lessgo run.")
+		graceful = false
+	}
 
 	var err error
-	if !graceful {
-		if canHttps {
-			err = server.ListenAndServeTLS(tlsCertfile, tlsKeyfile)
-		} else {
-			err = server.ListenAndServe()
+	if canHttps {
+		if server.TLSConfig, err = this.newTLSConfig(tlsCertfile, tlsKeyfile); err != nil {
+			Log.Fatal("%v", err)
+			select {}
+		}
+		if strict {
+			// 严格解析包装了TLS连接，http.Server无法据此协商HTTP/2，仅支持HTTP/1.1
+			server.TLSConfig.NextProtos = []string{"http/1.1"}
 		}
+	}
+	if !graceful {
+		err = this.serve(server, wrapListener)
 
 	} else {
 
 		endRunning := make(chan bool, 1)
 		graceServer := grace.NewServer(address, server, Log)
+		graceServer.WrapListener = wrapListener
+		drain := func() {
+			startDrain(server)
+			// 超时后grace不再等待连接关闭
+			timeout := drainTimeout()
+			grace.DefaultTimeout = timeout
+			if timeout == 0 {
+				grace.DefaultTimeout = -1
+			}
+			go waitDrain(address, lc, timeout, nil)
+		}
+		for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM} {
+			graceServer.SignalHooks[grace.PreSignal][sig] = append(graceServer.SignalHooks[grace.PreSignal][sig], drain)
+		}
 		if canHttps {
 			go func() {
 				time.Sleep(20 * time.Microsecond)
 				if err = graceServer.ListenAndServeTLS(tlsCertfile, tlsKeyfile); err != nil {
 					err = fmt.Errorf("Grace-ListenAndServeTLS: %v, %d", err, os.Getpid())
 					time.Sleep(100 * time.Microsecond)
-					endRunning <- true
 				}
+				endRunning <- true
 			}()
 		} else {
 			go func() {
@@ -372,8 +470,8 @@ func (this *App) run(address, tlsCertfile, tlsKeyfile string, readTimeout, write
 				if err = graceServer.ListenAndServe(); err != nil {
 					err = fmt.Errorf("Grace-ListenAndServe: %v, %d", err, os.Getpid())
 					time.Sleep(100 * time.Microsecond)
-					endRunning <- true
 				}
+				endRunning <- true
 			}()
 		}
 		<-endRunning
@@ -383,11 +481,123 @@ func (this *App) run(address, tlsCertfile, tlsKeyfile string, readTimeout, write
 		Log.Fatal("%v", err)
 		select {}
 	}
+	// 监听已关闭(平滑模式下已处理完进行中的请求)，执行退出钩子
+	this.shutdown()
+}
+
+// 非平滑模式下运行服务，server.TLSConfig不为nil时使用HTTPS，收到SIGINT或SIGTERM时关闭监听、排空连接后返回nil；
+// wrap不为nil时用于包装(TLS层之上的)监听器；由lessgo run启动时使用其传入的监听套接字
+func (this *App) serve(server *http.Server, wrap func(net.Listener) net.Listener) error {
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := devListener()
+	if err != nil {
+		return err
+	}
+	if ln != nil {
+		Log.Sys("Serving on the listener of lessgo run %v, listen::address is ignored.", ln.Addr())
+	} else if ln, err = net.Listen("tcp", addr); err != nil {
+		return err
+	}
+	if server.TLSConfig != nil {
+		ln = tls.NewListener(ln, server.TLSConfig)
+	}
+	if wrap != nil {
+		ln = wrap(ln)
+	}
+
+	var closing int32
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(sigChan)
+		close(sigChan)
+	}()
+	go func() {
+		if sig, ok := <-sigChan; ok {
+			Log.Sys("%v Received %v.", os.Getpid(), sig)
+			startDrain(server)
+			atomic.StoreInt32(&closing, 1)
+			ln.Close()
+		}
+	}()
+	err = server.Serve(ln)
+	if atomic.LoadInt32(&closing) == 1 {
+		// 等待进行中的请求完成、连接关闭，超时后强制关闭
+		waitDrain(server.Addr, getListenerCounter(server.Addr), drainTimeout(), func() { server.Close() })
+		return nil
+	}
+	return err
+}
+
+// 加载证书并创建TLS配置，按配置启用会话票据密钥轮换与OCSP装订，服务退出时停止
+func (this *App) newTLSConfig(tlsCertfile, tlsKeyfile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(tlsCertfile, tlsKeyfile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+	stop := make(chan struct{})
+	this.onShutdown(func() { close(stop) })
+
+	if sec := Config.Listen.TicketKeyRotateSeconds; sec > 0 {
+		interval := time.Duration(sec) * time.Second
+		if err = syncTicketKeys(config, interval); err != nil {
+			Log.Error("TLS session ticket keys: %v", err)
+		}
+		go rotateTicketKeys(config, interval, stop)
+	}
+	if Config.Listen.OCSPStapling {
+		stapler, err := newOCSPStapler(cert)
+		if err != nil {
+			Log.Error("%v", err)
+		} else {
+			config.GetCertificate = stapler.GetCertificate
+			go stapler.run(stop)
+		}
+	}
+	return config, nil
+}
+
+// 添加服务退出时执行的钩子
+func (this *App) onShutdown(fn func()) {
+	this.hooksLock.Lock()
+	this.shutdownHooks = append(this.shutdownHooks, fn)
+	this.hooksLock.Unlock()
+}
+
+// 依次执行退出钩子，然后关闭所有websocket连接并写出缓冲的日志，只执行一次
+func (this *App) shutdown() {
+	this.shutdownOnce.Do(func() {
+		setDraining()
+		this.hooksLock.Lock()
+		hooks := this.shutdownHooks
+		this.hooksLock.Unlock()
+		for _, fn := range hooks {
+			func() {
+				defer func() {
+					if rcv := recover(); rcv != nil {
+						Log.Error("Shutdown hook panic: %v", rcv)
+					}
+				}()
+				fn()
+			}()
+		}
+		if n := websocket.CloseAll(); n > 0 {
+			Log.Sys("> Closed %d websocket connections", n)
+		}
+		Log.Flush()
+	})
 }
 
 // 设置文件缓存
 func (this *App) setMemoryCache(m *MemoryCache) {
-	m.SetEnable(!this.debug)
+	m.SetEnable(!this.Debug())
 	this.memoryCache = m
 }
 
@@ -429,7 +639,9 @@ func (this *App) afterUse(middleware ...MiddlewareFunc) {
 
 func (this *App) cleanRouter() {
 	this.router.trees = make(map[string]*node)
+	this.router.statics = nil
 	this.routes = make(map[string]Route)
+	this.apiHandlers = make(map[string]*ApiHandler)
 	this.chainNodes = []MiddlewareFunc{this.router.process}
 	this.routerIndex = 0
 	this.chainHandler = chainEndHandler
@@ -457,6 +669,12 @@ func (this *App) static(prefix, root string, middleware ...MiddlewareFunc) {
 	Log.Sys("| %-7s | %-30s | %v", GET, prefix+"/*filepath", root)
 }
 
+// staticFS registers a new route with path prefix to serve static files from fsys.
+func (this *App) staticFS(prefix string, fsys fs.FS, middleware ...MiddlewareFunc) {
+	this.addwithlog(false, GET, prefix+"/*filepath", StaticFSFunc(fsys), middleware...)
+	Log.Sys("| %-7s | %-30s | %T", GET, prefix+"/*filepath", fsys)
+}
+
 // file registers a new route with path to serve a static filthis.
 func (this *App) file(path, file string, middleware ...MiddlewareFunc) {
 	this.addwithlog(false, GET, path, HandlerFunc(func(c *Context) error {
@@ -497,6 +715,8 @@ func (this *App) add(method, path string, handler HandlerFunc, middleware ...Mid
 	this.addwithlog(true, method, path, handler, middleware...)
 }
 
+// 注册时即将路由的中间件与操作组合为单个HandlerFunc，请求时不再包装；
+// 动态修改路由或中间件配置后，由ReregisterRouter重建路由时重新组合。
 func (this *App) addwithlog(logprint bool, method, path string, handler HandlerFunc, middleware ...MiddlewareFunc) {
 	path = joinpath(path, "")
 	name := handlerName(handler)
@@ -505,7 +725,7 @@ func (this *App) addwithlog(logprint bool, method, path string, handler HandlerF
 	for i := len(middleware) - 1; i >= 0; i-- {
 		h = middleware[i](h)
 	}
-	this.router.Handle(method, path, h)
+	this.router.Handle(method, path, routePathHandler(method, path, this.apiHandlers[method+path], h))
 
 	this.routes[method+path] = Route{
 		Method:  method,
@@ -550,13 +770,13 @@ func (this *App) url(h HandlerFunc, params ...interface{}) string {
 
 // newContext returns a Context instancthis.
 func (this *App) newContext(resp *Response, req *http.Request) *Context {
-	return &Context{
+	c := &Context{
 		request:  req,
 		response: resp,
-		pvalues:  nil,
-		pkeys:    nil,
-		store:    make(store),
 	}
+	c.pkeys = c.pkeysArr[:0]
+	c.pvalues = c.pvaluesArr[:0]
+	return c
 }
 
 // getContext returns `Context` from the sync.Pool. You must return the context by
@@ -600,6 +820,25 @@ func wrapMiddlewares(middleware []interface{}) []MiddlewareFunc {
 	return ms
 }
 
+// 记录请求匹配到的路由及操作，并统计路由进行中的请求数、耗时与错误
+func routePathHandler(method, path string, ah *ApiHandler, h HandlerFunc) HandlerFunc {
+	m := routeMetricsFor(method, path)
+	return func(c *Context) error {
+		c.path = path
+		c.apiHandler = ah
+		start := time.Now()
+		atomic.AddInt64(&m.inFlight, 1)
+		failed := true // 发生恐慌时计为错误
+		defer func() {
+			atomic.AddInt64(&m.inFlight, -1)
+			m.observe(time.Since(start), failed)
+		}()
+		err := h(c)
+		failed = err != nil || c.response.Status() >= 500
+		return err
+	}
+}
+
 func handlerName(h HandlerFunc) string {
 	v := reflect.ValueOf(h)
 	t := v.Type()
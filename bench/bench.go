@@ -0,0 +1,378 @@
+// Package bench replays recorded or synthetic traffic against a running app
+// and reports the throughput and latency percentiles per route, with
+// thresholds to fail CI performance gates.
+//
+//	reqs, err := bench.ReadAccessLog(file) // "json" or "combined" access log
+//	report, err := bench.Run(bench.Options{
+//		Target:      "http://127.0.0.1:8080",
+//		Concurrency: 20,
+//		Duration:    30 * time.Second,
+//	}, reqs)
+//	report.WriteText(os.Stdout)
+//	err = report.Check(bench.Threshold{MaxP99: 200 * time.Millisecond, MaxErrorRate: 0.01})
+//
+// The lessgo-bench command under cmd wraps it.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Request to replay.
+type Request struct {
+	Method string
+	URI    string // path and query, relative to Options.Target
+	Route  string // route pattern the request is reported under, the path by default
+	Header http.Header
+	Body   []byte
+}
+
+// Options of a run.
+type Options struct {
+	// Base URL of the app, e.g. http://127.0.0.1:8080.
+	Target string
+
+	// Concurrent workers, 10 by default.
+	Concurrency int
+
+	// The requests are replayed in turn until Duration elapses or Requests
+	// are sent; with neither set each request is sent once.
+	Duration time.Duration
+	Requests int
+
+	// Max requests per second over all workers, 0 for no limit.
+	Rate float64
+
+	// Timeout of a request, 10s by default.
+	Timeout time.Duration
+
+	// Headers set on every request.
+	Header http.Header
+
+	// Client sending the requests, built from Timeout and Concurrency if nil.
+	Client *http.Client
+}
+
+// RouteResult are the stats of the requests of a route, or of all of them.
+type RouteResult struct {
+	Route     string         `json:"route"`
+	Requests  uint64         `json:"requests"`
+	Errors    uint64         `json:"errors"` // failed requests and 5xx responses
+	ErrorRate float64        `json:"error_rate"`
+	RPS       float64        `json:"rps"`
+	AvgMs     float64        `json:"avg_ms"`
+	P50Ms     float64        `json:"p50_ms"`
+	P90Ms     float64        `json:"p90_ms"`
+	P99Ms     float64        `json:"p99_ms"`
+	MaxMs     float64        `json:"max_ms"`
+	Status    map[string]int `json:"status"` // responses per status code, "error" for failed requests
+}
+
+// Report of a run.
+type Report struct {
+	Target   string        `json:"target"`
+	Duration time.Duration `json:"duration"`
+	Total    RouteResult   `json:"total"`
+	Routes   []RouteResult `json:"routes"` // sorted by route
+}
+
+// Threshold fails Report.Check when exceeded, zero fields aren't checked.
+type Threshold struct {
+	Route        string // route to check, all requests if empty
+	MaxP99       time.Duration
+	MaxP90       time.Duration
+	MaxErrorRate float64
+	MinRPS       float64
+}
+
+type sample struct {
+	route   string
+	latency time.Duration
+	status  string
+	failed  bool
+}
+
+// Run replays reqs against opt.Target and returns the report.
+func Run(opt Options, reqs []Request) (*Report, error) {
+	if len(reqs) == 0 {
+		return nil, errors.New("bench: no request to replay")
+	}
+	target, err := url.Parse(opt.Target)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("bench: invalid target %q", opt.Target)
+	}
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = 10
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = 10 * time.Second
+	}
+	client := opt.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout:   opt.Timeout,
+			Transport: &http.Transport{MaxIdleConnsPerHost: opt.Concurrency},
+		}
+	}
+	total := opt.Requests
+	if total <= 0 && opt.Duration <= 0 {
+		total = len(reqs)
+	}
+
+	var (
+		next     int64 = -1
+		deadline time.Time
+		tokens   <-chan time.Time
+		samples  = make(chan sample, opt.Concurrency*4)
+		wg       sync.WaitGroup
+	)
+	if opt.Duration > 0 {
+		deadline = time.Now().Add(opt.Duration)
+	}
+	if opt.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opt.Rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	base := strings.TrimRight(opt.Target, "/")
+	start := time.Now()
+	for i := 0; i < opt.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n := atomic.AddInt64(&next, 1)
+				if total > 0 && n >= int64(total) {
+					return
+				}
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					return
+				}
+				if tokens != nil {
+					<-tokens
+				}
+				samples <- send(client, base, opt.Header, reqs[n%int64(len(reqs))])
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(samples)
+	}()
+
+	routes := map[string]*routeSamples{}
+	all := &routeSamples{status: map[string]int{}}
+	for s := range samples {
+		rs := routes[s.route]
+		if rs == nil {
+			rs = &routeSamples{status: map[string]int{}}
+			routes[s.route] = rs
+		}
+		rs.add(s)
+		all.add(s)
+	}
+	elapsed := time.Since(start)
+
+	report := &Report{Target: opt.Target, Duration: elapsed, Total: all.result("", elapsed)}
+	for route, rs := range routes {
+		report.Routes = append(report.Routes, rs.result(route, elapsed))
+	}
+	sort.Sort(routeResults(report.Routes))
+	return report, nil
+}
+
+func send(client *http.Client, base string, header http.Header, r Request) sample {
+	s := sample{route: r.Route}
+	if s.route == "" {
+		s.route = r.Method + " " + strings.SplitN(r.URI, "?", 2)[0]
+	}
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+	req, err := http.NewRequest(r.Method, base+r.URI, body)
+	if err != nil {
+		s.status, s.failed = "error", true
+		return s
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	s.latency = time.Since(start)
+	if err != nil {
+		s.status, s.failed = "error", true
+	} else {
+		s.status = fmt.Sprint(resp.StatusCode)
+		s.failed = resp.StatusCode >= 500
+	}
+	return s
+}
+
+type routeSamples struct {
+	latencies []time.Duration
+	errors    uint64
+	total     time.Duration
+	status    map[string]int
+}
+
+func (rs *routeSamples) add(s sample) {
+	rs.latencies = append(rs.latencies, s.latency)
+	rs.total += s.latency
+	rs.status[s.status]++
+	if s.failed {
+		rs.errors++
+	}
+}
+
+func (rs *routeSamples) result(route string, elapsed time.Duration) RouteResult {
+	n := len(rs.latencies)
+	r := RouteResult{Route: route, Requests: uint64(n), Errors: rs.errors, Status: rs.status}
+	if n == 0 {
+		return r
+	}
+	sort.Sort(durations(rs.latencies))
+	r.ErrorRate = float64(rs.errors) / float64(n)
+	r.RPS = float64(n) / elapsed.Seconds()
+	r.AvgMs = ms(rs.total / time.Duration(n))
+	r.P50Ms = ms(rs.latencies[n*50/100])
+	r.P90Ms = ms(rs.latencies[n*90/100])
+	r.P99Ms = ms(rs.latencies[n*99/100])
+	r.MaxMs = ms(rs.latencies[n-1])
+	return r
+}
+
+// Check returns an error listing the thresholds exceeded, nil if none.
+func (r *Report) Check(thresholds ...Threshold) error {
+	var violations []string
+	for _, th := range thresholds {
+		res, name := r.Total, "total"
+		if th.Route != "" {
+			found := false
+			for _, rr := range r.Routes {
+				if rr.Route == th.Route {
+					res, found = rr, true
+					break
+				}
+			}
+			if !found {
+				violations = append(violations, fmt.Sprintf("%s: no request", th.Route))
+				continue
+			}
+			name = th.Route
+		}
+		if th.MaxP99 > 0 && res.P99Ms > ms(th.MaxP99) {
+			violations = append(violations, fmt.Sprintf("%s: p99 %.2fms > %v", name, res.P99Ms, th.MaxP99))
+		}
+		if th.MaxP90 > 0 && res.P90Ms > ms(th.MaxP90) {
+			violations = append(violations, fmt.Sprintf("%s: p90 %.2fms > %v", name, res.P90Ms, th.MaxP90))
+		}
+		if th.MaxErrorRate > 0 && res.ErrorRate > th.MaxErrorRate {
+			violations = append(violations, fmt.Sprintf("%s: error rate %.4f > %.4f", name, res.ErrorRate, th.MaxErrorRate))
+		}
+		if th.MinRPS > 0 && res.RPS < th.MinRPS {
+			violations = append(violations, fmt.Sprintf("%s: %.1f rps < %.1f", name, res.RPS, th.MinRPS))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return errors.New("bench: thresholds exceeded:\n  " + strings.Join(violations, "\n  "))
+}
+
+// WriteText writes the report as a table.
+func (r *Report) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Target %s, %v\n\n", r.Target, r.Duration)
+	fmt.Fprintf(&buf, "%-40s %9s %7s %9s %9s %9s %9s %9s\n", "ROUTE", "REQUESTS", "ERRORS", "RPS", "P50(ms)", "P90(ms)", "P99(ms)", "MAX(ms)")
+	line := func(res RouteResult, name string) {
+		fmt.Fprintf(&buf, "%-40s %9d %7d %9.1f %9.2f %9.2f %9.2f %9.2f\n",
+			name, res.Requests, res.Errors, res.RPS, res.P50Ms, res.P90Ms, res.P99Ms, res.MaxMs)
+	}
+	for _, res := range r.Routes {
+		line(res, res.Route)
+	}
+	line(r.Total, "TOTAL")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadAccessLog reads requests from an access log written by the lessgo
+// AccessLog middleware in the "json" or "combined" format. JSON lines are
+// reported under their route, lines that can't be parsed are skipped.
+func ReadAccessLog(r io.Reader) ([]Request, error) {
+	var reqs []Request
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] == '{' {
+			var entry struct {
+				Method, URI, Route string
+			}
+			if json.Unmarshal([]byte(line), &entry) == nil && entry.Method != "" && entry.URI != "" {
+				req := Request{Method: entry.Method, URI: entry.URI}
+				if entry.Route != "" {
+					req.Route = entry.Method + " " + entry.Route
+				}
+				reqs = append(reqs, req)
+			}
+			continue
+		}
+		// combined: host ident user [time] "METHOD URI PROTO" status size "referer" "agent"
+		i := strings.Index(line, `"`)
+		if i < 0 {
+			continue
+		}
+		j := strings.Index(line[i+1:], `"`)
+		if j < 0 {
+			continue
+		}
+		fields := strings.Fields(line[i+1 : i+1+j])
+		if len(fields) >= 2 && strings.HasPrefix(fields[1], "/") {
+			reqs = append(reqs, Request{Method: fields[0], URI: fields[1]})
+		}
+	}
+	return reqs, scanner.Err()
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+type routeResults []RouteResult
+
+func (l routeResults) Len() int           { return len(l) }
+func (l routeResults) Less(i, j int) bool { return l[i].Route < l[j].Route }
+func (l routeResults) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadAccessLog(t *testing.T) {
+	log := `{"time":"2016-01-02T15:04:05Z","method":"GET","uri":"/users/1?x=1","route":"/users/:id","status":200}
+127.0.0.1 - - [02/Jan/2016:15:04:05 +0000] "POST /orders HTTP/1.1" 201 12 "-" "curl"
+not a log line
+`
+	reqs, err := ReadAccessLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %+v", reqs)
+	}
+	if r := reqs[0]; r.Method != "GET" || r.URI != "/users/1?x=1" || r.Route != "GET /users/:id" {
+		t.Errorf("json line = %+v", r)
+	}
+	if r := reqs[1]; r.Method != "POST" || r.URI != "/orders" || r.Route != "" {
+		t.Errorf("combined line = %+v", r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Bench") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	report, err := Run(Options{
+		Target:      srv.URL,
+		Concurrency: 4,
+		Requests:    40,
+		Header:      http.Header{"X-Bench": {"1"}},
+	}, []Request{
+		{Method: "GET", URI: "/users/1", Route: "GET /users/:id"},
+		{Method: "GET", URI: "/users/2", Route: "GET /users/:id"},
+		{Method: "GET", URI: "/fail?x=1"},
+		{Method: "POST", URI: "/ok", Body: []byte("{}")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.Requests != 40 || report.Total.Errors != 10 || len(report.Routes) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := map[string]uint64{"GET /fail": 10, "GET /users/:id": 20, "POST /ok": 10}
+	for _, r := range report.Routes {
+		if r.Requests != want[r.Route] || r.P99Ms < r.P50Ms || r.MaxMs < r.P99Ms {
+			t.Errorf("route = %+v", r)
+		}
+	}
+	if report.Routes[0].Status["500"] != 10 || report.Routes[1].Status["200"] != 20 {
+		t.Errorf("status = %v, %v", report.Routes[0].Status, report.Routes[1].Status)
+	}
+
+	if err = report.Check(Threshold{MaxP99: time.Minute}, Threshold{Route: "GET /users/:id", MaxErrorRate: 0.01}); err != nil {
+		t.Error(err)
+	}
+	err = report.Check(Threshold{MaxErrorRate: 0.01}, Threshold{Route: "GET /missing", MinRPS: 1})
+	if err == nil || !strings.Contains(err.Error(), "total: error rate") || !strings.Contains(err.Error(), "GET /missing") {
+		t.Errorf("Check = %v", err)
+	}
+
+	var buf bytes.Buffer
+	report.WriteText(&buf)
+	if !strings.Contains(buf.String(), "GET /users/:id") || !strings.Contains(buf.String(), "TOTAL") {
+		t.Errorf("text report:\n%s", buf.String())
+	}
+}
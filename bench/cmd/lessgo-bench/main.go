@@ -0,0 +1,92 @@
+// Command lessgo-bench replays an access log or a list of URIs against a
+// running app and prints the throughput and latency percentiles per route.
+// It exits with status 1 when a threshold is exceeded, for CI performance
+// gates:
+//
+//	lessgo-bench -target http://127.0.0.1:8080 -log access.log -d 30s -c 20 -p99 200ms -max-error-rate 0.01
+//	lessgo-bench -target http://127.0.0.1:8080 -n 1000 GET:/users/1 POST:/orders
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lessgo/lessgo/bench"
+)
+
+func main() {
+	var (
+		opt          bench.Options
+		th           bench.Threshold
+		logFile      = flag.String("log", "", "access log to replay, in the json or combined format")
+		asJSON       = flag.Bool("json", false, "print the report as JSON")
+		headerValues = flag.String("H", "", "headers sent with every request, as \"Key: value\" separated by \"\\n\"")
+	)
+	flag.StringVar(&opt.Target, "target", "http://127.0.0.1:8080", "base URL of the app")
+	flag.IntVar(&opt.Concurrency, "c", 10, "concurrent workers")
+	flag.DurationVar(&opt.Duration, "d", 0, "duration of the run")
+	flag.IntVar(&opt.Requests, "n", 0, "requests to send, each request once if neither -n nor -d is set")
+	flag.Float64Var(&opt.Rate, "rate", 0, "max requests per second, 0 for no limit")
+	flag.DurationVar(&opt.Timeout, "timeout", 10*time.Second, "timeout of a request")
+	flag.DurationVar(&th.MaxP99, "p99", 0, "fail if the p99 latency exceeds it")
+	flag.DurationVar(&th.MaxP90, "p90", 0, "fail if the p90 latency exceeds it")
+	flag.Float64Var(&th.MaxErrorRate, "max-error-rate", 0, "fail if the error rate exceeds it")
+	flag.Float64Var(&th.MinRPS, "min-rps", 0, "fail if the throughput is lower")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lessgo-bench [flags] [METHOD:]URI ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var reqs []bench.Request
+	if *logFile != "" {
+		f, err := os.Open(*logFile)
+		if err != nil {
+			fatal(err)
+		}
+		reqs, err = bench.ReadAccessLog(f)
+		f.Close()
+		if err != nil {
+			fatal(err)
+		}
+	}
+	for _, arg := range flag.Args() {
+		method, uri := "GET", arg
+		if i := strings.Index(arg, ":"); i > 0 && !strings.HasPrefix(arg, "/") {
+			method, uri = strings.ToUpper(arg[:i]), arg[i+1:]
+		}
+		reqs = append(reqs, bench.Request{Method: method, URI: uri})
+	}
+	if *headerValues != "" {
+		opt.Header = http.Header{}
+		for _, line := range strings.Split(*headerValues, "\n") {
+			if kv := strings.SplitN(line, ":", 2); len(kv) == 2 {
+				opt.Header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+			}
+		}
+	}
+
+	report, err := bench.Run(opt, reqs)
+	if err != nil {
+		fatal(err)
+	}
+	if *asJSON {
+		b, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Printf("%s\n", b)
+	} else {
+		report.WriteText(os.Stdout)
+	}
+	if err = report.Check(th); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
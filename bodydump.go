@@ -0,0 +1,243 @@
+package lessgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type (
+	// BodyDumpConfig defines the config for body dump middleware.
+	BodyDumpConfig struct {
+		// 请求体与响应体各自最多记录的字节数
+		MaxBytes int64
+
+		// 允许记录的Content-Type前缀，为空时全部记录
+		ContentTypes []string
+
+		// 需隐藏值的请求头(不区分大小写)
+		RedactHeaders []string
+
+		// 需隐藏值的JSON字段路径，如"password"、"user.token"、"cards.*.number"
+		RedactJSONPaths []string
+
+		// 需隐藏值的表单(application/x-www-form-urlencoded)字段名
+		RedactFormFields []string
+	}
+
+	// 一次请求的数据转储
+	BodyDump struct {
+		Method       string      `json:"method"`
+		URI          string      `json:"uri"`
+		Route        string      `json:"route"`
+		Status       int         `json:"status"`
+		ReqHeader    http.Header `json:"req_header"`
+		ReqBody      string      `json:"req_body"`
+		ReqTruncated bool        `json:"req_truncated"`
+		RespBody     string      `json:"resp_body"`
+		RespTrunc    bool        `json:"resp_truncated"`
+	}
+
+	// 按上限复制响应体的ResponseWriter
+	bodyDumpWriter struct {
+		responseWriterWrapper
+		buf       *bytes.Buffer
+		max       int64
+		truncated bool
+	}
+)
+
+var (
+	// 转储结果的处理函数，默认打印到系统日志
+	bodyDumpHandler = func(c *Context, dump *BodyDump) {
+		b, _ := json.Marshal(dump)
+		Log.Info("[BodyDump] %s", b)
+	}
+	bodyDumpHandlerLock sync.RWMutex
+)
+
+var DumpBody = ApiMiddleware{
+	Name: "请求响应数据转储",
+	Desc: "调试用，记录请求体与响应体(限制大小、过滤类型、隐藏敏感字段)",
+	Config: BodyDumpConfig{
+		MaxBytes:         4 << 10, // 4 KB
+		ContentTypes:     []string{MIMEApplicationJSON, MIMEApplicationXML, MIMEApplicationForm, "text/"},
+		RedactHeaders:    []string{HeaderAuthorization, HeaderCookie, HeaderSetCookie},
+		RedactJSONPaths:  []string{"password"},
+		RedactFormFields: []string{"password"},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(BodyDumpConfig)
+		if config.MaxBytes <= 0 {
+			config.MaxBytes = 4 << 10
+		}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				dump := &BodyDump{
+					Method: req.Method,
+					URI:    req.RequestURI,
+				}
+
+				// 读取请求体，并还原供后续处理使用
+				if req.Body != nil && matchContentType(req.Header.Get(HeaderContentType), config.ContentTypes) {
+					b, _ := ioutil.ReadAll(io.LimitReader(req.Body, config.MaxBytes+1))
+					if int64(len(b)) > config.MaxBytes {
+						dump.ReqTruncated = true
+						req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(b), req.Body))
+						b = b[:config.MaxBytes]
+					} else {
+						req.Body = ioutil.NopCloser(bytes.NewReader(b))
+					}
+					dump.ReqBody = redactBody(req.Header.Get(HeaderContentType), b, dump.ReqTruncated, &config)
+				}
+
+				w := &bodyDumpWriter{
+					responseWriterWrapper: responseWriterWrapper{c.response.writer},
+					buf:                   getBuffer(0),
+					max:                   config.MaxBytes,
+				}
+				c.response.writer = w
+				err := next(c)
+				c.response.writer = w.ResponseWriter
+
+				dump.Route = c.path
+				dump.Status = c.response.Status()
+				dump.ReqHeader = redactHeader(req.Header, config.RedactHeaders)
+				ctype := c.response.Header().Get(HeaderContentType)
+				if matchContentType(ctype, config.ContentTypes) {
+					dump.RespBody = redactBody(ctype, w.buf.Bytes(), w.truncated, &config)
+					dump.RespTrunc = w.truncated
+				}
+
+				bodyDumpHandlerLock.RLock()
+				fn := bodyDumpHandler
+				bodyDumpHandlerLock.RUnlock()
+				putBuffer(w.buf)
+				fn(c, dump)
+				return err
+			}
+		}
+	},
+}.Reg()
+
+// 设置数据转储结果的处理函数(内部默认打印到系统日志)
+func SetBodyDumpHandler(fn func(c *Context, dump *BodyDump)) {
+	bodyDumpHandlerLock.Lock()
+	bodyDumpHandler = fn
+	bodyDumpHandlerLock.Unlock()
+}
+
+func (w *bodyDumpWriter) Write(b []byte) (int, error) {
+	if remain := w.max - int64(w.buf.Len()); remain > 0 {
+		if int64(len(b)) > remain {
+			w.buf.Write(b[:remain])
+			w.truncated = true
+		} else {
+			w.buf.Write(b)
+		}
+	} else if len(b) > 0 {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func matchContentType(ctype string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.HasPrefix(ctype, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeader(header http.Header, redact []string) http.Header {
+	h := make(http.Header, len(header))
+	for k, v := range header {
+		h[k] = v
+	}
+	for _, k := range redact {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; ok {
+			h.Set(k, "***")
+		}
+	}
+	return h
+}
+
+// 隐藏JSON数据中指定路径的值及表单中指定字段的值，其他类型数据原样返回。
+// 需要隐藏但无法完整解析(如被截断)的数据整体以占位符代替。
+func redactBody(ctype string, b []byte, truncated bool, config *BodyDumpConfig) string {
+	switch {
+	case strings.HasPrefix(ctype, MIMEApplicationJSON) && len(config.RedactJSONPaths) > 0:
+		var v interface{}
+		if truncated || json.Unmarshal(b, &v) != nil {
+			return redactedBody(b)
+		}
+		for _, p := range config.RedactJSONPaths {
+			redactJSONPath(v, strings.Split(p, "."))
+		}
+		r, err := json.Marshal(v)
+		if err != nil {
+			return redactedBody(b)
+		}
+		return string(r)
+
+	case strings.HasPrefix(ctype, MIMEApplicationForm) && len(config.RedactFormFields) > 0:
+		form, err := url.ParseQuery(string(b))
+		if err != nil {
+			return redactedBody(b)
+		}
+		for _, k := range config.RedactFormFields {
+			if _, ok := form[k]; ok {
+				form.Set(k, "***")
+			}
+		}
+		return form.Encode()
+	}
+	return string(b)
+}
+
+func redactedBody(b []byte) string {
+	return fmt.Sprintf("[redacted %d bytes]", len(b))
+}
+
+func redactJSONPath(v interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if path[0] != "*" && k != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				t[k] = "***"
+			} else {
+				redactJSONPath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		// 数组按元素逐个匹配，"*"可显式表示任意下标
+		if path[0] == "*" {
+			path = path[1:]
+		}
+		for i, child := range t {
+			if len(path) == 0 {
+				t[i] = "***"
+			} else {
+				redactJSONPath(child, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDumpBody(t *testing.T) {
+	var dump *BodyDump
+	SetBodyDumpHandler(func(c *Context, d *BodyDump) { dump = d })
+	defer SetBodyDumpHandler(func(c *Context, d *BodyDump) {})
+	config := DumpBody.Config.(BodyDumpConfig)
+	config.MaxBytes = 16
+	// 将请求体原样回写，响应类型与请求相同
+	h := DumpBody.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+		b, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set(HeaderContentType, c.Request().Header.Get(HeaderContentType))
+		c.WriteHeader(http.StatusOK)
+		_, err = c.Write(b)
+		return err
+	})
+	do := func(ctype, body string) {
+		dump = nil
+		req, _ := http.NewRequest(POST, "/dump", strings.NewReader(body))
+		req.Header.Set(HeaderContentType, ctype)
+		req.Header.Set(HeaderAuthorization, "Bearer secret")
+		c, rec := testContext(req)
+		defer c.free()
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Body.String() != body {
+			t.Errorf("%s: client received %q, want %q", ctype, rec.Body.String(), body)
+		}
+		if dump == nil {
+			t.Fatalf("%s: no dump", ctype)
+		}
+	}
+
+	// 超出上限时截断记录，处理函数与客户端仍得到完整数据
+	long := "0123456789abcdefXYZ"
+	do(MIMETextPlain, long)
+	if dump.ReqBody != long[:16] || !dump.ReqTruncated || dump.RespBody != long[:16] || !dump.RespTrunc {
+		t.Errorf("truncated dump = %+v", dump)
+	}
+	if dump.Status != http.StatusOK || dump.ReqHeader.Get(HeaderAuthorization) != "***" {
+		t.Errorf("dump = %+v", dump)
+	}
+	do(MIMETextPlain, long[:16])
+	if dump.ReqBody != long[:16] || dump.ReqTruncated || dump.RespTrunc {
+		t.Errorf("dump at the limit = %+v", dump)
+	}
+
+	// 不记录二进制类型
+	do("image/png", "\x89PNG\r\n\x1a\n")
+	if dump.ReqBody != "" || dump.RespBody != "" {
+		t.Errorf("binary dump = %+v", dump)
+	}
+
+	do(MIMEApplicationJSON, `{"password":"x"}`)
+	if dump.ReqBody != `{"password":"***"}` || dump.RespBody != `{"password":"***"}` {
+		t.Errorf("json dump = %+v", dump)
+	}
+	// 截断的JSON无法解析，整体隐藏
+	do(MIMEApplicationJSON, `{"password":"xyz"}`)
+	if dump.ReqBody != "[redacted 16 bytes]" {
+		t.Errorf("truncated json dump = %q", dump.ReqBody)
+	}
+}
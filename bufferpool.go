@@ -0,0 +1,134 @@
+package lessgo
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// 响应缓冲池，按容量分级复用：最小级别512B，逐级翻倍至4MB，超出最大级别的缓冲不回收；
+// 取用时大小未知的，按近期归还缓冲的常见大小校准默认级别。
+const (
+	minBufferSize        = 512
+	bufferClasses        = 14 // 512B<<13 = 4MB
+	bufferCalibrateCalls = 1024
+)
+
+// 响应缓冲池统计
+type BufferPoolStats struct {
+	Gets        uint64  `json:"gets"`
+	Hits        uint64  `json:"hits"` // 取自池中的次数
+	Puts        uint64  `json:"puts"`
+	Drops       uint64  `json:"drops"` // 因容量超出最大级别而丢弃的次数
+	HitRate     float64 `json:"hit_rate"`
+	DefaultSize int     `json:"default_size"` // 大小未知时取用的缓冲容量
+}
+
+type bufferPool struct {
+	pools        [bufferClasses]sync.Pool
+	gets         uint64
+	hits         uint64
+	puts         uint64
+	drops        uint64
+	defaultClass uint32
+	classCalls   [bufferClasses]uint32 // 校准周期内各级别的归还次数
+	calls        uint32
+	calibrating  uint32
+}
+
+var responseBufferPool = new(bufferPool)
+
+// 取用至少可容纳size字节的缓冲，size<=0时按校准的默认大小；用完需调用putBuffer归还
+func getBuffer(size int) *bytes.Buffer {
+	return responseBufferPool.get(size)
+}
+
+// 归还缓冲，归还后不可再使用其内容
+func putBuffer(b *bytes.Buffer) {
+	responseBufferPool.put(b)
+}
+
+// 返回响应缓冲池统计
+func ReadBufferPoolStats() BufferPoolStats {
+	return responseBufferPool.stats()
+}
+
+// 容量不小于size的最小级别，超出最大级别时返回bufferClasses
+func bufferClassOf(size int) int {
+	class := 0
+	for class < bufferClasses && minBufferSize<<uint(class) < size {
+		class++
+	}
+	return class
+}
+
+func (p *bufferPool) get(size int) *bytes.Buffer {
+	atomic.AddUint64(&p.gets, 1)
+	class := int(atomic.LoadUint32(&p.defaultClass))
+	if size > 0 {
+		class = bufferClassOf(size)
+	}
+	if class == bufferClasses {
+		return bytes.NewBuffer(make([]byte, 0, size))
+	}
+	if b, _ := p.pools[class].Get().(*bytes.Buffer); b != nil {
+		atomic.AddUint64(&p.hits, 1)
+		return b
+	}
+	return bytes.NewBuffer(make([]byte, 0, minBufferSize<<uint(class)))
+}
+
+func (p *bufferPool) put(b *bytes.Buffer) {
+	atomic.AddUint64(&p.puts, 1)
+	c := b.Cap()
+	if c < minBufferSize || c > minBufferSize<<(bufferClasses-1) {
+		atomic.AddUint64(&p.drops, 1)
+		return
+	}
+	p.calibrate(bufferClassOf(b.Len()))
+	// 放入容量不超过c的最大级别，保证取出的缓冲满足该级别的大小
+	class := bufferClassOf(c)
+	if minBufferSize<<uint(class) > c {
+		class--
+	}
+	b.Reset()
+	p.pools[class].Put(b)
+}
+
+// 每归还bufferCalibrateCalls次，将默认级别设为其间归还最多的级别
+func (p *bufferPool) calibrate(class int) {
+	if class == bufferClasses {
+		return
+	}
+	atomic.AddUint32(&p.classCalls[class], 1)
+	if atomic.AddUint32(&p.calls, 1) < bufferCalibrateCalls {
+		return
+	}
+	if !atomic.CompareAndSwapUint32(&p.calibrating, 0, 1) {
+		return
+	}
+	var max uint32
+	best := 0
+	for i := range p.classCalls {
+		if n := atomic.SwapUint32(&p.classCalls[i], 0); n > max {
+			max, best = n, i
+		}
+	}
+	atomic.StoreUint32(&p.defaultClass, uint32(best))
+	atomic.StoreUint32(&p.calls, 0)
+	atomic.StoreUint32(&p.calibrating, 0)
+}
+
+func (p *bufferPool) stats() BufferPoolStats {
+	s := BufferPoolStats{
+		Gets:        atomic.LoadUint64(&p.gets),
+		Hits:        atomic.LoadUint64(&p.hits),
+		Puts:        atomic.LoadUint64(&p.puts),
+		Drops:       atomic.LoadUint64(&p.drops),
+		DefaultSize: minBufferSize << atomic.LoadUint32(&p.defaultClass),
+	}
+	if s.Gets > 0 {
+		s.HitRate = float64(s.Hits) / float64(s.Gets)
+	}
+	return s
+}
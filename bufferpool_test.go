@@ -0,0 +1,43 @@
+package lessgo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	p := new(bufferPool)
+	b := p.get(1000)
+	if b.Cap() < 1000 || b.Cap() != minBufferSize<<1 {
+		t.Fatalf("cap = %d", b.Cap())
+	}
+	b.Write(make([]byte, 3000)) // grows beyond its class
+	c := b.Cap()
+	p.put(b)
+	if b2 := p.get(minBufferSize << bufferClassOf(c)); b2 == b {
+		t.Errorf("buffer of cap %d reused for a larger class", c)
+	}
+	if b2 := p.get(2048); b2 != b && b2.Cap() < 2048 {
+		t.Errorf("got cap %d for 2048", b2.Cap())
+	}
+
+	p.put(bytes.NewBuffer(make([]byte, 0, 8<<20)))
+	big := p.get(5 << 20)
+	if big.Cap() < 5<<20 {
+		t.Errorf("big cap = %d", big.Cap())
+	}
+
+	for i := 0; i < bufferCalibrateCalls; i++ {
+		b := p.get(0)
+		b.Write(make([]byte, 6000))
+		p.put(b)
+	}
+	s := p.stats()
+	if s.DefaultSize != minBufferSize<<uint(bufferClassOf(6000)) {
+		t.Errorf("default size = %d", s.DefaultSize)
+	}
+	// sync.Pool可能随时丢弃对象(竞态检测下尤甚)，命中数不确定，只校验确定的计数
+	if s.Drops != 1 || s.Gets != uint64(4+bufferCalibrateCalls) || s.Puts != uint64(2+bufferCalibrateCalls) || s.Hits > s.Gets {
+		t.Errorf("stats = %+v", s)
+	}
+}
@@ -0,0 +1,369 @@
+// Package cache is an in-process cache bounded by the total cost of its
+// entries, with LRU or ARC(adaptive replacement) eviction, per-entry TTL,
+// singleflight loading and tag based invalidation.
+//
+//	c := cache.New(cache.Options{MaxCost: 64 << 20, Policy: cache.ARC})
+//	v, err := c.GetOrLoad("user:1", func() (interface{}, int64, time.Duration, error) {
+//		u, err := loadUser(1)
+//		return u, 1024, time.Minute, err
+//	})
+//	c.Set("user:1:orders", orders, 4096, time.Minute, cache.Tags("user:1"))
+//	c.InvalidateTag("user:1") // removes both entries
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LRU = "lru"
+	ARC = "arc"
+)
+
+// Options configures a Cache.
+type Options struct {
+	// Max total cost of the entries, such as the bytes held, <=0 for no limit.
+	MaxCost int64
+	// Eviction policy, LRU(default) or ARC, which also keeps entries used
+	// more than once from being flushed by a scan.
+	Policy string
+}
+
+// Stats of a Cache.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Loads     uint64 `json:"loads"` // loader calls, shared by concurrent GetOrLoad
+	Evictions uint64 `json:"evictions"`
+	Items     int    `json:"items"`
+	Cost      int64  `json:"cost"`
+}
+
+// ErrTooLarge is returned by Set when the cost of the entry exceeds MaxCost.
+var ErrTooLarge = errors.New("cache: entry too large")
+
+type entry struct {
+	key    string
+	value  interface{}
+	cost   int64
+	expire time.Time // zero for no expiry
+	ghost  bool      // evicted from ARC, only the key is remembered
+	tags   []string
+	list   *costList
+}
+
+// SetOption sets an optional attribute of an entry.
+type SetOption func(e *entry)
+
+// Tags tags the entry, InvalidateTag removes all entries of a tag.
+func Tags(tags ...string) SetOption {
+	return func(e *entry) {
+		e.tags = append(e.tags, tags...)
+	}
+}
+
+// costList is a list of entries with the sum of their costs.
+type costList struct {
+	l    *list.List
+	cost int64
+}
+
+func newCostList() *costList { return &costList{l: list.New()} }
+
+func (cl *costList) pushFront(e *entry) *list.Element {
+	e.list = cl
+	cl.cost += e.cost
+	return cl.l.PushFront(e)
+}
+
+func (cl *costList) remove(el *list.Element) *entry {
+	e := cl.l.Remove(el).(*entry)
+	cl.cost -= e.cost
+	return e
+}
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	maxCost int64
+	arc     bool
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	// LRU uses t1 only; ARC keeps t1 for entries seen once, t2 for entries
+	// seen more, b1 and b2 for their ghosts and p as the target cost of t1.
+	t1, t2, b1, b2 *costList
+	p              int64
+	calls          map[string]*call
+	tags           map[string]map[string]struct{} // tag to keys of live entries
+
+	hits, misses, loads, evictions uint64
+}
+
+// New returns a Cache.
+func New(opt Options) *Cache {
+	return &Cache{
+		maxCost: opt.MaxCost,
+		arc:     opt.Policy == ARC,
+		items:   map[string]*list.Element{},
+		t1:      newCostList(),
+		t2:      newCostList(),
+		b1:      newCostList(),
+		b2:      newCostList(),
+		calls:   map[string]*call{},
+		tags:    map[string]map[string]struct{}{},
+	}
+}
+
+// Get returns the value of key and whether it's found and not expired.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if ok {
+		e := el.Value.(*entry)
+		if !e.ghost && (e.expire.IsZero() || time.Now().Before(e.expire)) {
+			c.touch(el)
+			atomic.AddUint64(&c.hits, 1)
+			return e.value, true
+		}
+		if !e.ghost {
+			c.removeElement(el)
+		}
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return nil, false
+}
+
+// Set stores value under key with the cost, ttl<=0 for no expiry,
+// evicting other entries when MaxCost is exceeded.
+func (c *Cache) Set(key string, value interface{}, cost int64, ttl time.Duration, opts ...SetOption) error {
+	if cost < 0 {
+		cost = 0
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		c.Delete(key)
+		return ErrTooLarge
+	}
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.t1
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		if c.arc {
+			// seen again: a ghost hit adapts the target size of t1
+			switch e.list {
+			case c.b1:
+				c.p = min64(c.maxCost, c.p+max64(ratio(c.b2.cost, c.b1.cost), 1)*cost)
+			case c.b2:
+				c.p = max64(0, c.p-max64(ratio(c.b1.cost, c.b2.cost), 1)*cost)
+			}
+			target = c.t2
+		}
+		c.removeElement(el)
+	}
+	e := &entry{key: key, value: value, cost: cost, expire: expire}
+	for _, opt := range opts {
+		opt(e)
+	}
+	c.items[key] = target.pushFront(e)
+	c.index(e)
+	c.evict(target == c.t2)
+	return nil
+}
+
+// Delete removes key.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+	c.mu.Unlock()
+}
+
+// InvalidateTag removes the entries of the tags, returns the number removed.
+func (c *Cache) InvalidateTag(tags ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.items[key]; ok {
+				c.removeElement(el)
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Purge removes all entries.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.items = map[string]*list.Element{}
+	c.tags = map[string]map[string]struct{}{}
+	c.t1, c.t2, c.b1, c.b2 = newCostList(), newCostList(), newCostList(), newCostList()
+	c.p = 0
+	c.mu.Unlock()
+}
+
+// GetOrLoad returns the cached value of key, or calls load to get and cache
+// it; concurrent calls for the same key share one load. An error from load
+// is returned and not cached.
+func (c *Cache) GetOrLoad(key string, load func() (value interface{}, cost int64, ttl time.Duration, err error)) (interface{}, error) {
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+	c.mu.Lock()
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.value, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+	atomic.AddUint64(&c.loads, 1)
+	var (
+		cost int64
+		ttl  time.Duration
+	)
+	cl.value, cost, ttl, cl.err = load()
+	if cl.err == nil {
+		if err := c.Set(key, cl.value, cost, ttl); err != nil && err != ErrTooLarge {
+			cl.err = err
+		}
+	}
+	return cl.value, cl.err
+}
+
+// Len returns the number of live entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t1.l.Len() + c.t2.l.Len()
+}
+
+// Stats returns the counters and the current size.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	s := Stats{Items: c.t1.l.Len() + c.t2.l.Len(), Cost: c.t1.cost + c.t2.cost}
+	c.mu.Unlock()
+	s.Hits = atomic.LoadUint64(&c.hits)
+	s.Misses = atomic.LoadUint64(&c.misses)
+	s.Loads = atomic.LoadUint64(&c.loads)
+	s.Evictions = atomic.LoadUint64(&c.evictions)
+	return s
+}
+
+// touch marks a live entry as used.
+func (c *Cache) touch(el *list.Element) {
+	e := el.Value.(*entry)
+	if !c.arc || e.list == c.t2 {
+		e.list.l.MoveToFront(el)
+		return
+	}
+	// used twice: promote from t1 to t2
+	c.t1.remove(el)
+	c.items[e.key] = c.t2.pushFront(e)
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	e := el.Value.(*entry)
+	e.list.remove(el)
+	delete(c.items, e.key)
+	c.unindex(e)
+}
+
+func (c *Cache) index(e *entry) {
+	for _, tag := range e.tags {
+		keys := c.tags[tag]
+		if keys == nil {
+			keys = map[string]struct{}{}
+			c.tags[tag] = keys
+		}
+		keys[e.key] = struct{}{}
+	}
+}
+
+func (c *Cache) unindex(e *entry) {
+	for _, tag := range e.tags {
+		if keys := c.tags[tag]; keys != nil {
+			delete(keys, e.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+	e.tags = nil
+}
+
+// evict drops entries until the live cost fits MaxCost; with ARC the evicted
+// entries become ghosts, which are trimmed to MaxCost per list.
+func (c *Cache) evict(inB2 bool) {
+	if c.maxCost <= 0 {
+		return
+	}
+	for c.t1.cost+c.t2.cost > c.maxCost {
+		from, ghosts := c.t2, c.b2
+		if !c.arc || c.t1.l.Len() > 0 && (c.t1.cost > c.p || inB2 && c.t1.cost == c.p) || c.t2.l.Len() == 0 {
+			from, ghosts = c.t1, c.b1
+		}
+		e := from.remove(from.l.Back())
+		c.unindex(e)
+		atomic.AddUint64(&c.evictions, 1)
+		if !c.arc {
+			delete(c.items, e.key)
+			continue
+		}
+		e.value, e.ghost = nil, true
+		c.items[e.key] = ghosts.pushFront(e)
+	}
+	for _, ghosts := range []*costList{c.b1, c.b2} {
+		for ghosts.cost > c.maxCost && ghosts.l.Len() > 0 {
+			e := ghosts.remove(ghosts.l.Back())
+			delete(c.items, e.key)
+		}
+	}
+}
+
+func ratio(a, b int64) int64 {
+	if b == 0 {
+		return 1
+	}
+	return a / b
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	c := New(Options{MaxCost: 10})
+	c.Set("a", 1, 4, 0)
+	c.Set("b", 2, 4, 0)
+	c.Get("a")
+	c.Set("c", 3, 4, 0) // evicts b, the least recently used
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v", v, ok)
+	}
+	if err := c.Set("big", 0, 11, 0); err != ErrTooLarge {
+		t.Errorf("Set(big) error = %v", err)
+	}
+	s := c.Stats()
+	if s.Items != 2 || s.Cost != 8 || s.Evictions != 1 || s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c := New(Options{})
+	c.Set("a", 1, 1, 20*time.Millisecond)
+	c.Set("b", 2, 1, 0)
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should be expired")
+	}
+	if _, ok := c.Get("b"); !ok || c.Len() != 1 {
+		t.Errorf("b should be kept, Len() = %d", c.Len())
+	}
+}
+
+func TestARCScanResistance(t *testing.T) {
+	for _, policy := range []string{LRU, ARC} {
+		c := New(Options{MaxCost: 100, Policy: policy})
+		for i := 0; i < 50; i++ {
+			key := "hot" + strconv.Itoa(i)
+			c.Set(key, i, 1, 0)
+			c.Get(key)
+		}
+		// a scan touching each key once
+		for i := 0; i < 1000; i++ {
+			c.Set("scan"+strconv.Itoa(i), i, 1, 0)
+		}
+		hot := 0
+		for i := 0; i < 50; i++ {
+			if _, ok := c.Get("hot" + strconv.Itoa(i)); ok {
+				hot++
+			}
+		}
+		if policy == ARC && hot != 50 || policy == LRU && hot != 0 {
+			t.Errorf("%s: %d hot keys kept", policy, hot)
+		}
+		if s := c.Stats(); s.Cost > 100 {
+			t.Errorf("%s: cost %d exceeds MaxCost", policy, s.Cost)
+		}
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := New(Options{MaxCost: 100, Policy: ARC})
+	var loads int32
+	release := make(chan struct{})
+	load := func() (interface{}, int64, time.Duration, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "v", 1, 0, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad("k", load); v != "v" || err != nil {
+				t.Errorf("GetOrLoad() = %v, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+
+	fail := errors.New("fail")
+	if _, err := c.GetOrLoad("bad", func() (interface{}, int64, time.Duration, error) {
+		return nil, 0, 0, fail
+	}); err != fail {
+		t.Errorf("GetOrLoad() error = %v", err)
+	}
+	if _, ok := c.Get("bad"); ok {
+		t.Error("failed load should not be cached")
+	}
+}
+
+func TestInvalidateTag(t *testing.T) {
+	c := New(Options{MaxCost: 3})
+	c.Set("a", 1, 1, 0, Tags("user:1"))
+	c.Set("b", 2, 1, 0, Tags("user:1", "user:2"))
+	c.Set("c", 3, 1, 0, Tags("user:2"))
+	if n := c.InvalidateTag("user:1"); n != 2 || c.Len() != 1 {
+		t.Errorf("InvalidateTag(user:1) = %d, Len() = %d", n, c.Len())
+	}
+	// overwritten and evicted entries leave their tags
+	c.Set("c", 3, 1, 0)
+	c.Set("d", 4, 3, 0, Tags("user:3")) // evicts c
+	c.Set("e", 5, 3, 0)                 // evicts d
+	if n := c.InvalidateTag("user:2", "user:3"); n != 0 || c.Len() != 1 {
+		t.Errorf("InvalidateTag(user:2, user:3) = %d, Len() = %d", n, c.Len())
+	}
+	if len(c.tags) != 0 {
+		t.Errorf("tags = %v, want empty", c.tags)
+	}
+}
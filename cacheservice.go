@@ -0,0 +1,213 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/cache"
+	"github.com/lessgo/lessgo/redis"
+)
+
+type (
+	// 缓存存储接口，默认为进程内的cache.Cache
+	CacheStore interface {
+		// 返回key对应的值，不存在或已过期时返回false
+		Get(key string) (interface{}, bool)
+		// 保存值及其开销(如占用的字节数)，ttl<=0时不过期，可用cache.Tags设置标签
+		Set(key string, value interface{}, cost int64, ttl time.Duration, opts ...cache.SetOption) error
+		Delete(key string)
+		// 移除带有任一标签的条目，返回移除的条目数；仅作用于本实例，多实例间见InvalidateCacheTag
+		InvalidateTag(tags ...string) int
+		// 返回缓存的值，不存在时调用load加载并缓存，并发的相同key只加载一次
+		GetOrLoad(key string, load func() (value interface{}, cost int64, ttl time.Duration, err error)) (interface{}, error)
+	}
+
+	// 默认缓存的配置，对应配置中的[cache]段
+	CacheConfig struct {
+		MaxMB  int64  `config:"max_mb" default:"64" validate:"min=0"` // 缓存的最大字节数(按开销计)，0为不限
+		Policy string `default:"lru" validate:"oneof=lru|arc"`        // 淘汰策略：lru或arc(可抵御扫描式访问)
+	}
+
+	// 经Redis的pub/sub在多实例间同步的缓存标签失效
+	cacheTagSync struct {
+		client  *redis.Client
+		channel string
+		ps      *redis.PubSub
+		closed  bool
+		mu      sync.Mutex
+	}
+
+	// 缓存标签失效的广播消息
+	cacheTagMessage struct {
+		Node string   `json:"node"`
+		Tags []string `json:"tags"`
+	}
+)
+
+// 同步缓存标签失效的默认Redis频道
+const CACHE_TAG_CHANNEL = "lessgo:cache:tags"
+
+var (
+	cacheStore     CacheStore
+	cacheStoreLock sync.RWMutex
+
+	cacheTagSyncer *cacheTagSync
+	cacheTagLock   sync.RWMutex
+	cacheNode      = newEventId()
+)
+
+// 设置全局缓存存储，如多实例间共享的外部缓存
+func SetCache(store CacheStore) {
+	cacheStoreLock.Lock()
+	cacheStore = store
+	cacheStoreLock.Unlock()
+}
+
+// 返回全局缓存存储，未设置时按[cache]段的配置创建进程内缓存
+func GetCache() CacheStore {
+	cacheStoreLock.RLock()
+	store := cacheStore
+	cacheStoreLock.RUnlock()
+	if store != nil {
+		return store
+	}
+
+	cacheStoreLock.Lock()
+	defer cacheStoreLock.Unlock()
+	if cacheStore == nil {
+		var conf struct {
+			Cache CacheConfig
+		}
+		if err := Config.Bind(&conf); err != nil {
+			Log.Error("Invalid cache config, use the defaults: %v", err)
+			conf.Cache = CacheConfig{MaxMB: 64, Policy: cache.LRU}
+		}
+		cacheStore = cache.New(cache.Options{MaxCost: conf.Cache.MaxMB * MB, Policy: conf.Cache.Policy})
+	}
+	return cacheStore
+}
+
+// 返回全局缓存存储
+func (c *Context) Cache() CacheStore {
+	return GetCache()
+}
+
+// 使全局缓存中带有任一标签的条目失效，并在启用SyncCacheTags时广播至其它实例，返回本实例中移除的条目数
+func InvalidateCacheTag(tags ...string) int {
+	n := GetCache().InvalidateTag(tags...)
+	cacheTagLock.RLock()
+	s := cacheTagSyncer
+	cacheTagLock.RUnlock()
+	if s != nil {
+		b, _ := json.Marshal(cacheTagMessage{Node: cacheNode, Tags: tags})
+		if _, err := s.client.Do("PUBLISH", s.channel, b); err != nil {
+			Log.Error("Publish cache tags %v failed: %v", tags, err)
+		}
+	}
+	return n
+}
+
+// 使各实例缓存中带有任一标签的条目失效，如写操作后清除相关的缓存响应
+func (c *Context) InvalidateCacheTag(tags ...string) int {
+	return InvalidateCacheTag(tags...)
+}
+
+// 经Redis的pub/sub在多实例间同步InvalidateCacheTag，channel为空时使用CACHE_TAG_CHANNEL；
+// 连接中断后自动重新订阅，服务退出时停止
+func SyncCacheTags(client *redis.Client, channel string) error {
+	if channel == "" {
+		channel = CACHE_TAG_CHANNEL
+	}
+	s := &cacheTagSync{client: client, channel: channel}
+	ps, err := s.subscribe()
+	if err != nil {
+		return err
+	}
+	cacheTagLock.Lock()
+	old := cacheTagSyncer
+	cacheTagSyncer = s
+	cacheTagLock.Unlock()
+	if old != nil {
+		old.close()
+	} else {
+		OnShutdown(func() {
+			cacheTagLock.Lock()
+			s := cacheTagSyncer
+			cacheTagSyncer = nil
+			cacheTagLock.Unlock()
+			if s != nil {
+				s.close()
+			}
+		})
+	}
+	go s.receive(ps)
+	return nil
+}
+
+func (s *cacheTagSync) subscribe() (*redis.PubSub, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("cache tag sync: closed")
+	}
+	ps, err := s.client.Subscribe(s.channel)
+	if err != nil {
+		return nil, err
+	}
+	s.ps = ps
+	return ps, nil
+}
+
+func (s *cacheTagSync) receive(ps *redis.PubSub) {
+	for {
+		msg, err := ps.Receive()
+		if err == nil {
+			var m cacheTagMessage
+			// 忽略本实例发出的消息
+			if json.Unmarshal(msg.Data, &m) == nil && m.Node != cacheNode {
+				GetCache().InvalidateTag(m.Tags...)
+			}
+			continue
+		}
+		ps.Close()
+		for {
+			if ps, err = s.subscribe(); err == nil {
+				break
+			}
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+			Log.Error("Subscribe cache tags failed: %v", err)
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func (s *cacheTagSync) close() {
+	s.mu.Lock()
+	s.closed = true
+	if s.ps != nil {
+		s.ps.Close()
+	}
+	s.mu.Unlock()
+}
+
+// 查询缓存命中率等状态的操作，供后台管理路由使用
+var CacheStatsHandler = ApiHandler{
+	Desc:   "查询缓存状态",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		if s, ok := GetCache().(interface {
+			Stats() cache.Stats
+		}); ok {
+			return c.JSON(http.StatusOK, s.Stats())
+		}
+		return NewHTTPError(http.StatusNotImplemented, "the cache store has no stats")
+	},
+}.Reg()
@@ -0,0 +1,176 @@
+package lessgo
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CanonicalConfig defines the config for HTTPS and WWW canonicalization middleware.
+type CanonicalConfig struct {
+	// 是否将HTTP请求重定向到HTTPS
+	HTTPS bool
+
+	// HTTPS端口，为0或443时不在地址中显示端口
+	HTTPSPort int
+
+	// www规范化："add"添加www前缀，"remove"去掉www前缀，为空时不处理
+	WWW string
+
+	// 按主机名单独指定规范主机名，优先于WWW规则，如{"example.cn":"www.example.com"}
+	Hosts map[string]string
+
+	// 可信代理的IP或CIDR网段，仅信任来自这些地址的X-Forwarded-Proto与X-Forwarded-Host
+	TrustedProxies []string
+
+	// 不做重定向的路径前缀，如"/.well-known/acme-challenge/"
+	ExemptPaths []string
+
+	// 重定向状态码，301或302
+	Code int
+}
+
+var Canonical = ApiMiddleware{
+	Name: "HTTPS与WWW规范化",
+	Desc: "将HTTP重定向到HTTPS，并按主机规范化www前缀(支持X-Forwarded-Proto与可信代理)",
+	Config: CanonicalConfig{
+		HTTPS:          true,
+		HTTPSPort:      443,
+		WWW:            "",
+		Hosts:          map[string]string{},
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+		ExemptPaths:    []string{"/.well-known/acme-challenge/"},
+		Code:           http.StatusMovedPermanently,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(CanonicalConfig)
+		if config.Code != http.StatusFound && config.Code != http.StatusTemporaryRedirect {
+			config.Code = http.StatusMovedPermanently
+		}
+		proxies := parseIPNets(config.TrustedProxies, "Canonical: invalid TrustedProxies item")
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				for _, p := range config.ExemptPaths {
+					if strings.HasPrefix(req.URL.Path, p) {
+						return next(c)
+					}
+				}
+
+				scheme := c.Scheme()
+				host := req.Host
+				if ipNetsContain(proxies, remoteIP(req)) {
+					if p := req.Header.Get(HeaderXForwardedProto); p != "" {
+						scheme = strings.ToLower(strings.TrimSpace(strings.Split(p, ",")[0]))
+					}
+					if h := req.Header.Get("X-Forwarded-Host"); h != "" {
+						host = strings.TrimSpace(strings.Split(h, ",")[0])
+					}
+				}
+				hostname, port := host, ""
+				if h, p, err := net.SplitHostPort(host); err == nil {
+					hostname, port = h, p
+				}
+
+				newScheme, newHostname := scheme, strings.ToLower(hostname)
+				if h, ok := config.Hosts[newHostname]; ok && h != "" {
+					newHostname = h
+				} else if net.ParseIP(newHostname) == nil {
+					switch config.WWW {
+					case "add":
+						if !strings.HasPrefix(newHostname, "www.") {
+							newHostname = "www." + newHostname
+						}
+					case "remove":
+						newHostname = strings.TrimPrefix(newHostname, "www.")
+					}
+				}
+				if config.HTTPS && scheme != "https" {
+					newScheme = "https"
+					port = ""
+					if config.HTTPSPort != 0 && config.HTTPSPort != 443 {
+						port = strconv.Itoa(config.HTTPSPort)
+					}
+				}
+				if newScheme == scheme && newHostname == strings.ToLower(hostname) {
+					return next(c)
+				}
+				if port != "" {
+					newHostname = net.JoinHostPort(newHostname, port)
+				}
+				return c.Redirect(config.Code, newScheme+"://"+newHostname+req.URL.RequestURI())
+			}
+		}
+	},
+}.Reg()
+
+// 解析IP或CIDR列表，无效项记录错误日志后忽略
+func parseIPNets(list []string, errPrefix string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			if strings.Contains(s, ":") {
+				s += "/128"
+			} else {
+				s += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			Log.Error("%s %q: %v", errPrefix, s, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func ipNetsContain(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 直接连接的对端IP(不信任转发头)
+func remoteIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// 客户端IP：直接连接的对端为可信代理时，取X-Forwarded-For中自右向左第一个非可信代理的地址，
+// 无X-Forwarded-For时取X-Real-IP；否则为直接连接的对端IP
+func clientIP(req *http.Request, proxies []*net.IPNet) net.IP {
+	ip := remoteIP(req)
+	if !ipNetsContain(proxies, ip) {
+		return ip
+	}
+	if xff := req.Header[HeaderXForwardedFor]; len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				break
+			}
+			ip = hop
+			if !ipNetsContain(proxies, hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get(HeaderXRealIP))); realIP != nil {
+		return realIP
+	}
+	return ip
+}
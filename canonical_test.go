@@ -0,0 +1,101 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := parseIPNets([]string{"10.0.0.0/8"}, "")
+	for _, test := range []struct {
+		remote, xff, realIP string
+		want                string
+	}{
+		{"1.2.3.4:5678", "", "", "1.2.3.4"},
+		// 非可信代理发来的转发头不被信任
+		{"1.2.3.4:5678", "9.9.9.9", "8.8.8.8", "1.2.3.4"},
+		{"10.0.0.1:80", "9.9.9.9", "", "9.9.9.9"},
+		// 取最右侧的非可信代理地址，忽略客户端伪造的左侧地址
+		{"10.0.0.1:80", "6.6.6.6, 9.9.9.9, 10.0.0.2", "", "9.9.9.9"},
+		{"10.0.0.1:80", "", "8.8.8.8", "8.8.8.8"},
+		{"10.0.0.1:80", "", "", "10.0.0.1"},
+	} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remote
+		if test.xff != "" {
+			req.Header.Set(HeaderXForwardedFor, test.xff)
+		}
+		if test.realIP != "" {
+			req.Header.Set(HeaderXRealIP, test.realIP)
+		}
+		if got := clientIP(req, proxies).String(); got != test.want {
+			t.Errorf("clientIP(%s, %q, %q) = %s, want %s", test.remote, test.xff, test.realIP, got, test.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	config := Canonical.Config.(CanonicalConfig)
+	config.WWW = "add"
+	config.Hosts = map[string]string{"example.cn": "www.example.com"}
+	h := Canonical.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	for _, test := range []struct {
+		url, remote, proto string
+		code               int
+		location           string
+	}{
+		{"http://www.example.com/a?b=1&c=2", "1.2.3.4:5", "", 301, "https://www.example.com/a?b=1&c=2"},
+		{"http://example.com:8080/a?b=1", "1.2.3.4:5", "", 301, "https://www.example.com/a?b=1"},
+		{"http://example.cn/a/?q=x%20y", "1.2.3.4:5", "", 301, "https://www.example.com/a/?q=x%20y"},
+		// 可信代理已终止TLS，只规范化主机名
+		{"http://example.com/a?b=1", "127.0.0.1:5", "https", 301, "https://www.example.com/a?b=1"},
+		{"http://www.example.com/a", "127.0.0.1:5", "https", 200, ""},
+		// 非可信来源的X-Forwarded-Proto被忽略
+		{"http://www.example.com/a", "1.2.3.4:5", "https", 301, "https://www.example.com/a"},
+		{"http://192.168.1.1/a", "127.0.0.1:5", "https", 200, ""},
+		{"http://example.com/.well-known/acme-challenge/t", "1.2.3.4:5", "", 200, ""},
+	} {
+		req, _ := http.NewRequest(GET, test.url, nil)
+		req.RemoteAddr = test.remote
+		if test.proto != "" {
+			req.Header.Set(HeaderXForwardedProto, test.proto)
+		}
+		c, rec := testContext(req)
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != test.code || rec.Header().Get(HeaderLocation) != test.location {
+			t.Errorf("%s (proto %q from %s) = %d %q, want %d %q", test.url, test.proto, test.remote,
+				rec.Code, rec.Header().Get(HeaderLocation), test.code, test.location)
+		}
+		c.free()
+	}
+}
+
+func TestTrailingSlashRedirect(t *testing.T) {
+	ReregisterRouter()
+	defer ReregisterRouter()
+	ok := func(c *Context) error { return c.NoContent(http.StatusOK) }
+	app.add(GET, "/canonical/items", ok)
+	app.add(POST, "/canonical/items", ok)
+	for _, test := range []struct {
+		method, url string
+		code        int
+		location    string
+	}{
+		{GET, "/canonical/items/?page=2&sort=name", 301, "/canonical/items?page=2&sort=name"},
+		// 非GET请求以307重定向，保留请求方法
+		{POST, "/canonical/items/?x=1", 307, "/canonical/items?x=1"},
+		{GET, "/canonical/items?page=2", 200, ""},
+	} {
+		req, _ := http.NewRequest(test.method, test.url, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		if rec.Code != test.code || rec.Header().Get(HeaderLocation) != test.location {
+			t.Errorf("%s %s = %d %q, want %d %q", test.method, test.url, rec.Code, rec.Header().Get(HeaderLocation), test.code, test.location)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 路由的处理链在注册时组合完成，请求时不应再分配
+func TestRouteChainAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not stable under the race detector")
+	}
+	ReregisterRouter()
+	defer ReregisterRouter()
+	var calls int
+	mw := func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			calls++
+			return next(c)
+		}
+	}
+	app.add(GET, "/chain/:id", func(c *Context) error { return nil }, mw, mw, mw)
+	req, _ := http.NewRequest(GET, "/chain/1", nil)
+	rw := httptest.NewRecorder()
+	allocs := testing.AllocsPerRun(100, func() { app.ServeHTTP(rw, req) })
+	if calls != 3*101 {
+		t.Fatalf("middleware calls = %d", calls)
+	}
+	if allocs > 0 {
+		t.Errorf("serving a route allocates %v times per request", allocs)
+	}
+}
@@ -0,0 +1,428 @@
+// Package client is an instrumented HTTP client for outbound calls made while
+// handling a request. It propagates the request ID and trace headers of the
+// inbound request, applies a timeout to every request and records metrics
+// per host. Connections are pooled per host, the requests in flight to a host
+// can be limited and failed idempotent requests retried with backoff.
+//
+//	var inventory = client.New(client.Options{Timeout: 3 * time.Second})
+//
+//	func handler(c *lessgo.Context) error {
+//		resp, err := inventory.Get(c, "http://inventory/items/1")
+//		...
+//	}
+//
+// Hooks added by AddHook are called around every request, for tracing spans
+// or custom metrics.
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Headers propagated by default from the inbound request, W3C Trace Context
+// and B3 besides the request ID.
+var PropagatedHeaders = []string{
+	"X-Request-ID",
+	"traceparent",
+	"tracestate",
+	"X-B3-TraceId",
+	"X-B3-SpanId",
+	"X-B3-ParentSpanId",
+	"X-B3-Sampled",
+	"X-B3-Flags",
+	"b3",
+}
+
+// Propagator provides the headers to propagate to outbound requests,
+// *lessgo.Context implements it.
+type Propagator interface {
+	PropagationHeader() http.Header
+}
+
+// Options configures a Client.
+type Options struct {
+	// Timeout of a request including reading the response body, 10s by
+	// default, <0 for none.
+	Timeout time.Duration
+
+	// Transport used to send requests, a pooled transport built from
+	// MaxIdleConnsPerHost by default.
+	Transport http.RoundTripper
+
+	// Idle keep-alive connections kept per host by the default transport,
+	// 32 by default.
+	MaxIdleConnsPerHost int
+
+	// Max requests in flight per host, 0 for no limit. Requests over the
+	// limit wait for a slot within their timeout; a slot is released when
+	// the response body is closed.
+	MaxConnsPerHost int
+
+	// Retries of a failed idempotent request, after a connection error or a
+	// 502, 503 or 504 response. GET, HEAD, OPTIONS, PUT, DELETE and requests
+	// with an Idempotency-Key header are idempotent. Request bodies are
+	// buffered to be sent again. The timeout covers all the attempts.
+	Retries int
+
+	// Backoff before the first retry, doubled for each next one up to 5s,
+	// with jitter; 100ms by default.
+	RetryBackoff time.Duration
+
+	// Headers set on every request unless already set, e.g. User-Agent.
+	Header http.Header
+}
+
+// Hook is called before a request is sent, the returned func, if not nil,
+// is called with the result when the response headers are received or the
+// request fails.
+type Hook func(req *http.Request) func(resp *http.Response, err error)
+
+// Stats of the requests sent to a host.
+type Stats struct {
+	Host     string  `json:"host"`
+	Requests uint64  `json:"requests"`
+	Errors   uint64  `json:"errors"`   // failed requests and 5xx responses
+	Timeouts uint64  `json:"timeouts"` // requests canceled by the timeout
+	Retries  uint64  `json:"retries"`  // attempts after the first
+	AvgMs    float64 `json:"avg_ms"`   // until the response headers are received
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// ErrTimeout is returned when a request is canceled by its timeout.
+var ErrTimeout = errors.New("client: request timeout")
+
+// Client is safe for concurrent use.
+type Client struct {
+	opt   Options
+	http  *http.Client
+	mu    sync.RWMutex
+	hooks []Hook
+	hosts map[string]*hostMetrics
+}
+
+type hostMetrics struct {
+	requests, errors, timeouts, retries uint64
+	mu                                  sync.Mutex
+	total, max                          time.Duration
+	slots                               chan struct{} // nil if not limited
+}
+
+// New returns a Client.
+func New(opt Options) *Client {
+	if opt.Timeout == 0 {
+		opt.Timeout = 10 * time.Second
+	}
+	if opt.MaxIdleConnsPerHost <= 0 {
+		opt.MaxIdleConnsPerHost = 32
+	}
+	if opt.RetryBackoff <= 0 {
+		opt.RetryBackoff = 100 * time.Millisecond
+	}
+	if opt.Transport == nil {
+		opt.Transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			Dial: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			MaxIdleConnsPerHost:   opt.MaxIdleConnsPerHost,
+		}
+	}
+	return &Client{
+		opt:   opt,
+		http:  &http.Client{Transport: opt.Transport},
+		hosts: map[string]*hostMetrics{},
+	}
+}
+
+// AddHook adds a hook called around every request.
+func (c *Client) AddHook(h Hook) {
+	c.mu.Lock()
+	c.hooks = append(c.hooks, h)
+	c.mu.Unlock()
+}
+
+// Do sends req with the default timeout, src may be nil.
+func (c *Client) Do(src Propagator, req *http.Request) (*http.Response, error) {
+	return c.DoTimeout(src, req, c.opt.Timeout)
+}
+
+// DoTimeout sends req, copying the headers provided by src that req doesn't
+// set. The timeout covers waiting for a host slot, the retries and reading
+// the response body, which must be closed.
+func (c *Client) DoTimeout(src Propagator, req *http.Request, timeout time.Duration) (resp *http.Response, err error) {
+	if src != nil {
+		for k, v := range src.PropagationHeader() {
+			if _, ok := req.Header[k]; !ok {
+				req.Header[k] = v
+			}
+		}
+	}
+	for k, v := range c.opt.Header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = v
+		}
+	}
+
+	var (
+		timer    *time.Timer
+		cancel   chan struct{}
+		timedOut int32
+	)
+	if timeout > 0 {
+		cancel = make(chan struct{})
+		req.Cancel = cancel
+		timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&timedOut, 1)
+			close(cancel)
+		})
+	}
+
+	retries := 0
+	var body []byte
+	if c.opt.Retries > 0 && idempotent(req) {
+		retries = c.opt.Retries
+		if req.Body != nil {
+			if body, err = ioutil.ReadAll(req.Body); err != nil {
+				stopTimer(timer)
+				return nil, err
+			}
+			req.Body.Close()
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	c.mu.RLock()
+	hooks := c.hooks
+	c.mu.RUnlock()
+	var finish []func(*http.Response, error)
+	for _, h := range hooks {
+		if f := h(req); f != nil {
+			finish = append(finish, f)
+		}
+	}
+
+	m := c.metrics(req.URL.Host)
+	start := time.Now()
+	release, err := m.acquire(cancel)
+	for attempt := 0; release != nil; attempt++ {
+		if attempt > 0 {
+			atomic.AddUint64(&m.retries, 1)
+			if body != nil {
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+			if !sleep(backoff(c.opt.RetryBackoff, attempt), cancel) {
+				err = ErrTimeout
+				break
+			}
+		}
+		resp, err = c.http.Do(req)
+		if attempt >= retries || atomic.LoadInt32(&timedOut) == 1 || !retryable(resp, err) {
+			break
+		}
+		if err == nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	if err != nil && atomic.LoadInt32(&timedOut) == 1 {
+		err = fmt.Errorf("%v: %s %s after %v", ErrTimeout, req.Method, req.URL, timeout)
+	}
+	m.observe(time.Since(start), err != nil || resp.StatusCode >= 500, atomic.LoadInt32(&timedOut) == 1)
+	for i := len(finish) - 1; i >= 0; i-- {
+		finish[i](resp, err)
+	}
+
+	if err != nil {
+		stopTimer(timer)
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	if timer != nil || m.slots != nil {
+		resp.Body = &releaseBody{ReadCloser: resp.Body, timer: timer, release: release}
+	}
+	return resp, nil
+}
+
+// Get sends a GET request.
+func (c *Client) Get(src Propagator, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(src, req)
+}
+
+// Post sends a POST request.
+func (c *Client) Post(src Propagator, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(src, req)
+}
+
+// Stats returns the stats per host, sorted by host.
+func (c *Client) Stats() []Stats {
+	c.mu.RLock()
+	stats := make([]Stats, 0, len(c.hosts))
+	for host, m := range c.hosts {
+		stats = append(stats, m.stats(host))
+	}
+	c.mu.RUnlock()
+	sort.Sort(statsSlice(stats))
+	return stats
+}
+
+// idempotent reports whether req can be sent again.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryable reports whether the result of an attempt is worth a retry.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the attempt-th retry, between half and
+// all of base doubled attempt-1 times, up to 5s.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt-1)
+	if d > 5*time.Second || d <= 0 {
+		d = 5 * time.Second
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits d, false if cancel is closed first.
+func sleep(d time.Duration, cancel <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+func (c *Client) metrics(host string) *hostMetrics {
+	host = strings.ToLower(host)
+	c.mu.RLock()
+	m := c.hosts[host]
+	c.mu.RUnlock()
+	if m != nil {
+		return m
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m = c.hosts[host]; m == nil {
+		m = &hostMetrics{}
+		if c.opt.MaxConnsPerHost > 0 {
+			m.slots = make(chan struct{}, c.opt.MaxConnsPerHost)
+		}
+		c.hosts[host] = m
+	}
+	return m
+}
+
+// acquire waits for a slot of the host, the returned func releases it.
+func (m *hostMetrics) acquire(cancel <-chan struct{}) (func(), error) {
+	if m.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case m.slots <- struct{}{}:
+	case <-cancel:
+		return nil, ErrTimeout
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-m.slots }) }, nil
+}
+
+func (m *hostMetrics) observe(d time.Duration, failed, timedOut bool) {
+	atomic.AddUint64(&m.requests, 1)
+	if failed {
+		atomic.AddUint64(&m.errors, 1)
+	}
+	if timedOut {
+		atomic.AddUint64(&m.timeouts, 1)
+	}
+	m.mu.Lock()
+	m.total += d
+	if d > m.max {
+		m.max = d
+	}
+	m.mu.Unlock()
+}
+
+func (m *hostMetrics) stats(host string) Stats {
+	s := Stats{
+		Host:     host,
+		Requests: atomic.LoadUint64(&m.requests),
+		Errors:   atomic.LoadUint64(&m.errors),
+		Timeouts: atomic.LoadUint64(&m.timeouts),
+		Retries:  atomic.LoadUint64(&m.retries),
+	}
+	m.mu.Lock()
+	if s.Requests > 0 {
+		s.AvgMs = float64(m.total) / float64(s.Requests) / float64(time.Millisecond)
+	}
+	s.MaxMs = float64(m.max) / float64(time.Millisecond)
+	m.mu.Unlock()
+	return s
+}
+
+// releaseBody stops the timeout timer and releases the host slot when the
+// body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	stopTimer(b.timer)
+	b.release()
+	return b.ReadCloser.Close()
+}
+
+type statsSlice []Stats
+
+func (l statsSlice) Len() int           { return len(l) }
+func (l statsSlice) Less(i, j int) bool { return l[i].Host < l[j].Host }
+func (l statsSlice) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
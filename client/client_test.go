@@ -0,0 +1,195 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type header http.Header
+
+func (h header) PropagationHeader() http.Header { return http.Header(h) }
+
+func TestPropagation(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	c := New(Options{Header: http.Header{"User-Agent": {"test"}}})
+	src := header{
+		"X-Request-Id": {"abc"},
+		"Traceparent":  {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Traceparent", "override")
+	resp, err := c.Do(src, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Get("X-Request-Id") != "abc" {
+		t.Errorf("request id = %q", got.Get("X-Request-Id"))
+	}
+	if got.Get("Traceparent") != "override" {
+		t.Errorf("traceparent = %q, want the request's own", got.Get("Traceparent"))
+	}
+	if got.Get("User-Agent") != "test" {
+		t.Errorf("user agent = %q", got.Get("User-Agent"))
+	}
+}
+
+func TestTimeoutAndStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := New(Options{Timeout: 50 * time.Millisecond})
+	var hooked []string
+	c.AddHook(func(req *http.Request) func(*http.Response, error) {
+		return func(resp *http.Response, err error) {
+			hooked = append(hooked, req.URL.Path)
+		}
+	})
+
+	resp, err := c.Get(nil, srv.URL+"/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("body = %q", body)
+	}
+
+	if _, err = c.Get(nil, srv.URL+"/slow"); err == nil || !strings.Contains(err.Error(), ErrTimeout.Error()) {
+		t.Errorf("slow request err = %v, want timeout", err)
+	}
+	resp, err = c.DoTimeout(nil, mustRequest(srv.URL+"/fail"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	stats := c.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	s := stats[0]
+	if s.Requests != 3 || s.Errors != 2 || s.Timeouts != 1 {
+		t.Errorf("stats = %+v, want 3 requests, 2 errors, 1 timeout", s)
+	}
+	if len(hooked) != 3 {
+		t.Errorf("hooked = %v", hooked)
+	}
+}
+
+func mustRequest(url string) *http.Request {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+func TestRetries(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	c := New(Options{Retries: 2, RetryBackoff: time.Millisecond})
+	req, _ := http.NewRequest("PUT", srv.URL, strings.NewReader("payload"))
+	resp, err := c.Do(nil, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" {
+		t.Errorf("response = %d %q, want the body resent", resp.StatusCode, body)
+	}
+	if s := c.Stats()[0]; s.Requests != 1 || s.Retries != 2 || s.Errors != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+
+	// POST isn't idempotent without an Idempotency-Key.
+	atomic.StoreInt32(&attempts, 0)
+	resp, err = c.Post(nil, srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("POST status = %d after %d attempts", resp.StatusCode, attempts)
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	var inFlight, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+	}))
+	defer srv.Close()
+
+	c := New(Options{MaxConnsPerHost: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(nil, srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if max > 2 {
+		t.Errorf("%d requests in flight, want at most 2", max)
+	}
+
+	// A request waiting for a slot is bounded by its timeout.
+	resp, err := c.Get(nil, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	resp2, err := c.Get(nil, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+	if _, err = c.DoTimeout(nil, mustRequest(srv.URL), 20*time.Millisecond); err == nil || !strings.Contains(err.Error(), ErrTimeout.Error()) {
+		t.Errorf("err = %v, want timeout waiting for a slot", err)
+	}
+}
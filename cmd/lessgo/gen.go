@@ -0,0 +1,1223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var cmdGen = &command{
+	name:  "gen",
+	usage: "server -spec file [-out dir] [-package name] [-prefix path]",
+	short: "generate handlers, request and response types and handler stubs from an OpenAPI or Swagger spec",
+}
+
+func init() {
+	cmdGen.run = runGen
+	commands = append(commands, cmdGen)
+}
+
+// The generated files: genFile is rewritten on every run, stubFile is
+// created once and then only gets the stubs of new operations.
+const (
+	genFile  = "server_gen.go"
+	stubFile = "server.go"
+)
+
+const lessgoImport = "github.com/lessgo/lessgo"
+
+func runGen(args []string) error {
+	fs := cmdGen.flagSet()
+	specFile := fs.String("spec", "", "OpenAPI 3.x or Swagger 2.0 spec, in JSON or YAML")
+	out := fs.String("out", "bizhandler/api", "directory of the generated package")
+	pkg := fs.String("package", "", "name of the generated package, the base name of -out by default")
+	prefix := fs.String("prefix", "", "path prefix of the routes, the path of the first server URL or the base path of the spec by default")
+	if len(args) == 0 || args[0] != "server" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fs.Parse(args[1:])
+	if *specFile == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := *pkg
+	if name == "" {
+		name = filepath.Base(filepath.Clean(*out))
+	}
+	if !token.IsIdentifier(name) || name == "main" {
+		return fmt.Errorf("invalid package name %q, set one with -package", name)
+	}
+	spec, err := loadSpec(*specFile)
+	if err != nil {
+		return err
+	}
+	g := newGenerator(spec, filepath.Base(*specFile), name)
+	if *prefix != "" {
+		g.prefix = *prefix
+	}
+	files, err := g.write(*out)
+	for _, w := range g.warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Println("write", f)
+	}
+	fmt.Printf("\nImplement the handlers in %s and mount the routes in an init function of the router:\n\n\tlessgo.Root(%s.Routes())\n",
+		filepath.Join(*out, stubFile), name)
+	return nil
+}
+
+type generator struct {
+	spec   *apiSpec
+	source string // base name of the spec file
+	pkg    string
+	prefix string
+
+	names       map[string]string // Go names of the named schemas
+	structs     map[string]bool   // Go types that are structs
+	declared    map[string]bool   // declared Go identifiers
+	decls       []string          // type declarations
+	imports     map[string]bool
+	needCookie  bool
+	needSplit   bool
+	ops         []*genOperation
+	warnings    []string
+	lowerNames  map[string]bool // unexported identifiers
+	currentPath string          // for error messages
+}
+
+type genOperation struct {
+	name   string // exported name of the ApiHandler
+	stub   string // unexported name of the function implementing it
+	method string
+	path   string
+	desc   string
+	doc    string
+
+	leaf string // prefix of the route, "" if the path cannot be routed
+
+	params     []*genParam
+	paramsType string // "" if the operation has no parameters
+
+	body         string // Go type of the request body, "" if none
+	bodyRequired bool
+	bodyRaw      string // media type of a body the stub reads itself
+
+	code    int
+	resType string // Go type of the JSON response, "" if none
+	resRaw  string // media type of a response the stub writes itself
+}
+
+type genParam struct {
+	name     string
+	in       string
+	desc     string
+	required bool
+	field    string
+	typ      string // Go type of the field
+	base     string // builtin type parsed from the value
+	array    bool
+	file     bool // Swagger 2.0 file upload, read by the stub
+	def      string
+}
+
+func newGenerator(spec *apiSpec, source, pkg string) *generator {
+	return &generator{
+		spec:       spec,
+		source:     source,
+		pkg:        pkg,
+		prefix:     spec.basePath(),
+		names:      map[string]string{},
+		structs:    map[string]bool{},
+		declared:   map[string]bool{"Routes": true},
+		imports:    map[string]bool{"net/http": true, lessgoImport: true},
+		lowerNames: map[string]bool{"cookieParam": true, "splitParam": true},
+	}
+}
+
+// write generates the package into dir and returns the written files.
+func (g *generator) write(dir string) ([]string, error) {
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(g.genSource())
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %v", genFile, err)
+	}
+	name := filepath.Join(dir, genFile)
+	if err = os.WriteFile(name, src, 0644); err != nil {
+		return nil, err
+	}
+	files := []string{name}
+
+	name = filepath.Join(dir, stubFile)
+	old, err := os.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return files, err
+	}
+	src, n, err := g.stubSource(old)
+	if err != nil {
+		return files, err
+	}
+	if n > 0 {
+		if err = os.WriteFile(name, src, 0644); err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// load declares the types and collects the operations of the spec.
+func (g *generator) load() error {
+	schemas := g.spec.schemas()
+	var names []string
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.names[name] = g.uniqueName(goName(name))
+	}
+	for _, name := range names {
+		if s, err := g.spec.resolve(schemas[name]); err == nil && isObject(s) {
+			g.structs[g.names[name]] = true
+		}
+	}
+	for _, name := range names {
+		if err := g.declareNamed(g.names[name], schemas[name]); err != nil {
+			return fmt.Errorf("schema %q: %v", name, err)
+		}
+	}
+
+	var paths []string
+	for p := range g.spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	type pending struct {
+		path string
+		item *apiPathItem
+		mo   methodOperation
+		op   *genOperation
+	}
+	var all []pending
+	// name the operations before declaring their types, so the names of
+	// the handlers do not depend on the types of other operations
+	for _, p := range paths {
+		item := g.spec.Paths[p]
+		if item == nil {
+			continue
+		}
+		if item.Ref != "" {
+			return fmt.Errorf("path %q: references to path items are not supported", p)
+		}
+		for _, mo := range item.operations() {
+			name := mo.op.OperationID
+			if name == "" {
+				name = strings.ToLower(mo.method) + " " + p
+			}
+			op := &genOperation{
+				name:   g.uniqueName(goName(name)),
+				method: mo.method,
+				path:   p,
+			}
+			op.stub = g.uniqueLower(lowerName(op.name))
+			all = append(all, pending{p, item, mo, op})
+		}
+	}
+	for _, x := range all {
+		g.currentPath = x.mo.method + " " + x.path
+		if err := g.operation(x.op, x.item, x.mo.op); err != nil {
+			return fmt.Errorf("%s: %v", g.currentPath, err)
+		}
+		g.ops = append(g.ops, x.op)
+	}
+	return nil
+}
+
+func isObject(s *apiSchema) bool {
+	return len(s.AllOf) > 1 || len(s.Properties) > 0 || len(s.AllOf) == 1 && s.AllOf[0].Ref == "" && isObject(s.AllOf[0])
+}
+
+func (g *generator) uniqueName(name string) string {
+	n := name
+	for i := 2; g.declared[n]; i++ {
+		n = name + strconv.Itoa(i)
+	}
+	g.declared[n] = true
+	return n
+}
+
+func (g *generator) uniqueLower(name string) string {
+	n := name
+	for i := 2; g.lowerNames[n]; i++ {
+		n = name + strconv.Itoa(i)
+	}
+	g.lowerNames[n] = true
+	return n
+}
+
+func (g *generator) warnf(format string, args ...interface{}) {
+	g.warnings = append(g.warnings, g.currentPath+": "+fmt.Sprintf(format, args...))
+}
+
+// declareNamed declares the type of a named schema.
+func (g *generator) declareNamed(name string, s *apiSchema) error {
+	if g.structs[name] && s.Ref == "" {
+		_, err := g.declareStruct(name, s)
+		return err
+	}
+	if s.Ref == "" && s.typ() == "string" && len(s.Enum) > 0 && s.Format == "" {
+		g.declareEnum(name, s)
+		return nil
+	}
+	typ, err := g.goType(s, name)
+	if err != nil {
+		return err
+	}
+	g.decls = append(g.decls, docComment(s.Description)+"type "+name+" "+typ+"\n")
+	return nil
+}
+
+func (g *generator) declareEnum(name string, s *apiSchema) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%stype %s string\n\nconst (\n", docComment(s.Description), name)
+	for _, v := range s.Enum {
+		if v, ok := v.(string); ok {
+			fmt.Fprintf(&b, "\t%s %s = %s\n", g.uniqueName(name+goName(v)), name, strconv.Quote(v))
+		}
+	}
+	b.WriteString(")\n")
+	g.decls = append(g.decls, b.String())
+}
+
+// declareStruct declares a struct type with the properties of s.
+func (g *generator) declareStruct(name string, s *apiSchema) (string, error) {
+	g.structs[name] = true
+	i := len(g.decls)
+	g.decls = append(g.decls, "") // keep the struct before the types of its fields
+	props := map[string]*apiSchema{}
+	required := map[string]bool{}
+	if err := g.properties(s, props, required, 0); err != nil {
+		return "", err
+	}
+	var keys []string
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%stype %s struct {\n", docComment(s.Description), name)
+	fields := map[string]bool{}
+	for _, k := range keys {
+		field := goName(k)
+		for j := 2; fields[field]; j++ {
+			field = goName(k) + strconv.Itoa(j)
+		}
+		fields[field] = true
+		p := props[k]
+		typ, err := g.goType(p, name+field)
+		if err != nil {
+			return "", fmt.Errorf("property %q: %v", k, err)
+		}
+		tag := k
+		if !required[k] {
+			tag += ",omitempty"
+			if g.structs[typ] {
+				typ = "*" + typ
+			}
+		}
+		if desc := firstLine(p.Description); desc != "" {
+			fmt.Fprintf(&b, "\t// %s\n", desc)
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%s`\n", field, typ, strconv.Quote(tag))
+	}
+	b.WriteString("}\n")
+	g.decls[i] = b.String()
+	return name, nil
+}
+
+// properties collects the properties of s and of the schemas it is
+// composed of.
+func (g *generator) properties(s *apiSchema, props map[string]*apiSchema, required map[string]bool, depth int) error {
+	s, err := g.spec.resolve(s)
+	if err != nil {
+		return err
+	}
+	if depth > 32 {
+		return fmt.Errorf("allOf is too deep")
+	}
+	for _, sub := range s.AllOf {
+		if err = g.properties(sub, props, required, depth+1); err != nil {
+			return err
+		}
+	}
+	for k, v := range s.Properties {
+		props[k] = v
+	}
+	for _, k := range s.Required {
+		required[k] = true
+	}
+	return nil
+}
+
+// goType returns the Go type of a schema, declaring the structs of inline
+// objects with names starting with hint.
+func (g *generator) goType(s *apiSchema, hint string) (string, error) {
+	if s == nil {
+		return "interface{}", nil
+	}
+	if s.Ref != "" {
+		name, err := refName(s.Ref, "components/schemas", "definitions")
+		if err != nil {
+			return "", err
+		}
+		if g.names[name] == "" {
+			return "", fmt.Errorf("schema %q not found", s.Ref)
+		}
+		return g.names[name], nil
+	}
+	if len(s.AllOf) == 1 && len(s.Properties) == 0 {
+		return g.goType(s.AllOf[0], hint)
+	}
+	if isObject(s) {
+		return g.declareStruct(g.uniqueName(hint), s)
+	}
+	switch s.typ() {
+	case "integer":
+		if s.Format == "int32" {
+			return "int32", nil
+		}
+		return "int64", nil
+	case "number":
+		if s.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time", nil
+		case "byte":
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "array":
+		elem, err := g.goType(s.Items, hint+"Item")
+		return "[]" + elem, err
+	case "object":
+		if len(s.AdditionalProperties) > 0 && s.AdditionalProperties[0] == '{' {
+			ap := new(apiSchema)
+			if err := json.Unmarshal(s.AdditionalProperties, ap); err != nil {
+				return "", err
+			}
+			elem, err := g.goType(ap, hint+"Value")
+			return "map[string]" + elem, err
+		}
+		return "map[string]interface{}", nil
+	}
+	return "interface{}", nil
+}
+
+// scalarType returns the Go type of a parameter value and the builtin type
+// it is parsed as, "" if s is not a scalar.
+func (g *generator) scalarType(s *apiSchema) (typ, base string, err error) {
+	if s == nil {
+		return "", "", nil
+	}
+	var named string
+	if s.Ref != "" {
+		if named, err = g.goType(s, ""); err != nil {
+			return "", "", err
+		}
+		if s, err = g.spec.resolve(s); err != nil {
+			return "", "", err
+		}
+	}
+	switch t := s.typ(); {
+	case t == "integer" || t == "number" || t == "boolean" || t == "string" && s.Format != "byte":
+		base, _ = g.goType(s, "")
+	default:
+		return "", "", nil
+	}
+	if named != "" {
+		return named, base, nil
+	}
+	return base, base, nil
+}
+
+var routeParams = regexp.MustCompile(`^(/\{[^{}/]+\})+$`)
+
+// route returns the prefix of the route of a path and its path parameters,
+// ok is false if the parameters are not the trailing segments, which
+// virtual routes cannot express.
+func route(path string) (leaf string, names []string, ok bool) {
+	leaf = path
+	if i := strings.IndexByte(path, '{'); i >= 0 {
+		leaf = path[:i]
+		if !strings.HasSuffix(leaf, "/") || !routeParams.MatchString(path[i-1:]) {
+			return "", nil, false
+		}
+		for _, s := range strings.Split(path[i:], "/") {
+			names = append(names, s[1:len(s)-1])
+		}
+	}
+	if strings.ContainsAny(leaf, ":*") {
+		return "", nil, false
+	}
+	if leaf = strings.TrimSuffix(leaf, "/"); leaf == "" {
+		leaf = "/"
+	}
+	return leaf, names, true
+}
+
+func (g *generator) operation(op *genOperation, item *apiPathItem, o *apiOperation) error {
+	op.desc = o.Summary
+	if op.desc == "" {
+		op.desc = firstLine(o.Description)
+	}
+	if op.desc == "" {
+		op.desc = op.method + " " + op.path
+	}
+	op.doc = o.Description
+	if o.Deprecated {
+		op.doc = strings.TrimSpace(op.doc + "\n\nDeprecated: the operation is deprecated in " + g.source + ".")
+	}
+
+	leaf, pathNames, routable := route(op.path)
+	if routable {
+		op.leaf = leaf
+	} else {
+		g.warnf("path parameters must be the last segments of the path, the operation is not routed")
+	}
+
+	// operation parameters override the parameters of the path
+	var params []*apiParam
+	seen := map[string]int{}
+	for _, list := range [][]*apiParam{item.Parameters, o.Parameters} {
+		for _, p := range list {
+			p, err := g.spec.param(p)
+			if err != nil {
+				return err
+			}
+			if i, ok := seen[p.In+" "+p.Name]; ok {
+				params[i] = p
+				continue
+			}
+			seen[p.In+" "+p.Name] = len(params)
+			params = append(params, p)
+		}
+	}
+	// path parameters first, in the order of the path
+	for _, name := range pathNames {
+		if _, ok := seen["path "+name]; !ok {
+			g.warnf("path parameter %q is not declared, it is read as a string", name)
+			params = append(params, &apiParam{Name: name, In: "path", Type: apiTypes{"string"}})
+			seen["path "+name] = len(params) - 1
+		}
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return pathIndex(params[i], pathNames) < pathIndex(params[j], pathNames)
+	})
+
+	fields := map[string]bool{}
+	consumes := o.Consumes
+	if consumes == nil {
+		consumes = g.spec.Consumes
+	}
+	for _, p := range params {
+		switch p.In {
+		case "body":
+			typ, err := g.goType(p.Schema, op.name+"Body")
+			if err != nil {
+				return fmt.Errorf("body: %v", err)
+			}
+			op.body, op.bodyRequired = typ, p.Required
+			if len(consumes) > 0 && !bindable(consumes[0]) {
+				op.body, op.bodyRaw = "", consumes[0]
+			}
+			continue
+		case "path", "query", "header", "cookie", "formData":
+		default:
+			return fmt.Errorf("parameter %q: unknown location %q", p.Name, p.In)
+		}
+		gp, err := g.param(p)
+		if err != nil {
+			return fmt.Errorf("parameter %q: %v", p.Name, err)
+		}
+		if !gp.file {
+			gp.field = goName(p.Name)
+			for i := 2; fields[gp.field]; i++ {
+				gp.field = goName(p.Name) + strconv.Itoa(i)
+			}
+			fields[gp.field] = true
+		}
+		op.params = append(op.params, gp)
+	}
+	if len(fields) > 0 {
+		op.paramsType = g.uniqueName(op.name + "Params")
+	}
+
+	if rb, err := g.spec.requestBody(o.RequestBody); err != nil {
+		return err
+	} else if rb != nil {
+		mt, schema := jsonMedia(rb.Content)
+		op.bodyRequired = rb.Required
+		if bindable(mt) {
+			typ, err := g.goType(schema, op.name+"Body")
+			if err != nil {
+				return fmt.Errorf("request body: %v", err)
+			}
+			op.body = typ
+		} else {
+			op.bodyRaw = mt
+		}
+	}
+
+	return g.result(op, o)
+}
+
+func pathIndex(p *apiParam, names []string) int {
+	if p.In == "path" {
+		for i, name := range names {
+			if name == p.Name {
+				return i
+			}
+		}
+	}
+	return len(names)
+}
+
+// bindable reports whether c.Bind decodes bodies of the media type.
+func bindable(mediaType string) bool {
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-www-form-urlencoded", "multipart/form-data", "*/*":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json")
+}
+
+func (g *generator) param(p *apiParam) (*genParam, error) {
+	gp := &genParam{name: p.Name, in: p.In, desc: firstLine(p.Description), required: p.Required || p.In == "path"}
+	s := p.schema()
+	if p.In == "formData" && s.typ() == "file" {
+		gp.file = true
+		return gp, nil
+	}
+	typ, base, err := g.scalarType(s)
+	if err != nil {
+		return nil, err
+	}
+	if typ == "" {
+		items := s
+		if items, err = g.spec.resolve(s); err != nil {
+			return nil, err
+		}
+		if items.typ() == "array" {
+			if typ, base, err = g.scalarType(items.Items); err != nil {
+				return nil, err
+			}
+			gp.array = typ != ""
+		}
+	}
+	if typ == "" {
+		g.warnf("parameter %q is not a scalar or an array of scalars, it is read as a string", p.Name)
+		typ, base = "string", "string"
+	}
+	gp.typ, gp.base = typ, base
+	if !gp.array && !gp.required {
+		gp.def = literal(base, s.Default)
+	}
+	return gp, nil
+}
+
+// literal returns v as a Go constant of the builtin type, "" if it is not
+// one.
+func literal(base string, v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if base == "string" {
+			return strconv.Quote(v)
+		}
+	case bool:
+		if base == "bool" {
+			return strconv.FormatBool(v)
+		}
+	case float64:
+		switch base {
+		case "int32", "int64":
+			if v == math.Trunc(v) {
+				return strconv.FormatInt(int64(v), 10)
+			}
+		case "float32", "float64":
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	}
+	return ""
+}
+
+// result picks the response of the operation: the first success response,
+// or the default one.
+func (g *generator) result(op *genOperation, o *apiOperation) error {
+	var codes []string
+	for code := range o.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	op.code = 200
+	var res *apiResponse
+	for _, code := range codes {
+		if n, err := strconv.Atoi(code); err == nil && n >= 200 && n < 300 {
+			op.code, res = n, o.Responses[code]
+			break
+		}
+	}
+	if res == nil {
+		res = o.Responses["default"]
+	}
+	if res == nil {
+		return nil
+	}
+	res, err := g.spec.response(res)
+	if err != nil {
+		return err
+	}
+	mt, schema := "application/json", res.Schema
+	if res.Content != nil {
+		mt, schema = jsonMedia(res.Content)
+	}
+	switch {
+	case schema == nil || op.code == 204:
+	case mt == "application/json" || strings.HasSuffix(mt, "+json") || mt == "*/*":
+		if op.resType, err = g.goType(schema, op.name+"Response"); err != nil {
+			return fmt.Errorf("response: %v", err)
+		}
+		if g.structs[op.resType] {
+			op.resType = "*" + op.resType
+		}
+	default:
+		op.resRaw = mt
+	}
+	return nil
+}
+
+// genSource returns the unformatted source of genFile.
+func (g *generator) genSource() []byte {
+	var body bytes.Buffer
+	for _, d := range g.decls {
+		body.WriteString(d)
+		body.WriteByte('\n')
+	}
+	for _, op := range g.ops {
+		g.paramsDecl(&body, op)
+	}
+	for _, op := range g.ops {
+		g.handlerDecl(&body, op)
+	}
+	g.routesDecl(&body)
+	if g.needCookie {
+		body.WriteString(`
+// cookieParam returns the value of the named cookie, "" if it is missing.
+func cookieParam(c *lessgo.Context, name string) string {
+	if cookie := c.CookieParam(name); cookie != nil {
+		return cookie.Value
+	}
+	return ""
+}
+`)
+	}
+	if g.needSplit {
+		g.imports["strings"] = true
+		body.WriteString(`
+// splitParam splits a comma separated array parameter.
+func splitParam(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+`)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by lessgo gen server from %s; DO NOT EDIT.\n\n", g.source)
+	if g.spec.Info.Title != "" {
+		fmt.Fprintf(&b, "// Package %s implements %s.\n", g.pkg, firstLine(g.spec.Info.Title))
+	}
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+	b.WriteString(importDecl(g.imports))
+	b.Write(body.Bytes())
+	return b.Bytes()
+}
+
+func importDecl(imports map[string]bool) string {
+	var std, other []string
+	for path := range imports {
+		if strings.Contains(path, ".") {
+			other = append(other, strconv.Quote(path))
+		} else {
+			std = append(std, strconv.Quote(path))
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	groups := []string{}
+	for _, list := range [][]string{std, other} {
+		if len(list) > 0 {
+			groups = append(groups, "\t"+strings.Join(list, "\n\t")+"\n")
+		}
+	}
+	return "import (\n" + strings.Join(groups, "\n") + ")\n\n"
+}
+
+func (g *generator) paramsDecl(b *bytes.Buffer, op *genOperation) {
+	if op.paramsType == "" {
+		return
+	}
+	fmt.Fprintf(b, "// %s holds the parameters of %s.\ntype %s struct {\n", op.paramsType, op.name, op.paramsType)
+	for _, p := range op.params {
+		if p.file {
+			continue
+		}
+		typ := p.typ
+		if p.array {
+			typ = "[]" + typ
+		}
+		fmt.Fprintf(b, "\t%s %s // %s parameter %s", p.field, typ, p.in, p.name)
+		if p.desc != "" {
+			fmt.Fprintf(b, ": %s", p.desc)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n\n")
+}
+
+// model returns the Param.Model of a parameter.
+func (p *genParam) model() string {
+	switch {
+	case p.file:
+		return "nil"
+	case p.array:
+		return "[]" + p.typ + "{}"
+	case p.def != "":
+		return p.typ + "(" + p.def + ")"
+	case p.base == "string":
+		if p.typ == "string" {
+			return `""`
+		}
+		return p.typ + `("")`
+	case p.base == "bool":
+		return p.typ + "(false)"
+	case p.base == "time.Time":
+		return "time.Time{}"
+	}
+	return p.typ + "(0)"
+}
+
+func (g *generator) handlerDecl(b *bytes.Buffer, op *genOperation) {
+	fmt.Fprintf(b, "// %s is the ApiHandler of %s %s, implemented by %s.\n", op.name, op.method, op.path, op.stub)
+	if op.doc != "" {
+		b.WriteString("//\n")
+		b.WriteString(docComment(op.doc))
+	}
+	fmt.Fprintf(b, "var %s = lessgo.ApiHandler{\n\tDesc: %s,\n\tMethod: %q,\n", op.name, strconv.Quote(op.desc), op.method)
+	if len(op.params) > 0 {
+		b.WriteString("\tParams: []lessgo.Param{\n")
+		for _, p := range op.params {
+			fmt.Fprintf(b, "\t\t{Name: %q, In: %q, Required: %v, Model: %s", p.name, p.in, p.required, p.model())
+			if p.desc != "" {
+				fmt.Fprintf(b, ", Desc: %s", strconv.Quote(p.desc))
+			}
+			b.WriteString("},\n")
+		}
+		b.WriteString("\t},\n")
+	}
+	b.WriteString("\tHandler: func(c *lessgo.Context) error {\n")
+	args := []string{"c"}
+	if op.paramsType != "" {
+		var defs []string
+		for _, p := range op.params {
+			if p.def != "" && !p.file {
+				defs = append(defs, p.field+": "+p.def)
+			}
+		}
+		fmt.Fprintf(b, "params := &%s{%s}\n", op.paramsType, strings.Join(defs, ", "))
+		for _, p := range op.params {
+			if !p.file {
+				g.parseParam(b, p)
+			}
+		}
+		args = append(args, "params")
+	}
+	if op.body != "" {
+		ptr := g.structs[op.body]
+		switch {
+		case ptr && op.bodyRequired:
+			fmt.Fprintf(b, "body := new(%s)\nif err := c.Bind(body); err != nil {\nreturn err\n}\n", op.body)
+		case ptr:
+			fmt.Fprintf(b, "var body *%s\nif c.Request().ContentLength != 0 {\nbody = new(%s)\nif err := c.Bind(body); err != nil {\nreturn err\n}\n}\n", op.body, op.body)
+		case op.bodyRequired:
+			fmt.Fprintf(b, "var body %s\nif err := c.Bind(&body); err != nil {\nreturn err\n}\n", op.body)
+		default:
+			fmt.Fprintf(b, "var body %s\nif c.Request().ContentLength != 0 {\nif err := c.Bind(&body); err != nil {\nreturn err\n}\n}\n", op.body)
+		}
+		args = append(args, "body")
+	}
+	call := op.stub + "(" + strings.Join(args, ", ") + ")"
+	switch {
+	case op.resType != "":
+		fmt.Fprintf(b, "res, err := %s\nif err != nil {\nreturn err\n}\nreturn c.JSON(%s, res)\n", call, statusConst(op.code))
+	case op.resRaw != "":
+		fmt.Fprintf(b, "return %s\n", call)
+	default:
+		fmt.Fprintf(b, "if err := %s; err != nil {\nreturn err\n}\nreturn c.NoContent(%s)\n", call, statusConst(op.code))
+	}
+	b.WriteString("\t},\n}.Reg()\n\n")
+}
+
+// parseParam writes the code reading a parameter into params.
+func (g *generator) parseParam(b *bytes.Buffer, p *genParam) {
+	var single, multi string
+	switch p.in {
+	case "path":
+		single = fmt.Sprintf("c.PathParam(%q)", p.name)
+	case "query":
+		single, multi = fmt.Sprintf("c.QueryParam(%q)", p.name), fmt.Sprintf("c.QueryParams(%q)", p.name)
+	case "formData":
+		single, multi = fmt.Sprintf("c.FormParam(%q)", p.name), fmt.Sprintf("c.FormParams(%q)", p.name)
+	case "header":
+		single = fmt.Sprintf("c.HeaderParam(%q)", p.name)
+	case "cookie":
+		g.needCookie = true
+		single = fmt.Sprintf("cookieParam(c, %q)", p.name)
+	}
+	missing := fmt.Sprintf("return lessgo.NewHTTPError(http.StatusBadRequest, %s)", strconv.Quote("missing "+p.in+" parameter "+p.name))
+	target := "params." + p.field
+	if !p.array {
+		fmt.Fprintf(b, "if s := %s; s != \"\" {\n", single)
+		g.convert(b, p, func(v string) string { return target + " = " + v })
+		if p.required && p.in != "path" {
+			fmt.Fprintf(b, "} else {\n%s\n", missing)
+		}
+		b.WriteString("}\n")
+		return
+	}
+	if multi == "" {
+		g.needSplit = true
+		multi = "splitParam(" + single + ")"
+	}
+	if p.typ == "string" {
+		fmt.Fprintf(b, "%s = %s\n", target, multi)
+	} else {
+		fmt.Fprintf(b, "for _, s := range %s {\n", multi)
+		g.convert(b, p, func(v string) string { return target + " = append(" + target + ", " + v + ")" })
+		b.WriteString("}\n")
+	}
+	if p.required {
+		fmt.Fprintf(b, "if len(%s) == 0 {\n%s\n}\n", target, missing)
+	}
+}
+
+// convert writes the code parsing the string s as the type of p.
+func (g *generator) convert(b *bytes.Buffer, p *genParam, assign func(v string) string) {
+	var parse string
+	switch p.base {
+	case "string":
+		v := "s"
+		if p.typ != "string" {
+			v = p.typ + "(s)"
+		}
+		fmt.Fprintln(b, assign(v))
+		return
+	case "int32":
+		parse = "strconv.ParseInt(s, 10, 32)"
+	case "int64":
+		parse = "strconv.ParseInt(s, 10, 64)"
+	case "float32":
+		parse = "strconv.ParseFloat(s, 32)"
+	case "float64":
+		parse = "strconv.ParseFloat(s, 64)"
+	case "bool":
+		parse = "strconv.ParseBool(s)"
+	case "time.Time":
+		parse = "time.Parse(time.RFC3339, s)"
+	}
+	if strings.HasPrefix(parse, "strconv.") {
+		g.imports["strconv"] = true
+	}
+	fmt.Fprintf(b, "v, err := %s\nif err != nil {\nreturn lessgo.NewHTTPError(http.StatusBadRequest, %s+err.Error())\n}\n",
+		parse, strconv.Quote("invalid "+p.in+" parameter "+p.name+": "))
+	v := "v"
+	if p.typ != "int64" && p.typ != "float64" && p.typ != "bool" && p.typ != "time.Time" {
+		v = p.typ + "(v)"
+	}
+	fmt.Fprintln(b, assign(v))
+}
+
+func (g *generator) routesDecl(b *bytes.Buffer) {
+	prefix := g.prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	title := g.spec.Info.Title
+	if title == "" {
+		title = g.pkg
+	}
+	fmt.Fprintf(b, `// Routes returns the routes of the operations, mount them in an init
+// function with lessgo.Root(%s.Routes()). The middlewares apply to every
+// operation.
+func Routes(middlewares ...*lessgo.ApiMiddleware) *lessgo.VirtRouter {
+	return lessgo.Branch(%q, %s,
+`, g.pkg, prefix, strconv.Quote(firstLine(title)))
+	for _, op := range g.ops {
+		if op.leaf == "" {
+			fmt.Fprintf(b, "// %s %s: path parameters must be the last segments of the path\n", op.method, op.path)
+			continue
+		}
+		fmt.Fprintf(b, "lessgo.Leaf(%q, %s, middlewares...),\n", op.leaf, op.name)
+	}
+	b.WriteString(")\n}\n")
+}
+
+func statusConst(code int) string {
+	if name, ok := statusNames[code]; ok {
+		return "http.Status" + name
+	}
+	return strconv.Itoa(code)
+}
+
+var statusNames = map[int]string{
+	200: "OK", 201: "Created", 202: "Accepted", 203: "NonAuthoritativeInfo", 204: "NoContent",
+	205: "ResetContent", 206: "PartialContent", 207: "MultiStatus", 208: "AlreadyReported", 226: "IMUsed",
+}
+
+// stubSource returns src with the stubs of the operations it does not
+// declare yet, and the number of added stubs.
+func (g *generator) stubSource(src []byte) ([]byte, int, error) {
+	declared := map[string]bool{}
+	var b bytes.Buffer
+	imports := map[string]bool{}
+	if len(src) == 0 {
+		fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+	} else {
+		f, err := parser.ParseFile(token.NewFileSet(), stubFile, src, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, d := range f.Decls {
+			if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil {
+				declared[fn.Name.Name] = true
+			}
+		}
+		for _, imp := range f.Imports {
+			if imp.Name == nil {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				imports[path] = true
+			}
+		}
+		b.Write(bytes.TrimRight(src, "\n"))
+		b.WriteString("\n")
+	}
+	need := map[string]bool{}
+	var stubs bytes.Buffer
+	n := 0
+	for _, op := range g.ops {
+		if declared[op.stub] {
+			continue
+		}
+		n++
+		g.stubDecl(&stubs, op, need)
+	}
+	if n == 0 {
+		return src, 0, nil
+	}
+	missing := map[string]bool{}
+	for path := range need {
+		if !imports[path] {
+			missing[path] = true
+		}
+	}
+	if len(missing) > 0 {
+		b.WriteString("\n")
+		b.WriteString(importDecl(missing))
+	}
+	b.Write(stubs.Bytes())
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, 0, fmt.Errorf("formatting %s: %v", stubFile, err)
+	}
+	return out, n, nil
+}
+
+func (g *generator) stubDecl(b *bytes.Buffer, op *genOperation, imports map[string]bool) {
+	imports["net/http"] = true
+	imports[lessgoImport] = true
+	fmt.Fprintf(b, "\n// %s implements %s %s", op.stub, op.method, op.path)
+	if desc := op.desc; desc != op.method+" "+op.path {
+		if !strings.HasSuffix(desc, ".") {
+			desc += "."
+		}
+		fmt.Fprintf(b, ": %s", desc)
+	} else {
+		b.WriteString(".")
+	}
+	b.WriteString("\n")
+	args := []string{"c *lessgo.Context"}
+	if op.paramsType != "" {
+		args = append(args, "params *"+op.paramsType)
+	}
+	for _, p := range op.params {
+		if p.file {
+			fmt.Fprintf(b, "// Read the uploaded file %q with c.FormFile.\n", p.name)
+		}
+	}
+	if op.body != "" {
+		typ := op.body
+		if g.structs[typ] {
+			typ = "*" + typ
+		}
+		args = append(args, "body "+typ)
+		if !op.bodyRequired && g.structs[op.body] {
+			b.WriteString("// The body is optional, it is nil when the request has none.\n")
+		} else if !op.bodyRequired {
+			b.WriteString("// The body is optional, it is the zero value when the request has none.\n")
+		}
+	} else if op.bodyRaw != "" {
+		fmt.Fprintf(b, "// Read the %s body from c.Request().Body.\n", op.bodyRaw)
+	}
+	if strings.Contains(op.resType, "time.Time") {
+		imports["time"] = true
+	}
+	switch {
+	case op.resType != "":
+		fmt.Fprintf(b, "// The result is written as JSON with status %d.\n", op.code)
+		fmt.Fprintf(b, "func %s(%s) (res %s, err error) {\n\treturn res, lessgo.NewHTTPError(http.StatusNotImplemented)\n}\n",
+			op.stub, strings.Join(args, ", "), op.resType)
+	case op.resRaw != "":
+		fmt.Fprintf(b, "// Write the %s response with status %d to c.Response().\n", op.resRaw, op.code)
+		fmt.Fprintf(b, "func %s(%s) error {\n\treturn lessgo.NewHTTPError(http.StatusNotImplemented)\n}\n", op.stub, strings.Join(args, ", "))
+	default:
+		fmt.Fprintf(b, "// The response has status %d and no body.\n", op.code)
+		fmt.Fprintf(b, "func %s(%s) error {\n\treturn lessgo.NewHTTPError(http.StatusNotImplemented)\n}\n", op.stub, strings.Join(args, ", "))
+	}
+}
+
+// Initialisms written in upper case in Go names.
+var initialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "HTML": true,
+	"HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "QPS": true, "SQL": true,
+	"SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true, "URI": true,
+	"URL": true, "UUID": true, "XML": true,
+}
+
+// goName converts a name of the spec to an exported Go identifier, like
+// "PetID" for "pet_id" or "petId".
+func goName(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) > 0:
+			prev := runes[i-1]
+			// split "petId" before "I" and "HTTPServer" before "S"
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	var b strings.Builder
+	for _, w := range words {
+		if up := strings.ToUpper(w); initialisms[up] {
+			b.WriteString(up)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// lowerName returns an unexported form of an exported Go identifier, like
+// "getPet" for "GetPet" and "urlList" for "URLList".
+func lowerName(s string) string {
+	r := []rune(s)
+	i := 0
+	for i < len(r) && unicode.IsUpper(r[i]) {
+		i++
+	}
+	if i > 1 && i < len(r) && unicode.IsLower(r[i]) {
+		i-- // keep the first letter of the next word
+	}
+	if i == 0 {
+		i = 1
+	}
+	name := strings.ToLower(string(r[:i])) + string(r[i:])
+	if token.IsKeyword(name) {
+		name += "Op"
+	}
+	return name
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+func docComment(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimRight(line, " \t"); line == "" {
+			b.WriteString("//\n")
+		} else {
+			b.WriteString("// " + line + "\n")
+		}
+	}
+	return b.String()
+}
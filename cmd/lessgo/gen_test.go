@@ -0,0 +1,187 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"pet_id":         "PetID",
+		"petId":          "PetID",
+		"HTTPServer":     "HTTPServer",
+		"get /pets/{id}": "GetPetsID",
+		"list-urls":      "ListUrls",
+		"2fa":            "X2fa",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for in, want := range map[string]string{
+		"GetPet":  "getPet",
+		"URLList": "urlList",
+		"ID":      "id",
+		"Func":    "funcOp",
+	} {
+		if got := lowerName(in); got != want {
+			t.Errorf("lowerName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	for _, c := range []struct {
+		path  string
+		leaf  string
+		names []string
+		ok    bool
+	}{
+		{"/users", "/users", nil, true},
+		{"/users/{id}", "/users", []string{"id"}, true},
+		{"/users/{id}/{tab}", "/users", []string{"id", "tab"}, true},
+		{"/{id}", "/", []string{"id"}, true},
+		{"/users/{id}/posts", "", nil, false},
+		{"/users/u{id}", "", nil, false},
+		{"/users:search", "", nil, false},
+	} {
+		leaf, names, ok := route(c.path)
+		if leaf != c.leaf || !reflect.DeepEqual(names, c.names) || ok != c.ok {
+			t.Errorf("route(%q) = %q, %q, %v", c.path, leaf, names, ok)
+		}
+	}
+}
+
+const swaggerSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "Users"},
+  "basePath": "/api",
+  "paths": {
+    "/users/{id}": {
+      "get": {
+        "operationId": "getUser",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "integer"},
+          {"name": "fields", "in": "header", "type": "array", "items": {"type": "string"}}
+        ],
+        "responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/User"}}}
+      },
+      "put": {
+        "operationId": "updateUser",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "integer"},
+          {"name": "user", "in": "body", "required": true, "schema": {"$ref": "#/definitions/User"}}
+        ],
+        "responses": {"204": {"description": "updated"}}
+      }
+    },
+    "/users/{id}/avatar": {
+      "post": {
+        "operationId": "uploadAvatar",
+        "consumes": ["multipart/form-data"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "type": "integer"},
+          {"name": "file", "in": "formData", "type": "file"}
+        ],
+        "responses": {"201": {"description": "created"}}
+      }
+    }
+  },
+  "definitions": {
+    "User": {
+      "type": "object",
+      "required": ["id"],
+      "properties": {
+        "id": {"type": "integer", "format": "int64"},
+        "created_at": {"type": "string", "format": "date-time"},
+        "roles": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+func TestGenServer(t *testing.T) {
+	dir := t.TempDir()
+	specFile := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(specFile, []byte(swaggerSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gen := func() *generator {
+		spec, err := loadSpec(specFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := newGenerator(spec, "users.json", "api")
+		if _, err = g.write(filepath.Join(dir, "api")); err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	g := gen()
+	if len(g.warnings) != 1 || !strings.Contains(g.warnings[0], "/users/{id}/avatar") {
+		t.Errorf("warnings: %q", g.warnings)
+	}
+
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, "api", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = parser.ParseFile(token.NewFileSet(), name, b, 0); err != nil {
+			t.Fatalf("%s: %v\n%s", name, err, b)
+		}
+		return string(b)
+	}
+	src := read(genFile)
+	for _, want := range []string{
+		"// Code generated by lessgo gen server from users.json; DO NOT EDIT.",
+		"CreatedAt time.Time `json:\"created_at,omitempty\"`",
+		"ID        int64     `json:\"id\"`",
+		`{Name: "id", In: "path", Required: true, Model: int64(0)},`,
+		`{Name: "file", In: "formData", Required: false, Model: nil},`,
+		`params.Fields = splitParam(c.HeaderParam("fields"))`,
+		"body := new(User)",
+		"return c.NoContent(http.StatusNoContent)",
+		"return c.JSON(http.StatusOK, res)",
+		`lessgo.Branch("/api", "Users",`,
+		`lessgo.Leaf("/users", GetUser, middlewares...),`,
+		"// POST /users/{id}/avatar: path parameters must be the last segments of the path",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("%s does not contain %q", genFile, want)
+		}
+	}
+	stubs := read(stubFile)
+	for _, want := range []string{
+		"func getUser(c *lessgo.Context, params *GetUserParams) (res *User, err error) {",
+		"func updateUser(c *lessgo.Context, params *UpdateUserParams, body *User) error {",
+		`// Read the uploaded file "file" with c.FormFile.`,
+	} {
+		if !strings.Contains(stubs, want) {
+			t.Errorf("%s does not contain %q:\n%s", stubFile, want, stubs)
+		}
+	}
+
+	// implemented stubs are kept, stubs of new operations are appended
+	implemented := strings.Replace(stubs, "return res, lessgo.NewHTTPError(http.StatusNotImplemented)", "return &User{}, nil", 1)
+	if err := os.WriteFile(filepath.Join(dir, "api", stubFile), []byte(implemented), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec := strings.Replace(swaggerSpec, `"/users/{id}/avatar"`, `"/users": {"get": {"operationId": "listUsers", "responses": {}}}, "/users/{id}/avatar"`, 1)
+	if err := os.WriteFile(specFile, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gen()
+	stubs = read(stubFile)
+	if !strings.HasPrefix(stubs, strings.TrimRight(implemented, "\n")) {
+		t.Errorf("existing stubs changed:\n%s", stubs)
+	}
+	if strings.Count(stubs, "func ") != 4 || !strings.Contains(stubs, "func listUsers(c *lessgo.Context) error {") {
+		t.Errorf("stub of the new operation not appended:\n%s", stubs)
+	}
+}
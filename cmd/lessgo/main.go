@@ -0,0 +1,77 @@
+// Command lessgo is the development tool of lessgo projects.
+//
+//	lessgo new [-module path] [-force] <dir>    create a project skeleton
+//	lessgo run [-addr address] [-- app args]    run the app, rebuilding it when source files change
+//	lessgo gen server -spec api.yaml            generate handlers from an OpenAPI or Swagger spec
+//	lessgo routes [-o file] [-diff file]        print the route table, or diff it against an export
+//
+// Run "lessgo help <command>" for the flags of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string // arguments after the command name
+	short string
+	run   func(args []string) error
+}
+
+var commands []*command
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name, args := os.Args[1], os.Args[2:]
+	if name == "help" || name == "-h" || name == "--help" {
+		if len(args) > 0 && lookup(args[0]) != nil {
+			name, args = args[0], []string{"-h"}
+		} else {
+			usage()
+			return
+		}
+	}
+	cmd := lookup(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "lessgo: unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "lessgo %s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// flagSet creates the flag set of a command, printing its usage on -h.
+func (cmd *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: lessgo %s %s\n\n%s\n\n", cmd.name, cmd.usage, cmd.short)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: lessgo <command> [arguments]\n\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun \"lessgo help <command>\" for the flags of a command.")
+}
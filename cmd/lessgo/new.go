@@ -0,0 +1,155 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// skeleton holds the files of a new project. Files ending in ".tmpl" are
+// executed as text/template with a project value and written without the
+// suffix; other files are copied as is.
+//
+//go:embed skeleton
+var skeleton embed.FS
+
+// Files whose names embed does not accept.
+var skeletonRenames = map[string]string{
+	"gitignore": ".gitignore",
+}
+
+type project struct {
+	Name      string // directory name, also the binary name
+	Module    string // module path
+	GoVersion string // like "1.21"
+}
+
+var cmdNew = &command{
+	name:  "new",
+	usage: "[-module path] [-force] <dir>",
+	short: "create a project skeleton with config, router, example handlers and middleware, and a Dockerfile",
+}
+
+func init() {
+	cmdNew.run = runNew
+	commands = append(commands, cmdNew)
+}
+
+func runNew(args []string) error {
+	fs := cmdNew.flagSet()
+	module := fs.String("module", "", "module path of the project, the directory name by default")
+	force := fs.Bool("force", false, "write into a non-empty directory, overwriting existing files")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	dir := fs.Arg(0)
+	p := project{
+		Name:      filepath.Base(filepath.Clean(dir)),
+		Module:    *module,
+		GoVersion: goVersion(),
+	}
+	if p.Module == "" {
+		p.Module = p.Name
+	}
+	files, err := newProject(dir, p, *force)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Println("create", filepath.Join(dir, f))
+	}
+	fmt.Printf("\ncd %s && go mod tidy && go run .\n", dir)
+	return nil
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// newProject writes the skeleton into dir and returns the created files,
+// relative to dir. Unless force is set, dir must not exist or be empty.
+func newProject(dir string, p project, force bool) ([]string, error) {
+	if !validName.MatchString(p.Name) {
+		return nil, fmt.Errorf("invalid project name %q", p.Name)
+	}
+	if p.Module == "" || strings.ContainsAny(p.Module, " \t\"'`\\") {
+		return nil, fmt.Errorf("invalid module path %q", p.Module)
+	}
+	if !force {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			return nil, fmt.Errorf("%s is not empty, use -force to write into it", dir)
+		}
+	}
+	var created []string
+	err := fs.WalkDir(skeleton, "skeleton", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := skeleton.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(name, "skeleton/")
+		if strings.HasSuffix(rel, ".tmpl") {
+			rel = strings.TrimSuffix(rel, ".tmpl")
+			if b, err = execTemplate(rel, b, p); err != nil {
+				return err
+			}
+		}
+		if to, ok := skeletonRenames[path.Base(rel)]; ok {
+			rel = path.Join(path.Dir(rel), to)
+		}
+		out := filepath.Join(dir, filepath.FromSlash(rel))
+		if err = os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(out, b, 0644); err != nil {
+			return err
+		}
+		created = append(created, filepath.FromSlash(rel))
+		return nil
+	})
+	return created, err
+}
+
+func execTemplate(name string, text []byte, p project) ([]byte, error) {
+	t, err := template.New(name).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	var buf strings.Builder
+	if err = t.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	b := []byte(buf.String())
+	if strings.HasSuffix(name, ".go") {
+		// keep the output gofmt-clean whatever the module path
+		if b, err = format.Source(b); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return b, nil
+}
+
+// goVersion returns the major and minor version of the running toolchain,
+// used for the go directive and the build image.
+func goVersion() string {
+	v := strings.TrimPrefix(runtime.Version(), "go")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 || strings.ContainsAny(parts[1], " -+") {
+		return "1.21"
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewProject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+	p := project{Name: "shop", Module: "example.com/acme/shop", GoVersion: "1.21"}
+	files, err := newProject(dir, p, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"main.go", "go.mod", "Dockerfile", ".gitignore", "config/app.yaml",
+		"router/bizrouter.go", "bizhandler/home/index.go", "bizview/home/index.tpl", "middleware/middleware.go"} {
+		found := false
+		for _, f := range files {
+			found = found || filepath.ToSlash(f) == want
+		}
+		if !found {
+			t.Errorf("%s not created, got %v", want, files)
+		}
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(f, ".go") {
+			if _, err = parser.ParseFile(token.NewFileSet(), f, b, parser.ImportsOnly); err != nil {
+				t.Errorf("%s: %v", f, err)
+			}
+		}
+		if strings.Contains(string(b), "<no value>") {
+			t.Errorf("%s: unresolved template field", f)
+		}
+	}
+	for f, want := range map[string]string{
+		"go.mod":              "module example.com/acme/shop\n",
+		"router/bizrouter.go": `"example.com/acme/shop/bizhandler/home"`,
+		"Dockerfile":          "go build -o /out/shop",
+		"config/app.yaml":     "appname: shop",
+	} {
+		b, _ := os.ReadFile(filepath.Join(dir, f))
+		if !strings.Contains(string(b), want) {
+			t.Errorf("%s does not contain %q:\n%s", f, want, b)
+		}
+	}
+
+	if _, err = newProject(dir, p, false); err == nil {
+		t.Error("non-empty directory accepted without force")
+	}
+	if _, err = newProject(dir, p, true); err != nil {
+		t.Errorf("force: %v", err)
+	}
+	if _, err = newProject(t.TempDir(), project{Name: "shop", Module: "a b"}, false); err == nil {
+		t.Error("invalid module path accepted")
+	}
+}
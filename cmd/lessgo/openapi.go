@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// apiSpec is the part of an OpenAPI 3.x or Swagger 2.0 document used by gen
+// server.
+type apiSpec struct {
+	Swagger string `json:"swagger"`
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Paths map[string]*apiPathItem `json:"paths"`
+
+	// OpenAPI 3.x
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Components struct {
+		Schemas       map[string]*apiSchema      `json:"schemas"`
+		Parameters    map[string]*apiParam       `json:"parameters"`
+		RequestBodies map[string]*apiRequestBody `json:"requestBodies"`
+		Responses     map[string]*apiResponse    `json:"responses"`
+	} `json:"components"`
+
+	// Swagger 2.0
+	BasePath    string                  `json:"basePath"`
+	Consumes    []string                `json:"consumes"`
+	Definitions map[string]*apiSchema   `json:"definitions"`
+	Parameters  map[string]*apiParam    `json:"parameters"`
+	Responses   map[string]*apiResponse `json:"responses"`
+}
+
+type apiPathItem struct {
+	Ref        string        `json:"$ref"`
+	Parameters []*apiParam   `json:"parameters"`
+	Get        *apiOperation `json:"get"`
+	Put        *apiOperation `json:"put"`
+	Post       *apiOperation `json:"post"`
+	Delete     *apiOperation `json:"delete"`
+	Options    *apiOperation `json:"options"`
+	Head       *apiOperation `json:"head"`
+	Patch      *apiOperation `json:"patch"`
+}
+
+type methodOperation struct {
+	method string
+	op     *apiOperation
+}
+
+// operations returns the operations of the path in a fixed order.
+func (p *apiPathItem) operations() []methodOperation {
+	var ops []methodOperation
+	for _, o := range []methodOperation{
+		{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch},
+		{"DELETE", p.Delete}, {"HEAD", p.Head}, {"OPTIONS", p.Options},
+	} {
+		if o.op != nil {
+			ops = append(ops, o)
+		}
+	}
+	return ops
+}
+
+type apiOperation struct {
+	OperationID string                  `json:"operationId"`
+	Summary     string                  `json:"summary"`
+	Description string                  `json:"description"`
+	Deprecated  bool                    `json:"deprecated"`
+	Consumes    []string                `json:"consumes"`
+	Parameters  []*apiParam             `json:"parameters"`
+	RequestBody *apiRequestBody         `json:"requestBody"`
+	Responses   map[string]*apiResponse `json:"responses"`
+}
+
+type apiParam struct {
+	Ref         string     `json:"$ref"`
+	Name        string     `json:"name"`
+	In          string     `json:"in"`
+	Description string     `json:"description"`
+	Required    bool       `json:"required"`
+	Schema      *apiSchema `json:"schema"`
+
+	// Swagger 2.0 declares the type of non-body parameters inline.
+	Type    apiTypes      `json:"type"`
+	Format  string        `json:"format"`
+	Items   *apiSchema    `json:"items"`
+	Enum    []interface{} `json:"enum"`
+	Default interface{}   `json:"default"`
+}
+
+// schema returns the schema of the parameter value.
+func (p *apiParam) schema() *apiSchema {
+	if p.Schema != nil {
+		return p.Schema
+	}
+	return &apiSchema{Type: p.Type, Format: p.Format, Items: p.Items, Enum: p.Enum, Default: p.Default}
+}
+
+type apiRequestBody struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Required    bool                 `json:"required"`
+	Content     map[string]*apiMedia `json:"content"`
+}
+
+type apiResponse struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]*apiMedia `json:"content"`
+	Schema      *apiSchema           `json:"schema"` // Swagger 2.0
+}
+
+type apiMedia struct {
+	Schema *apiSchema `json:"schema"`
+}
+
+type apiSchema struct {
+	Ref                  string                `json:"$ref"`
+	Type                 apiTypes              `json:"type"`
+	Format               string                `json:"format"`
+	Description          string                `json:"description"`
+	Properties           map[string]*apiSchema `json:"properties"`
+	Required             []string              `json:"required"`
+	Items                *apiSchema            `json:"items"`
+	AdditionalProperties json.RawMessage       `json:"additionalProperties"`
+	AllOf                []*apiSchema          `json:"allOf"`
+	Enum                 []interface{}         `json:"enum"`
+	Default              interface{}           `json:"default"`
+}
+
+// typ returns the type of the schema, ignoring "null" of OpenAPI 3.1.
+func (s *apiSchema) typ() string {
+	for _, t := range s.Type {
+		if t != "null" {
+			return t
+		}
+	}
+	if len(s.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// apiTypes is the type of a schema, a string or, since OpenAPI 3.1, an
+// array of strings.
+type apiTypes []string
+
+func (t *apiTypes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = apiTypes{s}
+		return nil
+	}
+	var a []string
+	if err := json.Unmarshal(b, &a); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = a
+	return nil
+}
+
+// loadSpec reads a JSON or YAML API spec.
+func loadSpec(name string) (*apiSpec, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(name)); ext != ".json" && !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		v, err := decodeYAML(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if b, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	spec := new(apiSpec)
+	if err = json.Unmarshal(b, spec); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if spec.Swagger == "" && spec.OpenAPI == "" {
+		return nil, fmt.Errorf("%s: neither an OpenAPI nor a Swagger document", name)
+	}
+	return spec, nil
+}
+
+// basePath returns the path of the first server URL or the Swagger base
+// path.
+func (spec *apiSpec) basePath() string {
+	if spec.BasePath != "" {
+		return spec.BasePath
+	}
+	if len(spec.Servers) == 0 {
+		return ""
+	}
+	u := spec.Servers[0].URL
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+		if i = strings.IndexByte(u, '/'); i < 0 {
+			return ""
+		}
+		u = u[i:]
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// refName returns the last element of a local reference of the given
+// section, like "User" for "#/components/schemas/User".
+func refName(ref string, sections ...string) (string, error) {
+	for _, s := range sections {
+		if name := strings.TrimPrefix(ref, "#/"+s+"/"); name != ref && name != "" {
+			return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~"), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported reference %q", ref)
+}
+
+// schemas returns the named schemas of the spec.
+func (spec *apiSpec) schemas() map[string]*apiSchema {
+	if spec.Definitions != nil {
+		return spec.Definitions
+	}
+	return spec.Components.Schemas
+}
+
+func (spec *apiSpec) param(p *apiParam) (*apiParam, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "components/parameters", "parameters")
+	if err != nil {
+		return nil, err
+	}
+	if q := spec.Components.Parameters[name]; q != nil {
+		return q, nil
+	}
+	if q := spec.Parameters[name]; q != nil {
+		return q, nil
+	}
+	return nil, fmt.Errorf("parameter %q not found", p.Ref)
+}
+
+func (spec *apiSpec) requestBody(b *apiRequestBody) (*apiRequestBody, error) {
+	if b == nil || b.Ref == "" {
+		return b, nil
+	}
+	name, err := refName(b.Ref, "components/requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	if r := spec.Components.RequestBodies[name]; r != nil {
+		return r, nil
+	}
+	return nil, fmt.Errorf("request body %q not found", b.Ref)
+}
+
+func (spec *apiSpec) response(r *apiResponse) (*apiResponse, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "components/responses", "responses")
+	if err != nil {
+		return nil, err
+	}
+	if q := spec.Components.Responses[name]; q != nil {
+		return q, nil
+	}
+	if q := spec.Responses[name]; q != nil {
+		return q, nil
+	}
+	return nil, fmt.Errorf("response %q not found", r.Ref)
+}
+
+// resolve follows schema references.
+func (spec *apiSpec) resolve(s *apiSchema) (*apiSchema, error) {
+	for i := 0; s != nil && s.Ref != ""; i++ {
+		name, err := refName(s.Ref, "components/schemas", "definitions")
+		if err != nil {
+			return nil, err
+		}
+		next := spec.schemas()[name]
+		if next == nil || i > 32 {
+			return nil, fmt.Errorf("schema %q not found", s.Ref)
+		}
+		s = next
+	}
+	return s, nil
+}
+
+// jsonMedia returns the schema of the JSON content, or of any content if
+// there is no JSON one.
+func jsonMedia(content map[string]*apiMedia) (mediaType string, schema *apiSchema) {
+	var types []string
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		mt := strings.ToLower(strings.TrimSpace(strings.Split(t, ";")[0]))
+		if mt == "application/json" || strings.HasSuffix(mt, "+json") {
+			return mt, content[t].schemaOrNil()
+		}
+	}
+	for _, t := range types {
+		mt := strings.ToLower(strings.TrimSpace(strings.Split(t, ";")[0]))
+		if mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data" || mt == "application/xml" || mt == "*/*" {
+			return mt, content[t].schemaOrNil()
+		}
+	}
+	if len(types) > 0 {
+		return strings.ToLower(types[0]), content[types[0]].schemaOrNil()
+	}
+	return "", nil
+}
+
+func (m *apiMedia) schemaOrNil() *apiSchema {
+	if m == nil {
+		return nil
+	}
+	return m.Schema
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var cmdRoutes = &command{
+	name:  "routes",
+	usage: "[-tags tags] [-json] [-o file] [-diff file] [-- app arguments]",
+	short: "print the route table of the app in the current directory without serving it, or diff it against a previous export",
+}
+
+func init() {
+	cmdRoutes.run = runRoutes
+	commands = append(commands, cmdRoutes)
+}
+
+// The environment read by the app, see lessgo.ROUTES_ENV.
+const routesEnv = "LESSGO_ROUTES"
+
+// routeTable mirrors lessgo.RouteTable, the format of the exports.
+type routeTable struct {
+	Before []string     `json:"before"`
+	After  []string     `json:"after"`
+	Routes []routeEntry `json:"routes"`
+}
+
+type routeEntry struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Handler     string   `json:"handler"`
+	Desc        string   `json:"desc,omitempty"`
+	Middlewares []string `json:"middlewares"`
+}
+
+func runRoutes(args []string) error {
+	fs := cmdRoutes.flagSet()
+	tags := fs.String("tags", "", "build tags")
+	asJSON := fs.Bool("json", false, "print the table as JSON, the format of -o and -diff")
+	out := fs.String("o", "", "export the table as JSON to the file")
+	diff := fs.String("diff", "", "print the changes against a table exported with -o instead of the table, and fail if there are any")
+	timeout := fs.Duration("timeout", time.Minute, "time to wait for the app to build its router")
+	fs.Parse(args)
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	table, err := loadRoutes(dir, *tags, fs.Args(), *timeout)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		b, _ := json.MarshalIndent(table, "", "  ")
+		if err = os.WriteFile(*out, append(b, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	if *diff != "" {
+		old, err := readRouteTable(*diff)
+		if err != nil {
+			return err
+		}
+		changes := diffRoutes(old, table)
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+		if len(changes) > 0 {
+			return fmt.Errorf("%d routing changes against %s", len(changes), *diff)
+		}
+		fmt.Fprintf(os.Stderr, "no routing changes against %s\n", *diff)
+		return nil
+	}
+	if *asJSON {
+		b, _ := json.MarshalIndent(table, "", "  ")
+		fmt.Printf("%s\n", b)
+		return nil
+	}
+	printRoutes(os.Stdout, table)
+	return nil
+}
+
+// loadRoutes builds the app in dir and runs it in dry-run mode: lessgo.Run
+// builds the router, writes the route table and exits without serving.
+func loadRoutes(dir, tags string, args []string, timeout time.Duration) (*routeTable, error) {
+	tmp, err := os.MkdirTemp("", "lessgo-routes")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	bin := filepath.Join(tmp, filepath.Base(dir))
+	if err = buildApp(dir, bin, tags); err != nil {
+		return nil, fmt.Errorf("build failed: %v", err)
+	}
+	file := filepath.Join(tmp, "routes.json")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = append(os.Environ(), routesEnv+"="+file)
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("the app did not call lessgo.Run() within %v", timeout)
+	}
+	if err != nil {
+		os.Stderr.Write(output.Bytes())
+		return nil, fmt.Errorf("the app failed: %v", err)
+	}
+	table, err := readRouteTable(file)
+	if os.IsNotExist(err) {
+		os.Stderr.Write(output.Bytes())
+		return nil, fmt.Errorf("the app exited without calling lessgo.Run()")
+	}
+	return table, err
+}
+
+func readRouteTable(name string) (*routeTable, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	table := new(routeTable)
+	if err = json.Unmarshal(b, table); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return table, nil
+}
+
+func printRoutes(w io.Writer, table *routeTable) {
+	fmt.Fprintf(w, "before: %s\nafter:  %s\n\n", strings.Join(table.Before, ", "), strings.Join(table.After, ", "))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tDESC\tMIDDLEWARE")
+	for _, r := range table.Routes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Method, r.Path, shortHandler(r.Handler), r.Desc, strings.Join(r.Middlewares, ", "))
+	}
+	tw.Flush()
+}
+
+// shortHandler strips the directories of the import path from a function
+// name, like "home.glob..func1" for "example.com/shop/bizhandler/home.glob..func1".
+func shortHandler(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 && !strings.HasPrefix(name, "file ") && !strings.HasPrefix(name, "static ") {
+		return name[i+1:]
+	}
+	return name
+}
+
+var closureSuffix = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// handlerKey identifies the handler of a route across builds. Function
+// literals are numbered in source order, so they are identified by their
+// package and the description of the handler instead.
+func handlerKey(r routeEntry) string {
+	if name := closureSuffix.ReplaceAllString(r.Handler, ""); name != r.Handler {
+		return strings.TrimSuffix(strings.TrimSuffix(name, ".glob."), ".init") + " " + r.Desc
+	}
+	return r.Handler
+}
+
+// diffRoutes returns the changes from old to cur: added (+), removed (-)
+// and changed (~) routes, and changes of the shared middleware chains.
+func diffRoutes(old, cur *routeTable) []string {
+	var changes []string
+	chain := func(name string, a, b []string) {
+		if strings.Join(a, "\x00") != strings.Join(b, "\x00") {
+			changes = append(changes, fmt.Sprintf("~ %s middlewares: [%s] -> [%s]", name, strings.Join(a, ", "), strings.Join(b, ", ")))
+		}
+	}
+	chain("before", old.Before, cur.Before)
+	chain("after", old.After, cur.After)
+
+	index := func(t *routeTable) map[string]routeEntry {
+		m := make(map[string]routeEntry, len(t.Routes))
+		for _, r := range t.Routes {
+			m[r.Method+" "+r.Path] = r
+		}
+		return m
+	}
+	a, b := index(old), index(cur)
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, pj := keys[i][strings.IndexByte(keys[i], ' ')+1:], keys[j][strings.IndexByte(keys[j], ' ')+1:]
+		if pi != pj {
+			return pi < pj
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		ra, inA := a[k]
+		rb, inB := b[k]
+		switch {
+		case !inA:
+			changes = append(changes, fmt.Sprintf("+ %s  %s  [%s]", k, shortHandler(rb.Handler), strings.Join(rb.Middlewares, ", ")))
+		case !inB:
+			changes = append(changes, fmt.Sprintf("- %s  %s  [%s]", k, shortHandler(ra.Handler), strings.Join(ra.Middlewares, ", ")))
+		default:
+			if handlerKey(ra) != handlerKey(rb) {
+				changes = append(changes, fmt.Sprintf("~ %s handler: %s (%s) -> %s (%s)", k, shortHandler(ra.Handler), ra.Desc, shortHandler(rb.Handler), rb.Desc))
+			}
+			chain(k, ra.Middlewares, rb.Middlewares)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRoutes(t *testing.T) {
+	old := &routeTable{
+		Before: []string{"Recover"},
+		Routes: []routeEntry{
+			{Method: "GET", Path: "/home", Handler: "example.com/shop/bizhandler/home.glob..func1", Desc: "主页", Middlewares: []string{}},
+			{Method: "GET", Path: "/users/:id", Handler: "example.com/shop/bizhandler/user.glob..func2", Desc: "用户", Middlewares: []string{"auth"}},
+			{Method: "POST", Path: "/users", Handler: "example.com/shop/bizhandler/user.glob..func3", Desc: "创建用户", Middlewares: []string{"auth"}},
+		},
+	}
+	cur := &routeTable{
+		Before: []string{"Recover", "SecureHeaders"},
+		Routes: []routeEntry{
+			// renumbered function literal, same handler
+			{Method: "GET", Path: "/home", Handler: "example.com/shop/bizhandler/home.glob..func4", Desc: "主页", Middlewares: []string{}},
+			{Method: "GET", Path: "/users/:id", Handler: "example.com/shop/bizhandler/admin.glob..func1", Desc: "用户", Middlewares: []string{}},
+			{Method: "DELETE", Path: "/users/:id", Handler: "example.com/shop/bizhandler/user.DeleteUser", Desc: "删除用户", Middlewares: []string{"auth"}},
+		},
+	}
+	want := []string{
+		"~ before middlewares: [Recover] -> [Recover, SecureHeaders]",
+		"- POST /users  user.glob..func3  [auth]",
+		"+ DELETE /users/:id  user.DeleteUser  [auth]",
+		"~ GET /users/:id handler: user.glob..func2 (用户) -> admin.glob..func1 (用户)",
+		"~ GET /users/:id middlewares: [auth] -> []",
+	}
+	if got := diffRoutes(old, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffRoutes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := diffRoutes(cur, cur); len(got) != 0 {
+		t.Errorf("diffRoutes of equal tables: %q", got)
+	}
+
+	var b strings.Builder
+	printRoutes(&b, cur)
+	if !strings.Contains(b.String(), "DELETE  /users/:id  user.DeleteUser") {
+		t.Errorf("printRoutes:\n%s", b.String())
+	}
+}
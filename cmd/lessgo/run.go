@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var cmdRun = &command{
+	name:  "run",
+	usage: "[-addr address] [-tags tags] [-exts .go,...] [-livereload=false] [-- app arguments]",
+	short: "build and run the app in the current directory, rebuilding and restarting it when source files change",
+}
+
+func init() {
+	cmdRun.run = runRun
+	commands = append(commands, cmdRun)
+}
+
+// The environment read by the app, see lessgo.DEV_LISTENER_ENV and
+// lessgo.LIVERELOAD_ENV. The lessgo package is not imported: its
+// initialization loads and writes the config of the current directory.
+const (
+	devListenerEnv = "LESSGO_DEV_LISTENER"
+	liveReloadEnv  = "LESSGO_LIVERELOAD"
+)
+
+// Directories never watched, besides hidden ones.
+var skipDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"uploads":      true,
+	"logger":       true,
+	"database":     true,
+}
+
+type devServer struct {
+	dir        string
+	tags       string
+	exts       []string
+	args       []string
+	liveReload bool
+	listener   *os.File // passed to every app process as fd 3
+	bin        string
+	proc       *exec.Cmd
+	exited     chan struct{}
+}
+
+func runRun(args []string) error {
+	fs := cmdRun.flagSet()
+	addr := fs.String("addr", "0.0.0.0:8080", "address to listen on, kept open across restarts; it overrides listen::address")
+	tags := fs.String("tags", "", "build tags")
+	exts := fs.String("exts", ".go", "comma separated extensions of the files that trigger a rebuild")
+	interval := fs.Duration("interval", 500*time.Millisecond, "interval of checking the files")
+	liveReload := fs.Bool("livereload", true, "reload pages in the browser after a rebuild or a template change (debug mode only)")
+	fs.Parse(args)
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	lf, err := ln.(*net.TCPListener).File()
+	ln.Close()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "lessgo-run")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	s := &devServer{
+		dir:        dir,
+		tags:       *tags,
+		exts:       strings.Split(*exts, ","),
+		args:       fs.Args(),
+		liveReload: *liveReload,
+		listener:   lf,
+		bin:        filepath.Join(tmp, filepath.Base(dir)),
+	}
+	fmt.Fprintf(os.Stderr, "lessgo run: listening on %s\n", *addr)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	state := s.snapshot()
+	s.rebuild()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sigs:
+			s.stop()
+			return nil
+		case <-ticker.C:
+		}
+		cur := s.snapshot()
+		if cur == state {
+			continue
+		}
+		// wait for the editor or the generator to finish writing
+		for {
+			time.Sleep(*interval)
+			next := s.snapshot()
+			if next == cur {
+				break
+			}
+			cur = next
+		}
+		state = cur
+		fmt.Fprintln(os.Stderr, "lessgo run: files changed, rebuilding")
+		s.rebuild()
+	}
+}
+
+// snapshot returns a digest of the watched files, which changes when a file
+// is created, removed or modified.
+func (s *devServer) snapshot() string {
+	var buf bytes.Buffer
+	filepath.Walk(s.dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		base := info.Name()
+		if info.IsDir() {
+			if name != s.dir && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") || skipDirs[base]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !s.watched(base) {
+			return nil
+		}
+		fmt.Fprintf(&buf, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return buf.String()
+}
+
+func (s *devServer) watched(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		return false
+	}
+	for _, ext := range s.exts {
+		if ext = strings.TrimSpace(ext); ext != "" && strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuild builds the app and, if the build succeeds, replaces the running
+// process. On failure the old process keeps serving.
+func (s *devServer) rebuild() {
+	out := s.bin + ".new"
+	if err := buildApp(s.dir, out, s.tags); err != nil {
+		fmt.Fprintf(os.Stderr, "lessgo run: build failed: %v\n", err)
+		return
+	}
+	s.stop()
+	if err := os.Rename(out, s.bin); err != nil {
+		fmt.Fprintf(os.Stderr, "lessgo run: %v\n", err)
+		return
+	}
+	if err := s.start(); err != nil {
+		fmt.Fprintf(os.Stderr, "lessgo run: %v\n", err)
+	}
+}
+
+// buildApp builds the package in dir into the binary out, printing the
+// output of the compiler to stderr.
+func buildApp(dir, out, tags string) error {
+	args := []string{"build", "-o", out}
+	if tags != "" {
+		args = append(args, "-tags", tags)
+	}
+	build := exec.Command("go", append(args, ".")...)
+	build.Dir = dir
+	build.Stdout = os.Stderr
+	build.Stderr = os.Stderr
+	return build.Run()
+}
+
+func (s *devServer) start() error {
+	cmd := exec.Command(s.bin, s.args...)
+	cmd.Dir = s.dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{s.listener}
+	cmd.Env = append(os.Environ(), devListenerEnv+"=3")
+	if s.liveReload {
+		cmd.Env = append(cmd.Env, liveReloadEnv+"="+strconv.FormatInt(time.Now().UnixNano(), 36))
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	s.proc, s.exited = cmd, exited
+	return nil
+}
+
+// stop terminates the running process, killing it if it has not exited
+// within 10 seconds. Connections arriving meanwhile wait in the backlog of
+// the listener.
+func (s *devServer) stop() {
+	if s.proc == nil {
+		return
+	}
+	select {
+	case <-s.exited:
+	default:
+		s.proc.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-s.exited:
+		case <-time.After(10 * time.Second):
+			s.proc.Process.Kill()
+			<-s.exited
+		}
+	}
+	s.proc = nil
+}
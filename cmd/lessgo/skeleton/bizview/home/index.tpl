@@ -0,0 +1,13 @@
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{ name }}</title>
+  <link rel="stylesheet" href="/static/css/app.css">
+</head>
+<body>
+  <h1>{{ name }}</h1>
+  <p><a href="/home/hello/lessgo">/home/hello/lessgo</a></p>
+  <script nonce="{{ csp_nonce }}">console.log("{{ name }}");</script>
+</body>
+</html>
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeYAML decodes the subset of YAML used by API specs: block and flow
+// mappings and sequences, plain and quoted scalars, literal and folded block
+// scalars and comments. Anchors, aliases, tags and multiple documents are
+// not supported. Mappings decode to map[string]interface{}, sequences to
+// []interface{} and scalars to string, int64, float64, bool or nil, like
+// encoding/json.
+func decodeYAML(b []byte) (interface{}, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")}
+	p.skipBlank()
+	if p.i < len(p.lines) && strings.TrimSpace(p.lines[p.i]) == "---" {
+		p.i++
+	}
+	v, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.i < len(p.lines) && strings.TrimSpace(p.lines[p.i]) != "..." {
+		return nil, p.errorf("unexpected content")
+	}
+	return v, nil
+}
+
+type yamlParser struct {
+	lines []string
+	i     int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", p.i+1, fmt.Sprintf(format, args...))
+}
+
+// skipBlank moves to the next line with content.
+func (p *yamlParser) skipBlank() {
+	for ; p.i < len(p.lines); p.i++ {
+		s := strings.TrimSpace(p.lines[p.i])
+		if s != "" && s[0] != '#' {
+			return
+		}
+	}
+}
+
+// current returns the indentation and content of the next line with
+// content, ok is false at the end of the input.
+func (p *yamlParser) current() (indent int, content string, ok bool) {
+	p.skipBlank()
+	if p.i >= len(p.lines) {
+		return 0, "", false
+	}
+	line := p.lines[p.i]
+	indent = len(line) - len(strings.TrimLeft(line, " "))
+	return indent, stripComment(line[indent:]), true
+}
+
+// node parses the block node whose lines are indented by at least indent.
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	ind, content, ok := p.current()
+	if !ok || ind < indent {
+		return nil, nil
+	}
+	if strings.HasPrefix(content, "\t") {
+		return nil, p.errorf("tabs are not allowed in indentation")
+	}
+	if isSeqItem(content) {
+		return p.sequence(ind)
+	}
+	if _, _, ok := splitKey(content); ok {
+		return p.mapping(ind)
+	}
+	p.i++
+	return p.inline(content, ind)
+}
+
+func isSeqItem(s string) bool {
+	return s == "-" || strings.HasPrefix(s, "- ")
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for {
+		ind, content, ok := p.current()
+		if !ok || ind < indent {
+			return m, nil
+		}
+		if ind > indent {
+			return nil, p.errorf("bad indentation")
+		}
+		if isSeqItem(content) {
+			return nil, p.errorf("sequence item in a mapping")
+		}
+		key, rest, ok := splitKey(content)
+		if !ok {
+			return nil, p.errorf("expected a mapping key")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.i++
+		var v interface{}
+		var err error
+		switch {
+		case rest == "":
+			// a sequence may be indented like its key
+			if ind, content, ok := p.current(); ok && ind == indent && isSeqItem(content) {
+				v, err = p.sequence(ind)
+			} else {
+				v, err = p.node(indent + 1)
+			}
+		case rest[0] == '|' || rest[0] == '>':
+			v, err = p.blockScalar(rest, indent)
+		default:
+			v, err = p.inline(rest, indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	s := []interface{}{}
+	for {
+		ind, content, ok := p.current()
+		if !ok || ind < indent || ind == indent && !isSeqItem(content) {
+			return s, nil
+		}
+		if ind > indent || !isSeqItem(content) {
+			return nil, p.errorf("bad indentation of a sequence item")
+		}
+		rest := strings.TrimLeft(content[1:], " ")
+		var v interface{}
+		var err error
+		if rest == "" {
+			p.i++
+			v, err = p.node(indent + 1)
+		} else {
+			// parse "- key: value" and "- - item" as a node starting at the
+			// column of its content
+			col := indent + len(content) - len(rest)
+			p.lines[p.i] = strings.Repeat(" ", col) + p.lines[p.i][col:]
+			v, err = p.node(col)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+}
+
+// blockScalar reads a literal (|) or folded (>) scalar.
+func (p *yamlParser) blockScalar(header string, indent int) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case c == '-' || c == '+':
+			chomp = c
+		case c >= '1' && c <= '9', c == ' ':
+		default:
+			return nil, p.errorf("invalid block scalar header %q", header)
+		}
+	}
+	var lines []string
+	blockIndent := -1
+	for ; p.i < len(p.lines); p.i++ {
+		line := p.lines[p.i]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		ind := len(line) - len(strings.TrimLeft(line, " "))
+		if blockIndent < 0 {
+			if ind <= indent {
+				break
+			}
+			blockIndent = ind
+		}
+		if ind < blockIndent {
+			break
+		}
+		lines = append(lines, line[blockIndent:])
+	}
+	// trailing blank lines belong to the block only for chomping
+	n := len(lines)
+	for n > 0 && lines[n-1] == "" {
+		n--
+	}
+	trailing := len(lines) - n
+	lines = lines[:n]
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			switch {
+			case !folded:
+				b.WriteByte('\n')
+			case line == "" || lines[i-1] == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(lines[i-1], " "):
+				if line != "" || lines[i-1] == "" {
+					b.WriteByte('\n')
+				}
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(line)
+	}
+	s := b.String()
+	switch chomp {
+	case '-':
+	case '+':
+		s += strings.Repeat("\n", trailing+1)
+	default:
+		if s != "" {
+			s += "\n"
+		}
+	}
+	return s, nil
+}
+
+// inline parses a value written on the line of its key or sequence dash,
+// joining the following lines of a flow collection, a quoted scalar or a
+// plain scalar spanning several lines.
+func (p *yamlParser) inline(s string, indent int) (interface{}, error) {
+	for {
+		v, rest, err := parseFlow(s, false)
+		if err == nil {
+			if rest = strings.TrimSpace(rest); rest != "" {
+				return nil, p.errorf("unexpected %q after value", rest)
+			}
+			if str, ok := v.(string); ok && isPlain(s) {
+				// continuation lines of a plain scalar
+				for {
+					ind, content, ok := p.current()
+					if !ok || ind <= indent || isSeqItem(content) {
+						break
+					}
+					if _, _, isKey := splitKey(content); isKey {
+						break
+					}
+					str += " " + strings.TrimSpace(content)
+					p.i++
+				}
+				return resolvePlain(str), nil
+			}
+			return v, nil
+		}
+		if err != errYAMLIncomplete {
+			return nil, p.errorf("%v", err)
+		}
+		ind, content, ok := p.current()
+		if !ok || ind <= indent && !strings.HasPrefix(content, "]") && !strings.HasPrefix(content, "}") {
+			return nil, p.errorf("unterminated flow collection or quoted scalar")
+		}
+		s += " " + strings.TrimSpace(content)
+		p.i++
+	}
+}
+
+func isPlain(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && !strings.ContainsRune("[{\"'", rune(s[0]))
+}
+
+var errYAMLIncomplete = fmt.Errorf("incomplete value")
+
+// parseFlow parses a flow node at the start of s and returns the rest. In
+// flow context plain scalars end at ',', ']' and '}'. Plain scalars are
+// returned as strings and resolved by the caller.
+func parseFlow(s string, inFlow bool) (v interface{}, rest string, err error) {
+	s = strings.TrimLeft(s, " ")
+	if s == "" {
+		if inFlow {
+			return nil, "", errYAMLIncomplete
+		}
+		return nil, "", nil
+	}
+	switch s[0] {
+	case '[':
+		seq := []interface{}{}
+		s = strings.TrimLeft(s[1:], " ")
+		for {
+			if s == "" {
+				return nil, "", errYAMLIncomplete
+			}
+			if s[0] == ']' {
+				return seq, s[1:], nil
+			}
+			if v, s, err = parseFlow(s, true); err != nil {
+				return nil, "", err
+			}
+			if str, ok := v.(string); ok {
+				v = resolvePlain(str)
+			}
+			seq = append(seq, v)
+			if s, err = flowSeparator(s, ']'); err != nil {
+				return nil, "", err
+			}
+		}
+	case '{':
+		m := make(map[string]interface{})
+		s = strings.TrimLeft(s[1:], " ")
+		for {
+			if s == "" {
+				return nil, "", errYAMLIncomplete
+			}
+			if s[0] == '}' {
+				return m, s[1:], nil
+			}
+			var k interface{}
+			if k, s, err = parseFlowKey(s); err != nil {
+				return nil, "", err
+			}
+			s = strings.TrimLeft(s, " ")
+			if s == "" {
+				return nil, "", errYAMLIncomplete
+			}
+			if s[0] == ':' {
+				if v, s, err = parseFlow(s[1:], true); err != nil {
+					return nil, "", err
+				}
+				if str, ok := v.(string); ok {
+					v = resolvePlain(str)
+				}
+			} else {
+				v = nil
+			}
+			m[fmt.Sprint(k)] = v
+			if s, err = flowSeparator(s, '}'); err != nil {
+				return nil, "", err
+			}
+		}
+	case '"':
+		return parseDoubleQuoted(s)
+	case '\'':
+		return parseSingleQuoted(s)
+	case '&', '*', '!':
+		return nil, "", fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+	if !inFlow {
+		return strings.TrimSpace(s), "", nil
+	}
+	i := strings.IndexAny(s, ",]}")
+	if i < 0 {
+		i = len(s)
+	}
+	return strings.TrimSpace(s[:i]), s[i:], nil
+}
+
+func parseFlowKey(s string) (interface{}, string, error) {
+	if s[0] == '"' || s[0] == '\'' {
+		return parseFlow(s, true)
+	}
+	i := strings.IndexAny(s, ":,}")
+	if i < 0 {
+		return nil, "", errYAMLIncomplete
+	}
+	return strings.TrimSpace(s[:i]), s[i:], nil
+}
+
+func flowSeparator(s string, end byte) (string, error) {
+	s = strings.TrimLeft(s, " ")
+	switch {
+	case s == "":
+		return "", errYAMLIncomplete
+	case s[0] == ',':
+		return strings.TrimLeft(s[1:], " "), nil
+	case s[0] == end:
+		return s, nil
+	}
+	return "", fmt.Errorf("expected ',' or '%c' in flow collection", end)
+}
+
+func parseDoubleQuoted(s string) (interface{}, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(strings.ReplaceAll(s[:i+1], `\/`, `/`))
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid double-quoted scalar %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return nil, "", errYAMLIncomplete
+}
+
+func parseSingleQuoted(s string) (interface{}, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			b.WriteByte('\'')
+			i++
+			continue
+		}
+		return b.String(), s[i+1:], nil
+	}
+	return nil, "", errYAMLIncomplete
+}
+
+// resolvePlain converts a plain scalar to null, a boolean or a number.
+func resolvePlain(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if c := s[0]; c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9' {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXpP_") {
+			return f
+		}
+	}
+	return s
+}
+
+// splitKey splits a mapping entry "key: value" into the key and the value
+// text, ok is false if s is not a mapping entry.
+func splitKey(s string) (key, rest string, ok bool) {
+	if s == "" {
+		return "", "", false
+	}
+	var k interface{}
+	var err error
+	switch s[0] {
+	case '"':
+		k, rest, err = parseDoubleQuoted(s)
+	case '\'':
+		k, rest, err = parseSingleQuoted(s)
+	case '[', '{', '&', '*', '!', '|', '>', '#':
+		return "", "", false
+	default:
+		i := strings.Index(s, ": ")
+		if i < 0 {
+			if !strings.HasSuffix(s, ":") {
+				return "", "", false
+			}
+			i = len(s) - 1
+		}
+		k, rest = strings.TrimSpace(s[:i]), s[i:]
+	}
+	if err != nil || !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' {
+		return "", "", false
+	}
+	return fmt.Sprint(k), strings.TrimSpace(rest[1:]), true
+}
+
+// stripComment removes a trailing comment outside of quoted scalars.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" [{,:-", s[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return strings.TrimRight(s[:i], " ")
+		}
+	}
+	return strings.TrimRight(s, " ")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	src := `---
+# comment
+openapi: 3.0.3
+info:
+  title: "Pet store" # trailing comment
+  version: '1.0'
+  description: |
+    Line one
+    line two
+
+paths:
+  /pets/{petId}:
+    get:
+      operationId: showPet
+      tags: [pets, "read only"]
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema: {type: integer, format: int64}
+        -   name: verbose
+            in: query
+      responses:
+        '200':
+          description: >-
+            A pet
+            by id
+list:
+- a
+- - b
+  - c
+- ~
+- 1.5
+- -3
+- "#not a comment"
+empty: {}
+flow: [1, {x: y,
+  z: [2]}]
+plain: this value
+  continues here
+`
+	got, err := decodeYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Pet store",
+			"version":     "1.0",
+			"description": "Line one\nline two\n",
+		},
+		"paths": map[string]interface{}{
+			"/pets/{petId}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"operationId": "showPet",
+					"tags":        []interface{}{"pets", "read only"},
+					"parameters": []interface{}{
+						map[string]interface{}{
+							"name":     "petId",
+							"in":       "path",
+							"required": true,
+							"schema":   map[string]interface{}{"type": "integer", "format": "int64"},
+						},
+						map[string]interface{}{"name": "verbose", "in": "query"},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{"description": "A pet by id"},
+					},
+				},
+			},
+		},
+		"list":  []interface{}{"a", []interface{}{"b", "c"}, nil, 1.5, int64(-3), "#not a comment"},
+		"empty": map[string]interface{}{},
+		"flow":  []interface{}{int64(1), map[string]interface{}{"x": "y", "z": []interface{}{int64(2)}}},
+		"plain": "this value continues here",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+
+	for _, bad := range []string{
+		"a: 1\n  b: 2\n",
+		"a: [1, 2\n",
+		"a: &x 1\n",
+		"a: 1\na: 2\n",
+		"a: 'open\n",
+	} {
+		if _, err := decodeYAML([]byte(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package lessgo
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// CoalesceConfig defines the config for request coalescing middleware.
+	CoalesceConfig struct {
+		// 参与计算合并键的请求头，如"Accept-Encoding"；
+		// 携带Cookie或Authorization的请求仅当该头被列入时才参与合并
+		VaryHeaders []string
+
+		// 允许共享的最大响应体，超出时等待者自行处理
+		MaxBytes int64
+
+		// 等待者的最长等待时间，单位毫秒，超时后自行处理
+		WaitTimeoutMS int64
+	}
+
+	// 正在执行的请求
+	coalesceCall struct {
+		done   chan struct{}
+		ok     bool
+		status int
+		header http.Header
+		body   []byte
+	}
+)
+
+var (
+	coalesceCalls    = map[string]*coalesceCall{}
+	coalesceCallsMux sync.Mutex
+)
+
+var Coalesce = ApiMiddleware{
+	Name: "合并并发请求",
+	Desc: "将并发的相同GET请求合并为一次处理并共享响应，防止缓存击穿时压垮高开销的读接口",
+	Config: CoalesceConfig{
+		VaryHeaders:   []string{HeaderAcceptEncoding},
+		MaxBytes:      4 * MB,
+		WaitTimeoutMS: 10000,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(CoalesceConfig)
+		timeout := time.Duration(config.WaitTimeoutMS) * time.Millisecond
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				if req.Method != GET || !coalescable(req, config.VaryHeaders) {
+					return next(c)
+				}
+				key := coalesceKey(req, config.VaryHeaders)
+
+				coalesceCallsMux.Lock()
+				if call, ok := coalesceCalls[key]; ok {
+					coalesceCallsMux.Unlock()
+					if call.wait(timeout) {
+						header := c.response.Header()
+						for k, v := range call.header {
+							header[k] = v
+						}
+						c.WriteHeader(call.status)
+						_, err := c.response.Write(call.body)
+						return err
+					}
+					return next(c)
+				}
+				call := &coalesceCall{done: make(chan struct{})}
+				coalesceCalls[key] = call
+				coalesceCallsMux.Unlock()
+
+				w := &captureWriter{
+					responseWriterWrapper: responseWriterWrapper{c.response.writer},
+					max:                   config.MaxBytes,
+				}
+				c.response.writer = w
+				defer func() {
+					c.response.writer = w.ResponseWriter
+					coalesceCallsMux.Lock()
+					delete(coalesceCalls, key)
+					coalesceCallsMux.Unlock()
+					close(call.done)
+				}()
+
+				err := next(c)
+				if err == nil && c.response.Committed() && !w.overflow && c.response.Status() < 500 {
+					call.ok = true
+					call.status = c.response.Status()
+					call.header = make(http.Header, len(c.response.Header()))
+					for k, v := range c.response.Header() {
+						call.header[k] = v
+					}
+					// 不向其他客户端重放Cookie
+					call.header.Del(HeaderSetCookie)
+					call.body = w.buf.Bytes()
+				}
+				return err
+			}
+		}
+	},
+}.Reg()
+
+// 携带凭证的请求只有在凭证参与合并键时才可合并，避免不同用户共享响应
+func coalescable(req *http.Request, vary []string) bool {
+	for _, h := range []string{HeaderCookie, HeaderAuthorization} {
+		if req.Header.Get(h) == "" {
+			continue
+		}
+		varied := false
+		for _, v := range vary {
+			if strings.EqualFold(v, h) {
+				varied = true
+				break
+			}
+		}
+		if !varied {
+			return false
+		}
+	}
+	return true
+}
+
+func coalesceKey(req *http.Request, vary []string) string {
+	key := req.Host + req.URL.RequestURI()
+	if len(vary) == 0 {
+		return key
+	}
+	parts := make([]string, 0, len(vary)+1)
+	parts = append(parts, key)
+	for _, h := range vary {
+		parts = append(parts, req.Header.Get(h))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// 等待执行结果，返回是否可共享
+func (call *coalesceCall) wait(timeout time.Duration) bool {
+	if timeout <= 0 {
+		<-call.done
+		return call.ok
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-call.done:
+		return call.ok
+	case <-t.C:
+		return false
+	}
+}
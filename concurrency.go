@@ -0,0 +1,134 @@
+package lessgo
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// ConcurrencyLimitConfig defines the config for concurrency limiter middleware.
+	ConcurrencyLimitConfig struct {
+		// 同时处理的最大请求数
+		MaxInFlight int
+
+		// 等待队列长度，为0时超出并发数立即拒绝
+		MaxQueue int
+
+		// 排队的最长等待时间，单位毫秒
+		QueueTimeoutMS int64
+
+		// 是否按路由分别限制，否则挂载点下的所有路由共享限额；
+		// 按路由限制时须挂载在路由或路由组上，全局挂载时无法确定路由，请求将被拒绝
+		PerRoute bool
+
+		// 拒绝时返回的状态码，429或503
+		RejectStatus int
+
+		// 拒绝时建议客户端重试的等待时长，单位秒
+		RetryAfter int
+	}
+
+	// 信号量与排队计数
+	bulkhead struct {
+		sem     chan struct{}
+		waiting int32
+	}
+)
+
+// 并发限制中间件：额度属于挂载的中间件实例(或按路由划分)，不计入运行时统计；
+// 需要多个挂载点共享额度或按配置调整时使用Bulkhead工作池
+var ConcurrencyLimit = ApiMiddleware{
+	Name: "并发限制",
+	Desc: "限制路由或路由组的同时处理请求数(可排队等待)，过载时返回429/503，避免单个重负载接口拖垮整个应用",
+	Config: ConcurrencyLimitConfig{
+		MaxInFlight:    100,
+		MaxQueue:       100,
+		QueueTimeoutMS: 1000,
+		PerRoute:       false,
+		RejectStatus:   http.StatusServiceUnavailable,
+		RetryAfter:     1,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(ConcurrencyLimitConfig)
+		if config.MaxInFlight <= 0 {
+			config.MaxInFlight = 100
+		}
+		if config.RejectStatus != http.StatusTooManyRequests {
+			config.RejectStatus = http.StatusServiceUnavailable
+		}
+		timeout := time.Duration(config.QueueTimeoutMS) * time.Millisecond
+		retryAfter := strconv.Itoa(config.RetryAfter)
+
+		shared := newBulkhead(config.MaxInFlight)
+		var (
+			routes    = map[string]*bulkhead{}
+			routesMux sync.Mutex
+		)
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				b := shared
+				if config.PerRoute {
+					if c.path == "" {
+						return NewHTTPError(http.StatusInternalServerError, "ConcurrencyLimit: PerRoute requires mounting on a route or group")
+					}
+					routesMux.Lock()
+					b = routes[c.path]
+					if b == nil {
+						b = newBulkhead(config.MaxInFlight)
+						routes[c.path] = b
+					}
+					routesMux.Unlock()
+				}
+				if !b.acquire(config.MaxQueue, timeout) {
+					if config.RetryAfter > 0 {
+						c.response.Header().Set("Retry-After", retryAfter)
+					}
+					return NewHTTPError(config.RejectStatus)
+				}
+				defer b.release()
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+func newBulkhead(size int) *bulkhead {
+	return &bulkhead{sem: make(chan struct{}, size)}
+}
+
+// 获取处理许可，队列已满或等待超时时返回false
+func (b *bulkhead) acquire(maxQueue int, timeout time.Duration) bool {
+	select {
+	case b.sem <- struct{}{}:
+		return true
+	default:
+	}
+	if maxQueue <= 0 {
+		return false
+	}
+	if atomic.AddInt32(&b.waiting, 1) > int32(maxQueue) {
+		atomic.AddInt32(&b.waiting, -1)
+		return false
+	}
+	defer atomic.AddInt32(&b.waiting, -1)
+	if timeout <= 0 {
+		b.sem <- struct{}{}
+		return true
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case b.sem <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.sem
+}
@@ -0,0 +1,95 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	newHandler := func(config ConcurrencyLimitConfig) (HandlerFunc, chan struct{}, chan struct{}) {
+		started, release := make(chan struct{}), make(chan struct{})
+		h := ConcurrencyLimit.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+			switch c.Request().Header.Get("X-Mode") {
+			case "block":
+				started <- struct{}{}
+				<-release
+			case "panic":
+				panic("boom")
+			}
+			return nil
+		})
+		return h, started, release
+	}
+	do := func(h HandlerFunc, mode string) (*http.Response, error) {
+		req, _ := http.NewRequest(GET, "/", nil)
+		req.Header.Set("X-Mode", mode)
+		c, rec := testContext(req)
+		defer c.free()
+		err := h(c)
+		return rec.Result(), err
+	}
+	code := func(err error) int {
+		if he, ok := err.(*HTTPError); ok {
+			return he.Code
+		}
+		return 0
+	}
+
+	// 达到并发上限且不排队时立即拒绝
+	h, started, release := newHandler(ConcurrencyLimitConfig{MaxInFlight: 1, RejectStatus: http.StatusTooManyRequests, RetryAfter: 3})
+	done := make(chan error)
+	go func() {
+		_, err := do(h, "block")
+		done <- err
+	}()
+	<-started
+	resp, err := do(h, "")
+	if code(err) != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "3" {
+		t.Errorf("request at capacity = %v, Retry-After %q", err, resp.Header.Get("Retry-After"))
+	}
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err = do(h, ""); err != nil {
+		t.Errorf("request after release = %v", err)
+	}
+
+	// 排队超时后拒绝
+	h, started, release = newHandler(ConcurrencyLimitConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeoutMS: 50})
+	go func() {
+		_, err := do(h, "block")
+		done <- err
+	}()
+	<-started
+	start := time.Now()
+	if _, err = do(h, ""); code(err) != http.StatusServiceUnavailable {
+		t.Errorf("queued request = %v, want 503", err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("rejected after %v, before the queue timeout", d)
+	}
+	close(release)
+	<-done
+
+	// 处理函数恐慌时释放许可
+	h, _, _ = newHandler(ConcurrencyLimitConfig{MaxInFlight: 1})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic not propagated")
+			}
+		}()
+		do(h, "panic")
+	}()
+	if _, err = do(h, ""); err != nil {
+		t.Errorf("request after panic = %v", err)
+	}
+
+	// 按路由限制时须能确定路由
+	h, _, _ = newHandler(ConcurrencyLimitConfig{MaxInFlight: 1, PerRoute: true})
+	if _, err = do(h, ""); code(err) != http.StatusInternalServerError {
+		t.Errorf("PerRoute without route = %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package lessgo
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 以内容的哈希生成强校验ETag，适用于可低成本计算摘要的内容(如版本号、更新时间拼接的串)
+func ETag(b []byte) string {
+	sum := sha1.Sum(b)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+}
+
+// 设置ETag与Last-Modified响应头，并与GET/HEAD请求的If-None-Match、If-Modified-Since比较，
+// 客户端缓存仍有效时响应304并返回true，此时操作应直接返回，如：
+//
+//	if c.NotModified(ETag([]byte(article.Version)), article.Updated) {
+//		return nil
+//	}
+//	// 渲染页面等耗时操作
+//
+// etag可省略引号，以W/开头为弱校验；etag为空或lastModified为零值时不使用对应的校验
+func (c *Context) NotModified(etag string, lastModified time.Time) bool {
+	etag = quoteETag(etag)
+	header := c.response.Header()
+	if etag != "" {
+		header.Set(HeaderETag, etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set(HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.request.Method != GET && c.request.Method != HEAD {
+		return false
+	}
+	// If-None-Match存在时忽略If-Modified-Since
+	if inm := c.request.Header.Get(HeaderIfNoneMatch); inm != "" {
+		if etag == "" || !matchETag(inm, etag, true) {
+			return false
+		}
+	} else if lastModified.IsZero() || !notModifiedSince(c.request.Header.Get(HeaderIfModifiedSince), lastModified) {
+		return false
+	}
+	header.Del(HeaderContentType)
+	header.Del(HeaderContentLength)
+	c.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// 与修改请求(如PUT、DELETE)的If-Match、If-Unmodified-Since比较当前资源的校验值，
+// 资源已被他人修改时响应412并返回true，用于乐观并发控制，如：
+//
+//	if c.PreconditionFailed(ETag([]byte(doc.Version)), doc.Updated) {
+//		return nil
+//	}
+func (c *Context) PreconditionFailed(etag string, lastModified time.Time) bool {
+	etag = quoteETag(etag)
+	failed := false
+	if im := c.request.Header.Get(HeaderIfMatch); im != "" {
+		failed = etag == "" || !matchETag(im, etag, false)
+	} else if ius := c.request.Header.Get(HeaderIfUnmodifiedSince); ius != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ius)
+		failed = err == nil && lastModified.Truncate(time.Second).After(t)
+	}
+	if failed {
+		c.NoContent(http.StatusPreconditionFailed)
+	}
+	return failed
+}
+
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return `W/"` + etag[2:] + `"`
+	}
+	return `"` + etag + `"`
+}
+
+// 判断请求头中以逗号分隔的ETag列表是否包含etag，weak为true时忽略W/前缀
+func matchETag(list, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		} else if strings.HasPrefix(v, "W/") {
+			continue
+		}
+		if v == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// 资源在If-Modified-Since之后是否未修改，Last-Modified精确到秒
+func notModifiedSince(ims string, lastModified time.Time) bool {
+	if ims == "" {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	return err == nil && !lastModified.Truncate(time.Second).After(t)
+}
@@ -0,0 +1,74 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	updated := time.Date(2016, 5, 1, 10, 0, 0, 500, time.UTC)
+	etag := ETag([]byte("v1"))
+	for _, test := range []struct {
+		method string
+		header http.Header
+		etag   string
+		want   bool
+	}{
+		{GET, nil, etag, false},
+		{GET, http.Header{HeaderIfNoneMatch: {etag}}, etag, true},
+		{HEAD, http.Header{HeaderIfNoneMatch: {`"x", W/` + etag}}, etag, true},
+		{GET, http.Header{HeaderIfNoneMatch: {"*"}}, etag, true},
+		{GET, http.Header{HeaderIfNoneMatch: {`"x"`}, HeaderIfModifiedSince: {updated.Format(http.TimeFormat)}}, etag, false},
+		{GET, http.Header{HeaderIfModifiedSince: {updated.Format(http.TimeFormat)}}, etag, true},
+		{GET, http.Header{HeaderIfModifiedSince: {updated.Add(-time.Second).Format(http.TimeFormat)}}, etag, false},
+		{GET, http.Header{HeaderIfNoneMatch: {`"v1"`}}, "v1", true},
+		{GET, http.Header{HeaderIfNoneMatch: {`"v1"`}}, "W/v1", true},
+		{POST, http.Header{HeaderIfNoneMatch: {etag}}, etag, false},
+	} {
+		req, _ := http.NewRequest(test.method, "/", nil)
+		for k, v := range test.header {
+			req.Header[k] = v
+		}
+		c, rec := testContext(req)
+		got := c.NotModified(test.etag, updated)
+		c.free()
+		if got != test.want {
+			t.Errorf("%s %v with %s = %v", test.method, test.header, test.etag, got)
+		}
+		if got && rec.Code != http.StatusNotModified {
+			t.Errorf("status = %d", rec.Code)
+		}
+		if rec.Header().Get(HeaderETag) != quoteETag(test.etag) || rec.Header().Get(HeaderLastModified) != "Sun, 01 May 2016 10:00:00 GMT" {
+			t.Errorf("validators = %v", rec.Header())
+		}
+	}
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	updated := time.Date(2016, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		header http.Header
+		etag   string
+		want   bool
+	}{
+		{nil, `"v2"`, false},
+		{http.Header{HeaderIfMatch: {`"v1"`}}, `"v2"`, true},
+		{http.Header{HeaderIfMatch: {`"v1", "v2"`}}, `"v2"`, false},
+		{http.Header{HeaderIfMatch: {`W/"v2"`}}, `"v2"`, true},
+		{http.Header{HeaderIfMatch: {"*"}}, `"v2"`, false},
+		{http.Header{HeaderIfUnmodifiedSince: {updated.Format(http.TimeFormat)}}, "", false},
+		{http.Header{HeaderIfUnmodifiedSince: {updated.Add(-time.Hour).Format(http.TimeFormat)}}, "", true},
+	} {
+		req, _ := http.NewRequest(PUT, "/", nil)
+		for k, v := range test.header {
+			req.Header[k] = v
+		}
+		c, rec := testContext(req)
+		got := c.PreconditionFailed(test.etag, updated)
+		c.free()
+		if got != test.want || got && rec.Code != http.StatusPreconditionFailed {
+			t.Errorf("%v with %s = %v, %d", test.header, test.etag, got, rec.Code)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package lessgo
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 
 	confpkg "github.com/lessgo/lessgo/config"
@@ -14,15 +17,21 @@ import (
 type (
 	// Config is the main struct for Config
 	config struct {
-		AppName     string // Application name
-		Info        Info   // Application info
-		Debug       bool   // enable/disable debug mode.
-		CrossDomain bool
-		MaxMemoryMB int64 // 文件上传默认内存缓存大小，单位MB
-		Listen      Listen
-		Session     SessionConfig
-		Log         LogConfig
-		FileCache   FileCacheConfig
+		AppName      string // Application name
+		Info         Info   // Application info
+		Debug        bool   // enable/disable debug mode.
+		CrossDomain  bool
+		Maintenance  bool   // 启动时是否处于维护模式
+		MaxMemoryMB  int64  // 文件上传默认内存缓存大小，单位MB
+		ReloadSecond int    // 配置热加载的检查间隔，单位秒，0为关闭
+		Profile      string // 运行环境，如dev、test、staging、prod，将加载对应的app.<profile>.yaml等覆盖配置
+		SecureKeys   string // 应用密钥环，形如"2:secret2,1:secret1"，首个密钥用于签发，其余仅用于验证，见SecureKeys()
+		Listen       Listen
+		Session      SessionConfig
+		Log          LogConfig
+		FileCache    FileCacheConfig
+		Runtime      RuntimeConfig
+		HTTPClient   HTTPClientConfig
 	}
 	Info struct {
 		Version           string
@@ -41,6 +50,18 @@ type (
 		EnableHTTPS   bool
 		HTTPSKeyFile  string
 		HTTPSCertFile string
+		// HTTPS会话票据密钥的轮换间隔，单位秒，0为使用Go默认的(各实例独立的)密钥；密钥存储见SetTicketKeyStore
+		TicketKeyRotateSeconds int64
+		OCSPStapling           bool // 为HTTPS证书获取并定期刷新OCSP响应，握手时随证书发送
+		StrictParsing          bool // 严格解析请求，拒绝可能用于请求走私的请求(如同时含Content-Length与Transfer-Encoding、折行或含NUL的请求头、请求头过多)
+		// 请求URI与请求头的限制，0为不限制，超出时分别响应414与431，见RequestLimits
+		MaxURIBytes    int
+		MaxHeaderCount int
+		MaxHeaderBytes int
+		// 退出时等待进行中的请求完成、连接关闭的最长秒数，超时后强制关闭连接，<=0为不限
+		DrainTimeout int64
+		// 退出期间关闭空闲的保持连接，并在响应中设置Connection: close，使客户端改连其他实例
+		DrainConnClose bool
 	}
 	// SessionConfig holds session related config
 	SessionConfig struct {
@@ -59,14 +80,34 @@ type (
 
 	// LogConfig holds Log related config
 	LogConfig struct {
-		Level     int
-		AsyncChan int64
+		Level            int
+		AsyncChan        int64
+		AsyncDrop        bool   // 异步日志通道已满时丢弃最早的日志而不阻塞请求
+		SampleFirst      int    // 日志采样：每秒内同一条日志先输出SampleFirst条，为0时不采样
+		SampleThereafter int    // 日志采样：超出SampleFirst后每SampleThereafter条输出1条
+		Syslog           string // 同时输出到syslog，"local"为本机，远程如"udp://10.0.0.1:514"，为空时不启用
+		Journald         bool   // 同时输出到systemd-journald
+		ConsoleFormat    string // 控制台日志格式：text(默认)、json、logfmt、pretty(彩色，适合开发)
+		FileFormat       string // 文件日志格式：text(默认)、json、logfmt
 	}
 	FileCacheConfig struct {
 		CacheSecond       int64 // 静态资源缓存监测频率与缓存动态释放的最大时长，单位秒，默认600秒
 		SingleFileAllowMB int64 // 允许的最大文件，单位MB
 		MaxCapMB          int64 // 最大缓存总量，单位MB
 	}
+	RuntimeConfig struct {
+		MaxProcs  int   // GOMAXPROCS，0为自动：容器中按cgroup CPU配额设置，否则为CPU核数
+		GCPercent int   // GOGC，0为不修改(默认100或GOGC环境变量)，小于0为关闭GC
+		BallastMB int64 // GC压舱物大小，单位MB，0为不使用
+	}
+	// HTTPClientConfig 默认出站HTTP客户端(见GetHTTPClient("default"))的配置
+	HTTPClientConfig struct {
+		Timeout             int64 // 请求超时(含重试与读取响应体)，单位秒，默认10秒
+		MaxIdleConnsPerHost int   // 每个主机保持的空闲连接数，默认32
+		MaxConnsPerHost     int   // 每个主机同时进行的请求数上限，0为不限
+		Retries             int   // 幂等请求遇连接错误或502、503、504时的重试次数，0为不重试
+		RetryBackoffMs      int64 // 首次重试前的等待时长，此后逐次加倍，单位毫秒，默认100毫秒
+	}
 )
 
 // 项目固定目录文件名称
@@ -115,15 +156,24 @@ func newConfig() *config {
 		},
 		Debug:       true,
 		CrossDomain: false,
+		Maintenance: false,
 		MaxMemoryMB: 64, // 64MB
 		Listen: Listen{
-			Graceful:      false,
-			Address:       "0.0.0.0:8080",
-			ReadTimeout:   0,
-			WriteTimeout:  0,
-			EnableHTTPS:   false,
-			HTTPSCertFile: "",
-			HTTPSKeyFile:  "",
+			Graceful:               false,
+			Address:                "0.0.0.0:8080",
+			ReadTimeout:            0,
+			WriteTimeout:           0,
+			EnableHTTPS:            false,
+			HTTPSCertFile:          "",
+			HTTPSKeyFile:           "",
+			TicketKeyRotateSeconds: 0,
+			OCSPStapling:           false,
+			StrictParsing:          false,
+			MaxURIBytes:            0,
+			MaxHeaderCount:         0,
+			MaxHeaderBytes:         0,
+			DrainTimeout:           60,
+			DrainConnClose:         true,
 		},
 		Session: SessionConfig{
 			SessionOn:               false,
@@ -148,20 +198,108 @@ func newConfig() *config {
 			Level:     logs.DEBUG,
 			AsyncChan: 1000,
 		},
+		HTTPClient: HTTPClientConfig{
+			Timeout:             10,
+			MaxIdleConnsPerHost: 32,
+			RetryBackoffMs:      100,
+		},
 	}
 }
 
 func (this *config) LoadMainConfig() (err error) {
-	fname := APPCONFIG_FILE
-	iniconf, err := confpkg.NewConfig("ini", fname)
+	serr := this.readFiles()
+	err = this.writeMainConfig(APPCONFIG_FILE)
+
+	// 环境变量与命令行参数仅作用于本次运行，不写入app.config
+	ferr := this.readOverrides()
+
+	switch {
+	case err != nil:
+		return err
+	case serr != nil:
+		return serr
+	}
+	return ferr
+}
+
+// 读取app.config，结构化配置文件(YAML/TOML/JSON)优先于app.config
+func (this *config) readFiles() error {
+	resetConfigSecrets()
+	iniconf, err := confpkg.NewConfig("ini", APPCONFIG_FILE)
 	if err == nil {
-		os.Remove(fname)
-		ReadSingleConfig("system", this, iniconf)
-		ReadSingleConfig("filecache", &this.FileCache, iniconf)
-		ReadSingleConfig("info", &this.Info, iniconf)
-		ReadSingleConfig("listen", &this.Listen, iniconf)
-		ReadSingleConfig("log", &this.Log, iniconf)
-		ReadSingleConfig("session", &this.Session, iniconf)
+		this.readAll(iniconf)
+	}
+	sfname, serr := findStructuredConfig(CONFIG_DIR + "/app")
+	if err := this.readStructured(sfname); err != nil {
+		return err
+	}
+	// 运行环境可由以上配置、环境变量或命令行参数指定，其配置文件覆盖以上配置
+	if profile := this.overriddenProfile(); profile != "" {
+		pfname, perr := findStructuredConfig(CONFIG_DIR + "/app." + profile)
+		if err := this.readStructured(pfname); err != nil {
+			return err
+		}
+		if serr == nil {
+			serr = perr
+		}
+	}
+	return serr
+}
+
+func (this *config) readStructured(fname string) error {
+	if fname == "" {
+		return nil
+	}
+	data, err := readStructuredConfig(fname)
+	if err != nil {
+		return fmt.Errorf("Read %s failed: %v.", fname, err)
+	}
+	this.readAll(flattenConfig(data))
+	return nil
+}
+
+// 返回存在的结构化配置文件，依次为app.yaml等及运行环境对应的app.<profile>.yaml等
+func appConfigFiles(profile string) []string {
+	var fnames []string
+	if fname, _ := findStructuredConfig(CONFIG_DIR + "/app"); fname != "" {
+		fnames = append(fnames, fname)
+	}
+	if profile == "" {
+		return fnames
+	}
+	if fname, _ := findStructuredConfig(CONFIG_DIR + "/app." + profile); fname != "" {
+		fnames = append(fnames, fname)
+	}
+	return fnames
+}
+
+// 返回环境变量或命令行参数覆盖后的运行环境
+func (this *config) overriddenProfile() string {
+	profile := this.Profile
+	if p := envConfig(os.Environ()).String("system::profile"); p != "" {
+		profile = p
+	}
+	if fconf, _ := flagConfig(os.Args[1:]); fconf.String("system::profile") != "" {
+		profile = fconf.String("system::profile")
+	}
+	return profile
+}
+
+// 读取环境变量与命令行参数
+func (this *config) readOverrides() error {
+	this.readAll(envConfig(os.Environ()))
+	fconf, err := flagConfig(os.Args[1:])
+	this.readAll(fconf)
+	return err
+}
+
+// 将当前配置写入app.config，自动补填默认值，并保留应用自定义的段
+func (this *config) writeMainConfig(fname string) error {
+	var custom map[string]map[string]string
+	if old, err := confpkg.NewConfig("ini", fname); err == nil {
+		if ini, ok := old.(*confpkg.IniConfigContainer); ok {
+			custom = ini.GetAllSections()
+		}
 	}
 	os.MkdirAll(filepath.Dir(fname), 0777)
 	f, err := os.Create(fname)
@@ -169,20 +307,375 @@ func (this *config) LoadMainConfig() (err error) {
 		return err
 	}
 	f.Close()
-	iniconf, err = confpkg.NewConfig("ini", fname)
+	iniconf, err := confpkg.NewConfig("ini", fname)
 	if err != nil {
 		return err
 	}
-	WriteSingleConfig("system", this, iniconf)
-	WriteSingleConfig("filecache", &this.FileCache, iniconf)
-	WriteSingleConfig("info", &this.Info, iniconf)
-	WriteSingleConfig("listen", &this.Listen, iniconf)
-	WriteSingleConfig("log", &this.Log, iniconf)
-	WriteSingleConfig("session", &this.Session, iniconf)
-
+	for _, s := range this.sections() {
+		WriteSingleConfig(s.name, s.p, iniconf)
+		delete(custom, s.name)
+	}
+	for section, kvs := range custom {
+		for k, v := range kvs {
+			if section == confpkg.DefaultSection {
+				iniconf.Set(k, v)
+			} else {
+				iniconf.Set(section+"::"+k, v)
+			}
+		}
+	}
 	return iniconf.SaveConfigFile(fname)
 }
 
+func (this *config) readAll(conf confpkg.Configer) {
+	for _, s := range this.sections() {
+		ReadSingleConfig(s.name, s.p, conf)
+	}
+}
+
+type configSection struct {
+	name string
+	p    interface{}
+}
+
+func (this *config) sections() []configSection {
+	return []configSection{
+		{"system", this},
+		{"filecache", &this.FileCache},
+		{"httpclient", &this.HTTPClient},
+		{"info", &this.Info},
+		{"listen", &this.Listen},
+		{"log", &this.Log},
+		{"runtime", &this.Runtime},
+		{"session", &this.Session},
+	}
+}
+
+// 返回所有可配置的键(section::key)及其类型
+func configKeys() map[string]reflect.Kind {
+	keys := make(map[string]reflect.Kind)
+	for _, s := range newConfig().sections() {
+		pt := reflect.TypeOf(s.p).Elem()
+		for i := 0; i < pt.NumField(); i++ {
+			switch k := pt.Field(i).Type.Kind(); k {
+			case reflect.String, reflect.Int, reflect.Int64, reflect.Bool:
+				keys[getfullname(s.name, pt.Field(i).Name)] = k
+			}
+		}
+	}
+	return keys
+}
+
+// 环境变量覆盖配置的前缀，如LESSGO_LISTEN_ADDRESS对应listen::address，
+// system段的键省略段名，如LESSGO_DEBUG；键名中的'_'被忽略，如LESSGO_LOG_ASYNC_CHAN
+const ENV_PREFIX = "LESSGO_"
+
+// 从环境变量中读取配置，忽略无法识别的变量
+func envConfig(environ []string) confpkg.Configer {
+	conf := confpkg.NewFakeConfig()
+	keys := configKeys()
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, ENV_PREFIX) {
+			continue
+		}
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			continue
+		}
+		if key := lookupConfigKey(keys, kv[len(ENV_PREFIX):i], "_"); key != "" {
+			conf.Set(key, kv[i+1:])
+		}
+	}
+	return conf
+}
+
+// 命令行参数覆盖配置的前缀，如-lessgo.listen.address=:80，
+// system段的键省略段名，如-lessgo.debug=false；bool值的参数可省略值
+const FLAG_PREFIX = "lessgo."
+
+// 从命令行参数中读取配置，支持-name=value、-name value及--name形式，"--"之后的参数不做解析；
+// 含段名的未知参数留给Config.Bind绑定的应用配置
+func flagConfig(args []string) (confpkg.Configer, error) {
+	conf := confpkg.NewFakeConfig()
+	keys := configKeys()
+	var unknown []string
+	for _, f := range parseConfigFlags(args, func(name string) bool {
+		return keys[lookupConfigKey(keys, name, ".")] == reflect.Bool
+	}) {
+		key := lookupConfigKey(keys, f.name, ".")
+		if key == "" {
+			if !strings.Contains(f.name, ".") {
+				unknown = append(unknown, "-"+FLAG_PREFIX+f.name)
+			}
+			continue
+		}
+		conf.Set(key, f.value)
+	}
+	if len(unknown) > 0 {
+		return conf, fmt.Errorf("Unknown config flags: %s.", strings.Join(unknown, ", "))
+	}
+	return conf, nil
+}
+
+type configFlagArg struct {
+	name, value string
+}
+
+// 解析以FLAG_PREFIX开头的命令行参数，返回去掉前缀的名称及其值；
+// 省略值时，bool参数或后面紧跟另一参数时值为"true"
+func parseConfigFlags(args []string, isBool func(name string) bool) []configFlagArg {
+	var flags []configFlagArg
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if len(arg)-len(name) == 0 || len(arg)-len(name) > 2 || !strings.HasPrefix(name, FLAG_PREFIX) {
+			continue
+		}
+		name = name[len(FLAG_PREFIX):]
+		f := configFlagArg{name: name, value: "true"}
+		if j := strings.Index(name, "="); j >= 0 {
+			f.name, f.value = name[:j], name[j+1:]
+		} else if !isBool(name) && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			i++
+			f.value = args[i]
+		}
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// 将以sep分隔的名称转为已知的配置键，无法识别时返回空
+func lookupConfigKey(keys map[string]reflect.Kind, name, sep string) string {
+	if i := strings.Index(name, sep); i > 0 {
+		key := getfullname(name[:i], normalizeConfigKey(strings.Replace(name[i+1:], sep, "", -1)))
+		if _, ok := keys[key]; ok {
+			return key
+		}
+	}
+	key := getfullname("system", normalizeConfigKey(strings.Replace(name, sep, "", -1)))
+	if _, ok := keys[key]; ok {
+		return key
+	}
+	return ""
+}
+
+func init() {
+	// 在标准命令行参数中注册配置参数，以免应用调用flag.Parse()时报错，并出现在帮助信息中
+	for key, kind := range configKeys() {
+		name := FLAG_PREFIX + strings.TrimPrefix(strings.Replace(key, "::", ".", -1), "system.")
+		if flag.Lookup(name) == nil {
+			flag.Var(&configFlag{isBool: kind == reflect.Bool}, name, "override config "+key)
+		}
+	}
+}
+
+// 配置参数已在加载配置时解析，此处仅供flag包识别
+type configFlag struct {
+	value  string
+	isBool bool
+}
+
+func (f *configFlag) String() string     { return f.value }
+func (f *configFlag) Set(s string) error { f.value = s; return nil }
+func (f *configFlag) IsBoolFlag() bool   { return f.isBool }
+
+// 结构化配置文件的扩展名，按优先级从高到低排列
+var structuredConfigExts = []string{".yaml", ".yml", ".toml", ".json"}
+
+// 查找与base同名的结构化配置文件，存在多个时使用优先级最高者，并返回提示错误
+func findStructuredConfig(base string) (fname string, err error) {
+	var found []string
+	for _, ext := range structuredConfigExts {
+		if info, e := os.Stat(base + ext); e == nil && !info.IsDir() {
+			found = append(found, base+ext)
+		}
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	if len(found) > 1 {
+		err = fmt.Errorf("Found multiple config files %s, only %s is used.", strings.Join(found, ", "), found[0])
+	}
+	return found[0], err
+}
+
+// 按扩展名解析结构化配置文件
+func readStructuredConfig(fname string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return confpkg.Decode(filepath.Ext(fname), b)
+}
+
+// 将配置映射到应用自定义的结构体，设置默认值并校验取值，出错时返回所有无效的配置项；
+// 配置来源及其优先级与系统配置相同，如：
+//
+//	var conf struct {
+//		DB struct {
+//			Host string `validate:"required"`
+//			Port int    `default:"3306" validate:"min=1,max=65535"`
+//		}
+//	}
+//	err := lessgo.Config.Bind(&conf)
+//
+// 对应app.config中[db]段的host与port、环境变量LESSGO_DB_HOST或命令行参数-lessgo.db.host，
+// 标签的用法详见config.Bind；值中的${secret:name}由SetSecretProvider设置的密钥源解析
+func (this *config) Bind(v interface{}) error {
+	src, serrs := bindSource()
+	err := confpkg.Bind(src, v)
+	if len(serrs) == 0 {
+		return err
+	}
+	failed := make(map[string]bool, len(serrs))
+	for _, fe := range serrs {
+		failed[fe.Key] = true
+	}
+	if errs, ok := err.(confpkg.BindErrors); ok {
+		for _, fe := range errs {
+			if !failed[fe.Key] && !failed[normalizeConfigKey(fe.Key)] {
+				serrs = append(serrs, fe)
+			}
+		}
+	}
+	return serrs
+}
+
+// 汇总所有配置来源的键值，供Bind使用
+func bindSource() (confpkg.Configer, confpkg.BindErrors) {
+	values, errs := bindValues()
+	conf := confpkg.NewFakeConfig()
+	for k, v := range values {
+		conf.Set(k, v)
+	}
+	return conf, errs
+}
+
+// 汇总所有配置来源的键值，形如section::key，键名去掉'_'与'-'并转为小写，并解析其中的密钥引用
+func bindValues() (map[string]string, confpkg.BindErrors) {
+	values := make(map[string]string)
+	var errs confpkg.BindErrors
+	set := func(section, key, value string) {
+		key = normalizeConfigKey(key)
+		if section != "" && section != confpkg.DefaultSection {
+			key = normalizeConfigKey(section) + "::" + key
+		}
+		v, err := resolveSecrets(value)
+		if err != nil {
+			errs = append(errs, &confpkg.FieldError{Key: key, Msg: "resolve secret failed: " + err.Error()})
+			return
+		}
+		values[key] = v
+	}
+	setData := func(data map[string]interface{}) {
+		for k, v := range data {
+			if section, ok := v.(map[string]interface{}); ok {
+				for name, val := range section {
+					if s, ok := configValueString(val); ok {
+						set(k, name, s)
+					}
+				}
+			} else if s, ok := configValueString(v); ok {
+				set("", k, s)
+			}
+		}
+	}
+
+	if iniconf, err := confpkg.NewConfig("ini", APPCONFIG_FILE); err == nil {
+		if ini, ok := iniconf.(*confpkg.IniConfigContainer); ok {
+			for section, kvs := range ini.GetAllSections() {
+				for k, v := range kvs {
+					set(section, k, v)
+				}
+			}
+		}
+	}
+	for _, fname := range appConfigFiles(Config.Profile) {
+		if data, err := readStructuredConfig(fname); err == nil {
+			setData(data)
+		}
+	}
+	configReloadLock.Lock()
+	setData(configRemoteData)
+	configReloadLock.Unlock()
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if !strings.HasPrefix(kv, ENV_PREFIX) || i < 0 {
+			continue
+		}
+		name := kv[len(ENV_PREFIX):i]
+		set("", name, kv[i+1:])
+		if j := strings.Index(name, "_"); j > 0 {
+			set(name[:j], name[j+1:], kv[i+1:])
+		}
+	}
+	for _, f := range parseConfigFlags(os.Args[1:], func(string) bool { return false }) {
+		if j := strings.Index(f.name, "."); j > 0 {
+			set(f.name[:j], f.name[j+1:], f.value)
+		} else {
+			set("", f.name, f.value)
+		}
+	}
+	return values, errs
+}
+
+// 将结构化配置转为section::key形式的扁平配置，与app.config使用相同的结构：
+// 顶层的表对应段名，顶层的标量值归入system段；
+// 键名不区分大小写，并忽略其中的'_'与'-'，如max_memory_mb即maxmemorymb
+func flattenConfig(data map[string]interface{}) confpkg.Configer {
+	conf := confpkg.NewFakeConfig()
+	for k, v := range data {
+		section, ok := v.(map[string]interface{})
+		if !ok {
+			if s, ok := configValueString(v); ok {
+				conf.Set("system::"+normalizeConfigKey(k), s)
+			}
+			continue
+		}
+		for name, val := range section {
+			if s, ok := configValueString(val); ok {
+				conf.Set(normalizeConfigKey(k)+"::"+normalizeConfigKey(name), s)
+			}
+		}
+	}
+	return conf
+}
+
+func normalizeConfigKey(k string) string {
+	k = strings.Replace(k, "_", "", -1)
+	return strings.ToLower(strings.Replace(k, "-", "", -1))
+}
+
+func configValueString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case bool:
+		return strconv.FormatBool(x), true
+	case int:
+		return strconv.Itoa(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case []interface{}:
+		// 与Strings()一致，以';'连接
+		ss := make([]string, 0, len(x))
+		for _, e := range x {
+			s, ok := configValueString(e)
+			if !ok {
+				return "", false
+			}
+			ss = append(ss, s)
+		}
+		return strings.Join(ss, ";"), true
+	}
+	return "", false
+}
+
 func ReadSingleConfig(section string, p interface{}, iniconf confpkg.Configer) {
 	pt := reflect.TypeOf(p)
 	if pt.Kind() != reflect.Ptr {
@@ -204,6 +697,9 @@ func ReadSingleConfig(section string, p interface{}, iniconf confpkg.Configer) {
 		switch pf.Kind() {
 		case reflect.String:
 			str := iniconf.DefaultString(fullname, pf.String())
+			if str != pf.String() {
+				str = readConfigSecret(fullname, str)
+			}
 			switch name {
 			case "TableFix", "ColumnFix":
 				pf.SetString(strings.ToLower(str))
@@ -219,12 +715,12 @@ func ReadSingleConfig(section string, p interface{}, iniconf confpkg.Configer) {
 					pf.SetInt(num)
 				}
 			case "filecache::cachesecond", "filecache::singlefileallowmb", "filecache::maxcapmb",
-				"listen::readtimeout", "listen::writetimeout",
+				"listen::readtimeout", "listen::writetimeout", "listen::ticketkeyrotateseconds",
 				"session::sessiongcmaxlifetime", "session::sessioncookielifetime":
 				if num > 0 {
 					pf.SetInt(num)
 				}
-			case "log::asyncchan":
+			case "log::asyncchan", "listen::maxuribytes", "listen::maxheadercount", "listen::maxheaderbytes":
 				if num >= 0 {
 					pf.SetInt(num)
 				}
@@ -264,16 +760,24 @@ func WriteSingleConfig(section string, p interface{}, iniconf confpkg.Configer)
 		fullname := getfullname(section, pt.Field(i).Name)
 		switch pf.Kind() {
 		case reflect.String, reflect.Int, reflect.Int64, reflect.Bool:
-			switch fullname {
-			case "log::level":
-				iniconf.Set(fullname, logLevelString(int(pf.Int())))
-			default:
-				iniconf.Set(fullname, fmt.Sprint(pf.Interface()))
+			// 引用密钥的配置项写入原始的引用，密钥不落盘
+			if ref, ok := configSecretRef(fullname); ok {
+				iniconf.Set(fullname, ref)
+				continue
 			}
+			iniconf.Set(fullname, configFieldString(fullname, pf))
 		}
 	}
 }
 
+// 配置项写入配置文件时的字符串形式
+func configFieldString(fullname string, v reflect.Value) string {
+	if fullname == "log::level" {
+		return logLevelString(int(v.Int()))
+	}
+	return fmt.Sprint(v.Interface())
+}
+
 // section name and key name case insensitive
 func getfullname(section, name string) string {
 	if section == "" {
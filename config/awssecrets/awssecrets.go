@@ -0,0 +1,213 @@
+// Package awssecrets provides a config.SecretProvider reading secrets from
+// AWS Secrets Manager, signing requests with Signature Version 4, so the
+// AWS SDK is not required.
+//
+// A secret name is "secret-id" or "secret-id#key"; with a key, the secret
+// string is decoded as a json object and the value of the key is returned.
+//
+//	lessgo.SetSecretProvider(awssecrets.FromEnv("us-east-1"), 5*time.Minute)
+package awssecrets
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provider reads secrets of the account in Region.
+type Provider struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // for temporary credentials, optional
+	Endpoint        string // https://secretsmanager.<region>.amazonaws.com by default
+	Client          *http.Client
+
+	now func() time.Time
+}
+
+// FromEnv returns a Provider with the credentials in the environment variables
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func FromEnv(region string) *Provider {
+	return &Provider{
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// GetSecret implements config.SecretProvider.
+func (p *Provider) GetSecret(name string) (string, error) {
+	id, key := name, ""
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		id, key = name[:i], name[i+1:]
+	}
+	body, _ := json.Marshal(map[string]string{"SecretId": id})
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + p.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.sign(req, body, "secretsmanager")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("awssecrets: get %s: %s %s", id, resp.Status, bytes.TrimSpace(b))
+	}
+	var out struct {
+		SecretString *string
+	}
+	if err = json.Unmarshal(b, &out); err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("awssecrets: %s has no secret string", id)
+	}
+	if key == "" {
+		return *out.SecretString, nil
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal([]byte(*out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("awssecrets: %s is not a json object", id)
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("awssecrets: key %q not found in %s", key, id)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	vb, _ := json.Marshal(v)
+	return string(vb), nil
+}
+
+// sign adds the Signature Version 4 headers to req.
+func (p *Provider) sign(req *http.Request, body []byte, service string) {
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.SessionToken)
+	}
+	payloadHash := sha256Hex(body)
+
+	// canonical headers: host and all x-amz-*/content-type headers, sorted
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "content-type" || strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + p.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+p.SecretAccessKey), date)
+	key = hmacSHA256(key, p.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+p.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(req *http.Request) string {
+	q := req.URL.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := q[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything except the unreserved characters.
+func awsEscape(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
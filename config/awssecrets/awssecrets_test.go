@@ -0,0 +1,59 @@
+package awssecrets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixedNow() time.Time {
+	return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+}
+
+// get-vanilla from the AWS Signature Version 4 test suite.
+func TestSign(t *testing.T) {
+	p := &Provider{Region: "us-east-1", AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", now: fixedNow}
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	p.sign(req, nil, "service")
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGetSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			r.Header.Get("X-Amz-Security-Token") != "token" {
+			t.Errorf("unexpected request headers %v", r.Header)
+		}
+		switch string(body) {
+		case `{"SecretId":"prod/db"}`:
+			w.Write([]byte(`{"Name":"prod/db","SecretString":"{\"password\":\"s3cret\",\"port\":5432}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := &Provider{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "SECRET", SessionToken: "token", Endpoint: srv.URL}
+	if v, err := p.GetSecret("prod/db#password"); err != nil || v != "s3cret" {
+		t.Errorf("GetSecret(#password) = %q, %v", v, err)
+	}
+	if v, err := p.GetSecret("prod/db#port"); err != nil || v != "5432" {
+		t.Errorf("GetSecret(#port) = %q, %v", v, err)
+	}
+	if v, err := p.GetSecret("prod/db"); err != nil || !strings.Contains(v, "s3cret") {
+		t.Errorf("GetSecret() = %q, %v", v, err)
+	}
+	if _, err := p.GetSecret("missing"); err == nil {
+		t.Error("GetSecret(missing) should fail")
+	}
+}
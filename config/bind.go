@@ -0,0 +1,283 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes an invalid config key found by Bind.
+type FieldError struct {
+	Key   string
+	Value string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return e.Key + ": " + e.Msg
+	}
+	return fmt.Sprintf("%s: %s, got %q", e.Key, e.Msg, e.Value)
+}
+
+// BindErrors lists every invalid key found by Bind.
+type BindErrors []*FieldError
+
+func (e BindErrors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("config: %d invalid key(s):", len(e)))
+	for _, fe := range e {
+		lines = append(lines, "  "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind maps the config onto the struct pointed to by v, applying defaults and
+// validating values. It returns BindErrors listing every invalid key.
+//
+// Fields of struct type are sections and the others are keys, e.g.
+//
+//	type AppConfig struct {
+//		Name string `default:"demo"`
+//		DB   struct {
+//			Host    string        `validate:"required"`
+//			Port    int           `default:"3306" validate:"min=1,max=65535"`
+//			Driver  string        `default:"mysql" validate:"oneof=mysql|postgres"`
+//			Timeout time.Duration `default:"5s"`
+//		}
+//	}
+//
+// binds the keys name, db::host, db::port, db::driver and db::timeout.
+// A key is the lower-cased field name unless set by the `config` tag, "-" skips
+// the field; it's looked up as is and then with '_' and '-' removed.
+// A missing key takes the `default` tag, or keeps the current field value.
+//
+// Supported kinds are string, bool, ints, uints, floats, time.Duration("5s",
+// or a number of seconds) and []string(separated by ';' or ',').
+// The `validate` tag holds comma separated rules: required, min=n and max=n
+// (the value of numbers and durations, the length of strings and slices),
+// oneof=a|b|c.
+func Bind(c Configer, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("config: Bind needs a non-nil pointer to struct")
+	}
+	var errs BindErrors
+	bindStruct(c, "", rv.Elem(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func bindStruct(c Configer, prefix string, rv reflect.Value, errs *BindErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		if sf.PkgPath != "" || !fv.CanSet() {
+			continue
+		}
+		name := sf.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		key := prefix + name
+
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			bindStruct(c, key+"::", fv, errs)
+			continue
+		}
+
+		raw := lookup(c, key)
+		if raw == "" {
+			raw = sf.Tag.Get("default")
+		}
+		rules := strings.Split(sf.Tag.Get("validate"), ",")
+		if raw == "" {
+			if hasRule(rules, "required") {
+				*errs = append(*errs, &FieldError{Key: key, Msg: "is required"})
+			}
+			continue
+		}
+		if err := setValue(fv, raw); err != nil {
+			*errs = append(*errs, &FieldError{Key: key, Value: raw, Msg: err.Error()})
+			continue
+		}
+		if msg := validate(fv, raw, rules); msg != "" {
+			*errs = append(*errs, &FieldError{Key: key, Value: raw, Msg: msg})
+		}
+	}
+}
+
+// lookup returns the value of key as a string, empty if missing.
+func lookup(c Configer, key string) string {
+	keys := []string{key}
+	if k := strings.NewReplacer("_", "", "-", "").Replace(key); k != key {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if s := c.String(k); s != "" {
+			return s
+		}
+		if v, err := c.DIY(k); err == nil {
+			switch x := v.(type) {
+			case bool, int, int64, string:
+				if s := fmt.Sprint(x); s != "" {
+					return s
+				}
+			case float64:
+				return strconv.FormatFloat(x, 'f', -1, 64)
+			case []interface{}:
+				ss := make([]string, len(x))
+				for i := range x {
+					ss[i] = fmt.Sprint(x[i])
+				}
+				return strings.Join(ss, ";")
+			}
+		}
+	}
+	return ""
+}
+
+func setValue(fv reflect.Value, raw string) error {
+	if fv.Type() == durationType {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return errors.New("invalid duration")
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := ParseBool(raw)
+		if err != nil {
+			return errors.New("invalid bool")
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New("invalid integer")
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New("invalid unsigned integer")
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return errors.New("invalid number")
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == ',' })
+		s := reflect.MakeSlice(fv.Type(), 0, len(parts))
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				s = reflect.Append(s, reflect.ValueOf(p).Convert(fv.Type().Elem()))
+			}
+		}
+		fv.Set(s)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// parseDuration accepts a Go duration or a number of seconds.
+func parseDuration(s string) (time.Duration, error) {
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(n * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func hasRule(rules []string, name string) bool {
+	for _, r := range rules {
+		if strings.TrimSpace(r) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the field against the rules, returns a message if failed.
+func validate(fv reflect.Value, raw string, rules []string) string {
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		i := strings.Index(rule, "=")
+		if i < 0 {
+			continue
+		}
+		name, arg := rule[:i], rule[i+1:]
+		switch name {
+		case "oneof":
+			ok := false
+			for _, opt := range strings.Split(arg, "|") {
+				if raw == opt {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return "must be one of " + strings.Replace(arg, "|", ", ", -1)
+			}
+		case "min", "max":
+			n, limit, err := measure(fv, arg)
+			if err != nil {
+				return fmt.Sprintf("invalid rule %q", rule)
+			}
+			if name == "min" && n < limit {
+				return "must be at least " + arg
+			}
+			if name == "max" && n > limit {
+				return "must be at most " + arg
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the comparable size of the field and the parsed limit.
+func measure(fv reflect.Value, arg string) (n, limit float64, err error) {
+	if fv.Type() == durationType {
+		d, err := parseDuration(arg)
+		return float64(fv.Int()), float64(d), err
+	}
+	limit, err = strconv.ParseFloat(arg, 64)
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice:
+		n = float64(fv.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = fv.Float()
+	}
+	return
+}
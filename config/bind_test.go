@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindDB struct {
+	Host    string        `validate:"required"`
+	Port    int           `default:"3306" validate:"min=1,max=65535"`
+	Driver  string        `default:"mysql" validate:"oneof=mysql|postgres"`
+	Timeout time.Duration `default:"5s" validate:"max=1m"`
+	MaxConn uint          `config:"max_conn"`
+}
+
+type bindApp struct {
+	Name    string `default:"demo"`
+	Debug   bool
+	Ratio   float64
+	Tags    []string
+	Secret  string `config:"-"`
+	DB      bindDB
+	Replica *bindDB `config:"replica"`
+}
+
+func TestBind(t *testing.T) {
+	conf, err := NewConfigData("json", []byte(`{
+		"debug": true,
+		"ratio": 0.5,
+		"tags": ["a", "b"],
+		"secret": "leak",
+		"db": {"host": "10.0.0.1", "timeout": 2, "maxconn": 20},
+		"replica": {"host": "10.0.0.2", "port": "5432", "driver": "postgres"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var app bindApp
+	if err := Bind(conf, &app); err != nil {
+		t.Fatal(err)
+	}
+	want := bindApp{
+		Name:    "demo",
+		Debug:   true,
+		Ratio:   0.5,
+		Tags:    []string{"a", "b"},
+		DB:      bindDB{Host: "10.0.0.1", Port: 3306, Driver: "mysql", Timeout: 2 * time.Second, MaxConn: 20},
+		Replica: &bindDB{Host: "10.0.0.2", Port: 5432, Driver: "postgres", Timeout: 5 * time.Second},
+	}
+	if !reflect.DeepEqual(app, want) {
+		t.Errorf("Bind() =\n%+v\nwant\n%+v", app, want)
+	}
+
+	bad := NewFakeConfig()
+	bad.Set("debug", "maybe")
+	bad.Set("db::port", "70000")
+	bad.Set("db::driver", "sqlite")
+	bad.Set("db::timeout", "2m")
+	bad.Set("replica::host", "h")
+	err = Bind(bad, &bindApp{})
+	errs, ok := err.(BindErrors)
+	if !ok {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	var keys []string
+	for _, fe := range errs {
+		keys = append(keys, fe.Key)
+	}
+	if got := strings.Join(keys, ","); got != "debug,db::host,db::port,db::driver,db::timeout" {
+		t.Errorf("invalid keys = %s\n%v", got, err)
+	}
+	if Bind(bad, app) == nil {
+		t.Error("Bind() should reject a non-pointer")
+	}
+}
@@ -0,0 +1,134 @@
+// Package consul provides a config.Provider reading keys under a prefix from
+// the Consul KV store through its HTTP API, watched with blocking queries.
+//
+// Keys map to config entries relative to the prefix, e.g. with prefix
+// "lessgo/app", the key "lessgo/app/log/level" sets log::level.
+//
+//	lessgo.SetConfigProvider(consul.New("http://127.0.0.1:8500", "lessgo/app"))
+package consul
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/config"
+)
+
+// Provider reads and watches the keys under Prefix.
+type Provider struct {
+	Address string        // such as http://127.0.0.1:8500
+	Prefix  string        // key prefix without leading slash
+	Token   string        // ACL token, optional
+	Wait    time.Duration // max duration of a blocking query, 5m by default
+	Client  *http.Client
+
+	mu    sync.Mutex
+	index uint64
+}
+
+// New returns a Provider for the keys under prefix.
+func New(address, prefix string) *Provider {
+	return &Provider{Address: strings.TrimRight(address, "/"), Prefix: strings.Trim(prefix, "/")}
+}
+
+// Load reads all keys under the prefix.
+func (p *Provider) Load() (map[string]interface{}, error) {
+	kvs, index, err := p.get(0, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.index = index
+	p.mu.Unlock()
+	return config.KVMap(p.Prefix, kvs), nil
+}
+
+// Watch blocks until any key under the prefix is changed after the last Load.
+func (p *Provider) Watch(stop <-chan struct{}) error {
+	p.mu.Lock()
+	last := p.index
+	p.mu.Unlock()
+	for {
+		_, index, err := p.get(last, stop)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if err != nil {
+			return err
+		}
+		// an index going backwards(e.g. after a Consul restore) is a change too
+		if index != last {
+			return nil
+		}
+	}
+}
+
+func (p *Provider) get(index uint64, cancel <-chan struct{}) (map[string]string, uint64, error) {
+	q := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		wait := p.Wait
+		if wait <= 0 {
+			wait = 5 * time.Minute
+		}
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", strconv.Itoa(int(wait/time.Second))+"s")
+	}
+	req, err := http.NewRequest("GET", p.Address+"/v1/kv/"+p.Prefix+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.Token != "" {
+		req.Header.Set("X-Consul-Token", p.Token)
+	}
+	req.Cancel = cancel
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	kvs := make(map[string]string)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		// no keys under the prefix yet
+		return kvs, newIndex, nil
+	case http.StatusOK:
+	default:
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, 0, fmt.Errorf("consul: GET %s: %s %s", p.Prefix, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var items []struct {
+		Key   string
+		Value *string
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, 0, err
+	}
+	for _, item := range items {
+		if item.Value == nil {
+			// folder
+			continue
+		}
+		v, err := base64.StdEncoding.DecodeString(*item.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("consul: invalid value of %q", item.Key)
+		}
+		kvs[item.Key] = string(v)
+	}
+	return kvs, newIndex, nil
+}
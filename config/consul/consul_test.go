@@ -0,0 +1,62 @@
+package consul
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProvider(t *testing.T) {
+	var index int32 = 7
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/lessgo/app" || r.URL.Query().Get("recurse") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			t.Errorf("missing token")
+		}
+		cur := atomic.LoadInt32(&index)
+		if r.URL.Query().Get("index") == fmt.Sprint(cur) {
+			// blocking query: wait a little and return unchanged
+			time.Sleep(10 * time.Millisecond)
+		}
+		w.Header().Set("X-Consul-Index", fmt.Sprint(atomic.LoadInt32(&index)))
+		fmt.Fprintf(w, `[{"Key":"lessgo/app/log/","Value":null},{"Key":"lessgo/app/log/level","Value":%q}]`,
+			base64.StdEncoding.EncodeToString([]byte("warn")))
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL, "/lessgo/app/")
+	p.Token = "secret"
+	m, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"log": map[string]interface{}{"level": "warn"}}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Load() = %v, want %v", m, want)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Watch(make(chan struct{})) }()
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Watch returned before any change")
+	default:
+	}
+	atomic.StoreInt32(&index, 8)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not notice the change")
+	}
+}
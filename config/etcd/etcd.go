@@ -0,0 +1,173 @@
+// Package etcd provides a config.Provider reading keys under a prefix from
+// etcd v3 through its JSON gateway, so no client library is required.
+//
+// Keys map to config entries relative to the prefix, e.g. with prefix
+// "/lessgo/app", the key "/lessgo/app/log/level" sets log::level.
+//
+//	lessgo.SetConfigProvider(etcd.New("http://127.0.0.1:2379", "/lessgo/app"))
+package etcd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lessgo/lessgo/config"
+)
+
+// Provider reads and watches the keys under Prefix.
+type Provider struct {
+	Endpoint string // such as http://127.0.0.1:2379
+	Prefix   string
+	Client   *http.Client
+
+	mu       sync.Mutex
+	revision int64
+}
+
+// New returns a Provider for the keys under prefix.
+func New(endpoint, prefix string) *Provider {
+	return &Provider{Endpoint: strings.TrimRight(endpoint, "/"), Prefix: prefix}
+}
+
+type kv struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type header struct {
+	Revision string `json:"revision"`
+}
+
+// Load reads all keys under the prefix.
+func (p *Provider) Load() (map[string]interface{}, error) {
+	var resp struct {
+		Header header `json:"header"`
+		Kvs    []kv   `json:"kvs"`
+	}
+	body, err := p.post("/v3/kv/range", p.keyRange(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if err = json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	kvs := make(map[string]string, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		k, err1 := base64.StdEncoding.DecodeString(item.Key)
+		v, err2 := base64.StdEncoding.DecodeString(item.Value)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("etcd: invalid kv %q", item.Key)
+		}
+		kvs[string(k)] = string(v)
+	}
+	rev, _ := strconv.ParseInt(resp.Header.Revision, 10, 64)
+	p.mu.Lock()
+	p.revision = rev
+	p.mu.Unlock()
+	return config.KVMap(p.Prefix, kvs), nil
+}
+
+// Watch blocks until any key under the prefix is changed after the last Load.
+func (p *Provider) Watch(stop <-chan struct{}) error {
+	r := p.keyRange()
+	p.mu.Lock()
+	r["start_revision"] = strconv.FormatInt(p.revision+1, 10)
+	p.mu.Unlock()
+	body, err := p.post("/v3/watch", map[string]interface{}{"create_request": r}, stop)
+	if err != nil {
+		select {
+		case <-stop:
+			return nil
+		default:
+			return err
+		}
+	}
+	defer body.Close()
+	dec := json.NewDecoder(body)
+	for {
+		var msg struct {
+			Result struct {
+				Canceled bool              `json:"canceled"`
+				Events   []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return err
+			}
+		}
+		switch {
+		case msg.Error != nil:
+			return fmt.Errorf("etcd: watch: %s", msg.Error.Message)
+		case msg.Result.Canceled:
+			return fmt.Errorf("etcd: watch canceled")
+		case len(msg.Result.Events) > 0:
+			return nil
+		}
+	}
+}
+
+func (p *Provider) keyRange() map[string]interface{} {
+	key := []byte(p.Prefix)
+	end := prefixEnd(key)
+	return map[string]interface{}{
+		"key":       base64.StdEncoding.EncodeToString(key),
+		"range_end": base64.StdEncoding.EncodeToString(end),
+	}
+}
+
+// prefixEnd returns the range end matching all keys with the prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// all 0xff: read to the end of the keyspace
+	return []byte{0}
+}
+
+func (p *Provider) post(api string, v interface{}, cancel <-chan struct{}) (io.ReadCloser, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", p.Endpoint+api, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Cancel = cancel
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("etcd: POST %s: %s %s", api, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp.Body, nil
+}
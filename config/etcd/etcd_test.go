@@ -0,0 +1,51 @@
+package etcd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			if req["key"] != b64("/lessgo/app") || req["range_end"] != b64("/lessgo/apq") {
+				t.Errorf("unexpected range %v", req)
+			}
+			fmt.Fprintf(w, `{"header":{"revision":"41"},"kvs":[{"key":%q,"value":%q}]}`,
+				b64("/lessgo/app/log/level"), b64("error"))
+		case "/v3/watch":
+			if rev := req["create_request"].(map[string]interface{})["start_revision"]; rev != "42" {
+				t.Errorf("start_revision = %v", rev)
+			}
+			fmt.Fprint(w, `{"result":{"header":{"revision":"41"},"created":true}}`)
+			w.(http.Flusher).Flush()
+			fmt.Fprintf(w, `{"result":{"header":{"revision":"42"},"events":[{"kv":{"key":%q}}]}}`, b64("/lessgo/app/log/level"))
+		}
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL+"/", "/lessgo/app")
+	m, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"log": map[string]interface{}{"level": "error"}}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Load() = %v, want %v", m, want)
+	}
+	if err := p.Watch(make(chan struct{})); err != nil {
+		t.Fatal(err)
+	}
+}
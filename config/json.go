@@ -89,7 +89,10 @@ func (c *JSONConfigContainer) DefaultBool(key string, defaultval bool) bool {
 func (c *JSONConfigContainer) Int(key string) (int, error) {
 	val := c.getData(key)
 	if val != nil {
-		if v, ok := val.(float64); ok {
+		switch v := val.(type) {
+		case float64:
+			return int(v), nil
+		case int64:
 			return int(v), nil
 		}
 		return 0, errors.New("not int value")
@@ -110,7 +113,10 @@ func (c *JSONConfigContainer) DefaultInt(key string, defaultval int) int {
 func (c *JSONConfigContainer) Int64(key string) (int64, error) {
 	val := c.getData(key)
 	if val != nil {
-		if v, ok := val.(float64); ok {
+		switch v := val.(type) {
+		case float64:
+			return int64(v), nil
+		case int64:
 			return int64(v), nil
 		}
 		return 0, errors.New("not int64 value")
@@ -131,8 +137,11 @@ func (c *JSONConfigContainer) DefaultInt64(key string, defaultval int64) int64 {
 func (c *JSONConfigContainer) Float(key string) (float64, error) {
 	val := c.getData(key)
 	if val != nil {
-		if v, ok := val.(float64); ok {
+		switch v := val.(type) {
+		case float64:
 			return v, nil
+		case int64:
+			return float64(v), nil
 		}
 		return 0.0, errors.New("not float64 value")
 	}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/config/yaml/goyaml2"
+)
+
+// Provider is a configuration source outside the local files, such as etcd,
+// Consul or an HTTP endpoint, shared by a fleet of instances.
+type Provider interface {
+	// Load returns the whole configuration as nested maps,
+	// whose top level keys are the sections.
+	Load() (map[string]interface{}, error)
+	// Watch blocks until the configuration may have changed or stop is closed.
+	Watch(stop <-chan struct{}) error
+}
+
+// Decode decodes a structured document in the format "yaml"("yml"), "toml" or "json".
+func Decode(format string, data []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "yaml", "yml":
+		if len(bytes.TrimSpace(data)) == 0 {
+			return m, nil
+		}
+		v, err := goyaml2.Read(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			var ok bool
+			if m, ok = v.(map[string]interface{}); !ok {
+				return nil, errors.New("yaml document is not a map")
+			}
+		}
+		return m, nil
+	case "toml":
+		return ParseTOML(data)
+	case "json":
+		err := json.Unmarshal(data, &m)
+		return m, err
+	}
+	return nil, fmt.Errorf("unsupported config format %q", format)
+}
+
+// KVMap converts flat key-value pairs such as "prefix/log/level" = "info"
+// into nested maps, relative to prefix.
+func KVMap(prefix string, kvs map[string]string) map[string]interface{} {
+	m := make(map[string]interface{})
+	prefix = strings.Trim(prefix, "/")
+	for k, v := range kvs {
+		k = strings.Trim(strings.TrimPrefix(strings.Trim(k, "/"), prefix), "/")
+		if k == "" {
+			continue
+		}
+		keys := strings.Split(k, "/")
+		t := m
+		for _, key := range keys[:len(keys)-1] {
+			sub, ok := t[key].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				t[key] = sub
+			}
+			t = sub
+		}
+		if _, ok := t[keys[len(keys)-1]].(map[string]interface{}); !ok {
+			t[keys[len(keys)-1]] = v
+		}
+	}
+	return m
+}
+
+// HTTPProvider polls a structured document(yaml, toml or json) from an URL.
+// The format is detected from the Content-Type, then the URL extension,
+// json by default.
+type HTTPProvider struct {
+	URL      string
+	Interval time.Duration // poll interval, 30s by default
+	Header   http.Header   // extra request headers, such as Authorization
+	Client   *http.Client
+
+	mu   sync.Mutex
+	etag string
+	sum  [sha1.Size]byte
+}
+
+// NewHTTPProvider returns a HTTPProvider polling url every interval.
+func NewHTTPProvider(url string, interval time.Duration) *HTTPProvider {
+	return &HTTPProvider{URL: url, Interval: interval}
+}
+
+// Load fetches and decodes the document.
+func (p *HTTPProvider) Load() (map[string]interface{}, error) {
+	resp, body, err := p.fetch(nil, false)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.etag = resp.Header.Get("ETag")
+	p.sum = sha1.Sum(body)
+	p.mu.Unlock()
+
+	format := path.Ext(resp.Request.URL.Path)
+	switch ct := resp.Header.Get("Content-Type"); {
+	case strings.Contains(ct, "yaml"):
+		format = "yaml"
+	case strings.Contains(ct, "toml"):
+		format = "toml"
+	case strings.Contains(ct, "json"):
+		format = "json"
+	}
+	switch strings.TrimPrefix(format, ".") {
+	case "yaml", "yml", "toml":
+	default:
+		format = "json"
+	}
+	return Decode(format, body)
+}
+
+// Watch polls the URL until the document differs from the last loaded one.
+func (p *HTTPProvider) Watch(stop <-chan struct{}) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(interval):
+		}
+		resp, body, err := p.fetch(stop, true)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusNotModified {
+			continue
+		}
+		p.mu.Lock()
+		changed := sha1.Sum(body) != p.sum
+		p.mu.Unlock()
+		if changed {
+			return nil
+		}
+	}
+}
+
+func (p *HTTPProvider) fetch(stop <-chan struct{}, conditional bool) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("GET", p.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range p.Header {
+		req.Header[k] = v
+	}
+	if conditional {
+		p.mu.Lock()
+		if p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		p.mu.Unlock()
+	}
+	req.Cancel = stop
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		return nil, nil, fmt.Errorf("GET %s: %s", p.URL, resp.Status)
+	}
+	return resp, body, nil
+}
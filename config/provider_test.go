@@ -0,0 +1,78 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKVMap(t *testing.T) {
+	m := KVMap("/lessgo/app", map[string]string{
+		"/lessgo/app/appname":    "demo",
+		"/lessgo/app/log/level":  "info",
+		"/lessgo/app/log/syslog": "local",
+		"/lessgo/app/":           "ignored",
+	})
+	want := map[string]interface{}{
+		"appname": "demo",
+		"log":     map[string]interface{}{"level": "info", "syslog": "local"},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("KVMap() = %v, want %v", m, want)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var version int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := atomic.LoadInt32(&version)
+		etag := `"v` + string('0'+v) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.Write([]byte("log:\n  level: " + []string{"info", "warn"}[v] + "\n"))
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL+"/app", 10*time.Millisecond)
+	m, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["log"].(map[string]interface{})["level"] != "info" {
+		t.Fatalf("Load() = %v", m)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Watch(make(chan struct{})) }()
+	select {
+	case <-done:
+		t.Fatal("Watch returned before any change")
+	case <-time.After(50 * time.Millisecond):
+	}
+	atomic.StoreInt32(&version, 1)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not notice the change")
+	}
+	if m, _ = p.Load(); m["log"].(map[string]interface{})["level"] != "warn" {
+		t.Fatalf("Load() = %v", m)
+	}
+
+	stop := make(chan struct{})
+	go func() { done <- p.Watch(stop) }()
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("Watch() after stop = %v", err)
+	}
+}
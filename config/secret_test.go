@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSecretProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "db_password"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &FileSecretProvider{Dir: dir}
+	if v, err := p.GetSecret("db_password"); err != nil || v != "s3cret" {
+		t.Errorf("GetSecret() = %q, %v", v, err)
+	}
+	for _, name := range []string{"../db_password", "/etc/passwd", ""} {
+		if _, err := p.GetSecret(name); err == nil {
+			t.Errorf("GetSecret(%q) should fail", name)
+		}
+	}
+}
+
+func TestEncryptedFileSecretProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	ciphertext, err := EncryptSecret(key, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(map[string]string{"db_password": ciphertext})
+	fname := filepath.Join(dir, "secrets.json")
+	if err := ioutil.WriteFile(fname, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &EncryptedFileSecretProvider{File: fname, Key: key}
+	if v, err := p.GetSecret("db_password"); err != nil || v != "s3cret" {
+		t.Errorf("GetSecret() = %q, %v", v, err)
+	}
+	if _, err := p.GetSecret("missing"); err == nil {
+		t.Error("GetSecret(missing) should fail")
+	}
+	p.Key = []byte("fedcba9876543210fedcba9876543210")
+	if _, err := p.GetSecret("db_password"); err == nil {
+		t.Error("GetSecret() with a wrong key should fail")
+	}
+}
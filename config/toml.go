@@ -0,0 +1,553 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TOMLConfig is a toml config parser and implements Config interface.
+// The parsed document is held by a JSONConfigContainer, so tables are
+// addressed as "section::key" just like json. Integers are decoded as int64,
+// floats as float64 and date-times are kept as strings.
+type TOMLConfig struct {
+}
+
+// Parse returns a ConfigContainer with parsed toml config map.
+func (t *TOMLConfig) Parse(filename string) (Configer, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return t.ParseData(content)
+}
+
+// ParseData returns a ConfigContainer with toml string
+func (t *TOMLConfig) ParseData(data []byte) (Configer, error) {
+	m, err := ParseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONConfigContainer{data: m}, nil
+}
+
+// ParseTOML decodes a TOML document into nested maps.
+// Arrays of tables become []interface{} of map[string]interface{}.
+func ParseTOML(data []byte) (map[string]interface{}, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("toml: document is not valid UTF-8")
+	}
+	root := make(map[string]interface{})
+	p := &tomlParser{src: []rune(string(data)), line: 1, root: root, cur: root}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+type tomlParser struct {
+	src  []rune
+	pos  int
+	line int
+	root map[string]interface{}
+	cur  map[string]interface{}
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) peek() rune {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) hasPrefix(s string) bool {
+	for i, r := range []rune(s) {
+		if p.pos+i >= len(p.src) || p.src[p.pos+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *tomlParser) next() rune {
+	r := p.peek()
+	if r != 0 {
+		p.pos++
+		if r == '\n' {
+			p.line++
+		}
+	}
+	return r
+}
+
+func (p *tomlParser) skipSpace() {
+	for r := p.peek(); r == ' ' || r == '\t'; r = p.peek() {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for r := p.peek(); r != 0 && r != '\n'; r = p.peek() {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments inside arrays.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		switch p.peek() {
+		case '\n', '\r':
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// endLine expects nothing but an optional comment until the end of line.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.peek() == '\r' {
+		p.next()
+	}
+	switch p.peek() {
+	case 0:
+		return nil
+	case '\n':
+		p.next()
+		return nil
+	}
+	return p.errorf("unexpected %q after value", p.peek())
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank()
+		switch p.peek() {
+		case 0:
+			return nil
+		case '[':
+			if err := p.parseTable(); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseKeyValue(p.cur); err != nil {
+				return err
+			}
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *tomlParser) parseTable() error {
+	isArray := p.hasPrefix("[[")
+	p.next()
+	if isArray {
+		p.next()
+	}
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if isArray {
+		if !p.hasPrefix("]]") {
+			return p.errorf("expected ]] to close array of tables")
+		}
+		p.next()
+		p.next()
+	} else if p.next() != ']' {
+		return p.errorf("expected ] to close table")
+	}
+
+	t, err := p.descend(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if isArray {
+		arr, _ := t[last].([]interface{})
+		if _, ok := t[last]; ok && arr == nil {
+			return p.errorf("key %q is already defined", strings.Join(keys, "."))
+		}
+		m := make(map[string]interface{})
+		t[last] = append(arr, m)
+		p.cur = m
+		return nil
+	}
+	switch v := t[last].(type) {
+	case nil:
+		m := make(map[string]interface{})
+		t[last] = m
+		p.cur = m
+	case map[string]interface{}:
+		p.cur = v
+	default:
+		return p.errorf("key %q is already defined", strings.Join(keys, "."))
+	}
+	return nil
+}
+
+// descend walks the dotted keys from t, creating missing tables.
+// An array of tables resolves to its last element.
+func (p *tomlParser) descend(t map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, k := range keys {
+		switch v := t[k].(type) {
+		case nil:
+			m := make(map[string]interface{})
+			t[k] = m
+			t = m
+		case map[string]interface{}:
+			t = v
+		case []interface{}:
+			if len(v) == 0 {
+				return nil, p.errorf("key %q is not a table", k)
+			}
+			m, ok := v[len(v)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("key %q is not a table", k)
+			}
+			t = m
+		default:
+			return nil, p.errorf("key %q is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) parseKeyValue(t map[string]interface{}) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.next() != '=' {
+		return p.errorf("expected = after key %q", strings.Join(keys, "."))
+	}
+	p.skipSpace()
+	v, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	t, err = p.descend(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := t[last]; ok {
+		return p.errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	t[last] = v
+	return nil
+}
+
+// parseKey reads a bare, quoted or dotted key.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var (
+			k   string
+			err error
+		)
+		switch r := p.peek(); {
+		case r == '"':
+			p.next()
+			k, err = p.parseBasicString()
+		case r == '\'':
+			p.next()
+			k, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for r = p.peek(); r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'; r = p.peek() {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected key, found %q", r)
+			}
+			k = string(p.src[start:p.pos])
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch r := p.peek(); {
+	case p.hasPrefix(`"""`):
+		p.pos += 3
+		return p.parseMultilineString(true)
+	case p.hasPrefix("'''"):
+		p.pos += 3
+		return p.parseMultilineString(false)
+	case r == '"':
+		p.next()
+		return p.parseBasicString()
+	case r == '\'':
+		p.next()
+		return p.parseLiteralString()
+	case r == '[':
+		p.next()
+		return p.parseArray()
+	case r == '{':
+		p.next()
+		return p.parseInlineTable()
+	case p.hasPrefix("true"):
+		p.pos += 4
+		return true, nil
+	case p.hasPrefix("false"):
+		p.pos += 5
+		return false, nil
+	case r == 0 || r == '\n' || r == '\r':
+		return nil, p.errorf("missing value")
+	}
+	return p.parseScalar()
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	var buf []rune
+	for {
+		r := p.next()
+		switch r {
+		case 0, '\n':
+			return "", p.errorf("unterminated string")
+		case '"':
+			return string(buf), nil
+		case '\\':
+			e, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, e)
+		default:
+			buf = append(buf, r)
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	start := p.pos
+	for {
+		switch p.next() {
+		case 0, '\n':
+			return "", p.errorf("unterminated string")
+		case '\'':
+			return string(p.src[start : p.pos-1]), nil
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineString(basic bool) (string, error) {
+	delim := "'''"
+	if basic {
+		delim = `"""`
+	}
+	// a newline right after the opening delimiter is trimmed
+	if p.hasPrefix("\r\n") {
+		p.next()
+	}
+	if p.peek() == '\n' {
+		p.next()
+	}
+	var buf []rune
+	for {
+		if p.hasPrefix(delim) {
+			p.pos += 3
+			// up to two quotes may end the content
+			for i := 0; i < 2 && p.hasPrefix(delim[:1]); i++ {
+				buf = append(buf, p.next())
+			}
+			return string(buf), nil
+		}
+		r := p.next()
+		switch {
+		case r == 0:
+			return "", p.errorf("unterminated multi-line string")
+		case basic && r == '\\':
+			// a line ending backslash trims all following whitespace
+			save, line := p.pos, p.line
+			p.skipSpace()
+			if p.peek() == '\r' || p.peek() == '\n' {
+				for r := p.peek(); r == ' ' || r == '\t' || r == '\r' || r == '\n'; r = p.peek() {
+					p.next()
+				}
+				continue
+			}
+			p.pos, p.line = save, line
+			e, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, e)
+		default:
+			buf = append(buf, r)
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape() (rune, error) {
+	switch r := p.next(); r {
+	case 'b':
+		return '\b', nil
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case '"':
+		return '"', nil
+	case '\\':
+		return '\\', nil
+	case 'u', 'U':
+		n := 4
+		if r == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return 0, p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return 0, p.errorf("invalid unicode escape")
+		}
+		p.pos += n
+		return rune(code), nil
+	default:
+		return 0, p.errorf("invalid escape \\%c", r)
+	}
+}
+
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	arr := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.next()
+			return arr, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+		p.skipBlank()
+		switch p.next() {
+		case ',':
+		case ']':
+			return arr, nil
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	t := make(map[string]interface{})
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.next()
+		return t, nil
+	}
+	for {
+		if err := p.parseKeyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.next() {
+		case ',':
+		case '}':
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// parseScalar reads a number or a date-time.
+func (p *tomlParser) parseScalar() (interface{}, error) {
+	start := p.pos
+	for r := p.peek(); r != 0 && !strings.ContainsRune(" \t\r\n,]}#", r); r = p.peek() {
+		p.pos++
+	}
+	tok := string(p.src[start:p.pos])
+	// date and time may be separated by a space: 1979-05-27 07:32:00
+	if len(tok) == 10 && tok[4] == '-' && p.peek() == ' ' && p.pos+1 < len(p.src) && p.src[p.pos+1] >= '0' && p.src[p.pos+1] <= '9' {
+		p.pos++
+		for r := p.peek(); r != 0 && !strings.ContainsRune(" \t\r\n,]}#", r); r = p.peek() {
+			p.pos++
+		}
+		tok = string(p.src[start:p.pos])
+	}
+	if len(tok) >= 8 && (tok[4] == '-' || tok[2] == ':') {
+		return tok, nil
+	}
+
+	switch strings.TrimLeft(tok, "+-") {
+	case "inf":
+		if tok[0] == '-' {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	num := strings.Replace(tok, "_", "", -1)
+	if len(num) > 2 && num[0] == '0' {
+		base := 0
+		switch num[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 0 {
+			i, err := strconv.ParseInt(num[2:], base, 64)
+			if err != nil {
+				return nil, p.errorf("invalid integer %q", tok)
+			}
+			return i, nil
+		}
+	}
+	if strings.ContainsAny(num, ".eE") {
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", tok)
+		}
+		return f, nil
+	}
+	i, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return nil, p.errorf("invalid value %q", tok)
+	}
+	return i, nil
+}
+
+func init() {
+	Register("toml", &TOMLConfig{})
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTOML(t *testing.T) {
+	const doc = `
+# system settings
+appname = "lessgo"
+debug = false
+pi = 3.14_15
+port = 8_080
+mask = 0xff
+date = 1979-05-27 07:32:00Z
+path = 'C:\Users\lessgo'
+motd = """
+hello \
+   world\t!"""
+
+[log]
+level = "info"  # trailing comment
+hosts = [
+  "a",
+  "b", # second
+]
+
+[listen.tls]
+cert = "cert.pem"
+
+[[routes]]
+prefix = "/api"
+meta = { group = true, "x.y" = 1 }
+
+[[routes]]
+prefix = "/home"
+`
+	m, err := ParseTOML([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"appname": "lessgo",
+		"debug":   false,
+		"pi":      3.1415,
+		"port":    int64(8080),
+		"mask":    int64(255),
+		"date":    "1979-05-27 07:32:00Z",
+		"path":    `C:\Users\lessgo`,
+		"motd":    "hello world\t!",
+		"log": map[string]interface{}{
+			"level": "info",
+			"hosts": []interface{}{"a", "b"},
+		},
+		"listen": map[string]interface{}{
+			"tls": map[string]interface{}{"cert": "cert.pem"},
+		},
+		"routes": []interface{}{
+			map[string]interface{}{
+				"prefix": "/api",
+				"meta":   map[string]interface{}{"group": true, "x.y": int64(1)},
+			},
+			map[string]interface{}{"prefix": "/home"},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("ParseTOML() =\n%#v\nwant\n%#v", m, want)
+	}
+
+	conf, err := NewConfigData("toml", []byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := conf.Int("port"); v != 8080 {
+		t.Errorf("Int(port) = %d", v)
+	}
+	if v := conf.String("log::level"); v != "info" {
+		t.Errorf("String(log::level) = %q", v)
+	}
+	if v := conf.String("listen::tls::cert"); v != "cert.pem" {
+		t.Errorf("String(listen::tls::cert) = %q", v)
+	}
+
+	for _, bad := range []string{
+		"a = 1\na = 2",
+		"a = \"open",
+		"a = 1 b = 2",
+		"[a]\n[a.b]\nc = 1\n[a]\nb = 2",
+		"a =",
+	} {
+		if _, err := ParseTOML([]byte(bad)); err == nil {
+			t.Errorf("ParseTOML(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package vault provides a config.SecretProvider reading secrets from the
+// HashiCorp Vault KV secrets engine through its HTTP API.
+//
+// A secret name is "path#field", e.g. ${secret:app/db#password} reads the
+// field password of the secret app/db. The field defaults to "value".
+//
+//	lessgo.SetSecretProvider(vault.New("https://vault:8200", os.Getenv("VAULT_TOKEN")), 5*time.Minute)
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Provider reads secrets from a KV mount.
+type Provider struct {
+	Address   string // such as https://127.0.0.1:8200
+	Token     string
+	Mount     string // mount path of the KV engine, "secret" by default
+	KVVersion int    // 1 or 2(default)
+	Namespace string // Vault Enterprise namespace, optional
+	Client    *http.Client
+}
+
+// New returns a Provider for the KV v2 engine mounted at "secret".
+func New(address, token string) *Provider {
+	return &Provider{Address: strings.TrimRight(address, "/"), Token: token, Mount: "secret", KVVersion: 2}
+}
+
+// GetSecret implements config.SecretProvider.
+func (p *Provider) GetSecret(name string) (string, error) {
+	path, field := name, "value"
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		path, field = name[:i], name[i+1:]
+	}
+	path = strings.Trim(path, "/")
+	mount := strings.Trim(p.Mount, "/")
+	if mount == "" {
+		mount = "secret"
+	}
+	url := p.Address + "/v1/" + mount + "/"
+	if p.KVVersion == 1 {
+		url += path
+	} else {
+		url += "data/" + path
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+	if p.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.Namespace)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("vault: read %s: %s %s", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	data := body.Data
+	if p.KVVersion != 1 {
+		var v2 struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err = json.Unmarshal(data, &v2); err != nil {
+			return "", err
+		}
+		data = v2.Data
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("vault: field %q not found in %s", field, path)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, _ := json.Marshal(v)
+	return string(b), nil
+}
@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app/db":
+			w.Write([]byte(`{"data":{"data":{"password":"s3cret","value":"v"},"metadata":{"version":3}}}`))
+		case "/v1/kv/app/db":
+			w.Write([]byte(`{"data":{"password":"old"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL, "root")
+	if v, err := p.GetSecret("app/db#password"); err != nil || v != "s3cret" {
+		t.Errorf("GetSecret(app/db#password) = %q, %v", v, err)
+	}
+	if v, err := p.GetSecret("/app/db"); err != nil || v != "v" {
+		t.Errorf("GetSecret(app/db) = %q, %v", v, err)
+	}
+	if _, err := p.GetSecret("app/db#missing"); err == nil {
+		t.Error("missing field should fail")
+	}
+	if _, err := p.GetSecret("app/none#password"); err == nil {
+		t.Error("missing secret should fail")
+	}
+
+	v1 := &Provider{Address: srv.URL, Token: "root", Mount: "kv", KVVersion: 1}
+	if v, err := v1.GetSecret("app/db#password"); err != nil || v != "old" {
+		t.Errorf("KV v1 GetSecret() = %q, %v", v, err)
+	}
+}
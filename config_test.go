@@ -0,0 +1,261 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lessgo/lessgo/logs"
+)
+
+func TestStructuredConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lessgo-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		".yaml": "appname: yamlapp\nmax_memory_mb: 32\nlog:\n  level: warn\n  async-chan: 10\nlisten:\n  address: \":80\"\n",
+		".toml": "appname = \"tomlapp\"\nmaxmemorymb = 32\n[log]\nlevel = \"warn\"\nasync_chan = 10\n[listen]\naddress = \":80\"\n",
+		".json": `{"AppName":"jsonapp","MaxMemoryMB":32,"Log":{"Level":"warn","AsyncChan":10},"Listen":{"Address":":80"}}`,
+	}
+	for ext, content := range files {
+		fname := filepath.Join(dir, "app"+ext)
+		if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		data, err := readStructuredConfig(fname)
+		if err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		c := newConfig()
+		c.readAll(flattenConfig(data))
+		if c.AppName != ext[1:]+"app" || c.MaxMemoryMB != 32 || c.Log.Level != logs.WARN ||
+			c.Log.AsyncChan != 10 || c.Listen.Address != ":80" || c.FileCache.MaxCapMB != 256 {
+			t.Errorf("%s: got %+v", ext, c)
+		}
+	}
+
+	// 存在多个时以yaml优先
+	fname, err := findStructuredConfig(filepath.Join(dir, "app"))
+	if filepath.Ext(fname) != ".yaml" || err == nil {
+		t.Errorf("findStructuredConfig() = %s, %v", fname, err)
+	}
+	if fname, err = findStructuredConfig(filepath.Join(dir, "none")); fname != "" || err != nil {
+		t.Errorf("findStructuredConfig() = %s, %v", fname, err)
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	c := newConfig()
+	c.readAll(envConfig([]string{
+		"PATH=/bin",
+		"LESSGO_LISTEN_ADDRESS=:9090",
+		"LESSGO_LOG_ASYNC_CHAN=5",
+		"LESSGO_DEBUG=false",
+		"LESSGO_UNKNOWN=1",
+	}))
+	if c.Listen.Address != ":9090" || c.Log.AsyncChan != 5 || c.Debug {
+		t.Errorf("env: got %+v", c)
+	}
+
+	conf, err := flagConfig([]string{
+		"-lessgo.listen.address=:7070",
+		"--lessgo.log.level", "error",
+		"-lessgo.maintenance",
+		"-v",
+		"-lessgo.nope=1",
+		"--", "-lessgo.appname=x",
+	})
+	if err == nil {
+		t.Error("unknown flag should be reported")
+	}
+	c.readAll(conf)
+	if c.Listen.Address != ":7070" || c.Log.Level != logs.ERROR || !c.Maintenance || c.AppName != "lessgo" {
+		t.Errorf("flags: got %+v", c)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	fname := CONFIG_DIR + "/app.yaml"
+	if _, err := os.Stat(fname); err == nil {
+		t.Skip(fname + " exists")
+	}
+	atomic.StoreInt32(&serving, 1)
+	defer func(level int) {
+		atomic.StoreInt32(&serving, 0)
+		os.Remove(fname)
+		Config.Log.Level = level
+		Log.SetLevel(level)
+		DisableMaintenance()
+	}(Config.Log.Level)
+
+	var got []ConfigChange
+	OnConfigChange(func(changes []ConfigChange) {
+		got = changes
+	})
+	content := "maintenance: true\nlog:\n  level: error\nlisten:\n  address: \":1\"\n"
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if !Maintenance() || Config.Log.Level != logs.ERROR || Config.Listen.Address == ":1" {
+		t.Errorf("config not applied: %+v", Config)
+	}
+	applied := map[string]bool{}
+	for _, ch := range got {
+		applied[ch.Key] = ch.Applied
+	}
+	want := map[string]bool{"system::maintenance": true, "log::level": true, "listen::address": false}
+	if !reflect.DeepEqual(applied, want) {
+		t.Errorf("changes = %+v", got)
+	}
+}
+
+type fakeConfigProvider struct {
+	data    chan map[string]interface{}
+	current map[string]interface{}
+}
+
+func (p *fakeConfigProvider) Load() (map[string]interface{}, error) {
+	return p.current, nil
+}
+
+func (p *fakeConfigProvider) Watch(stop <-chan struct{}) error {
+	select {
+	case p.current = <-p.data:
+	case <-stop:
+	}
+	return nil
+}
+
+func TestConfigProvider(t *testing.T) {
+	defer func(level int) {
+		SetConfigProvider(nil)
+		Config.Log.Level = level
+		Log.SetLevel(level)
+	}(Config.Log.Level)
+
+	changed := make(chan []ConfigChange, 1)
+	OnConfigChange(func(changes []ConfigChange) {
+		select {
+		case changed <- changes:
+		default:
+		}
+	})
+	p := &fakeConfigProvider{
+		data:    make(chan map[string]interface{}),
+		current: map[string]interface{}{"log": map[string]interface{}{"level": "warn"}},
+	}
+	if err := SetConfigProvider(p); err != nil {
+		t.Fatal(err)
+	}
+	<-changed
+	if Config.Log.Level != logs.WARN {
+		t.Fatalf("level = %d after load", Config.Log.Level)
+	}
+	p.data <- map[string]interface{}{"log": map[string]interface{}{"level": "error"}}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("config not reloaded after watch")
+	}
+	if Config.Log.Level != logs.ERROR {
+		t.Fatalf("level = %d after watch", Config.Log.Level)
+	}
+}
+
+func TestConfigBind(t *testing.T) {
+	os.Setenv("LESSGO_DEMO_HOST", "10.0.0.1")
+	os.Setenv("LESSGO_DEMO_MAX_CONN", "0")
+	defer os.Unsetenv("LESSGO_DEMO_HOST")
+	defer os.Unsetenv("LESSGO_DEMO_MAX_CONN")
+
+	var conf struct {
+		Demo struct {
+			Host    string `validate:"required"`
+			Port    int    `default:"3306"`
+			MaxConn int    `config:"max_conn" validate:"min=1"`
+		}
+	}
+	err := Config.Bind(&conf)
+	if err == nil || !strings.Contains(err.Error(), "demo::max_conn: must be at least 1") {
+		t.Errorf("Bind() error = %v", err)
+	}
+	if conf.Demo.Host != "10.0.0.1" || conf.Demo.Port != 3306 {
+		t.Errorf("Bind() = %+v", conf)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	base, prod := CONFIG_DIR+"/app.yaml", CONFIG_DIR+"/app.prod.yaml"
+	for _, fname := range []string{base, prod} {
+		if _, err := os.Stat(fname); err == nil {
+			t.Skip(fname + " exists")
+		}
+	}
+	defer os.Remove(base)
+	defer os.Remove(prod)
+	ioutil.WriteFile(base, []byte("profile: prod\nappname: base\nlog:\n  level: info\n"), 0644)
+	ioutil.WriteFile(prod, []byte("appname: prod\n"), 0644)
+
+	c := newConfig()
+	if err := c.readFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if c.AppName != "prod" || c.Log.Level != logs.INFO {
+		t.Errorf("profile prod: got %+v", c)
+	}
+
+	os.Setenv("LESSGO_PROFILE", "test")
+	defer os.Unsetenv("LESSGO_PROFILE")
+	c = newConfig()
+	c.readFiles()
+	c.readOverrides()
+	if c.AppName != "base" || c.Profile != "test" {
+		t.Errorf("profile test: got %+v", c)
+	}
+
+	defer func(p string) { Config.Profile = p }(Config.Profile)
+	Config.Profile = "dev"
+	leaf := &VirtRouter{}
+	if Profiled(leaf, "DEV", "test") != leaf || Profiled(leaf, "prod") != nil {
+		t.Error("Profiled() mismatch")
+	}
+}
+
+func TestReloadDebugConcurrent(t *testing.T) {
+	// 热加载切换调试模式时，请求中仍可并发读取(以-race运行)
+	debug := app.Debug()
+	defer func() {
+		SetDebug(debug)
+		Log.SetLevel(Config.Log.Level)
+	}()
+	apply := configApplier("system::debug")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			apply(&config{Debug: i%2 == 0})
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			if app.Debug() {
+				t.Error("debug mode not applied")
+			}
+			return
+		default:
+			app.Debug()
+		}
+	}
+}
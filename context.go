@@ -1,8 +1,6 @@
 package lessgo
 
 import (
-	"bytes"
-	"encoding/json"
 	"encoding/xml"
 	"io"
 	"io/ioutil"
@@ -31,17 +29,32 @@ type (
 		request        *http.Request
 		response       *Response
 		path           string
+		apiHandler     *ApiHandler
 		realRemoteAddr string
 		query          url.Values
 		form           url.Values
 		pkeys          []string
 		pvalues        []string
+		pkeysArr       [inlineParams]string // pkeys、pvalues的初始底层数组，随Context复用，避免每个请求分配
+		pvaluesArr     [inlineParams]string
 		store          store
+		releasedBy     string // 泄漏检测模式下，请求结束后记录所属请求的路由，见SetPoolCheck
 		cruSession     session.Store
 		socket         *websocket.Conn
+		renderer       string // UseRenderer选用的模板引擎名称，为空时使用App的默认引擎
 	}
 
-	store map[string]interface{}
+	// 请求上下文数据，前inlineStoreSize个键值存于定长数组，超出部分才分配map
+	store struct {
+		n     int
+		kvs   [inlineStoreSize]storeEntry
+		extra map[string]interface{}
+	}
+
+	storeEntry struct {
+		key string
+		val interface{}
+	}
 
 	// Common message format of JSON and JSONP.
 	CommJSON Result
@@ -52,6 +65,11 @@ type (
 	}
 )
 
+const (
+	inlineParams    = 8
+	inlineStoreSize = 8
+)
+
 var (
 	_ http.ResponseWriter = new(Context)
 
@@ -66,7 +84,17 @@ var (
 	}
 )
 
+// 创建不经过路由的Context(不取自对象池)，供单元测试操作与中间件使用，见lessgotest.NewContext
+func NewContext(rw http.ResponseWriter, req *http.Request) *Context {
+	c := app.newContext(new(Response), req)
+	if err := c.init(rw, req); err != nil {
+		Log.Error("NewContext: %v", err)
+	}
+	return c
+}
+
 func (c *Context) Request() *http.Request {
+	c.checkReleased()
 	return c.request
 }
 
@@ -122,6 +150,7 @@ func (c *Context) PathParamValues() []string {
 
 // PathParam returns path param by key.
 func (c *Context) PathParam(key string) string {
+	c.checkReleased()
 	l := len(c.pkeys)
 	for i, n := range c.pkeys {
 		if n == key && i < l {
@@ -187,6 +216,7 @@ func (c *Context) QueryParams(key string) []string {
 
 // QueryParam returns the query param for the provided key.
 func (c *Context) QueryParam(key string) string {
+	c.checkReleased()
 	if c.query == nil {
 		c.query = c.request.URL.Query()
 	}
@@ -231,6 +261,7 @@ func (c *Context) HeaderParams(key string) []string {
 
 // HeaderParam returns request header value for the provided key.
 func (c *Context) HeaderParam(key string) string {
+	c.checkReleased()
 	return c.request.Header.Get(key)
 }
 
@@ -250,6 +281,64 @@ func (c *Context) AddHeaderParam(key string, value string) {
 // 	c.request.Header.Del(key)
 // }
 
+// 以下[]byte取值方法直接引用请求中的字符串，不做复制，返回值只读，不可修改。
+
+// ParamBytes returns path param by key as []byte.
+func (c *Context) ParamBytes(key string) []byte {
+	return stringBytes(c.PathParam(key))
+}
+
+// HeaderBytes returns request header value for the provided key as []byte.
+func (c *Context) HeaderBytes(key string) []byte {
+	return stringBytes(c.request.Header.Get(key))
+}
+
+// QueryBytes returns the query param for the provided key as []byte.
+// 查询参数未解析且值无需转义时，直接从URL.RawQuery截取，不分配内存。
+func (c *Context) QueryBytes(key string) []byte {
+	if c.query != nil {
+		return stringBytes(c.QueryParam(key))
+	}
+	q := c.request.URL.RawQuery
+	for q != "" {
+		pair := q
+		if i := strings.IndexByte(q, '&'); i >= 0 {
+			pair, q = q[:i], q[i+1:]
+		} else {
+			q = ""
+		}
+		k, v := pair, ""
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			k, v = pair[:i], pair[i+1:]
+		}
+		if strings.ContainsAny(k, "%+") {
+			var err error
+			if k, err = url.QueryUnescape(k); err != nil {
+				continue
+			}
+		}
+		if k != key {
+			continue
+		}
+		if strings.ContainsAny(v, "%+") {
+			v, err := url.QueryUnescape(v)
+			if err != nil {
+				continue
+			}
+			return []byte(v)
+		}
+		return stringBytes(v)
+	}
+	return nil
+}
+
+func stringBytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return utils.String2Bytes(s)
+}
+
 // FormValues returns the form params as url.Values.
 func (c *Context) FormValues() url.Values {
 	c.parseForm()
@@ -267,6 +356,7 @@ func (c *Context) FormParams(key string) []string {
 
 // FormParam returns the form field value for the provided key.
 func (c *Context) FormParam(key string) string {
+	c.checkReleased()
 	c.parseForm()
 	if vs := c.form[key]; len(vs) > 0 {
 		return vs[0]
@@ -366,6 +456,7 @@ func (c *Context) Bind(container interface{}) error {
 }
 
 func (c *Context) Response() *Response {
+	c.checkReleased()
 	return c.response
 }
 
@@ -443,14 +534,25 @@ func (c *Context) WriteHeader(code int) {
 }
 
 // Render renders a template with data and sends a text/html response with status
-// code. Templates can be registered using `App.SetRenderer()`.
+// code. Templates can be registered using `App.SetRenderer()`, or per route group
+// using `RegisterRenderer()` and the `UseRenderer` middleware.
 func (c *Context) Render(code int, name string, data interface{}) error {
-	if app.renderer == nil {
+	renderer := app.renderer
+	if c.renderer != "" {
+		renderer, _ = GetRenderer(c.renderer)
+	}
+	if renderer == nil {
 		return ErrRendererNotRegistered
 	}
-	buf := new(bytes.Buffer)
+	buf := getBuffer(0)
+	defer putBuffer(buf)
 	var err error
-	if err = app.renderer.Render(buf, name, data, c); err != nil {
+	if err = renderer.Render(buf, name, data, c); err != nil {
+		if app.Debug() {
+			// 调试模式下显示错误页，而非空白的500
+			Log.Error("Render %s: %v", name, err)
+			return c.templateErrorPage(name, err)
+		}
 		return err
 	}
 	c.response.Header().Set(HeaderContentType, MIMETextHTMLCharsetUTF8)
@@ -477,42 +579,15 @@ func (c *Context) String(code int, s string) error {
 
 // JSON sends a JSON response with status code.
 func (c *Context) JSON(code int, i interface{}) error {
-	var (
-		b   []byte
-		err error
-	)
-
-	if Debug() {
-		b, err = json.MarshalIndent(i, "", "  ")
-	} else {
-		b, err = json.Marshal(i)
-	}
-	if err != nil {
-		return err
-	}
-	return c.JSONBlob(code, b)
+	return c.writeJSON(code, MIMEApplicationJSONCharsetUTF8, "", i)
 }
 
 // JSON with default format.
 func (c *Context) JSONMsg(code int, msgcode int, info interface{}) error {
-	var (
-		b   []byte
-		err error
-	)
-	i := CommJSON{
+	return c.writeJSON(code, MIMEApplicationJSONCharsetUTF8, "", CommJSON{
 		Code: msgcode,
 		Info: info,
-	}
-	if Debug() {
-		b, err = json.MarshalIndent(i, "", "  ")
-	} else {
-		b, err = json.Marshal(i)
-	}
-	if err != nil {
-		return err
-	}
-
-	return c.JSONBlob(code, b)
+	})
 }
 
 // JSONBlob sends a JSON blob response with status code.
@@ -526,58 +601,15 @@ func (c *Context) JSONBlob(code int, b []byte) error {
 // JSONP sends a JSONP response with status code. It uses `callback` to construct
 // the JSONP payload.
 func (c *Context) JSONP(code int, callback string, i interface{}) error {
-	var (
-		b   []byte
-		err error
-	)
-	if Debug() {
-		b, err = json.MarshalIndent(i, "", "  ")
-	} else {
-		b, err = json.Marshal(i)
-	}
-	if err != nil {
-		return err
-	}
-	c.response.Header().Set(HeaderContentType, MIMEApplicationJavaScriptCharsetUTF8)
-	c.WriteHeader(code)
-	if _, err = c.response.Write(utils.String2Bytes(callback + "(")); err != nil {
-		return err
-	}
-	if _, err = c.response.Write(b); err != nil {
-		return err
-	}
-	_, err = c.response.Write(utils.String2Bytes(");"))
-	return err
+	return c.writeJSON(code, MIMEApplicationJavaScriptCharsetUTF8, callback, i)
 }
 
 // JSONP with default format.
 func (c *Context) JSONPMsg(code int, callback string, msgcode int, info interface{}) error {
-	var (
-		b   []byte
-		err error
-	)
-	i := CommJSON{
+	return c.writeJSON(code, MIMEApplicationJavaScriptCharsetUTF8, callback, CommJSON{
 		Code: msgcode,
 		Info: info,
-	}
-	if Debug() {
-		b, err = json.MarshalIndent(i, "", "  ")
-	} else {
-		b, err = json.Marshal(i)
-	}
-	if err != nil {
-		return err
-	}
-	c.response.Header().Set(HeaderContentType, MIMEApplicationJavaScriptCharsetUTF8)
-	c.WriteHeader(code)
-	if _, err = c.response.Write(utils.String2Bytes(callback + "(")); err != nil {
-		return err
-	}
-	if _, err = c.response.Write(b); err != nil {
-		return err
-	}
-	_, err = c.response.Write(utils.String2Bytes(");"))
-	return err
+	})
 }
 
 // XML sends an XML response with status code.
@@ -780,9 +812,9 @@ func (c *Context) ReverseProxy(targetUrlBase string, pathAppend bool) error {
 	return nil
 }
 
-// Error invokes the registered HTTP error handler. Generally used by middleware.
+// Error invokes the error handler registered by OnError for err, or the HTTP error handler. Generally used by middleware.
 func (c *Context) Error(err error) {
-	app.router.ErrorPanicHandler(c, err, nil)
+	handleError(c, err, nil)
 }
 
 // CruSession returns session data info.
@@ -844,6 +876,17 @@ func (c *Context) SetWs(conn *websocket.Conn) {
 	c.socket = conn
 }
 
+// 将当前请求升级为websocket连接，适用于普通路由中按需升级，调用者需负责关闭连接
+func (c *Context) WsUpgrade(u *websocket.Upgrader) (*websocket.Conn, error) {
+	conn, err := websocket.Upgrade(c.response, c.request, u)
+	if err != nil {
+		return nil, err
+	}
+	c.response.committed = true
+	c.socket = conn
+	return conn, nil
+}
+
 // 关闭websocket
 func (c *Context) WsClose() error {
 	return c.socket.Close()
@@ -871,33 +914,102 @@ func (c *Context) WsSendString(v string) (int, error) {
 
 // Get retrieves data from the context.
 func (c *Context) Set(key string, val interface{}) {
-	if c.store == nil {
-		c.store = make(store)
-	}
-	c.store[key] = val
+	c.checkReleased()
+	c.store.set(key, val)
 }
 
 // Set saves data in the context.
 func (c *Context) Get(key string) interface{} {
-	return c.store[key]
+	c.checkReleased()
+	val, _ := c.store.get(key)
+	return val
 }
 
 // Del deletes data from the context.
 func (c *Context) Del(key string) {
-	delete(c.store, key)
+	c.store.del(key)
 }
 
 // Contains checks if the key exists in the context.
 func (c *Context) Contains(key string) bool {
-	_, ok := c.store[key]
+	_, ok := c.store.get(key)
 	return ok
 }
 
+func (s *store) set(key string, val interface{}) {
+	for i := 0; i < s.n; i++ {
+		if s.kvs[i].key == key {
+			s.kvs[i].val = val
+			return
+		}
+	}
+	if _, ok := s.extra[key]; !ok && s.n < inlineStoreSize {
+		s.kvs[s.n] = storeEntry{key, val}
+		s.n++
+		return
+	}
+	if s.extra == nil {
+		s.extra = make(map[string]interface{})
+	}
+	s.extra[key] = val
+}
+
+func (s *store) get(key string) (interface{}, bool) {
+	for i := 0; i < s.n; i++ {
+		if s.kvs[i].key == key {
+			return s.kvs[i].val, true
+		}
+	}
+	val, ok := s.extra[key]
+	return val, ok
+}
+
+func (s *store) del(key string) {
+	for i := 0; i < s.n; i++ {
+		if s.kvs[i].key == key {
+			s.n--
+			s.kvs[i] = s.kvs[s.n]
+			s.kvs[s.n] = storeEntry{}
+			return
+		}
+	}
+	delete(s.extra, key)
+}
+
+// 清空数据，释放对值的引用
+func (s *store) reset() {
+	for i := 0; i < s.n; i++ {
+		s.kvs[i] = storeEntry{}
+	}
+	s.n = 0
+	s.extra = nil
+}
+
 // Log returns the `Logger` instance.
 func (c *Context) Log() logs.Logger {
 	return Log
 }
 
+// Logger returns a structured logger with the request ID, route, client IP,
+// tenant and current user (the "user" context value) attached, using the same
+// field names as the JSON access log so that lines can be correlated.
+func (c *Context) Logger() logs.StructuredLogger {
+	fields := []logs.Field{logs.F("remote_ip", c.RealRemoteAddr())}
+	if id := requestID(c); id != "" {
+		fields = append(fields, logs.F("request_id", id))
+	}
+	if c.path != "" {
+		fields = append(fields, logs.F("route", c.path))
+	}
+	if tenant := c.TenantID(); tenant != "" {
+		fields = append(fields, logs.F("tenant", tenant))
+	}
+	if u := c.Get("user"); u != nil {
+		fields = append(fields, logs.F("user", u))
+	}
+	return logs.Structured(Log).With(fields...)
+}
+
 func (c *Context) parseForm() {
 	if c.form != nil {
 		return
@@ -935,15 +1047,18 @@ func (c *Context) init(rw http.ResponseWriter, req *http.Request) error {
 	}
 	c.request = req
 	c.response.init(rw)
-	c.store = make(store)
+	c.store.reset()
 	return err
 }
 
 func (c *Context) free() {
 	c.freeSession()
 	c.socket = nil
-	c.store = nil
+	c.store.reset()
+	c.path = ""
+	c.apiHandler = nil
 	c.realRemoteAddr = ""
+	c.renderer = ""
 	c.query = nil
 	c.form = nil
 	c.response.free()
@@ -0,0 +1,222 @@
+// Package cron parses cron expressions into schedules.
+//
+// An expression has 5 fields, or 6 with a leading seconds field:
+//
+//	[second] minute hour day-of-month month day-of-week
+//
+// A field is *, a value, a range a-b, a step */n or a-b/n, or a comma
+// separated list of them; months and weekdays also accept names such as JAN
+// and MON, and both 0 and 7 are Sunday. As in Vixie cron, when neither
+// day-of-month nor day-of-week is * a day matching either one is scheduled.
+// The descriptors @yearly(@annually), @monthly, @weekly, @daily(@midnight),
+// @hourly and @every <duration> are accepted too.
+//
+//	s, err := cron.Parse("0 */15 9-18 * * MON-FRI")
+//	next := s.Next(time.Now())
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the activation times of a job.
+type Schedule interface {
+	// Next returns the first activation time after t, or the zero time if
+	// there is none within five years.
+	Next(t time.Time) time.Time
+}
+
+type spec struct {
+	second, minute, hour, dom, month, dow uint64
+	domStar, dowStar                      bool
+}
+
+// every runs at a fixed interval from the previous activation.
+type every time.Duration
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dows = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse parses a cron expression, the times are in the location of the
+// time passed to Next.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(expr[len("@every "):]))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("cron: invalid interval in %q", expr)
+		}
+		return every(d), nil
+	}
+	if s, ok := descriptors[expr]; ok {
+		expr = s
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron: expected 5 or 6 fields in %q", expr)
+	}
+
+	s := &spec{}
+	var err error
+	for i, f := range []struct {
+		bits *uint64
+		b    bounds
+	}{
+		{&s.second, seconds},
+		{&s.minute, minutes},
+		{&s.hour, hours},
+		{&s.dom, doms},
+		{&s.month, months},
+		{&s.dow, dows},
+	} {
+		if *f.bits, err = parseField(fields[i], f.b); err != nil {
+			return nil, fmt.Errorf("cron: %v in %q", err, expr)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = fields[3] == "*" || fields[3] == "?"
+	s.dowStar = fields[5] == "*" || fields[5] == "?"
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeStep := strings.SplitN(part, "/", 2)
+		lowHigh := strings.SplitN(rangeStep[0], "-", 2)
+		start, end := b.min, b.max
+		if lowHigh[0] != "*" && lowHigh[0] != "?" {
+			var err error
+			if start, err = parseValue(lowHigh[0], b); err != nil {
+				return 0, err
+			}
+			end = start
+			if len(lowHigh) == 2 {
+				if end, err = parseValue(lowHigh[1], b); err != nil {
+					return 0, err
+				}
+			} else if len(rangeStep) == 2 {
+				// a/n steps from a to the max
+				end = b.max
+			}
+		} else if len(lowHigh) == 2 {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+		step := 1
+		if len(rangeStep) == 2 {
+			n, err := strconv.Atoi(rangeStep[1])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			step = n
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if n, ok := b.names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < b.min || n > b.max {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return n, nil
+}
+
+func (s *spec) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for 1<<uint(t.Month())&s.month == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	for 1<<uint(t.Second())&s.second == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, loc)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+func (s *spec) dayMatches(t time.Time) bool {
+	dom := 1<<uint(t.Day())&s.dom != 0
+	dow := 1<<uint(t.Weekday())&s.dow != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e) - time.Duration(t.Nanosecond()))
+}
@@ -0,0 +1,48 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2016, 2, 27, 10, 30, 15, 500, time.UTC) // Saturday
+	for _, c := range []struct {
+		expr, next string
+	}{
+		{"* * * * *", "2016-02-27 10:31:00"},
+		{"*/20 * * * * *", "2016-02-27 10:30:20"},
+		{"0 9 * * MON-FRI", "2016-02-29 09:00:00"},
+		{"0 0 29 2 *", "2016-02-29 00:00:00"},
+		{"0 0 30 2 *", ""},
+		{"15,45 10 * * *", "2016-02-27 10:45:00"},
+		{"0 0 1 * 7", "2016-02-28 00:00:00"}, // the 1st or a Sunday
+		{"0 0 13 * 5", "2016-03-04 00:00:00"},
+		{"5/20 * * * *", "2016-02-27 10:45:00"},
+		{"@monthly", "2016-03-01 00:00:00"},
+		{"@yearly", "2017-01-01 00:00:00"},
+		{"@every 90s", "2016-02-27 10:31:45"},
+	} {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", c.expr, err)
+			continue
+		}
+		next := s.Next(from)
+		got := ""
+		if !next.IsZero() {
+			got = next.Format("2006-01-02 15:04:05")
+		}
+		if got != c.next {
+			t.Errorf("Parse(%q).Next() = %q, want %q", c.expr, got, c.next)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "* * * FOO *", "*-5 * * * *", "@every 1ms"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) should fail", expr)
+		}
+	}
+}
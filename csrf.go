@@ -0,0 +1,85 @@
+package lessgo
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"net/http"
+	"time"
+)
+
+// CSRFConfig defines the config for CSRF middleware.
+type CSRFConfig struct {
+	// 存放签名密钥的Cookie
+	CookieName   string
+	CookiePath   string
+	CookieSecure bool
+
+	// 提交令牌的请求头与表单字段
+	HeaderName string
+	FormField  string
+
+	// 令牌与Cookie的有效期，单位秒
+	MaxAgeSeconds int64
+}
+
+var CSRF = ApiMiddleware{
+	Name: "CSRF防护",
+	Desc: "以应用密钥环签名的Cookie保存随机密钥，要求POST、PUT、PATCH、DELETE请求经请求头或表单字段提交匹配的令牌；令牌以CSRF_TOKEN_KEY存入请求上下文，模板中可用csrf_token输出",
+	Config: CSRFConfig{
+		CookieName:    "_csrf",
+		CookiePath:    "/",
+		CookieSecure:  false,
+		HeaderName:    HeaderXCSRFToken,
+		FormField:     "csrf_token",
+		MaxAgeSeconds: 86400,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(CSRFConfig)
+		maxAge := time.Duration(config.MaxAgeSeconds) * time.Second
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				var secret []byte
+				if cookie := c.CookieParam(config.CookieName); cookie != nil {
+					secret, _ = secureKeys.Verify("csrf", cookie.Value, maxAge)
+				}
+				if len(secret) == 0 {
+					secret = make([]byte, 32)
+					if _, err := rand.Read(secret); err != nil {
+						return err
+					}
+					value, err := secureKeys.Sign("csrf", secret)
+					if err != nil {
+						return err
+					}
+					c.response.AddCookie(&http.Cookie{
+						Name:     config.CookieName,
+						Value:    value,
+						Path:     config.CookiePath,
+						MaxAge:   int(config.MaxAgeSeconds),
+						Secure:   config.CookieSecure,
+						HttpOnly: true,
+						SameSite: http.SameSiteLaxMode,
+					})
+				}
+				switch c.request.Method {
+				case POST, PUT, PATCH, DELETE:
+					token := c.request.Header.Get(config.HeaderName)
+					if token == "" && config.FormField != "" {
+						token = c.FormParam(config.FormField)
+					}
+					got, err := secureKeys.Decrypt("csrf-token", token, maxAge)
+					if err != nil || !hmac.Equal(got, secret) {
+						return NewHTTPError(http.StatusForbidden, "invalid CSRF token")
+					}
+				}
+				// 每次生成不同的令牌，避免页面中的令牌被压缩侧信道攻击推断
+				token, err := secureKeys.Encrypt("csrf-token", secret)
+				if err != nil {
+					return err
+				}
+				c.Set(CSRF_TOKEN_KEY, token)
+				return next(c)
+			}
+		}
+	},
+}.Reg()
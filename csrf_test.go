@@ -0,0 +1,97 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	h := CSRF.Middleware.(Middleware).getMiddlewareFunc(CSRF.Config)(func(c *Context) error {
+		return c.String(http.StatusOK, c.Get(CSRF_TOKEN_KEY).(string))
+	})
+	do := func(method string, cookie *http.Cookie, token, field string) (int, string, *http.Cookie) {
+		var body *strings.Reader
+		if field != "" {
+			body = strings.NewReader(url.Values{"csrf_token": {field}}.Encode())
+		} else {
+			body = strings.NewReader("")
+		}
+		req, _ := http.NewRequest(method, "/", body)
+		if field != "" {
+			req.Header.Set(HeaderContentType, MIMEApplicationForm)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		if token != "" {
+			req.Header.Set(HeaderXCSRFToken, token)
+		}
+		c, rec := testContext(req)
+		defer c.free()
+		if err := h(c); err != nil {
+			return err.(*HTTPError).Code, "", nil
+		}
+		var set *http.Cookie
+		for _, ck := range (&http.Response{Header: rec.Header()}).Cookies() {
+			if ck.Name == "_csrf" {
+				set = ck
+			}
+		}
+		return rec.Code, rec.Body.String(), set
+	}
+
+	code, token, cookie := do(GET, nil, "", "")
+	if code != http.StatusOK || token == "" || cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("GET = %d %q %v", code, token, cookie)
+	}
+	if code, token2, set := do(GET, cookie, "", ""); code != http.StatusOK || token2 == token || set != nil {
+		t.Errorf("GET with cookie = %d %q %v", code, token2, set)
+	}
+	if code, _, _ := do(POST, cookie, token, ""); code != http.StatusOK {
+		t.Errorf("POST with token = %d", code)
+	}
+	if code, _, _ := do(PUT, cookie, "", token); code != http.StatusOK {
+		t.Errorf("PUT with form token = %d", code)
+	}
+	if code, _, _ := do(POST, cookie, "", ""); code != http.StatusForbidden {
+		t.Errorf("POST without token = %d", code)
+	}
+	if code, _, _ := do(DELETE, nil, token, ""); code != http.StatusForbidden {
+		t.Errorf("DELETE without cookie = %d", code)
+	}
+	_, other, _ := do(GET, nil, "", "")
+	if code, _, _ := do(POST, cookie, other, ""); code != http.StatusForbidden {
+		t.Errorf("POST with token of another cookie = %d", code)
+	}
+	forged := &http.Cookie{Name: "_csrf", Value: "forged"}
+	if code, _, _ := do(POST, forged, token, ""); code != http.StatusForbidden {
+		t.Errorf("POST with forged cookie = %d", code)
+	}
+}
+
+func TestSecureCookie(t *testing.T) {
+	req, _ := http.NewRequest(GET, "/", nil)
+	c, rec := testContext(req)
+	if err := c.SetSecureCookie(&http.Cookie{Name: "uid", Value: "42", Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	c.free()
+	cookies := (&http.Response{Header: rec.Header()}).Cookies()
+	if len(cookies) != 1 || cookies[0].Value == "42" || cookies[0].Path != "/" {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	req, _ = http.NewRequest(GET, "/", nil)
+	req.AddCookie(cookies[0])
+	req.AddCookie(&http.Cookie{Name: "other", Value: cookies[0].Value})
+	c, _ = testContext(req)
+	defer c.free()
+	if v, err := c.SecureCookieParam("uid", 0); err != nil || v != "42" {
+		t.Errorf("uid = %q, %v", v, err)
+	}
+	if _, err := c.SecureCookieParam("other", 0); err == nil {
+		t.Error("value of another cookie accepted")
+	}
+}
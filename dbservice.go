@@ -0,0 +1,291 @@
+package lessgo
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	confpkg "github.com/lessgo/lessgo/config"
+)
+
+type (
+	// 数据库连接池配置，对应配置中的[db.<name>]段，如：
+	//
+	//	[db.orders]
+	//	driver = mysql
+	//	dsn = ${secret:orders_dsn}
+	//	max_open = 50
+	//
+	// 使用的驱动须由应用导入，如_ "github.com/go-sql-driver/mysql"
+	DBConfig struct {
+		Driver      string        `validate:"required"`
+		DSN         string        `validate:"required"`
+		MaxOpen     int           `config:"max_open" validate:"min=0"`             // 最大连接数，0为不限
+		MaxIdle     int           `config:"max_idle" default:"2" validate:"min=0"` // 最大空闲连接数
+		MaxLifetime time.Duration `config:"max_lifetime"`                          // 连接最长复用时间，0为不限
+		HealthCheck time.Duration `config:"health_check" default:"30s"`            // 健康检查间隔，0为不检查
+	}
+
+	// 数据库连接池状态
+	DBStats struct {
+		sql.DBStats
+		Name      string    `json:"name"`
+		Driver    string    `json:"driver"`
+		Healthy   bool      `json:"healthy"`
+		Error     string    `json:"error,omitempty"`
+		CheckedAt time.Time `json:"checked_at"`
+		Latency   string    `json:"latency"` // 最近一次健康检查的耗时
+	}
+
+	dbPool struct {
+		name    string
+		db      *sql.DB
+		conf    DBConfig
+		stop    chan struct{}
+		lock    sync.RWMutex
+		err     error
+		checked time.Time
+		latency time.Duration
+	}
+)
+
+// 配置中数据库连接池段名的前缀
+const DB_SECTION_PREFIX = "db."
+
+var (
+	dbPools      = map[string]*dbPool{}
+	dbPoolsLock  sync.RWMutex
+	dbConfigOnce sync.Once
+	dbCloseOnce  sync.Once
+)
+
+// 注册名为name的数据库连接池，同名的连接池将被关闭并替换；
+// 配置中[db.<name>]段定义的连接池在首次使用或启动服务时自动注册
+func RegisterDB(name string, conf DBConfig) error {
+	db, err := sql.Open(conf.Driver, conf.DSN)
+	if err != nil {
+		return fmt.Errorf("Open DB %s failed: %v.", name, err)
+	}
+	db.SetMaxOpenConns(conf.MaxOpen)
+	db.SetMaxIdleConns(conf.MaxIdle)
+	db.SetConnMaxLifetime(conf.MaxLifetime)
+
+	p := &dbPool{name: name, db: db, conf: conf, stop: make(chan struct{})}
+	dbPoolsLock.Lock()
+	old := dbPools[name]
+	dbPools[name] = p
+	dbPoolsLock.Unlock()
+	if old != nil {
+		old.close()
+	}
+	dbCloseOnce.Do(func() {
+		OnShutdown(closeDBs)
+	})
+	if conf.HealthCheck > 0 {
+		go p.healthCheck(conf.HealthCheck)
+	}
+	return nil
+}
+
+// 返回名为name的数据库连接池，不存在时返回错误
+func GetDB(name string) (*sql.DB, error) {
+	loadDBConfigs()
+	dbPoolsLock.RLock()
+	p := dbPools[name]
+	dbPoolsLock.RUnlock()
+	if p == nil {
+		return nil, fmt.Errorf("DB %s is not registered.", name)
+	}
+	return p.db, nil
+}
+
+// 返回名为name的数据库连接池，不存在时返回nil
+func (c *Context) DB(name string) *sql.DB {
+	db, err := GetDB(name)
+	if err != nil {
+		Log.Error("%v", err)
+	}
+	return db
+}
+
+// 返回所有数据库连接池的状态，按名称排序
+func DBStatsAll() []DBStats {
+	loadDBConfigs()
+	dbPoolsLock.RLock()
+	names := make([]string, 0, len(dbPools))
+	for name := range dbPools {
+		names = append(names, name)
+	}
+	dbPoolsLock.RUnlock()
+	sort.Strings(names)
+
+	stats := make([]DBStats, 0, len(names))
+	for _, name := range names {
+		dbPoolsLock.RLock()
+		p := dbPools[name]
+		dbPoolsLock.RUnlock()
+		if p == nil {
+			continue
+		}
+		p.lock.RLock()
+		s := DBStats{
+			DBStats:   p.db.Stats(),
+			Name:      name,
+			Driver:    p.conf.Driver,
+			Healthy:   p.err == nil,
+			CheckedAt: p.checked,
+			Latency:   p.latency.String(),
+		}
+		if p.err != nil {
+			s.Error = p.err.Error()
+		}
+		p.lock.RUnlock()
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// 检查所有数据库连接池的连通性，有不可用的连接池时返回错误
+func CheckDBs() error {
+	loadDBConfigs()
+	dbPoolsLock.RLock()
+	pools := make(map[string]*dbPool, len(dbPools))
+	for name, p := range dbPools {
+		pools[name] = p
+	}
+	dbPoolsLock.RUnlock()
+	var errs []string
+	for name, p := range pools {
+		if err := p.ping(); err != nil {
+			errs = append(errs, name+": "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("DB unavailable: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// 查询数据库连接池状态的操作，供后台管理路由使用
+var DBStatsHandler = ApiHandler{
+	Desc:   "查询数据库连接池状态",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, DBStatsAll())
+	},
+}.Reg()
+
+// 注册配置中定义的数据库连接池，只执行一次
+func loadDBConfigs() {
+	dbConfigOnce.Do(func() {
+		confs, err := dbConfigs()
+		if err != nil {
+			Log.Error("%v", err)
+		}
+		names := make([]string, 0, len(confs))
+		for name := range confs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := RegisterDB(name, confs[name]); err != nil {
+				Log.Error("%v", err)
+				continue
+			}
+			Log.Sys("> Registered DB %s (%s)", name, confs[name].Driver)
+		}
+	})
+}
+
+// 读取配置中[db.<name>]段定义的连接池
+func dbConfigs() (map[string]DBConfig, error) {
+	values, serrs := bindValues()
+	sections := map[string]confpkg.Configer{}
+	for k, v := range values {
+		i := strings.Index(k, "::")
+		if i < 0 || !strings.HasPrefix(k, DB_SECTION_PREFIX) {
+			continue
+		}
+		name := k[len(DB_SECTION_PREFIX):i]
+		if sections[name] == nil {
+			sections[name] = confpkg.NewFakeConfig()
+		}
+		sections[name].Set(k[i+2:], v)
+	}
+
+	var errs []string
+	for _, fe := range serrs {
+		if strings.HasPrefix(fe.Key, DB_SECTION_PREFIX) {
+			errs = append(errs, fe.Error())
+		}
+	}
+	confs := make(map[string]DBConfig, len(sections))
+	for name, section := range sections {
+		var conf DBConfig
+		if err := confpkg.Bind(section, &conf); err != nil {
+			errs = append(errs, fmt.Sprintf("[%s%s] %v", DB_SECTION_PREFIX, name, err))
+			continue
+		}
+		confs[name] = conf
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return confs, fmt.Errorf("Invalid DB config: %s", strings.Join(errs, "\n"))
+	}
+	return confs, nil
+}
+
+// 关闭所有数据库连接池，在服务退出时执行
+func closeDBs() {
+	dbPoolsLock.Lock()
+	pools := dbPools
+	dbPools = map[string]*dbPool{}
+	dbPoolsLock.Unlock()
+	for name, p := range pools {
+		if err := p.close(); err != nil {
+			Log.Error("Close DB %s failed: %v", name, err)
+		}
+	}
+}
+
+func (p *dbPool) ping() error {
+	start := time.Now()
+	err := p.db.Ping()
+	p.lock.Lock()
+	p.err = err
+	p.checked = time.Now()
+	p.latency = p.checked.Sub(start)
+	p.lock.Unlock()
+	return err
+}
+
+func (p *dbPool) healthCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.lock.RLock()
+			healthy := p.err == nil
+			p.lock.RUnlock()
+			err := p.ping()
+			if err != nil && healthy {
+				Log.Error("DB %s is unavailable: %v", p.name, err)
+			} else if err == nil && !healthy {
+				Log.Sys("DB %s is available again", p.name)
+			}
+		}
+	}
+}
+
+func (p *dbPool) close() error {
+	close(p.stop)
+	return p.db.Close()
+}
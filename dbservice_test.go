@@ -0,0 +1,80 @@
+package lessgo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	if dsn == "down" {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+// 提交与回滚的次数
+var fakeCommits, fakeRollbacks int32
+
+func (fakeTx) Commit() error   { atomic.AddInt32(&fakeCommits, 1); return nil }
+func (fakeTx) Rollback() error { atomic.AddInt32(&fakeRollbacks, 1); return nil }
+
+func init() {
+	sql.Register("lessgofake", fakeDriver{})
+}
+
+func TestDBService(t *testing.T) {
+	fname := CONFIG_DIR + "/app.yaml"
+	if _, err := os.Stat(fname); err == nil {
+		t.Skip(fname + " exists")
+	}
+	content := "db.orders:\n  driver: lessgofake\n  dsn: orders\n  max_open: 5\ndb.broken:\n  driver: lessgofake\n  max_idle: -1\n"
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+
+	confs, err := dbConfigs()
+	if err == nil {
+		t.Error("dbConfigs() should report the invalid pool")
+	}
+	if _, ok := confs["broken"]; ok || confs["orders"].MaxOpen != 5 || confs["orders"].MaxIdle != 2 {
+		t.Fatalf("dbConfigs() = %+v", confs)
+	}
+
+	if err := RegisterDB("orders", confs["orders"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDB("users", DBConfig{Driver: "lessgofake", DSN: "down"}); err != nil {
+		t.Fatal(err)
+	}
+	defer closeDBs()
+	if db, err := GetDB("orders"); err != nil || db == nil {
+		t.Fatalf("GetDB() = %v, %v", db, err)
+	}
+	if _, err := GetDB("missing"); err == nil {
+		t.Error("GetDB(missing) should fail")
+	}
+	if err := CheckDBs(); err == nil {
+		t.Error("CheckDBs() should fail")
+	}
+	stats := DBStatsAll()
+	if len(stats) != 2 || stats[0].Name != "orders" || !stats[0].Healthy || stats[1].Healthy ||
+		stats[0].MaxOpenConnections != 5 {
+		t.Errorf("DBStatsAll() = %+v", stats)
+	}
+}
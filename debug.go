@@ -0,0 +1,73 @@
+package lessgo
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+)
+
+// 访问调试操作所需的权限，由权限控制中间件校验
+const DEBUG_PERMISSION = "admin:debug"
+
+// 性能剖析操作，name为profile(CPU)、trace、heap、goroutine、block、mutex、allocs、threadcreate、cmdline、symbol，
+// 或index列出全部；仅在挂载后可访问，应挂载于需认证的后台路由，见DebugRoutes
+var PprofHandler = ApiHandler{
+	Desc:        "性能剖析(pprof)",
+	Method:      "GET|POST",
+	Params:      []Param{{"name", "path", true, "index", "剖析项，index列出全部"}},
+	Permissions: []string{DEBUG_PERMISSION},
+	Handler: func(c *Context) error {
+		w, r := c.response, c.request
+		switch name := c.PathParam("name"); name {
+		case "", "index":
+			pprof.Index(w, r)
+		case "cmdline":
+			pprof.Cmdline(w, r)
+		case "profile":
+			pprof.Profile(w, r)
+		case "symbol":
+			pprof.Symbol(w, r)
+		case "trace":
+			pprof.Trace(w, r)
+		default:
+			pprof.Handler(name).ServeHTTP(w, r)
+		}
+		return nil
+	},
+}.Reg()
+
+// 以JSON输出expvar发布的变量(含memstats与cmdline)
+var ExpvarHandler = ApiHandler{
+	Desc:        "运行时变量(expvar)",
+	Method:      "GET",
+	Permissions: []string{DEBUG_PERMISSION},
+	Handler: func(c *Context) error {
+		w := c.response
+		w.Header().Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "{\n")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+		})
+		fmt.Fprint(w, "\n}\n")
+		return nil
+	},
+}.Reg()
+
+// 返回挂载pprof(prefix/pprof/:name)与expvar(prefix/vars)的路由分组，需显式挂载并配置认证，如：
+//
+//	lessgo.Root(lessgo.DebugRoutes("/admin/debug", auth, lessgo.RBAC))
+//
+// 其中auth为在Context中设置当前用户的认证中间件，RBAC校验该用户拥有DEBUG_PERMISSION权限
+func DebugRoutes(prefix string, middlewares ...*ApiMiddleware) *VirtRouter {
+	return Branch(prefix, "调试",
+		Leaf("/pprof", PprofHandler, middlewares...),
+		Leaf("/vars", ExpvarHandler, middlewares...),
+	)
+}
@@ -0,0 +1,35 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandlers(t *testing.T) {
+	req, _ := http.NewRequest(GET, "/admin/debug/vars", nil)
+	c, rec := testContext(req)
+	if err := ExpvarHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil || vars["memstats"] == nil {
+		t.Errorf("expvar = %s, %v", rec.Body.String(), err)
+	}
+
+	req, _ = http.NewRequest(GET, "/admin/debug/pprof/goroutine?debug=1", nil)
+	c, rec = testContext(req)
+	c.pkeys, c.pvalues = []string{"name"}, []string{"goroutine"}
+	if err := PprofHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rec.Body.String(), "goroutine profile:") {
+		t.Errorf("pprof goroutine = %.200s", rec.Body.String())
+	}
+
+	vr := DebugRoutes("/admin/debug", RBAC)
+	if children := vr.Children; len(children) != 2 || children[0].apiHandler.Permissions[0] != DEBUG_PERMISSION {
+		t.Errorf("DebugRoutes() = %+v", children)
+	}
+}
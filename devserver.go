@@ -0,0 +1,168 @@
+package lessgo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 由lessgo run设置的环境变量
+const (
+	// 继承的监听套接字的文件描述符；lessgo run持有监听套接字，重启应用期间的连接在队列中等待而不被拒绝
+	DEV_LISTENER_ENV = "LESSGO_DEV_LISTENER"
+	// 本次构建的标识，非空时调试模式下向HTML响应注入自动刷新脚本
+	LIVERELOAD_ENV = "LESSGO_LIVERELOAD"
+)
+
+// 自动刷新脚本与事件流的路径
+const (
+	LIVERELOAD_PATH   = "/__lessgo/livereload"
+	LIVERELOAD_SCRIPT = LIVERELOAD_PATH + ".js"
+)
+
+// 模板变化的次数，页面据此在模板重新编译后刷新
+var liveReloadVersion int32
+
+// 事件流的心跳间隔
+var liveReloadHeartbeat = 15 * time.Second
+
+// 返回继承自lessgo run的监听器，未设置DEV_LISTENER_ENV时返回nil
+func devListener() (net.Listener, error) {
+	s := os.Getenv(DEV_LISTENER_ENV)
+	if s == "" {
+		return nil, nil
+	}
+	fd, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s=%q: %v", DEV_LISTENER_ENV, s, err)
+	}
+	f := os.NewFile(uintptr(fd), "lessgo-dev-listener")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// 是否由lessgo run启动并启用了自动刷新
+func liveReloadEnabled() bool {
+	return os.Getenv(LIVERELOAD_ENV) != ""
+}
+
+// 当前的页面版本：构建标识加模板变化次数
+func liveReloadID() string {
+	return os.Getenv(LIVERELOAD_ENV) + "." + strconv.Itoa(int(atomic.LoadInt32(&liveReloadVersion)))
+}
+
+var LiveReload = ApiMiddleware{
+	Name: "开发模式自动刷新",
+	Desc: "由lessgo run启动时在调试模式下向HTML响应注入自动刷新脚本，应用重新构建或模板变化后浏览器自动刷新页面",
+	Middleware: func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			if !app.Debug() {
+				return next(c)
+			}
+			switch c.request.URL.Path {
+			case LIVERELOAD_SCRIPT:
+				h := c.response.Header()
+				h.Set(HeaderContentType, MIMEApplicationJavaScriptCharsetUTF8)
+				h.Set(HeaderCacheControl, "no-store")
+				c.response.WriteHeader(http.StatusOK)
+				_, err := c.response.Write([]byte(liveReloadJS))
+				return err
+			case LIVERELOAD_PATH:
+				return liveReloadEvents(c)
+			}
+			w := &liveReloadWriter{responseWriterWrapper: responseWriterWrapper{c.response.writer}}
+			c.response.writer = w
+			defer func() {
+				c.response.writer = w.ResponseWriter
+				if w.inject {
+					n, _ := w.ResponseWriter.Write([]byte(liveReloadSnippet))
+					c.response.size += int64(n)
+				}
+			}()
+			return next(c)
+		}
+	},
+}.Reg()
+
+// 以事件流推送页面版本，版本变化时推送新版本；应用重启后浏览器重新连接并收到新版本
+func liveReloadEvents(c *Context) error {
+	h := c.response.Header()
+	h.Set(HeaderContentType, "text/event-stream")
+	h.Set(HeaderCacheControl, "no-store")
+	c.response.WriteHeader(http.StatusOK)
+	id := liveReloadID()
+	fmt.Fprintf(c.response, "retry: 500\ndata: %s\n\n", id)
+	c.response.Flush()
+	check := time.NewTicker(templateWatchInterval)
+	defer check.Stop()
+	last := time.Now()
+	ctx := c.request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-check.C:
+		}
+		if cur := liveReloadID(); cur != id {
+			id = cur
+			fmt.Fprintf(c.response, "data: %s\n\n", id)
+		} else if time.Since(last) >= liveReloadHeartbeat {
+			c.response.Write([]byte(": ping\n\n"))
+		} else {
+			continue
+		}
+		last = time.Now()
+		c.response.Flush()
+	}
+}
+
+// 在HTML响应末尾追加自动刷新脚本的ResponseWriter
+type liveReloadWriter struct {
+	responseWriterWrapper
+	decided bool
+	inject  bool
+}
+
+func (w *liveReloadWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	h := w.Header()
+	if h.Get(HeaderContentEncoding) != "" || !strings.HasPrefix(h.Get(HeaderContentType), MIMETextHTML) {
+		return
+	}
+	w.inject = true
+	h.Del(HeaderContentLength)
+}
+
+func (w *liveReloadWriter) WriteHeader(code int) {
+	w.decide()
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified {
+		w.inject = false
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *liveReloadWriter) Write(b []byte) (int, error) {
+	w.decide()
+	return w.ResponseWriter.Write(b)
+}
+
+// 以外部脚本引入，不受Content-Security-Policy对内联脚本的限制
+const liveReloadSnippet = "\n<script src=\"" + LIVERELOAD_SCRIPT + "\"></script>\n"
+
+const liveReloadJS = `(function () {
+	if (!window.EventSource) return;
+	var id;
+	new EventSource("` + LIVERELOAD_PATH + `").onmessage = function (e) {
+		if (id && id !== e.data) location.reload();
+		id = e.data;
+	};
+})();
+`
@@ -0,0 +1,72 @@
+package lessgo
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLiveReload(t *testing.T) {
+	h := LiveReload.Middleware.(Middleware).getMiddlewareFunc(LiveReload.Config)(func(c *Context) error {
+		if c.request.URL.Path == "/api" {
+			return c.JSON(http.StatusOK, "ok")
+		}
+		c.response.Header().Set(HeaderContentLength, "13")
+		return c.HTML(http.StatusOK, "<p>page</p>\n")
+	})
+	do := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(GET, path, nil)
+		c, rec := testContext(req)
+		defer c.free()
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	rec := do("/page")
+	if body := rec.Body.String(); body != "<p>page</p>\n"+liveReloadSnippet || rec.Header().Get(HeaderContentLength) != "" {
+		t.Errorf("page = %q, headers %v", body, rec.Header())
+	}
+	if body := do("/api").Body.String(); strings.Contains(body, LIVERELOAD_SCRIPT) {
+		t.Errorf("api = %q", body)
+	}
+	rec = do(LIVERELOAD_SCRIPT)
+	if body := rec.Body.String(); !strings.Contains(body, LIVERELOAD_PATH) || !strings.HasPrefix(rec.Header().Get(HeaderContentType), MIMEApplicationJavaScript) {
+		t.Errorf("script = %q", body)
+	}
+}
+
+func TestDevListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ln.(*net.TCPListener).File()
+	ln.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	t.Setenv(DEV_LISTENER_ENV, strconv.Itoa(int(f.Fd())))
+	dl, err := devListener()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dl.Close()
+	if dl.Addr().String() != ln.Addr().String() {
+		t.Errorf("addr = %v, want %v", dl.Addr(), ln.Addr())
+	}
+	go func() {
+		if conn, err := dl.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("tcp", dl.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
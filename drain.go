@@ -0,0 +1,127 @@
+package lessgo
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 监听地址的连接与请求数
+type ListenerStats struct {
+	Connections int64 `json:"connections"` // 打开的连接数，不含已被接管的(如websocket)
+	Idle        int64 `json:"idle"`        // 其中空闲(保持连接等待下一请求)的连接数
+	Requests    int64 `json:"requests"`    // 进行中的请求数
+}
+
+// 监听地址的连接与请求计数器
+type listenerCounter struct {
+	conns     int64
+	idle      int64
+	requests  int64
+	idleConns sync.Map // 当前空闲的连接
+}
+
+// 返回各监听地址的连接与请求数
+func ReadListenerStats() map[string]ListenerStats {
+	listenerCountersLock.Lock()
+	defer listenerCountersLock.Unlock()
+	stats := make(map[string]ListenerStats, len(listenerCounters))
+	for addr, lc := range listenerCounters {
+		stats[addr] = lc.stats()
+	}
+	return stats
+}
+
+func getListenerCounter(addr string) *listenerCounter {
+	listenerCountersLock.Lock()
+	defer listenerCountersLock.Unlock()
+	lc := listenerCounters[addr]
+	if lc == nil {
+		lc = new(listenerCounter)
+		listenerCounters[addr] = lc
+	}
+	return lc
+}
+
+func (lc *listenerCounter) stats() ListenerStats {
+	return ListenerStats{
+		Connections: atomic.LoadInt64(&lc.conns),
+		Idle:        atomic.LoadInt64(&lc.idle),
+		Requests:    atomic.LoadInt64(&lc.requests),
+	}
+}
+
+// http.Server.ConnState回调
+func (lc *listenerCounter) connState(conn net.Conn, state http.ConnState) {
+	if state == http.StateIdle {
+		if _, loaded := lc.idleConns.LoadOrStore(conn, struct{}{}); !loaded {
+			atomic.AddInt64(&lc.idle, 1)
+		}
+		return
+	}
+	if _, loaded := lc.idleConns.LoadAndDelete(conn); loaded {
+		atomic.AddInt64(&lc.idle, -1)
+	}
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&lc.conns, 1)
+	case http.StateHijacked, http.StateClosed:
+		atomic.AddInt64(&lc.conns, -1)
+	}
+}
+
+// 包装服务的处理器，统计进行中的请求数
+func (lc *listenerCounter) countRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&lc.requests, 1)
+		defer atomic.AddInt64(&lc.requests, -1)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// 退出时等待连接排空的时限，0为不限
+func drainTimeout() time.Duration {
+	if Config.Listen.DrainTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(Config.Listen.DrainTimeout) * time.Second
+}
+
+// 开始排空：就绪检查失败，并按配置关闭保持连接，此后的响应带有Connection: close，空闲连接被关闭
+func startDrain(server *http.Server) {
+	setDraining()
+	if Config.Listen.DrainConnClose {
+		server.SetKeepAlivesEnabled(false)
+	}
+}
+
+// 等待监听地址的连接全部关闭(不含被接管的连接)，每秒记录一次进度；
+// 超过timeout(<=0时不限)仍未排空时调用onDeadline(不为nil时)并返回false
+func waitDrain(addr string, lc *listenerCounter, timeout time.Duration, onDeadline func()) bool {
+	start := time.Now()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	lastReport := start
+	for {
+		s := lc.stats()
+		if s.Connections <= 0 {
+			Log.Sys("> Drained %s in %v", addr, time.Since(start).Round(time.Millisecond))
+			return true
+		}
+		elapsed := time.Since(start)
+		if timeout > 0 && elapsed >= timeout {
+			Log.Warn("> Drain deadline %v of %s exceeded, closing %d connections with %d requests in flight", timeout, addr, s.Connections, s.Requests)
+			if onDeadline != nil {
+				onDeadline()
+			}
+			return false
+		}
+		if time.Since(lastReport) >= time.Second {
+			lastReport = time.Now()
+			Log.Sys("> Draining %s: %d requests in flight, %d connections open (%d idle), %v elapsed", addr, s.Requests, s.Connections, s.Idle, elapsed.Round(time.Second))
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,104 @@
+package lessgo
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListenerCounter(t *testing.T) {
+	lc := new(listenerCounter)
+	a, b := &net.TCPConn{}, &net.TCPConn{}
+	for _, s := range []struct {
+		conn  net.Conn
+		state http.ConnState
+	}{
+		{a, http.StateNew}, {a, http.StateActive}, {a, http.StateIdle},
+		{b, http.StateNew}, {b, http.StateActive}, {b, http.StateIdle}, {b, http.StateActive},
+	} {
+		lc.connState(s.conn, s.state)
+	}
+	if s := lc.stats(); s.Connections != 2 || s.Idle != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	lc.connState(a, http.StateClosed)
+	lc.connState(b, http.StateHijacked)
+	if s := lc.stats(); s.Connections != 0 || s.Idle != 0 {
+		t.Errorf("stats after close = %+v", s)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	old := Config.Listen.DrainConnClose
+	Config.Listen.DrainConnClose = true
+	defer func() { Config.Listen.DrainConnClose = old }()
+
+	lc := new(listenerCounter)
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	server := &http.Server{
+		Handler: lc.countRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				started <- struct{}{}
+				<-release
+			}
+			io.WriteString(w, "ok")
+		})),
+		ConnState: lc.connState,
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+	url := "http://" + ln.Addr().String()
+
+	// 一个空闲的保持连接与一个进行中的请求
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Get(url + "/fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	var (
+		wg   sync.WaitGroup
+		slow *http.Response
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		slow, _ = (&http.Client{Transport: &http.Transport{}}).Get(url + "/slow")
+	}()
+	<-started
+	if s := lc.stats(); s.Connections != 2 || s.Idle != 1 || s.Requests != 1 {
+		t.Errorf("stats before drain = %+v", s)
+	}
+
+	startDrain(server)
+	defer atomic.StoreInt32(&draining, 0)
+	ln.Close()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if !waitDrain("test", lc, 5*time.Second, nil) {
+		t.Fatal("not drained")
+	}
+	wg.Wait()
+	if slow == nil || !slow.Close {
+		t.Errorf("response during drain should close the connection: %+v", slow)
+	}
+
+	// 超过时限
+	atomic.StoreInt64(&lc.conns, 1)
+	var closed bool
+	if waitDrain("test", lc, 20*time.Millisecond, func() { closed = true }) || !closed {
+		t.Error("deadline not enforced")
+	}
+}
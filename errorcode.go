@@ -0,0 +1,150 @@
+package lessgo
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// 错误码定义
+	ErrorCode struct {
+		Code    string `json:"code"`              // 如ORDER_NOT_FOUND
+		Status  int    `json:"status"`            // HTTP状态码，默认500
+		Message string `json:"message"`           // 消息模板，按fmt格式化Err的参数
+		DocURL  string `json:"doc_url,omitempty"` // 说明文档链接
+	}
+
+	// 由Err创建的错误码错误，返回后按统一格式输出：
+	// {"code":"ORDER_NOT_FOUND","message":"order 42 not found","doc_url":"..."}
+	CodedError struct {
+		Code    string
+		Status  int
+		Message string
+		DocURL  string
+		Args    []interface{}
+	}
+
+	// 错误码及其返回次数
+	ErrorCodeStats struct {
+		ErrorCode
+		Count uint64 `json:"count"`
+	}
+
+	errorCodeEntry struct {
+		count uint64
+		ErrorCode
+	}
+)
+
+var (
+	errorCodes     = map[string]*errorCodeEntry{}
+	errorCodesLock sync.RWMutex
+)
+
+// 注册错误码，同码的将被替换，如：
+//
+//	lessgo.RegisterErrorCode(lessgo.ErrorCode{
+//		Code:    "ORDER_NOT_FOUND",
+//		Status:  http.StatusNotFound,
+//		Message: "order %d not found",
+//		DocURL:  "https://docs.example.com/errors#ORDER_NOT_FOUND",
+//	})
+func RegisterErrorCode(codes ...ErrorCode) {
+	errorCodesLock.Lock()
+	for _, ec := range codes {
+		if ec.Status == 0 {
+			ec.Status = http.StatusInternalServerError
+		}
+		errorCodes[ec.Code] = &errorCodeEntry{ErrorCode: ec}
+	}
+	errorCodesLock.Unlock()
+}
+
+// 返回已注册的错误码
+func GetErrorCode(code string) (ErrorCode, bool) {
+	errorCodesLock.RLock()
+	ec, ok := errorCodes[code]
+	errorCodesLock.RUnlock()
+	if !ok {
+		return ErrorCode{}, false
+	}
+	return ec.ErrorCode, true
+}
+
+// 创建错误码错误，args用于格式化消息模板；未注册的错误码按500处理并以错误码为消息，如：
+//
+//	return lessgo.Err("ORDER_NOT_FOUND", id)
+func Err(code string, args ...interface{}) error {
+	e := &CodedError{Code: code, Status: http.StatusInternalServerError, Message: code, Args: args}
+	if ec, ok := GetErrorCode(code); ok {
+		e.Status = ec.Status
+		e.DocURL = ec.DocURL
+		if ec.Message != "" {
+			e.Message = ec.Message
+			if len(args) > 0 {
+				e.Message = fmt.Sprintf(ec.Message, args...)
+			}
+		}
+	} else {
+		Log.Warn("Error code %s is not registered.", code)
+	}
+	return e
+}
+
+func (e *CodedError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// 返回全部错误码及其返回次数，按错误码排序
+func ErrorCodeStatsAll() []ErrorCodeStats {
+	errorCodesLock.RLock()
+	stats := make([]ErrorCodeStats, 0, len(errorCodes))
+	for _, ec := range errorCodes {
+		stats = append(stats, ErrorCodeStats{ErrorCode: ec.ErrorCode, Count: atomic.LoadUint64(&ec.count)})
+	}
+	errorCodesLock.RUnlock()
+	sort.Sort(errorCodeStatsSlice(stats))
+	return stats
+}
+
+// 输出错误码错误并计数；启用多语言时优先使用消息包中键为"errors.<错误码>"的翻译
+func renderCodedError(c *Context, e *CodedError) {
+	errorCodesLock.RLock()
+	if ec, ok := errorCodes[e.Code]; ok {
+		atomic.AddUint64(&ec.count, 1)
+	}
+	errorCodesLock.RUnlock()
+	if e.Status >= 500 {
+		Log.Error("%v", e)
+	}
+	if c.response.Committed() {
+		return
+	}
+	msg := e.Message
+	if key := "errors." + e.Code; c.T(key, e.Args...) != key {
+		msg = c.T(key, e.Args...)
+	}
+	body := map[string]interface{}{"code": e.Code, "message": msg}
+	if e.DocURL != "" {
+		body["doc_url"] = e.DocURL
+	}
+	c.JSON(e.Status, body)
+}
+
+type errorCodeStatsSlice []ErrorCodeStats
+
+func (l errorCodeStatsSlice) Len() int           { return len(l) }
+func (l errorCodeStatsSlice) Less(i, j int) bool { return l[i].Code < l[j].Code }
+func (l errorCodeStatsSlice) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// 查询错误码目录及各错误码返回次数的操作，供后台管理路由使用
+var ErrorCodesHandler = ApiHandler{
+	Desc:   "查询错误码目录及返回次数",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, ErrorCodeStatsAll())
+	},
+}.Reg()
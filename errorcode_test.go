@@ -0,0 +1,38 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	RegisterErrorCode(ErrorCode{
+		Code:    "TEST_ORDER_NOT_FOUND",
+		Status:  http.StatusNotFound,
+		Message: "order %d not found",
+		DocURL:  "https://docs.example.com/errors#TEST_ORDER_NOT_FOUND",
+	})
+
+	req, _ := http.NewRequest(GET, "/orders/42", nil)
+	c, rec := testContext(req)
+	c.Error(Err("TEST_ORDER_NOT_FOUND", 42))
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusNotFound || body["code"] != "TEST_ORDER_NOT_FOUND" ||
+		body["message"] != "order 42 not found" || body["doc_url"] == "" {
+		t.Errorf("response = %d %v", rec.Code, body)
+	}
+
+	if err := Err("TEST_UNKNOWN").(*CodedError); err.Status != http.StatusInternalServerError || err.Message != "TEST_UNKNOWN" {
+		t.Errorf("unregistered code = %+v", err)
+	}
+
+	for _, s := range ErrorCodeStatsAll() {
+		if s.Code == "TEST_ORDER_NOT_FOUND" && s.Count != 1 {
+			t.Errorf("count = %d, want 1", s.Count)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package lessgo
+
+import (
+	"fmt"
+	"net/http"
+	pathpkg "path"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// 路由分组的错误处理，为nil的项沿用全局设置(SetNotFound、SetMethodNotAllowed、SetInternalServerError)
+type GroupErrorHandlers struct {
+	NotFound            func(*Context) error
+	MethodNotAllowed    func(*Context) error
+	InternalServerError func(c *Context, err error, rcv interface{})
+}
+
+// 运行时恐慌转换成的错误，由恐慌捕获中间件或服务入口返回给统一的错误处理
+type PanicError struct {
+	Value  interface{} // recover()得到的值
+	Stack  []byte      // 发生恐慌时的调用栈
+	logged bool        // 调用栈是否已由恐慌捕获中间件打印
+}
+
+// 将recover()得到的值转换为*PanicError并记录当前协程的调用栈，已是*PanicError时原样返回，如：
+//
+//	defer func() {
+//		if rcv := recover(); rcv != nil {
+//			err = lessgo.NewPanicError(rcv)
+//		}
+//	}()
+func NewPanicError(rcv interface{}) *PanicError {
+	return newPanicError(rcv, 4<<10, false)
+}
+
+func newPanicError(rcv interface{}, stackSize int, all bool) *PanicError {
+	if pe, ok := rcv.(*PanicError); ok {
+		return pe
+	}
+	stack := make([]byte, stackSize)
+	return &PanicError{Value: rcv, Stack: stack[:runtime.Stack(stack, all)]}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// 恐慌值为error时返回该错误，否则返回nil
+func (e *PanicError) Cause() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+type errorHandlerEntry struct {
+	value  error        // 按错误值匹配
+	typ    reflect.Type // 按错误类型匹配
+	status int          // 按状态码匹配
+	fn     func(*Context, error)
+}
+
+var (
+	errorHandlers     []errorHandlerEntry
+	errorHandlersLock sync.RWMutex
+
+	groupErrorHandlers     = map[string]GroupErrorHandlers{}
+	groupErrorHandlersLock sync.RWMutex
+)
+
+// 以JSON输出{"code":状态码,"message":说明}的分组错误处理，适用于如/api的分组
+var JSONErrorHandlers = GroupErrorHandlers{
+	NotFound: func(c *Context) error {
+		return jsonError(c, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	},
+	MethodNotAllowed: func(c *Context) error {
+		return jsonError(c, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	},
+	InternalServerError: func(c *Context, err error, rcv interface{}) {
+		code, msg := errorStatus(err, rcv)
+		logRequestError(err, rcv)
+		if !c.response.Committed() {
+			jsonError(c, code, msg)
+		}
+	},
+}
+
+func jsonError(c *Context, code int, msg string) error {
+	return c.JSON(code, map[string]interface{}{"code": code, "message": msg})
+}
+
+// 设置路径前缀为prefix的路由分组的404、405、500处理，请求按最长匹配的分组处理，
+// 分组未设置的项沿用上级分组的设置；h为零值时移除，如：
+//
+//	lessgo.SetGroupErrorHandlers("/api", lessgo.JSONErrorHandlers)
+//	lessgo.SetGroupErrorHandlers("/web", lessgo.GroupErrorHandlers{NotFound: renderNotFoundPage})
+func SetGroupErrorHandlers(prefix string, h GroupErrorHandlers) {
+	prefix = cleanPrefix(prefix)
+	groupErrorHandlersLock.Lock()
+	if h.NotFound == nil && h.MethodNotAllowed == nil && h.InternalServerError == nil {
+		delete(groupErrorHandlers, prefix)
+	} else {
+		groupErrorHandlers[prefix] = h
+	}
+	groupErrorHandlersLock.Unlock()
+}
+
+// 返回请求路径所属分组的错误处理，各项按最长前缀匹配且设置了该项的分组取值，均未设置时为nil
+func groupErrorHandlersFor(path string) GroupErrorHandlers {
+	var h GroupErrorHandlers
+	groupErrorHandlersLock.RLock()
+	defer groupErrorHandlersLock.RUnlock()
+	if len(groupErrorHandlers) == 0 {
+		return h
+	}
+	for p := pathpkg.Join("/", path); ; p = pathpkg.Dir(p) {
+		if g, ok := groupErrorHandlers[p]; ok {
+			if h.NotFound == nil {
+				h.NotFound = g.NotFound
+			}
+			if h.MethodNotAllowed == nil {
+				h.MethodNotAllowed = g.MethodNotAllowed
+			}
+			if h.InternalServerError == nil {
+				h.InternalServerError = g.InternalServerError
+			}
+		}
+		if p == "/" {
+			return h
+		}
+	}
+}
+
+// 依次调用路径所属分组与全局的404处理
+func handleNotFound(c *Context, global HandlerFunc) error {
+	if fn := groupErrorHandlersFor(c.request.URL.Path).NotFound; fn != nil {
+		return fn(c)
+	}
+	return global(c)
+}
+
+// 依次调用路径所属分组与全局的405处理
+func handleMethodNotAllowed(c *Context, global HandlerFunc) error {
+	if fn := groupErrorHandlersFor(c.request.URL.Path).MethodNotAllowed; fn != nil {
+		return fn(c)
+	}
+	return global(c)
+}
+
+// 注册错误处理函数，操作或中间件返回的错误在此统一转换为响应，未匹配的交由SetInternalServerError设置的处理；
+// target可为：
+//
+//	错误值，如sql.ErrNoRows，与返回的错误相等时匹配；
+//	类型化的空指针，如(*ValidationError)(nil)，返回的错误为该类型时匹配；
+//	状态码，如http.StatusNotFound，*HTTPError与Err创建的错误按其状态码匹配，其余错误视为500。
+//
+// 匹配顺序为错误值、错误类型、状态码，同类按注册顺序；运行时恐慌以*PanicError传入。如：
+//
+//	lessgo.OnError(sql.ErrNoRows, func(c *lessgo.Context, err error) {
+//		c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+//	})
+func OnError(target interface{}, fn func(c *Context, err error)) {
+	e := errorHandlerEntry{fn: fn}
+	switch t := target.(type) {
+	case int:
+		e.status = t
+	case error:
+		if v := reflect.ValueOf(t); v.Kind() == reflect.Ptr && v.IsNil() {
+			e.typ = v.Type()
+		} else {
+			e.value = t
+		}
+	default:
+		panic(fmt.Sprintf("OnError: target must be an error or a status code, got %T", target))
+	}
+	errorHandlersLock.Lock()
+	errorHandlers = append(errorHandlers, e)
+	errorHandlersLock.Unlock()
+}
+
+// 清空注册的错误处理函数
+func ResetOnError() {
+	errorHandlersLock.Lock()
+	errorHandlers = nil
+	errorHandlersLock.Unlock()
+}
+
+// 返回与err匹配的错误处理函数
+func matchErrorHandler(err error) func(*Context, error) {
+	errorHandlersLock.RLock()
+	defer errorHandlersLock.RUnlock()
+	if len(errorHandlers) == 0 {
+		return nil
+	}
+	typ := reflect.TypeOf(err)
+	if typ.Comparable() {
+		for _, e := range errorHandlers {
+			if e.value != nil && e.value == err {
+				return e.fn
+			}
+		}
+	}
+	for _, e := range errorHandlers {
+		if e.typ != nil && e.typ == typ {
+			return e.fn
+		}
+	}
+	status := http.StatusInternalServerError
+	switch e := err.(type) {
+	case *HTTPError:
+		status = e.Code
+	case *CodedError:
+		status = e.Status
+	}
+	for _, e := range errorHandlers {
+		if e.status != 0 && e.status == status {
+			return e.fn
+		}
+	}
+	return nil
+}
+
+// 处理请求的错误或恐慌，依次使用OnError注册的处理函数、错误码错误的统一输出、路径所属分组与全局的错误处理
+func handleError(c *Context, err error, rcv interface{}) {
+	if pe, ok := err.(*PanicError); ok && rcv == nil {
+		rcv = pe.Value
+	}
+	if err != nil {
+		if fn := matchErrorHandler(err); fn != nil {
+			fn(c, err)
+			return
+		}
+	}
+	if e, ok := err.(*CodedError); ok && rcv == nil {
+		renderCodedError(c, e)
+		return
+	}
+	if fn := groupErrorHandlersFor(c.request.URL.Path).InternalServerError; fn != nil {
+		fn(c, err, rcv)
+		return
+	}
+	if h := app.router.ErrorPanicHandler; h != nil {
+		h(c, err, rcv)
+	} else {
+		defaultInternalServerErrorHandler(c, err, rcv)
+	}
+}
@@ -0,0 +1,110 @@
+package lessgo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type testValidationError struct{ Field string }
+
+func (e *testValidationError) Error() string { return e.Field + " is invalid" }
+
+func TestOnError(t *testing.T) {
+	defer ResetOnError()
+	errNoRows := errors.New("no rows")
+	OnError(http.StatusNotFound, func(c *Context, err error) {
+		c.String(http.StatusNotFound, "status:"+err.Error())
+	})
+	OnError((*testValidationError)(nil), func(c *Context, err error) {
+		c.String(http.StatusBadRequest, "type:"+err.Error())
+	})
+	OnError(errNoRows, func(c *Context, err error) {
+		c.String(http.StatusNotFound, "value:"+err.Error())
+	})
+
+	cases := []struct {
+		err  error
+		code int
+		body string
+	}{
+		{errNoRows, http.StatusNotFound, "value:no rows"},
+		{&testValidationError{"name"}, http.StatusBadRequest, "type:name is invalid"},
+		{ErrNotFound, http.StatusNotFound, "status:Not Found"},
+		{ErrUnauthorized, http.StatusUnauthorized, "Unauthorized"},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(GET, "/", nil)
+		c, rec := testContext(req)
+		c.Error(tc.err)
+		if rec.Code != tc.code || rec.Body.String() != tc.body {
+			t.Errorf("Error(%v) = %d %q, want %d %q", tc.err, rec.Code, rec.Body.String(), tc.code, tc.body)
+		}
+	}
+}
+
+func TestGroupErrorHandlers(t *testing.T) {
+	SetGroupErrorHandlers("/api", JSONErrorHandlers)
+	SetGroupErrorHandlers("/api/v2", GroupErrorHandlers{
+		NotFound: func(c *Context) error { return c.String(http.StatusNotFound, "v2") },
+	})
+	defer func() {
+		SetGroupErrorHandlers("/api", GroupErrorHandlers{})
+		SetGroupErrorHandlers("/api/v2", GroupErrorHandlers{})
+	}()
+	global := HandlerFunc(func(c *Context) error { return c.String(http.StatusNotFound, "global") })
+
+	cases := []struct{ path, body string }{
+		{"/api/users/1", `"code"`},
+		{"/api/v2/users", "v2"},
+		{"/apix", "global"},
+		{"/web/page", "global"},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(GET, tc.path, nil)
+		c, rec := testContext(req)
+		if err := handleNotFound(c, global); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), tc.body) {
+			t.Errorf("%s: %d %q, want %q", tc.path, rec.Code, rec.Body.String(), tc.body)
+		}
+	}
+
+	// /api/v2未设置500处理，沿用/api的JSON处理
+	req, _ := http.NewRequest(GET, "/api/v2/orders", nil)
+	c, rec := testContext(req)
+	c.Error(NewHTTPError(http.StatusConflict, "conflict"))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"message"`) {
+		t.Errorf("group 500 handler = %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPanicError(t *testing.T) {
+	defer ResetOnError()
+	var got *PanicError
+	OnError((*PanicError)(nil), func(c *Context, err error) {
+		got = err.(*PanicError)
+		c.String(http.StatusInternalServerError, "recovered")
+	})
+
+	h := Recover.Middleware.(Middleware).getMiddlewareFunc(RecoverConfig{DisablePrintStack: true})(func(c *Context) error {
+		panic(errors.New("boom"))
+	})
+	req, _ := http.NewRequest(GET, "/", nil)
+	c, rec := testContext(req)
+	err := h(c)
+	pe, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("Recover returned %T %v, want *PanicError", err, err)
+	}
+	if pe.Cause() == nil || pe.Cause().Error() != "boom" || !strings.Contains(string(pe.Stack), "TestPanicError") {
+		t.Errorf("PanicError = %v, stack %.200s", pe, pe.Stack)
+	}
+
+	c.Error(err)
+	if got != pe || rec.Body.String() != "recovered" {
+		t.Errorf("OnError got %v, body %q", got, rec.Body.String())
+	}
+}
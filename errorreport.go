@@ -0,0 +1,360 @@
+package lessgo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/logs"
+)
+
+type (
+	// ErrorReportConfig defines the config for Sentry-compatible error reporting middleware.
+	ErrorReportConfig struct {
+		// Sentry DSN，如"https://<key>@sentry.example.com/<project>"，为空时不上报
+		DSN string
+
+		// 运行环境与版本标记
+		Environment string
+		Release     string
+
+		// 采样率，取值0~1
+		SampleRate float64
+
+		// 上报时需隐藏值的请求头与参数(不区分大小写)
+		ScrubHeaders []string
+		ScrubParams  []string
+
+		// Context中存放当前用户的键名
+		UserKey string
+
+		// 附带的当前请求面包屑最大条数，包括Context.Breadcrumb记录的与c.Logger()输出的日志
+		// (取自"ring"日志适配器，启用上报时自动添加；无请求ID时不附带日志)
+		Breadcrumbs int
+
+		// 上报超时，单位毫秒
+		TimeoutMS int64
+
+		// 异步上报队列长度，队列满时丢弃
+		QueueSize int
+	}
+
+	// 发送到Sentry兼容服务的事件
+	errorReportEvent struct {
+		EventId     string                   `json:"event_id"`
+		Timestamp   string                   `json:"timestamp"`
+		Level       string                   `json:"level"`
+		Platform    string                   `json:"platform"`
+		Logger      string                   `json:"logger"`
+		ServerName  string                   `json:"server_name,omitempty"`
+		Release     string                   `json:"release,omitempty"`
+		Environment string                   `json:"environment,omitempty"`
+		Message     string                   `json:"message"`
+		Exception   map[string]interface{}   `json:"exception,omitempty"`
+		Request     map[string]interface{}   `json:"request,omitempty"`
+		User        map[string]interface{}   `json:"user,omitempty"`
+		Tags        map[string]string        `json:"tags,omitempty"`
+		Extra       map[string]interface{}   `json:"extra,omitempty"`
+		Breadcrumbs []map[string]interface{} `json:"breadcrumbs,omitempty"`
+	}
+
+	// 按中间件配置创建的上报器，创建后不再修改
+	errorReporter struct {
+		config    ErrorReportConfig
+		transport *errorTransport
+	}
+
+	// 解析DSN后的上报端点，相同DSN共享
+	errorTransport struct {
+		storeUrl string
+		auth     string
+		client   *http.Client
+		queue    chan *errorReportEvent
+	}
+)
+
+const breadcrumbsKey = "__breadcrumbs__"
+
+var (
+	// 各DSN最近一次配置的上报器
+	errorReporters = map[string]*errorReporter{}
+	// 各DSN的上报端点
+	errorTransports   = map[string]*errorTransport{}
+	errorReportersMux sync.Mutex
+	errorReportRing   sync.Once
+	hostname, _       = os.Hostname()
+)
+
+var ErrorReport = ApiMiddleware{
+	Name: "错误上报",
+	Desc: "将运行时恐慌与5xx错误(附带请求信息、路由、用户与最近日志)上报到Sentry兼容服务",
+	Config: ErrorReportConfig{
+		DSN:          "",
+		SampleRate:   1,
+		ScrubHeaders: []string{HeaderAuthorization, HeaderCookie},
+		ScrubParams:  []string{"password", "passwd", "token", "secret"},
+		UserKey:      "user",
+		Breadcrumbs:  30,
+		TimeoutMS:    5000,
+		QueueSize:    256,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(ErrorReportConfig)
+		reporter, err := getErrorReporter(config)
+		if err != nil {
+			Log.Error("ErrorReport: %v", err)
+		}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) (err error) {
+				if reporter == nil {
+					return next(c)
+				}
+				defer func() {
+					if rcv := recover(); rcv != nil {
+						// 转换为*PanicError返回，交由统一的错误处理
+						pe := NewPanicError(rcv)
+						reporter.report(c, "fatal", pe, pe.Stack)
+						err = pe
+					}
+				}()
+				err = next(c)
+				if pe, ok := err.(*PanicError); ok {
+					reporter.report(c, "fatal", pe, pe.Stack)
+				} else if err != nil {
+					if he, ok := err.(*HTTPError); !ok || he.Code >= 500 {
+						reporter.report(c, "error", err, nil)
+					}
+				} else if c.response.Status() >= 500 {
+					reporter.report(c, "error", fmt.Errorf("%d %s", c.response.Status(), http.StatusText(c.response.Status())), nil)
+				}
+				return err
+			}
+		}
+	},
+}.Reg()
+
+// 主动上报错误到已配置的Sentry兼容服务
+func ReportError(c *Context, err error) {
+	errorReportersMux.Lock()
+	reporters := make([]*errorReporter, 0, len(errorReporters))
+	for _, r := range errorReporters {
+		reporters = append(reporters, r)
+	}
+	errorReportersMux.Unlock()
+	for _, r := range reporters {
+		r.report(c, "error", err, nil)
+	}
+}
+
+// 记录当前请求的面包屑，请求出错上报时附带最近的若干条
+func (c *Context) Breadcrumb(category, format string, a ...interface{}) {
+	crumbs, _ := c.Get(breadcrumbsKey).([]map[string]interface{})
+	c.Set(breadcrumbsKey, append(crumbs, map[string]interface{}{
+		"timestamp": breadcrumbTime(time.Now()),
+		"category":  category,
+		"message":   fmt.Sprintf(format, a...),
+	}))
+}
+
+func getErrorReporter(config ErrorReportConfig) (*errorReporter, error) {
+	if config.DSN == "" {
+		return nil, nil
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 256
+	}
+	if config.TimeoutMS <= 0 {
+		config.TimeoutMS = 5000
+	}
+	errorReportRing.Do(func() {
+		// 在内存中保留最近的日志，从中取出当前请求的日志作为面包屑；已添加或不支持时忽略
+		Log.AddAdapter("ring", `{"size":1000}`)
+	})
+	errorReportersMux.Lock()
+	defer errorReportersMux.Unlock()
+	t, ok := errorTransports[config.DSN]
+	if !ok {
+		var err error
+		if t, err = newErrorTransport(config); err != nil {
+			return nil, err
+		}
+		errorTransports[config.DSN] = t
+	}
+	r := &errorReporter{
+		config:    config,
+		transport: t,
+	}
+	errorReporters[config.DSN] = r
+	return r, nil
+}
+
+func newErrorTransport(config ErrorReportConfig) (*errorTransport, error) {
+	u, err := url.Parse(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if u.User == nil {
+		return nil, fmt.Errorf("invalid DSN %q: missing public key", config.DSN)
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	project := p[strings.LastIndex(p, "/")+1:]
+	if project == "" {
+		return nil, fmt.Errorf("invalid DSN %q: missing project id", config.DSN)
+	}
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s/%s, sentry_key=%s", strings.ToLower(NAME), VERSION, u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	t := &errorTransport{
+		storeUrl: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, strings.TrimSuffix(p, "/"+project), project),
+		auth:     auth,
+		client:   &http.Client{Timeout: time.Duration(config.TimeoutMS) * time.Millisecond},
+		queue:    make(chan *errorReportEvent, config.QueueSize),
+	}
+	go t.run()
+	return t, nil
+}
+
+func (r *errorReporter) report(c *Context, level string, err error, stack []byte) {
+	config := r.config
+	if config.SampleRate < 1 && mrand.Float64() >= config.SampleRate {
+		return
+	}
+	event := &errorReportEvent{
+		EventId:     newEventId(),
+		Timestamp:   time.Now().UTC().Format("2006-01-02T15:04:05"),
+		Level:       level,
+		Platform:    "go",
+		Logger:      strings.ToLower(NAME),
+		ServerName:  hostname,
+		Release:     config.Release,
+		Environment: config.Environment,
+		Message:     err.Error(),
+		Exception: map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":  fmt.Sprintf("%T", err),
+				"value": err.Error(),
+			}},
+		},
+		Tags:  map[string]string{},
+		Extra: map[string]interface{}{},
+	}
+	if stack != nil {
+		event.Extra["stack"] = string(stack)
+	}
+	if c != nil {
+		req := c.request
+		query := req.URL.Query()
+		for k := range query {
+			for _, s := range config.ScrubParams {
+				if strings.EqualFold(k, s) {
+					query.Set(k, "***")
+				}
+			}
+		}
+		headers := map[string]string{}
+		for k := range req.Header {
+			headers[k] = req.Header.Get(k)
+		}
+		for _, k := range config.ScrubHeaders {
+			if _, ok := headers[http.CanonicalHeaderKey(k)]; ok {
+				headers[http.CanonicalHeaderKey(k)] = "***"
+			}
+		}
+		event.Request = map[string]interface{}{
+			"url":          c.Scheme() + "://" + req.Host + req.URL.Path,
+			"method":       req.Method,
+			"query_string": query.Encode(),
+			"headers":      headers,
+			"env":          map[string]string{"REMOTE_ADDR": c.RealRemoteAddr()},
+		}
+		if c.path != "" {
+			event.Tags["route"] = c.path
+		}
+		if id := requestID(c); id != "" {
+			event.Tags["request_id"] = id
+		}
+		if u := accessLogUser(c, config.UserKey); u != "-" {
+			event.User = map[string]interface{}{"id": u, "ip_address": c.RealRemoteAddr()}
+		}
+		if config.Breadcrumbs > 0 {
+			event.Breadcrumbs = breadcrumbs(c, config.Breadcrumbs)
+		}
+	}
+	select {
+	case r.transport.queue <- event:
+	default:
+		Log.Warn("ErrorReport: queue is full, event %s dropped", event.EventId)
+	}
+}
+
+// 当前请求最近的max条面包屑，按时间先后排序
+func breadcrumbs(c *Context, max int) []map[string]interface{} {
+	crumbs, _ := c.Get(breadcrumbsKey).([]map[string]interface{})
+	crumbs = append([]map[string]interface{}{}, crumbs...)
+	if id := requestID(c); id != "" {
+		// c.Logger()输出的日志带有request_id字段
+		marker := " request_id=" + id + " "
+		for _, m := range logs.Recent() {
+			if strings.Contains(m.Msg+" ", marker) {
+				crumbs = append(crumbs, map[string]interface{}{
+					"timestamp": breadcrumbTime(m.When),
+					"category":  "log",
+					"level":     m.Level,
+					"message":   m.Msg,
+				})
+			}
+		}
+		sort.SliceStable(crumbs, func(i, j int) bool {
+			return crumbs[i]["timestamp"].(float64) < crumbs[j]["timestamp"].(float64)
+		})
+	}
+	if len(crumbs) > max {
+		crumbs = crumbs[len(crumbs)-max:]
+	}
+	return crumbs
+}
+
+// 面包屑时间戳，带小数的Unix秒数
+func breadcrumbTime(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func (t *errorTransport) run() {
+	for event := range t.queue {
+		b, err := json.Marshal(event)
+		if err != nil {
+			Log.Error("ErrorReport: %v", err)
+			continue
+		}
+		req, _ := http.NewRequest("POST", t.storeUrl, bytes.NewReader(b))
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		req.Header.Set("X-Sentry-Auth", t.auth)
+		resp, err := t.client.Do(req)
+		if err != nil {
+			Log.Error("ErrorReport: %v", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			Log.Error("ErrorReport: %s returned %s", t.storeUrl, resp.Status)
+		}
+	}
+}
+
+func newEventId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,101 @@
+package lessgo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lessgo/lessgo/logs"
+)
+
+func testErrorReporter(config ErrorReportConfig) *errorReporter {
+	return &errorReporter{config: config, transport: &errorTransport{queue: make(chan *errorReportEvent, 100)}}
+}
+
+func TestErrorReportSampling(t *testing.T) {
+	for _, c := range []struct {
+		rate float64
+		want int
+	}{
+		{0, 0},
+		{1, 50},
+	} {
+		r := testErrorReporter(ErrorReportConfig{SampleRate: c.rate})
+		for i := 0; i < 50; i++ {
+			r.report(nil, "error", errors.New("boom"), nil)
+		}
+		if n := len(r.transport.queue); n != c.want {
+			t.Errorf("sample rate %v: %d events, want %d", c.rate, n, c.want)
+		}
+	}
+	r := testErrorReporter(ErrorReportConfig{SampleRate: 0.5})
+	for i := 0; i < 100; i++ {
+		r.report(nil, "error", errors.New("boom"), nil)
+	}
+	if n := len(r.transport.queue); n == 0 || n == 100 {
+		t.Errorf("sample rate 0.5: %d of 100 events", n)
+	}
+}
+
+func TestErrorReportEvent(t *testing.T) {
+	l := logs.NewLogger(10)
+	l.AddAdapter("ring", `{"size":16}`)
+	old := Log
+	Log = l
+	defer func() { Log = old }()
+
+	config := ErrorReport.Config.(ErrorReportConfig)
+	config.Breadcrumbs = 3
+	r := testErrorReporter(config)
+	req, _ := http.NewRequest(GET, "/orders?id=7&Password=p&token=t", nil)
+	req.Header.Set(HeaderAuthorization, "Bearer secret")
+	req.Header.Set(HeaderXRequestID, "req-7")
+	req.RemoteAddr = "10.0.0.1:5000"
+	c, _ := testContext(req)
+	defer c.free()
+	c.path = "/orders"
+	c.Breadcrumb("db", "query %d", 1)
+	c.Logger().Info("loading order")
+	other, _ := testContext(func() *http.Request {
+		req, _ := http.NewRequest(GET, "/", nil)
+		req.Header.Set(HeaderXRequestID, "req-8")
+		return req
+	}())
+	other.Logger().Info("another request")
+	other.free()
+	l.Flush()
+	c.Breadcrumb("cache", "miss")
+	c.Breadcrumb("http", "call upstream")
+
+	r.report(c, "error", errors.New("boom"), nil)
+	event := <-r.transport.queue
+	if q := event.Request["query_string"]; q != "Password=%2A%2A%2A&id=7&token=%2A%2A%2A" {
+		t.Errorf("query_string = %v", q)
+	}
+	headers := event.Request["headers"].(map[string]string)
+	if headers[HeaderAuthorization] != "***" || headers[http.CanonicalHeaderKey(HeaderXRequestID)] != "req-7" {
+		t.Errorf("headers = %v", headers)
+	}
+	if event.Tags["route"] != "/orders" || event.Tags["request_id"] != "req-7" {
+		t.Errorf("tags = %v", event.Tags)
+	}
+
+	// 面包屑含当前请求的日志，按时间排序并只保留最近的3条
+	var got []string
+	for _, b := range event.Breadcrumbs {
+		got = append(got, b["category"].(string)+": "+b["message"].(string))
+	}
+	want := []string{
+		"log: loading order remote_ip=10.0.0.1 request_id=req-7 route=/orders",
+		"cache: miss",
+		"http: call upstream",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("breadcrumbs = %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("breadcrumb %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
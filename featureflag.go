@@ -0,0 +1,309 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+type (
+	// FeatureFlagConfig defines the config for feature flag middleware.
+	FeatureFlagConfig struct {
+		// Context中存放当前用户标识的键名，未登录时按客户端IP分桶
+		UserKey string
+
+		// 从数据源刷新开关的间隔，单位秒
+		RefreshSeconds int64
+	}
+
+	// 功能开关
+	FeatureFlag struct {
+		Name string `json:"name"`
+
+		// 总开关，关闭时对所有人关闭
+		Enabled bool `json:"enabled"`
+
+		// 灰度比例，取值0~100，为100时对所有人开启
+		Rollout float64 `json:"rollout"`
+
+		// 始终开启的用户(或IP)
+		Users []string `json:"users"`
+
+		// A/B测试分组，开启时按权重分配；为空时只区分on/off
+		Variants []FeatureVariant `json:"variants"`
+	}
+
+	// A/B测试分组
+	FeatureVariant struct {
+		Name   string `json:"name"`
+		Weight int    `json:"weight"`
+	}
+
+	// 功能开关数据源
+	FeatureProvider interface {
+		Flags() ([]*FeatureFlag, error)
+	}
+
+	// 固定的开关列表
+	StaticFeatureProvider []*FeatureFlag
+
+	// 从JSON文件读取开关列表，文件修改后自动生效
+	FileFeatureProvider struct {
+		Filename string
+	}
+
+	// 从远程服务(返回JSON数组)读取开关列表
+	RemoteFeatureProvider struct {
+		URL    string
+		Client *http.Client
+	}
+
+	// 请求内已计算的分组
+	featureVariants map[string]string
+)
+
+const (
+	FeatureOn  = "on"
+	FeatureOff = "off"
+)
+
+// Context中存放分组结果与分桶标识的键名
+const (
+	featureVariantsKey = "__features__"
+	featureSubjectKey  = "__feature_subject__"
+)
+
+var (
+	featureProvider     FeatureProvider = StaticFeatureProvider{}
+	featureFlags                        = map[string]*FeatureFlag{}
+	featureLastRefresh  time.Time
+	featureRefreshing   bool
+	featureLock         sync.RWMutex
+	featureRefreshEvery = time.Minute
+)
+
+var FeatureFlags = ApiMiddleware{
+	Name: "功能开关",
+	Desc: "按用户或IP稳定分桶的功能开关与A/B测试，handler中通过c.FeatureEnabled()判断，分组自动记入访问日志",
+	Config: FeatureFlagConfig{
+		UserKey:        "user",
+		RefreshSeconds: 60,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(FeatureFlagConfig)
+		if config.RefreshSeconds > 0 {
+			featureLock.Lock()
+			featureRefreshEvery = time.Duration(config.RefreshSeconds) * time.Second
+			featureLock.Unlock()
+		}
+		RefreshFeatureFlags()
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				subject := accessLogUser(c, config.UserKey)
+				if subject == "-" {
+					subject = strings.TrimSpace(strings.Split(c.RealRemoteAddr(), ",")[0])
+				}
+				c.Set(featureSubjectKey, subject)
+				maybeRefreshFeatureFlags()
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 设置功能开关数据源并立即刷新
+func SetFeatureProvider(p FeatureProvider) {
+	featureLock.Lock()
+	featureProvider = p
+	featureLock.Unlock()
+	RefreshFeatureFlags()
+}
+
+// 立即从数据源刷新功能开关，失败时保留原有开关
+func RefreshFeatureFlags() error {
+	featureLock.RLock()
+	p := featureProvider
+	featureLock.RUnlock()
+	flags, err := p.Flags()
+
+	featureLock.Lock()
+	defer featureLock.Unlock()
+	featureLastRefresh = time.Now()
+	featureRefreshing = false
+	if err != nil {
+		Log.Error("FeatureFlags: %v", err)
+		return err
+	}
+	m := make(map[string]*FeatureFlag, len(flags))
+	for _, f := range flags {
+		m[f.Name] = f
+	}
+	featureFlags = m
+	return nil
+}
+
+// 到期时在后台刷新
+func maybeRefreshFeatureFlags() {
+	featureLock.RLock()
+	due := !featureRefreshing && time.Since(featureLastRefresh) >= featureRefreshEvery
+	featureLock.RUnlock()
+	if !due {
+		return
+	}
+	featureLock.Lock()
+	if featureRefreshing {
+		featureLock.Unlock()
+		return
+	}
+	featureRefreshing = true
+	featureLock.Unlock()
+	go RefreshFeatureFlags()
+}
+
+// 获取当前全部功能开关
+func FeatureFlagList() []*FeatureFlag {
+	featureLock.RLock()
+	defer featureLock.RUnlock()
+	list := make([]*FeatureFlag, 0, len(featureFlags))
+	for _, f := range featureFlags {
+		list = append(list, f)
+	}
+	sort.Sort(featureFlagSlice(list))
+	return list
+}
+
+type featureFlagSlice []*FeatureFlag
+
+func (s featureFlagSlice) Len() int           { return len(s) }
+func (s featureFlagSlice) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s featureFlagSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// 判断功能对当前请求是否开启
+func (c *Context) FeatureEnabled(name string) bool {
+	return c.FeatureVariant(name) != FeatureOff
+}
+
+// 获取当前请求所在的分组，未开启时返回"off"，无A/B分组时返回"on"
+func (c *Context) FeatureVariant(name string) string {
+	variants, _ := c.Get(featureVariantsKey).(featureVariants)
+	if v, ok := variants[name]; ok {
+		return v
+	}
+	subject, _ := c.Get(featureSubjectKey).(string)
+	if subject == "" {
+		subject = c.RealRemoteAddr()
+	}
+	featureLock.RLock()
+	f := featureFlags[name]
+	featureLock.RUnlock()
+	v := f.Variant(subject)
+	if variants == nil {
+		variants = featureVariants{}
+		c.Set(featureVariantsKey, variants)
+	}
+	variants[name] = v
+	return v
+}
+
+// 当前请求已使用的功能开关及分组，用于日志与统计
+func (c *Context) FeatureVariants() map[string]string {
+	variants, _ := c.Get(featureVariantsKey).(featureVariants)
+	return variants
+}
+
+// 计算subject所在的分组，同一subject结果稳定
+func (f *FeatureFlag) Variant(subject string) string {
+	if f == nil || !f.Enabled {
+		return FeatureOff
+	}
+	if !utils.InSlice(subject, f.Users) {
+		if featureBucket(f.Name, subject, 10000) >= int(f.Rollout*100) {
+			return FeatureOff
+		}
+	}
+	total := 0
+	for _, v := range f.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return FeatureOn
+	}
+	// 使用不同的盐，使分组与是否命中灰度相互独立
+	n := featureBucket(f.Name+"#variant", subject, total)
+	for _, v := range f.Variants {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	return FeatureOn
+}
+
+func featureBucket(name, subject string, n int) int {
+	return int(crc32.ChecksumIEEE([]byte(name+":"+subject)) % uint32(n))
+}
+
+// 按名称排序的"name=variant"列表，用于日志
+func (v featureVariants) String() string {
+	if len(v) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + v[name]
+	}
+	return strings.Join(names, ",")
+}
+
+func (s StaticFeatureProvider) Flags() ([]*FeatureFlag, error) {
+	return s, nil
+}
+
+func (p *FileFeatureProvider) Flags() ([]*FeatureFlag, error) {
+	b, err := ioutil.ReadFile(p.Filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var flags []*FeatureFlag
+	if err = json.Unmarshal(b, &flags); err != nil {
+		return nil, fmt.Errorf("%s: %v", p.Filename, err)
+	}
+	return flags, nil
+}
+
+func (p *RemoteFeatureProvider) Flags() ([]*FeatureFlag, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	resp, err := client.Get(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", p.URL, resp.Status)
+	}
+	var flags []*FeatureFlag
+	if err = json.NewDecoder(resp.Body).Decode(&flags); err != nil {
+		return nil, fmt.Errorf("%s: %v", p.URL, err)
+	}
+	return flags, nil
+}
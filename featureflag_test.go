@@ -0,0 +1,127 @@
+package lessgo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	SetFeatureProvider(StaticFeatureProvider{
+		{Name: "new-checkout", Enabled: true, Rollout: 0, Users: []string{"alice"}},
+		{Name: "dark-mode", Enabled: true, Rollout: 100},
+		{Name: "killed", Enabled: false, Rollout: 100},
+		{Name: "pricing", Enabled: true, Rollout: 100, Variants: []FeatureVariant{{"a", 1}, {"b", 1}}},
+	})
+	defer SetFeatureProvider(StaticFeatureProvider{})
+
+	h := FeatureFlags.Middleware.(Middleware).getMiddlewareFunc(FeatureFlags.Config)(func(c *Context) error {
+		return c.String(http.StatusOK, fmt.Sprintf("%v %v %v %v %s", c.FeatureEnabled("new-checkout"), c.FeatureEnabled("dark-mode"),
+			c.FeatureEnabled("killed"), c.FeatureEnabled("missing"), c.FeatureVariant("pricing")))
+	})
+	do := func(user, remote string) (string, *Context) {
+		req, _ := http.NewRequest(GET, "/", nil)
+		req.RemoteAddr = remote
+		c, rec := testContext(req)
+		if user != "" {
+			c.Set("user", user)
+		}
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Body.String(), c
+	}
+
+	// 名单内的用户不受灰度比例限制
+	body, c := do("alice", "10.0.0.1:1")
+	pricing := c.FeatureVariant("pricing")
+	if want := "true true false false " + pricing; body != want {
+		t.Errorf("alice = %q, want %q", body, want)
+	}
+	if got, want := featureVariants(c.FeatureVariants()).String(), "dark-mode=on,killed=off,missing=off,new-checkout=on,pricing="+pricing; got != want {
+		t.Errorf("FeatureVariants() = %q, want %q", got, want)
+	}
+	c.free()
+	body, c = do("bob", "10.0.0.1:1")
+	if want := "false true false false "; body[:len(want)] != want {
+		t.Errorf("bob = %q", body)
+	}
+	c.free()
+
+	// 同一用户或IP分组稳定，不同用户按权重分布到各组
+	counts := map[string]int{}
+	for i := 0; i < 200; i++ {
+		user := fmt.Sprintf("user%d", i)
+		v := (&FeatureFlag{Name: "pricing", Enabled: true, Rollout: 100, Variants: []FeatureVariant{{"a", 1}, {"b", 1}}}).Variant(user)
+		for j := 0; j < 3; j++ {
+			if body, c := do(user, "10.0.0.2:1"); body[len(body)-1:] != v {
+				t.Fatalf("%s: variant %q not stable, want %q", user, body, v)
+			} else {
+				c.free()
+			}
+		}
+		counts[v]++
+	}
+	if counts["a"] < 60 || counts["b"] < 60 {
+		t.Errorf("variant distribution = %v", counts)
+	}
+	// 未登录时按客户端IP分桶
+	_, c = do("", "10.0.0.3:1")
+	if s, _ := c.Get(featureSubjectKey).(string); s != "10.0.0.3" {
+		t.Errorf("anonymous subject = %q", s)
+	}
+	c.free()
+
+	half := &FeatureFlag{Name: "half", Enabled: true, Rollout: 50}
+	on := 0
+	for i := 0; i < 1000; i++ {
+		if half.Variant(fmt.Sprint(i)) == FeatureOn {
+			on++
+		}
+	}
+	if on < 400 || on > 600 {
+		t.Errorf("50%% rollout enabled for %d of 1000", on)
+	}
+}
+
+func TestFeatureProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "features")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "features.json")
+	p := &FileFeatureProvider{Filename: fname}
+	if flags, err := p.Flags(); err != nil || flags != nil {
+		t.Errorf("missing file = %v, %v", flags, err)
+	}
+	ioutil.WriteFile(fname, []byte(`[{"name":"beta","enabled":true,"rollout":100}]`), 0644)
+	if flags, err := p.Flags(); err != nil || len(flags) != 1 || flags[0].Name != "beta" || !flags[0].Enabled {
+		t.Errorf("file flags = %v, %v", flags, err)
+	}
+	ioutil.WriteFile(fname, []byte(`{`), 0644)
+	if _, err := p.Flags(); err == nil {
+		t.Error("invalid file accepted")
+	}
+
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`[{"name":"remote","enabled":true}]`))
+	}))
+	defer ts.Close()
+	SetFeatureProvider(&RemoteFeatureProvider{URL: ts.URL})
+	defer SetFeatureProvider(StaticFeatureProvider{})
+	if list := FeatureFlagList(); len(list) != 1 || list[0].Name != "remote" {
+		t.Errorf("remote flags = %v", list)
+	}
+	// 刷新失败时保留原有开关
+	status = http.StatusInternalServerError
+	if err := RefreshFeatureFlags(); err == nil || len(FeatureFlagList()) != 1 {
+		t.Errorf("failed refresh = %v, flags %v", err, FeatureFlagList())
+	}
+}
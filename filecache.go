@@ -16,7 +16,7 @@ type MemoryCache struct {
 	gc              time.Duration         // 缓存更新检查时长及动态过期时长
 	filemap         map[string]*Cachefile // 已监控的文件缓存
 	trigger         chan struct{}         // 主动触发扫描本地文件
-	once            sync.Once
+	monitoring      bool                  // 监控协程是否在运行，受锁保护
 	sync.RWMutex
 }
 
@@ -46,7 +46,6 @@ func (m *MemoryCache) SetEnable(bl bool) {
 	}
 	*m.enable = bl
 	if !_bl { //没开启就开启
-		m.once = sync.Once{}
 		m.memoryCacheMonitor()
 	}
 }
@@ -105,15 +104,15 @@ func (m *MemoryCache) TriggerScan() {
 	m.trigger <- struct{}{}
 }
 
-// 全局监控协程
+// 全局监控协程，须在持有写锁时调用；关闭缓存后协程退出，
+// 退出前再次开启时沿用原协程
 func (m *MemoryCache) memoryCacheMonitor() {
-	enable := m.enable
-	go m.once.Do(func() {
-		defer func() {
-			// 退出清理缓存
-			m.filemap = make(map[string]*Cachefile)
-		}()
-		for *enable {
+	if m.monitoring {
+		return
+	}
+	m.monitoring = true
+	go func() {
+		for m.monitorRunning() {
 			// 屏蔽上次扫描期间，主动触发的不必要的扫描请求
 			close(m.trigger)
 			m.trigger = make(chan struct{})
@@ -172,7 +171,19 @@ func (m *MemoryCache) memoryCacheMonitor() {
 			}
 			m.RUnlock()
 		}
-	})
+	}()
+}
+
+// 缓存开启时返回true，否则清理缓存并标记监控协程退出
+func (m *MemoryCache) monitorRunning() bool {
+	m.Lock()
+	defer m.Unlock()
+	if *m.enable {
+		return true
+	}
+	m.filemap = make(map[string]*Cachefile)
+	m.monitoring = false
+	return false
 }
 
 const (
@@ -0,0 +1,230 @@
+package lessgo
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+type (
+	// GeoIPConfig defines the config for GeoIP middleware.
+	GeoIPConfig struct {
+		// MaxMind格式(mmdb)的数据库文件，如GeoLite2-City.mmdb
+		Database string
+
+		// 检查数据库文件是否更新的间隔，单位秒，为0时不重新加载
+		ReloadSeconds int64
+
+		// 允许或拒绝访问的国家代码(ISO 3166-1)，AllowCountries非空时仅允许其中的国家
+		AllowCountries []string
+		DenyCountries  []string
+
+		// 无法识别国家时是否允许访问(仅在设置了AllowCountries时有效)
+		AllowUnknown bool
+
+		// 可信代理的IP或CIDR网段，仅信任来自这些地址的X-Forwarded-For与X-Real-IP
+		TrustedProxies []string
+	}
+
+	// IP的地理位置信息
+	GeoInfo struct {
+		IP          string `json:"ip"`
+		Country     string `json:"country"`      // 国家代码，如"CN"
+		CountryName string `json:"country_name"` // 国家英文名
+		Region      string `json:"region"`       // 一级行政区代码，如"GD"
+		RegionName  string `json:"region_name"`  // 一级行政区英文名
+		City        string `json:"city"`         // 城市英文名
+	}
+
+	// 支持热加载的数据库
+	geoIPDB struct {
+		filename  string
+		reader    *mmdbReader
+		modTime   time.Time
+		lastCheck time.Time
+		interval  time.Duration
+		sync.RWMutex
+	}
+)
+
+const (
+	// Context中存放GeoInfo的键名
+	geoIPKey = "__geoip__"
+	// 数据库加载失败后的重试间隔
+	geoIPRetryInterval = 10 * time.Second
+)
+
+var (
+	geoIPDBs    = map[string]*geoIPDB{}
+	geoIPDBsMux sync.Mutex
+)
+
+var GeoIP = ApiMiddleware{
+	Name: "GeoIP",
+	Desc: "根据MaxMind格式的数据库解析客户端IP的国家与地区(支持热加载)，并可按国家允许或拒绝访问",
+	Config: GeoIPConfig{
+		Database:       "GeoLite2-City.mmdb",
+		ReloadSeconds:  60,
+		AllowCountries: []string{},
+		DenyCountries:  []string{},
+		AllowUnknown:   true,
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(GeoIPConfig)
+		db := getGeoIPDB(config.Database, time.Duration(config.ReloadSeconds)*time.Second)
+		allow := upperCountries(config.AllowCountries)
+		deny := upperCountries(config.DenyCountries)
+		filter := len(allow) > 0 || len(deny) > 0
+		proxies := parseIPNets(config.TrustedProxies, "GeoIP: invalid TrustedProxies item")
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				info, ok := db.lookup(clientIP(c.request, proxies))
+				if !ok {
+					// 数据库不可用时，设置了国家限制则拒绝访问
+					if filter {
+						return NewHTTPError(http.StatusServiceUnavailable)
+					}
+					return next(c)
+				}
+				c.Set(geoIPKey, info)
+
+				if len(deny) > 0 && info.Country != "" && utils.InSlice(info.Country, deny) {
+					return NewHTTPError(http.StatusForbidden)
+				}
+				if len(allow) > 0 {
+					if info.Country == "" {
+						if !config.AllowUnknown {
+							return NewHTTPError(http.StatusForbidden)
+						}
+					} else if !utils.InSlice(info.Country, allow) {
+						return NewHTTPError(http.StatusForbidden)
+					}
+				}
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 获取GeoIP中间件解析出的客户端地理位置，未启用时返回nil
+func (c *Context) GeoIP() *GeoInfo {
+	info, _ := c.Get(geoIPKey).(*GeoInfo)
+	return info
+}
+
+func upperCountries(list []string) []string {
+	upper := make([]string, len(list))
+	for i, s := range list {
+		upper[i] = strings.ToUpper(s)
+	}
+	return upper
+}
+
+// 多个挂载点共用同一数据库文件；首次加载失败时仍返回，之后按间隔重试
+func getGeoIPDB(filename string, interval time.Duration) *geoIPDB {
+	geoIPDBsMux.Lock()
+	defer geoIPDBsMux.Unlock()
+	if db, ok := geoIPDBs[filename]; ok {
+		db.Lock()
+		db.interval = interval
+		db.Unlock()
+		return db
+	}
+	db := &geoIPDB{filename: filename, interval: interval}
+	db.Lock()
+	db.reload()
+	db.Unlock()
+	geoIPDBs[filename] = db
+	return db
+}
+
+// 文件修改后重新加载，加载失败时继续使用旧数据；尚未加载成功时按间隔重试
+func (db *geoIPDB) checkReload() {
+	db.RLock()
+	interval := db.interval
+	if db.reader == nil && (interval <= 0 || interval > geoIPRetryInterval) {
+		interval = geoIPRetryInterval
+	}
+	due := interval > 0 && time.Since(db.lastCheck) >= interval
+	db.RUnlock()
+	if !due {
+		return
+	}
+	db.Lock()
+	defer db.Unlock()
+	if time.Since(db.lastCheck) < interval {
+		return
+	}
+	db.reload()
+}
+
+// 调用者需持有写锁
+func (db *geoIPDB) reload() {
+	db.lastCheck = time.Now()
+	fi, err := os.Stat(db.filename)
+	if err != nil {
+		if db.reader == nil {
+			Log.Error("GeoIP: %v", err)
+		}
+		return
+	}
+	if db.reader != nil && !fi.ModTime().After(db.modTime) {
+		return
+	}
+	r, err := openMmdb(db.filename)
+	if err != nil {
+		Log.Error("GeoIP: load %s: %v", db.filename, err)
+		return
+	}
+	db.reader = r
+	db.modTime = fi.ModTime()
+	Log.Sys("GeoIP: %s loaded.", db.filename)
+}
+
+// 查询IP的地理位置，数据库不可用时返回false
+func (db *geoIPDB) lookup(ip net.IP) (*GeoInfo, bool) {
+	db.checkReload()
+	db.RLock()
+	r := db.reader
+	db.RUnlock()
+	if r == nil {
+		return nil, false
+	}
+	info := &GeoInfo{}
+	if ip == nil {
+		return info, true
+	}
+	info.IP = ip.String()
+	record, err := r.lookup(ip)
+	if err != nil || record == nil {
+		return info, true
+	}
+	country := mmdbMap(record["country"])
+	info.Country, _ = country["iso_code"].(string)
+	info.CountryName = mmdbName(country)
+	if subs, ok := record["subdivisions"].([]interface{}); ok && len(subs) > 0 {
+		sub := mmdbMap(subs[0])
+		info.Region, _ = sub["iso_code"].(string)
+		info.RegionName = mmdbName(sub)
+	}
+	info.City = mmdbName(mmdbMap(record["city"]))
+	return info, true
+}
+
+func mmdbMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// 取英文名称
+func mmdbName(m map[string]interface{}) string {
+	s, _ := mmdbMap(m["names"])["en"].(string)
+	return s
+}
@@ -0,0 +1,73 @@
+package lessgo
+
+import (
+	"net"
+	"testing"
+)
+
+// 构造一个只含0.0.0.0/2网段的IPv4小型数据库
+func testMmdb() []byte {
+	var b []byte
+	// 搜索树：2个节点，记录长度24位
+	b = append(b, 0, 0, 1, 0, 0, 2)
+	b = append(b, 0, 0, 18, 0, 0, 2)
+	b = append(b, make([]byte, 16)...)
+	// 数据段：{"country":{"iso_code":"CN"}}
+	b = append(b, 0xE1, 0x47)
+	b = append(b, "country"...)
+	b = append(b, 0xE1, 0x48)
+	b = append(b, "iso_code"...)
+	b = append(b, 0x42, 'C', 'N')
+	// 元数据
+	b = append(b, mmdbMetadataMarker...)
+	b = append(b, 0xE3, 0x4A)
+	b = append(b, "node_count"...)
+	b = append(b, 0xC1, 2, 0x4B)
+	b = append(b, "record_size"...)
+	b = append(b, 0xA1, 24, 0x4A)
+	b = append(b, "ip_version"...)
+	b = append(b, 0xA1, 4)
+	return b
+}
+
+func TestMmdbLookup(t *testing.T) {
+	r, err := newMmdbReader(testMmdb())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		ip      string
+		country string
+	}{
+		{"1.2.3.4", "CN"},
+		{"63.255.255.255", "CN"},
+		{"64.0.0.1", ""},
+		{"200.1.1.1", ""},
+		{"::1", ""},
+	} {
+		record, err := r.lookup(net.ParseIP(test.ip))
+		if err != nil {
+			t.Errorf("lookup(%s): %v", test.ip, err)
+			continue
+		}
+		iso, _ := mmdbMap(record["country"])["iso_code"].(string)
+		if iso != test.country {
+			t.Errorf("lookup(%s) country = %q, want %q", test.ip, iso, test.country)
+		}
+	}
+}
+
+func TestMmdbInvalid(t *testing.T) {
+	if _, err := newMmdbReader([]byte("not a database")); err == nil {
+		t.Fatal("expected error for invalid database")
+	}
+	for _, data := range [][]byte{
+		{0x20, 0x00},                  // 指向自身的指针
+		{0xE1, 0x41, 'a', 0x20, 0x00}, // 值为指向所在map的指针
+		{0x1D, 0x04, 0xFF},            // 长度超出数据段的数组
+	} {
+		if _, _, err := mmdbDecode(data, 0); err == nil {
+			t.Errorf("mmdbDecode(% x): expected error", data)
+		}
+	}
+}
@@ -21,6 +21,7 @@ type Server struct {
 	*http.Server
 	logger           logs.Logger
 	GraceListener    net.Listener
+	WrapListener     func(net.Listener) net.Listener // optional, wraps GraceListener when serving
 	SignalHooks      map[int]map[os.Signal][]func()
 	tlsInnerListener *graceListener
 	wg               sync.WaitGroup
@@ -35,7 +36,15 @@ type Server struct {
 // The service goroutines read requests and then call srv.Handler to reply to them.
 func (srv *Server) Serve() (err error) {
 	srv.state = StateRunning
-	err = srv.Server.Serve(srv.GraceListener)
+	l := srv.GraceListener
+	if srv.WrapListener != nil {
+		l = srv.WrapListener(l)
+	}
+	err = srv.Server.Serve(l)
+	if srv.state == StateShuttingDown {
+		// listener closed by shutdown, not an error
+		err = nil
+	}
 	srv.logger.Sys("%v Waiting for connections to finish...", syscall.Getpid())
 	srv.wg.Wait()
 	srv.state = StateTerminate
@@ -67,7 +76,8 @@ func (srv *Server) ListenAndServe() (err error) {
 			srv.logger.Sys("%v", err)
 			return err
 		}
-		err = process.Kill()
+		// let the parent drain its connections and run its shutdown hooks
+		err = process.Signal(syscall.SIGTERM)
 		if err != nil {
 			return err
 		}
@@ -122,7 +132,8 @@ func (srv *Server) ListenAndServeTLS(certFile, keyFile string) (err error) {
 			srv.logger.Sys("%v", err)
 			return err
 		}
-		err = process.Kill()
+		// let the parent drain its connections and run its shutdown hooks
+		err = process.Signal(syscall.SIGTERM)
 		if err != nil {
 			return err
 		}
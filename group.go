@@ -39,3 +39,14 @@ func (g *Group) add(methods, path string, handler HandlerFunc, middleware ...Mid
 		g.app.add(methods, path, handler, middleware...)
 	}
 }
+
+// 记录路由对应的操作，供中间件读取操作的元信息
+func (g *Group) bindApiHandler(methods []string, path string, ah *ApiHandler) {
+	path = joinpath(joinpath(g.prefix, path), "")
+	for _, method := range methods {
+		if method == WS {
+			method = GET
+		}
+		g.app.apiHandlers[method+path] = ah
+	}
+}
@@ -0,0 +1,214 @@
+package lessgo
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// 健康检查项
+	HealthCheck struct {
+		Func     func() error
+		Timeout  time.Duration // 单次检查超时，默认2秒
+		CacheTTL time.Duration // 结果的缓存时长，避免探针频繁访问依赖，默认1秒，<0为不缓存
+		Liveness bool          // 是否为存活检查(/healthz)，否则为就绪检查(/readyz)
+	}
+
+	// 健康检查结果
+	HealthResult struct {
+		Name      string    `json:"name"`
+		Healthy   bool      `json:"healthy"`
+		Error     string    `json:"error,omitempty"`
+		LatencyMs int64     `json:"latency_ms"`
+		CheckedAt time.Time `json:"checked_at"`
+	}
+
+	healthChecker struct {
+		name  string
+		check HealthCheck
+		last  HealthResult
+		lock  sync.Mutex // 检查期间持有，并发的探针共享一次检查
+	}
+)
+
+var (
+	healthCheckers     = map[string]*healthChecker{}
+	healthCheckersLock sync.RWMutex
+	draining           int32
+)
+
+// 注册健康检查项，同名的将被替换；如：
+//
+//	lessgo.RegisterHealthCheck("db:default", lessgo.HealthCheck{Func: lessgo.DBPing("default")})
+func RegisterHealthCheck(name string, check HealthCheck) {
+	if check.Timeout <= 0 {
+		check.Timeout = 2 * time.Second
+	}
+	if check.CacheTTL == 0 {
+		check.CacheTTL = time.Second
+	}
+	healthCheckersLock.Lock()
+	healthCheckers[name] = &healthChecker{name: name, check: check}
+	healthCheckersLock.Unlock()
+}
+
+// 移除健康检查项
+func RemoveHealthCheck(name string) {
+	healthCheckersLock.Lock()
+	delete(healthCheckers, name)
+	healthCheckersLock.Unlock()
+}
+
+// 执行存活或就绪检查，返回是否全部健康及各项结果(按名称排序)
+func CheckHealth(liveness bool) (bool, []HealthResult) {
+	healthCheckersLock.RLock()
+	var checkers []*healthChecker
+	for _, hc := range healthCheckers {
+		if hc.check.Liveness == liveness {
+			checkers = append(checkers, hc)
+		}
+	}
+	healthCheckersLock.RUnlock()
+
+	results := make([]HealthResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, hc := range checkers {
+		wg.Add(1)
+		go func(i int, hc *healthChecker) {
+			defer wg.Done()
+			results[i] = hc.run()
+		}(i, hc)
+	}
+	wg.Wait()
+	sort.Sort(healthResults(results))
+	healthy := true
+	for _, r := range results {
+		healthy = healthy && r.Healthy
+	}
+	return healthy, results
+}
+
+// 服务是否正在退出，退出时就绪检查失败，以便负载均衡摘除本实例
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// 标记服务正在退出，在收到退出信号时调用
+func setDraining() {
+	if atomic.CompareAndSwapInt32(&draining, 0, 1) {
+		Log.Sys("> Draining, readiness check fails from now on")
+	}
+}
+
+// 返回对命名数据库连接池执行Ping的检查函数
+func DBPing(name string) func() error {
+	return func() error {
+		db, err := GetDB(name)
+		if err != nil {
+			return err
+		}
+		return db.Ping()
+	}
+}
+
+// 返回对共享Redis客户端执行PING的检查函数
+func RedisPing(name string) func() error {
+	return func() error {
+		client, err := GetRedis(name)
+		if err != nil {
+			return err
+		}
+		_, err = client.Do("PING")
+		return err
+	}
+}
+
+func (hc *healthChecker) run() HealthResult {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	if hc.check.CacheTTL > 0 && time.Since(hc.last.CheckedAt) < hc.check.CacheTTL {
+		return hc.last
+	}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				done <- fmt.Errorf("panic: %v", rcv)
+			}
+		}()
+		done <- hc.check.Func()
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(hc.check.Timeout):
+		err = fmt.Errorf("timeout after %v", hc.check.Timeout)
+	}
+	hc.last = HealthResult{
+		Name:      hc.name,
+		Healthy:   err == nil,
+		LatencyMs: int64(time.Since(start) / time.Millisecond),
+		CheckedAt: start,
+	}
+	if err != nil {
+		hc.last.Error = err.Error()
+	}
+	return hc.last
+}
+
+type healthResults []HealthResult
+
+func (l healthResults) Len() int           { return len(l) }
+func (l healthResults) Less(i, j int) bool { return l[i].Name < l[j].Name }
+func (l healthResults) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+func healthResponse(c *Context, status string, healthy bool, results []HealthResult) error {
+	code := http.StatusOK
+	if !healthy {
+		code = http.StatusServiceUnavailable
+	}
+	c.response.CacheControl().NoStore()
+	return c.JSON(code, map[string]interface{}{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// 存活检查，供如/healthz的路由使用，任一存活检查项失败时返回503
+var HealthzHandler = ApiHandler{
+	Desc:   "存活检查",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		healthy, results := CheckHealth(true)
+		status := "ok"
+		if !healthy {
+			status = "fail"
+		}
+		return healthResponse(c, status, healthy, results)
+	},
+}.Reg()
+
+// 就绪检查，供如/readyz的路由使用，任一就绪检查项失败、服务正在预热或正在退出时返回503
+var ReadyzHandler = ApiHandler{
+	Desc:   "就绪检查",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		if Draining() {
+			return healthResponse(c, "draining", false, nil)
+		}
+		if Warming() {
+			return healthResponse(c, "warming", false, nil)
+		}
+		healthy, results := CheckHealth(false)
+		status := "ok"
+		if !healthy {
+			status = "fail"
+		}
+		return healthResponse(c, status, healthy, results)
+	},
+}.Reg()
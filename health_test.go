@@ -0,0 +1,60 @@
+package lessgo
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	var calls int32
+	RegisterHealthCheck("test:ok", HealthCheck{Func: func() error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}})
+	RegisterHealthCheck("test:slow", HealthCheck{Func: func() error {
+		time.Sleep(time.Second)
+		return nil
+	}, Timeout: 10 * time.Millisecond, CacheTTL: -1})
+	RegisterHealthCheck("test:live", HealthCheck{Func: func() error {
+		return errors.New("deadlocked")
+	}, Liveness: true})
+	defer func() {
+		RemoveHealthCheck("test:ok")
+		RemoveHealthCheck("test:slow")
+		RemoveHealthCheck("test:live")
+		atomic.StoreInt32(&draining, 0)
+	}()
+
+	healthy, results := CheckHealth(false)
+	if healthy || len(results) != 2 || !results[0].Healthy || results[1].Error != "timeout after 10ms" {
+		t.Errorf("CheckHealth(false) = %v, %+v", healthy, results)
+	}
+	CheckHealth(false)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("cached check called %d times", n)
+	}
+
+	get := func(h HandlerFunc) (int, string) {
+		req, _ := http.NewRequest(GET, "/", nil)
+		c, rec := testContext(req)
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, rec.Body.String()
+	}
+	if code, body := get(HealthzHandler.Handler); code != http.StatusServiceUnavailable || !strings.Contains(body, "deadlocked") {
+		t.Errorf("healthz = %d %s", code, body)
+	}
+	RemoveHealthCheck("test:slow")
+	if code, body := get(ReadyzHandler.Handler); code != http.StatusOK || !strings.Contains(body, "test:ok") {
+		t.Errorf("readyz = %d %s", code, body)
+	}
+	setDraining()
+	if code, body := get(ReadyzHandler.Handler); code != http.StatusServiceUnavailable || !strings.Contains(body, `"draining"`) {
+		t.Errorf("readyz while draining = %d %s", code, body)
+	}
+}
@@ -0,0 +1,333 @@
+package lessgo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	confpkg "github.com/lessgo/lessgo/config"
+	"github.com/lessgo/lessgo/logs"
+)
+
+// 配置变更事件
+type ConfigChange struct {
+	Key     string // 配置键，形如section::key，路由节点为virtrouter::<path>
+	Old     string
+	New     string
+	Applied bool // 是否已在运行时生效，否则需重启服务
+}
+
+var (
+	configReloadLock   sync.Mutex
+	configHooks        []func([]ConfigChange)
+	configWatching     int32
+	configWatchSecond  int64
+	configProvider     confpkg.Provider
+	configRemoteData   map[string]interface{}
+	configProviderStop chan struct{}
+	serving            int32
+)
+
+// 返回运行时可安全生效的配置项的设置方法，不支持时返回nil
+func configApplier(key string) func(c *config) {
+	switch key {
+	case "system::debug":
+		return func(c *config) {
+			SetDebug(c.Debug)
+		}
+	case "system::maintenance":
+		return func(c *config) {
+			if c.Maintenance {
+				EnableMaintenance()
+			} else {
+				DisableMaintenance()
+			}
+		}
+	case "system::reloadsecond":
+		return func(c *config) {
+			atomic.StoreInt64(&configWatchSecond, int64(c.ReloadSecond))
+			startConfigWatcher()
+		}
+	case "log::level":
+		return func(c *config) {
+			Log.SetLevel(c.Log.Level)
+		}
+	case "log::asyncdrop":
+		return func(c *config) {
+			if c.Log.AsyncDrop {
+				Log.SetAsyncPolicy(logs.ASYNC_DROP_OLDEST)
+			} else {
+				Log.SetAsyncPolicy(logs.ASYNC_BLOCK)
+			}
+		}
+	case "log::samplefirst", "log::samplethereafter":
+		return setLogSampling
+	case "listen::maxuribytes", "listen::maxheadercount", "listen::maxheaderbytes":
+		return setRequestLimits
+	case "listen::draintimeout", "listen::drainconnclose":
+		// 开始排空时读取
+		return func(*config) {}
+	case "system::securekeys":
+		return setSecureKeys
+	}
+	return nil
+}
+
+// 注册配置变更的回调，热加载后以全部变更项(含需重启才能生效的)调用
+func OnConfigChange(fn func(changes []ConfigChange)) {
+	configReloadLock.Lock()
+	configHooks = append(configHooks, fn)
+	configReloadLock.Unlock()
+}
+
+// 重新读取配置文件、环境变量与命令行参数，使可安全生效的变更立即生效，
+// 并依据结构化路由配置启用/禁用虚拟路由节点
+func ReloadConfig() error {
+	configReloadLock.Lock()
+	defer configReloadLock.Unlock()
+
+	oldRefs := resetConfigSecrets()
+	c := newConfig()
+	err := c.readFiles()
+	if err != nil {
+		return err
+	}
+	// 远程配置优先于配置文件，读取失败时保持当前配置
+	if configProvider == nil {
+		configRemoteData = nil
+	} else {
+		data, err := configProvider.Load()
+		if err != nil {
+			return err
+		}
+		c.readAll(flattenConfig(data))
+		configRemoteData = data
+	}
+	c.readOverrides()
+	if err = configSecretError(); err != nil {
+		return err
+	}
+
+	changes := diffConfig(Config, c)
+	for i := range changes {
+		ch := &changes[i]
+		_, secret := configSecretRef(ch.Key)
+		if _, ok := oldRefs[ch.Key]; ok || secret || ch.Key == "system::securekeys" {
+			// 密钥不输出到日志
+			ch.Old, ch.New = "******", "******"
+		}
+		apply := configApplier(ch.Key)
+		if apply == nil && atomic.LoadInt32(&serving) == 0 && (secret || strings.HasPrefix(ch.Key, "listen::")) {
+			// 服务启动前尚未使用监听配置，可直接生效；引用密钥的配置项需设置密钥源后解析，同样在启动前生效
+			apply = func(*config) {}
+		}
+		if apply == nil {
+			Log.Warn("Config %s changed from %q to %q, restart to take effect.", ch.Key, ch.Old, ch.New)
+			continue
+		}
+		copyConfigField(Config, c, ch.Key)
+		apply(Config)
+		ch.Applied = true
+		Log.Sys("Config %s changed from %q to %q.", ch.Key, ch.Old, ch.New)
+	}
+	changes = append(changes, reloadVirtRouterEnable()...)
+
+	if len(changes) > 0 {
+		for _, fn := range configHooks {
+			fn(changes)
+		}
+	}
+	return nil
+}
+
+// 设置远程配置源(如etcd、Consul或HTTP)，其优先级高于配置文件、低于环境变量与命令行参数；
+// 设置后立即加载，并在配置源通知变更时热加载，p为nil时取消
+func SetConfigProvider(p confpkg.Provider) error {
+	configReloadLock.Lock()
+	if configProviderStop != nil {
+		close(configProviderStop)
+		configProviderStop = nil
+	}
+	configProvider = p
+	stop := make(chan struct{})
+	if p != nil {
+		configProviderStop = stop
+	}
+	configReloadLock.Unlock()
+
+	err := ReloadConfig()
+	if p != nil {
+		go watchConfigProvider(p, stop)
+	}
+	return err
+}
+
+// 等待配置源的变更通知并热加载，出错时稍后重试
+func watchConfigProvider(p confpkg.Provider, stop chan struct{}) {
+	for {
+		err := p.Watch(stop)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if err != nil {
+			Log.Error("Watch config provider failed: %v", err)
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		if err := ReloadConfig(); err != nil {
+			Log.Error("Reload config failed: %v", err)
+		}
+	}
+}
+
+// 比较两份配置，返回有差异的配置项
+func diffConfig(a, b *config) []ConfigChange {
+	var changes []ConfigChange
+	as, bs := a.sections(), b.sections()
+	for i := range as {
+		av := reflect.ValueOf(as[i].p).Elem()
+		bv := reflect.ValueOf(bs[i].p).Elem()
+		for j := 0; j < av.NumField(); j++ {
+			switch av.Field(j).Kind() {
+			case reflect.String, reflect.Int, reflect.Int64, reflect.Bool:
+			default:
+				continue
+			}
+			key := getfullname(as[i].name, av.Type().Field(j).Name)
+			o, n := configFieldString(key, av.Field(j)), configFieldString(key, bv.Field(j))
+			if o != n {
+				changes = append(changes, ConfigChange{Key: key, Old: o, New: n})
+			}
+		}
+	}
+	return changes
+}
+
+// 将src中key对应的配置项复制到dst
+func copyConfigField(dst, src *config, key string) {
+	ds, ss := dst.sections(), src.sections()
+	for i := range ds {
+		dv := reflect.ValueOf(ds[i].p).Elem()
+		for j := 0; j < dv.NumField(); j++ {
+			if getfullname(ds[i].name, dv.Type().Field(j).Name) == key {
+				dv.Field(j).Set(reflect.ValueOf(ss[i].p).Elem().Field(j))
+				return
+			}
+		}
+	}
+}
+
+// 设置日志采样
+func setLogSampling(c *config) {
+	var s *logs.Sampling
+	if c.Log.SampleFirst > 0 {
+		s = &logs.Sampling{First: c.Log.SampleFirst, Thereafter: c.Log.SampleThereafter}
+	}
+	for _, level := range []int{logs.DEBUG, logs.INFO, logs.WARN, logs.ERROR} {
+		Log.SetSampling(level, s)
+	}
+}
+
+// 依据结构化路由配置启用/禁用虚拟路由节点，仅动态路由可被修改
+func reloadVirtRouterEnable() []ConfigChange {
+	sfname, _ := findStructuredConfig(CONFIG_DIR + "/virtrouter")
+	if sfname == "" {
+		return nil
+	}
+	vr, err := readStructuredVirtRouterConfig(sfname)
+	if err != nil {
+		Log.Error("Read %s failed: %v.", sfname, err)
+		return nil
+	}
+	var (
+		changes []ConfigChange
+		walk    func(*VirtRouter)
+		applied bool
+	)
+	walk = func(n *VirtRouter) {
+		if n == nil {
+			return
+		}
+		if cur, ok := GetVirtRouter(n.Id); ok && cur.Enable != n.Enable {
+			ch := ConfigChange{
+				Key: "virtrouter::" + cur.Path(),
+				Old: strconv.FormatBool(cur.Enable),
+				New: strconv.FormatBool(n.Enable),
+			}
+			if err := cur.SetEnable(n.Enable); err != nil {
+				Log.Warn("Config %s changed but can not be applied: %v.", ch.Key, err)
+			} else {
+				ch.Applied = true
+				applied = true
+			}
+			changes = append(changes, ch)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(vr)
+	if applied {
+		ReregisterRouter()
+	}
+	return changes
+}
+
+// 配置热加载所监视文件的状态摘要
+func configFilesState() string {
+	var buf bytes.Buffer
+	files := []string{APPCONFIG_FILE}
+	for _, ext := range structuredConfigExts {
+		files = append(files, CONFIG_DIR+"/app"+ext, CONFIG_DIR+"/virtrouter"+ext)
+		if Config.Profile != "" {
+			files = append(files, CONFIG_DIR+"/app."+Config.Profile+ext)
+		}
+	}
+	for _, fname := range files {
+		if info, err := os.Stat(fname); err == nil {
+			fmt.Fprintf(&buf, "%s:%d:%d;", fname, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return buf.String()
+}
+
+// 开启配置热加载，Config.ReloadSecond为0时不开启
+func watchConfig() {
+	atomic.StoreInt64(&configWatchSecond, int64(Config.ReloadSecond))
+	startConfigWatcher()
+}
+
+// 启动配置文件监视，按设定的间隔检查文件变化，间隔被热加载为0时停止
+func startConfigWatcher() {
+	if atomic.LoadInt64(&configWatchSecond) <= 0 || !atomic.CompareAndSwapInt32(&configWatching, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&configWatching, 0)
+		state := configFilesState()
+		for {
+			sec := atomic.LoadInt64(&configWatchSecond)
+			if sec <= 0 {
+				return
+			}
+			time.Sleep(time.Duration(sec) * time.Second)
+			if s := configFilesState(); s != state {
+				state = s
+				if err := ReloadConfig(); err != nil {
+					Log.Error("Reload config failed: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,256 @@
+package lessgo
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// html/template模板引擎的配置
+	HTMLRenderConfig struct {
+		Dir      string           // 模板根目录，模板名为其下以/分隔的相对路径
+		FS       fs.FS            // 不为nil时从虚拟文件系统(如LayeredFS)读取模板，忽略Dir
+		Layout   string           // 默认布局模板，如"layouts/base.html"，为空时不使用布局
+		Partials string           // 局部模板目录，其中的模板可被各页面引用，默认"partials"
+		Funcs    template.FuncMap // 自定义模板函数
+	}
+
+	// 基于html/template的模板引擎，支持布局继承、局部模板与按请求注入的模板函数：
+	//
+	//	layouts/base.html:  <html><body>{{block "content" .}}{{end}}</body></html>
+	//	users/show.html:    {{define "content"}}{{partial "partials/user.html" (dict "user" .User)}}{{end}}
+	//	partials/user.html: <a href="{{url "/users/:id" .user.ID}}">{{.user.Name}}</a> {{T "hello"}}
+	//
+	// 页面以{{define "content"}}等覆盖布局中的同名block；页面可用{{define "layout"}}admin.html{{end}}
+	// 选用其他布局，内容为空时不使用布局。模板中可使用：
+	//
+	//	T          按请求语言翻译，同c.T
+	//	url        按路由生成URL，同URLFor
+	//	csrf_token 当前请求的CSRF令牌，由CSRF中间件以CSRF_TOKEN_KEY存入请求上下文
+	//	csp_nonce  当前请求的CSP随机数，同c.CSPNonce()
+	//	partial    以局部数据渲染局部模板，模板名可为变量
+	//	dict       以键值对创建map，用作局部数据
+	HTMLRender struct {
+		conf     HTMLRenderConfig
+		fsys     fs.FS
+		caching  bool
+		tplCache map[string]*htmlTpl
+		sync.RWMutex
+	}
+
+	htmlTpl struct {
+		set   *template.Template // 从不执行，每次渲染时复制后注入请求相关的函数
+		entry string             // 执行的模板名，使用布局时为布局名
+	}
+)
+
+// 存放当前请求CSRF令牌的请求上下文键
+const CSRF_TOKEN_KEY = "csrf_token"
+
+// 创建html/template模板引擎，caching为false时每次渲染均重新解析模板；
+// 可注册后按路由分组选用：RegisterRenderer("html", NewHTMLRender(conf, !Config.Debug))
+func NewHTMLRender(conf HTMLRenderConfig, caching bool) *HTMLRender {
+	if conf.Partials == "" {
+		conf.Partials = "partials"
+	}
+	fsys := conf.FS
+	if fsys == nil {
+		fsys = os.DirFS(conf.Dir)
+	}
+	return &HTMLRender{
+		conf:     conf,
+		fsys:     fsys,
+		caching:  caching,
+		tplCache: make(map[string]*htmlTpl),
+	}
+}
+
+// Render should render the template to the io.Writer.
+func (h *HTMLRender) Render(w io.Writer, name string, data interface{}, c *Context) error {
+	name = fsPath(name)
+	tpl, err := h.get(name)
+	if err != nil {
+		return h.templateError(name, err)
+	}
+	t, err := tpl.set.Clone()
+	if err != nil {
+		return err
+	}
+	t.Funcs(template.FuncMap{
+		"T": func(key string, args ...interface{}) string {
+			if c == nil {
+				return key
+			}
+			return c.T(key, args...)
+		},
+		"csrf_token": func() string {
+			if c == nil {
+				return ""
+			}
+			token, _ := c.Get(CSRF_TOKEN_KEY).(string)
+			return token
+		},
+		"csp_nonce": func() string {
+			if c == nil {
+				return ""
+			}
+			return c.CSPNonce()
+		},
+		"partial": func(name string, data interface{}) (template.HTML, error) {
+			var buf bytes.Buffer
+			err := t.ExecuteTemplate(&buf, name, data)
+			return template.HTML(buf.String()), err
+		},
+	})
+	// 先写入缓冲，出错时不输出不完整的页面
+	buf := getBuffer(0)
+	defer putBuffer(buf)
+	if err = t.ExecuteTemplate(buf, tpl.entry, data); err != nil {
+		return h.templateError(name, err)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// 清空模板编译缓存
+func (h *HTMLRender) ClearCache() {
+	h.Lock()
+	h.tplCache = make(map[string]*htmlTpl)
+	h.Unlock()
+}
+
+func (h *HTMLRender) get(name string) (*htmlTpl, error) {
+	if !h.caching {
+		return h.compile(name)
+	}
+	h.RLock()
+	tpl, ok := h.tplCache[name]
+	h.RUnlock()
+	if ok {
+		return tpl, nil
+	}
+	tpl, err := h.compile(name)
+	if err != nil {
+		return nil, err
+	}
+	h.Lock()
+	h.tplCache[name] = tpl
+	h.Unlock()
+	return tpl, nil
+}
+
+// 解析局部模板、布局与页面，页面中的define覆盖布局中的同名block
+func (h *HTMLRender) compile(name string) (*htmlTpl, error) {
+	page, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	funcs := h.funcs()
+
+	// 单独解析页面，以确定使用的布局
+	layout := h.conf.Layout
+	probe, err := template.New(name).Funcs(funcs).Parse(string(page))
+	if err != nil {
+		return nil, err
+	}
+	if l := probe.Lookup("layout"); l != nil {
+		var buf bytes.Buffer
+		if err = l.Execute(&buf, nil); err != nil {
+			return nil, err
+		}
+		layout = strings.TrimSpace(buf.String())
+	}
+
+	set := template.New(name).Funcs(funcs)
+	err = fs.WalkDir(h.fsys, fsPath(h.conf.Partials), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || p == name {
+			return nil
+		}
+		return h.parse(set, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	tpl := &htmlTpl{set: set, entry: name}
+	if layout != "" {
+		tpl.entry = fsPath(layout)
+		if err = h.parse(set, tpl.entry); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = set.Parse(string(page)); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+func (h *HTMLRender) parse(set *template.Template, name string) error {
+	b, err := fs.ReadFile(h.fsys, name)
+	if err != nil {
+		return err
+	}
+	_, err = set.New(name).Parse(string(b))
+	return err
+}
+
+// 解析时使用的模板函数，按请求注入的函数在此为占位
+func (h *HTMLRender) funcs() template.FuncMap {
+	funcs := template.FuncMap{
+		"T":          func(key string, args ...interface{}) string { return key },
+		"csrf_token": func() string { return "" },
+		"csp_nonce":  func() string { return "" },
+		"partial":    func(name string, data interface{}) (template.HTML, error) { return "", nil },
+		"url":        URLFor,
+		"dict":       templateDict,
+	}
+	for k, v := range h.conf.Funcs {
+		funcs[k] = v
+	}
+	return funcs
+}
+
+// 以键值对创建map，如dict "user" .User "compact" true
+func templateDict(kvs ...interface{}) (map[string]interface{}, error) {
+	if len(kvs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]interface{}, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		k, ok := kvs[i].(string)
+		if !ok {
+			return nil, errors.New("dict: keys must be strings")
+		}
+		m[k] = kvs[i+1]
+	}
+	return m, nil
+}
+
+var htmlTemplateErrorRe = regexp.MustCompile(`^(?:html/)?template: ([^:]+):(\d+):(?:(\d+):)? (.*)$`)
+
+// 将html/template的错误转换为带模板源码的TemplateError
+func (h *HTMLRender) templateError(name string, err error) error {
+	m := htmlTemplateErrorRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return &TemplateError{Name: name, Message: err.Error()}
+	}
+	te := &TemplateError{Name: m[1], Message: m[4]}
+	te.Line, _ = strconv.Atoi(m[2])
+	te.Column, _ = strconv.Atoi(m[3])
+	te.Source, _ = fs.ReadFile(h.fsys, path.Clean(te.Name))
+	return te
+}
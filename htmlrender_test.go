@@ -0,0 +1,88 @@
+package lessgo
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHTMLRender(t *testing.T) {
+	ReregisterRouter()
+	defer ReregisterRouter()
+	app.add(GET, "/htmlrender/users/:id", func(c *Context) error { return nil })
+	fsys := fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`<title>{{block "title" .}}Site{{end}}</title><main>{{block "content" .}}empty{{end}}</main>`)},
+		"layouts/admin.html": {Data: []byte(`<admin>{{template "content" .}}</admin>`)},
+		"partials/user.html": {Data: []byte(`<a href="{{url "/htmlrender/users/:id" .user.ID}}">{{.user.Name}}</a>{{if .compact}}!{{end}}`)},
+		"users/show.html": {Data: []byte(`{{define "title"}}{{.User.Name}}{{end}}` +
+			`{{define "content"}}{{partial "partials/user.html" (dict "user" .User "compact" true)}} {{T "hi"}} {{csrf_token}} {{csp_nonce}}{{end}}`)},
+		"admin/index.html": {Data: []byte(`{{define "layout"}}layouts/admin.html{{end}}{{define "content"}}dashboard{{end}}`)},
+		"plain.html":       {Data: []byte(`{{define "layout"}}{{end}}plain {{.}}`)},
+		"broken.html":      {Data: []byte("{{define \"content\"}}\n{{.Missing.Field}}\n{{end}}")},
+	}
+	r := NewHTMLRender(HTMLRenderConfig{FS: fsys, Layout: "layouts/base.html"}, true)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	c, _ := testContext(req)
+	defer c.free()
+	c.Set(CSRF_TOKEN_KEY, "tok")
+	c.Set(CSP_NONCE_KEY, "n0")
+	type user struct {
+		ID   int
+		Name string
+	}
+	for _, test := range []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{"users/show.html", map[string]interface{}{"User": user{7, "<Bob>"}},
+			`<title>&lt;Bob&gt;</title><main><a href="/htmlrender/users/7">&lt;Bob&gt;</a>! hi tok n0</main>`},
+		{"/admin/index.html", nil, `<admin>dashboard</admin>`},
+		{"plain.html", "<x>", `plain &lt;x&gt;`},
+	} {
+		for i := 0; i < 2; i++ {
+			var buf bytes.Buffer
+			if err := r.Render(&buf, test.name, test.data, c); err != nil {
+				t.Fatalf("Render(%s): %v", test.name, err)
+			}
+			if buf.String() != test.want {
+				t.Errorf("Render(%s) = %q, want %q", test.name, buf.String(), test.want)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := r.Render(&buf, "broken.html", struct{}{}, c)
+	te, ok := err.(*TemplateError)
+	if !ok || te.Name != "broken.html" || te.Line != 2 || !strings.Contains(string(te.Source), "Missing") {
+		t.Errorf("err = %#v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("partial output %q", buf.String())
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	ReregisterRouter()
+	defer ReregisterRouter()
+	app.add(GET, "/urlfor/:name/files/*path", func(c *Context) error { return nil })
+	for _, test := range []struct {
+		route  string
+		params []interface{}
+		want   string
+		ok     bool
+	}{
+		{"/urlfor/:name/files/*path", []interface{}{"a b", "x/y z.txt"}, "/urlfor/a%20b/files/x/y%20z.txt", true},
+		{"/urlfor/:name/files/*path", []interface{}{"a"}, "", false},
+		{"/urlfor/:name/files/*path", []interface{}{"a", "b", "c"}, "", false},
+		{"/urlfor/missing", nil, "", false},
+	} {
+		got, err := URLFor(test.route, test.params...)
+		if got != test.want || (err == nil) != test.ok {
+			t.Errorf("URLFor(%s, %v) = %q, %v", test.route, test.params, got, err)
+		}
+	}
+}
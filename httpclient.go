@@ -0,0 +1,97 @@
+package lessgo
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/client"
+)
+
+var (
+	httpClients = map[string]*client.Client{
+		"default": client.New(httpClientOptions(Config.HTTPClient)),
+	}
+	httpClientsLock sync.RWMutex
+)
+
+// 由[httpclient]配置生成默认出站HTTP客户端的选项
+func httpClientOptions(conf HTTPClientConfig) client.Options {
+	opt := client.Options{
+		Timeout:             time.Duration(conf.Timeout) * time.Second,
+		MaxIdleConnsPerHost: conf.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     conf.MaxConnsPerHost,
+		Retries:             conf.Retries,
+		RetryBackoff:        time.Duration(conf.RetryBackoffMs) * time.Millisecond,
+	}
+	if conf.Timeout < 0 {
+		opt.Timeout = -1
+	}
+	return opt
+}
+
+// 注册名为name的出站HTTP客户端，同名的将被替换；名为default的客户端已默认注册，按[httpclient]配置连接池、超时与重试，如：
+//
+//	lessgo.RegisterHTTPClient("inventory", client.New(client.Options{Timeout: 3 * time.Second}))
+func RegisterHTTPClient(name string, c *client.Client) {
+	httpClientsLock.Lock()
+	httpClients[name] = c
+	httpClientsLock.Unlock()
+}
+
+// 返回名为name的出站HTTP客户端
+func GetHTTPClient(name string) (*client.Client, error) {
+	httpClientsLock.RLock()
+	c := httpClients[name]
+	httpClientsLock.RUnlock()
+	if c == nil {
+		return nil, fmt.Errorf("HTTP client %s is not registered.", name)
+	}
+	return c, nil
+}
+
+// 返回名为name的出站HTTP客户端，不存在时返回nil；发送请求时传入Context以传递请求ID与链路追踪头，如：
+//
+//	resp, err := c.HTTPClient("default").Get(c, "http://inventory/items/1")
+func (c *Context) HTTPClient(name string) *client.Client {
+	hc, err := GetHTTPClient(name)
+	if err != nil {
+		Log.Error("%v", err)
+	}
+	return hc
+}
+
+// 返回需传递给出站请求的头部：请求ID及入站请求携带的链路追踪头(见client.PropagatedHeaders)，实现client.Propagator
+func (c *Context) PropagationHeader() http.Header {
+	h := http.Header{}
+	for _, k := range client.PropagatedHeaders {
+		if v := c.request.Header.Get(k); v != "" {
+			h.Set(k, v)
+		}
+	}
+	if id := requestID(c); id != "" {
+		h.Set(HeaderXRequestID, id)
+	}
+	return h
+}
+
+// 返回所有出站HTTP客户端按目标主机的请求统计
+func HTTPClientStatsAll() map[string][]client.Stats {
+	httpClientsLock.RLock()
+	defer httpClientsLock.RUnlock()
+	stats := make(map[string][]client.Stats, len(httpClients))
+	for name, c := range httpClients {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+// 查询出站HTTP客户端统计的操作，供后台管理路由使用
+var HTTPClientStatsHandler = ApiHandler{
+	Desc:   "查询出站HTTP客户端统计",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, HTTPClientStatsAll())
+	},
+}.Reg()
@@ -0,0 +1,38 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextHTTPClient(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", "/orders", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	c, _ := testContext(req)
+	c.response.Header().Set(HeaderXRequestID, "req-1")
+
+	resp, err := c.HTTPClient("default").Get(c, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Get(HeaderXRequestID) != "req-1" {
+		t.Errorf("request id = %q", got.Get(HeaderXRequestID))
+	}
+	if got.Get("traceparent") != req.Header.Get("traceparent") {
+		t.Errorf("traceparent = %q", got.Get("traceparent"))
+	}
+	if stats := HTTPClientStatsAll()["default"]; len(stats) == 0 || stats[0].Requests == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if c.HTTPClient("missing") != nil {
+		t.Error("missing client should be nil")
+	}
+}
@@ -0,0 +1,47 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	Root(Leaf("/httphandler", ApiHandler{
+		Desc:   "http handler test",
+		Method: "GET",
+		Handler: func(c *Context) error {
+			return c.String(http.StatusOK, "embedded")
+		},
+	}.Reg()))
+
+	// 虚拟路由配置文件写入临时目录
+	dir, err := ioutil.TempDir("", "httphandler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	mux := http.NewServeMux()
+	mux.Handle("/app/", http.StripPrefix("/app", app.HTTPHandler()))
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("other")) })
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for path, want := range map[string]int{"/app/httphandler": 200, "/app/nope": 404, "/other": 200} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package lessgo
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/lessgo/lessgo/i18n"
+)
+
+// I18nConfig defines the config for language negotiation middleware.
+type I18nConfig struct {
+	// 消息文件目录(*.toml、*.json，文件名即语言标记)
+	Dir string
+
+	// 默认语言，同时作为最终回退语言
+	Default string
+
+	// 指定语言的查询参数与Cookie名称，为空时不使用
+	QueryKey  string
+	CookieKey string
+
+	// 通过查询参数指定语言时，是否写入Cookie以便后续请求沿用
+	SetCookie bool
+}
+
+// Context中存放语言与消息包的键名
+const (
+	localeKey     = "__locale__"
+	i18nBundleKey = "__i18n_bundle__"
+)
+
+var (
+	i18nBundles    = map[string]*i18n.Bundle{}
+	i18nBundlesMux sync.Mutex
+)
+
+var I18n = ApiMiddleware{
+	Name: "多语言",
+	Desc: "依次根据查询参数、Cookie与Accept-Language确定请求语言，供c.T()及模板中的T()翻译使用",
+	Config: I18nConfig{
+		Dir:       "i18n",
+		Default:   "zh-CN",
+		QueryKey:  "lang",
+		CookieKey: "lang",
+		SetCookie: true,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(I18nConfig)
+		bundle := getI18nBundle(config.Dir, config.Default)
+		// 查询参数属于URL，无需声明；未指定时语言取决于Cookie与Accept-Language
+		vary := []string{HeaderAcceptLanguage}
+		if config.CookieKey != "" {
+			vary = append(vary, HeaderCookie)
+		}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				c.response.Vary(vary...)
+				var lang string
+				if config.QueryKey != "" {
+					if q := c.QueryParam(config.QueryKey); q != "" && bundle.Has(q) {
+						lang = i18n.Normalize(q)
+						if config.SetCookie && config.CookieKey != "" {
+							c.SetCookie(&http.Cookie{Name: config.CookieKey, Value: lang, Path: "/", MaxAge: 365 * 86400})
+						}
+					}
+				}
+				if lang == "" && config.CookieKey != "" {
+					if ck := c.CookieParam(config.CookieKey); ck != nil && bundle.Has(ck.Value) {
+						lang = i18n.Normalize(ck.Value)
+					}
+				}
+				if lang == "" {
+					lang = bundle.Match(i18n.ParseAcceptLanguage(c.request.Header.Get(HeaderAcceptLanguage))...)
+				}
+				c.Set(i18nBundleKey, bundle)
+				c.Set(localeKey, lang)
+				c.response.Header().Set(HeaderContentLanguage, lang)
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 获取消息目录对应的消息包，多个挂载点共用
+func getI18nBundle(dir, defaultLang string) *i18n.Bundle {
+	i18nBundlesMux.Lock()
+	defer i18nBundlesMux.Unlock()
+	key := dir + "|" + defaultLang
+	if b, ok := i18nBundles[key]; ok {
+		return b
+	}
+	b := i18n.NewBundle(defaultLang)
+	if err := b.LoadDir(dir); err != nil {
+		Log.Error("I18n: %v", err)
+	}
+	i18nBundles[key] = b
+	return b
+}
+
+// 返回当前请求的语言，未启用多语言中间件时返回空
+func (c *Context) Locale() string {
+	lang, _ := c.Get(localeKey).(string)
+	return lang
+}
+
+// 按当前请求的语言翻译消息，args用于格式化及选择复数形式
+func (c *Context) T(key string, args ...interface{}) string {
+	bundle, ok := c.Get(i18nBundleKey).(*i18n.Bundle)
+	if !ok {
+		return key
+	}
+	return bundle.Tr(c.Locale(), key, args...)
+}
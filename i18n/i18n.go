@@ -0,0 +1,394 @@
+// Package i18n 提供多语言消息包的加载与翻译，支持TOML/JSON文件、复数形式与语言回退链。
+//
+// 消息文件以语言标记命名，如"zh-CN.toml"、"en.json"：
+//
+//	# en.toml
+//	hello = "Hello, %s!"
+//	[cart.items]
+//	one = "%d item"
+//	other = "%d items"
+//
+//	b := i18n.NewBundle("en")
+//	b.LoadDir("i18n")
+//	b.Tr("en", "cart.items", 3) // "3 items"
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Bundle 多语言消息包
+	Bundle struct {
+		defaultLang string
+		messages    map[string]map[string]*Message
+		fallbacks   map[string][]string
+		sync.RWMutex
+	}
+
+	// Message 一条消息的各复数形式，无复数形式时只使用Other
+	Message struct {
+		Zero  string `json:"zero"`
+		One   string `json:"one"`
+		Two   string `json:"two"`
+		Few   string `json:"few"`
+		Many  string `json:"many"`
+		Other string `json:"other"`
+	}
+)
+
+// NewBundle 创建消息包，defaultLang为最终回退语言
+func NewBundle(defaultLang string) *Bundle {
+	return &Bundle{
+		defaultLang: Normalize(defaultLang),
+		messages:    map[string]map[string]*Message{},
+		fallbacks:   map[string][]string{},
+	}
+}
+
+// DefaultLang 返回默认语言
+func (b *Bundle) DefaultLang() string {
+	return b.defaultLang
+}
+
+// LoadDir 加载目录下所有.toml与.json消息文件
+func (b *Bundle) LoadDir(dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		switch filepath.Ext(info.Name()) {
+		case ".toml", ".json":
+			if err := b.LoadFile(filepath.Join(dir, info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFile 加载消息文件，文件名(不含扩展名)即语言标记
+func (b *Bundle) LoadFile(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	ext := filepath.Ext(filename)
+	lang := strings.TrimSuffix(filepath.Base(filename), ext)
+	return b.Load(lang, ext[1:], data)
+}
+
+// Load 加载指定格式("toml"或"json")的消息数据
+func (b *Bundle) Load(lang, format string, data []byte) error {
+	var tree map[string]interface{}
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("i18n: %s: %v", lang, err)
+		}
+	case "toml":
+		var err error
+		if tree, err = parseTOML(data); err != nil {
+			return fmt.Errorf("i18n: %s: %v", lang, err)
+		}
+	default:
+		return fmt.Errorf("i18n: unsupported format %q", format)
+	}
+	msgs := map[string]*Message{}
+	flatten("", tree, msgs)
+	b.AddMessages(lang, msgs)
+	return nil
+}
+
+// AddMessages 添加消息，覆盖同名消息
+func (b *Bundle) AddMessages(lang string, msgs map[string]*Message) {
+	lang = Normalize(lang)
+	b.Lock()
+	defer b.Unlock()
+	m, ok := b.messages[lang]
+	if !ok {
+		m = map[string]*Message{}
+		b.messages[lang] = m
+	}
+	for k, v := range msgs {
+		m[k] = v
+	}
+}
+
+// AddStrings 添加无复数形式的消息
+func (b *Bundle) AddStrings(lang string, msgs map[string]string) {
+	m := make(map[string]*Message, len(msgs))
+	for k, v := range msgs {
+		m[k] = &Message{Other: v}
+	}
+	b.AddMessages(lang, m)
+}
+
+// SetFallback 设置语言的回退链，如SetFallback("zh-HK", "zh-TW", "zh-CN")
+func (b *Bundle) SetFallback(lang string, fallbacks ...string) {
+	for i := range fallbacks {
+		fallbacks[i] = Normalize(fallbacks[i])
+	}
+	b.Lock()
+	b.fallbacks[Normalize(lang)] = fallbacks
+	b.Unlock()
+}
+
+// Langs 返回已加载的语言
+func (b *Bundle) Langs() []string {
+	b.RLock()
+	defer b.RUnlock()
+	langs := make([]string, 0, len(b.messages))
+	for lang := range b.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Has 查询是否已加载该语言
+func (b *Bundle) Has(lang string) bool {
+	b.RLock()
+	_, ok := b.messages[Normalize(lang)]
+	b.RUnlock()
+	return ok
+}
+
+// Match 从候选语言中选出第一个已加载(或其基础语言已加载)的语言，均不匹配时返回默认语言
+func (b *Bundle) Match(langs ...string) string {
+	for _, lang := range langs {
+		lang = Normalize(lang)
+		if lang == "" {
+			continue
+		}
+		if b.Has(lang) {
+			return lang
+		}
+		if base := baseLang(lang); base != lang && b.Has(base) {
+			return base
+		}
+	}
+	return b.defaultLang
+}
+
+// Tr 翻译消息，args中的第一个整数同时用于选择复数形式；未找到消息时返回key
+func (b *Bundle) Tr(lang, key string, args ...interface{}) string {
+	lang = Normalize(lang)
+	msg, msgLang := b.lookup(lang, key)
+	if msg == nil {
+		return key
+	}
+	text := msg.Other
+	for _, arg := range args {
+		if n, ok := toNumber(arg); ok {
+			text = msg.form(PluralCategory(msgLang, n))
+			break
+		}
+	}
+	if len(args) == 0 || !strings.Contains(text, "%") {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// 按回退链查找消息
+func (b *Bundle) lookup(lang, key string) (*Message, string) {
+	b.RLock()
+	defer b.RUnlock()
+	for _, l := range b.chain(lang) {
+		if msg, ok := b.messages[l][key]; ok {
+			return msg, l
+		}
+	}
+	return nil, ""
+}
+
+// 回退链：lang -> 自定义回退 -> 基础语言 -> 默认语言
+func (b *Bundle) chain(lang string) []string {
+	chain := []string{lang}
+	chain = append(chain, b.fallbacks[lang]...)
+	if base := baseLang(lang); base != lang {
+		chain = append(chain, base)
+		chain = append(chain, b.fallbacks[base]...)
+	}
+	return append(chain, b.defaultLang)
+}
+
+func (m *Message) form(category string) string {
+	var s string
+	switch category {
+	case Zero:
+		s = m.Zero
+	case One:
+		s = m.One
+	case Two:
+		s = m.Two
+	case Few:
+		s = m.Few
+	case Many:
+		s = m.Many
+	}
+	if s == "" {
+		return m.Other
+	}
+	return s
+}
+
+// Normalize 规范化语言标记，如"zh_cn"转为"zh-CN"
+func Normalize(lang string) string {
+	lang = strings.TrimSpace(strings.Replace(lang, "_", "-", -1))
+	parts := strings.Split(lang, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.Title(strings.ToLower(parts[i]))
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// ParseAcceptLanguage 按权重从高到低返回Accept-Language中的语言
+func ParseAcceptLanguage(header string) []string {
+	var items acceptLangs
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		q := 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			if p := strings.TrimSpace(part[i+1:]); strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+			part = strings.TrimSpace(part[:i])
+		}
+		if part == "*" || q <= 0 {
+			continue
+		}
+		items = append(items, acceptLang{part, q})
+	}
+	sort.Stable(items)
+	langs := make([]string, len(items))
+	for i, it := range items {
+		langs[i] = it.lang
+	}
+	return langs
+}
+
+type (
+	acceptLang struct {
+		lang string
+		q    float64
+	}
+	acceptLangs []acceptLang
+)
+
+func (a acceptLangs) Len() int           { return len(a) }
+func (a acceptLangs) Less(i, j int) bool { return a[i].q > a[j].q }
+func (a acceptLangs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+func baseLang(lang string) string {
+	if i := strings.Index(lang, "-"); i > 0 {
+		return lang[:i]
+	}
+	return lang
+}
+
+// 将嵌套的消息树展开为"a.b.c"形式的键
+func flatten(prefix string, tree map[string]interface{}, msgs map[string]*Message) {
+	for k, v := range tree {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch t := v.(type) {
+		case string:
+			msgs[key] = &Message{Other: t}
+		case map[string]interface{}:
+			if msg, ok := pluralMessage(t); ok {
+				msgs[key] = msg
+			} else {
+				flatten(key, t, msgs)
+			}
+		default:
+			msgs[key] = &Message{Other: fmt.Sprint(t)}
+		}
+	}
+}
+
+// 仅包含复数形式键(且含other)的表视为一条消息
+func pluralMessage(t map[string]interface{}) (*Message, bool) {
+	if _, ok := t[Other]; !ok {
+		return nil, false
+	}
+	msg := &Message{}
+	for k, v := range t {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		switch k {
+		case Zero:
+			msg.Zero = s
+		case One:
+			msg.One = s
+		case Two:
+			msg.Two = s
+		case Few:
+			msg.Few = s
+		case Many:
+			msg.Many = s
+		case Other:
+			msg.Other = s
+		default:
+			return nil, false
+		}
+	}
+	return msg, true
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
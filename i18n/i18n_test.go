@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testTOML = `
+# 英文消息
+hello = "Hello, %s!"
+"quoted.key" = 'literal \n'
+
+[cart.items]
+one = "%d item"
+other = "%d items"
+
+[menu]
+file.open = "Open"
+note = """
+Line1
+Line2"""
+`
+
+const testJSON = `{
+	"hello": "你好，%s！",
+	"cart": {"items": {"other": "%d 件商品"}}
+}`
+
+func testBundle(t *testing.T) *Bundle {
+	b := NewBundle("en")
+	if err := b.Load("en", "toml", []byte(testTOML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Load("zh_cn", "json", []byte(testJSON)); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestTr(t *testing.T) {
+	b := testBundle(t)
+	b.SetFallback("zh-TW", "zh-CN")
+	for _, test := range []struct {
+		lang, key string
+		args      []interface{}
+		want      string
+	}{
+		{"en", "hello", []interface{}{"Tom"}, "Hello, Tom!"},
+		{"zh-CN", "hello", []interface{}{"Tom"}, "你好，Tom！"},
+		{"zh-TW", "hello", []interface{}{"Tom"}, "你好，Tom！"},
+		{"fr", "hello", []interface{}{"Tom"}, "Hello, Tom!"},
+		{"en", "cart.items", []interface{}{1}, "1 item"},
+		{"en", "cart.items", []interface{}{3}, "3 items"},
+		{"zh-CN", "cart.items", []interface{}{1}, "1 件商品"},
+		{"en", "menu.file.open", nil, "Open"},
+		{"en", "menu.note", nil, "Line1\nLine2"},
+		{"en", "quoted.key", nil, `literal \n`},
+		{"en", "missing", nil, "missing"},
+	} {
+		if got := b.Tr(test.lang, test.key, test.args...); got != test.want {
+			t.Errorf("Tr(%q, %q) = %q, want %q", test.lang, test.key, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	b := testBundle(t)
+	for _, test := range []struct {
+		header, want string
+	}{
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh-CN"},
+		{"fr;q=0.9, en-US;q=0.5", "en"},
+		{"de", "en"},
+		{"", "en"},
+	} {
+		if got := b.Match(ParseAcceptLanguage(test.header)...); got != test.want {
+			t.Errorf("Match(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("en;q=0.5, zh-CN, *;q=0.1, fr;q=0.8, de;q=0")
+	want := []string{"zh-CN", "fr", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAcceptLanguage = %v, want %v", got, want)
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	for _, test := range []struct {
+		lang string
+		n    float64
+		want string
+	}{
+		{"en", 1, One},
+		{"en", 0, Other},
+		{"zh", 1, Other},
+		{"fr", 0, One},
+		{"ru", 21, One},
+		{"ru", 22, Few},
+		{"ru", 25, Many},
+		{"ru", 12, Many},
+		{"pl", 22, Few},
+		{"ar", 2, Two},
+	} {
+		if got := PluralCategory(test.lang, test.n); got != test.want {
+			t.Errorf("PluralCategory(%q, %v) = %q, want %q", test.lang, test.n, got, test.want)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, s := range []string{
+		"key",
+		`key = "unterminated`,
+		"key = [1, 2]",
+		"a = \"1\"\na = \"2\"",
+		"[[array]]",
+	} {
+		if _, err := parseTOML([]byte(s)); err == nil {
+			t.Errorf("parseTOML(%q) expected error", s)
+		}
+	}
+}
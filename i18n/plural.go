@@ -0,0 +1,151 @@
+package i18n
+
+import (
+	"math"
+	"sync"
+)
+
+// CLDR复数类别
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// PluralFunc 根据数量返回复数类别
+type PluralFunc func(n float64) string
+
+var (
+	pluralRules = map[string]PluralFunc{}
+	pluralLock  sync.RWMutex
+)
+
+func init() {
+	// 无复数变化
+	for _, lang := range []string{"zh", "ja", "ko", "vi", "th", "id", "ms", "lo", "my"} {
+		pluralRules[lang] = pluralNone
+	}
+	// one: n=1
+	for _, lang := range []string{"en", "de", "nl", "sv", "da", "no", "nb", "fi", "et", "it", "es", "el", "hu", "tr", "bg", "ca", "he"} {
+		pluralRules[lang] = pluralOne
+	}
+	// one: 0<=n<2
+	for _, lang := range []string{"fr", "pt", "hy"} {
+		pluralRules[lang] = pluralFrench
+	}
+	// 斯拉夫语系
+	for _, lang := range []string{"ru", "uk", "be", "sr", "hr", "bs"} {
+		pluralRules[lang] = pluralSlavic
+	}
+	pluralRules["pl"] = pluralPolish
+	pluralRules["cs"] = pluralCzech
+	pluralRules["sk"] = pluralCzech
+	pluralRules["ar"] = pluralArabic
+}
+
+// SetPluralRule 设置语言的复数规则
+func SetPluralRule(lang string, fn PluralFunc) {
+	pluralLock.Lock()
+	pluralRules[baseLang(Normalize(lang))] = fn
+	pluralLock.Unlock()
+}
+
+// PluralCategory 返回数量n在该语言中的复数类别，未知语言按英语规则处理
+func PluralCategory(lang string, n float64) string {
+	pluralLock.RLock()
+	fn, ok := pluralRules[lang]
+	if !ok {
+		fn, ok = pluralRules[baseLang(lang)]
+	}
+	pluralLock.RUnlock()
+	if !ok {
+		fn = pluralOne
+	}
+	return fn(n)
+}
+
+func isInt(n float64) bool {
+	return n == math.Trunc(n)
+}
+
+func pluralNone(n float64) string {
+	return Other
+}
+
+func pluralOne(n float64) string {
+	if n == 1 {
+		return One
+	}
+	return Other
+}
+
+func pluralFrench(n float64) string {
+	if n >= 0 && n < 2 {
+		return One
+	}
+	return Other
+}
+
+func pluralSlavic(n float64) string {
+	if !isInt(n) {
+		return Other
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i%10 == 1 && i%100 != 11:
+		return One
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return Few
+	}
+	return Many
+}
+
+func pluralPolish(n float64) string {
+	if !isInt(n) {
+		return Other
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i == 1:
+		return One
+	case i%10 >= 2 && i%10 <= 4 && (i%100 < 12 || i%100 > 14):
+		return Few
+	}
+	return Many
+}
+
+func pluralCzech(n float64) string {
+	if !isInt(n) {
+		return Many
+	}
+	switch {
+	case n == 1:
+		return One
+	case n >= 2 && n <= 4:
+		return Few
+	}
+	return Other
+}
+
+func pluralArabic(n float64) string {
+	if !isInt(n) {
+		return Other
+	}
+	i := int64(math.Abs(n))
+	switch {
+	case i == 0:
+		return Zero
+	case i == 1:
+		return One
+	case i == 2:
+		return Two
+	case i%100 >= 3 && i%100 <= 10:
+		return Few
+	case i%100 >= 11:
+		return Many
+	}
+	return Other
+}
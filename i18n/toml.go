@@ -0,0 +1,273 @@
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 解析消息文件所需的TOML子集：表头、(点分)键、字符串、数字与布尔值
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	current := root
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimSpace(lines[n])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		lineno := n + 1
+
+		// 表头
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header", lineno)
+			}
+			if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != '#' {
+				return nil, fmt.Errorf("line %d: unexpected %q after table header", lineno, rest)
+			}
+			keys, err := splitTOMLKey(line[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			if current, err = tomlTable(root, keys); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineno, err)
+			}
+			continue
+		}
+
+		// 键值对
+		eq := tomlKeyEnd(line)
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", lineno)
+		}
+		keys, err := splitTOMLKey(line[:eq])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		raw := strings.TrimSpace(line[eq+1:])
+
+		// 多行字符串
+		for _, delim := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(raw, delim) && strings.Count(raw, delim) < 2 {
+				for n+1 < len(lines) {
+					n++
+					raw += "\n" + lines[n]
+					if strings.Contains(lines[n], delim) {
+						break
+					}
+				}
+			}
+		}
+
+		value, err := parseTOMLValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		table, err := tomlTable(current, keys[:len(keys)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+		last := keys[len(keys)-1]
+		if _, ok := table[last]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineno, last)
+		}
+		table[last] = value
+	}
+	return root, nil
+}
+
+// 获取(必要时创建)嵌套表
+func tomlTable(root map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	t := root
+	for _, k := range keys {
+		v, ok := t[k]
+		if !ok {
+			child := map[string]interface{}{}
+			t[k] = child
+			t = child
+			continue
+		}
+		child, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %q is not a table", k)
+		}
+		t = child
+	}
+	return t, nil
+}
+
+// 查找键之后的等号位置(跳过引号中的内容)
+func tomlKeyEnd(line string) int {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+func splitTOMLKey(s string) ([]string, error) {
+	var keys []string
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var key string
+		switch s[0] {
+		case '"', '\'':
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted key")
+			}
+			key, s = s[1:end+1], strings.TrimSpace(s[end+2:])
+		default:
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			key, s = strings.TrimSpace(s[:end]), s[end:]
+			if key == "" || strings.ContainsAny(key, " \t") {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+		}
+		keys = append(keys, key)
+		if s == "" {
+			break
+		}
+		if s[0] != '.' {
+			return nil, fmt.Errorf("invalid key")
+		}
+		s = strings.TrimSpace(s[1:])
+		if s == "" {
+			return nil, fmt.Errorf("invalid key")
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	return keys, nil
+}
+
+func parseTOMLValue(raw string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(raw, `"""`):
+		end := strings.Index(raw[3:], `"""`)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		s := strings.TrimPrefix(raw[3:3+end], "\n")
+		return unescapeTOML(s)
+	case strings.HasPrefix(raw, `'''`):
+		end := strings.Index(raw[3:], `'''`)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		return strings.TrimPrefix(raw[3:3+end], "\n"), nil
+	case strings.HasPrefix(raw, `"`):
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '\\' {
+				i++
+				continue
+			}
+			if raw[i] == '"' {
+				if err := tomlTrailing(raw[i+1:]); err != nil {
+					return nil, err
+				}
+				return unescapeTOML(raw[1:i])
+			}
+		}
+		return nil, fmt.Errorf("unterminated string")
+	case strings.HasPrefix(raw, `'`):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		if err := tomlTrailing(raw[end+2:]); err != nil {
+			return nil, err
+		}
+		return raw[1 : end+1], nil
+	}
+	if i := strings.IndexByte(raw, '#'); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	switch raw {
+	case "true", "false":
+		return raw, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(raw, "_", "", -1), 64); err == nil {
+		return raw, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", raw)
+}
+
+func tomlTrailing(s string) error {
+	s = strings.TrimSpace(s)
+	if s != "" && s[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", s)
+	}
+	return nil
+}
+
+func unescapeTOML(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("invalid escape")
+		}
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			i += n
+		case '\n':
+			// 行尾反斜杠：去掉换行及下一行开头的空白
+			for i+1 < len(s) && strings.IndexByte(" \t\n", s[i+1]) >= 0 {
+				i++
+			}
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
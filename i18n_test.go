@@ -0,0 +1,61 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lessgo/lessgo/i18n"
+)
+
+func TestI18nNegotiation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"hello":"Hello"}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "zh_cn.json"), []byte(`{"hello":"你好"}`), 0644)
+
+	for _, test := range []struct {
+		cookieKey, url, cookie, accept string
+		want, vary                     string
+	}{
+		{"lang", "/?lang=zh_cn", "", "en", "zh_cn", "Accept-Language, Cookie"},
+		{"lang", "/", "zh_cn", "en", "zh_cn", "Accept-Language, Cookie"},
+		{"lang", "/", "", "zh-CN,en;q=0.5", "zh_cn", "Accept-Language, Cookie"},
+		{"lang", "/", "", "fr", "en", "Accept-Language, Cookie"},
+		// 不使用Cookie时只与Accept-Language有关
+		{"", "/?lang=zh_cn", "", "en", "zh_cn", "Accept-Language"},
+		{"", "/", "", "en", "en", "Accept-Language"},
+	} {
+		config := I18nConfig{Dir: dir, Default: "en", QueryKey: "lang", CookieKey: test.cookieKey}
+		var body string
+		h := I18n.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+			body = c.T("hello")
+			return nil
+		})
+		req, _ := http.NewRequest(GET, test.url, nil)
+		req.Header.Set(HeaderAcceptLanguage, test.accept)
+		if test.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "lang", Value: test.cookie})
+		}
+		c, rec := testContext(req)
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		want := i18n.Normalize(test.want)
+		if c.Locale() != want || rec.Header().Get(HeaderContentLanguage) != want {
+			t.Errorf("%s (cookie %q, accept %q): locale %q, want %q", test.url, test.cookie, test.accept, c.Locale(), want)
+		}
+		if want == i18n.Normalize("zh_cn") && body != "你好" || want == "en" && body != "Hello" {
+			t.Errorf("%s: T(hello) = %q in %s", test.url, body, want)
+		}
+		if got := rec.Header().Get(HeaderVary); got != test.vary {
+			t.Errorf("%s (cookie %q): Vary = %q, want %q", test.url, test.cookie, got, test.vary)
+		}
+		c.free()
+	}
+}
@@ -0,0 +1,263 @@
+package lessgo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+type (
+	// IdempotencyConfig defines the config for Idempotency-Key middleware.
+	IdempotencyConfig struct {
+		// 携带幂等键的请求头
+		Header string
+
+		// 需要进行幂等处理的请求方法
+		Methods []string
+
+		// 记录保留时长，单位秒
+		TTL int64
+
+		// 允许记录的最大响应体，超出时不记录
+		MaxBytes int64
+
+		// 计算指纹时允许读取的最大请求体，超出时返回413
+		MaxBodyBytes int64
+
+		// 是否要求请求必须携带幂等键
+		Required bool
+
+		// Context中存放当前用户的键名，幂等键按用户隔离；无用户时按客户端IP隔离
+		UserKey string
+
+		// 可信代理的IP或CIDR网段，用于确定客户端IP
+		TrustedProxies []string
+	}
+
+	// 记录的响应
+	IdempotentResponse struct {
+		Fingerprint string
+		Status      int
+		Header      http.Header
+		Body        []byte
+	}
+
+	// 幂等记录存储接口
+	IdempotencyStore interface {
+		// 锁定key；已有完成的记录时返回该记录，正在处理中时返回locked=true
+		Begin(key string, ttl time.Duration) (resp *IdempotentResponse, locked bool, err error)
+		// 保存处理结果并解除锁定
+		Save(key string, resp *IdempotentResponse, ttl time.Duration) error
+		// 放弃保存并解除锁定，以便客户端重试
+		Release(key string) error
+	}
+
+	// 内存中的幂等记录存储
+	memoryIdempotencyStore struct {
+		entries map[string]*idempotencyEntry
+		lastGC  time.Time
+		sync.Mutex
+	}
+
+	idempotencyEntry struct {
+		resp   *IdempotentResponse
+		expire time.Time
+	}
+)
+
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+var (
+	idempotencyStore     IdempotencyStore = NewMemoryIdempotencyStore()
+	idempotencyStoreLock sync.RWMutex
+)
+
+var Idempotency = ApiMiddleware{
+	Name: "幂等键",
+	Desc: "根据Idempotency-Key记录响应并在重试时重放，并发的重复请求返回409",
+	Config: IdempotencyConfig{
+		Header:         HeaderIdempotencyKey,
+		Methods:        []string{POST, PATCH},
+		TTL:            86400,
+		MaxBytes:       1 * MB,
+		MaxBodyBytes:   4 * MB,
+		Required:       false,
+		UserKey:        "user",
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(IdempotencyConfig)
+		if config.Header == "" {
+			config.Header = HeaderIdempotencyKey
+		}
+		ttl := time.Duration(config.TTL) * time.Second
+		proxies := parseIPNets(config.TrustedProxies, "Idempotency: invalid TrustedProxies item")
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				if !utils.InSlice(req.Method, config.Methods) {
+					return next(c)
+				}
+				key := req.Header.Get(config.Header)
+				if key == "" {
+					if config.Required {
+						return NewHTTPError(http.StatusBadRequest, "missing "+config.Header+" header")
+					}
+					return next(c)
+				}
+				key = idempotencyScope(c, config.UserKey, proxies) + "|" + key
+				fingerprint, err := idempotencyFingerprint(req, config.MaxBodyBytes)
+				if err != nil {
+					return err
+				}
+
+				idempotencyStoreLock.RLock()
+				store := idempotencyStore
+				idempotencyStoreLock.RUnlock()
+
+				resp, locked, err := store.Begin(key, ttl)
+				if err != nil {
+					return err
+				}
+				if locked {
+					return NewHTTPError(http.StatusConflict, "a request with the same "+config.Header+" is being processed")
+				}
+				if resp != nil {
+					if resp.Fingerprint != fingerprint {
+						return NewHTTPError(http.StatusUnprocessableEntity, config.Header+" has been used by another request")
+					}
+					header := c.response.Header()
+					for k, v := range resp.Header {
+						header[k] = v
+					}
+					header.Set("Idempotent-Replayed", "true")
+					c.WriteHeader(resp.Status)
+					_, err = c.response.Write(resp.Body)
+					return err
+				}
+
+				w := &captureWriter{
+					responseWriterWrapper: responseWriterWrapper{c.response.writer},
+					max:                   config.MaxBytes,
+				}
+				c.response.writer = w
+				defer func() {
+					c.response.writer = w.ResponseWriter
+					// 发生恐慌时解除锁定，允许重试
+					if rcv := recover(); rcv != nil {
+						store.Release(key)
+						panic(rcv)
+					}
+				}()
+				err = next(c)
+				status := c.response.Status()
+				if err != nil || status >= 500 || w.overflow || !c.response.Committed() {
+					store.Release(key)
+					return err
+				}
+				header := make(http.Header, len(c.response.Header()))
+				for k, v := range c.response.Header() {
+					header[k] = v
+				}
+				return store.Save(key, &IdempotentResponse{
+					Fingerprint: fingerprint,
+					Status:      status,
+					Header:      header,
+					Body:        w.buf.Bytes(),
+				}, ttl)
+			}
+		}
+	},
+}.Reg()
+
+// 幂等键的隔离范围：已认证用户或客户端IP
+func idempotencyScope(c *Context, userKey string, proxies []*net.IPNet) string {
+	if userKey != "" {
+		if u := c.Get(userKey); u != nil {
+			return "user:" + fmt.Sprint(u)
+		}
+	}
+	return "ip:" + clientIP(c.request, proxies).String()
+}
+
+// 请求指纹：方法、路径与请求体的SHA-256，读取后还原请求体；
+// 请求体超过max字节时返回413
+func idempotencyFingerprint(req *http.Request, max int64) (string, error) {
+	h := sha256.New()
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(io.LimitReader(req.Body, max+1))
+		if err != nil {
+			return "", NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if int64(len(b)) > max {
+			return "", NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		h.Write(b)
+	}
+	return req.Method + " " + req.URL.Path + " " + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 设置幂等记录存储(内部默认为内存存储)
+func SetIdempotencyStore(store IdempotencyStore) {
+	idempotencyStoreLock.Lock()
+	idempotencyStore = store
+	idempotencyStoreLock.Unlock()
+}
+
+// 创建内存中的幂等记录存储
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		entries: map[string]*idempotencyEntry{},
+		lastGC:  time.Now(),
+	}
+}
+
+func (m *memoryIdempotencyStore) Begin(key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	now := time.Now()
+	m.gc(now, ttl)
+	if e, ok := m.entries[key]; ok && now.Before(e.expire) {
+		return e.resp, e.resp == nil, nil
+	}
+	m.entries[key] = &idempotencyEntry{expire: now.Add(ttl)}
+	return nil, false, nil
+}
+
+func (m *memoryIdempotencyStore) Save(key string, resp *IdempotentResponse, ttl time.Duration) error {
+	m.Lock()
+	m.entries[key] = &idempotencyEntry{resp: resp, expire: time.Now().Add(ttl)}
+	m.Unlock()
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Release(key string) error {
+	m.Lock()
+	delete(m.entries, key)
+	m.Unlock()
+	return nil
+}
+
+// 清理过期记录，最多每个ttl周期执行一次
+func (m *memoryIdempotencyStore) gc(now time.Time, ttl time.Duration) {
+	if now.Sub(m.lastGC) < ttl {
+		return
+	}
+	m.lastGC = now
+	for k, e := range m.entries {
+		if now.After(e.expire) {
+			delete(m.entries, k)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package lessgo
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIdempotency(t *testing.T) {
+	SetIdempotencyStore(NewMemoryIdempotencyStore())
+	defer SetIdempotencyStore(NewMemoryIdempotencyStore())
+	config := Idempotency.Config.(IdempotencyConfig)
+	config.MaxBodyBytes = 16
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	h := Idempotency.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+		n := atomic.AddInt32(&calls, 1)
+		if c.Request().Header.Get("X-Block") != "" {
+			started <- struct{}{}
+			<-release
+		}
+		c.Response().Header().Set("X-Call", string(rune('0'+n)))
+		return c.String(http.StatusCreated, "order "+string(rune('0'+n)))
+	})
+	do := func(key, body string, header ...string) (*http.Response, string, error) {
+		req, _ := http.NewRequest(POST, "/orders", strings.NewReader(body))
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set(HeaderIdempotencyKey, key)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		c, rec := testContext(req)
+		defer c.free()
+		err := h(c)
+		return rec.Result(), rec.Body.String(), err
+	}
+	code := func(err error) int {
+		if he, ok := err.(*HTTPError); ok {
+			return he.Code
+		}
+		return 0
+	}
+
+	resp, body, err := do("a", `{"sku":1}`)
+	if err != nil || resp.StatusCode != http.StatusCreated || body != "order 1" {
+		t.Fatalf("first request = %d %q, %v", resp.StatusCode, body, err)
+	}
+	// 重试时重放记录的响应，不再调用处理函数
+	resp, body, err = do("a", `{"sku":1}`)
+	if err != nil || resp.StatusCode != http.StatusCreated || body != "order 1" ||
+		resp.Header.Get("X-Call") != "1" || resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay = %d %q %v, %v", resp.StatusCode, body, resp.Header, err)
+	}
+	// 同一幂等键用于不同的请求体
+	if _, _, err = do("a", `{"sku":2}`); code(err) != http.StatusUnprocessableEntity {
+		t.Errorf("fingerprint mismatch = %v, want 422", err)
+	}
+	// 请求体超出限制，不读取全部内容
+	if _, _, err = do("big", strings.Repeat("x", 17)); code(err) != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body = %v, want 413", err)
+	}
+	if _, _, err = do("fit", strings.Repeat("x", 16)); err != nil {
+		t.Errorf("body at the limit = %v", err)
+	}
+
+	// 处理中的重复请求返回409，完成后可重放
+	done := make(chan error)
+	go func() {
+		_, _, err := do("b", "{}", "X-Block", "1")
+		done <- err
+	}()
+	<-started
+	if _, _, err = do("b", "{}"); code(err) != http.StatusConflict {
+		t.Errorf("concurrent duplicate = %v, want 409", err)
+	}
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, body, err = do("b", "{}"); err != nil || body != "order 3" {
+		t.Errorf("replay after concurrent request = %q, %v", body, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("handler calls = %d, want 3", n)
+	}
+}
@@ -0,0 +1,311 @@
+package lessgo
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// 图片变体服务的配置
+	ImageVariantConfig struct {
+		Root      string        // 原图目录
+		CacheDir  string        // 变体缓存目录，原图a/b.jpg宽320的webp变体缓存为a/b.jpg/320.webp，可预先放入构建时生成的变体
+		Widths    []int         // 允许的宽度，为空时允许不超过MaxWidth的任意宽度
+		MaxWidth  int           // 最大宽度，默认2048
+		MaxPixels int           // 允许处理的原图最大像素数，默认4000万，防止解压炸弹
+		Workers   int           // 同时进行的转换数，默认为GOMAXPROCS
+		Wait      time.Duration // 等待空闲转换线程的最长时间，超时响应503，默认10秒
+		Quality   int           // JPEG质量，默认80
+		MaxAge    int64         // 响应的Cache-Control max-age，单位秒，0为不设置
+	}
+
+	// 图片编码器
+	ImageEncoder struct {
+		Ext    string // 变体文件的扩展名，如".webp"
+		Encode func(w io.Writer, img image.Image, quality int) error
+	}
+)
+
+var (
+	imageEncoders = map[string]*ImageEncoder{
+		"jpeg": {".jpg", func(w io.Writer, img image.Image, quality int) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		}},
+		"png": {".png", func(w io.Writer, img image.Image, quality int) error {
+			return png.Encode(w, img)
+		}},
+		"gif": {".gif", func(w io.Writer, img image.Image, quality int) error {
+			return gif.Encode(w, img, nil)
+		}},
+	}
+	imageEncodersLock sync.RWMutex
+)
+
+// 注册图片编码器，format为fmt参数的取值；内置jpeg、png与gif，标准库不支持编码webp等格式，
+// 可注册第三方编码器，或在缓存目录中预先放入生成好的变体
+func RegisterImageEncoder(format string, encoder *ImageEncoder) {
+	imageEncodersLock.Lock()
+	imageEncoders[strings.ToLower(format)] = encoder
+	imageEncodersLock.Unlock()
+}
+
+func getImageEncoder(format string) *ImageEncoder {
+	imageEncodersLock.RLock()
+	defer imageEncodersLock.RUnlock()
+	return imageEncoders[format]
+}
+
+// 创建图片变体服务的操作(用法同StaticFunc)：无参数时返回原图，
+// ?w=320按宽度等比缩小(不放大)，?fmt=webp转换格式；变体按需生成并缓存到磁盘，
+// 原图更新后重新生成，同一变体的并发请求只生成一次
+func ImageVariantFunc(conf ImageVariantConfig) HandlerFunc {
+	if conf.MaxWidth <= 0 {
+		conf.MaxWidth = 2048
+	}
+	if conf.MaxPixels <= 0 {
+		conf.MaxPixels = 40000000
+	}
+	if conf.Workers <= 0 {
+		conf.Workers = runtime.GOMAXPROCS(0)
+	}
+	if conf.Wait <= 0 {
+		conf.Wait = 10 * time.Second
+	}
+	if conf.Quality <= 0 {
+		conf.Quality = 80
+	}
+	v := &imageVariants{
+		conf:     conf,
+		workers:  make(chan struct{}, conf.Workers),
+		inflight: map[string]chan struct{}{},
+	}
+	return v.serve
+}
+
+type imageVariants struct {
+	conf     ImageVariantConfig
+	workers  chan struct{}
+	mu       sync.Mutex
+	inflight map[string]chan struct{}
+}
+
+func (v *imageVariants) serve(c *Context) error {
+	name := path.Clean("/" + c.PathParamByIndex(0))
+	src := filepath.Join(v.conf.Root, filepath.FromSlash(name))
+	if v.conf.MaxAge > 0 {
+		c.response.CacheControl().Public().MaxAge(time.Duration(v.conf.MaxAge) * time.Second)
+	}
+	ws, format := c.QueryParam("w"), strings.ToLower(c.QueryParam("fmt"))
+	if ws == "" && format == "" {
+		return c.File(src)
+	}
+
+	width := 0
+	if ws != "" {
+		var err error
+		if width, err = strconv.Atoi(ws); err != nil || width <= 0 || width > v.conf.MaxWidth || !v.allowed(width) {
+			return c.String(http.StatusBadRequest, "invalid width")
+		}
+	}
+	switch format {
+	case "":
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+		if format == "jpg" {
+			format = "jpeg"
+		}
+	case "jpg":
+		format = "jpeg"
+	}
+	for _, r := range format {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return c.String(http.StatusBadRequest, "invalid image format")
+		}
+	}
+	fi, err := os.Stat(src)
+	if err != nil || fi.IsDir() {
+		return ErrNotFound
+	}
+
+	ext := "." + format
+	encoder := getImageEncoder(format)
+	if encoder != nil {
+		ext = encoder.Ext
+	}
+	variant := "orig"
+	if width > 0 {
+		variant = strconv.Itoa(width)
+	}
+	dst := filepath.Join(v.conf.CacheDir, filepath.FromSlash(name), variant+ext)
+	if variantFresh(dst, fi.ModTime()) {
+		return c.File(dst)
+	}
+	if encoder == nil {
+		return c.String(http.StatusBadRequest, "unsupported image format "+format)
+	}
+
+	// 同一变体只生成一次，其余请求等待其完成
+	v.mu.Lock()
+	done, ok := v.inflight[dst]
+	if !ok {
+		done = make(chan struct{})
+		v.inflight[dst] = done
+	}
+	v.mu.Unlock()
+	if ok {
+		<-done
+		if variantFresh(dst, fi.ModTime()) {
+			return c.File(dst)
+		}
+		return ErrStatusInternalServerError
+	}
+	defer func() {
+		v.mu.Lock()
+		delete(v.inflight, dst)
+		v.mu.Unlock()
+		close(done)
+	}()
+
+	timer := time.NewTimer(v.conf.Wait)
+	select {
+	case v.workers <- struct{}{}:
+		timer.Stop()
+	case <-timer.C:
+		return c.String(http.StatusServiceUnavailable, "too many image transforms")
+	}
+	err = v.transform(src, dst, width, format, encoder)
+	<-v.workers
+	if err != nil {
+		if he, ok := err.(*HTTPError); ok {
+			return c.String(he.Code, he.Message)
+		}
+		return err
+	}
+	return c.File(dst)
+}
+
+func (v *imageVariants) allowed(width int) bool {
+	if len(v.conf.Widths) == 0 {
+		return true
+	}
+	for _, w := range v.conf.Widths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// 生成变体，先写入临时文件再改名，避免并发读取到不完整的文件
+func (v *imageVariants) transform(src, dst string, width int, format string, encoder *ImageEncoder) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return ErrNotFound
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "not a supported image")
+	}
+	if cfg.Width*cfg.Height > v.conf.MaxPixels {
+		return NewHTTPError(http.StatusRequestEntityTooLarge, "image too large")
+	}
+	if _, err = f.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "not a supported image")
+	}
+	if width > 0 && width < img.Bounds().Dx() {
+		img = resizeImage(img, width)
+	}
+	if format == "jpeg" {
+		// JPEG不支持透明，以白色为底
+		bg := image.NewRGBA(img.Bounds())
+		draw.Draw(bg, bg.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+		draw.Draw(bg, bg.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = bg
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", dst, time.Now().UnixNano())
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = encoder.Encode(out, img, v.conf.Quality)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// 缓存的变体是否存在且不早于原图
+func variantFresh(file string, modtime time.Time) bool {
+	fi, err := os.Stat(file)
+	return err == nil && !fi.ModTime().Before(modtime)
+}
+
+// 按区域平均等比缩小到指定宽度
+func resizeImage(src image.Image, width int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	height := sh * width / sw
+	if height < 1 {
+		height = 1
+	}
+	rgba, ok := src.(*image.RGBA)
+	if !ok || sb.Min != image.ZP {
+		rgba = image.NewRGBA(image.Rect(0, 0, sw, sh))
+		draw.Draw(rgba, rgba.Bounds(), src, sb.Min, draw.Src)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					p := rgba.Pix[i : i+4 : i+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+					i += 4
+				}
+			}
+			d := dst.PixOffset(x, y)
+			dst.Pix[d] = uint8(r / n)
+			dst.Pix[d+1] = uint8(g / n)
+			dst.Pix[d+2] = uint8(b / n)
+			dst.Pix[d+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,114 @@
+package lessgo
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageVariants(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imagevariant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root, cache := filepath.Join(dir, "root"), filepath.Join(dir, "cache")
+	os.MkdirAll(filepath.Join(root, "avatars"), 0755)
+
+	// 左半红色、右半蓝色
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.RGBA{255, 0, 0, 255}
+			if x >= 50 {
+				c = color.RGBA{0, 0, 255, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	f, _ := os.Create(filepath.Join(root, "avatars", "a.png"))
+	png.Encode(f, src)
+	f.Close()
+
+	r := NewRouter()
+	r.Handle(GET, "/media/*filepath", ImageVariantFunc(ImageVariantConfig{
+		Root:     root,
+		CacheDir: cache,
+		Widths:   []int{10, 200},
+		MaxAge:   3600,
+	}))
+	get := func(target string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(GET, target, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/media/avatars/a.png?w=10")
+	if rec.Code != http.StatusOK || rec.Header().Get(HeaderContentType) != "image/png" {
+		t.Fatalf("w=10: %d %q %q", rec.Code, rec.Header().Get(HeaderContentType), rec.Body.String())
+	}
+	if rec.Header().Get(HeaderCacheControl) != "public, max-age=3600" {
+		t.Errorf("Cache-Control = %q", rec.Header().Get(HeaderCacheControl))
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil || img.Bounds().Dx() != 10 || img.Bounds().Dy() != 5 {
+		t.Fatalf("variant = %v, %v", img.Bounds(), err)
+	}
+	if r, _, b, _ := img.At(0, 0).RGBA(); r>>8 != 255 || b != 0 {
+		t.Errorf("left pixel = %v", img.At(0, 0))
+	}
+	if r, _, b, _ := img.At(9, 4).RGBA(); r != 0 || b>>8 != 255 {
+		t.Errorf("right pixel = %v", img.At(9, 4))
+	}
+	cached := filepath.Join(cache, "avatars", "a.png", "10.png")
+	if _, err = os.Stat(cached); err != nil {
+		t.Errorf("variant not cached: %v", err)
+	}
+
+	// 缓存中的变体直接返回
+	ioutil.WriteFile(cached, []byte("cached"), 0644)
+	if rec = get("/media/avatars/a.png?w=10"); rec.Body.String() != "cached" {
+		t.Errorf("cached variant not served: %q", rec.Body.String())
+	}
+
+	// 不放大
+	rec = get("/media/avatars/a.png?w=200&fmt=jpg")
+	if img, err = jpeg.Decode(rec.Body); err != nil || img.Bounds().Dx() != 100 {
+		t.Errorf("jpeg variant: %d %v", rec.Code, err)
+	}
+
+	// 无编码器的格式只返回预先生成的变体
+	if rec = get("/media/avatars/a.png?fmt=webp"); rec.Code != http.StatusBadRequest {
+		t.Errorf("webp without encoder: %d", rec.Code)
+	}
+	os.MkdirAll(filepath.Join(cache, "avatars", "a.png"), 0755)
+	ioutil.WriteFile(filepath.Join(cache, "avatars", "a.png", "orig.webp"), []byte("webp"), 0644)
+	rec = get("/media/avatars/a.png?fmt=webp")
+	if rec.Code != http.StatusOK || rec.Header().Get(HeaderContentType) != "image/webp" || rec.Body.String() != "webp" {
+		t.Errorf("precompressed webp: %d %q %q", rec.Code, rec.Header().Get(HeaderContentType), rec.Body.String())
+	}
+
+	for _, target := range []string{
+		"/media/avatars/a.png?w=20",
+		"/media/avatars/a.png?w=x",
+		"/media/avatars/a.png?fmt=../x",
+	} {
+		if rec = get(target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", target, rec.Code)
+		}
+	}
+	if rec = get("/media/../root/avatars/missing.png?w=10"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing: %d", rec.Code)
+	}
+	if rec = get("/media/avatars/a.png"); rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("original: %d", rec.Code)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"mime"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/lessgo/lessgo/logs"
 	"github.com/lessgo/lessgo/session"
 )
 
@@ -14,22 +16,36 @@ func newLessgo() *Lessgo {
 	registerMime()
 
 	l := &Lessgo{
-		App:            app,
-		config:         Config,
-		home:           "/",
-		serverEnable:   true,
-		apiHandlers:    []*ApiHandler{},
-		apiMiddlewares: []*ApiMiddleware{},
-		virtBefore:     []*MiddlewareConfig{},
-		virtAfter:      []*MiddlewareConfig{},
-		virtStatics:    []*VirtStatic{},
-		virtFiles:      []*VirtFile{},
-		virtRouter:     newRootVirtRouter(),
+		App:          app,
+		config:       Config,
+		home:         "/",
+		serverEnable: true,
+		virtBefore:   []*MiddlewareConfig{},
+		virtAfter:    []*MiddlewareConfig{},
+		virtStatics:  []*VirtStatic{},
+		virtFiles:    []*VirtFile{},
+		virtRouter:   newRootVirtRouter(),
 	}
 
 	// 初始化全局日志
 	Log.SetMsgChan(Config.Log.AsyncChan)
 	Log.SetLevel(Config.Log.Level)
+	if Config.Log.AsyncDrop {
+		Log.SetAsyncPolicy(logs.ASYNC_DROP_OLDEST)
+	}
+	if Config.Log.SampleFirst > 0 {
+		setLogSampling(Config)
+	}
+	if Config.Log.Syslog != "" {
+		if err := Log.AddAdapter("syslog", syslogAdapterConfig(Config.Log.Syslog)); err != nil {
+			Log.Error("Failed to enable syslog output: %v.", err)
+		}
+	}
+	if Config.Log.Journald {
+		if err := Log.AddAdapter("journald", ""); err != nil {
+			Log.Error("Failed to enable journald output: %v.", err)
+		}
+	}
 
 	// 设置运行模式
 	l.App.SetDebug(Config.Debug)
@@ -42,11 +58,28 @@ func newLessgo() *Lessgo {
 	)
 
 	// 设置渲染接口
-	l.App.SetRenderer(NewPongo2Render(!Config.Debug))
+	pongo2Render := NewPongo2Render(!Config.Debug)
+	l.App.SetRenderer(pongo2Render)
+	RegisterRenderer("pongo2", pongo2Render)
+	RegisterRenderer("html", NewHTMLRender(HTMLRenderConfig{Dir: "."}, !Config.Debug))
+
+	// 设置维护模式
+	if Config.Maintenance {
+		EnableMaintenance()
+	}
 
 	// 设置上传文件允许的最大尺寸
 	MaxMemory = Config.MaxMemoryMB * MB
 
+	// 设置请求URI与请求头的限制
+	setRequestLimits(Config)
+
+	// 设置应用密钥环，配置了密钥时同时用于加密Cookie会话
+	setSecureKeys(Config)
+	if Config.SecureKeys != "" {
+		session.SetCookieKeyRing(secureKeys)
+	}
+
 	// 初始化sessions管理实例
 	sessions, err := newSessions()
 	if err != nil {
@@ -110,10 +143,14 @@ func tryRegisterDefaultHandler() {
 func registerBefore() {
 	PreUse(
 		&MiddlewareConfig{Name: "检查服务器是否启用"},
+		&MiddlewareConfig{Name: "维护模式"},
 		&MiddlewareConfig{Name: "检查是否为访问主页"},
 		&MiddlewareConfig{Name: "系统运行日志打印"},
 		&MiddlewareConfig{Name: "捕获运行时恐慌"},
 	)
+	if liveReloadEnabled() {
+		PreUse(&MiddlewareConfig{Name: "开发模式自动刷新"})
+	}
 	if Config.CrossDomain {
 		BeforeUse(&MiddlewareConfig{Name: "设置允许跨域"})
 	}
@@ -136,3 +173,16 @@ func registerStatics() {
 func registerFiles() {
 	File("/favicon.ico", IMG_DIR+"/favicon.ico")
 }
+
+// 将"local"或"udp://host:port"形式的syslog地址转换为适配器配置
+func syslogAdapterConfig(addr string) string {
+	network := "udp"
+	if addr == "local" {
+		network, addr = "", ""
+	}
+	if i := strings.Index(addr, "://"); i > 0 {
+		network, addr = addr[:i], addr[i+3:]
+	}
+	b, _ := json.Marshal(map[string]string{"net": network, "addr": addr, "tag": Config.AppName})
+	return string(b)
+}
@@ -0,0 +1,315 @@
+package lessgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+type (
+	// JSON-RPC 2.0 服务，通过ApiHandler挂载到任意路径
+	JSONRPCServer struct {
+		// 请求体大小上限
+		MaxBodyBytes int64
+		// 单个批量请求包含的最大调用数，为0时不限制
+		MaxBatch int
+
+		methods map[string]*rpcMethod
+		lock    sync.RWMutex
+	}
+
+	// JSON-RPC错误对象，方法返回该类型错误时原样响应给客户端
+	JSONRPCError struct {
+		Code    int         `json:"code"`
+		Message string      `json:"message"`
+		Data    interface{} `json:"data,omitempty"`
+	}
+
+	rpcMethod struct {
+		fn      reflect.Value
+		withCtx bool         // 首个参数为*Context
+		argType reflect.Type // 为nil时表示无参数
+		retVal  bool         // 是否有结果返回值
+	}
+
+	jsonrpcRequest struct {
+		Jsonrpc string           `json:"jsonrpc"`
+		Method  string           `json:"method"`
+		Params  json.RawMessage  `json:"params"`
+		ID      *json.RawMessage `json:"id"`
+	}
+
+	jsonrpcResponse struct {
+		Jsonrpc string           `json:"jsonrpc"`
+		Result  *json.RawMessage `json:"result,omitempty"`
+		Error   *JSONRPCError    `json:"error,omitempty"`
+		ID      *json.RawMessage `json:"id"`
+	}
+)
+
+// JSON-RPC 2.0 预定义错误码
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	JSONRPCServerError    = -32000
+)
+
+var (
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfContext = reflect.TypeOf((*Context)(nil))
+	jsonNull      = json.RawMessage("null")
+)
+
+// 创建JSON-RPC 2.0服务
+func NewJSONRPCServer() *JSONRPCServer {
+	return &JSONRPCServer{
+		MaxBodyBytes: 4 * MB,
+		methods:      map[string]*rpcMethod{},
+	}
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc: %d %s", e.Code, e.Message)
+}
+
+// 以"类型名.方法名"注册rcvr的全部可导出方法，不符合签名要求的方法被忽略
+func (s *JSONRPCServer) Register(rcvr interface{}) error {
+	return s.RegisterName(reflect.Indirect(reflect.ValueOf(rcvr)).Type().Name(), rcvr)
+}
+
+// 以"name.方法名"注册rcvr的全部可导出方法
+func (s *JSONRPCServer) RegisterName(name string, rcvr interface{}) error {
+	v := reflect.ValueOf(rcvr)
+	t := v.Type()
+	var n int
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.PkgPath != "" {
+			continue
+		}
+		if s.RegisterFunc(name+"."+m.Name, v.Method(i).Interface()) == nil {
+			n++
+		}
+	}
+	if n == 0 {
+		return fmt.Errorf("jsonrpc: type %s has no suitable methods", t)
+	}
+	return nil
+}
+
+// 注册单个方法，fn的签名须为 func([*Context,] [args T]) ([R,] error)
+func (s *JSONRPCServer) RegisterFunc(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("jsonrpc: %s is not a func", name)
+	}
+	m := &rpcMethod{fn: v}
+	in := 0
+	if t.NumIn() > in && t.In(in) == typeOfContext {
+		m.withCtx = true
+		in++
+	}
+	if t.NumIn() > in {
+		m.argType = t.In(in)
+		in++
+	}
+	if t.NumIn() != in {
+		return fmt.Errorf("jsonrpc: %s has too many arguments", name)
+	}
+	switch t.NumOut() {
+	case 1:
+	case 2:
+		m.retVal = true
+	default:
+		return fmt.Errorf("jsonrpc: %s must return (result, error) or error", name)
+	}
+	if t.Out(t.NumOut()-1) != typeOfError {
+		return fmt.Errorf("jsonrpc: %s must return error as the last value", name)
+	}
+	s.lock.Lock()
+	s.methods[name] = m
+	s.lock.Unlock()
+	return nil
+}
+
+// 返回已注册的方法名
+func (s *JSONRPCServer) Methods() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	return names
+}
+
+// 返回挂载本服务的操作，如 Leaf("/rpc", rpcServer.ApiHandler("RPC接口"))
+func (s *JSONRPCServer) ApiHandler(desc string) *ApiHandler {
+	return ApiHandler{
+		Desc:    desc,
+		Method:  POST,
+		Handler: s.Handle,
+	}.Reg()
+}
+
+// 处理单个或批量JSON-RPC请求
+func (s *JSONRPCServer) Handle(c *Context) error {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.response, c.request.Body, s.MaxBodyBytes))
+	if err != nil {
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	body = bytes.TrimSpace(body)
+
+	// 批量请求
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return c.JSON(http.StatusOK, rpcErrorResponse(nil, JSONRPCParseError, err.Error()))
+		}
+		if len(batch) == 0 {
+			return c.JSON(http.StatusOK, rpcErrorResponse(nil, JSONRPCInvalidRequest, "empty batch"))
+		}
+		if s.MaxBatch > 0 && len(batch) > s.MaxBatch {
+			return c.JSON(http.StatusOK, rpcErrorResponse(nil, JSONRPCInvalidRequest, "batch too large"))
+		}
+		resps := make([]*jsonrpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := s.call(c, raw); resp != nil {
+				resps = append(resps, resp)
+			}
+		}
+		if len(resps) == 0 {
+			return c.NoContent(http.StatusNoContent)
+		}
+		return c.JSON(http.StatusOK, resps)
+	}
+
+	resp := s.call(c, body)
+	if resp == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// 执行一次调用，通知(无id)时返回nil
+func (s *JSONRPCServer) call(c *Context, raw []byte) *jsonrpcResponse {
+	var req jsonrpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return rpcErrorResponse(nil, JSONRPCParseError, err.Error())
+		}
+		return rpcErrorResponse(nil, JSONRPCInvalidRequest, err.Error())
+	}
+	if req.Jsonrpc != "2.0" || req.Method == "" {
+		return rpcErrorResponse(req.ID, JSONRPCInvalidRequest, "invalid request")
+	}
+
+	result, rpcErr := s.invoke(c, &req)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return &jsonrpcResponse{Jsonrpc: "2.0", Error: rpcErr, ID: req.ID}
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return rpcErrorResponse(req.ID, JSONRPCInternalError, err.Error())
+	}
+	res := json.RawMessage(b)
+	return &jsonrpcResponse{Jsonrpc: "2.0", Result: &res, ID: req.ID}
+}
+
+func (s *JSONRPCServer) invoke(c *Context, req *jsonrpcRequest) (result interface{}, rpcErr *JSONRPCError) {
+	s.lock.RLock()
+	m := s.methods[req.Method]
+	s.lock.RUnlock()
+	if m == nil {
+		return nil, &JSONRPCError{Code: JSONRPCMethodNotFound, Message: "method not found"}
+	}
+
+	var args []reflect.Value
+	if m.withCtx {
+		args = append(args, reflect.ValueOf(c))
+	}
+	if m.argType != nil {
+		arg, err := decodeRPCParams(req.Params, m.argType)
+		if err != nil {
+			return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: err.Error()}
+		}
+		args = append(args, arg)
+	}
+
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			Log.Error("JSON-RPC method %s panic: %v", req.Method, rcv)
+			rpcErr = &JSONRPCError{Code: JSONRPCInternalError, Message: "internal error"}
+		}
+	}()
+	out := m.fn.Call(args)
+	if errv := out[len(out)-1]; !errv.IsNil() {
+		return nil, toJSONRPCError(errv.Interface().(error))
+	}
+	if m.retVal {
+		return out[0].Interface(), nil
+	}
+	return nil, nil
+}
+
+// 解码参数，参数为数组且目标类型不是切片时取第一个元素
+func decodeRPCParams(params json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	ptr := reflect.New(t)
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, jsonNull) {
+		return ptr.Elem(), nil
+	}
+	if params[0] == '[' && t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		var list []json.RawMessage
+		if err := json.Unmarshal(params, &list); err != nil {
+			return ptr.Elem(), err
+		}
+		if len(list) != 1 {
+			return ptr.Elem(), fmt.Errorf("expected 1 positional param, got %d", len(list))
+		}
+		params = list[0]
+	}
+	err := json.Unmarshal(params, ptr.Interface())
+	return ptr.Elem(), err
+}
+
+// 将方法返回的错误转换为JSON-RPC错误
+func toJSONRPCError(err error) *JSONRPCError {
+	switch e := err.(type) {
+	case *JSONRPCError:
+		return e
+	case *HTTPError:
+		code := JSONRPCServerError
+		switch e.Code {
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			code = JSONRPCInvalidParams
+		case http.StatusInternalServerError:
+			code = JSONRPCInternalError
+		}
+		return &JSONRPCError{Code: code, Message: e.Message, Data: map[string]int{"status": e.Code}}
+	}
+	return &JSONRPCError{Code: JSONRPCServerError, Message: err.Error()}
+}
+
+func rpcErrorResponse(id *json.RawMessage, code int, msg string) *jsonrpcResponse {
+	if id == nil {
+		null := jsonNull
+		id = &null
+	}
+	return &jsonrpcResponse{
+		Jsonrpc: "2.0",
+		Error:   &JSONRPCError{Code: code, Message: msg},
+		ID:      id,
+	}
+}
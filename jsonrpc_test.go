@@ -0,0 +1,70 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type rpcArith struct{}
+
+type rpcArgs struct {
+	A, B int
+}
+
+func (rpcArith) Add(args rpcArgs) (int, error) {
+	return args.A + args.B, nil
+}
+
+func (rpcArith) Div(args rpcArgs) (int, error) {
+	if args.B == 0 {
+		return 0, errors.New("divide by zero")
+	}
+	return args.A / args.B, nil
+}
+
+func (rpcArith) Reset() error {
+	return nil
+}
+
+func TestJSONRPCCall(t *testing.T) {
+	s := NewJSONRPCServer()
+	if err := s.Register(rpcArith{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterFunc("bad", func(a, b int) error { return nil }); err == nil {
+		t.Fatal("func with two params should be rejected")
+	}
+
+	tests := []struct {
+		req  string
+		want string
+	}{
+		{`{"jsonrpc":"2.0","method":"rpcArith.Add","params":{"A":1,"B":2},"id":1}`,
+			`{"jsonrpc":"2.0","result":3,"id":1}`},
+		{`{"jsonrpc":"2.0","method":"rpcArith.Add","params":[{"A":2,"B":2}],"id":"x"}`,
+			`{"jsonrpc":"2.0","result":4,"id":"x"}`},
+		{`{"jsonrpc":"2.0","method":"rpcArith.Reset","id":2}`,
+			`{"jsonrpc":"2.0","result":null,"id":2}`},
+		{`{"jsonrpc":"2.0","method":"rpcArith.Div","params":{"A":1,"B":0},"id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"divide by zero"},"id":3}`},
+		{`{"jsonrpc":"2.0","method":"rpcArith.Mul","id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":4}`},
+		{`{"jsonrpc":"2.0","method":"rpcArith.Add","params":[1,2],"id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"expected 1 positional param, got 2"},"id":5}`},
+		{`{"method":"rpcArith.Add","id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":6}`},
+		{`{"jsonrpc":"2.0","method":"rpcArith.Add","params":{"A":1,"B":2}}`, ``},
+	}
+	for _, tt := range tests {
+		resp := s.call(nil, []byte(tt.req))
+		var got string
+		if resp != nil {
+			b, _ := json.Marshal(resp)
+			got = string(b)
+		}
+		if got != tt.want {
+			t.Errorf("call(%s)\n got %s\nwant %s", tt.req, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+// JSON流式输出：编码器与其写入器一同池化复用，编码结果直接写入响应，不再经json.Marshal复制出[]byte；
+// 编码失败时不写入任何内容，调用方仍可输出错误响应。
+type jsonStream struct {
+	c        *Context
+	code     int
+	ctype    string
+	callback string // 非空时输出JSONP
+	enc      *json.Encoder
+}
+
+var jsonStreamPool = sync.Pool{
+	New: func() interface{} {
+		s := new(jsonStream)
+		s.enc = json.NewEncoder(s)
+		return s
+	},
+}
+
+// Encode一次写入完整的编码结果，此时才写响应头
+func (s *jsonStream) Write(b []byte) (int, error) {
+	n := len(b)
+	if n > 0 && b[n-1] == '\n' {
+		b = b[:n-1] // 去掉Encode追加的换行，与json.Marshal的输出一致
+	}
+	resp := s.c.response
+	resp.Header().Set(HeaderContentType, s.ctype)
+	s.c.WriteHeader(s.code)
+	if s.callback != "" {
+		if _, err := resp.Write(utils.String2Bytes(s.callback + "(")); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := resp.Write(b); err != nil {
+		return 0, err
+	}
+	if s.callback != "" {
+		if _, err := resp.Write(utils.String2Bytes(");")); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// 以JSON(callback非空时为JSONP)格式输出i，调试模式下缩进
+func (c *Context) writeJSON(code int, ctype, callback string, i interface{}) error {
+	s := jsonStreamPool.Get().(*jsonStream)
+	s.c, s.code, s.ctype, s.callback = c, code, ctype, callback
+	var err error
+	if Debug() {
+		var b []byte
+		if b, err = json.MarshalIndent(i, "", "  "); err == nil {
+			_, err = s.Write(b)
+		}
+	} else {
+		err = s.enc.Encode(i)
+	}
+	s.c = nil
+	// json.Encoder会记住写入错误，出错的编码器不再复用
+	if err == nil {
+		jsonStreamPool.Put(s)
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type jsonTestPayload struct {
+	ID    int               `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+}
+
+var jsonTestValue = jsonTestPayload{1, "<gopher>", []string{"a", "b"}, map[string]string{"k": "v"}}
+
+func TestJSONStream(t *testing.T) {
+	defer app.SetDebug(Debug())
+	app.SetDebug(false)
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	c, rec := testContext(req)
+	if err := c.JSON(http.StatusCreated, jsonTestValue); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(jsonTestValue)
+	if rec.Code != http.StatusCreated || rec.Body.String() != string(want) ||
+		rec.Header().Get(HeaderContentType) != MIMEApplicationJSONCharsetUTF8 {
+		t.Errorf("JSON = %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	c, rec = testContext(req)
+	c.JSONP(http.StatusOK, "cb", []int{1})
+	if rec.Body.String() != "cb([1]);" || rec.Header().Get(HeaderContentType) != MIMEApplicationJavaScriptCharsetUTF8 {
+		t.Errorf("JSONP = %q %v", rec.Body.String(), rec.Header())
+	}
+
+	// 编码失败时不写入响应
+	c, rec = testContext(req)
+	if err := c.JSON(http.StatusOK, map[string]interface{}{"ch": make(chan int)}); err == nil {
+		t.Error("expected an encoding error")
+	}
+	if c.response.Committed() || rec.Body.Len() != 0 {
+		t.Errorf("response written on error: %q", rec.Body.String())
+	}
+
+	app.SetDebug(true)
+	c, rec = testContext(req)
+	c.JSONMsg(http.StatusOK, 3, "x")
+	if want, _ := json.MarshalIndent(CommJSON{Code: 3, Info: "x"}, "", "  "); rec.Body.String() != string(want) {
+		t.Errorf("debug JSONMsg = %q", rec.Body.String())
+	}
+}
+
+// 约40KB的响应
+var jsonBenchValue = func() []jsonTestPayload {
+	v := make([]jsonTestPayload, 500)
+	for i := range v {
+		v[i] = jsonTestValue
+	}
+	return v
+}()
+
+func BenchmarkJSON(b *testing.B) {
+	defer app.SetDebug(Debug())
+	app.SetDebug(false)
+	req, _ := http.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	c := app.newContext(new(Response), req)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rec.Body.Reset()
+		c.init(rec, req)
+		c.JSON(http.StatusOK, jsonBenchValue)
+		c.free()
+	}
+}
+
+// 流式输出之前的实现：先Marshal出[]byte再写入
+func BenchmarkJSONMarshalThenWrite(b *testing.B) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	c := app.newContext(new(Response), req)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rec.Body.Reset()
+		c.init(rec, req)
+		data, _ := json.Marshal(jsonBenchValue)
+		c.JSONBlob(http.StatusOK, data)
+		c.free()
+	}
+}
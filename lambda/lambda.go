@@ -0,0 +1,298 @@
+// Package lambda runs a lessgo app on AWS Lambda, behind API Gateway (REST
+// and HTTP APIs, payload versions 1.0 and 2.0) or an Application Load
+// Balancer. Events are converted to *http.Request and served by the app's
+// http.Handler, no listener is opened:
+//
+//	func main() {
+//		lessgo.Root(...)
+//		log.Fatal(lambda.Start(nil)) // nil for lessgo.HTTPHandler()
+//	}
+//
+// Start implements the Lambda runtime API itself, so the binary is deployed
+// as a custom runtime ("provided.al2" with a "bootstrap" executable) and no
+// AWS SDK is needed. Handle converts a single event for other runtimes.
+package lambda
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lessgo/lessgo"
+)
+
+// Request is an API Gateway or ALB event, the fields of all supported
+// payload formats are merged.
+type Request struct {
+	Version string `json:"version"` // "2.0" for HTTP API payload 2.0, empty or "1.0" otherwise
+
+	// Payload 1.0 and ALB.
+	HTTPMethod                      string              `json:"httpMethod"`
+	Path                            string              `json:"path"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+
+	// Payload 2.0.
+	RawPath        string   `json:"rawPath"`
+	RawQueryString string   `json:"rawQueryString"`
+	Cookies        []string `json:"cookies"`
+
+	RequestContext RequestContext `json:"requestContext"`
+
+	Body            string `json:"body"`
+	IsBase64Encoded bool   `json:"isBase64Encoded"`
+}
+
+// RequestContext of an event.
+type RequestContext struct {
+	RequestID string `json:"requestId"`
+	Stage     string `json:"stage"`
+	Identity  struct {
+		SourceIP string `json:"sourceIp"`
+	} `json:"identity"` // payload 1.0
+	HTTP struct {
+		Method   string `json:"method"`
+		Path     string `json:"path"`
+		SourceIP string `json:"sourceIp"`
+	} `json:"http"` // payload 2.0
+	ELB *struct {
+		TargetGroupArn string `json:"targetGroupArn"`
+	} `json:"elb,omitempty"` // ALB
+}
+
+// Response to an event.
+type Response struct {
+	StatusCode        int                 `json:"statusCode"`
+	StatusDescription string              `json:"statusDescription,omitempty"` // ALB
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	Cookies           []string            `json:"cookies,omitempty"` // payload 2.0
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// NewHTTPRequest converts an event to a request.
+func NewHTTPRequest(e *Request) (*http.Request, error) {
+	method, path, rawQuery := e.HTTPMethod, e.Path, ""
+	if e.Version == "2.0" {
+		method, path, rawQuery = e.RequestContext.HTTP.Method, e.RawPath, e.RawQueryString
+	} else if len(e.MultiValueQueryStringParameters) > 0 {
+		q := url.Values{}
+		for k, vs := range e.MultiValueQueryStringParameters {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+		rawQuery = q.Encode()
+	} else if len(e.QueryStringParameters) > 0 {
+		q := url.Values{}
+		for k, v := range e.QueryStringParameters {
+			q.Set(k, v)
+		}
+		rawQuery = q.Encode()
+	}
+	if method == "" {
+		return nil, errors.New("lambda: event without HTTP method")
+	}
+	if path == "" {
+		path = "/"
+	}
+	body := []byte(e.Body)
+	if e.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(e.Body); err != nil {
+			return nil, fmt.Errorf("lambda: decoding body: %v", err)
+		}
+	}
+	target := path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range e.MultiValueHeaders {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if len(e.MultiValueHeaders) == 0 {
+		for k, v := range e.Headers {
+			req.Header.Set(k, v)
+		}
+	}
+	for _, cookie := range e.Cookies {
+		req.Header.Add("Cookie", cookie)
+	}
+	req.Host = req.Header.Get("Host")
+	sourceIP := e.RequestContext.Identity.SourceIP
+	if sourceIP == "" {
+		sourceIP = e.RequestContext.HTTP.SourceIP
+	}
+	if sourceIP != "" {
+		req.RemoteAddr = sourceIP + ":0"
+	}
+	if e.RequestContext.RequestID != "" && req.Header.Get(lessgo.HeaderXRequestID) == "" {
+		req.Header.Set(lessgo.HeaderXRequestID, e.RequestContext.RequestID)
+	}
+	return req, nil
+}
+
+// Serve serves an event with h.
+func Serve(h http.Handler, e *Request) (*Response, error) {
+	req, err := NewHTTPRequest(e)
+	if err != nil {
+		return nil, err
+	}
+	w := &responseWriter{header: http.Header{}}
+	h.ServeHTTP(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	resp := &Response{StatusCode: w.status}
+	if e.RequestContext.ELB != nil {
+		resp.StatusDescription = fmt.Sprintf("%d %s", w.status, http.StatusText(w.status))
+	}
+	body := w.body.Bytes()
+	if isText(w.header.Get("Content-Type"), body) {
+		resp.Body = string(body)
+	} else {
+		resp.Body = base64.StdEncoding.EncodeToString(body)
+		resp.IsBase64Encoded = true
+	}
+	switch {
+	case e.Version == "2.0":
+		resp.Headers = map[string]string{}
+		for k, vs := range w.header {
+			if k == "Set-Cookie" {
+				resp.Cookies = vs
+				continue
+			}
+			resp.Headers[k] = strings.Join(vs, ",")
+		}
+	case len(e.MultiValueHeaders) > 0:
+		// The multi-value format is answered in kind, required by ALBs
+		// with multi-value headers enabled.
+		resp.MultiValueHeaders = map[string][]string(w.header)
+	default:
+		resp.Headers = map[string]string{}
+		for k, vs := range w.header {
+			resp.Headers[k] = vs[0]
+		}
+	}
+	return resp, nil
+}
+
+// Handle serves a JSON encoded event with h, nil for lessgo.HTTPHandler(),
+// and returns the JSON encoded response.
+func Handle(h http.Handler, payload []byte) ([]byte, error) {
+	if h == nil {
+		h = lessgo.HTTPHandler()
+	}
+	var e Request
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("lambda: decoding event: %v", err)
+	}
+	resp, err := Serve(h, &e)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
+const runtimeAPIVersion = "/2018-06-01/runtime"
+
+// Start serves the events of the function with h, nil for
+// lessgo.HTTPHandler(), through the Lambda runtime API. It only returns if
+// the runtime API fails.
+func Start(h http.Handler) error {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		return errors.New("lambda: AWS_LAMBDA_RUNTIME_API is not set, not running on Lambda")
+	}
+	if h == nil {
+		h = lessgo.HTTPHandler()
+	}
+	base := "http://" + api + runtimeAPIVersion
+	for {
+		resp, err := http.Get(base + "/invocation/next")
+		if err != nil {
+			return err
+		}
+		payload, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		id := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+		out, err := Handle(h, payload)
+		if err != nil {
+			out, _ = json.Marshal(map[string]string{"errorMessage": err.Error(), "errorType": "InvalidEvent"})
+			resp, err = http.Post(base+"/invocation/"+id+"/error", "application/json", bytes.NewReader(out))
+		} else {
+			resp, err = http.Post(base+"/invocation/"+id+"/response", "application/json", bytes.NewReader(out))
+		}
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+}
+
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+// isText reports whether a body can be returned as is rather than base64
+// encoded.
+func isText(contentType string, body []byte) bool {
+	if len(body) == 0 {
+		return true
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "xml"),
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded":
+		return utf8.Valid(body)
+	}
+	return false
+}
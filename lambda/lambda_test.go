@@ -0,0 +1,173 @@
+package lambda
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lessgo/lessgo"
+)
+
+func echoHandler() http.Handler {
+	r := lessgo.NewRouter()
+	r.Handle("POST", "/users/:id", func(c *lessgo.Context) error {
+		body, _ := ioutil.ReadAll(c.Request().Body)
+		c.SetCookie(&http.Cookie{Name: "a", Value: "1"})
+		c.AddCookie(&http.Cookie{Name: "b", Value: "2"})
+		return c.JSON(http.StatusCreated, map[string]string{
+			"id":     c.PathParam("id"),
+			"q":      c.QueryParam("q"),
+			"header": c.HeaderParam("X-Test"),
+			"cookie": c.HeaderParam("Cookie"),
+			"body":   string(body),
+			"remote": c.Request().RemoteAddr,
+			"reqid":  c.HeaderParam(lessgo.HeaderXRequestID),
+		})
+	})
+	r.Handle("GET", "/png", func(c *lessgo.Context) error {
+		c.Response().Header().Set(lessgo.HeaderContentType, "image/png")
+		c.Response().WriteHeader(http.StatusOK)
+		_, err := c.Write([]byte{0x89, 'P', 'N', 'G', 0xff})
+		return err
+	})
+	return r
+}
+
+func decode(t *testing.T, resp *Response) map[string]string {
+	var m map[string]string
+	if err := json.Unmarshal([]byte(resp.Body), &m); err != nil {
+		t.Fatalf("body %q: %v", resp.Body, err)
+	}
+	return m
+}
+
+func TestServeRESTEvent(t *testing.T) {
+	e := &Request{
+		HTTPMethod:            "POST",
+		Path:                  "/users/42",
+		Headers:               map[string]string{"X-Test": "yes", "Content-Type": "text/plain"},
+		QueryStringParameters: map[string]string{"q": "a b"},
+		Body:                  base64.StdEncoding.EncodeToString([]byte("hello")),
+		IsBase64Encoded:       true,
+	}
+	e.RequestContext.RequestID = "req-1"
+	e.RequestContext.Identity.SourceIP = "192.0.2.1"
+	resp, err := Serve(echoHandler(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.IsBase64Encoded || resp.MultiValueHeaders != nil {
+		t.Fatalf("response = %+v", resp)
+	}
+	m := decode(t, resp)
+	want := map[string]string{"id": "42", "q": "a b", "header": "yes", "body": "hello", "remote": "192.0.2.1:0", "reqid": "req-1"}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s = %q, want %q", k, m[k], v)
+		}
+	}
+	if !strings.HasPrefix(resp.Headers["Content-Type"], lessgo.MIMEApplicationJSON) {
+		t.Errorf("Content-Type = %q", resp.Headers["Content-Type"])
+	}
+}
+
+func TestServeHTTPAPIEvent(t *testing.T) {
+	e := &Request{
+		Version:        "2.0",
+		RawPath:        "/users/7",
+		RawQueryString: "q=x",
+		Cookies:        []string{"s=1", "t=2"},
+		Body:           "{}",
+	}
+	e.RequestContext.HTTP.Method = "POST"
+	e.RequestContext.HTTP.SourceIP = "192.0.2.2"
+	resp, err := Serve(echoHandler(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := decode(t, resp)
+	if m["id"] != "7" || m["q"] != "x" || m["cookie"] != "s=1" || m["remote"] != "192.0.2.2:0" {
+		t.Errorf("body = %v", m)
+	}
+	if len(resp.Cookies) != 2 || resp.Headers["Set-Cookie"] != "" {
+		t.Errorf("cookies = %q, headers = %v", resp.Cookies, resp.Headers)
+	}
+}
+
+func TestServeALBMultiValueEvent(t *testing.T) {
+	e := &Request{
+		HTTPMethod:                      "POST",
+		Path:                            "/users/1",
+		MultiValueHeaders:               map[string][]string{"X-Test": {"v"}},
+		MultiValueQueryStringParameters: map[string][]string{"q": {"1", "2"}},
+	}
+	e.RequestContext.ELB = &struct {
+		TargetGroupArn string `json:"targetGroupArn"`
+	}{"arn"}
+	resp, err := Serve(echoHandler(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusDescription != "201 Created" || resp.Headers != nil {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(resp.MultiValueHeaders["Set-Cookie"]) != 2 {
+		t.Errorf("Set-Cookie = %q", resp.MultiValueHeaders["Set-Cookie"])
+	}
+	if m := decode(t, resp); m["header"] != "v" || m["q"] != "1" {
+		t.Errorf("body = %v", m)
+	}
+}
+
+func TestServeBinary(t *testing.T) {
+	resp, err := Serve(echoHandler(), &Request{HTTPMethod: "GET", Path: "/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := base64.StdEncoding.DecodeString(resp.Body)
+	if !resp.IsBase64Encoded || string(b) != "\x89PNG\xff" {
+		t.Errorf("response = %+v", resp)
+	}
+	if _, err := Serve(echoHandler(), &Request{Path: "/png"}); err == nil {
+		t.Error("event without method served")
+	}
+}
+
+func TestStart(t *testing.T) {
+	var result []byte
+	served := false
+	runtime := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == runtimeAPIVersion+"/invocation/next" && !served:
+			served = true
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", "inv-1")
+			w.Write([]byte(`{"httpMethod":"POST","path":"/users/3","body":"x"}`))
+		case r.URL.Path == runtimeAPIVersion+"/invocation/inv-1/response":
+			result, _ = ioutil.ReadAll(r.Body)
+		default:
+			// Make Start return after the first invocation.
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+		}
+	}))
+	defer runtime.Close()
+
+	os.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(runtime.URL, "http://"))
+	defer os.Unsetenv("AWS_LAMBDA_RUNTIME_API")
+	if err := Start(echoHandler()); err == nil {
+		t.Fatal("Start returned no error")
+	}
+	var resp Response
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("result %q: %v", result, err)
+	}
+	if m := decode(t, &resp); resp.StatusCode != http.StatusCreated || m["id"] != "3" || m["body"] != "x" {
+		t.Errorf("response = %+v", resp)
+	}
+}
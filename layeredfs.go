@@ -0,0 +1,194 @@
+package lessgo
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// 叠加的虚拟文件系统，靠前的层覆盖靠后的层，如：
+//
+//	//go:embed static
+//	var embedded embed.FS
+//
+//	static, _ := fs.Sub(embedded, "static")
+//	lessgo.StaticFS("/static", lessgo.NewLayeredFS(os.DirFS("static"), static))
+//
+// 运维人员只需在磁盘目录放入同名文件即可替换内置资源，无需重新编译
+type LayeredFS []fs.FS
+
+// 创建叠加的虚拟文件系统，忽略nil层
+func NewLayeredFS(layers ...fs.FS) LayeredFS {
+	l := make(LayeredFS, 0, len(layers))
+	for _, layer := range layers {
+		if layer != nil {
+			l = append(l, layer)
+		}
+	}
+	return l
+}
+
+// 打开首个存在该文件的层中的文件；目录的ReadDir结果为各层的合集
+func (l LayeredFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range l {
+		f, err := layer.Open(name)
+		if err == nil {
+			if fi, err := f.Stat(); err == nil && fi.IsDir() {
+				return &layeredDir{File: f, fsys: l, name: name}, nil
+			}
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// 合并各层的目录项，同名时取靠前的层
+func (l LayeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		entries []fs.DirEntry
+		seen    = map[string]bool{}
+		found   bool
+	)
+	for _, layer := range l {
+		list, err := fs.ReadDir(layer, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, e := range list {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Sort(dirEntries(entries))
+	return entries, nil
+}
+
+type dirEntries []fs.DirEntry
+
+func (d dirEntries) Len() int           { return len(d) }
+func (d dirEntries) Less(i, j int) bool { return d[i].Name() < d[j].Name() }
+func (d dirEntries) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// 叠加文件系统中的目录
+type layeredDir struct {
+	fs.File
+	fsys    LayeredFS
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *layeredDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// 单独注册虚拟文件系统的静态目录虚拟路由VirtStatic(无法在Root()下使用)
+func StaticFS(prefix string, fsys fs.FS, middlewares ...interface{}) error {
+	ms, err := WrapMiddlewareConfigs(middlewares)
+	if err != nil {
+		return err
+	}
+	for _, v := range lessgo.virtStatics {
+		if v.Prefix == prefix {
+			v.Root = ""
+			v.FS = fsys
+			v.Middlewares = ms
+			return nil
+		}
+	}
+	lessgo.virtStatics = append(lessgo.virtStatics, &VirtStatic{
+		Prefix:      prefix,
+		FS:          fsys,
+		Middlewares: ms,
+	})
+	return nil
+}
+
+// 创建虚拟文件系统静态目录服务的操作(用于在Root()下)
+func StaticFSFunc(fsys fs.FS) HandlerFunc {
+	return func(c *Context) error {
+		return c.FileFS(fsys, c.PathParamByIndex(0))
+	}
+}
+
+// 从虚拟文件系统发送文件，目录时发送其中的index.html
+func (c *Context) FileFS(fsys fs.FS, name string) error {
+	name = fsPath(name)
+	f, err := fsys.Open(name)
+	if err != nil {
+		return ErrNotFound
+	}
+	defer func() { f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return ErrNotFound
+	}
+	if fi.IsDir() {
+		index, err := fsys.Open(path.Join(name, indexPage))
+		if err != nil {
+			return ErrNotFound
+		}
+		f.Close()
+		f = index
+		if fi, err = f.Stat(); err != nil || fi.IsDir() {
+			return ErrNotFound
+		}
+	}
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return c.ServeContent(rs, fi.Name(), fi.ModTime())
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return c.ServeContent2(b, fi.Name(), fi.ModTime())
+}
+
+// 将URL路径转换为fs.FS使用的相对路径
+func fsPath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
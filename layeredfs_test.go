@@ -0,0 +1,113 @@
+package lessgo
+
+import (
+	"bytes"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLayeredFS(t *testing.T) {
+	embedded := fstest.MapFS{
+		"app.css":         {Data: []byte("embedded css")},
+		"app.js":          {Data: []byte("embedded js")},
+		"img/logo.png":    {Data: []byte("png")},
+		"docs/index.html": {Data: []byte("docs")},
+	}
+	disk := fstest.MapFS{
+		"app.css":     {Data: []byte("patched css")},
+		"img/new.png": {Data: []byte("new")},
+	}
+	l := NewLayeredFS(disk, nil, embedded)
+	if len(l) != 2 {
+		t.Fatalf("len = %d, want nil layer dropped", len(l))
+	}
+	for name, want := range map[string]string{"app.css": "patched css", "app.js": "embedded js", "img/new.png": "new"} {
+		if b, err := fs.ReadFile(l, name); err != nil || string(b) != want {
+			t.Errorf("ReadFile(%s) = %q, %v, want %q", name, b, err, want)
+		}
+	}
+	if _, err := l.Open("missing"); !os.IsNotExist(err) {
+		t.Errorf("Open(missing) = %v", err)
+	}
+	entries, err := fs.ReadDir(l, "img")
+	if err != nil || len(entries) != 2 || entries[0].Name() != "logo.png" || entries[1].Name() != "new.png" {
+		t.Errorf("ReadDir(img) = %v, %v", entries, err)
+	}
+	if err = fstest.TestFS(l, "app.css", "app.js", "img/logo.png", "img/new.png", "docs/index.html"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStaticFS(t *testing.T) {
+	l := NewLayeredFS(fstest.MapFS{
+		"app.css": {Data: []byte("patched"), ModTime: time.Now()},
+	}, fstest.MapFS{
+		"app.css":         {Data: []byte("embedded")},
+		"docs/index.html": {Data: []byte("<p>docs</p>")},
+	})
+	r := NewRouter()
+	r.Handle(GET, "/static/*filepath", StaticFSFunc(l))
+	for path, want := range map[string]struct {
+		code int
+		body string
+		ct   string
+	}{
+		"/static/app.css":           {http.StatusOK, "patched", "text/css; charset=utf-8"},
+		"/static/docs/":             {http.StatusOK, "<p>docs</p>", "text/html; charset=utf-8"},
+		"/static/../layeredfs.go":   {http.StatusNotFound, "", ""},
+		"/static/docs/missing.html": {http.StatusNotFound, "", ""},
+	} {
+		req, _ := http.NewRequest(GET, path, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != want.code || want.code == http.StatusOK && (rec.Body.String() != want.body || rec.Header().Get(HeaderContentType) != want.ct) {
+			t.Errorf("%s = %d %q %q, want %+v", path, rec.Code, rec.Body.String(), rec.Header().Get(HeaderContentType), want)
+		}
+	}
+}
+
+func TestPongo2RenderFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "renderfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	embedded := fstest.MapFS{
+		"layout.html":     {Data: []byte(`[{% block body %}{% endblock %}]`)},
+		"pages/home.html": {Data: []byte(`{% extends "../layout.html" %}{% block body %}{% include "/footer.html" %}{% endblock %}`)},
+		"footer.html":     {Data: []byte(`embedded footer`)},
+	}
+	req, _ := http.NewRequest(GET, "/", nil)
+	c, _ := testContext(req)
+	defer c.free()
+
+	r := NewPongo2RenderFS(NewLayeredFS(os.DirFS(dir), embedded), true)
+	render := func() string {
+		var buf bytes.Buffer
+		if err := r.Render(&buf, "/pages/home.html", nil, c); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	if got := render(); got != "[embedded footer]" {
+		t.Errorf("Render = %q", got)
+	}
+
+	// 在磁盘层放入覆盖文件后无需重启即生效
+	if err = os.MkdirAll(filepath.Join(dir, "pages"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "pages", "home.html"), []byte(`{% extends "../layout.html" %}{% block body %}patched{% endblock %}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(); got != "[patched]" {
+		t.Errorf("Render after patch = %q", got)
+	}
+}
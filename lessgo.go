@@ -9,11 +9,14 @@ package lessgo
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
-	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/lessgo/lessgo/_fixture"
@@ -27,12 +30,6 @@ type Lessgo struct {
 	*App
 	*config
 
-	//全局操作列表
-	apiHandlers []*ApiHandler
-
-	//全局中间件列表
-	apiMiddlewares []*ApiMiddleware
-
 	// 路由执行前后的中间件登记
 	virtBefore  []*MiddlewareConfig //处理链中路由操作之前的中间件子链
 	virtAfter   []*MiddlewareConfig //处理链中路由操作之后的中间件子链
@@ -66,6 +63,7 @@ var (
 		err := c.LoadMainConfig()
 		if err != nil {
 			fmt.Println(err)
+			configLoadErr = err
 		}
 		return c
 	}()
@@ -79,8 +77,9 @@ var (
 	// 全局运行日志实例(来自数据库的日志除外)
 	Log = func() logs.Logger {
 		l := logs.NewLogger(1000)
-		l.AddAdapter("console", "")
-		l.AddAdapter("file", `{"filename":"`+LOG_FILE+`"}`)
+		l.AddAdapter("console", `{"format":"`+Config.Log.ConsoleFormat+`"}`)
+		l.AddAdapter("file", `{"filename":"`+LOG_FILE+`","format":"`+Config.Log.FileFormat+`"}`)
+		logs.SetDefault(l)
 		return l
 	}()
 
@@ -156,6 +155,18 @@ func SetRenderer(r Renderer) {
 	app.SetRenderer(r)
 }
 
+// 替换全局运行日志(内部有默认实现)，如logs/slog、logs/zap、logs/logrus适配的外部日志，
+// 模块日志也随之输出到l；应在注册路由与启动服务之前调用
+func SetLogger(l logs.Logger) {
+	Log = l
+	logs.SetDefault(l)
+}
+
+// 添加服务退出时执行的钩子，在停止接受新连接(平滑模式下并等待进行中的请求处理完)后按添加顺序执行
+func OnShutdown(fn func()) {
+	app.onShutdown(fn)
+}
+
 // 判断当前是否为调试模式
 func Debug() bool {
 	return app.Debug()
@@ -188,12 +199,12 @@ func RefreshMemoryCache() {
 
 // 获取已注册的操作列表
 func Handlers() []*ApiHandler {
-	return lessgo.apiHandlers
+	return apiHandlerRegistry.list()
 }
 
 // 获取已注册的中间件列表
 func Middlewares() []*ApiMiddleware {
-	return lessgo.apiMiddlewares
+	return apiMiddlewareRegistry.list()
 }
 
 // 返回当前虚拟的路由列表(不含单独注册的静态路由VirtFiles/VirtStatics)
@@ -216,6 +227,46 @@ func RealRoutes() []Route {
 	return app.RealRoutes()
 }
 
+// 按已注册的路由生成URL，依次以params替换路由中的:name与*name参数，
+// 如URLFor("/users/:id", 7)返回"/users/7"；路由未注册或参数个数不符时返回错误
+func URLFor(route string, params ...interface{}) (string, error) {
+	found := false
+	for _, r := range app.routes {
+		if r.Path == route {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("route %s is not registered", route)
+	}
+	segs := strings.Split(route, "/")
+	n := 0
+	for i, seg := range segs {
+		if seg == "" || seg[0] != ':' && seg[0] != '*' {
+			continue
+		}
+		if n >= len(params) {
+			return "", fmt.Errorf("route %s needs more than %d params", route, len(params))
+		}
+		v := fmt.Sprint(params[n])
+		n++
+		if seg[0] == ':' {
+			segs[i] = url.PathEscape(v)
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+		for j := range parts {
+			parts[j] = url.PathEscape(parts[j])
+		}
+		segs[i] = strings.Join(parts, "/")
+	}
+	if n != len(params) {
+		return "", fmt.Errorf("route %s takes %d params, got %d", route, n, len(params))
+	}
+	return strings.Join(segs, "/"), nil
+}
+
 // 虚拟路由根节点
 func RootRouter() *VirtRouter {
 	return lessgo.virtRouter
@@ -223,7 +274,7 @@ func RootRouter() *VirtRouter {
 
 // 操作列表(禁止修改)
 func ApiHandlerList() []*ApiHandler {
-	return lessgo.apiHandlers
+	return apiHandlerRegistry.list()
 }
 
 // 添加到处理链最前端的中间件(子链)
@@ -296,6 +347,7 @@ func Static(prefix, root string, middlewares ...interface{}) error {
 	for _, v := range lessgo.virtStatics {
 		if v.Prefix == prefix {
 			v.Root = root
+			v.FS = nil
 			v.Middlewares = ms
 			return nil
 		}
@@ -399,6 +451,8 @@ func WrapMiddleware(h interface{}) MiddlewareFunc {
 		return t
 	case func(HandlerFunc) HandlerFunc:
 		return MiddlewareFunc(t)
+	case func(http.Handler) http.Handler:
+		return WrapHTTPMiddleware(t)
 	case HandlerFunc:
 		x = t
 	case func(*Context) error:
@@ -487,31 +541,84 @@ func ReregisterRouter() {
 	}
 }
 
-// 运行服务
-func Run() {
-	// 尝试设置系统默认通用操作
-	tryRegisterDefaultHandler()
+var (
+	registerDefaultsOnce sync.Once
+	prepareOnce          sync.Once
+)
 
-	// 添加系统预设的路由操作前的中间件
-	registerBefore()
+// 注册系统预设的操作、中间件与静态路由，仅执行一次
+func registerDefaults() {
+	registerDefaultsOnce.Do(func() {
+		// 尝试设置系统默认通用操作
+		tryRegisterDefaultHandler()
 
-	// 添加系统预设的路由操作后的中间件
-	registerAfter()
+		// 添加系统预设的路由操作前的中间件
+		registerBefore()
 
-	// 添加系统预设的静态目录虚拟路由
-	registerStatics()
+		// 添加系统预设的路由操作后的中间件
+		registerAfter()
 
-	// 添加系统预设的静态文件虚拟路由
-	registerFiles()
+		// 添加系统预设的静态目录虚拟路由
+		registerStatics()
 
-	// 从数据库初始化虚拟路由
-	initVirtRouterConfig()
+		// 添加系统预设的静态文件虚拟路由
+		registerFiles()
+	})
+}
 
-	// 重建路由
+// 构建路由但不启动服务，返回处理全部请求的http.Handler，供测试在内存中执行请求(见lessgotest)；
+// 与Run相同地注册系统预设的中间件与静态路由，但不读取虚拟路由配置文件、不开启配置热加载；
+// 重复调用时仅重建路由，以包含之后注册的路由
+func BuildHandler() http.Handler {
+	registerDefaults()
 	ReregisterRouter()
+	return app
+}
+
+// 构建路由(见buildRouter)并开启配置热加载等后台任务，仅执行一次
+func prepare() {
+	prepareOnce.Do(func() {
+		buildRouter()
+
+		// 开启配置热加载
+		watchConfig()
+
+		// 调试模式下监视模板目录
+		startTemplateWatcher()
+
+		// 注册配置中定义的数据库连接池与Redis客户端
+		loadDBConfigs()
+		loadRedisConfigs()
+		loadWorkerPoolConfigs()
+	})
+}
+
+// 返回处理全部请求的http.Handler(含路由与全部中间件)，用于将应用挂载到已有的net/http路由、
+// 测试服务器或只接受http.Handler的第三方服务器中，由对方负责监听；
+// 与Run相同地完成路由构建、配置热加载等准备工作，但不修改GOMAXPROCS等运行时设置
+func HTTPHandler() http.Handler {
+	prepare()
+	atomic.StoreInt32(&serving, 1)
+	return app
+}
+
+// HTTPHandler returns the app as an http.Handler, see lessgo.HTTPHandler.
+func (this *App) HTTPHandler() http.Handler {
+	return HTTPHandler()
+}
+
+// 运行服务
+func Run() {
+	if file := os.Getenv(ROUTES_ENV); file != "" {
+		dumpRoutesAndExit(file)
+	}
+	if checkFlag(os.Args[1:]) {
+		checkAndExit()
+	}
+	prepare()
 
-	// 开启最大核心数运行
-	runtime.GOMAXPROCS(runtime.NumCPU())
+	// 按容器CPU配额等设置GOMAXPROCS，及GOGC、GC压舱物
+	applyRuntimeConfig(Config.Runtime)
 
 	// 配置服务器引擎
 	var (
@@ -540,6 +647,7 @@ func Run() {
 	Log.Sys("> %s listening and serving %s on %v (%s-mode) %v", Config.AppName, protocol, Config.Listen.Address, mode, graceful)
 
 	// 启动服务
+	atomic.StoreInt32(&serving, 1)
 	app.run(
 		Config.Listen.Address,
 		tlsCertfile,
@@ -547,5 +655,6 @@ func Run() {
 		time.Duration(Config.Listen.ReadTimeout),
 		time.Duration(Config.Listen.WriteTimeout),
 		Config.Listen.Graceful,
+		Config.Listen.StrictParsing,
 	)
 }
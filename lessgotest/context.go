@@ -0,0 +1,97 @@
+package lessgotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/lessgo/lessgo"
+)
+
+// Option configures a Context built by NewContext.
+type Option func(c *lessgo.Context)
+
+// PathParam sets a path parameter.
+func PathParam(key, value string) Option {
+	return func(c *lessgo.Context) { c.SetPathParam(key, value) }
+}
+
+// Header sets a request header.
+func Header(key, value string) Option {
+	return func(c *lessgo.Context) { c.Request().Header.Set(key, value) }
+}
+
+// Cookie adds a request cookie.
+func Cookie(name, value string) Option {
+	return func(c *lessgo.Context) { c.AddCookieParam(&http.Cookie{Name: name, Value: value}) }
+}
+
+// RemoteAddr sets the address of the client, "192.0.2.1:1234" by default.
+func RemoteAddr(addr string) Option {
+	return func(c *lessgo.Context) { c.Request().RemoteAddr = addr }
+}
+
+// Store sets a value in the context, e.g. the user set by an authentication
+// middleware.
+func Store(key string, value interface{}) Option {
+	return func(c *lessgo.Context) { c.Set(key, value) }
+}
+
+// Route sets the registered path and the handler matched by the request,
+// used by middleware reading the handler's metadata, such as RBAC.
+func Route(path string, ah *lessgo.ApiHandler) Option {
+	return func(c *lessgo.Context) {
+		c.SetPath(path)
+		c.SetApiHandler(ah)
+	}
+}
+
+// NewContext returns a Context for a request to path, which may contain a
+// query string, and the recorder of its response, to unit test handlers and
+// middleware without routing:
+//
+//	c, rec := lessgotest.NewContext("GET", "/users/1", nil, lessgotest.PathParam("id", "1"))
+//	err := GetUser.Handler(c)
+//
+// body is nil, a string, []byte or io.Reader sent as is, or another value
+// sent as JSON with its Content-Type set.
+func NewContext(method, path string, body interface{}, opts ...Option) (*lessgo.Context, *httptest.ResponseRecorder) {
+	var (
+		r           io.Reader
+		contentType string
+	)
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	case []byte:
+		r = bytes.NewReader(b)
+	case io.Reader:
+		r = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic("lessgotest: encoding JSON body: " + err.Error())
+		}
+		r = bytes.NewReader(data)
+		contentType = lessgo.MIMEApplicationJSONCharsetUTF8
+	}
+	req, err := http.NewRequest(method, path, r)
+	if err != nil {
+		panic("lessgotest: " + err.Error())
+	}
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Host = "example.com"
+	if contentType != "" {
+		req.Header.Set(lessgo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	c := lessgo.NewContext(rec, req)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, rec
+}
@@ -0,0 +1,42 @@
+package lessgotest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lessgo/lessgo"
+)
+
+func TestNewContext(t *testing.T) {
+	c, rec := NewContext("POST", "/orders/9?expand=items", map[string]int{"qty": 2},
+		PathParam("id", "9"),
+		Header("X-Token", "secret"),
+		Cookie("sid", "abc"),
+		Store("user", "gopher"),
+		Route("/orders/:id", testUserHandler),
+	)
+	var body map[string]int
+	if err := c.Bind(&body); err != nil || body["qty"] != 2 {
+		t.Errorf("Bind = %v, %v", body, err)
+	}
+	if c.PathParam("id") != "9" || c.QueryParam("expand") != "items" ||
+		c.Request().Header.Get("X-Token") != "secret" || c.CookieParam("sid") == nil ||
+		c.Get("user") != "gopher" || c.Path() != "/orders/:id" || c.ApiHandler() != testUserHandler {
+		t.Errorf("context not set up: %+v", c.Request())
+	}
+
+	if err := c.String(http.StatusAccepted, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "ok" {
+		t.Errorf("response = %d %q", rec.Code, rec.Body.String())
+	}
+
+	c, rec = NewContext("GET", "/lessgotest/users/3", nil, PathParam("id", "3"))
+	if err := testUserHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get(lessgo.HeaderContentType) == "" {
+		t.Errorf("handler response = %d %v", rec.Code, rec.Header())
+	}
+}
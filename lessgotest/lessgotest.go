@@ -0,0 +1,317 @@
+// Package lessgotest executes requests in memory through the full middleware
+// and router stack, no socket is opened, and asserts on the responses.
+//
+//	func TestGetUser(t *testing.T) {
+//		client := lessgotest.New(nil) // nil for the lessgo app
+//		var user User
+//		client.GET("/users/1").
+//			WithHeader("Authorization", "Bearer token").
+//			Expect(t).
+//			Status(http.StatusOK).
+//			JSON(&user)
+//	}
+//
+// Cookies set by responses are kept by the Client and sent with later
+// requests, so session based flows can be tested.
+//
+// NewContext builds a Context to unit test a single handler or middleware
+// without routing.
+package lessgotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lessgo/lessgo"
+)
+
+// Client executes requests against a handler in memory.
+type Client struct {
+	handler http.Handler
+	header  http.Header
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie
+}
+
+// New returns a Client for h, nil for the lessgo app whose routes are
+// built by lessgo.BuildHandler.
+func New(h http.Handler) *Client {
+	if h == nil {
+		h = lessgo.BuildHandler()
+	}
+	return &Client{handler: h, header: http.Header{}, cookies: map[string]*http.Cookie{}}
+}
+
+// WithHeader sets a header sent with every request of the client.
+func (c *Client) WithHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// ClearCookies drops the cookies kept from previous responses.
+func (c *Client) ClearCookies() {
+	c.mu.Lock()
+	c.cookies = map[string]*http.Cookie{}
+	c.mu.Unlock()
+}
+
+func (c *Client) GET(path string) *Request     { return c.Request(lessgo.GET, path) }
+func (c *Client) POST(path string) *Request    { return c.Request(lessgo.POST, path) }
+func (c *Client) PUT(path string) *Request     { return c.Request(lessgo.PUT, path) }
+func (c *Client) PATCH(path string) *Request   { return c.Request(lessgo.PATCH, path) }
+func (c *Client) DELETE(path string) *Request  { return c.Request(lessgo.DELETE, path) }
+func (c *Client) HEAD(path string) *Request    { return c.Request(lessgo.HEAD, path) }
+func (c *Client) OPTIONS(path string) *Request { return c.Request(lessgo.OPTIONS, path) }
+
+// Request starts building a request, path may contain a query string.
+func (c *Client) Request(method, path string) *Request {
+	return &Request{client: c, method: method, path: path, header: http.Header{}, query: url.Values{}}
+}
+
+// Request is a request being built, its methods return it for chaining.
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    []byte
+	err     error
+}
+
+// WithHeader sets a request header.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithQuery adds a query parameter.
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithCookie adds a cookie.
+func (r *Request) WithCookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// WithBody sets the body and its content type.
+func (r *Request) WithBody(body []byte, contentType string) *Request {
+	r.body = body
+	r.header.Set(lessgo.HeaderContentType, contentType)
+	return r
+}
+
+// WithJSON sets v encoded as JSON as the body.
+func (r *Request) WithJSON(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+	}
+	return r.WithBody(b, lessgo.MIMEApplicationJSONCharsetUTF8)
+}
+
+// WithForm sets the url encoded form as the body.
+func (r *Request) WithForm(form url.Values) *Request {
+	return r.WithBody([]byte(form.Encode()), lessgo.MIMEApplicationForm)
+}
+
+// HTTPRequest returns the request to execute.
+func (r *Request) HTTPRequest() (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequest(r.method, r.path, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.query) > 0 {
+		q := req.URL.Query()
+		for k, vs := range r.query {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Host = "example.com"
+	for k, v := range r.client.header {
+		req.Header[k] = v
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	r.client.mu.Lock()
+	for _, cookie := range r.client.cookies {
+		req.AddCookie(cookie)
+	}
+	r.client.mu.Unlock()
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	return req, nil
+}
+
+// Do executes the request and returns the recorded response.
+func (r *Request) Do() (*httptest.ResponseRecorder, error) {
+	req, err := r.HTTPRequest()
+	if err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(rec, req)
+	resp := http.Response{Header: rec.Header()}
+	r.client.mu.Lock()
+	for _, cookie := range resp.Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(r.client.cookies, cookie.Name)
+		} else {
+			r.client.cookies[cookie.Name] = cookie
+		}
+	}
+	r.client.mu.Unlock()
+	return rec, nil
+}
+
+// Expect executes the request and returns the response to assert on,
+// failures are reported to t.
+func (r *Request) Expect(t testing.TB) *Response {
+	rec, err := r.Do()
+	if err != nil {
+		t.Fatalf("%s %s: %v", r.method, r.path, err)
+	}
+	return &Response{t: t, name: r.method + " " + r.path, Recorder: rec}
+}
+
+// Response asserts on a recorded response, its methods return it for chaining.
+type Response struct {
+	t        testing.TB
+	name     string
+	Recorder *httptest.ResponseRecorder
+}
+
+// Code returns the status code.
+func (r *Response) Code() int {
+	return r.Recorder.Code
+}
+
+// Body returns the body.
+func (r *Response) Body() string {
+	return r.Recorder.Body.String()
+}
+
+// Status asserts the status code.
+func (r *Response) Status(code int) *Response {
+	if r.Recorder.Code != code {
+		r.t.Errorf("%s: status = %d, want %d, body: %.500s", r.name, r.Recorder.Code, code, r.Body())
+	}
+	return r
+}
+
+// Header asserts a header value.
+func (r *Response) Header(key, value string) *Response {
+	if got := r.Recorder.Header().Get(key); got != value {
+		r.t.Errorf("%s: header %s = %q, want %q", r.name, key, got, value)
+	}
+	return r
+}
+
+// ContentType asserts the media type of the Content-Type header, ignoring
+// parameters such as the charset.
+func (r *Response) ContentType(mediaType string) *Response {
+	got := r.Recorder.Header().Get(lessgo.HeaderContentType)
+	if i := strings.Index(got, ";"); i >= 0 {
+		got = got[:i]
+	}
+	if strings.TrimSpace(got) != mediaType {
+		r.t.Errorf("%s: content type = %q, want %q", r.name, got, mediaType)
+	}
+	return r
+}
+
+// BodyEquals asserts the body.
+func (r *Response) BodyEquals(body string) *Response {
+	if got := r.Body(); got != body {
+		r.t.Errorf("%s: body = %q, want %q", r.name, got, body)
+	}
+	return r
+}
+
+// BodyContains asserts the body contains s.
+func (r *Response) BodyContains(s string) *Response {
+	if !strings.Contains(r.Body(), s) {
+		r.t.Errorf("%s: body %.500q doesn't contain %q", r.name, r.Body(), s)
+	}
+	return r
+}
+
+// JSON decodes the body into v.
+func (r *Response) JSON(v interface{}) *Response {
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), v); err != nil {
+		r.t.Errorf("%s: decoding JSON body: %v, body: %.500s", r.name, err, r.Body())
+	}
+	return r
+}
+
+// JSONEquals asserts the body decodes to the same JSON value as v.
+func (r *Response) JSONEquals(v interface{}) *Response {
+	var got, want interface{}
+	b, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(b, &want)
+	}
+	if err != nil {
+		r.t.Fatalf("%s: encoding expected JSON: %v", r.name, err)
+	}
+	if err = json.Unmarshal(r.Recorder.Body.Bytes(), &got); err != nil {
+		r.t.Errorf("%s: decoding JSON body: %v, body: %.500s", r.name, err, r.Body())
+		return r
+	}
+	if g, _ := json.Marshal(got); !bytes.Equal(g, mustMarshal(want)) {
+		r.t.Errorf("%s: JSON body = %s, want %s", r.name, g, mustMarshal(want))
+	}
+	return r
+}
+
+// Cookie returns the cookie set by the response, nil if not set.
+func (r *Response) Cookie(name string) *http.Cookie {
+	resp := http.Response{Header: r.Recorder.Header()}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// HTTPResponse returns the response as *http.Response.
+func (r *Response) HTTPResponse() *http.Response {
+	return &http.Response{
+		StatusCode:    r.Recorder.Code,
+		Status:        http.StatusText(r.Recorder.Code),
+		Header:        r.Recorder.Header(),
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Recorder.Body.Bytes())),
+		ContentLength: int64(r.Recorder.Body.Len()),
+	}
+}
+
+func mustMarshal(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -0,0 +1,64 @@
+package lessgotest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/lessgo/lessgo"
+)
+
+var testUserHandler = lessgo.ApiHandler{
+	Desc:   "lessgotest user",
+	Method: "GET|POST",
+	Params: []lessgo.Param{{Name: "id", In: "path", Required: true, Model: "", Desc: "user id"}},
+	Handler: func(c *lessgo.Context) error {
+		if c.Request().Method == lessgo.POST {
+			var body map[string]string
+			if err := c.Bind(&body); err != nil {
+				return err
+			}
+			c.SetCookie(&http.Cookie{Name: "seen", Value: body["name"], Path: "/"})
+			return c.JSON(http.StatusCreated, body)
+		}
+		seen := ""
+		if cookie := c.CookieParam("seen"); cookie != nil {
+			seen = cookie.Value
+		}
+		return c.JSON(http.StatusOK, map[string]string{
+			"id":    c.PathParam("id"),
+			"q":     c.QueryParam("q"),
+			"token": c.Request().Header.Get("X-Token"),
+			"seen":  seen,
+		})
+	},
+}.Reg()
+
+func init() {
+	lessgo.Root(lessgo.Leaf("/lessgotest/users", testUserHandler))
+}
+
+func TestClient(t *testing.T) {
+	client := New(nil).WithHeader("X-Token", "secret")
+
+	client.POST("/lessgotest/users/1").
+		WithJSON(map[string]string{"name": "gopher"}).
+		Expect(t).
+		Status(http.StatusCreated).
+		ContentType(lessgo.MIMEApplicationJSON).
+		JSONEquals(map[string]string{"name": "gopher"})
+
+	client.GET("/lessgotest/users/7").
+		WithQuery("q", "x y").
+		Expect(t).
+		Status(http.StatusOK).
+		JSONEquals(map[string]string{"id": "7", "q": "x y", "token": "secret", "seen": "gopher"})
+
+	client.GET("/lessgotest/missing").Expect(t).Status(http.StatusNotFound)
+
+	form := url.Values{"a": {"1"}}
+	req, err := client.PUT("/x").WithForm(form).HTTPRequest()
+	if err != nil || req.Header.Get(lessgo.HeaderContentType) != lessgo.MIMEApplicationForm {
+		t.Errorf("WithForm request = %+v, %v", req, err)
+	}
+}
@@ -0,0 +1,204 @@
+package lessgotest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/lessgo/lessgo"
+)
+
+// Router is a router under conformance test. Patterns use :name for a
+// segment parameter and *name for a trailing catch-all parameter, whose
+// value starts with "/".
+type Router interface {
+	Handle(method, path string, h lessgo.HandlerFunc)
+	http.Handler
+}
+
+// NewRouter returns the lessgo router.
+func NewRouter() Router {
+	return lessgo.NewRouter()
+}
+
+// Routes registered by RouterConformance and FuzzRouter.
+var conformanceRoutes = []struct{ method, path string }{
+	{"GET", "/"},
+	{"GET", "/users/:id"},
+	{"POST", "/users/:id"},
+	{"GET", "/users/:id/posts/:post"},
+	{"GET", "/files/*filepath"},
+	{"GET", "/static/a.b/c"},
+	{"GET", "/unicode/ü/:name"},
+	{"GET", "/search"},
+}
+
+// RouterConformanceCase is a request and the expected response of the router
+// set up with the conformance routes.
+type RouterConformanceCase struct {
+	Method, Target string
+	Status         int
+	Route          string            // pattern expected to handle the request
+	Params         map[string]string // expected parameters
+	Location       string            // expected redirect location
+	Allow          []string          // methods expected in the Allow header
+}
+
+// RouterConformanceCases covers parameter extraction, method dispatch,
+// redirects and adversarial paths: encoded slashes, dot segments, unicode.
+var RouterConformanceCases = []RouterConformanceCase{
+	{Method: "GET", Target: "/", Status: 200, Route: "/"},
+	{Method: "GET", Target: "/users/42", Status: 200, Route: "/users/:id", Params: map[string]string{"id": "42"}},
+	{Method: "POST", Target: "/users/42", Status: 200, Route: "/users/:id", Params: map[string]string{"id": "42"}},
+	{Method: "GET", Target: "/users/42/posts/7", Status: 200, Route: "/users/:id/posts/:post", Params: map[string]string{"id": "42", "post": "7"}},
+	// Parameters are matched against the decoded path.
+	{Method: "GET", Target: "/users/%E2%9C%93", Status: 200, Route: "/users/:id", Params: map[string]string{"id": "✓"}},
+	{Method: "GET", Target: "/users/a%20b", Status: 200, Route: "/users/:id", Params: map[string]string{"id": "a b"}},
+	// An encoded slash is decoded too, so it separates segments.
+	{Method: "GET", Target: "/users/a%2Fb", Status: 404},
+	{Method: "GET", Target: "/users/..", Status: 200, Route: "/users/:id", Params: map[string]string{"id": ".."}},
+	{Method: "GET", Target: "/users/.hidden", Status: 200, Route: "/users/:id", Params: map[string]string{"id": ".hidden"}},
+	// The catch-all value is kept as is, handlers serving files must clean it.
+	{Method: "GET", Target: "/files/a/../b", Status: 200, Route: "/files/*filepath", Params: map[string]string{"filepath": "/a/../b"}},
+	{Method: "GET", Target: "/files/", Status: 200, Route: "/files/*filepath", Params: map[string]string{"filepath": "/"}},
+	{Method: "GET", Target: "/static/a.b/c", Status: 200, Route: "/static/a.b/c"},
+	{Method: "GET", Target: "/static/a.b/d", Status: 404},
+	{Method: "GET", Target: "/unicode/%C3%BC/x", Status: 200, Route: "/unicode/ü/:name", Params: map[string]string{"name": "x"}},
+	{Method: "GET", Target: "/unicode/u/x", Status: 404},
+	{Method: "GET", Target: "/search?q=a/b", Status: 200, Route: "/search"},
+	{Method: "GET", Target: "/nope", Status: 404},
+	// Trailing slash and case fixes are redirected.
+	{Method: "GET", Target: "/users/42/", Status: 301, Location: "/users/42"},
+	{Method: "GET", Target: "/search/", Status: 301, Location: "/search"},
+	{Method: "POST", Target: "/users/42/", Status: 307, Location: "/users/42"},
+	{Method: "GET", Target: "/SEARCH", Status: 301, Location: "/search"},
+	{Method: "GET", Target: "/static/../search", Status: 301, Location: "/search"},
+	// Method dispatch.
+	{Method: "DELETE", Target: "/users/42", Status: 405, Allow: []string{"GET", "POST", "OPTIONS"}},
+	{Method: "OPTIONS", Target: "/users/42", Status: 200, Allow: []string{"GET", "POST", "OPTIONS"}},
+	{Method: "PUT", Target: "/nope", Status: 404},
+}
+
+// setupRouter registers the conformance routes, each handler responds with
+// its pattern and the parameters as JSON.
+func setupRouter(r Router) {
+	for _, route := range conformanceRoutes {
+		pattern := route.path
+		r.Handle(route.method, pattern, func(c *lessgo.Context) error {
+			params := map[string]string{}
+			for i, key := range c.PathParamKeys() {
+				params[key] = c.PathParamByIndex(i)
+			}
+			return c.JSON(http.StatusOK, conformanceResult{pattern, params})
+		})
+	}
+}
+
+type conformanceResult struct {
+	Route  string
+	Params map[string]string
+}
+
+func parseConformanceBody(body []byte) (string, map[string]string) {
+	var res conformanceResult
+	json.Unmarshal(body, &res)
+	return res.Route, res.Params
+}
+
+// RouterConformance runs RouterConformanceCases against routers returned by
+// newRouter, for routers extending or replacing the lessgo one:
+//
+//	func TestMyRouter(t *testing.T) {
+//		lessgotest.RouterConformance(t, func() lessgotest.Router { return NewMyRouter() })
+//	}
+func RouterConformance(t *testing.T, newRouter func() Router) {
+	r := newRouter()
+	setupRouter(r)
+	for _, tc := range RouterConformanceCases {
+		req, err := http.NewRequest(tc.Method, tc.Target, nil)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.Method, tc.Target, err)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		name := tc.Method + " " + tc.Target
+		if rec.Code != tc.Status {
+			t.Errorf("%s: status = %d, want %d, body: %q", name, rec.Code, tc.Status, rec.Body.String())
+			continue
+		}
+		if tc.Route != "" {
+			route, params := parseConformanceBody(rec.Body.Bytes())
+			if route != tc.Route {
+				t.Errorf("%s: routed to %q, want %q", name, route, tc.Route)
+			}
+			for k, v := range tc.Params {
+				if params[k] != v {
+					t.Errorf("%s: param %s = %q, want %q", name, k, params[k], v)
+				}
+			}
+			if len(params) != len(tc.Params) {
+				t.Errorf("%s: params = %v, want %v", name, params, tc.Params)
+			}
+		}
+		if tc.Location != "" {
+			if loc := rec.Header().Get(lessgo.HeaderLocation); loc != tc.Location {
+				t.Errorf("%s: location = %q, want %q", name, loc, tc.Location)
+			}
+		}
+		if tc.Allow != nil {
+			allow := rec.Header().Get("Allow")
+			for _, m := range tc.Allow {
+				if !strings.Contains(allow, m) {
+					t.Errorf("%s: Allow = %q, want %s in it", name, allow, m)
+				}
+			}
+		}
+	}
+}
+
+// FuzzRouter fuzzes request paths against routers returned by newRouter, set
+// up with the conformance routes. The router must not panic, and a matched
+// request must have valid parameters: segment ones are non-empty and contain
+// no "/", catch-all ones start with "/".
+//
+//	func FuzzMyRouter(f *testing.F) {
+//		lessgotest.FuzzRouter(f, func() lessgotest.Router { return NewMyRouter() })
+//	}
+func FuzzRouter(f *testing.F, newRouter func() Router) {
+	for _, tc := range RouterConformanceCases {
+		f.Add(tc.Method, tc.Target)
+	}
+	f.Add("GET", "/users/%2e%2e/posts/%00")
+	f.Add("GET", "//files///x")
+	f.Add("GET", "/unicode/\xff/x")
+	r := newRouter()
+	setupRouter(r)
+	f.Fuzz(func(t *testing.T, method, target string) {
+		if method == "" || strings.ContainsAny(method, " \t\r\n()<>@,;:\\\"/[]?={}") {
+			return
+		}
+		u, err := url.ParseRequestURI(target)
+		if err != nil || !strings.HasPrefix(u.Path, "/") {
+			return
+		}
+		req := &http.Request{Method: method, URL: u, Header: http.Header{}, Host: "example.com"}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || method == "OPTIONS" {
+			return
+		}
+		route, params := parseConformanceBody(rec.Body.Bytes())
+		for k, v := range params {
+			if strings.Contains(route, "*"+k) {
+				if !strings.HasPrefix(v, "/") {
+					t.Errorf("%s %s: catch-all %s = %q doesn't start with /", method, target, k, v)
+				}
+			} else if v == "" || strings.Contains(v, "/") {
+				t.Errorf("%s %s: param %s = %q", method, target, k, v)
+			}
+		}
+	})
+}
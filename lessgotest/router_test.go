@@ -0,0 +1,11 @@
+package lessgotest
+
+import "testing"
+
+func TestRouterConformance(t *testing.T) {
+	RouterConformance(t, NewRouter)
+}
+
+func FuzzRouterLessgo(f *testing.F) {
+	FuzzRouter(f, NewRouter)
+}
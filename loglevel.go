@@ -0,0 +1,34 @@
+package lessgo
+
+import (
+	"net/http"
+
+	"github.com/lessgo/lessgo/logs"
+)
+
+// 查询或调整模块日志级别的操作，供后台管理路由使用
+var LogLevelHandler = ApiHandler{
+	Desc:   "查询或调整模块日志级别",
+	Method: "GET|PUT",
+	Params: []Param{
+		{"module", "formData", false, "", "PUT时需要调整的模块名称"},
+		{"level", "formData", false, "", "PUT时设置的级别：debug、info、warn、error、fatal、off，inherit表示沿用全局级别"},
+	},
+	Handler: func(c *Context) error {
+		if c.request.Method == PUT {
+			module := c.FormParam("module")
+			if module == "" {
+				return NewHTTPError(http.StatusBadRequest, "param \"module\" is required")
+			}
+			l, err := logs.ParseLevel(c.FormParam("level"))
+			if err != nil {
+				return NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			logs.SetModuleLevel(module, l)
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"level":   logs.LevelName(Config.Log.Level),
+			"modules": logs.ModuleLevels(),
+		})
+	},
+}.Reg()
@@ -0,0 +1,172 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/logs/logs"
+)
+
+// 外部日志后端，由logs/slog、logs/zap、logs/logrus等适配包实现，
+// 级别均为DEBUG~FATAL
+type Backend interface {
+	// Enabled reports whether the backend writes messages of the level.
+	Enabled(level int) bool
+	// Log writes msg with fields, FATAL must not exit the process.
+	Log(level int, msg string, fields []Field)
+	// With returns a backend that attaches fields to every message.
+	With(fields []Field) Backend
+	// Sync flushes buffered messages.
+	Sync() error
+}
+
+// 由外部后端实现的日志，格式化、键值对解析、级别与钩子在此处理，
+// 输出、异步与调用位置由后端负责
+type backendLogger struct {
+	backend Backend
+	shared  *backendShared
+}
+
+type backendShared struct {
+	level    int32 // 额外的最低级别，默认DEBUG即完全由后端决定
+	hooks    []Hook
+	samplers [FATAL]*logs.Sampler
+	lock     sync.RWMutex
+}
+
+// 将外部日志后端包装为Logger
+func NewBackendLogger(b Backend) StructuredLogger {
+	return &backendLogger{backend: b, shared: &backendShared{level: DEBUG}}
+}
+
+// key为采样时区分日志的键，为空时不采样
+func (l *backendLogger) log(level int, key, msg string, fields []Field) {
+	l.shared.lock.RLock()
+	min, hooks := int(l.shared.level), l.shared.hooks
+	var sampler *logs.Sampler
+	if level < FATAL {
+		sampler = l.shared.samplers[level]
+	}
+	l.shared.lock.RUnlock()
+	if level < min || !l.backend.Enabled(level) || sampler != nil && key != "" && !sampler.Allow(key) {
+		return
+	}
+	if len(hooks) > 0 {
+		e := Entry{Level: level, Msg: msg, Fields: fields[:len(fields):len(fields)], Time: time.Now()}
+		for _, h := range hooks {
+			if !h(&e) {
+				return
+			}
+		}
+		msg, fields = e.Msg, e.Fields
+	}
+	l.backend.Log(level, msg, fields)
+}
+
+// SetLevel sets a minimum level on top of the backend's own level.
+func (l *backendLogger) SetLevel(level int) {
+	l.shared.lock.Lock()
+	l.shared.level = int32(level)
+	l.shared.lock.Unlock()
+}
+
+// SetMsgChan is a no-op, buffering is up to the backend.
+func (l *backendLogger) SetMsgChan(channelLen int64) {}
+
+// SetAsyncPolicy is a no-op, buffering is up to the backend.
+func (l *backendLogger) SetAsyncPolicy(policy int) {}
+
+// EnableFuncCallDepth is a no-op, caller reporting is up to the backend.
+func (l *backendLogger) EnableFuncCallDepth(b bool) {}
+
+// AddAdapter is not supported, outputs are configured on the backend.
+func (l *backendLogger) AddAdapter(adaptername string, config string) error {
+	return errors.New("logs: AddAdapter is not supported by an external backend, configure its outputs instead")
+}
+
+func (l *backendLogger) SetSampling(level int, s *Sampling) {
+	if level < DEBUG || level >= FATAL {
+		return
+	}
+	var sampler *logs.Sampler
+	if s != nil {
+		sampler = logs.NewSampler(logs.SamplingConfig{Tick: s.Tick, First: s.First, Thereafter: s.Thereafter})
+	}
+	l.shared.lock.Lock()
+	l.shared.samplers[level] = sampler
+	l.shared.lock.Unlock()
+}
+
+func (l *backendLogger) AddHook(h Hook) {
+	l.shared.lock.Lock()
+	l.shared.hooks = append(l.shared.hooks, h)
+	l.shared.lock.Unlock()
+}
+
+func (l *backendLogger) Flush() {
+	l.backend.Sync()
+}
+
+func (l *backendLogger) With(fields ...Field) StructuredLogger {
+	return &backendLogger{backend: l.backend.With(fields), shared: l.shared}
+}
+
+func (l *backendLogger) Write(p []byte) (n int, err error) {
+	l.log(INFO, "", logs.Bytes2String(p), nil)
+	return len(p), nil
+}
+
+// Sys logs at INFO, system messages cannot bypass the backend's level.
+func (l *backendLogger) Sys(format string, v ...interface{}) {
+	l.log(INFO, "", fmt.Sprintf(format, v...), nil)
+}
+
+func (l *backendLogger) Fatal(format string, v ...interface{}) {
+	l.log(FATAL, "", fmt.Sprintf(format, v...), nil)
+	l.backend.Sync()
+	os.Exit(1)
+}
+
+func (l *backendLogger) Error(format string, v ...interface{}) {
+	l.log(ERROR, format, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *backendLogger) Warn(format string, v ...interface{}) {
+	l.log(WARN, format, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *backendLogger) Info(format string, v ...interface{}) {
+	l.log(INFO, format, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *backendLogger) Debug(format string, v ...interface{}) {
+	l.log(DEBUG, format, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *backendLogger) Errorw(msg string, kv ...interface{}) {
+	l.log(ERROR, msg, msg, kvPairs(kv))
+}
+
+func (l *backendLogger) Warnw(msg string, kv ...interface{}) {
+	l.log(WARN, msg, msg, kvPairs(kv))
+}
+
+func (l *backendLogger) Infow(msg string, kv ...interface{}) {
+	l.log(INFO, msg, msg, kvPairs(kv))
+}
+
+func (l *backendLogger) Debugw(msg string, kv ...interface{}) {
+	l.log(DEBUG, msg, msg, kvPairs(kv))
+}
+
+func kvPairs(kv []interface{}) []Field {
+	lf := kvFields(nil, kv)
+	fields := make([]Field, len(lf))
+	for i, f := range lf {
+		fields[i] = Field(f)
+	}
+	return fields
+}
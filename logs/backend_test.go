@@ -0,0 +1,54 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recordBackend struct {
+	min    int
+	fields []Field
+	lines  *[]string
+}
+
+func (b recordBackend) Enabled(level int) bool { return level >= b.min }
+func (b recordBackend) Sync() error            { return nil }
+
+func (b recordBackend) Log(level int, msg string, fields []Field) {
+	line := fmt.Sprintf("%d %s", level, msg)
+	for _, f := range append(b.fields, fields...) {
+		line += fmt.Sprintf(" %s=%v", f.Key, f.Value)
+	}
+	*b.lines = append(*b.lines, line)
+}
+
+func (b recordBackend) With(fields []Field) Backend {
+	b.fields = append(b.fields[:len(b.fields):len(b.fields)], fields...)
+	return b
+}
+
+func TestBackendLogger(t *testing.T) {
+	var lines []string
+	l := NewBackendLogger(recordBackend{min: INFO, lines: &lines})
+	l.AddHook(func(e *Entry) bool { return e.Msg != "noise" })
+	l.Debug("hidden by the backend")
+	l.Info("hello %s", "world")
+	l.With(F("req", 7)).Warnw("slow", "ms", 300, "odd")
+	l.Info("noise")
+	l.SetLevel(ERROR)
+	l.Warn("hidden by SetLevel")
+	l.Error("boom")
+
+	want := []string{
+		fmt.Sprintf("%d hello world", INFO),
+		fmt.Sprintf("%d slow req=7 ms=300 odd=!MISSING", WARN),
+		fmt.Sprintf("%d boom", ERROR),
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if err := l.AddAdapter("console", ""); err == nil {
+		t.Error("AddAdapter should fail for an external backend")
+	}
+}
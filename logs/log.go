@@ -1,6 +1,10 @@
 package logs
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/lessgo/lessgo/logs/logs"
 )
 
@@ -16,6 +20,15 @@ type (
 		// AddAdapter provides a given logger adapter into Logger with config string.
 		// config need to be correct JSON as string: {"interval":360}.
 		AddAdapter(adaptername string, config string) error
+		// SetAsyncPolicy sets what to do when the async message channel is full,
+		// ASYNC_BLOCK(default) or ASYNC_DROP_OLDEST.
+		SetAsyncPolicy(policy int)
+		// Flush writes out all queued messages and flushes the adapters.
+		Flush()
+		// AddHook adds a hook called for every entry before it is written.
+		AddHook(h Hook)
+		// SetSampling sets sampling for messages of the level(DEBUG~ERROR), nil disables it.
+		SetSampling(level int, s *Sampling)
 
 		Write(p []byte) (n int, err error)
 		Sys(format string, v ...interface{})
@@ -26,11 +39,60 @@ type (
 		Debug(format string, v ...interface{})
 	}
 
+	// 支持结构化键值对的日志，NewLogger返回的实例均满足，
+	// 可通过 Structured(Log) 或类型断言获取
+	StructuredLogger interface {
+		Logger
+		// With returns a child logger that attaches fields to every message.
+		With(fields ...Field) StructuredLogger
+		// Errorw etc. log msg with alternating key-value pairs, e.g. Infow("login", "user", id).
+		// A Field may also be passed in place of a key-value pair.
+		Errorw(msg string, kv ...interface{})
+		Warnw(msg string, kv ...interface{})
+		Infow(msg string, kv ...interface{})
+		Debugw(msg string, kv ...interface{})
+	}
+
 	TgLogger struct {
 		*logs.BeeLogger
+		fields []logs.Field
+	}
+
+	// 结构化日志的键值对
+	Field struct {
+		Key   string
+		Value interface{}
+	}
+
+	// 传给钩子的日志条目
+	Entry struct {
+		Level  int // DEBUG~FATAL，系统日志为INFO
+		Msg    string
+		Fields []Field
+		Time   time.Time
+		Caller string // 如"main.go:10"，未开启调用位置时为空
+	}
+
+	// 日志钩子，在后台写入协程中按添加顺序对每条日志调用，
+	// 可修改条目(如追加全局字段)或返回false屏蔽该条日志；
+	// 转发告警等耗时操作不应阻塞钩子
+	Hook func(e *Entry) bool
+
+	// 日志采样：每个Tick(默认1秒)内同一条日志(按格式串或消息区分)先输出First条，
+	// 之后每Thereafter条输出1条，Thereafter为0时丢弃其余
+	Sampling struct {
+		First      int
+		Thereafter int
+		Tick       time.Duration
 	}
 )
 
+// 异步日志通道已满时的处理策略
+const (
+	ASYNC_BLOCK       = logs.AsyncBlock      // 等待后台写入
+	ASYNC_DROP_OLDEST = logs.AsyncDropOldest // 丢弃最早的日志，不阻塞调用者
+)
+
 // Log levels to control the logging output.
 const (
 	DEBUG = iota
@@ -42,11 +104,140 @@ const (
 )
 
 func NewLogger(channelLen int64) Logger {
-	tl := &TgLogger{logs.NewLogger(channelLen)}
+	tl := &TgLogger{BeeLogger: logs.NewLogger(channelLen)}
 	tl.BeeLogger.SetLogFuncCallDepth(3)
 	return tl
 }
 
+// 返回l的结构化日志接口，l不支持时返回一个仅输出格式化文本的包装
+func Structured(l Logger) StructuredLogger {
+	if sl, ok := l.(StructuredLogger); ok {
+		return sl
+	}
+	return &plainLogger{Logger: l}
+}
+
+// 创建键值对
+func F(key string, value interface{}) Field {
+	return Field{key, value}
+}
+
+func (t *TgLogger) With(fields ...Field) StructuredLogger {
+	child := &TgLogger{
+		BeeLogger: t.BeeLogger,
+		fields:    make([]logs.Field, len(t.fields), len(t.fields)+len(fields)),
+	}
+	copy(child.fields, t.fields)
+	for _, f := range fields {
+		child.fields = append(child.fields, logs.Field(f))
+	}
+	return child
+}
+
+func (t *TgLogger) Sys(format string, v ...interface{}) {
+	t.BeeLogger.Logf(logs.LevelSystem, t.fields, format, v...)
+}
+
+func (t *TgLogger) Fatal(format string, v ...interface{}) {
+	if !t.BeeLogger.Enabled(logs.LevelFatal) {
+		return
+	}
+	t.BeeLogger.Logf(logs.LevelFatal, t.fields, format, v...)
+	t.BeeLogger.Flush()
+	os.Exit(1)
+}
+
+func (t *TgLogger) Error(format string, v ...interface{}) {
+	t.BeeLogger.Logf(logs.LevelError, t.fields, format, v...)
+}
+
+func (t *TgLogger) Warn(format string, v ...interface{}) {
+	t.BeeLogger.Logf(logs.LevelWarning, t.fields, format, v...)
+}
+
+func (t *TgLogger) Info(format string, v ...interface{}) {
+	t.BeeLogger.Logf(logs.LevelInformational, t.fields, format, v...)
+}
+
+func (t *TgLogger) Debug(format string, v ...interface{}) {
+	t.BeeLogger.Logf(logs.LevelDebug, t.fields, format, v...)
+}
+
+func (t *TgLogger) Errorw(msg string, kv ...interface{}) {
+	t.BeeLogger.Logw(logs.LevelError, kvFields(t.fields, kv), msg)
+}
+
+func (t *TgLogger) Warnw(msg string, kv ...interface{}) {
+	t.BeeLogger.Logw(logs.LevelWarning, kvFields(t.fields, kv), msg)
+}
+
+func (t *TgLogger) Infow(msg string, kv ...interface{}) {
+	t.BeeLogger.Logw(logs.LevelInformational, kvFields(t.fields, kv), msg)
+}
+
+func (t *TgLogger) Debugw(msg string, kv ...interface{}) {
+	t.BeeLogger.Logw(logs.LevelDebug, kvFields(t.fields, kv), msg)
+}
+
+// 将键值对参数转换为Field列表(附加在base之后)，缺少值的键记为"!MISSING"
+func kvFields(base []logs.Field, kv []interface{}) []logs.Field {
+	if len(kv) == 0 {
+		return base
+	}
+	fields := make([]logs.Field, len(base), len(base)+len(kv)/2+1)
+	copy(fields, base)
+	for i := 0; i < len(kv); i++ {
+		if f, ok := kv[i].(Field); ok {
+			fields = append(fields, logs.Field(f))
+			continue
+		}
+		key := fmt.Sprint(kv[i])
+		if i+1 == len(kv) {
+			fields = append(fields, logs.Field{Key: key, Value: "!MISSING"})
+			break
+		}
+		i++
+		fields = append(fields, logs.Field{Key: key, Value: kv[i]})
+	}
+	return fields
+}
+
+func (t *TgLogger) AddHook(h Hook) {
+	t.BeeLogger.AddHook(wrapHook(h))
+}
+
+func wrapHook(h Hook) logs.Hook {
+	return func(e *logs.Entry) bool {
+		entry := Entry{
+			Level:  levelFrom(e.Level),
+			Msg:    e.Msg,
+			Fields: make([]Field, len(e.Fields)),
+			Time:   e.When,
+			Caller: e.Caller,
+		}
+		for i, f := range e.Fields {
+			entry.Fields[i] = Field(f)
+		}
+		if !h(&entry) {
+			return false
+		}
+		e.Msg = entry.Msg
+		e.Fields = make([]logs.Field, len(entry.Fields))
+		for i, f := range entry.Fields {
+			e.Fields[i] = logs.Field(f)
+		}
+		return true
+	}
+}
+
+func (t *TgLogger) SetSampling(level int, s *Sampling) {
+	if s == nil {
+		t.BeeLogger.SetSampling(ExchangeLevel(level), nil)
+		return
+	}
+	t.BeeLogger.SetSampling(ExchangeLevel(level), &logs.SamplingConfig{Tick: s.Tick, First: s.First, Thereafter: s.Thereafter})
+}
+
 func (t *TgLogger) SetLevel(l int) {
 	t.BeeLogger.SetLevel(ExchangeLevel(l))
 }
@@ -68,3 +259,61 @@ func ExchangeLevel(l int) int {
 	}
 	return logs.LevelError
 }
+
+// ExchangeLevel的逆转换
+func levelFrom(l int) int {
+	switch {
+	case l == logs.LevelSystem:
+		return INFO
+	case l == logs.LevelFatal:
+		return FATAL
+	case l <= logs.LevelError:
+		return ERROR
+	case l == logs.LevelWarning:
+		return WARN
+	case l <= logs.LevelInformational:
+		return INFO
+	}
+	return DEBUG
+}
+
+// 返回"ring"适配器在内存中保留的最近日志，按时间先后排序
+func Recent() []logs.RecentMsg {
+	return logs.Recent()
+}
+
+// 将结构化调用降级为普通格式化输出，用于不支持结构化日志的Logger实现
+type plainLogger struct {
+	Logger
+	fields []interface{}
+}
+
+func (p *plainLogger) With(fields ...Field) StructuredLogger {
+	child := &plainLogger{Logger: p.Logger, fields: append([]interface{}{}, p.fields...)}
+	for _, f := range fields {
+		child.fields = append(child.fields, f)
+	}
+	return child
+}
+
+func (p *plainLogger) Errorw(msg string, kv ...interface{}) { p.Logger.Error("%s", p.text(msg, kv)) }
+func (p *plainLogger) Warnw(msg string, kv ...interface{})  { p.Logger.Warn("%s", p.text(msg, kv)) }
+func (p *plainLogger) Infow(msg string, kv ...interface{})  { p.Logger.Info("%s", p.text(msg, kv)) }
+func (p *plainLogger) Debugw(msg string, kv ...interface{}) { p.Logger.Debug("%s", p.text(msg, kv)) }
+
+func (p *plainLogger) text(msg string, kv []interface{}) string {
+	all := append(append([]interface{}{}, p.fields...), kv...)
+	for i := 0; i < len(all); i++ {
+		if f, ok := all[i].(Field); ok {
+			msg += fmt.Sprintf(" %s=%v", f.Key, f.Value)
+			continue
+		}
+		if i+1 == len(all) {
+			msg += fmt.Sprintf(" %v=!MISSING", all[i])
+			break
+		}
+		msg += fmt.Sprintf(" %v=%v", all[i], all[i+1])
+		i++
+	}
+	return msg
+}
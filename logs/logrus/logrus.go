@@ -0,0 +1,60 @@
+// Package logrus adapts a github.com/sirupsen/logrus Logger to lessgo's logs.Logger.
+//
+// Usage:
+//
+//	l := logrus.New()
+//	l.Formatter = &logrus.JSONFormatter{}
+//	lessgo.SetLogger(llogrus.New(l))
+package logrus
+
+import (
+	"github.com/lessgo/lessgo/logs"
+
+	"github.com/sirupsen/logrus"
+)
+
+var levels = map[int]logrus.Level{
+	logs.DEBUG: logrus.DebugLevel,
+	logs.INFO:  logrus.InfoLevel,
+	logs.WARN:  logrus.WarnLevel,
+	logs.ERROR: logrus.ErrorLevel,
+	logs.FATAL: logrus.FatalLevel,
+}
+
+type backend struct {
+	e *logrus.Entry
+}
+
+// New returns a logs.StructuredLogger backed by l.
+func New(l *logrus.Logger) logs.StructuredLogger {
+	return logs.NewBackendLogger(backend{logrus.NewEntry(l)})
+}
+
+func (b backend) Enabled(level int) bool {
+	return b.e.Logger.IsLevelEnabled(levels[level])
+}
+
+// Log uses Entry.Log, which unlike Entry.Fatal does not exit the process.
+func (b backend) Log(level int, msg string, fields []logs.Field) {
+	e := b.e
+	if len(fields) > 0 {
+		e = e.WithFields(logrusFields(fields))
+	}
+	e.Log(levels[level], msg)
+}
+
+func (b backend) With(fields []logs.Field) logs.Backend {
+	return backend{b.e.WithFields(logrusFields(fields))}
+}
+
+func (b backend) Sync() error {
+	return nil
+}
+
+func logrusFields(fields []logs.Field) logrus.Fields {
+	lf := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		lf[f.Key] = f.Value
+	}
+	return lf
+}
@@ -0,0 +1,74 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 阻塞直到gate关闭的适配器
+type gateWriter struct {
+	gate    chan struct{}
+	started chan struct{}
+	msgs    []string
+}
+
+func (w *gateWriter) Init(string) error { return nil }
+func (w *gateWriter) Destroy()          {}
+func (w *gateWriter) Flush()            {}
+
+func (w *gateWriter) WriteMsg(lm logMsg) error {
+	if len(w.msgs) == 0 {
+		close(w.started)
+	}
+	<-w.gate
+	w.msgs = append(w.msgs, lm.msg)
+	return nil
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	w := &gateWriter{gate: make(chan struct{}), started: make(chan struct{})}
+	bl := NewLogger(2)
+	bl.outputs = []*nameLogger{{Logger: w, name: "gate"}}
+	bl.SetAsyncPolicy(AsyncDropOldest)
+
+	bl.Info("m0")
+	<-w.started // m0已被后台协程取出并阻塞在写入中
+	for _, m := range []string{"m1", "m2", "m3", "m4"} {
+		bl.Info(m)
+	}
+	close(w.gate)
+	bl.Flush()
+
+	if got := strings.Join(w.msgs, ","); got != "m0,m3,m4" {
+		t.Errorf("written = %s, want m0,m3,m4", got)
+	}
+	if n := bl.Dropped(); n != 2 {
+		t.Errorf("Dropped() = %d, want 2", n)
+	}
+}
+
+func TestSetMsgChanFlush(t *testing.T) {
+	w := &gateWriter{gate: make(chan struct{}), started: make(chan struct{})}
+	close(w.gate)
+	bl := NewLogger(2)
+	bl.outputs = []*nameLogger{{Logger: w, name: "gate"}}
+	bl.Info("m0")
+	bl.SetMsgChan(4)
+	bl.Info("m1")
+
+	done := make(chan struct{})
+	go func() {
+		bl.Flush()
+		bl.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush blocked after SetMsgChan")
+	}
+	if got := strings.Join(w.msgs, ","); got != "m0,m1" {
+		t.Errorf("written = %s, want m0,m1", got)
+	}
+}
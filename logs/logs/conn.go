@@ -30,6 +30,7 @@ type connWriter struct {
 	Net            string `json:"net"`
 	Addr           string `json:"addr"`
 	Level          int    `json:"level"`
+	Format         string `json:"format"` // "text"(default), "json" or "logfmt"
 }
 
 // NewConn create new ConnWrite returning as LoggerInterface.
@@ -62,7 +63,7 @@ func (c *connWriter) WriteMsg(lm logMsg) error {
 		defer c.innerWriter.Close()
 	}
 
-	c.lg.println(&lm)
+	c.lg.print(&lm, c.Format, false)
 	return nil
 }
 
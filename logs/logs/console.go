@@ -26,8 +26,9 @@ type (
 	// consoleWriter implements LoggerInterface and writes messages to terminal.
 	consoleWriter struct {
 		lg       *logWriter
-		Level    int  `json:"level"`
-		Colorful bool `json:"color"` //this filed is useful only when system's terminal supports color
+		Level    int    `json:"level"`
+		Colorful bool   `json:"color"`  //this filed is useful only when system's terminal supports color
+		Format   string `json:"format"` // "text"(default), "json", "logfmt" or "pretty"
 	}
 )
 
@@ -72,6 +73,10 @@ func (c *consoleWriter) WriteMsg(lm logMsg) error {
 	if lm.level > c.Level {
 		return nil
 	}
+	if c.Format != "" && c.Format != "text" {
+		c.lg.print(&lm, c.Format, c.Colorful)
+		return nil
+	}
 	if c.Colorful {
 		lm.line = color.Dim(lm.line)
 		lm.prefix = colors[lm.level](lm.prefix)
@@ -0,0 +1,199 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lessgo/lessgo/logs/color"
+)
+
+// Field is a key-value pair attached to a structured log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// text returns the message followed by its fields as " key=value" pairs.
+func (lm *logMsg) text() string {
+	if len(lm.fields) == 0 {
+		return lm.msg
+	}
+	var buf bytes.Buffer
+	buf.WriteString(lm.msg)
+	for _, f := range lm.fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		s := fieldString(f.Value)
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			s = strconv.Quote(s)
+		}
+		buf.WriteString(s)
+	}
+	return buf.String()
+}
+
+// jsonBytes encodes the message as one JSON object:
+// {"time":...,"level":...,"caller":...,"msg":...,<fields>}
+func (lm *logMsg) jsonBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSONValue(&buf, lm.when.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(&buf, LevelNames[lm.level])
+	if lm.line != "" {
+		buf.WriteString(`,"caller":`)
+		writeJSONValue(&buf, strings.Trim(lm.line, "[]"))
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(&buf, strings.TrimRight(lm.msg, "\n"))
+	for _, f := range lm.fields {
+		buf.WriteByte(',')
+		writeJSONValue(&buf, f.Key)
+		buf.WriteByte(':')
+		writeJSONValue(&buf, f.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// logfmtBytes encodes the message as one logfmt line:
+// time=... level=... caller=... msg=... <fields>
+func (lm *logMsg) logfmtBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("time=")
+	buf.WriteString(lm.when.Format(time.RFC3339Nano))
+	buf.WriteString(" level=")
+	buf.WriteString(LevelNames[lm.level])
+	if lm.line != "" {
+		buf.WriteString(" caller=")
+		writeLogfmtValue(&buf, strings.Trim(lm.line, "[]"))
+	}
+	buf.WriteString(" msg=")
+	writeLogfmtValue(&buf, strings.TrimRight(lm.msg, "\n"))
+	for _, f := range lm.fields {
+		buf.WriteByte(' ')
+		buf.WriteString(logfmtKey(f.Key))
+		buf.WriteByte('=')
+		writeLogfmtValue(&buf, fieldString(f.Value))
+	}
+	return buf.Bytes()
+}
+
+// prettyBytes formats the message for reading in a terminal during development:
+// 15:04:05.000 INFO  msg  key=value ...  caller
+// Levels and field keys are colorized if colorful is true.
+func (lm *logMsg) prettyBytes(colorful bool) []byte {
+	var buf bytes.Buffer
+	ts := lm.when.Format("15:04:05.000")
+	level := strings.ToUpper(LevelNames[lm.level])
+	if len(level) > 5 {
+		level = level[:5]
+	}
+	level += strings.Repeat(" ", 5-len(level))
+	if colorful {
+		ts = color.Dim(ts)
+		level = colors[lm.level](level)
+	}
+	buf.WriteString(ts)
+	buf.WriteByte(' ')
+	buf.WriteString(level)
+	buf.WriteByte(' ')
+	buf.WriteString(strings.TrimRight(lm.msg, "\n"))
+	for i, f := range lm.fields {
+		if i == 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte(' ')
+		key := f.Key + "="
+		if colorful {
+			key = color.Cyan(key)
+		}
+		buf.WriteString(key)
+		s := fieldString(f.Value)
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			s = strconv.Quote(s)
+		}
+		buf.WriteString(s)
+	}
+	if lm.line != "" {
+		line := strings.Trim(lm.line, "[]")
+		if colorful {
+			line = color.Dim(line)
+		}
+		buf.WriteString("  ")
+		buf.WriteString(line)
+	}
+	return buf.Bytes()
+}
+
+// logfmtKey replaces characters that are not allowed in logfmt keys.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func writeLogfmtValue(buf *bytes.Buffer, s string) {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=\\") {
+		s = strconv.Quote(s)
+	}
+	buf.WriteString(s)
+}
+
+// encode returns the message in the format followed by a newline:
+// "json", "logfmt", "pretty" (colorized if colorful) or text(default).
+func (lm *logMsg) encode(format string, colorful bool) []byte {
+	var b []byte
+	switch format {
+	case "json":
+		b = lm.jsonBytes()
+	case "logfmt":
+		b = lm.logfmtBytes()
+	case "pretty":
+		b = lm.prettyBytes(colorful)
+	default:
+		h, _ := formatTimeHeader(lm.when)
+		b = append(h, (lm.line + lm.prefix + " " + lm.text())...)
+	}
+	if len(b) == 0 || b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	return b
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	switch x := v.(type) {
+	case error:
+		v = x.Error()
+	case fmt.Stringer:
+		v = x.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+func fieldString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case error:
+		return x.Error()
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,43 @@
+package logs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLogMsgFields(t *testing.T) {
+	lm := &logMsg{
+		level: LevelInformational,
+		line:  "[main.go:10]",
+		msg:   "user login",
+		when:  time.Date(2016, 5, 1, 8, 30, 0, 0, time.UTC),
+		fields: []Field{
+			{"user", 42},
+			{"ip", "10.0.0.1"},
+			{"agent", "Mozilla/5.0 (X11)"},
+			{"err", errors.New("bad password")},
+		},
+	}
+	wantText := `user login user=42 ip=10.0.0.1 agent="Mozilla/5.0 (X11)" err="bad password"`
+	if got := lm.text(); got != wantText {
+		t.Errorf("text() = %s, want %s", got, wantText)
+	}
+	wantJSON := `{"time":"2016-05-01T08:30:00Z","level":"info","caller":"main.go:10","msg":"user login",` +
+		`"user":42,"ip":"10.0.0.1","agent":"Mozilla/5.0 (X11)","err":"bad password"}`
+	if got := string(lm.jsonBytes()); got != wantJSON {
+		t.Errorf("jsonBytes() = %s, want %s", got, wantJSON)
+	}
+	wantLogfmt := `time=2016-05-01T08:30:00Z level=info caller=main.go:10 msg="user login" ` +
+		`user=42 ip=10.0.0.1 agent="Mozilla/5.0 (X11)" err="bad password"`
+	if got := string(lm.logfmtBytes()); got != wantLogfmt {
+		t.Errorf("logfmtBytes() = %s, want %s", got, wantLogfmt)
+	}
+	wantPretty := `08:30:00.000 INFO  user login  user=42 ip=10.0.0.1 agent="Mozilla/5.0 (X11)" err="bad password"  main.go:10`
+	if got := string(lm.prettyBytes(false)); got != wantPretty {
+		t.Errorf("prettyBytes() = %s, want %s", got, wantPretty)
+	}
+	if got := string(lm.encode("logfmt", false)); got != wantLogfmt+"\n" {
+		t.Errorf("encode(logfmt) = %q", got)
+	}
+}
@@ -16,12 +16,15 @@ package logs
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -50,11 +53,22 @@ type fileLogWriter struct {
 
 	Rotate bool `json:"rotate"`
 
+	// Keep at most MaxBackups rotated files, 0 means no limit
+	MaxBackups int `json:"maxbackups"`
+
+	// Compress rotated files with gzip
+	Compress bool `json:"compress"`
+
 	Level int `json:"level"`
 
 	Perm os.FileMode `json:"perm"`
 
+	// "text"(default), "json" or "logfmt"
+	Format string `json:"format"`
+
 	fileNameOnly, suffix string // like "project.log", project is fileNameOnly and .log is suffix
+
+	cleanLock sync.Mutex // serializes compression and removal of rotated files
 }
 
 // newFileWriter create a FileLogWriter returning as LoggerInterface.
@@ -81,6 +95,8 @@ func newFileWriter() Logger {
 //	"daily":true,
 //	"maxDays":15,
 //	"rotate":true,
+//	"maxbackups":10,
+//	"compress":true,
 //  	"perm":0600
 //	}
 func (w *fileLogWriter) Init(jsonConfig string) error {
@@ -130,8 +146,8 @@ func (w *fileLogWriter) WriteMsg(lm logMsg) error {
 	if lm.level > w.Level {
 		return nil
 	}
-	h, d := formatTimeHeader(lm.when)
-	msg := Bytes2String(h) + lm.line + lm.prefix + " " + lm.msg + "\n"
+	d := lm.when.Day()
+	msg := Bytes2String(lm.encode(w.Format, false))
 	if w.Rotate {
 		if w.needRotate(len(msg), d) {
 			w.Lock()
@@ -220,11 +236,11 @@ func (w *fileLogWriter) doRotate(logTime time.Time) error {
 	if w.MaxLines > 0 || w.MaxSize > 0 {
 		for ; err == nil && num <= 999; num++ {
 			fName = w.fileNameOnly + fmt.Sprintf(".%s.%03d%s", logTime.Format("2006-01-02"), num, w.suffix)
-			_, err = os.Lstat(fName)
+			err = rotatedExists(fName)
 		}
 	} else {
 		fName = fmt.Sprintf("%s.%s%s", w.fileNameOnly, logTime.Format("2006-01-02"), w.suffix)
-		_, err = os.Lstat(fName)
+		err = rotatedExists(fName)
 	}
 	// return error if the last file checked still existed
 	if err == nil {
@@ -239,7 +255,9 @@ func (w *fileLogWriter) doRotate(logTime time.Time) error {
 	renameErr := os.Rename(w.Filename, fName)
 	// re-start logger
 	startLoggerErr := w.startLogger()
-	go w.deleteOldLog()
+	if renameErr == nil {
+		go w.afterRotate(fName)
+	}
 
 	if startLoggerErr != nil {
 		return fmt.Errorf("Rotate StartLogger: %s\n", startLoggerErr)
@@ -251,23 +269,101 @@ func (w *fileLogWriter) doRotate(logTime time.Time) error {
 
 }
 
+// rotatedExists returns nil if the rotated file or its compressed copy exists.
+func rotatedExists(fName string) error {
+	_, err := os.Lstat(fName)
+	if err != nil {
+		_, err = os.Lstat(fName + ".gz")
+	}
+	return err
+}
+
+// afterRotate compresses the rotated file and removes the old backups.
+// Rotations are serialized so that compression and cleanup never race each other.
+func (w *fileLogWriter) afterRotate(fName string) {
+	w.cleanLock.Lock()
+	defer w.cleanLock.Unlock()
+	if w.Compress {
+		if err := compressLogFile(fName); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to compress log '%s', error: %v\n", fName, err)
+		}
+	}
+	w.deleteOldLog()
+}
+
+func compressLogFile(fName string) error {
+	src, err := os.Open(fName)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(fName+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fName + ".gz")
+		return err
+	}
+	os.Chtimes(fName+".gz", fi.ModTime(), fi.ModTime())
+	return os.Remove(fName)
+}
+
+// deleteOldLog removes rotated files older than MaxDays and, if MaxBackups
+// is set, the oldest ones beyond that count.
 func (w *fileLogWriter) deleteOldLog() {
 	dir := filepath.Dir(w.Filename)
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) (returnErr error) {
-		defer func() {
-			if r := recover(); r != nil {
-				fmt.Fprintf(os.Stderr, "Unable to delete old log '%s', error: %v\n", path, r)
-			}
-		}()
-
-		if !info.IsDir() && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*w.MaxDays) {
-			if strings.HasPrefix(filepath.Base(path), w.fileNameOnly) &&
-				strings.HasSuffix(filepath.Base(path), w.suffix) {
-				os.Remove(path)
-			}
+	prefix := filepath.Base(w.fileNameOnly) + "."
+	current := filepath.Base(w.Filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to list old logs in '%s', error: %v\n", dir, err)
+		return
+	}
+	var backups logFileInfos
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || name == current || !strings.HasPrefix(name, prefix) ||
+			!(strings.HasSuffix(name, w.suffix) || strings.HasSuffix(name, w.suffix+".gz")) {
+			continue
 		}
+		if w.MaxDays > 0 && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*w.MaxDays) {
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		backups = append(backups, info)
+	}
+	if w.MaxBackups <= 0 || len(backups) <= w.MaxBackups {
 		return
-	})
+	}
+	sort.Sort(backups)
+	for _, info := range backups[w.MaxBackups:] {
+		os.Remove(filepath.Join(dir, info.Name()))
+	}
+}
+
+// logFileInfos sorts files from newest to oldest.
+type logFileInfos []os.FileInfo
+
+func (l logFileInfos) Len() int      { return len(l) }
+func (l logFileInfos) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l logFileInfos) Less(i, j int) bool {
+	if ti, tj := l[i].ModTime(), l[j].ModTime(); !ti.Equal(tj) {
+		return ti.After(tj)
+	}
+	return l[i].Name() > l[j].Name()
 }
 
 // Destroy close the file description, close file writer.
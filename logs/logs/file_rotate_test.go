@@ -0,0 +1,58 @@
+package logs
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRotateBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := newFileWriter().(*fileLogWriter)
+	if err := w.Init(`{"filename":"` + filepath.Join(dir, "test5.log") + `","maxlines":1,"maxbackups":2,"compress":true}`); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+	for i := 0; i < 6; i++ {
+		w.WriteMsg(logMsg{level: LevelInformational, msg: fmt.Sprintf("line %d", i), when: time.Now()})
+	}
+
+	// 压缩与清理在后台进行
+	date := time.Now().Format("2006-01-02")
+	want := []string{
+		filepath.Join(dir, "test5."+date+".004.log.gz"),
+		filepath.Join(dir, "test5."+date+".005.log.gz"),
+	}
+	var backups []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		backups, _ = filepath.Glob(filepath.Join(dir, "test5.*.log*"))
+		if fmt.Sprint(backups) == fmt.Sprint(want) {
+			break
+		}
+	}
+	if fmt.Sprint(backups) != fmt.Sprint(want) {
+		t.Fatalf("backups = %v, want %v", backups, want)
+	}
+	f, err := os.Open(want[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(zr)
+	if !strings.Contains(string(b), "line 4") {
+		t.Errorf("compressed backup = %q, want line 4", b)
+	}
+}
@@ -0,0 +1,130 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// journaldWriter implements LoggerInterface.
+// It sends messages to systemd-journald using the native journal protocol.
+type journaldWriter struct {
+	sync.Mutex
+	Socket     string `json:"socket"`     // defaults to /run/systemd/journal/socket
+	Identifier string `json:"identifier"` // SYSLOG_IDENTIFIER, defaults to the program name
+	Level      int    `json:"level"`
+
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+// NewJournald create a journaldWriter returning as LoggerInterface.
+func NewJournald() Logger {
+	return &journaldWriter{Level: LevelDebug}
+}
+
+// Init journald writer with json config.
+// jsonConfig like {"identifier":"myapp","level":7}, all keys are optional.
+func (w *journaldWriter) Init(jsonConfig string) error {
+	if jsonConfig != "" {
+		if err := json.Unmarshal([]byte(jsonConfig), w); err != nil {
+			return err
+		}
+	}
+	if w.Socket == "" {
+		w.Socket = "/run/systemd/journal/socket"
+	}
+	if w.Identifier == "" {
+		w.Identifier = filepath.Base(os.Args[0])
+	}
+	w.addr = &net.UnixAddr{Name: w.Socket, Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// WriteMsg sends the message as one journal entry.
+// Fields become journal fields with upper-cased names.
+func (w *journaldWriter) WriteMsg(lm logMsg) error {
+	if lm.level > w.Level {
+		return nil
+	}
+	var buf bytes.Buffer
+	journalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity[lm.level]))
+	journalField(&buf, "SYSLOG_IDENTIFIER", w.Identifier)
+	journalField(&buf, "MESSAGE", strings.TrimRight(lm.msg, "\n"))
+	if lm.line != "" {
+		caller := strings.Trim(lm.line, "[]")
+		if i := strings.LastIndex(caller, ":"); i > 0 {
+			journalField(&buf, "CODE_FILE", caller[:i])
+			journalField(&buf, "CODE_LINE", caller[i+1:])
+		}
+	}
+	for _, f := range lm.fields {
+		if key := journalFieldName(f.Key); key != "" {
+			journalField(&buf, key, fieldString(f.Value))
+		}
+	}
+	w.Lock()
+	defer w.Unlock()
+	_, err := w.conn.WriteToUnix(buf.Bytes(), w.addr)
+	return err
+}
+
+// journalField appends KEY=value, using the binary form for values with newlines.
+func journalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts key to a valid journal field name:
+// upper-case letters, digits and underscores, not starting with an underscore or digit.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// Flush implementing method. empty.
+func (w *journaldWriter) Flush() {
+}
+
+// Destroy close the socket.
+func (w *journaldWriter) Destroy() {
+	if w.conn != nil {
+		w.conn.Close()
+	}
+}
+
+func init() {
+	Register("journald", NewJournald)
+}
@@ -39,7 +39,9 @@ import (
 	"path"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -57,6 +59,14 @@ const (
 	LevelDebug
 )
 
+// policies when the async message channel is full.
+const (
+	// AsyncBlock makes the caller wait until the background writer catches up.
+	AsyncBlock = iota
+	// AsyncDropOldest discards the oldest queued message so the caller never waits.
+	AsyncDropOldest
+)
+
 var Prefix = map[int]string{
 	LevelSystem:        "",
 	LevelFatal:         "[F]",
@@ -106,8 +116,28 @@ type BeeLogger struct {
 	signalChan          chan string
 	wg                  sync.WaitGroup
 	outputs             []*nameLogger
+	asyncPolicy         int32
+	dropped             uint64
+	hooks               []Hook
+	hooksLock           sync.RWMutex
+	samplers            atomic.Value // [LevelDebug + 1]*Sampler
+	samplersLock        sync.Mutex
 }
 
+// Entry is a log entry passed to hooks.
+type Entry struct {
+	Level  int
+	Msg    string
+	Fields []Field
+	When   time.Time
+	Caller string // like "main.go:10", empty if func call depth is disabled
+}
+
+// Hook is called for every entry in the background writer goroutine before it
+// reaches the adapters. It may modify the entry (e.g. add fields) or return
+// false to suppress it. Slow work like alerting should not block the hook.
+type Hook func(e *Entry) bool
+
 type nameLogger struct {
 	Logger
 	name string
@@ -118,6 +148,7 @@ type logMsg struct {
 	line   string
 	prefix string
 	msg    string
+	fields []Field
 	when   time.Time
 }
 
@@ -144,7 +175,9 @@ func NewLogger(channelLen int64) *BeeLogger {
 func (bl *BeeLogger) SetMsgChan(channelLen int64) {
 	bl.lock.Lock()
 	defer bl.lock.Unlock()
-	bl.flush()
+	// stop the running goroutine first, it flushes the old chan before exiting
+	bl.signalChan <- "stop"
+	bl.wg.Wait()
 	bl.signalChan = make(chan string, 1)
 	bl.msgChan = make(chan *logMsg, channelLen)
 	bl.wg.Add(1)
@@ -197,7 +230,41 @@ func (bl *BeeLogger) DelAdapter(adapterName string) error {
 	return nil
 }
 
+// AddHook adds a hook, hooks are called in the order they were added.
+func (bl *BeeLogger) AddHook(h Hook) {
+	bl.hooksLock.Lock()
+	bl.hooks = append(bl.hooks, h)
+	bl.hooksLock.Unlock()
+}
+
+// runHooks returns false if a hook suppresses the message.
+func (bl *BeeLogger) runHooks(lm *logMsg) bool {
+	bl.hooksLock.RLock()
+	hooks := bl.hooks
+	bl.hooksLock.RUnlock()
+	if len(hooks) == 0 {
+		return true
+	}
+	e := Entry{
+		Level:  lm.level,
+		Msg:    lm.msg,
+		Fields: lm.fields[:len(lm.fields):len(lm.fields)], // 追加字段时不改写调用者的切片
+		When:   lm.when,
+		Caller: strings.Trim(lm.line, "[]"),
+	}
+	for _, h := range hooks {
+		if !h(&e) {
+			return false
+		}
+	}
+	lm.msg, lm.fields = e.Msg, e.Fields
+	return true
+}
+
 func (bl *BeeLogger) writeToLoggers(lm *logMsg) {
+	if !bl.runHooks(lm) {
+		return
+	}
 	for _, l := range bl.outputs {
 		err := l.WriteMsg(*lm)
 		if err != nil {
@@ -206,7 +273,8 @@ func (bl *BeeLogger) writeToLoggers(lm *logMsg) {
 	}
 }
 
-func (bl *BeeLogger) writeMsg(level int, msg string) {
+// skip is the number of extra stack frames to skip when reporting the caller.
+func (bl *BeeLogger) writeMsg(skip int, level int, msg string, fields []Field) {
 	bl.lock.RLock()
 	defer bl.lock.RUnlock()
 	lm := logMsgPool.Get().(*logMsg)
@@ -214,7 +282,7 @@ func (bl *BeeLogger) writeMsg(level int, msg string) {
 	lm.level = level
 	lm.prefix = Prefix[level]
 	if bl.enableFuncCallDepth {
-		_, file, line, ok := runtime.Caller(bl.loggerFuncCallDepth)
+		_, file, line, ok := runtime.Caller(bl.loggerFuncCallDepth + skip)
 		if !ok {
 			file = "???"
 			line = 0
@@ -223,7 +291,35 @@ func (bl *BeeLogger) writeMsg(level int, msg string) {
 		lm.line = "[" + filename + ":" + strconv.FormatInt(int64(line), 10) + "]"
 	}
 	lm.msg = msg
-	bl.msgChan <- lm
+	lm.fields = fields
+	if atomic.LoadInt32(&bl.asyncPolicy) != AsyncDropOldest || cap(bl.msgChan) == 0 {
+		bl.msgChan <- lm
+		return
+	}
+	for {
+		select {
+		case bl.msgChan <- lm:
+			return
+		default:
+		}
+		select {
+		case old := <-bl.msgChan:
+			logMsgPool.Put(old)
+			atomic.AddUint64(&bl.dropped, 1)
+		default:
+		}
+	}
+}
+
+// SetAsyncPolicy sets what to do when the async message channel is full,
+// AsyncBlock(default) or AsyncDropOldest.
+func (bl *BeeLogger) SetAsyncPolicy(policy int) {
+	atomic.StoreInt32(&bl.asyncPolicy, int32(policy))
+}
+
+// Dropped returns the number of messages discarded by AsyncDropOldest.
+func (bl *BeeLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&bl.dropped)
 }
 
 // SetLevel Set log message level.
@@ -258,15 +354,15 @@ func (bl *BeeLogger) startLogger() {
 			bl.writeToLoggers(bm)
 			logMsgPool.Put(bm)
 		case sg := <-bl.signalChan:
-			// Now should only send "flush" or "close" to bl.signalChan
+			// Now should only send "flush", "stop" or "close" to bl.signalChan
 			bl.flush()
 			if sg == "close" {
 				for _, l := range bl.outputs {
 					l.Destroy()
 				}
 				bl.outputs = nil
-				gameOver = true
 			}
+			gameOver = sg == "close" || sg == "stop"
 			bl.wg.Done()
 		}
 		if gameOver {
@@ -276,87 +372,119 @@ func (bl *BeeLogger) startLogger() {
 }
 
 func (bl *BeeLogger) Sys(format string, v ...interface{}) {
-	bl.writeMsg(LevelSystem, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelSystem, fmt.Sprintf(format, v...), nil)
 }
 
 func (bl *BeeLogger) Fatal(format string, v ...interface{}) {
 	if LevelFatal > bl.level {
 		return
 	}
-	bl.writeMsg(LevelFatal, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelFatal, fmt.Sprintf(format, v...), nil)
 	bl.Flush()
 	os.Exit(1)
 }
 
 // Emergency Log EMERGENCY level message.
 func (bl *BeeLogger) Emergency(format string, v ...interface{}) {
-	if LevelEmergency > bl.level {
+	if LevelEmergency > bl.level || !bl.sample(LevelEmergency, format) {
 		return
 	}
-	bl.writeMsg(LevelEmergency, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelEmergency, fmt.Sprintf(format, v...), nil)
 }
 
 // Alert Log ALERT level message.
 func (bl *BeeLogger) Alert(format string, v ...interface{}) {
-	if LevelAlert > bl.level {
+	if LevelAlert > bl.level || !bl.sample(LevelAlert, format) {
 		return
 	}
-	bl.writeMsg(LevelAlert, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelAlert, fmt.Sprintf(format, v...), nil)
 }
 
 // Critical Log CRITICAL level message.
 func (bl *BeeLogger) Critical(format string, v ...interface{}) {
-	if LevelCritical > bl.level {
+	if LevelCritical > bl.level || !bl.sample(LevelCritical, format) {
 		return
 	}
-	bl.writeMsg(LevelCritical, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelCritical, fmt.Sprintf(format, v...), nil)
 }
 
 // Error Log ERROR level message.
 func (bl *BeeLogger) Error(format string, v ...interface{}) {
-	if LevelError > bl.level {
+	if LevelError > bl.level || !bl.sample(LevelError, format) {
 		return
 	}
-	bl.writeMsg(LevelError, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelError, fmt.Sprintf(format, v...), nil)
 }
 
 // Warn Log WARN level message.
 // compatibility alias for Warning()
 func (bl *BeeLogger) Warn(format string, v ...interface{}) {
-	if LevelWarning > bl.level {
+	if LevelWarning > bl.level || !bl.sample(LevelWarning, format) {
 		return
 	}
-	bl.writeMsg(LevelWarning, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelWarning, fmt.Sprintf(format, v...), nil)
 }
 
 // Notice Log NOTICE level message.
 func (bl *BeeLogger) Notice(format string, v ...interface{}) {
-	if LevelNotice > bl.level {
+	if LevelNotice > bl.level || !bl.sample(LevelNotice, format) {
 		return
 	}
-	bl.writeMsg(LevelNotice, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelNotice, fmt.Sprintf(format, v...), nil)
 }
 
 // Info Log INFO level message.
 // compatibility alias for Informational()
 func (bl *BeeLogger) Info(format string, v ...interface{}) {
-	if LevelInformational > bl.level {
+	if LevelInformational > bl.level || !bl.sample(LevelInformational, format) {
 		return
 	}
-	bl.writeMsg(LevelInformational, fmt.Sprintf(format, v...))
+	bl.writeMsg(0, LevelInformational, fmt.Sprintf(format, v...), nil)
 }
 
 // Debug Log DEBUG level message.
 func (bl *BeeLogger) Debug(format string, v ...interface{}) {
-	if LevelDebug > bl.level {
+	if LevelDebug > bl.level || !bl.sample(LevelDebug, format) {
+		return
+	}
+	bl.writeMsg(0, LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+// Enabled reports whether messages of the level will be logged.
+func (bl *BeeLogger) Enabled(level int) bool {
+	return level == LevelSystem || level <= bl.level
+}
+
+// Logf logs a formatted message of the level with fields.
+func (bl *BeeLogger) Logf(level int, fields []Field, format string, v ...interface{}) {
+	if !bl.Enabled(level) || !bl.sample(level, format) {
+		return
+	}
+	bl.writeMsg(0, level, fmt.Sprintf(format, v...), fields)
+}
+
+// Logw logs msg of the level with fields.
+func (bl *BeeLogger) Logw(level int, fields []Field, msg string) {
+	if !bl.Enabled(level) || !bl.sample(level, msg) {
+		return
+	}
+	bl.writeMsg(0, level, msg, fields)
+}
+
+// Output logs msg of the level with fields without checking the logger level,
+// for wrappers that filter levels by themselves; sampling still applies,
+// keyed by msg. skip is the number of extra
+// stack frames between the wrapper's exported method and Output.
+func (bl *BeeLogger) Output(skip int, level int, fields []Field, msg string) {
+	if !bl.sample(level, msg) {
 		return
 	}
-	bl.writeMsg(LevelDebug, fmt.Sprintf(format, v...))
+	bl.writeMsg(skip, level, msg, fields)
 }
 
 // 简单实现io.Writer接口
 func (bl *BeeLogger) Write(p []byte) (n int, err error) {
-	bl.writeMsg(LevelSystem, Bytes2String(p))
+	bl.writeMsg(0, LevelSystem, Bytes2String(p), nil)
 	return len(p), nil
 }
 
@@ -30,12 +30,18 @@ func newLogWriter(wr io.Writer) *logWriter {
 }
 
 func (lg *logWriter) println(lm *logMsg) {
+	lg.print(lm, "", false)
+}
+
+// printJSON writes the message as one line of JSON.
+func (lg *logWriter) printJSON(lm *logMsg) {
+	lg.print(lm, "json", false)
+}
+
+// print writes the message as one line in the format, see logMsg.encode.
+func (lg *logWriter) print(lm *logMsg, format string, colorful bool) {
+	b := lm.encode(format, colorful)
 	lg.Lock()
-	h, _ := formatTimeHeader(lm.when)
-	b := append(h, (lm.line + lm.prefix + " " + lm.msg)...)
-	if b[len(b)-1] != '\n' {
-		b = append(b, '\n')
-	}
 	lg.writer.Write(b)
 	lg.Unlock()
 }
@@ -0,0 +1,114 @@
+package logs
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ringWriter implements LoggerInterface.
+// It keeps the latest messages in memory, e.g. used as breadcrumbs of error reports.
+type ringWriter struct {
+	Size  int `json:"size"`
+	Level int `json:"level"`
+}
+
+// RecentMsg is a log message kept by the ring adapter.
+type RecentMsg struct {
+	Level string    `json:"level"`
+	Msg   string    `json:"msg"`
+	When  time.Time `json:"when"`
+}
+
+var (
+	ring     []RecentMsg
+	ringNext int
+	ringFull bool
+	ringLock sync.RWMutex
+
+	// LevelNames maps log levels to names.
+	LevelNames = map[int]string{
+		LevelSystem:        "system",
+		LevelFatal:         "fatal",
+		LevelEmergency:     "emergency",
+		LevelAlert:         "alert",
+		LevelCritical:      "critical",
+		LevelError:         "error",
+		LevelWarning:       "warning",
+		LevelNotice:        "notice",
+		LevelInformational: "info",
+		LevelDebug:         "debug",
+	}
+)
+
+// NewRing create a ringWriter returning as LoggerInterface.
+func NewRing() Logger {
+	return &ringWriter{
+		Size:  100,
+		Level: LevelDebug,
+	}
+}
+
+// Init ring logger with json config.
+// jsonConfig like '{"size":100,"level":LevelDebug}'.
+func (r *ringWriter) Init(jsonConfig string) error {
+	if len(jsonConfig) > 0 {
+		if err := json.Unmarshal([]byte(jsonConfig), r); err != nil {
+			return err
+		}
+	}
+	if r.Size <= 0 {
+		r.Size = 100
+	}
+	ringLock.Lock()
+	ring = make([]RecentMsg, r.Size)
+	ringNext = 0
+	ringFull = false
+	ringLock.Unlock()
+	return nil
+}
+
+// WriteMsg keeps the message in memory.
+func (r *ringWriter) WriteMsg(lm logMsg) error {
+	if lm.level > r.Level {
+		return nil
+	}
+	ringLock.Lock()
+	ring[ringNext] = RecentMsg{
+		Level: LevelNames[lm.level],
+		Msg:   lm.text(),
+		When:  lm.when,
+	}
+	ringNext++
+	if ringNext == len(ring) {
+		ringNext = 0
+		ringFull = true
+	}
+	ringLock.Unlock()
+	return nil
+}
+
+// Destroy implementing method. empty.
+func (r *ringWriter) Destroy() {
+
+}
+
+// Flush implementing method. empty.
+func (r *ringWriter) Flush() {
+
+}
+
+// Recent returns the messages kept by the ring adapter, oldest first.
+func Recent() []RecentMsg {
+	ringLock.RLock()
+	defer ringLock.RUnlock()
+	var msgs []RecentMsg
+	if ringFull {
+		msgs = append(msgs, ring[ringNext:]...)
+	}
+	return append(msgs, ring[:ringNext]...)
+}
+
+func init() {
+	Register("ring", NewRing)
+}
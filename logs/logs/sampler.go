@@ -0,0 +1,114 @@
+package logs
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingConfig limits repeated messages: within each Tick the first First
+// messages with the same key are logged, then every Thereafter-th one.
+// Thereafter 0 drops the rest of the tick.
+type SamplingConfig struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+const samplerSlots = 4096
+
+// Sampler counts messages per key in a fixed number of slots, so memory does
+// not grow with the number of distinct keys; rare hash collisions only make
+// sampling slightly more aggressive.
+type Sampler struct {
+	config   SamplingConfig
+	counters [samplerSlots]samplerCounter
+	dropped  uint64
+}
+
+type samplerCounter struct {
+	resetAt int64
+	n       uint64
+}
+
+// NewSampler creates a Sampler, Tick defaults to one second.
+func NewSampler(config SamplingConfig) *Sampler {
+	if config.Tick <= 0 {
+		config.Tick = time.Second
+	}
+	return &Sampler{config: config}
+}
+
+// Allow reports whether a message with key should be logged.
+func (s *Sampler) Allow(key string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	c := &s.counters[h.Sum32()%samplerSlots]
+	n := c.incr(time.Now().UnixNano(), int64(s.config.Tick))
+	if n <= uint64(s.config.First) {
+		return true
+	}
+	if s.config.Thereafter > 0 && (n-uint64(s.config.First))%uint64(s.config.Thereafter) == 0 {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return false
+}
+
+// Dropped returns the number of messages rejected by Allow.
+func (s *Sampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (c *samplerCounter) incr(now, tick int64) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.n, 1)
+	}
+	if atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+tick) {
+		atomic.StoreUint64(&c.n, 1)
+		return 1
+	}
+	return atomic.AddUint64(&c.n, 1)
+}
+
+// SetSampling sets sampling for messages of the level, keyed by the format
+// string (or msg for Logw), nil disables it. System and fatal messages are
+// never sampled.
+func (bl *BeeLogger) SetSampling(level int, config *SamplingConfig) {
+	bl.samplersLock.Lock()
+	defer bl.samplersLock.Unlock()
+	var samplers [LevelDebug + 1]*Sampler
+	if old, ok := bl.samplers.Load().([LevelDebug + 1]*Sampler); ok {
+		samplers = old
+	}
+	if config == nil {
+		samplers[level] = nil
+	} else {
+		samplers[level] = NewSampler(*config)
+	}
+	bl.samplers.Store(samplers)
+}
+
+// Sampled returns the number of messages dropped by the current samplers.
+func (bl *BeeLogger) Sampled() uint64 {
+	samplers, _ := bl.samplers.Load().([LevelDebug + 1]*Sampler)
+	var n uint64
+	for _, s := range samplers {
+		if s != nil {
+			n += s.Dropped()
+		}
+	}
+	return n
+}
+
+func (bl *BeeLogger) sample(level int, key string) bool {
+	if level <= LevelFatal || level > LevelDebug {
+		return true
+	}
+	samplers, _ := bl.samplers.Load().([LevelDebug + 1]*Sampler)
+	if s := samplers[level]; s != nil {
+		return s.Allow(key)
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(SamplingConfig{Tick: time.Hour, First: 3, Thereafter: 10})
+	var allowed int
+	for i := 0; i < 100; i++ {
+		if s.Allow("db timeout: %v") {
+			allowed++
+		}
+	}
+	// 前3条，之后第13、23...93条
+	if allowed != 3+9 {
+		t.Errorf("allowed = %d, want 12", allowed)
+	}
+	if !s.Allow("other key") {
+		t.Error("a different key should be counted separately")
+	}
+	if s.Dropped() != 88 {
+		t.Errorf("Dropped() = %d, want 88", s.Dropped())
+	}
+
+	s = NewSampler(SamplingConfig{Tick: 20 * time.Millisecond, First: 1})
+	if !s.Allow("k") || s.Allow("k") {
+		t.Fatal("only the first message of a tick should pass")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !s.Allow("k") {
+		t.Error("the counter should reset after the tick")
+	}
+}
+
+func TestBeeLoggerSampling(t *testing.T) {
+	bl := NewLogger(100)
+	bl.SetSampling(LevelError, &SamplingConfig{Tick: time.Hour, First: 2})
+	for i := 0; i < 5; i++ {
+		bl.Error("disk full on %s", "sda")
+		bl.Warn("not sampled")
+	}
+	bl.Sys("system messages are never sampled")
+	if n := bl.Sampled(); n != 3 {
+		t.Errorf("Sampled() = %d, want 3", n)
+	}
+	bl.SetSampling(LevelError, nil)
+	bl.Error("disk full on %s", "sda")
+	if n := bl.Sampled(); n != 0 {
+		t.Errorf("Sampled() after disabling = %d, want 0", n)
+	}
+}
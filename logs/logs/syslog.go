@@ -0,0 +1,225 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogWriter implements LoggerInterface.
+// It sends messages to a local or remote syslog server in RFC 5424 format.
+type syslogWriter struct {
+	sync.Mutex
+	Net      string `json:"net"`      // "udp", "tcp", "unixgram" or "unix", empty means the local syslog socket
+	Addr     string `json:"addr"`     // server address or socket path
+	Facility string `json:"facility"` // "user"(default), "daemon", "local0"~"local7"...
+	Tag      string `json:"tag"`      // APP-NAME, defaults to the program name
+	Hostname string `json:"hostname"` // defaults to os.Hostname()
+	Level    int    `json:"level"`
+
+	facility int
+	conn     net.Conn
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverity maps log levels to syslog severities.
+var syslogSeverity = map[int]int{
+	LevelSystem:        5, // notice
+	LevelFatal:         2, // crit
+	LevelEmergency:     0, // emerg
+	LevelAlert:         1, // alert
+	LevelCritical:      2, // crit
+	LevelError:         3, // err
+	LevelWarning:       4, // warning
+	LevelNotice:        5, // notice
+	LevelInformational: 6, // info
+	LevelDebug:         7, // debug
+}
+
+// NewSyslog create a syslogWriter returning as LoggerInterface.
+func NewSyslog() Logger {
+	return &syslogWriter{Level: LevelDebug}
+}
+
+// Init syslog writer with json config.
+// jsonConfig like:
+//
+//	{
+//	"net":"udp",
+//	"addr":"10.0.0.1:514",
+//	"facility":"local0",
+//	"tag":"myapp"
+//	}
+func (w *syslogWriter) Init(jsonConfig string) error {
+	if jsonConfig != "" {
+		if err := json.Unmarshal([]byte(jsonConfig), w); err != nil {
+			return err
+		}
+	}
+	if w.Facility == "" {
+		w.Facility = "user"
+	}
+	f, ok := syslogFacilities[w.Facility]
+	if !ok {
+		return fmt.Errorf("syslog: unknown facility %q", w.Facility)
+	}
+	w.facility = f
+	if w.Tag == "" {
+		w.Tag = filepath.Base(os.Args[0])
+	}
+	if w.Hostname == "" {
+		w.Hostname, _ = os.Hostname()
+	}
+	return w.connect()
+}
+
+func (w *syslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	if w.Net != "" {
+		conn, err := net.Dial(w.Net, w.Addr)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		return nil
+	}
+	// 本地syslog套接字
+	paths := []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+	if w.Addr != "" {
+		paths = []string{w.Addr}
+	}
+	for _, p := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, p); err == nil {
+				w.conn = conn
+				return nil
+			}
+		}
+	}
+	return errors.New("syslog: unable to connect to the local syslog server")
+}
+
+// WriteMsg sends the message to syslog, reconnecting once if the write fails.
+func (w *syslogWriter) WriteMsg(lm logMsg) error {
+	if lm.level > w.Level {
+		return nil
+	}
+	msg := w.format(&lm)
+	w.Lock()
+	defer w.Unlock()
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		if err = w.connect(); err != nil {
+			return err
+		}
+		_, err = w.conn.Write(msg)
+		return err
+	}
+	return nil
+}
+
+// format builds an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+// Stream transports use octet-counting framing (RFC 6587).
+func (w *syslogWriter) format(lm *logMsg) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(w.facility*8 + syslogSeverity[lm.level]))
+	buf.WriteString(">1 ")
+	buf.WriteString(lm.when.Format(time.RFC3339Nano))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderField(w.Hostname, 255))
+	buf.WriteByte(' ')
+	buf.WriteString(syslogHeaderField(w.Tag, 48))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(os.Getpid()))
+	buf.WriteString(" - ")
+	if len(lm.fields) == 0 {
+		buf.WriteByte('-')
+	} else {
+		buf.WriteString("[fields@32473")
+		for _, f := range lm.fields {
+			buf.WriteByte(' ')
+			buf.WriteString(syslogHeaderField(strings.Map(syslogParamName, f.Key), 32))
+			buf.WriteString(`="`)
+			buf.WriteString(syslogParamEscaper.Replace(fieldString(f.Value)))
+			buf.WriteByte('"')
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(lm.line)
+	if lm.line != "" {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(strings.TrimRight(lm.msg, "\n"))
+	if w.Net == "tcp" || w.Net == "tcp4" || w.Net == "tcp6" || w.Net == "unix" {
+		return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+	}
+	return buf.Bytes()
+}
+
+// syslogHeaderField returns s limited to printable US-ASCII and max bytes, "-" if empty.
+func syslogHeaderField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func syslogParamName(r rune) rune {
+	if r == '=' || r == ']' || r == '"' || r == ' ' {
+		return '_'
+	}
+	return r
+}
+
+var syslogParamEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// Flush implementing method. empty.
+func (w *syslogWriter) Flush() {
+}
+
+// Destroy close the connection.
+func (w *syslogWriter) Destroy() {
+	w.Lock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	w.Unlock()
+}
+
+func init() {
+	Register("syslog", NewSyslog)
+}
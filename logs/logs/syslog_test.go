@@ -0,0 +1,108 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogWriter(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	w := NewSyslog()
+	if err := w.Init(`{"net":"udp","addr":"` + udp.LocalAddr().String() + `","facility":"local0","tag":"app","hostname":"web1"}`); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+	when := time.Date(2016, 5, 1, 8, 30, 0, 0, time.UTC)
+	w.WriteMsg(logMsg{level: LevelWarning, msg: "disk low", when: when, fields: []Field{{"free", "1 GB"}, {"path", `C:\"x"`}}})
+
+	buf := make([]byte, 1024)
+	udp.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<132>1 2016-05-01T08:30:00Z web1 app ` + strconv.Itoa(os.Getpid()) +
+		` - [fields@32473 free="1 GB" path="C:\\\"x\""] disk low`
+	if got := string(buf[:n]); got != want {
+		t.Errorf("syslog message\n got %s\nwant %s", got, want)
+	}
+
+	// 流式传输使用octet-counting分帧
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w2 := NewSyslog()
+	if err := w2.Init(`{"net":"tcp","addr":"` + ln.Addr().String() + `","tag":"app","hostname":"web1"}`); err != nil {
+		t.Fatal(err)
+	}
+	defer w2.Destroy()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	w2.WriteMsg(logMsg{level: LevelError, msg: "boom", when: when})
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+	size, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ = strconv.Atoi(strings.TrimSpace(size))
+	frame := make([]byte, n)
+	io.ReadFull(r, frame)
+	want = `<11>1 2016-05-01T08:30:00Z web1 app ` + strconv.Itoa(os.Getpid()) + ` - - boom`
+	if string(frame) != want {
+		t.Errorf("tcp frame\n got %s\nwant %s", frame, want)
+	}
+}
+
+func TestJournaldWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "socket")
+	ln, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	w := NewJournald()
+	if err := w.Init(`{"socket":"` + sock + `","identifier":"app"}`); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+	w.WriteMsg(logMsg{level: LevelError, line: "[main.go:10]", msg: "line1\nline2", fields: []Field{{"user-id", 42}}})
+
+	buf := make([]byte, 1024)
+	ln.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := ln.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len("line1\nline2")))
+	want := "PRIORITY=3\nSYSLOG_IDENTIFIER=app\nMESSAGE\n" + string(size) + "line1\nline2\n" +
+		"CODE_FILE=main.go\nCODE_LINE=10\nUSER_ID=42\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("journal entry = %q, want %q", got, want)
+	}
+}
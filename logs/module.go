@@ -0,0 +1,296 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lessgo/lessgo/logs/logs"
+)
+
+// 具名模块日志，与默认日志共享输出适配器，但可独立设置级别。
+// 未单独设置级别时沿用默认日志的级别。
+type ModuleLogger struct {
+	module *module
+	fields []logs.Field
+}
+
+type module struct {
+	name  string
+	level int32 // DEBUG~OFF，-1表示沿用默认日志的级别
+}
+
+// 模块未单独设置级别
+const LevelInherit = -1
+
+var (
+	defaultLogger Logger
+	modules       = map[string]*module{}
+	modulesLock   sync.RWMutex
+)
+
+var levelNames = map[int]string{
+	LevelInherit: "inherit",
+	DEBUG:        "debug",
+	INFO:         "info",
+	WARN:         "warn",
+	ERROR:        "error",
+	FATAL:        "fatal",
+	OFF:          "off",
+}
+
+// 设置模块日志共享的默认日志
+func SetDefault(l Logger) {
+	modulesLock.Lock()
+	defaultLogger = l
+	modulesLock.Unlock()
+}
+
+// 返回名为name的模块日志，同名模块共享同一级别设置
+func GetLogger(name string) *ModuleLogger {
+	return &ModuleLogger{
+		module: getModule(name),
+		fields: []logs.Field{{Key: "module", Value: name}},
+	}
+}
+
+// 设置模块的日志级别(DEBUG~OFF)，LevelInherit表示恢复沿用默认日志的级别；
+// 模块可在GetLogger之前设置
+func SetModuleLevel(name string, l int) error {
+	if _, ok := levelNames[l]; !ok {
+		return fmt.Errorf("logs: invalid level %d", l)
+	}
+	atomic.StoreInt32(&getModule(name).level, int32(l))
+	return nil
+}
+
+// 返回所有模块当前的日志级别名称
+func ModuleLevels() map[string]string {
+	modulesLock.RLock()
+	defer modulesLock.RUnlock()
+	levels := make(map[string]string, len(modules))
+	for name, m := range modules {
+		levels[name] = levelNames[int(atomic.LoadInt32(&m.level))]
+	}
+	return levels
+}
+
+// 返回已创建的模块名称，按字母排序
+func Modules() []string {
+	modulesLock.RLock()
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	modulesLock.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// 解析级别名称：debug、info、warn、error、fatal、off、inherit
+func ParseLevel(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for l, name := range levelNames {
+		if name == s {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("logs: unknown level %q", s)
+}
+
+// 返回级别名称
+func LevelName(l int) string {
+	return levelNames[l]
+}
+
+func getModule(name string) *module {
+	modulesLock.RLock()
+	m := modules[name]
+	modulesLock.RUnlock()
+	if m != nil {
+		return m
+	}
+	modulesLock.Lock()
+	defer modulesLock.Unlock()
+	if m = modules[name]; m == nil {
+		m = &module{name: name, level: LevelInherit}
+		modules[name] = m
+	}
+	return m
+}
+
+// 返回默认日志，未设置时创建一个输出到控制台的日志
+func getDefault() Logger {
+	modulesLock.RLock()
+	l := defaultLogger
+	modulesLock.RUnlock()
+	if l != nil {
+		return l
+	}
+	modulesLock.Lock()
+	defer modulesLock.Unlock()
+	if defaultLogger == nil {
+		defaultLogger = NewLogger(1000)
+		defaultLogger.AddAdapter("console", "")
+	}
+	return defaultLogger
+}
+
+func (m *ModuleLogger) enabled(level int) bool {
+	if l := int(atomic.LoadInt32(&m.module.level)); l != LevelInherit {
+		return level == logs.LevelSystem || level <= ExchangeLevel(l)
+	}
+	if tl, ok := getDefault().(*TgLogger); ok {
+		return tl.BeeLogger.Enabled(level)
+	}
+	return true
+}
+
+// 输出日志，默认日志不是*TgLogger时交由其结构化或格式化接口输出(仍受其自身级别限制)
+func (m *ModuleLogger) output(level int, fields []logs.Field, msg string) {
+	if !m.enabled(level) {
+		return
+	}
+	l := getDefault()
+	if tl, ok := l.(*TgLogger); ok {
+		tl.BeeLogger.Output(1, level, fields, msg)
+		return
+	}
+	if sl, ok := l.(StructuredLogger); ok && level != logs.LevelSystem && level != logs.LevelFatal {
+		kv := make([]interface{}, len(fields))
+		for i, f := range fields {
+			kv[i] = Field(f)
+		}
+		switch levelFrom(level) {
+		case ERROR:
+			sl.Errorw(msg, kv...)
+		case WARN:
+			sl.Warnw(msg, kv...)
+		case INFO:
+			sl.Infow(msg, kv...)
+		default:
+			sl.Debugw(msg, kv...)
+		}
+		return
+	}
+	for _, f := range fields {
+		msg += fmt.Sprintf(" %s=%v", f.Key, f.Value)
+	}
+	switch level {
+	case logs.LevelSystem:
+		l.Sys("%s", msg)
+	case logs.LevelFatal:
+		l.Fatal("%s", msg)
+	case logs.LevelError:
+		l.Error("%s", msg)
+	case logs.LevelWarning:
+		l.Warn("%s", msg)
+	case logs.LevelInformational:
+		l.Info("%s", msg)
+	default:
+		l.Debug("%s", msg)
+	}
+}
+
+// 名称
+func (m *ModuleLogger) Name() string {
+	return m.module.name
+}
+
+// SetLevel设置的是该模块的级别，不影响默认日志
+func (m *ModuleLogger) SetLevel(l int) {
+	SetModuleLevel(m.module.name, l)
+}
+
+func (m *ModuleLogger) SetMsgChan(channelLen int64) {
+	getDefault().SetMsgChan(channelLen)
+}
+
+func (m *ModuleLogger) SetAsyncPolicy(policy int) {
+	getDefault().SetAsyncPolicy(policy)
+}
+
+func (m *ModuleLogger) SetSampling(level int, s *Sampling) {
+	getDefault().SetSampling(level, s)
+}
+
+func (m *ModuleLogger) AddHook(h Hook) {
+	getDefault().AddHook(h)
+}
+
+func (m *ModuleLogger) Flush() {
+	getDefault().Flush()
+}
+
+func (m *ModuleLogger) EnableFuncCallDepth(b bool) {
+	getDefault().EnableFuncCallDepth(b)
+}
+
+func (m *ModuleLogger) AddAdapter(adaptername string, config string) error {
+	return getDefault().AddAdapter(adaptername, config)
+}
+
+func (m *ModuleLogger) With(fields ...Field) StructuredLogger {
+	child := &ModuleLogger{
+		module: m.module,
+		fields: make([]logs.Field, len(m.fields), len(m.fields)+len(fields)),
+	}
+	copy(child.fields, m.fields)
+	for _, f := range fields {
+		child.fields = append(child.fields, logs.Field(f))
+	}
+	return child
+}
+
+func (m *ModuleLogger) Write(p []byte) (n int, err error) {
+	m.output(logs.LevelSystem, m.fields, logs.Bytes2String(p))
+	return len(p), nil
+}
+
+func (m *ModuleLogger) Sys(format string, v ...interface{}) {
+	m.output(logs.LevelSystem, m.fields, fmt.Sprintf(format, v...))
+}
+
+func (m *ModuleLogger) Fatal(format string, v ...interface{}) {
+	if !m.enabled(logs.LevelFatal) {
+		return
+	}
+	m.output(logs.LevelFatal, m.fields, fmt.Sprintf(format, v...))
+	getDefault().Flush()
+	os.Exit(1)
+}
+
+func (m *ModuleLogger) Error(format string, v ...interface{}) {
+	m.output(logs.LevelError, m.fields, fmt.Sprintf(format, v...))
+}
+
+func (m *ModuleLogger) Warn(format string, v ...interface{}) {
+	m.output(logs.LevelWarning, m.fields, fmt.Sprintf(format, v...))
+}
+
+func (m *ModuleLogger) Info(format string, v ...interface{}) {
+	m.output(logs.LevelInformational, m.fields, fmt.Sprintf(format, v...))
+}
+
+func (m *ModuleLogger) Debug(format string, v ...interface{}) {
+	m.output(logs.LevelDebug, m.fields, fmt.Sprintf(format, v...))
+}
+
+func (m *ModuleLogger) Errorw(msg string, kv ...interface{}) {
+	m.output(logs.LevelError, kvFields(m.fields, kv), msg)
+}
+
+func (m *ModuleLogger) Warnw(msg string, kv ...interface{}) {
+	m.output(logs.LevelWarning, kvFields(m.fields, kv), msg)
+}
+
+func (m *ModuleLogger) Infow(msg string, kv ...interface{}) {
+	m.output(logs.LevelInformational, kvFields(m.fields, kv), msg)
+}
+
+func (m *ModuleLogger) Debugw(msg string, kv ...interface{}) {
+	m.output(logs.LevelDebug, kvFields(m.fields, kv), msg)
+}
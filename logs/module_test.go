@@ -0,0 +1,81 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lessgo/lessgo/logs/logs"
+)
+
+func TestModuleLogger(t *testing.T) {
+	root := NewLogger(100)
+	root.AddAdapter("ring", `{"size":16}`)
+	root.SetLevel(WARN)
+	SetDefault(root)
+	defer SetDefault(nil)
+
+	router := GetLogger("router")
+	sql := GetLogger("sql")
+	router.Debug("hidden by the default level")
+	if err := SetModuleLevel("router", DEBUG); err != nil {
+		t.Fatal(err)
+	}
+	router.Debug("route %s", "/users")
+	sql.Debug("hidden: sql inherits warn")
+	sql.Warn("slow query")
+	SetModuleLevel("router", OFF)
+	router.Error("hidden by off")
+	root.(*TgLogger).BeeLogger.Flush()
+
+	var got []string
+	for _, m := range logs.Recent() {
+		got = append(got, m.Msg)
+	}
+	want := []string{"route /users module=router", "slow query module=sql"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+	if levels := ModuleLevels(); levels["router"] != "off" || levels["sql"] != "inherit" {
+		t.Errorf("ModuleLevels() = %v", levels)
+	}
+	if l, err := ParseLevel("Warn"); err != nil || l != WARN {
+		t.Errorf("ParseLevel(Warn) = %d, %v", l, err)
+	}
+}
+
+func TestLoggerHooks(t *testing.T) {
+	root := NewLogger(100)
+	root.AddAdapter("ring", `{"size":16}`)
+	var alerts []string
+	root.AddHook(func(e *Entry) bool {
+		if e.Level == ERROR {
+			alerts = append(alerts, e.Msg)
+		}
+		return true
+	})
+	root.AddHook(func(e *Entry) bool {
+		e.Fields = append(e.Fields, F("host", "web1"))
+		return true
+	})
+	root.AddHook(func(e *Entry) bool {
+		return !strings.HasPrefix(e.Msg, "health check")
+	})
+
+	sl := Structured(root).With(F("req", 7))
+	sl.Error("db down")
+	sl.Info("health check ok")
+	sl.Infow("served", "status", 200)
+	root.Flush()
+
+	var got []string
+	for _, m := range logs.Recent() {
+		got = append(got, m.Msg)
+	}
+	want := "db down req=7 host=web1|served req=7 status=200 host=web1"
+	if strings.Join(got, "|") != want {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+	if len(alerts) != 1 || alerts[0] != "db down" {
+		t.Errorf("alerts = %q", alerts)
+	}
+}
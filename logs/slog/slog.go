@@ -0,0 +1,72 @@
+//go:build go1.21
+// +build go1.21
+
+// Package slog adapts a log/slog Logger to lessgo's logs.Logger.
+//
+// Usage:
+//
+//	import (
+//		"log/slog"
+//		"os"
+//
+//		"github.com/lessgo/lessgo"
+//		lslog "github.com/lessgo/lessgo/logs/slog"
+//	)
+//
+//	lessgo.SetLogger(lslog.New(slog.New(slog.NewJSONHandler(os.Stdout, nil))))
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/lessgo/lessgo/logs"
+)
+
+// LevelFatal is the slog level used for logs.FATAL.
+const LevelFatal = slog.LevelError + 4
+
+var levels = map[int]slog.Level{
+	logs.DEBUG: slog.LevelDebug,
+	logs.INFO:  slog.LevelInfo,
+	logs.WARN:  slog.LevelWarn,
+	logs.ERROR: slog.LevelError,
+	logs.FATAL: LevelFatal,
+}
+
+type backend struct {
+	l *slog.Logger
+}
+
+// New returns a logs.StructuredLogger backed by l.
+func New(l *slog.Logger) logs.StructuredLogger {
+	return logs.NewBackendLogger(backend{l})
+}
+
+func (b backend) Enabled(level int) bool {
+	return b.l.Enabled(context.Background(), levels[level])
+}
+
+func (b backend) Log(level int, msg string, fields []logs.Field) {
+	b.l.LogAttrs(context.Background(), levels[level], msg, attrs(fields)...)
+}
+
+func (b backend) With(fields []logs.Field) logs.Backend {
+	args := make([]interface{}, len(fields))
+	for i, a := range attrs(fields) {
+		args[i] = a
+	}
+	return backend{b.l.With(args...)}
+}
+
+func (b backend) Sync() error {
+	return nil
+}
+
+func attrs(fields []logs.Field) []slog.Attr {
+	as := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		as[i] = slog.Any(f.Key, f.Value)
+	}
+	return as
+}
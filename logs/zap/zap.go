@@ -0,0 +1,70 @@
+// Package zap adapts a go.uber.org/zap Logger to lessgo's logs.Logger.
+//
+// Usage:
+//
+//	z, _ := zap.NewProduction(zap.AddCallerSkip(3))
+//	lessgo.SetLogger(lzap.New(z))
+//
+// AddCallerSkip(3) makes zap report the caller of the lessgo logger
+// instead of the adapter.
+package zap
+
+import (
+	"time"
+
+	"github.com/lessgo/lessgo/logs"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var levels = map[int]zapcore.Level{
+	logs.DEBUG: zapcore.DebugLevel,
+	logs.INFO:  zapcore.InfoLevel,
+	logs.WARN:  zapcore.WarnLevel,
+	logs.ERROR: zapcore.ErrorLevel,
+	logs.FATAL: zapcore.FatalLevel,
+}
+
+type backend struct {
+	l *zap.Logger
+}
+
+// New returns a logs.StructuredLogger backed by l.
+func New(l *zap.Logger) logs.StructuredLogger {
+	return logs.NewBackendLogger(backend{l})
+}
+
+func (b backend) Enabled(level int) bool {
+	return b.l.Core().Enabled(levels[level])
+}
+
+func (b backend) Log(level int, msg string, fields []logs.Field) {
+	if level == logs.FATAL {
+		// 经由core写出，避免zap自身退出进程，由logs.Logger.Fatal同步后退出
+		ent := zapcore.Entry{Level: zapcore.FatalLevel, Time: time.Now(), Message: msg}
+		if ce := b.l.Core().Check(ent, nil); ce != nil {
+			ce.Write(zapFields(fields)...)
+		}
+		return
+	}
+	if ce := b.l.Check(levels[level], msg); ce != nil {
+		ce.Write(zapFields(fields)...)
+	}
+}
+
+func (b backend) With(fields []logs.Field) logs.Backend {
+	return backend{b.l.With(zapFields(fields)...)}
+}
+
+func (b backend) Sync() error {
+	return b.l.Sync()
+}
+
+func zapFields(fields []logs.Field) []zap.Field {
+	zf := make([]zap.Field, len(fields))
+	for i, f := range fields {
+		zf[i] = zap.Any(f.Key, f.Value)
+	}
+	return zf
+}
@@ -0,0 +1,46 @@
+package lessgo
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// 长轮询：保持连接直到source(任意类型的接收通道)中有数据或超时。
+// 收到数据时调用encode写出响应(为nil时以JSON格式输出)；
+// 超时或通道关闭时响应204，客户端断开时不写出任何内容并返回nil。
+func (c *Context) LongPoll(timeout time.Duration, source interface{}, encode func(v interface{}) error) error {
+	ch := reflect.ValueOf(source)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		panic("LongPoll: source must be a receivable channel")
+	}
+	if encode == nil {
+		encode = func(v interface{}) error {
+			return c.JSON(http.StatusOK, v)
+		}
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+	if closed := c.response.CloseNotify(); closed != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(closed)})
+	}
+
+	chosen, v, ok := reflect.Select(cases)
+	switch chosen {
+	case 0:
+		if !ok {
+			return c.NoContent(http.StatusNoContent)
+		}
+		return encode(v.Interface())
+	case 1:
+		return c.NoContent(http.StatusNoContent)
+	}
+	// 客户端已断开
+	c.response.committed = true
+	return nil
+}
@@ -0,0 +1,67 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 可模拟客户端断开的ResponseWriter
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (r *closeNotifyRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+func TestLongPoll(t *testing.T) {
+	closedCh := make(chan string)
+	close(closedCh)
+	for _, test := range []struct {
+		name       string
+		source     chan string
+		send       bool
+		disconnect bool
+		code       int
+		body       string
+	}{
+		{name: "data", source: make(chan string, 1), send: true, code: http.StatusOK, body: `"hello"`},
+		{name: "timeout", source: make(chan string), code: http.StatusNoContent},
+		{name: "closed channel", source: closedCh, code: http.StatusNoContent},
+		{name: "disconnect", source: make(chan string), disconnect: true, code: http.StatusOK},
+	} {
+		req, _ := http.NewRequest("GET", "/poll", nil)
+		c, _ := testContext(req)
+		rec := &closeNotifyRecorder{httptest.NewRecorder(), make(chan bool, 1)}
+		c.response.writer = rec
+		if test.send {
+			test.source <- "hello"
+		}
+		if test.disconnect {
+			rec.closed <- true
+		}
+		if err := c.LongPoll(50*time.Millisecond, test.source, nil); err != nil {
+			t.Errorf("%s: LongPoll() error = %v", test.name, err)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); rec.Code != test.code || got != test.body {
+			t.Errorf("%s: got %d %q, want %d %q", test.name, rec.Code, got, test.code, test.body)
+		}
+		if !c.response.Committed() {
+			t.Errorf("%s: response should be committed", test.name)
+		}
+		c.free()
+	}
+
+	// 不支持CloseNotifier的ResponseWriter不应引发恐慌
+	req, _ := http.NewRequest("GET", "/poll", nil)
+	c, rec := testContext(req)
+	c.response.writer = responseWriterWrapper{struct{ http.ResponseWriter }{rec}}
+	if err := c.LongPoll(10*time.Millisecond, make(chan int), nil); err != nil || rec.Code != http.StatusNoContent {
+		t.Errorf("LongPoll() without CloseNotifier = %v, %d", err, rec.Code)
+	}
+	c.free()
+}
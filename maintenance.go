@@ -0,0 +1,108 @@
+package lessgo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// MaintenanceConfig defines the config for maintenance mode middleware.
+type MaintenanceConfig struct {
+	// 建议客户端重试的等待时长，单位秒
+	RetryAfter int
+
+	// 维护期间仍允许访问的路径前缀，如健康检查与后台管理
+	AllowPaths []string
+
+	// 维护期间仍允许访问的IP或CIDR网段
+	AllowIPs []string
+
+	// 可信代理的IP或CIDR网段，仅信任来自这些地址的X-Forwarded-For与X-Real-IP
+	TrustedProxies []string
+
+	// 返回给客户端的提示信息
+	Message string
+}
+
+// 维护模式开关
+var maintenance int32
+
+// 查询是否处于维护模式
+func Maintenance() bool {
+	return atomic.LoadInt32(&maintenance) == 1
+}
+
+// 开启维护模式
+func EnableMaintenance() {
+	atomic.StoreInt32(&maintenance, 1)
+	Log.Sys("Maintenance mode is enable.")
+}
+
+// 关闭维护模式
+func DisableMaintenance() {
+	atomic.StoreInt32(&maintenance, 0)
+	Log.Sys("Maintenance mode is disable.")
+}
+
+var MaintenanceMode = ApiMiddleware{
+	Name: "维护模式",
+	Desc: "维护模式开启时，除白名单路径与IP外的请求均返回503及Retry-After",
+	Config: MaintenanceConfig{
+		RetryAfter:     300,
+		AllowPaths:     []string{"/healthz", "/readyz"},
+		AllowIPs:       []string{"127.0.0.1", "::1"},
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+		Message:        "Service is under maintenance, please try again later.",
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(MaintenanceConfig)
+		nets := parseIPNets(config.AllowIPs, "MaintenanceMode: invalid AllowIPs item")
+		proxies := parseIPNets(config.TrustedProxies, "MaintenanceMode: invalid TrustedProxies item")
+		retryAfter := strconv.Itoa(config.RetryAfter)
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				if !Maintenance() {
+					return next(c)
+				}
+				p := c.request.URL.Path
+				for _, allow := range config.AllowPaths {
+					if strings.HasPrefix(p, allow) {
+						return next(c)
+					}
+				}
+				if ipNetsContain(nets, clientIP(c.request, proxies)) {
+					return next(c)
+				}
+				if config.RetryAfter > 0 {
+					c.response.Header().Set("Retry-After", retryAfter)
+				}
+				return c.String(http.StatusServiceUnavailable, config.Message)
+			}
+		}
+	},
+}.Reg()
+
+// 查询或切换维护模式的操作，供后台管理路由使用
+var MaintenanceHandler = ApiHandler{
+	Desc:   "查询或切换维护模式",
+	Method: "GET|PUT",
+	Params: []Param{
+		{"enable", "formData", false, false, "PUT时设置是否开启维护模式"},
+	},
+	Handler: func(c *Context) error {
+		if c.request.Method == PUT {
+			on, err := strconv.ParseBool(c.FormParam("enable"))
+			if err != nil {
+				return NewHTTPError(http.StatusBadRequest, "param \"enable\" must be a bool")
+			}
+			if on {
+				EnableMaintenance()
+			} else {
+				DisableMaintenance()
+			}
+		}
+		return c.JSON(http.StatusOK, map[string]bool{"maintenance": Maintenance()})
+	},
+}.Reg()
@@ -457,6 +457,7 @@ var mimemaps = map[string]string{
 	".wav":         "audio/wav",
 	".wb1":         "application/x-qpro",
 	".wbmp":        "image/vnd.wap.wbmp",
+	".webp":        "image/webp",
 	".web":         "application/vndxara",
 	".wiz":         "application/msword",
 	".wk1":         "application/x-123",
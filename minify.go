@@ -0,0 +1,550 @@
+package lessgo
+
+import (
+	"strings"
+)
+
+type (
+	// MinifyConfig defines the config for response minification middleware.
+	MinifyConfig struct {
+		// 需要压缩的Content-Type，支持text/html、text/css、application/javascript
+		Types []string
+
+		// 不压缩的路径前缀
+		SkipPaths []string
+	}
+
+	// 流式压缩器，跨多次Write保持解析状态
+	minifier interface {
+		// 将src压缩后追加到dst
+		minify(dst, src []byte) []byte
+		// 输出结束时写出暂存的内容
+		flush(dst []byte) []byte
+	}
+
+	// 对指定类型响应进行流式压缩的ResponseWriter
+	minifyWriter struct {
+		responseWriterWrapper
+		types   []string
+		m       minifier
+		decided bool
+		buf     []byte
+		in, out int64 // 接收的与实际写出的字节数
+	}
+)
+
+var Minify = ApiMiddleware{
+	Name: "响应压缩(Minify)",
+	Desc: "流式去除HTML/CSS/JS响应中的注释与多余空白，主要用于服务端渲染的模板输出",
+	Config: MinifyConfig{
+		Types:     []string{MIMETextHTML, MIMETextCSS},
+		SkipPaths: []string{},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(MinifyConfig)
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				p := c.request.URL.Path
+				for _, skip := range config.SkipPaths {
+					if strings.HasPrefix(p, skip) {
+						return next(c)
+					}
+				}
+				w := &minifyWriter{
+					responseWriterWrapper: responseWriterWrapper{c.response.writer},
+					types:                 config.Types,
+				}
+				c.response.writer = w
+				defer func() {
+					c.response.writer = w.ResponseWriter
+					w.close()
+					// 记录实际写出的字节数
+					c.response.size += w.out - w.in
+				}()
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 根据响应头选择压缩器
+func (w *minifyWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	h := w.Header()
+	if h.Get(HeaderContentEncoding) != "" {
+		return
+	}
+	ctype := h.Get(HeaderContentType)
+	if !matchContentType(ctype, w.types) {
+		return
+	}
+	switch {
+	case strings.HasPrefix(ctype, MIMETextHTML):
+		w.m = &htmlMinifier{}
+	case strings.HasPrefix(ctype, MIMETextCSS):
+		w.m = &cssMinifier{}
+	case strings.HasPrefix(ctype, MIMEApplicationJavaScript), strings.HasPrefix(ctype, "text/javascript"):
+		w.m = &jsMinifier{}
+	default:
+		return
+	}
+	h.Del(HeaderContentLength)
+}
+
+func (w *minifyWriter) WriteHeader(code int) {
+	w.decide()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *minifyWriter) Write(b []byte) (int, error) {
+	w.decide()
+	if w.m == nil {
+		n, err := w.ResponseWriter.Write(b)
+		w.in += int64(n)
+		w.out += int64(n)
+		return n, err
+	}
+	w.in += int64(len(b))
+	w.buf = w.m.minify(w.buf[:0], b)
+	if len(w.buf) > 0 {
+		n, err := w.ResponseWriter.Write(w.buf)
+		w.out += int64(n)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *minifyWriter) Flush() {
+	// 暂存内容须等待后续数据才能确定，此处只刷新已输出部分
+	w.responseWriterWrapper.Flush()
+}
+
+func (w *minifyWriter) close() {
+	if w.m == nil {
+		return
+	}
+	if b := w.m.flush(nil); len(b) > 0 {
+		n, _ := w.ResponseWriter.Write(b)
+		w.out += int64(n)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// HTML压缩：合并空白、去除注释(保留条件注释)，pre/textarea/script/style内容原样输出
+type htmlMinifier struct {
+	state   int
+	space   bool   // 有待输出的空白
+	tag     []byte // 当前标签
+	quote   byte
+	tail    [3]byte // 注释中最近的3个字节
+	rawEnd  string  // 原样输出区的结束标签，如"</pre"
+	matched []byte  // 已匹配的结束标签前缀
+	keep    bool    // 是否为需保留的条件注释
+}
+
+const (
+	htmlText = iota
+	htmlTag
+	htmlComment
+	htmlRaw
+)
+
+var htmlRawTags = []string{"pre", "textarea", "script", "style"}
+
+func (m *htmlMinifier) minify(dst, src []byte) []byte {
+	for _, c := range src {
+		switch m.state {
+		case htmlText:
+			switch {
+			case isSpace(c):
+				m.space = true
+			case c == '<':
+				m.state = htmlTag
+				m.tag = append(m.tag[:0], c)
+				m.quote = 0
+			default:
+				if m.space {
+					dst = append(dst, ' ')
+					m.space = false
+				}
+				dst = append(dst, c)
+			}
+
+		case htmlTag:
+			if m.quote != 0 {
+				m.tag = append(m.tag, c)
+				if c == m.quote {
+					m.quote = 0
+				}
+				continue
+			}
+			if len(m.tag) == 4 && string(m.tag) == "<!--" {
+				m.state = htmlComment
+				m.keep = c == '['
+				if m.keep {
+					dst = m.flushSpace(dst)
+					dst = append(dst, "<!--["...)
+				}
+				m.tail = [3]byte{}
+				continue
+			}
+			switch {
+			case c == '"' || c == '\'':
+				m.quote = c
+				m.tag = append(m.tag, c)
+			case isSpace(c):
+				if last := m.tag[len(m.tag)-1]; !isSpace(last) {
+					m.tag = append(m.tag, ' ')
+				}
+			case c == '>':
+				if n := len(m.tag); isSpace(m.tag[n-1]) {
+					m.tag = m.tag[:n-1]
+				}
+				m.tag = append(m.tag, c)
+				dst = m.flushSpace(dst)
+				dst = append(dst, m.tag...)
+				m.state = htmlText
+				if name := htmlTagName(m.tag); name != "" && m.tag[len(m.tag)-2] != '/' {
+					for _, raw := range htmlRawTags {
+						if name == raw {
+							m.state = htmlRaw
+							m.rawEnd = "</" + raw
+							m.matched = m.matched[:0]
+						}
+					}
+				}
+			default:
+				m.tag = append(m.tag, c)
+			}
+
+		case htmlComment:
+			if m.keep {
+				dst = append(dst, c)
+			}
+			m.tail[0], m.tail[1], m.tail[2] = m.tail[1], m.tail[2], c
+			if m.tail == [3]byte{'-', '-', '>'} {
+				m.state = htmlText
+			}
+
+		case htmlRaw:
+			// 匹配结束标签(不区分大小写)
+			if lower(c) == m.rawEnd[len(m.matched)] {
+				m.matched = append(m.matched, c)
+				if len(m.matched) == len(m.rawEnd) {
+					m.state = htmlTag
+					m.tag = append(m.tag[:0], m.matched...)
+					m.quote = 0
+				}
+				continue
+			}
+			dst = append(dst, m.matched...)
+			m.matched = m.matched[:0]
+			if c == '<' {
+				m.matched = append(m.matched, c)
+				continue
+			}
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func (m *htmlMinifier) flushSpace(dst []byte) []byte {
+	if m.space {
+		dst = append(dst, ' ')
+		m.space = false
+	}
+	return dst
+}
+
+func (m *htmlMinifier) flush(dst []byte) []byte {
+	switch m.state {
+	case htmlTag:
+		dst = append(dst, m.tag...)
+	case htmlRaw:
+		dst = append(dst, m.matched...)
+	}
+	return dst
+}
+
+// 开始标签的小写名称，结束标签、注释等返回空
+func htmlTagName(tag []byte) string {
+	if len(tag) < 2 || !isIdentByte(tag[1]) {
+		return ""
+	}
+	end := 1
+	for end < len(tag) && (isIdentByte(tag[end]) || tag[end] == '-') {
+		end++
+	}
+	return strings.ToLower(string(tag[1:end]))
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// CSS压缩：去除注释，合并空白，去掉符号两侧及"}"前多余的";"
+type cssMinifier struct {
+	state int
+	space bool
+	slash bool // 暂存的"/"
+	semi  bool // 暂存的";"
+	star  bool // 注释中上一个字节为"*"
+	quote byte
+	esc   bool
+	last  byte // 上一个输出的字节
+}
+
+const (
+	cssNormal = iota
+	cssComment
+	cssString
+)
+
+func (m *cssMinifier) minify(dst, src []byte) []byte {
+	for _, c := range src {
+		switch m.state {
+		case cssComment:
+			if m.star && c == '/' {
+				m.state = cssNormal
+			}
+			m.star = c == '*'
+			continue
+		case cssString:
+			dst = append(dst, c)
+			if m.esc {
+				m.esc = false
+			} else if c == '\\' {
+				m.esc = true
+			} else if c == m.quote {
+				m.state = cssNormal
+			}
+			m.last = c
+			continue
+		}
+
+		if m.slash {
+			m.slash = false
+			if c == '*' {
+				m.state = cssComment
+				m.star = false
+				continue
+			}
+			dst = m.emit(dst, '/')
+		}
+		switch {
+		case c == '/':
+			m.slash = true
+		case isSpace(c):
+			m.space = true
+		case c == ';':
+			m.space = false
+			if m.semi {
+				continue
+			}
+			m.semi = true
+		default:
+			dst = m.emit(dst, c)
+			if c == '"' || c == '\'' {
+				m.state = cssString
+				m.quote = c
+			}
+		}
+	}
+	return dst
+}
+
+func (m *cssMinifier) emit(dst []byte, c byte) []byte {
+	if m.semi {
+		m.semi = false
+		if c != '}' {
+			dst = append(dst, ';')
+			m.last = ';'
+		}
+	}
+	if m.space {
+		m.space = false
+		if m.last != 0 && !strings.ContainsRune("{}:;,>", rune(m.last)) && !strings.ContainsRune("{};,>", rune(c)) {
+			dst = append(dst, ' ')
+		}
+	}
+	m.last = c
+	return append(dst, c)
+}
+
+func (m *cssMinifier) flush(dst []byte) []byte {
+	if m.slash {
+		dst = append(dst, '/')
+	}
+	if m.semi {
+		dst = append(dst, ';')
+	}
+	return dst
+}
+
+// JS压缩(保守)：去除注释，合并空白，保留换行以免影响自动分号插入
+type jsMinifier struct {
+	state   int
+	space   bool
+	newline bool
+	slash   bool // 暂存的"/"
+	star    bool
+	quote   byte
+	esc     bool
+	class   bool   // 正则中的字符类[...]
+	last    byte   // 上一个输出的非空白字节
+	word    []byte // 上一个输出的标识符或关键字
+}
+
+// 其后的"/"为正则表达式开始的关键字
+var jsRegexpKeywords = []string{
+	"return", "typeof", "instanceof", "in", "of", "new", "delete", "void",
+	"throw", "case", "do", "else", "yield", "await",
+}
+
+const (
+	jsNormal = iota
+	jsLineComment
+	jsBlockComment
+	jsString
+	jsRegexp
+)
+
+func (m *jsMinifier) minify(dst, src []byte) []byte {
+	for _, c := range src {
+		switch m.state {
+		case jsLineComment:
+			if c == '\n' {
+				m.state = jsNormal
+				m.newline = true
+			}
+			continue
+		case jsBlockComment:
+			if m.star && c == '/' {
+				m.state = jsNormal
+				m.space = true
+			} else if c == '\n' {
+				m.newline = true
+			}
+			m.star = c == '*'
+			continue
+		case jsString:
+			dst = append(dst, c)
+			if m.esc {
+				m.esc = false
+			} else if c == '\\' {
+				m.esc = true
+			} else if c == m.quote {
+				m.state = jsNormal
+				m.last = c
+			}
+			continue
+		case jsRegexp:
+			dst = append(dst, c)
+			switch {
+			case m.esc:
+				m.esc = false
+			case c == '\\':
+				m.esc = true
+			case c == '[':
+				m.class = true
+			case c == ']':
+				m.class = false
+			case c == '/' && !m.class:
+				m.state = jsNormal
+				m.last = c
+			}
+			continue
+		}
+
+		if m.slash {
+			m.slash = false
+			switch c {
+			case '/':
+				m.state = jsLineComment
+				continue
+			case '*':
+				m.state = jsBlockComment
+				m.star = false
+				continue
+			}
+			regexp := m.last == 0 || strings.IndexByte("(,=:[!&|?{};+-*%<>~^", m.last) >= 0 ||
+				isIdentByte(m.last) && m.isKeyword(jsRegexpKeywords)
+			dst = m.emit(dst, '/')
+			if regexp {
+				m.state = jsRegexp
+				m.esc, m.class = false, false
+				dst = m.minify(dst, []byte{c})
+				continue
+			}
+		}
+		switch {
+		case c == '/':
+			m.slash = true
+		case c == '\n' || c == '\r':
+			m.newline = true
+		case isSpace(c):
+			m.space = true
+		default:
+			dst = m.emit(dst, c)
+			if c == '"' || c == '\'' || c == '`' {
+				m.state = jsString
+				m.quote = c
+			}
+		}
+	}
+	return dst
+}
+
+func (m *jsMinifier) emit(dst []byte, c byte) []byte {
+	separated := m.space || m.newline
+	if m.newline && m.last != 0 {
+		dst = append(dst, '\n')
+	} else if m.space && (isIdentByte(m.last) && isIdentByte(c) || m.last == c && (c == '+' || c == '-')) {
+		dst = append(dst, ' ')
+	}
+	switch {
+	case !isIdentByte(c):
+		m.word = m.word[:0]
+	case separated || !isIdentByte(m.last):
+		m.word = append(m.word[:0], c)
+	default:
+		m.word = append(m.word, c)
+	}
+	m.space, m.newline = false, false
+	m.last = c
+	return append(dst, c)
+}
+
+func (m *jsMinifier) isKeyword(keywords []string) bool {
+	for _, k := range keywords {
+		if string(m.word) == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *jsMinifier) flush(dst []byte) []byte {
+	if m.slash {
+		dst = append(dst, '/')
+	}
+	return dst
+}
@@ -0,0 +1,59 @@
+package lessgo
+
+import "testing"
+
+// 逐字节输入，验证跨Write的状态保持
+func testMinify(newMinifier func() minifier, src string) (whole, stream string) {
+	m := newMinifier()
+	whole = string(m.flush(m.minify(nil, []byte(src))))
+	m = newMinifier()
+	var dst []byte
+	for i := 0; i < len(src); i++ {
+		dst = m.minify(dst, []byte{src[i]})
+	}
+	stream = string(m.flush(dst))
+	return
+}
+
+func TestMinify(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		new  func() minifier
+		src  string
+		want string
+	}{
+		{
+			"html", func() minifier { return &htmlMinifier{} },
+			"<html>\n  <body  class=\"a  b\" >\n    <!-- comment -->\n    <p>Hello,\n   world</p>\n<pre>\n  keep  </pre>\n<!--[if IE]><p>IE</p><![endif]-->\n</body>",
+			"<html> <body class=\"a  b\"> <p>Hello, world</p> <pre>\n  keep  </pre> <!--[if IE]><p>IE</p><![endif]--> </body>",
+		},
+		{
+			"html script", func() minifier { return &htmlMinifier{} },
+			"<script>\nif (a < b) {  x(); }\n</SCRIPT >\n<p> x </p>",
+			"<script>\nif (a < b) {  x(); }\n</SCRIPT> <p> x </p>",
+		},
+		{
+			"css", func() minifier { return &cssMinifier{} },
+			"/* header */\nbody , p > a {\n  color : red ;\n  font: 12px  \"Open  Sans\";\n}\na :hover { width: calc(1px + 2px); }",
+			"body,p>a{color :red;font:12px \"Open  Sans\"}a :hover{width:calc(1px + 2px)}",
+		},
+		{
+			"js", func() minifier { return &jsMinifier{} },
+			"// comment\nvar a = 1 ,  b = \"x  // y\";\n/* block */ var re = / +\\//g;\nc = a / b;\nd = a + +b;",
+			"var a=1,b=\"x  // y\";\nvar re=/ +\\//g;\nc=a/b;\nd=a+ +b;",
+		},
+		{
+			"js regexp after keyword", func() minifier { return &jsMinifier{} },
+			"function f(s) {\n  return /a  b/.test(s) ? typeof x / 2 : s;\n}",
+			"function f(s){\nreturn/a  b/.test(s)?typeof x/2:s;\n}",
+		},
+	} {
+		whole, stream := testMinify(test.new, test.src)
+		if whole != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, whole, test.want)
+		}
+		if stream != test.want {
+			t.Errorf("%s (streaming): got %q, want %q", test.name, stream, test.want)
+		}
+	}
+}
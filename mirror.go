@@ -0,0 +1,228 @@
+package lessgo
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+type (
+	// MirrorConfig defines the config for request mirroring middleware.
+	MirrorConfig struct {
+		// 影子服务地址，如"http://10.0.0.2:8080"
+		Upstream string
+
+		// 采样率，取值0~1
+		SampleRate float64
+
+		// 需要复制的请求方法，为空时全部复制
+		Methods []string
+
+		// 允许复制的最大请求体，超出时不复制该请求
+		MaxBodyBytes int64
+
+		// 影子请求超时，单位毫秒
+		TimeoutMS int64
+
+		// 并发发送数与队列长度，队列满时丢弃
+		Workers   int
+		QueueSize int
+	}
+
+	// 发送器的缓存键，配置相同的中间件实例共用发送器
+	mirrorSenderKey struct {
+		Upstream  string
+		Workers   int
+		TimeoutMS int64
+		QueueSize int
+	}
+
+	// 影子请求的发送器
+	mirrorSender struct {
+		key       mirrorSenderKey
+		upstream  *url.URL
+		client    *http.Client
+		queue     chan *http.Request
+		stop      chan struct{}
+		closeOnce sync.Once
+	}
+)
+
+// 标记影子请求的请求头
+const HeaderXShadowRequest = "X-Shadow-Request"
+
+var (
+	mirrorSenders      = map[mirrorSenderKey]*mirrorSender{}
+	mirrorSendersMux   sync.Mutex
+	mirrorShutdownOnce sync.Once
+)
+
+var Mirror = ApiMiddleware{
+	Name: "请求镜像",
+	Desc: "按采样率将请求(限制请求体大小)异步复制到影子服务并丢弃其响应，用于以线上流量安全验证新版本",
+	Config: MirrorConfig{
+		Upstream:     "",
+		SampleRate:   0.1,
+		Methods:      []string{},
+		MaxBodyBytes: 64 << 10, // 64 KB
+		TimeoutMS:    5000,
+		Workers:      4,
+		QueueSize:    1024,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(MirrorConfig)
+		sender, err := getMirrorSender(config)
+		if err != nil {
+			Log.Error("Mirror: %v", err)
+		}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				if sender == nil || req.Header.Get(HeaderXShadowRequest) != "" ||
+					(len(config.Methods) > 0 && !utils.InSlice(req.Method, config.Methods)) ||
+					(config.SampleRate < 1 && rand.Float64() >= config.SampleRate) {
+					return next(c)
+				}
+
+				var body []byte
+				if req.Body != nil {
+					body, _ = ioutil.ReadAll(io.LimitReader(req.Body, config.MaxBodyBytes+1))
+					if int64(len(body)) > config.MaxBodyBytes {
+						// 请求体过大，还原后不复制
+						req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+						return next(c)
+					}
+					req.Body = ioutil.NopCloser(bytes.NewReader(body))
+				}
+				sender.send(req, body, c.RealRemoteAddr())
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 返回配置对应的发送器；同一影子服务的配置变化后，关闭按旧配置创建的发送器
+func getMirrorSender(config MirrorConfig) (*mirrorSender, error) {
+	if config.Upstream == "" {
+		return nil, nil
+	}
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1024
+	}
+	key := mirrorSenderKey{
+		Upstream:  config.Upstream,
+		Workers:   config.Workers,
+		TimeoutMS: config.TimeoutMS,
+		QueueSize: config.QueueSize,
+	}
+	mirrorSendersMux.Lock()
+	defer mirrorSendersMux.Unlock()
+	if s, ok := mirrorSenders[key]; ok {
+		return s, nil
+	}
+	u, err := url.Parse(config.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	for k, old := range mirrorSenders {
+		if k.Upstream == key.Upstream {
+			delete(mirrorSenders, k)
+			old.close()
+		}
+	}
+	s := &mirrorSender{
+		key:      key,
+		upstream: u,
+		client: &http.Client{
+			Timeout: time.Duration(config.TimeoutMS) * time.Millisecond,
+			// 不跟随重定向
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		queue: make(chan *http.Request, config.QueueSize),
+		stop:  make(chan struct{}),
+	}
+	for i := 0; i < config.Workers; i++ {
+		go s.run()
+	}
+	mirrorSenders[key] = s
+	mirrorShutdownOnce.Do(func() {
+		app.onShutdown(closeMirrorSenders)
+	})
+	return s, nil
+}
+
+// 关闭全部发送器，队列中未发送的影子请求被丢弃
+func closeMirrorSenders() {
+	mirrorSendersMux.Lock()
+	defer mirrorSendersMux.Unlock()
+	for k, s := range mirrorSenders {
+		delete(mirrorSenders, k)
+		s.close()
+	}
+}
+
+// 停止发送协程，之后的影子请求被丢弃
+func (s *mirrorSender) close() {
+	s.closeOnce.Do(func() { close(s.stop) })
+}
+
+// 复制请求并放入发送队列
+func (s *mirrorSender) send(req *http.Request, body []byte, remoteAddr string) {
+	u := *req.URL
+	u.Scheme = s.upstream.Scheme
+	u.Host = s.upstream.Host
+	u.Path = strings.TrimSuffix(s.upstream.Path, "/") + req.URL.Path
+	shadow, err := http.NewRequest(req.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	for k, v := range req.Header {
+		shadow.Header[k] = v
+	}
+	shadow.Host = req.Host
+	shadow.Header.Set(HeaderXShadowRequest, "1")
+	if remoteAddr != "" {
+		shadow.Header.Set(HeaderXForwardedFor, remoteAddr)
+	}
+	select {
+	case <-s.stop:
+		return
+	default:
+	}
+	select {
+	case s.queue <- shadow:
+	default:
+		Log.Debug("Mirror: queue is full, request dropped")
+	}
+}
+
+func (s *mirrorSender) run() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case req := <-s.queue:
+			resp, err := s.client.Do(req)
+			if err != nil {
+				Log.Debug("Mirror: %v", err)
+				continue
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+}
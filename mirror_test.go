@@ -0,0 +1,102 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	defer closeMirrorSenders()
+	shadows := make(chan string, 4)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		shadows <- r.Method + " " + r.URL.Path + " " + r.Header.Get(HeaderXShadowRequest) + " " + string(body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	config := Mirror.Config.(MirrorConfig)
+	config.Upstream = upstream.URL + "/shadow"
+	config.SampleRate = 1
+	config.MaxBodyBytes = 8
+	h := Mirror.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+		body, _ := ioutil.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, string(body))
+	})
+	do := func(body string) string {
+		req, _ := http.NewRequest(POST, "/orders", strings.NewReader(body))
+		c, rec := testContext(req)
+		defer c.free()
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Body.String()
+	}
+
+	// 复制请求，影子服务的响应不影响客户端
+	if got := do("small"); got != "small" {
+		t.Errorf("response = %q", got)
+	}
+	select {
+	case got := <-shadows:
+		if got != "POST /shadow/orders 1 small" {
+			t.Errorf("shadow request = %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not mirrored")
+	}
+	// 请求体超出限制时不复制，但完整传给处理函数
+	if got := do("too large body"); got != "too large body" {
+		t.Errorf("response = %q", got)
+	}
+	select {
+	case got := <-shadows:
+		t.Errorf("oversized request mirrored: %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMirrorSenders(t *testing.T) {
+	defer closeMirrorSenders()
+	config := MirrorConfig{Upstream: "http://127.0.0.1:1", Workers: 1, QueueSize: 1, TimeoutMS: 100}
+	a, _ := getMirrorSender(config)
+	if b, _ := getMirrorSender(config); a != b {
+		t.Error("same config should share the sender")
+	}
+	other := config
+	other.Upstream = "http://127.0.0.1:2"
+	c, _ := getMirrorSender(other)
+
+	// 同一影子服务的配置变化后，按旧配置创建的发送器被关闭
+	config.Workers = 2
+	b, _ := getMirrorSender(config)
+	if a == b || b.key.Workers != 2 {
+		t.Fatalf("changed config reused the sender: %+v", b.key)
+	}
+	select {
+	case <-a.stop:
+	default:
+		t.Error("stale sender not closed")
+	}
+	req, _ := http.NewRequest(GET, "/", nil)
+	a.send(req, nil, "")
+	if len(a.queue) != 0 {
+		t.Error("closed sender queued a request")
+	}
+
+	closeMirrorSenders()
+	for _, s := range []*mirrorSender{b, c} {
+		select {
+		case <-s.stop:
+		default:
+			t.Errorf("sender for %s not closed", s.key.Upstream)
+		}
+	}
+	if len(mirrorSenders) != 0 {
+		t.Errorf("senders left: %d", len(mirrorSenders))
+	}
+}
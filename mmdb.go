@@ -0,0 +1,279 @@
+package lessgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+)
+
+// MaxMind DB(mmdb)格式的只读解析器
+// 格式说明见 http://maxmind.github.io/MaxMind-DB/
+type mmdbReader struct {
+	buf        []byte
+	data       []byte // 数据段
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+	dbType     string
+}
+
+// 数据最大嵌套深度，防止恶意文件中的循环引用
+const mmdbMaxDepth = 32
+
+var (
+	mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+	errMmdbInvalid     = errors.New("invalid MaxMind DB file")
+)
+
+func openMmdb(filename string) (*mmdbReader, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newMmdbReader(buf)
+}
+
+func newMmdbReader(buf []byte) (*mmdbReader, error) {
+	i := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, errMmdbInvalid
+	}
+	meta := buf[i+len(mmdbMetadataMarker):]
+	v, _, err := mmdbDecode(meta, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errMmdbInvalid
+	}
+	r := &mmdbReader{buf: buf}
+	r.nodeCount = mmdbUint(m["node_count"])
+	r.recordSize = mmdbUint(m["record_size"])
+	r.ipVersion = mmdbUint(m["ip_version"])
+	r.dbType, _ = m["database_type"].(string)
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", r.recordSize)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(i) {
+		return nil, errMmdbInvalid
+	}
+	r.data = buf[treeSize+16 : i]
+
+	// IPv6库中IPv4地址位于::/96之下
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < r.nodeCount; j++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// 查询IP对应的记录，未找到时返回nil
+func (r *mmdbReader) lookup(ip net.IP) (map[string]interface{}, error) {
+	node, bits := uint(0), 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = 32
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+	for i := 0; i < bits && node < r.nodeCount; i++ {
+		bit := uint(ip[i>>3]>>(7-uint(i&7))) & 1
+		node = r.readNode(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errMmdbInvalid
+	}
+	offset := node - r.nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, errMmdbInvalid
+	}
+	v, _, err := mmdbDecode(r.data, offset)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]interface{})
+	return m, nil
+}
+
+func (r *mmdbReader) readNode(node, bit uint) uint {
+	b := r.buf[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// 解码offset处的数据，返回值及其后的偏移
+func mmdbDecode(section []byte, offset uint) (interface{}, uint, error) {
+	return mmdbDecodeDepth(section, offset, 0)
+}
+
+func mmdbDecodeDepth(section []byte, offset uint, depth int) (interface{}, uint, error) {
+	if offset >= uint(len(section)) || depth > mmdbMaxDepth {
+		return nil, 0, errMmdbInvalid
+	}
+	ctrl := section[offset]
+	offset++
+	typ := ctrl >> 5
+	if typ == 1 {
+		// 指针
+		ss, vvv := uint(ctrl>>3)&3, uint(ctrl&7)
+		if offset+ss+1 > uint(len(section)) {
+			return nil, 0, errMmdbInvalid
+		}
+		b := section[offset : offset+ss+1]
+		var p uint
+		switch ss {
+		case 0:
+			p = vvv<<8 | uint(b[0])
+		case 1:
+			p = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 2:
+			p = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			p = uint(binary.BigEndian.Uint32(b))
+		}
+		// 指针不能指向另一个指针
+		if p >= uint(len(section)) || section[p]>>5 == 1 {
+			return nil, 0, errMmdbInvalid
+		}
+		v, _, err := mmdbDecodeDepth(section, p, depth+1)
+		return v, offset + ss + 1, err
+	}
+	if typ == 0 {
+		if offset >= uint(len(section)) {
+			return nil, 0, errMmdbInvalid
+		}
+		typ = 7 + section[offset]
+		offset++
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(section)) {
+			return nil, 0, errMmdbInvalid
+		}
+		var s uint
+		for _, c := range section[offset : offset+n] {
+			s = s<<8 | uint(c)
+		}
+		switch size {
+		case 29:
+			size = 29 + s
+		case 30:
+			size = 285 + s
+		default:
+			size = 65821 + s
+		}
+		offset += n
+	}
+
+	switch typ {
+	case 7, 11:
+		// 每个元素至少占一个字节
+		if size > uint(len(section))-offset {
+			return nil, 0, errMmdbInvalid
+		}
+	}
+	switch typ {
+	case 7: // map
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := mmdbDecodeDepth(section, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errMmdbInvalid
+			}
+			v, next, err := mmdbDecodeDepth(section, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			offset = next
+		}
+		return m, offset, nil
+	case 11: // array
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := mmdbDecodeDepth(section, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case 14: // boolean
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(section)) {
+		return nil, 0, errMmdbInvalid
+	}
+	b := section[offset : offset+size]
+	offset += size
+	switch typ {
+	case 2: // utf-8 string
+		return string(b), offset, nil
+	case 3: // double
+		if size != 8 {
+			return nil, 0, errMmdbInvalid
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 4: // bytes
+		return append([]byte(nil), b...), offset, nil
+	case 5, 6, 9: // uint16, uint32, uint64
+		var u uint64
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		return u, offset, nil
+	case 8: // int32
+		var u uint32
+		for _, c := range b {
+			u = u<<8 | uint32(c)
+		}
+		return int32(u), offset, nil
+	case 10: // uint128
+		return new(big.Int).SetBytes(b), offset, nil
+	case 15: // float
+		if size != 4 {
+			return nil, 0, errMmdbInvalid
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported MaxMind DB data type %d", typ)
+}
+
+func mmdbUint(v interface{}) uint {
+	u, _ := v.(uint64)
+	return uint(u)
+}
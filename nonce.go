@@ -0,0 +1,143 @@
+package lessgo
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// NonceConfig defines the config for anti-replay nonce middleware.
+	NonceConfig struct {
+		// 携带随机数与时间戳的请求头，TimestampHeader为空时不校验时间戳
+		NonceHeader     string
+		TimestampHeader string
+
+		// 随机数的有效窗口，单位秒，窗口内同一随机数只能使用一次
+		WindowSeconds int64
+
+		// 随机数的最小长度
+		MinLength int
+
+		// 可信代理的IP或CIDR网段，未经签名验证的请求按客户端IP区分随机数空间
+		TrustedProxies []string
+	}
+
+	// 随机数存储
+	NonceStore interface {
+		// 记录key并保留ttl时长，key在有效期内已存在时返回false
+		Use(key string, ttl time.Duration) (bool, error)
+	}
+
+	// 内存中的随机数存储
+	memoryNonceStore struct {
+		seen   map[string]time.Time
+		lastGC time.Time
+		sync.Mutex
+	}
+)
+
+const HeaderXNonce = "X-Nonce"
+
+// 随机数与签名记录的最短保留时长，避免时间窗口配置为0时无法防重放
+const minNonceTTL = 10 * time.Minute
+
+var (
+	nonceStore     NonceStore = NewMemoryNonceStore()
+	nonceStoreLock sync.RWMutex
+)
+
+var NonceVerify = ApiMiddleware{
+	Name: "防重放随机数",
+	Desc: "要求请求携带随机数，在时间窗口内拒绝重复的随机数，适用于敏感接口并可与HMAC签名验证配合使用",
+	Config: NonceConfig{
+		NonceHeader:     HeaderXNonce,
+		TimestampHeader: "X-Timestamp",
+		WindowSeconds:   300,
+		MinLength:       16,
+		TrustedProxies:  []string{"127.0.0.1", "::1"},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(NonceConfig)
+		window := time.Duration(config.WindowSeconds) * time.Second
+		proxies := parseIPNets(config.TrustedProxies, "NonceVerify: invalid TrustedProxies item")
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				nonce := req.Header.Get(config.NonceHeader)
+				if nonce == "" || len(nonce) < config.MinLength {
+					return NewHTTPError(http.StatusBadRequest, "missing or invalid nonce")
+				}
+				if config.TimestampHeader != "" {
+					unix, err := strconv.ParseInt(req.Header.Get(config.TimestampHeader), 10, 64)
+					if err != nil {
+						return NewHTTPError(http.StatusBadRequest, "invalid timestamp")
+					}
+					if d := time.Since(time.Unix(unix, 0)); d > window || d < -window {
+						return NewHTTPError(http.StatusUnauthorized, "timestamp out of range")
+					}
+				}
+
+				// 按签名客户端或IP区分随机数空间
+				scope, _ := c.Get("signatureKeyId").(string)
+				if scope == "" {
+					scope = clientIP(req, proxies).String()
+				}
+				ok, err := useNonce("nonce:"+scope+":"+nonce, 2*window)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return NewHTTPError(http.StatusConflict, "nonce has been used")
+				}
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 设置随机数存储(内部默认为内存存储)，同时用于签名验证的防重放
+func SetNonceStore(store NonceStore) {
+	nonceStoreLock.Lock()
+	nonceStore = store
+	nonceStoreLock.Unlock()
+}
+
+func useNonce(key string, ttl time.Duration) (bool, error) {
+	if ttl < minNonceTTL {
+		ttl = minNonceTTL
+	}
+	nonceStoreLock.RLock()
+	store := nonceStore
+	nonceStoreLock.RUnlock()
+	return store.Use(key, ttl)
+}
+
+// 创建内存中的随机数存储
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{seen: map[string]time.Time{}}
+}
+
+func (s *memoryNonceStore) Use(key string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	now := time.Now()
+	s.Lock()
+	defer s.Unlock()
+	if now.Sub(s.lastGC) > ttl {
+		s.lastGC = now
+		for k, exp := range s.seen {
+			if now.After(exp) {
+				delete(s.seen, k)
+			}
+		}
+	}
+	if exp, ok := s.seen[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.seen[key] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,55 @@
+package lessgo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	s := NewMemoryNonceStore().(*memoryNonceStore)
+	use := func(key string) bool {
+		ok, err := s.Use(key, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !use("a:1") {
+		t.Fatal("first use of a nonce should pass")
+	}
+	if use("a:1") {
+		t.Fatal("reused nonce should be rejected")
+	}
+	if !use("a:2") {
+		t.Fatal("another nonce should pass")
+	}
+	s.seen["a:1"] = time.Now().Add(-time.Second)
+	if !use("a:1") {
+		t.Fatal("expired nonce should be forgotten")
+	}
+}
+
+func TestSignatureReplayCache(t *testing.T) {
+	SetNonceStore(NewMemoryNonceStore())
+	defer SetNonceStore(NewMemoryNonceStore())
+	use := func(key string, ttl time.Duration) bool {
+		ok, err := useNonce(key, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !use("signature:a:sig1", time.Minute) {
+		t.Fatal("first use of a signature should pass")
+	}
+	if use("signature:a:sig1", time.Minute) {
+		t.Fatal("replayed signature should be rejected")
+	}
+	if !use("signature:a:sig2", time.Minute) {
+		t.Fatal("another signature should pass")
+	}
+	// SkewSeconds为0时仍需保留记录
+	if !use("signature:a:sig3", 0) || use("signature:a:sig3", 0) {
+		t.Fatal("signature should be remembered for the minimum TTL")
+	}
+}
@@ -0,0 +1,327 @@
+package lessgo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * OCSP装订(listen::ocspstapling)
+ * 从证书的OCSP服务获取已签名的吊销状态，握手时随证书发送，客户端无需再访问CA；
+ * 在响应有效期过半时刷新，获取失败时继续使用未过期的响应。
+ */
+
+type (
+	ocspStapler struct {
+		cert   atomic.Value // *tls.Certificate，含当前的OCSP响应
+		base   tls.Certificate
+		leaf   *x509.Certificate
+		issuer *x509.Certificate
+		expire time.Time // 当前OCSP响应的过期时间
+	}
+
+	// OCSP响应中的证书状态
+	ocspStatus struct {
+		revoked    bool
+		thisUpdate time.Time
+		nextUpdate time.Time
+	}
+
+	// RFC 6960中的ASN.1结构
+	ocspCertID struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		NameHash      []byte
+		IssuerKeyHash []byte
+		SerialNumber  *big.Int
+	}
+	ocspRequestEntry struct {
+		Cert ocspCertID
+	}
+	ocspTBSRequest struct {
+		Version     int `asn1:"explicit,tag:0,default:0,optional"`
+		RequestList []ocspRequestEntry
+	}
+	ocspRequest struct {
+		TBSRequest ocspTBSRequest
+	}
+	ocspResponse struct {
+		Status        asn1.Enumerated
+		ResponseBytes ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+	}
+	ocspResponseBytes struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	}
+	ocspBasicResponse struct {
+		TBSResponseData    ocspResponseData
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+		Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+	}
+	ocspResponseData struct {
+		Raw         asn1.RawContent
+		Version     int `asn1:"optional,default:0,explicit,tag:0"`
+		ResponderID asn1.RawValue
+		ProducedAt  time.Time `asn1:"generalized"`
+		Responses   []ocspSingleResponse
+		Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+	}
+	ocspSingleResponse struct {
+		CertID           ocspCertID
+		Good             asn1.Flag        `asn1:"tag:0,optional"`
+		Revoked          ocspRevokedInfo  `asn1:"tag:1,optional"`
+		Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+		ThisUpdate       time.Time        `asn1:"generalized"`
+		NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+		SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+	}
+	ocspRevokedInfo struct {
+		RevocationTime time.Time       `asn1:"generalized"`
+		Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+	}
+)
+
+var (
+	oidSHA1          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasic     = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	ocspSignatureAlg = map[string]x509.SignatureAlgorithm{
+		"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+		"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+		"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+		"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+		"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+		"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+		"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+		"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+		"1.3.101.112":           x509.PureEd25519,
+	}
+
+	// 访问OCSP服务与下载签发者证书的客户端
+	ocspHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// 为证书创建OCSP装订，证书链中没有签发者证书时按证书的签发者地址(AIA)下载
+func newOCSPStapler(cert tls.Certificate) (*ocspStapler, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("OCSP stapling: no certificate")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return nil, errors.New("OCSP stapling: the certificate has no OCSP server")
+	}
+	var issuer *x509.Certificate
+	if len(cert.Certificate) > 1 {
+		issuer, err = x509.ParseCertificate(cert.Certificate[1])
+	} else if len(leaf.IssuingCertificateURL) > 0 {
+		issuer, err = fetchIssuer(leaf.IssuingCertificateURL[0])
+	} else {
+		err = errors.New("OCSP stapling: the issuer certificate is not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &ocspStapler{base: cert, leaf: leaf, issuer: issuer}
+	s.cert.Store(&cert)
+	return s, nil
+}
+
+// 用作tls.Config.GetCertificate
+func (s *ocspStapler) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return s.cert.Load().(*tls.Certificate), nil
+}
+
+// 获取OCSP响应并更新证书，返回下次刷新前的等待时长
+func (s *ocspStapler) refresh() (time.Duration, error) {
+	now := time.Now()
+	der, status, err := fetchOCSP(s.leaf, s.issuer)
+	if err != nil {
+		if !s.expire.IsZero() && now.After(s.expire) {
+			// 不再发送已过期的响应
+			cert := s.base
+			s.cert.Store(&cert)
+			s.expire = time.Time{}
+		}
+		return 5 * time.Minute, err
+	}
+	if status.revoked {
+		Log.Error("OCSP stapling: the certificate %v has been revoked.", s.leaf.Subject)
+	}
+	cert := s.base
+	cert.OCSPStaple = der
+	s.cert.Store(&cert)
+	s.expire = status.nextUpdate
+
+	wait := time.Hour
+	if !status.nextUpdate.IsZero() {
+		wait = status.thisUpdate.Add(status.nextUpdate.Sub(status.thisUpdate) / 2).Sub(now)
+	}
+	if wait < time.Minute {
+		wait = time.Minute
+	}
+	if wait > 24*time.Hour {
+		wait = 24 * time.Hour
+	}
+	return wait, nil
+}
+
+// 定期刷新OCSP响应，直至stop关闭
+func (s *ocspStapler) run(stop <-chan struct{}) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+			wait, err := s.refresh()
+			if err != nil {
+				Log.Warn("OCSP stapling: %v", err)
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// 请求证书的OCSP响应并验证其签名与有效期
+func fetchOCSP(leaf, issuer *x509.Certificate) ([]byte, *ocspStatus, error) {
+	req, err := newOCSPRequest(leaf, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := ocspHTTPClient.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("OCSP server responded %s", resp.Status)
+	}
+	der, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	status, err := parseOCSPResponse(der, leaf, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, status, nil
+}
+
+func newOCSPRequest(leaf, issuer *x509.Certificate) ([]byte, error) {
+	id, err := newOCSPCertID(leaf, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ocspRequest{ocspTBSRequest{RequestList: []ocspRequestEntry{{id}}}})
+}
+
+func newOCSPCertID(leaf, issuer *x509.Certificate) (ocspCertID, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return ocspCertID{}, err
+	}
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(spki.PublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  leaf.SerialNumber,
+	}, nil
+}
+
+// 解析OCSP响应，验证签名者(签发者或由其授权的OCSP签名证书)、签名与有效期
+func parseOCSPResponse(der []byte, leaf, issuer *x509.Certificate) (*ocspStatus, error) {
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != 0 {
+		return nil, fmt.Errorf("OCSP response status %d", resp.Status)
+	}
+	if !resp.ResponseBytes.ResponseType.Equal(oidOCSPBasic) {
+		return nil, errors.New("unsupported OCSP response type")
+	}
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(resp.ResponseBytes.Response, &basic); err != nil {
+		return nil, err
+	}
+
+	signer := issuer
+	if len(basic.Certificates) > 0 {
+		cert, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(cert.Raw, issuer.Raw) {
+			if err = cert.CheckSignatureFrom(issuer); err != nil {
+				return nil, fmt.Errorf("OCSP responder certificate: %v", err)
+			}
+			delegated := false
+			for _, u := range cert.ExtKeyUsage {
+				delegated = delegated || u == x509.ExtKeyUsageOCSPSigning
+			}
+			if !delegated {
+				return nil, errors.New("OCSP responder certificate is not authorized for OCSP signing")
+			}
+			signer = cert
+		}
+	}
+	alg, ok := ocspSignatureAlg[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported OCSP signature algorithm %v", basic.SignatureAlgorithm.Algorithm)
+	}
+	if err := signer.CheckSignature(alg, basic.TBSResponseData.Raw, basic.Signature.RightAlign()); err != nil {
+		return nil, fmt.Errorf("OCSP response signature: %v", err)
+	}
+
+	for _, r := range basic.TBSResponseData.Responses {
+		if r.CertID.SerialNumber == nil || r.CertID.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+			continue
+		}
+		now := time.Now()
+		switch {
+		case bool(r.Unknown):
+			return nil, errors.New("OCSP status of the certificate is unknown")
+		case r.ThisUpdate.After(now.Add(5 * time.Minute)):
+			return nil, errors.New("OCSP response is not yet valid")
+		case !r.NextUpdate.IsZero() && r.NextUpdate.Before(now):
+			return nil, errors.New("OCSP response has expired")
+		}
+		return &ocspStatus{revoked: !bool(r.Good), thisUpdate: r.ThisUpdate, nextUpdate: r.NextUpdate}, nil
+	}
+	return nil, errors.New("OCSP response does not cover the certificate")
+}
+
+func fetchIssuer(url string) (*x509.Certificate, error) {
+	resp, err := ocspHTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading issuer certificate: %s", resp.Status)
+	}
+	der, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
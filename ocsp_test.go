@@ -0,0 +1,168 @@
+package lessgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert, key}
+}
+
+// 签发example.com的证书，证书链含CA证书
+func (ca *testCA) issue(t *testing.T, ocspServer string) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ocspServer != "" {
+		tpl.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}
+}
+
+// 按请求中的证书ID签发OCSP响应
+func (ca *testCA) ocspResponse(t *testing.T, reqDER []byte, revoked, tamper bool) []byte {
+	var req ocspRequest
+	if _, err := asn1.Unmarshal(reqDER, &req); err != nil {
+		t.Fatal(err)
+	}
+	id := req.TBSRequest.RequestList[0].Cert
+	now := time.Now().UTC().Truncate(time.Second)
+	single := ocspSingleResponse{CertID: id, ThisUpdate: now.Add(-time.Minute), NextUpdate: now.Add(2 * time.Hour)}
+	if revoked {
+		single.Revoked = ocspRevokedInfo{RevocationTime: now.Add(-time.Hour)}
+	} else {
+		single.Good = true
+	}
+	tbs, err := asn1.Marshal(ocspResponseData{
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: ca.cert.RawSubject},
+		ProducedAt:  now,
+		Responses:   []ocspSingleResponse{single},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(tbs)
+	sig, _ := ecdsa.SignASN1(rand.Reader, ca.key, digest[:])
+	if tamper {
+		tbs[len(tbs)-1] ^= 1
+	}
+	basic, err := asn1.Marshal(ocspBasicResponse{
+		TBSResponseData:    ocspResponseData{Raw: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature:          asn1.BitString{Bytes: sig, BitLength: len(sig) * 8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(ocspResponse{ResponseBytes: ocspResponseBytes{ResponseType: oidOCSPBasic, Response: basic}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestOCSPStapling(t *testing.T) {
+	ca := newTestCA(t)
+	var revoked, tamper bool
+	responder := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get(HeaderContentType) != "application/ocsp-request" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Write(ca.ocspResponse(t, b, revoked, tamper))
+	}))
+	defer responder.Close()
+
+	cert := ca.issue(t, responder.URL)
+	s, err := newOCSPStapler(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait, err := s.refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait < 50*time.Minute || wait > time.Hour {
+		t.Errorf("wait = %v", wait)
+	}
+	staple, _ := s.GetCertificate(nil)
+	if len(staple.OCSPStaple) == 0 {
+		t.Fatal("no staple")
+	}
+
+	// 握手时随证书发送
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	state := tlsConnect(t, &tls.Config{GetCertificate: s.GetCertificate}, &tls.Config{RootCAs: pool, ServerName: "example.com"})
+	if string(state.OCSPResponse) != string(staple.OCSPStaple) {
+		t.Errorf("OCSPResponse = %x", state.OCSPResponse)
+	}
+
+	// 签名无效时保留未过期的响应
+	tamper = true
+	if _, err = s.refresh(); err == nil {
+		t.Error("tampered response accepted")
+	}
+	if c, _ := s.GetCertificate(nil); string(c.OCSPStaple) != string(staple.OCSPStaple) {
+		t.Error("staple dropped before expiry")
+	}
+	tamper = false
+
+	revoked = true
+	if _, err = s.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	c, _ := s.GetCertificate(nil)
+	if status, err := parseOCSPResponse(c.OCSPStaple, leaf, ca.cert); err != nil || !status.revoked {
+		t.Errorf("status = %+v, %v", status, err)
+	}
+
+	if _, err = newOCSPStapler(ca.issue(t, "")); err == nil {
+		t.Error("certificate without OCSP server accepted")
+	}
+}
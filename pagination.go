@@ -0,0 +1,175 @@
+package lessgo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type (
+	// PaginationConfig defines the config for Paginate middleware.
+	PaginationConfig struct {
+		// 页码、每页条数与游标的URL参数名
+		PageParam   string
+		LimitParam  string
+		CursorParam string
+
+		// 默认与最大的每页条数
+		DefaultLimit int
+		MaxLimit     int
+	}
+
+	// 列表接口的分页参数，按页码或游标分页
+	Pagination struct {
+		Page   int    // 页码，从1开始
+		Limit  int    // 每页条数
+		Offset int    // 按页码分页时跳过的条数，即(Page-1)*Limit
+		Cursor string // 游标，不为空时应按游标分页并忽略Page与Offset
+		conf   PaginationConfig
+	}
+)
+
+const paginationKey = "__pagination__"
+
+var defaultPaginationConfig = PaginationConfig{
+	PageParam:    "page",
+	LimitParam:   "limit",
+	CursorParam:  "cursor",
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+// 解析分页参数供c.Pagination()使用，页码或每页条数不是整数时响应400，超出范围时取边界值
+var Paginate = ApiMiddleware{
+	Name:   "分页参数",
+	Desc:   "解析page/limit或cursor分页参数，供c.Pagination()使用，并可通过c.SetPageLinks写入Link与X-Total-Count响应头",
+	Config: defaultPaginationConfig,
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(PaginationConfig)
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				p, err := parsePagination(c, config)
+				if err != nil {
+					return c.String(http.StatusBadRequest, err.Error())
+				}
+				c.Set(paginationKey, p)
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 返回当前请求的分页参数；未使用Paginate中间件时按默认配置解析，非法的值取默认值
+func (c *Context) Pagination() *Pagination {
+	if p, ok := c.Get(paginationKey).(*Pagination); ok {
+		return p
+	}
+	p, _ := parsePagination(c, defaultPaginationConfig)
+	c.Set(paginationKey, p)
+	return p
+}
+
+func parsePagination(c *Context, config PaginationConfig) (*Pagination, error) {
+	if config.DefaultLimit <= 0 {
+		config.DefaultLimit = defaultPaginationConfig.DefaultLimit
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = defaultPaginationConfig.MaxLimit
+	}
+	p := &Pagination{Page: 1, Limit: config.DefaultLimit, conf: config}
+	var err error
+	if s := c.QueryParam(config.LimitParam); s != "" {
+		n, e := strconv.Atoi(s)
+		if e != nil {
+			err = NewHTTPError(http.StatusBadRequest, "invalid "+config.LimitParam)
+		} else if n > 0 {
+			p.Limit = n
+		}
+	}
+	if p.Limit > config.MaxLimit {
+		p.Limit = config.MaxLimit
+	}
+	if config.CursorParam != "" {
+		p.Cursor = c.QueryParam(config.CursorParam)
+	}
+	if s := c.QueryParam(config.PageParam); s != "" && p.Cursor == "" {
+		n, e := strconv.Atoi(s)
+		if e != nil {
+			err = NewHTTPError(http.StatusBadRequest, "invalid "+config.PageParam)
+		} else if n > 1 {
+			p.Page = n
+		}
+	}
+	// 避免Offset溢出
+	if max := int(^uint(0)>>1) / p.Limit; p.Page > max {
+		p.Page = max
+	}
+	p.Offset = (p.Page - 1) * p.Limit
+	return p, err
+}
+
+// 按总条数写入X-Total-Count与first、prev、next、last的Link响应头(RFC 5988)
+func (c *Context) SetPageLinks(total int64) {
+	p := c.Pagination()
+	header := c.response.Header()
+	header.Set(HeaderXTotalCount, strconv.FormatInt(total, 10))
+	last := int((total + int64(p.Limit) - 1) / int64(p.Limit))
+	if last < 1 {
+		last = 1
+	}
+	var links []string
+	link := func(page int, rel string) {
+		links = append(links, pageLink(c, map[string]string{
+			p.conf.PageParam:   strconv.Itoa(page),
+			p.conf.LimitParam:  strconv.Itoa(p.Limit),
+			p.conf.CursorParam: "",
+		}, rel))
+	}
+	link(1, "first")
+	if p.Page > 1 {
+		prev := p.Page - 1
+		if prev > last {
+			prev = last
+		}
+		link(prev, "prev")
+	}
+	if p.Page < last {
+		link(p.Page+1, "next")
+	}
+	link(last, "last")
+	header.Set(HeaderLink, strings.Join(links, ", "))
+}
+
+// 按游标分页时写入next与prev的Link响应头，游标为空时不写入对应的链接
+func (c *Context) SetCursorLinks(next, prev string) {
+	p := c.Pagination()
+	var links []string
+	for _, l := range []struct{ cursor, rel string }{{next, "next"}, {prev, "prev"}} {
+		if l.cursor != "" {
+			links = append(links, pageLink(c, map[string]string{
+				p.conf.CursorParam: l.cursor,
+				p.conf.LimitParam:  strconv.Itoa(p.Limit),
+				p.conf.PageParam:   "",
+			}, l.rel))
+		}
+	}
+	if len(links) > 0 {
+		c.response.Header().Set(HeaderLink, strings.Join(links, ", "))
+	}
+}
+
+// 以当前请求的路径与参数生成链接，params中值为空的参数被删除
+func pageLink(c *Context, params map[string]string, rel string) string {
+	q := c.request.URL.Query()
+	for k, v := range params {
+		if k == "" {
+			continue
+		}
+		if v == "" {
+			q.Del(k)
+		} else {
+			q.Set(k, v)
+		}
+	}
+	return "<" + c.request.URL.Path + "?" + q.Encode() + `>; rel="` + rel + `"`
+}
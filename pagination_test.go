@@ -0,0 +1,72 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	var got *Pagination
+	h := Paginate.Middleware.(Middleware).getMiddlewareFunc(PaginationConfig{
+		PageParam:    "page",
+		LimitParam:   "per_page",
+		CursorParam:  "cursor",
+		DefaultLimit: 10,
+		MaxLimit:     50,
+	})(func(c *Context) error {
+		got = c.Pagination()
+		return nil
+	})
+	for url, want := range map[string]Pagination{
+		"/items":                     {Page: 1, Limit: 10},
+		"/items?page=3&per_page=20":  {Page: 3, Limit: 20, Offset: 40},
+		"/items?page=0&per_page=500": {Page: 1, Limit: 50},
+		"/items?page=-2&per_page=-1": {Page: 1, Limit: 10},
+		"/items?page=3&cursor=abc":   {Page: 1, Limit: 10, Cursor: "abc"},
+	} {
+		req, _ := http.NewRequest(GET, url, nil)
+		c, rec := testContext(req)
+		got = nil
+		if err := h(c); err != nil || rec.Code != http.StatusOK || got == nil {
+			t.Fatalf("%s: %v, %d", url, err, rec.Code)
+		}
+		got.conf = PaginationConfig{}
+		if *got != want {
+			t.Errorf("%s = %+v, want %+v", url, *got, want)
+		}
+		c.free()
+	}
+
+	req, _ := http.NewRequest(GET, "/items?page=x", nil)
+	c, rec := testContext(req)
+	defer c.free()
+	if h(c); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid page: %d", rec.Code)
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	req, _ := http.NewRequest(GET, "/items?q=go&page=2&limit=10", nil)
+	c, rec := testContext(req)
+	defer c.free()
+	if p := c.Pagination(); p.Page != 2 || p.Limit != 10 || p.Offset != 10 {
+		t.Fatalf("Pagination = %+v", p)
+	}
+	c.SetPageLinks(35)
+	if got := rec.Header().Get(HeaderXTotalCount); got != "35" {
+		t.Errorf("X-Total-Count = %q", got)
+	}
+	want := `</items?limit=10&page=1&q=go>; rel="first", </items?limit=10&page=1&q=go>; rel="prev", ` +
+		`</items?limit=10&page=3&q=go>; rel="next", </items?limit=10&page=4&q=go>; rel="last"`
+	if got := rec.Header().Get(HeaderLink); got != want {
+		t.Errorf("Link = %s", got)
+	}
+
+	req, _ = http.NewRequest(GET, "/items?cursor=c1", nil)
+	c2, rec := testContext(req)
+	defer c2.free()
+	c2.SetCursorLinks("c2", "")
+	if got := rec.Header().Get(HeaderLink); got != `</items?cursor=c2&limit=20>; rel="next"` {
+		t.Errorf("cursor Link = %s", got)
+	}
+}
@@ -2,6 +2,7 @@ package lessgo
 
 import (
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -85,3 +86,33 @@ func TestPathCleanMallocs(t *testing.T) {
 		}
 	}
 }
+
+func FuzzCleanPath(f *testing.F) {
+	for _, test := range cleanTests {
+		f.Add(test.path)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		s := CleanPath(p)
+		if s == "" || s[0] != '/' {
+			t.Errorf("CleanPath(%q) = %q, want a rooted path", p, s)
+		}
+		if again := CleanPath(s); again != s {
+			t.Errorf("CleanPath(%q) = %q, not idempotent: %q", p, s, again)
+		}
+	})
+}
+
+func FuzzCleanPrefix(f *testing.F) {
+	for _, p := range []string{"", "/", "api", "/api/v1/", "/users/:id", "/a/../b", "/:x/y", "//a//"} {
+		f.Add(p)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		s := cleanPrefix(p)
+		if s == "" || s[0] != '/' || strings.Contains(s, ":") {
+			t.Errorf("cleanPrefix(%q) = %q, want a rooted path without params", p, s)
+		}
+		if again := cleanPrefix(s); again != s {
+			t.Errorf("cleanPrefix(%q) = %q, not idempotent: %q", p, s, again)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package lessgo
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// Context对象池的泄漏检测模式：
+// 开启后请求结束的Context不再放回对象池，而是标记为已归还，
+// 之后通过它读取请求、存取数据或写响应时，按模式记录错误日志或panic，并指明其所属请求的路由，
+// 用于排查处理函数中启动的协程在请求结束后仍持有Context导致的数据串用问题。
+// 以"lessgo_pooldebug"构建标签编译时默认以panic模式开启。
+const (
+	POOL_CHECK_OFF   = ""
+	POOL_CHECK_LOG   = "log"
+	POOL_CHECK_PANIC = "panic"
+)
+
+var poolCheckMode atomic.Value
+
+func init() {
+	poolCheckMode.Store(POOL_CHECK_OFF)
+}
+
+// 设置Context对象池的泄漏检测模式
+func SetPoolCheck(mode string) {
+	switch mode {
+	case POOL_CHECK_OFF, POOL_CHECK_LOG, POOL_CHECK_PANIC:
+		poolCheckMode.Store(mode)
+	default:
+		Log.Error("SetPoolCheck: unknown mode %q", mode)
+	}
+}
+
+// 返回Context对象池的泄漏检测模式
+func PoolCheck() string {
+	return poolCheckMode.Load().(string)
+}
+
+// 请求结束后标记Context为已归还，不再复用
+func (c *Context) release() {
+	route := "unknown request"
+	if c.request != nil && c.request.URL != nil {
+		route = c.request.Method + " " + c.request.URL.Path
+	}
+	c.free()
+	c.releasedBy = route
+	c.response.writer = releasedWriter{c}
+}
+
+// 检查Context是否已归还
+func (c *Context) checkReleased() {
+	if c.releasedBy == "" {
+		return
+	}
+	msg := "lessgo: Context of the completed request [" + c.releasedBy + "] is used after being returned to the pool"
+	if PoolCheck() == POOL_CHECK_PANIC {
+		panic(msg)
+	}
+	Log.Error("%s\n%s", msg, debug.Stack())
+}
+
+// 已归还Context的响应写入器，任何使用都会报告
+type releasedWriter struct {
+	c *Context
+}
+
+func (w releasedWriter) Header() http.Header {
+	w.c.checkReleased()
+	return http.Header{}
+}
+
+func (w releasedWriter) Write(b []byte) (int, error) {
+	w.c.checkReleased()
+	return 0, http.ErrHijacked
+}
+
+func (w releasedWriter) WriteHeader(int) {
+	w.c.checkReleased()
+}
@@ -0,0 +1,8 @@
+//go:build lessgo_pooldebug
+// +build lessgo_pooldebug
+
+package lessgo
+
+func init() {
+	SetPoolCheck(POOL_CHECK_PANIC)
+}
@@ -0,0 +1,50 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPoolCheck(t *testing.T) {
+	defer SetPoolCheck(PoolCheck())
+	SetPoolCheck(POOL_CHECK_PANIC)
+
+	var leaked *Context
+	req, _ := http.NewRequest("GET", "/poolcheck/1", nil)
+	c, _ := testContext(req)
+	leaked = c
+	c.Set("user", "gopher")
+	c.release()
+
+	for name, use := range map[string]func(){
+		"Request": func() { leaked.Request() },
+		"Get":     func() { leaked.Get("user") },
+		"String":  func() { leaked.String(http.StatusOK, "late") },
+	} {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, "[GET /poolcheck/1]") {
+					t.Errorf("%s: recovered %q", name, msg)
+				}
+			}()
+			use()
+		}()
+	}
+
+	// 日志模式下不panic
+	SetPoolCheck(POOL_CHECK_LOG)
+	if leaked.Get("user") != nil {
+		t.Error("released context kept its store")
+	}
+
+	// 检测模式下请求结束的Context不再复用
+	SetPoolCheck(POOL_CHECK_PANIC)
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	c = app.ctxPool.Get().(*Context)
+	if c.releasedBy != "" {
+		t.Error("released context returned to the pool")
+	}
+}
@@ -0,0 +1,32 @@
+package lessgo
+
+import (
+	"strings"
+)
+
+// 当前运行环境，由配置项Profile、环境变量LESSGO_PROFILE或命令行参数-lessgo.profile指定
+func Profile() string {
+	return Config.Profile
+}
+
+// 判断当前是否为指定的运行环境之一(不区分大小写)
+func IsProfile(profiles ...string) bool {
+	for _, p := range profiles {
+		if strings.EqualFold(p, Config.Profile) {
+			return true
+		}
+	}
+	return false
+}
+
+// 仅在指定的运行环境中注册虚拟路由节点，否则返回nil(Root与Branch将忽略)，如仅在开发环境开放调试接口：
+//
+//	lessgo.Root(
+//		lessgo.Profiled(lessgo.Leaf("/debug/vars", DebugVarsHandle), "dev"),
+//	)
+func Profiled(node *VirtRouter, profiles ...string) *VirtRouter {
+	if !IsProfile(profiles...) {
+		return nil
+	}
+	return node
+}
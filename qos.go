@@ -0,0 +1,209 @@
+package lessgo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// QoSConfig defines the config for priority admission control middleware.
+	QoSConfig struct {
+		// 全局同时处理的最大请求数
+		MaxInFlight int
+
+		// 优先级类别
+		Classes []QoSClass
+
+		// 路径前缀对应的类别，按最长前缀匹配
+		Routes map[string]string
+
+		// 携带类别名称的请求头(应由可信网关设置)，为空时不使用
+		Header string
+
+		// 携带API Key的请求头，以及API Key对应的类别
+		APIKeyHeader string
+		APIKeys      map[string]string
+
+		// 未匹配时使用的类别
+		DefaultClass string
+	}
+
+	// 优先级类别
+	QoSClass struct {
+		Name string
+
+		// 优先级，数值越大越优先获得处理
+		Priority int
+
+		// 本类别可使用的容量比例(0~1]，为高优先级请求预留余量
+		MaxUtilization float64
+
+		// 排队上限与最长等待时间(毫秒)，超出时直接拒绝
+		MaxQueue       int
+		QueueTimeoutMS int64
+	}
+
+	// 准入控制器，每个中间件实例独立计数
+	qosController struct {
+		max      int
+		inFlight int
+		waiters  []*qosWaiter
+		queued   map[string]int
+		sync.Mutex
+	}
+
+	qosWaiter struct {
+		ch       chan struct{}
+		class    string
+		priority int
+		limit    int
+		admitted bool
+	}
+)
+
+var QoS = ApiMiddleware{
+	Name: "优先级准入控制",
+	Desc: "按路由、请求头或API Key为请求分配优先级，饱和时低优先级请求先排队或被拒绝，保证健康检查与后台接口可用",
+	Config: QoSConfig{
+		MaxInFlight: 500,
+		Classes: []QoSClass{
+			{Name: "critical", Priority: 100, MaxUtilization: 1, MaxQueue: 100, QueueTimeoutMS: 5000},
+			{Name: "normal", Priority: 50, MaxUtilization: 0.9, MaxQueue: 500, QueueTimeoutMS: 2000},
+			{Name: "low", Priority: 10, MaxUtilization: 0.7, MaxQueue: 100, QueueTimeoutMS: 500},
+		},
+		Routes:       map[string]string{"/healthz": "critical", "/readyz": "critical"},
+		Header:       "",
+		APIKeyHeader: "X-API-Key",
+		APIKeys:      map[string]string{},
+		DefaultClass: "normal",
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(QoSConfig)
+		if config.MaxInFlight <= 0 {
+			config.MaxInFlight = 500
+		}
+		classes := make(map[string]QoSClass, len(config.Classes))
+		for _, cl := range config.Classes {
+			if cl.MaxUtilization <= 0 || cl.MaxUtilization > 1 {
+				cl.MaxUtilization = 1
+			}
+			classes[cl.Name] = cl
+		}
+		prefixes := make([]string, 0, len(config.Routes))
+		for p := range config.Routes {
+			prefixes = append(prefixes, p)
+		}
+		// 长前缀优先
+		sort.Sort(sort.Reverse(sort.StringSlice(prefixes)))
+		qos := &qosController{max: config.MaxInFlight, queued: map[string]int{}}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				name := ""
+				if config.Header != "" {
+					name = req.Header.Get(config.Header)
+				}
+				if _, ok := classes[name]; !ok && config.APIKeyHeader != "" {
+					name = config.APIKeys[req.Header.Get(config.APIKeyHeader)]
+				}
+				if _, ok := classes[name]; !ok {
+					name = ""
+					for _, p := range prefixes {
+						if strings.HasPrefix(req.URL.Path, p) {
+							name = config.Routes[p]
+							break
+						}
+					}
+				}
+				class, ok := classes[name]
+				if !ok {
+					class, ok = classes[config.DefaultClass]
+					if !ok {
+						return next(c)
+					}
+				}
+				if !qos.acquire(class) {
+					c.response.Header().Set("Retry-After", "1")
+					return NewHTTPError(http.StatusServiceUnavailable)
+				}
+				defer qos.release()
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 获取处理许可，被拒绝或等待超时时返回false
+func (q *qosController) acquire(class QoSClass) bool {
+	limit := int(float64(q.max) * class.MaxUtilization)
+	if limit < 1 {
+		limit = 1
+	}
+	q.Lock()
+	// 没有同级或更高优先级的请求在排队时才可直接获得许可
+	if q.inFlight < limit && (len(q.waiters) == 0 || q.waiters[0].priority < class.Priority) {
+		q.inFlight++
+		q.Unlock()
+		return true
+	}
+	if class.MaxQueue <= 0 || q.queued[class.Name] >= class.MaxQueue {
+		q.Unlock()
+		return false
+	}
+	w := &qosWaiter{ch: make(chan struct{}), class: class.Name, priority: class.Priority, limit: limit}
+	// 按优先级插入，同优先级先到先得
+	i := sort.Search(len(q.waiters), func(i int) bool { return q.waiters[i].priority < w.priority })
+	q.waiters = append(q.waiters, nil)
+	copy(q.waiters[i+1:], q.waiters[i:])
+	q.waiters[i] = w
+	q.queued[class.Name]++
+	q.Unlock()
+
+	timer := time.NewTimer(time.Duration(class.QueueTimeoutMS) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-w.ch:
+		return true
+	case <-timer.C:
+	}
+	q.Lock()
+	defer q.Unlock()
+	if w.admitted {
+		return true
+	}
+	for i, v := range q.waiters {
+		if v == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			break
+		}
+	}
+	q.queued[class.Name]--
+	return false
+}
+
+func (q *qosController) release() {
+	q.Lock()
+	q.inFlight--
+	q.dispatch()
+	q.Unlock()
+}
+
+// 按优先级唤醒排队的请求
+func (q *qosController) dispatch() {
+	for i := 0; i < len(q.waiters) && q.inFlight < q.max; {
+		w := q.waiters[i]
+		if q.inFlight >= w.limit {
+			i++
+			continue
+		}
+		q.inFlight++
+		w.admitted = true
+		close(w.ch)
+		q.queued[w.class]--
+		q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+	}
+}
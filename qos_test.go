@@ -0,0 +1,72 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestQoS(t *testing.T) {
+	config := QoSConfig{
+		MaxInFlight: 1,
+		Classes: []QoSClass{
+			{Name: "critical", Priority: 100, MaxUtilization: 1, MaxQueue: 1, QueueTimeoutMS: 5000},
+			{Name: "low", Priority: 10, MaxUtilization: 1},
+		},
+		Header:       "X-QoS",
+		DefaultClass: "low",
+	}
+	newHandler := func() (HandlerFunc, chan struct{}, chan struct{}) {
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		h := QoS.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+			if c.Request().Header.Get("X-Block") != "" {
+				started <- struct{}{}
+				<-release
+			}
+			return nil
+		})
+		return h, started, release
+	}
+	do := func(h HandlerFunc, class string, block bool) (*http.Response, error) {
+		req, _ := http.NewRequest(GET, "/", nil)
+		req.Header.Set("X-QoS", class)
+		if block {
+			req.Header.Set("X-Block", "1")
+		}
+		c, rec := testContext(req)
+		defer c.free()
+		err := h(c)
+		return rec.Result(), err
+	}
+
+	a, started, release := newHandler()
+	b, _, _ := newHandler()
+	done := make(chan error, 2)
+	go func() {
+		_, err := do(a, "critical", true)
+		done <- err
+	}()
+	<-started
+
+	// 其他挂载点的中间件实例不共享并发额度
+	if _, err := do(b, "low", false); err != nil {
+		t.Errorf("another mount was throttled: %v", err)
+	}
+	// 饱和时不排队的低优先级请求被拒绝
+	resp, err := do(a, "low", false)
+	if he, ok := err.(*HTTPError); !ok || he.Code != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("low priority request = %v", err)
+	}
+	// 高优先级请求排队，许可释放后获得处理
+	go func() {
+		_, err := do(a, "critical", true)
+		done <- err
+	}()
+	release <- struct{}{}
+	<-started
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err = <-done; err != nil {
+			t.Errorf("critical request = %v", err)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+// +build race
+
+package lessgo
+
+// 竞态检测下sync.Pool会随机丢弃对象，分配次数不可预期
+const raceEnabled = true
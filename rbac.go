@@ -0,0 +1,180 @@
+package lessgo
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	// RBACConfig defines the config for role-based permission middleware.
+	RBACConfig struct {
+		// 认证中间件在Context中存放当前用户的键名
+		SubjectKey string
+
+		// 操作声明多个权限时，"all"要求全部满足，"any"满足其一即可
+		Mode string
+	}
+
+	// 权限策略存储
+	PolicyStore interface {
+		// 判断subject是否拥有权限
+		Enforce(subject, permission string) (bool, error)
+	}
+
+	// Casbin执行器需满足的接口，*casbin.Enforcer即满足
+	CasbinEnforcer interface {
+		Enforce(rvals ...interface{}) (bool, error)
+	}
+
+	// 基于Casbin的策略存储，权限"obj:act"对应Enforce(sub, obj, act)
+	casbinPolicyStore struct {
+		enforcer CasbinEnforcer
+	}
+
+	// 内存中的角色权限表
+	MemoryPolicyStore struct {
+		roles map[string][]string // subject -> roles
+		perms map[string][]string // role或subject -> permissions
+		sync.RWMutex
+	}
+
+	// 可作为权限主体的用户对象
+	RBACSubject interface {
+		RBACSubject() string
+	}
+)
+
+var (
+	policyStore     PolicyStore = NewMemoryPolicyStore()
+	policyStoreLock sync.RWMutex
+)
+
+var RBAC = ApiMiddleware{
+	Name: "权限控制",
+	Desc: "根据操作(ApiHandler)声明的Permissions，校验认证中间件提供的当前用户是否拥有所需权限",
+	Config: RBACConfig{
+		SubjectKey: "user",
+		Mode:       "all",
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(RBACConfig)
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				// 全局挂载时尚未路由，无法确定操作所需权限，拒绝请求
+				if c.path == "" {
+					return NewHTTPError(http.StatusInternalServerError, "RBAC: must be mounted on a route or group")
+				}
+				ah := c.ApiHandler()
+				if ah == nil || len(ah.Permissions) == 0 {
+					return next(c)
+				}
+				var subject string
+				switch u := c.Get(config.SubjectKey).(type) {
+				case nil:
+					return NewHTTPError(http.StatusUnauthorized)
+				case string:
+					subject = u
+				case RBACSubject:
+					subject = u.RBACSubject()
+				default:
+					subject = fmt.Sprint(u)
+				}
+
+				policyStoreLock.RLock()
+				store := policyStore
+				policyStoreLock.RUnlock()
+				for _, p := range ah.Permissions {
+					ok, err := store.Enforce(subject, p)
+					if err != nil {
+						return err
+					}
+					if ok && config.Mode == "any" {
+						return next(c)
+					}
+					if !ok && config.Mode != "any" {
+						return NewHTTPError(http.StatusForbidden, "permission denied: "+p)
+					}
+				}
+				if config.Mode == "any" {
+					return NewHTTPError(http.StatusForbidden)
+				}
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 设置权限策略存储(内部默认为内存角色权限表)
+func SetPolicyStore(store PolicyStore) {
+	policyStoreLock.Lock()
+	policyStore = store
+	policyStoreLock.Unlock()
+}
+
+// 返回基于Casbin执行器的策略存储
+func NewCasbinPolicyStore(e CasbinEnforcer) PolicyStore {
+	return &casbinPolicyStore{enforcer: e}
+}
+
+func (s *casbinPolicyStore) Enforce(subject, permission string) (bool, error) {
+	obj, act := permission, "*"
+	if i := strings.LastIndex(permission, ":"); i >= 0 {
+		obj, act = permission[:i], permission[i+1:]
+	}
+	return s.enforcer.Enforce(subject, obj, act)
+}
+
+// 创建内存角色权限表
+func NewMemoryPolicyStore() *MemoryPolicyStore {
+	return &MemoryPolicyStore{
+		roles: map[string][]string{},
+		perms: map[string][]string{},
+	}
+}
+
+// 为用户分配角色
+func (s *MemoryPolicyStore) AddRoles(subject string, roles ...string) {
+	s.Lock()
+	s.roles[subject] = append(s.roles[subject], roles...)
+	s.Unlock()
+}
+
+// 为角色(或用户)授予权限，支持"*"与"order:*"形式的通配
+func (s *MemoryPolicyStore) Grant(role string, permissions ...string) {
+	s.Lock()
+	s.perms[role] = append(s.perms[role], permissions...)
+	s.Unlock()
+}
+
+func (s *MemoryPolicyStore) Enforce(subject, permission string) (bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, owner := range append([]string{subject}, s.roles[subject]...) {
+		for _, p := range s.perms[owner] {
+			if matchPermission(p, permission) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchPermission(pattern, permission string) bool {
+	if pattern == "*" || pattern == permission {
+		return true
+	}
+	return strings.HasSuffix(pattern, "*") && strings.HasPrefix(permission, pattern[:len(pattern)-1])
+}
+
+// 获取当前请求匹配到的操作，未匹配到或路由未绑定操作时返回nil
+func (c *Context) ApiHandler() *ApiHandler {
+	return c.apiHandler
+}
+
+// 设置当前请求匹配到的操作，通常由路由设置，供测试操作的元信息(如所需权限)时使用
+func (c *Context) SetApiHandler(ah *ApiHandler) {
+	c.apiHandler = ah
+}
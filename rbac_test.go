@@ -0,0 +1,109 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+type rbacUser string
+
+func (u rbacUser) RBACSubject() string { return string(u) }
+
+type fakeEnforcer [][3]string
+
+func (e fakeEnforcer) Enforce(rvals ...interface{}) (bool, error) {
+	for _, p := range e {
+		if p[0] == rvals[0] && p[1] == rvals[1] && (p[2] == "*" || p[2] == rvals[2]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestMemoryPolicyStore(t *testing.T) {
+	s := NewMemoryPolicyStore()
+	s.AddRoles("alice", "editor")
+	s.Grant("editor", "order:*", "report:read")
+	s.Grant("bob", "order:read")
+	s.Grant("root", "*")
+	for _, test := range []struct {
+		subject, permission string
+		want                bool
+	}{
+		{"alice", "order:write", true},
+		{"alice", "report:read", true},
+		{"alice", "report:write", false},
+		{"bob", "order:read", true},
+		{"bob", "order:write", false},
+		{"root", "anything", true},
+		{"nobody", "order:read", false},
+	} {
+		if ok, _ := s.Enforce(test.subject, test.permission); ok != test.want {
+			t.Errorf("Enforce(%q, %q) = %v", test.subject, test.permission, ok)
+		}
+	}
+
+	casbin := NewCasbinPolicyStore(fakeEnforcer{{"alice", "order", "read"}, {"bob", "report", "*"}})
+	for _, test := range []struct {
+		subject, permission string
+		want                bool
+	}{
+		{"alice", "order:read", true},
+		{"alice", "order:write", false},
+		{"bob", "report", true},
+	} {
+		if ok, _ := casbin.Enforce(test.subject, test.permission); ok != test.want {
+			t.Errorf("casbin Enforce(%q, %q) = %v", test.subject, test.permission, ok)
+		}
+	}
+}
+
+func TestRBAC(t *testing.T) {
+	store := NewMemoryPolicyStore()
+	store.Grant("alice", "order:read")
+	store.Grant("bob", "order:read", "order:write")
+	SetPolicyStore(store)
+	defer SetPolicyStore(NewMemoryPolicyStore())
+
+	do := func(mode string, user interface{}, route string, perms ...string) int {
+		h := RBAC.Middleware.(Middleware).getMiddlewareFunc(RBACConfig{SubjectKey: "user", Mode: mode})(func(c *Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+		req, _ := http.NewRequest(GET, "/orders", nil)
+		c, rec := testContext(req)
+		defer c.free()
+		c.path = route
+		c.SetApiHandler(&ApiHandler{Permissions: perms})
+		if user != nil {
+			c.Set("user", user)
+		}
+		if err := h(c); err != nil {
+			if he, ok := err.(*HTTPError); ok {
+				return he.Code
+			}
+			t.Fatal(err)
+		}
+		return rec.Code
+	}
+	for _, test := range []struct {
+		mode  string
+		user  interface{}
+		route string
+		perms []string
+		want  int
+	}{
+		{"all", nil, "/orders", nil, 200},
+		{"all", nil, "/orders", []string{"order:read"}, 401},
+		{"all", "alice", "/orders", []string{"order:read"}, 200},
+		{"all", "alice", "/orders", []string{"order:read", "order:write"}, 403},
+		{"all", rbacUser("bob"), "/orders", []string{"order:read", "order:write"}, 200},
+		{"any", "alice", "/orders", []string{"order:write", "order:read"}, 200},
+		{"any", "alice", "/orders", []string{"order:write", "order:delete"}, 403},
+		// 全局挂载时无法确定操作
+		{"all", "bob", "", []string{"order:read"}, 500},
+	} {
+		if got := do(test.mode, test.user, test.route, test.perms...); got != test.want {
+			t.Errorf("mode %s, user %v, perms %v = %d, want %d", test.mode, test.user, test.perms, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+// Package redis is a Redis client with connection pooling that supports
+// a standalone server, Sentinel managed master and Redis Cluster, speaking
+// RESP directly so no third-party driver is needed.
+//
+//	client, err := redis.New(redis.Options{Addrs: []string{"127.0.0.1:6379"}})
+//	n, err := redis.Int64(client.Do("INCR", "visits"))
+//
+// Hooks added by AddHook are called around every command, for metrics or
+// tracing spans.
+package redis
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options configures a Client. With lessgo it's bound from a [redis.<name>]
+// config section, the tags set the defaults.
+type Options struct {
+	// Addresses of the server, of the sentinels when MasterName is set,
+	// or of some cluster nodes when Cluster is true.
+	Addrs []string `validate:"required"`
+
+	// Name of the master monitored by the sentinels, enables Sentinel mode.
+	MasterName       string `config:"master_name"`
+	SentinelPassword string `config:"sentinel_password"`
+
+	// Enables Redis Cluster mode, commands are routed by the slot of their key.
+	Cluster bool
+
+	Password string
+	DB       int
+
+	// Max open connections per server, 0 for no limit.
+	PoolSize int `config:"pool_size" default:"100" validate:"min=0"`
+	// Max idle connections per server, 10 if 0.
+	MaxIdle      int           `config:"max_idle" default:"10" validate:"min=0"`
+	IdleTimeout  time.Duration `config:"idle_timeout" default:"5m"`
+	PoolTimeout  time.Duration `config:"pool_timeout" default:"3s"`
+	DialTimeout  time.Duration `config:"dial_timeout" default:"5s"`
+	ReadTimeout  time.Duration `config:"read_timeout" default:"3s"`
+	WriteTimeout time.Duration `config:"write_timeout" default:"3s"`
+}
+
+// Hook is called before a command is sent, the returned func, if not nil,
+// is called with the result when it completes.
+type Hook func(cmd string, args []interface{}) func(err error)
+
+// Stats of a Client, summed over the servers.
+type Stats struct {
+	Commands   uint64 `json:"commands"`
+	Errors     uint64 `json:"errors"`
+	Hits       uint64 `json:"hits"`     // connections reused from the pool
+	Misses     uint64 `json:"misses"`   // connections dialed
+	Timeouts   uint64 `json:"timeouts"` // waits for a connection timed out
+	TotalConns int    `json:"total_conns"`
+	IdleConns  int    `json:"idle_conns"`
+	Master     string `json:"master,omitempty"` // current master in Sentinel mode
+	Nodes      int    `json:"nodes"`
+}
+
+// Client is safe for concurrent use.
+type Client struct {
+	opt    Options
+	mu     sync.RWMutex
+	pools  map[string]*pool
+	master string
+	slots  []string // slot to node address in Cluster mode
+	hooks  []Hook
+	closed bool
+
+	commands, errors uint64
+}
+
+const clusterSlots = 16384
+
+// New returns a Client. In Sentinel mode the master is resolved and in
+// Cluster mode the slots are loaded, a standalone server is dialed lazily.
+func New(opt Options) (*Client, error) {
+	if len(opt.Addrs) == 0 {
+		return nil, errors.New("redis: no address")
+	}
+	if opt.MaxIdle == 0 {
+		opt.MaxIdle = 10
+	}
+	c := &Client{opt: opt, pools: map[string]*pool{}}
+	switch {
+	case opt.MasterName != "":
+		if _, err := c.resolveMaster(); err != nil {
+			return nil, err
+		}
+	case opt.Cluster:
+		if err := c.refreshSlots(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// AddHook adds a hook called around every command.
+func (c *Client) AddHook(h Hook) {
+	c.mu.Lock()
+	c.hooks = append(c.hooks, h)
+	c.mu.Unlock()
+}
+
+// Do sends a command and returns its reply, see readReply for the reply types.
+// In Cluster mode the first argument is taken as the key, or the first key
+// of EVAL and EVALSHA; commands without arguments go to any node.
+func (c *Client) Do(cmd string, args ...interface{}) (reply interface{}, err error) {
+	atomic.AddUint64(&c.commands, 1)
+	c.mu.RLock()
+	hooks := c.hooks
+	c.mu.RUnlock()
+	var finish []func(error)
+	for _, h := range hooks {
+		if f := h(cmd, args); f != nil {
+			finish = append(finish, f)
+		}
+	}
+	defer func() {
+		if err != nil {
+			atomic.AddUint64(&c.errors, 1)
+		}
+		for i := len(finish) - 1; i >= 0; i-- {
+			finish[i](err)
+		}
+	}()
+
+	switch {
+	case c.opt.MasterName != "":
+		return c.doSentinel(cmd, args)
+	case c.opt.Cluster:
+		return c.doCluster(cmd, args)
+	}
+	return c.pool(c.opt.Addrs[0]).do(false, cmd, args)
+}
+
+// Stats returns the command counts and the pool stats.
+func (c *Client) Stats() Stats {
+	s := Stats{
+		Commands: atomic.LoadUint64(&c.commands),
+		Errors:   atomic.LoadUint64(&c.errors),
+	}
+	c.mu.RLock()
+	s.Master = c.master
+	s.Nodes = len(c.pools)
+	pools := make([]*pool, 0, len(c.pools))
+	for _, p := range c.pools {
+		pools = append(pools, p)
+	}
+	c.mu.RUnlock()
+	for _, p := range pools {
+		p.stats(&s)
+	}
+	return s
+}
+
+// Close closes the idle connections and makes later commands fail.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	pools := c.pools
+	c.pools = map[string]*pool{}
+	c.mu.Unlock()
+	for _, p := range pools {
+		p.close()
+	}
+	return nil
+}
+
+func (c *Client) pool(addr string) *pool {
+	c.mu.RLock()
+	p := c.pools[addr]
+	c.mu.RUnlock()
+	if p != nil {
+		return p
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p = c.pools[addr]; p == nil {
+		p = newPool(addr, &c.opt)
+		if c.closed {
+			p.closed = true
+		}
+		c.pools[addr] = p
+	}
+	return p
+}
+
+func (c *Client) doSentinel(cmd string, args []interface{}) (interface{}, error) {
+	c.mu.RLock()
+	addr := c.master
+	c.mu.RUnlock()
+	reply, err := c.pool(addr).do(false, cmd, args)
+	if !failover(err) {
+		return reply, err
+	}
+	// the master may have failed over, ask the sentinels and retry once
+	newAddr, rerr := c.resolveMaster()
+	if rerr != nil || newAddr == addr {
+		return reply, err
+	}
+	return c.pool(newAddr).do(false, cmd, args)
+}
+
+// failover reports whether err may be caused by a master switch.
+func failover(err error) bool {
+	if err == nil || err == ErrPoolTimeout || err == errPoolClosed {
+		return false
+	}
+	if e, ok := err.(Error); ok {
+		return strings.HasPrefix(string(e), "READONLY")
+	}
+	return true
+}
+
+// resolveMaster asks the sentinels for the address of the master.
+func (c *Client) resolveMaster() (string, error) {
+	var lastErr error
+	for _, addr := range c.opt.Addrs {
+		cn, err := dial(addr, &c.opt, c.opt.SentinelPassword, 0)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		hp, err := Strings(cn.do("SENTINEL", "get-master-addr-by-name", c.opt.MasterName))
+		cn.nc.Close()
+		if err != nil || len(hp) != 2 {
+			if err == nil {
+				err = errors.New("redis: bad sentinel reply")
+			}
+			lastErr = err
+			continue
+		}
+		master := net.JoinHostPort(hp[0], hp[1])
+		c.mu.Lock()
+		old := c.pools[c.master]
+		if c.master != master {
+			delete(c.pools, c.master)
+		} else {
+			old = nil
+		}
+		c.master = master
+		c.mu.Unlock()
+		if old != nil {
+			old.close()
+		}
+		return master, nil
+	}
+	return "", errors.New("redis: no sentinel available: " + lastErr.Error())
+}
+
+func (c *Client) doCluster(cmd string, args []interface{}) (interface{}, error) {
+	addr := ""
+	if key, ok := commandKey(cmd, args); ok {
+		slot := Slot(key)
+		c.mu.RLock()
+		if c.slots != nil {
+			addr = c.slots[slot]
+		}
+		c.mu.RUnlock()
+	}
+	if addr == "" {
+		addr = c.anyNode()
+	}
+
+	asking := false
+	refreshed := false
+	for i := 0; ; i++ {
+		reply, err := c.pool(addr).do(asking, cmd, args)
+		asking = false
+		e, ok := err.(Error)
+		if ok && i < 5 {
+			// MOVED <slot> <addr> or ASK <slot> <addr>
+			parts := strings.Fields(string(e))
+			if len(parts) == 3 && (parts[0] == "MOVED" || parts[0] == "ASK") {
+				if parts[0] == "MOVED" {
+					if slot, err := strconv.Atoi(parts[1]); err == nil && slot >= 0 && slot < clusterSlots {
+						c.mu.Lock()
+						if c.slots != nil {
+							c.slots[slot] = parts[2]
+						}
+						c.mu.Unlock()
+					}
+				} else {
+					asking = true
+				}
+				addr = parts[2]
+				continue
+			}
+		}
+		if err != nil && !ok && !refreshed && err != ErrPoolTimeout && err != errPoolClosed {
+			// the node may be down, reload the slots and retry once
+			refreshed = true
+			if c.refreshSlots() == nil {
+				if key, ok := commandKey(cmd, args); ok {
+					c.mu.RLock()
+					addr = c.slots[Slot(key)]
+					c.mu.RUnlock()
+				}
+				if addr == "" {
+					addr = c.anyNode()
+				}
+				continue
+			}
+		}
+		return reply, err
+	}
+}
+
+func (c *Client) anyNode() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, addr := range c.slots {
+		if addr != "" {
+			return addr
+		}
+	}
+	return c.opt.Addrs[0]
+}
+
+// refreshSlots loads the slot map by CLUSTER SLOTS from a known node.
+func (c *Client) refreshSlots() error {
+	c.mu.RLock()
+	addrs := append([]string{}, c.opt.Addrs...)
+	for addr := range c.pools {
+		addrs = append(addrs, addr)
+	}
+	c.mu.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		reply, err := c.pool(addr).do(false, "CLUSTER", []interface{}{"SLOTS"})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ranges, ok := reply.([]interface{})
+		if !ok {
+			lastErr = errors.New("redis: bad CLUSTER SLOTS reply")
+			continue
+		}
+		slots := make([]string, clusterSlots)
+		for _, r := range ranges {
+			// [start, end, [host, port, id], replicas...]
+			rr, ok := r.([]interface{})
+			if !ok || len(rr) < 3 {
+				continue
+			}
+			start, _ := rr[0].(int64)
+			end, _ := rr[1].(int64)
+			node, ok := rr[2].([]interface{})
+			if !ok || len(node) < 2 {
+				continue
+			}
+			host, _ := String(node[0], nil)
+			port, _ := Int64(node[1], nil)
+			if host == "" {
+				// the node answering the request
+				host, _, _ = net.SplitHostPort(addr)
+			}
+			nodeAddr := net.JoinHostPort(host, strconv.FormatInt(port, 10))
+			for s := start; s <= end && s < clusterSlots; s++ {
+				slots[s] = nodeAddr
+			}
+		}
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("redis: no cluster node")
+	}
+	return lastErr
+}
+
+// commandKey returns the key of the command used to select the cluster slot.
+func commandKey(cmd string, args []interface{}) (string, bool) {
+	i := 0
+	switch strings.ToUpper(cmd) {
+	case "EVAL", "EVALSHA":
+		if len(args) < 3 {
+			return "", false
+		}
+		i = 2
+	}
+	if len(args) <= i {
+		return "", false
+	}
+	key, err := String(args[i], nil)
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// Slot returns the cluster hash slot of key, only the part inside the
+// first {} hash tag counts if there's one.
+func Slot(key string) int {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	return int(crc16(key)) % clusterSlots
+}
+
+// crc16 is the CRC16-CCITT(XMODEM) used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
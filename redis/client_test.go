@@ -0,0 +1,314 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer answers RESP commands with handler, a string is sent as a
+// simple string and the others as by readReply.
+type fakeServer struct {
+	ln      net.Listener
+	handler func(args []string) interface{}
+}
+
+func newFakeServer(t *testing.T, handler func(args []string) interface{}) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, handler: handler}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.ln.Addr().String() }
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	br, bw := bufio.NewReader(c), bufio.NewWriter(c)
+	for {
+		req, err := readReply(br)
+		if err != nil {
+			return
+		}
+		args, _ := Strings(req, nil)
+		writeReply(bw, s.handler(args))
+		bw.Flush()
+	}
+}
+
+// replies are written one after another, as pushed by SUBSCRIBE.
+type replies []interface{}
+
+func writeReply(w *bufio.Writer, v interface{}) {
+	switch x := v.(type) {
+	case replies:
+		for _, e := range x {
+			writeReply(w, e)
+		}
+	case string:
+		w.WriteString("+" + x + "\r\n")
+	case Error:
+		w.WriteString("-" + string(x) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(x, 10) + "\r\n")
+	case []byte:
+		writeBulk(w, string(x))
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(x))
+		for _, e := range x {
+			writeReply(w, e)
+		}
+	default:
+		w.WriteString("$-1\r\n")
+	}
+}
+
+// kvHandler implements AUTH, GET, SET and INCR on an in-memory map.
+func kvHandler(password string) func(args []string) interface{} {
+	var mu sync.Mutex
+	data := map[string]string{}
+	return func(args []string) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != password {
+				return Error("ERR invalid password")
+			}
+			return "OK"
+		case "SET":
+			data[args[1]] = args[2]
+			return "OK"
+		case "GET":
+			v, ok := data[args[1]]
+			if !ok {
+				return nil
+			}
+			return []byte(v)
+		case "INCR":
+			n, _ := strconv.ParseInt(data[args[1]], 10, 64)
+			n++
+			data[args[1]] = strconv.FormatInt(n, 10)
+			return n
+		}
+		return Error("ERR unknown command '" + args[0] + "'")
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := newFakeServer(t, kvHandler("pw"))
+	defer srv.ln.Close()
+
+	client, err := New(Options{Addrs: []string{srv.addr()}, Password: "pw", PoolSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var hooked []string
+	client.AddHook(func(cmd string, args []interface{}) func(error) {
+		return func(err error) {
+			hooked = append(hooked, fmt.Sprintf("%s %v", cmd, err))
+		}
+	})
+
+	if _, err := client.Do("SET", "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := String(client.Do("GET", "k")); err != nil || v != "v" {
+		t.Errorf("GET = %q, %v", v, err)
+	}
+	if _, err := String(client.Do("GET", "missing")); err != ErrNil {
+		t.Errorf("GET missing error = %v", err)
+	}
+	if n, err := Int64(client.Do("INCR", "n")); err != nil || n != 1 {
+		t.Errorf("INCR = %d, %v", n, err)
+	}
+	if _, err := client.Do("NOPE"); err == nil || err.Error() != "ERR unknown command 'NOPE'" {
+		t.Errorf("NOPE error = %v", err)
+	}
+	if len(hooked) != 5 || hooked[4] != "NOPE ERR unknown command 'NOPE'" {
+		t.Errorf("hooks = %q", hooked)
+	}
+	s := client.Stats()
+	if s.Commands != 5 || s.Errors != 1 || s.Misses != 1 || s.Hits != 4 || s.IdleConns != 1 {
+		t.Errorf("Stats() = %+v", s)
+	}
+
+	bad, _ := New(Options{Addrs: []string{srv.addr()}, Password: "wrong"})
+	if _, err := bad.Do("GET", "k"); err == nil {
+		t.Error("AUTH with a wrong password should fail")
+	}
+}
+
+func TestPoolTimeout(t *testing.T) {
+	block := make(chan struct{})
+	srv := newFakeServer(t, func(args []string) interface{} {
+		<-block
+		return "OK"
+	})
+	defer srv.ln.Close()
+	defer close(block)
+
+	client, _ := New(Options{Addrs: []string{srv.addr()}, PoolSize: 1, PoolTimeout: 50 * time.Millisecond})
+	defer client.Close()
+	go client.Do("PING")
+	time.Sleep(20 * time.Millisecond)
+	if _, err := client.Do("PING"); err != ErrPoolTimeout {
+		t.Errorf("error = %v, want ErrPoolTimeout", err)
+	}
+}
+
+func TestSentinel(t *testing.T) {
+	master := newFakeServer(t, kvHandler(""))
+	defer master.ln.Close()
+	host, port, _ := net.SplitHostPort(master.addr())
+	sentinel := newFakeServer(t, func(args []string) interface{} {
+		if len(args) == 3 && args[0] == "SENTINEL" && args[2] == "mymaster" {
+			return []interface{}{[]byte(host), []byte(port)}
+		}
+		return nil
+	})
+	defer sentinel.ln.Close()
+
+	client, err := New(Options{Addrs: []string{"127.0.0.1:1", sentinel.addr()}, MasterName: "mymaster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Do("SET", "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if s := client.Stats(); s.Master != master.addr() {
+		t.Errorf("master = %q, want %q", s.Master, master.addr())
+	}
+}
+
+func TestCluster(t *testing.T) {
+	// node a serves slots 0-8191 and node b the others
+	var a, b *fakeServer
+	slotsReply := func() interface{} {
+		node := func(s *fakeServer) []interface{} {
+			host, port, _ := net.SplitHostPort(s.addr())
+			p, _ := strconv.ParseInt(port, 10, 64)
+			return []interface{}{[]byte(host), p}
+		}
+		return []interface{}{
+			[]interface{}{int64(0), int64(8191), node(a)},
+			[]interface{}{int64(8192), int64(16383), node(b)},
+		}
+	}
+	handler := func(self func() *fakeServer, lo, hi int) func(args []string) interface{} {
+		kv := kvHandler("")
+		return func(args []string) interface{} {
+			if args[0] == "CLUSTER" {
+				return slotsReply()
+			}
+			if slot := Slot(args[1]); slot < lo || slot > hi {
+				other := a
+				if self() == a {
+					other = b
+				}
+				return Error(fmt.Sprintf("MOVED %d %s", slot, other.addr()))
+			}
+			return kv(args)
+		}
+	}
+	a = newFakeServer(t, handler(func() *fakeServer { return a }, 0, 8191))
+	defer a.ln.Close()
+	b = newFakeServer(t, handler(func() *fakeServer { return b }, 8192, 16383))
+	defer b.ln.Close()
+
+	client, err := New(Options{Addrs: []string{a.addr()}, Cluster: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for _, key := range []string{"foo", "bar", "{user1}.name", "{user1}.age"} {
+		if _, err := client.Do("SET", key, key); err != nil {
+			t.Fatalf("SET %s: %v", key, err)
+		}
+		if v, err := String(client.Do("GET", key)); err != nil || v != key {
+			t.Errorf("GET %s = %q, %v", key, v, err)
+		}
+	}
+	// a stale slot is corrected by MOVED
+	client.mu.Lock()
+	client.slots[Slot("foo")] = a.addr()
+	client.slots[Slot("bar")] = a.addr()
+	client.mu.Unlock()
+	for _, key := range []string{"foo", "bar"} {
+		if v, err := String(client.Do("GET", key)); err != nil || v != key {
+			t.Errorf("GET %s after MOVED = %q, %v", key, v, err)
+		}
+	}
+}
+
+func TestSlot(t *testing.T) {
+	if crc16("123456789") != 0x31C3 {
+		t.Errorf("crc16 = %x", crc16("123456789"))
+	}
+	if Slot("{user1000}.following") != Slot("{user1000}.followers") || Slot("foo{}{bar}") != int(crc16("foo{}{bar}"))%clusterSlots {
+		t.Error("hash tags not honored")
+	}
+	if Slot("foo") != 12182 {
+		t.Errorf("Slot(foo) = %d", Slot("foo"))
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := newFakeServer(t, func(args []string) interface{} {
+		if strings.ToUpper(args[0]) != "SUBSCRIBE" {
+			return Error("ERR unknown command")
+		}
+		r := replies{}
+		for i, ch := range args[1:] {
+			r = append(r, []interface{}{[]byte("subscribe"), []byte(ch), int64(i + 1)})
+		}
+		return append(r, []interface{}{[]byte("message"), []byte(args[1]), []byte("hello")})
+	})
+	defer s.ln.Close()
+	client, err := New(Options{Addrs: []string{s.addr()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ps, err := client.Subscribe("a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := ps.Receive()
+	if err != nil || msg.Channel != "a" || string(msg.Data) != "hello" {
+		t.Errorf("Receive() = %+v, %v", msg, err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := ps.Receive()
+		done <- err
+	}()
+	ps.Close()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("Receive() after Close should fail")
+		}
+	case <-time.After(time.Second):
+		t.Error("Receive() is not unblocked by Close")
+	}
+}
@@ -0,0 +1,193 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPoolTimeout is returned when no connection is available within PoolTimeout.
+var ErrPoolTimeout = errors.New("redis: connection pool timeout")
+
+var errPoolClosed = errors.New("redis: client closed")
+
+type conn struct {
+	nc     net.Conn
+	br     *bufio.Reader
+	bw     *bufio.Writer
+	opt    *Options
+	broken bool
+	idleAt time.Time
+}
+
+func dial(addr string, opt *Options, password string, db int) (*conn, error) {
+	nc, err := net.DialTimeout("tcp", addr, opt.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{nc: nc, br: bufio.NewReader(nc), bw: bufio.NewWriter(nc), opt: opt}
+	if password != "" {
+		if _, err = c.do("AUTH", password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if db > 0 {
+		if _, err = c.do("SELECT", db); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do sends the command and reads its reply; an error reply is returned as Error.
+func (c *conn) do(cmd string, args ...interface{}) (interface{}, error) {
+	if c.opt.WriteTimeout > 0 {
+		c.nc.SetWriteDeadline(time.Now().Add(c.opt.WriteTimeout))
+	}
+	if err := writeCommand(c.bw, cmd, args); err != nil {
+		c.broken = true
+		return nil, err
+	}
+	if c.opt.ReadTimeout > 0 {
+		c.nc.SetReadDeadline(time.Now().Add(c.opt.ReadTimeout))
+	}
+	reply, err := readReply(c.br)
+	if err != nil {
+		c.broken = true
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// pool keeps the connections to one server.
+type pool struct {
+	addr   string
+	opt    *Options
+	sem    chan struct{} // limits the open connections, nil for no limit
+	mu     sync.Mutex
+	idle   []*conn
+	conns  int
+	closed bool
+
+	hits, misses, timeouts uint64
+}
+
+func newPool(addr string, opt *Options) *pool {
+	p := &pool{addr: addr, opt: opt}
+	if opt.PoolSize > 0 {
+		p.sem = make(chan struct{}, opt.PoolSize)
+	}
+	return p
+}
+
+func (p *pool) get() (*conn, error) {
+	if p.sem != nil {
+		select {
+		case p.sem <- struct{}{}:
+		default:
+			t := time.NewTimer(p.opt.PoolTimeout)
+			select {
+			case p.sem <- struct{}{}:
+				t.Stop()
+			case <-t.C:
+				atomic.AddUint64(&p.timeouts, 1)
+				return nil, ErrPoolTimeout
+			}
+		}
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.release()
+		return nil, errPoolClosed
+	}
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if p.opt.IdleTimeout > 0 && time.Since(c.idleAt) > p.opt.IdleTimeout {
+			p.conns--
+			c.nc.Close()
+			continue
+		}
+		p.mu.Unlock()
+		atomic.AddUint64(&p.hits, 1)
+		return c, nil
+	}
+	p.conns++
+	p.mu.Unlock()
+
+	atomic.AddUint64(&p.misses, 1)
+	c, err := dial(p.addr, p.opt, p.opt.Password, p.opt.DB)
+	if err != nil {
+		p.mu.Lock()
+		p.conns--
+		p.mu.Unlock()
+		p.release()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *pool) put(c *conn) {
+	p.mu.Lock()
+	if c.broken || p.closed || len(p.idle) >= p.opt.MaxIdle {
+		p.conns--
+		p.mu.Unlock()
+		c.nc.Close()
+	} else {
+		c.idleAt = time.Now()
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+	}
+	p.release()
+}
+
+func (p *pool) release() {
+	if p.sem != nil {
+		<-p.sem
+	}
+}
+
+func (p *pool) do(asking bool, cmd string, args []interface{}) (interface{}, error) {
+	c, err := p.get()
+	if err != nil {
+		return nil, err
+	}
+	defer p.put(c)
+	if asking {
+		if _, err = c.do("ASKING"); err != nil {
+			return nil, err
+		}
+	}
+	return c.do(cmd, args...)
+}
+
+func (p *pool) close() {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.conns -= len(idle)
+	p.mu.Unlock()
+	for _, c := range idle {
+		c.nc.Close()
+	}
+}
+
+func (p *pool) stats(s *Stats) {
+	s.Hits += atomic.LoadUint64(&p.hits)
+	s.Misses += atomic.LoadUint64(&p.misses)
+	s.Timeouts += atomic.LoadUint64(&p.timeouts)
+	p.mu.Lock()
+	s.TotalConns += p.conns
+	s.IdleConns += len(p.idle)
+	p.mu.Unlock()
+}
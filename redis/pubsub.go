@@ -0,0 +1,103 @@
+package redis
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Message is a message received by PubSub.
+type Message struct {
+	Channel string
+	Data    []byte
+}
+
+// PubSub is a dedicated connection subscribed to channels, messages are
+// published by Client.Do("PUBLISH", channel, message).
+type PubSub struct {
+	cn     *conn
+	mu     sync.Mutex
+	closed bool
+}
+
+// Subscribe opens a connection subscribed to the channels, on the master in
+// Sentinel mode and on any node in Cluster mode, which forwards the messages
+// of all nodes.
+func (c *Client) Subscribe(channels ...string) (*PubSub, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("redis: no channel to subscribe")
+	}
+	var addr string
+	switch {
+	case c.opt.MasterName != "":
+		c.mu.RLock()
+		addr = c.master
+		c.mu.RUnlock()
+	case c.opt.Cluster:
+		addr = c.anyNode()
+	default:
+		addr = c.opt.Addrs[0]
+	}
+	cn, err := dial(addr, &c.opt, c.opt.Password, 0)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(channels))
+	for i, ch := range channels {
+		args[i] = ch
+	}
+	if err = writeCommand(cn.bw, "SUBSCRIBE", args); err != nil {
+		cn.nc.Close()
+		return nil, err
+	}
+	// one confirmation per channel, then waiting for messages must not time out
+	if c.opt.ReadTimeout > 0 {
+		cn.nc.SetReadDeadline(time.Now().Add(c.opt.ReadTimeout))
+	}
+	for range channels {
+		reply, err := readReply(cn.br)
+		if err == nil {
+			if e, ok := reply.(Error); ok {
+				err = e
+			}
+		}
+		if err != nil {
+			cn.nc.Close()
+			return nil, err
+		}
+	}
+	cn.nc.SetReadDeadline(time.Time{})
+	return &PubSub{cn: cn}, nil
+}
+
+// Receive waits for the next message, it returns an error when the
+// connection is broken or closed.
+func (ps *PubSub) Receive() (Message, error) {
+	for {
+		reply, err := readReply(ps.cn.br)
+		if err != nil {
+			return Message{}, err
+		}
+		a, ok := reply.([]interface{})
+		if !ok || len(a) != 3 {
+			continue
+		}
+		if kind, _ := String(a[0], nil); kind != "message" {
+			continue
+		}
+		ch, _ := String(a[1], nil)
+		data, _ := Bytes(a[2], nil)
+		return Message{Channel: ch, Data: data}, nil
+	}
+}
+
+// Close closes the connection, a blocked Receive returns an error.
+func (ps *PubSub) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return nil
+	}
+	ps.closed = true
+	return ps.cn.nc.Close()
+}
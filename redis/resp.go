@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Error is an error reply from the server, such as "ERR unknown command".
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// ErrNil is returned by the reply helpers when the reply is nil.
+var ErrNil = errors.New("redis: nil reply")
+
+// writeCommand encodes the command as a RESP array of bulk strings.
+func writeCommand(w *bufio.Writer, cmd string, args []interface{}) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args)+1)
+	writeBulk(w, cmd)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			writeBulk(w, v)
+		case []byte:
+			fmt.Fprintf(w, "$%d\r\n", len(v))
+			w.Write(v)
+			w.WriteString("\r\n")
+		case int:
+			writeBulk(w, strconv.Itoa(v))
+		case int64:
+			writeBulk(w, strconv.FormatInt(v, 10))
+		case float64:
+			writeBulk(w, strconv.FormatFloat(v, 'g', -1, 64))
+		case bool:
+			if v {
+				writeBulk(w, "1")
+			} else {
+				writeBulk(w, "0")
+			}
+		case nil:
+			writeBulk(w, "")
+		default:
+			writeBulk(w, fmt.Sprint(v))
+		}
+	}
+	return w.Flush()
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n", len(s))
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// readReply decodes one RESP reply: a simple string as string, an error as
+// Error, an integer as int64, a bulk string as []byte, an array as
+// []interface{} and a null bulk string or array as nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+}
+
+// String converts a reply to a string.
+func String(reply interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return string(v), nil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case nil:
+		return "", ErrNil
+	}
+	return "", fmt.Errorf("redis: unexpected type %T for String", reply)
+}
+
+// Bytes converts a reply to a []byte.
+func Bytes(reply interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case nil:
+		return nil, ErrNil
+	}
+	return nil, fmt.Errorf("redis: unexpected type %T for Bytes", reply)
+}
+
+// Int64 converts a reply to an int64.
+func Int64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case nil:
+		return 0, ErrNil
+	}
+	return 0, fmt.Errorf("redis: unexpected type %T for Int64", reply)
+}
+
+// Strings converts an array reply to a []string.
+func Strings(reply interface{}, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []interface{}:
+		ss := make([]string, len(v))
+		for i := range v {
+			if v[i] == nil {
+				continue
+			}
+			if ss[i], err = String(v[i], nil); err != nil {
+				return nil, err
+			}
+		}
+		return ss, nil
+	case nil:
+		return nil, ErrNil
+	}
+	return nil, fmt.Errorf("redis: unexpected type %T for Strings", reply)
+}
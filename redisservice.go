@@ -0,0 +1,491 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	confpkg "github.com/lessgo/lessgo/config"
+	"github.com/lessgo/lessgo/redis"
+	"github.com/lessgo/lessgo/session"
+)
+
+type (
+	// Redis中的幂等记录存储
+	redisIdempotencyStore struct {
+		client *redis.Client
+		prefix string
+	}
+
+	// Redis中的随机数存储
+	redisNonceStore struct {
+		client *redis.Client
+		prefix string
+	}
+
+	// Redis中的任务队列
+	redisTaskQueue struct {
+		client *redis.Client
+		prefix string
+	}
+
+	// 使用共享Redis客户端的session存储，providerConfig为客户端名称
+	redisSessionProvider struct {
+		name        string
+		maxlifetime int64
+	}
+
+	redisSessionStore struct {
+		client      *redis.Client
+		sid         string
+		maxlifetime int64
+		values      map[interface{}]interface{}
+		lock        sync.RWMutex
+	}
+)
+
+// 配置中Redis客户端段名的前缀
+const REDIS_SECTION_PREFIX = "redis."
+
+// 共享Redis客户端的session provider名称
+const REDIS_SESSION_PROVIDER = "shared-redis"
+
+var (
+	redisClients      = map[string]*redis.Client{}
+	redisClientsLock  sync.RWMutex
+	redisConfigOnce   sync.Once
+	redisShutdownOnce sync.Once
+)
+
+// 注册名为name的共享Redis客户端，同名的客户端将被关闭并替换，服务退出时关闭；
+// 配置中[redis.<name>]段定义的客户端(键名见redis.Options)在首次使用或启动服务时自动注册，如：
+//
+//	[redis.default]
+//	addrs = 10.0.0.1:26379;10.0.0.2:26379
+//	master_name = mymaster
+//	password = ${secret:redis_password}
+func RegisterRedis(name string, client *redis.Client) {
+	redisClientsLock.Lock()
+	old := redisClients[name]
+	redisClients[name] = client
+	redisClientsLock.Unlock()
+	if old != nil && old != client {
+		old.Close()
+	}
+	redisShutdownOnce.Do(func() {
+		OnShutdown(closeRedisClients)
+	})
+}
+
+// 返回名为name的共享Redis客户端，不存在时返回错误
+func GetRedis(name string) (*redis.Client, error) {
+	loadRedisConfigs()
+	redisClientsLock.RLock()
+	client := redisClients[name]
+	redisClientsLock.RUnlock()
+	if client == nil {
+		return nil, fmt.Errorf("Redis %s is not registered.", name)
+	}
+	return client, nil
+}
+
+// 返回名为name的共享Redis客户端，不存在时返回nil
+func (c *Context) Redis(name string) *redis.Client {
+	client, err := GetRedis(name)
+	if err != nil {
+		Log.Error("%v", err)
+	}
+	return client
+}
+
+// 返回所有共享Redis客户端的连接与命令统计
+func RedisStatsAll() map[string]redis.Stats {
+	loadRedisConfigs()
+	redisClientsLock.RLock()
+	defer redisClientsLock.RUnlock()
+	stats := make(map[string]redis.Stats, len(redisClients))
+	for name, client := range redisClients {
+		stats[name] = client.Stats()
+	}
+	return stats
+}
+
+// 查询共享Redis客户端状态的操作，供后台管理路由使用
+var RedisStatsHandler = ApiHandler{
+	Desc:   "查询Redis客户端状态",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, RedisStatsAll())
+	},
+}.Reg()
+
+// 注册配置中定义的Redis客户端，只执行一次
+func loadRedisConfigs() {
+	redisConfigOnce.Do(func() {
+		opts, err := redisConfigs()
+		if err != nil {
+			Log.Error("%v", err)
+		}
+		names := make([]string, 0, len(opts))
+		for name := range opts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			client, err := redis.New(opts[name])
+			if err != nil {
+				Log.Error("Create Redis %s failed: %v", name, err)
+				continue
+			}
+			RegisterRedis(name, client)
+			Log.Sys("> Registered Redis %s (%s)", name, strings.Join(opts[name].Addrs, ","))
+		}
+	})
+}
+
+// 读取配置中[redis.<name>]段定义的客户端
+func redisConfigs() (map[string]redis.Options, error) {
+	values, serrs := bindValues()
+	sections := map[string]confpkg.Configer{}
+	for k, v := range values {
+		i := strings.Index(k, "::")
+		if i < 0 || !strings.HasPrefix(k, REDIS_SECTION_PREFIX) {
+			continue
+		}
+		name := k[len(REDIS_SECTION_PREFIX):i]
+		if sections[name] == nil {
+			sections[name] = confpkg.NewFakeConfig()
+		}
+		sections[name].Set(k[i+2:], v)
+	}
+
+	var errs []string
+	for _, fe := range serrs {
+		if strings.HasPrefix(fe.Key, REDIS_SECTION_PREFIX) {
+			errs = append(errs, fe.Error())
+		}
+	}
+	opts := make(map[string]redis.Options, len(sections))
+	for name, section := range sections {
+		var opt redis.Options
+		if err := confpkg.Bind(section, &opt); err != nil {
+			errs = append(errs, fmt.Sprintf("[%s%s] %v", REDIS_SECTION_PREFIX, name, err))
+			continue
+		}
+		opts[name] = opt
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return opts, fmt.Errorf("Invalid Redis config: %s", strings.Join(errs, "\n"))
+	}
+	return opts, nil
+}
+
+// 关闭所有共享Redis客户端，在服务退出时执行
+func closeRedisClients() {
+	redisClientsLock.Lock()
+	clients := redisClients
+	redisClients = map[string]*redis.Client{}
+	redisClientsLock.Unlock()
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// 创建Redis中的幂等记录存储，可在多实例间共享，键名以prefix开头
+func NewRedisIdempotencyStore(client *redis.Client, prefix string) IdempotencyStore {
+	return &redisIdempotencyStore{client: client, prefix: prefix}
+}
+
+// 处理中的请求以空值占位
+func (s *redisIdempotencyStore) Begin(key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	key = s.prefix + key
+	for i := 0; i < 2; i++ {
+		ok, err := redisSetNX(s.client, key, "", ttl)
+		if err != nil || ok {
+			return nil, false, err
+		}
+		b, err := redis.Bytes(s.client.Do("GET", key))
+		if err == redis.ErrNil {
+			// 恰好过期，重试
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if len(b) == 0 {
+			return nil, true, nil
+		}
+		resp := new(IdempotentResponse)
+		if err = json.Unmarshal(b, resp); err != nil {
+			return nil, false, err
+		}
+		return resp, false, nil
+	}
+	return nil, true, nil
+}
+
+func (s *redisIdempotencyStore) Save(key string, resp *IdempotentResponse, ttl time.Duration) error {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Do("SET", s.prefix+key, b, "PX", int64(ttl/time.Millisecond))
+	return err
+}
+
+func (s *redisIdempotencyStore) Release(key string) error {
+	_, err := s.client.Do("DEL", s.prefix+key)
+	return err
+}
+
+// 创建Redis中的随机数存储，可在多实例间共享，键名以prefix开头
+func NewRedisNonceStore(client *redis.Client, prefix string) NonceStore {
+	return &redisNonceStore{client: client, prefix: prefix}
+}
+
+func (s *redisNonceStore) Use(key string, ttl time.Duration) (bool, error) {
+	return redisSetNX(s.client, s.prefix+key, "1", ttl)
+}
+
+// 键不存在时设置值与过期时间，返回是否设置成功
+func redisSetNX(client *redis.Client, key, value string, ttl time.Duration) (bool, error) {
+	ms := int64(ttl / time.Millisecond)
+	if ms <= 0 {
+		ms = 1
+	}
+	reply, err := client.Do("SET", key, value, "NX", "PX", ms)
+	return reply != nil, err
+}
+
+// 创建Redis中的任务队列，可在多实例间共享，键名以prefix开头(Cluster模式下应含hash tag，如"{tasks}")；
+// 任务取出后即从Redis中移除，执行期间实例崩溃将丢失该任务
+func NewRedisTaskQueue(client *redis.Client, prefix string) TaskQueue {
+	return &redisTaskQueue{client: client, prefix: prefix}
+}
+
+// 到期的任务在ready列表中，延迟的任务在以执行时间为分值的delayed有序集合中
+func (q *redisTaskQueue) Push(t *Task) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if t.RunAt.After(time.Now()) {
+		_, err = q.client.Do("ZADD", q.prefix+":delayed", t.RunAt.UnixNano()/int64(time.Millisecond), b)
+	} else {
+		_, err = q.client.Do("RPUSH", q.prefix+":ready", b)
+	}
+	return err
+}
+
+func (q *redisTaskQueue) Pop(timeout time.Duration) (*Task, error) {
+	if err := q.promote(); err != nil {
+		return nil, err
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if timeout <= 0 {
+		b, err = redis.Bytes(q.client.Do("LPOP", q.prefix+":ready"))
+	} else {
+		// BLPOP以秒计，应小于客户端的ReadTimeout
+		sec := int64((timeout + time.Second - 1) / time.Second)
+		var reply interface{}
+		if reply, err = q.client.Do("BLPOP", q.prefix+":ready", sec); err == nil {
+			// 超时返回nil，否则为[key, value]
+			if a, ok := reply.([]interface{}); ok && len(a) == 2 {
+				b, err = redis.Bytes(a[1], nil)
+			} else {
+				err = redis.ErrNil
+			}
+		}
+	}
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := new(Task)
+	return t, json.Unmarshal(b, t)
+}
+
+// 将到期的延迟任务移入ready列表，ZREM成功者负责移动，避免多实例重复
+func (q *redisTaskQueue) promote() error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	due, err := redis.Strings(q.client.Do("ZRANGEBYSCORE", q.prefix+":delayed", "-inf", now, "LIMIT", 0, 100))
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+	for _, m := range due {
+		if n, err := redis.Int64(q.client.Do("ZREM", q.prefix+":delayed", m)); err != nil {
+			return err
+		} else if n == 1 {
+			if _, err = q.client.Do("RPUSH", q.prefix+":ready", m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (q *redisTaskQueue) Len() (int, error) {
+	ready, err := redis.Int64(q.client.Do("LLEN", q.prefix+":ready"))
+	if err != nil {
+		return 0, err
+	}
+	delayed, err := redis.Int64(q.client.Do("ZCARD", q.prefix+":delayed"))
+	return int(ready + delayed), err
+}
+
+func (q *redisTaskQueue) Dead(t *Task) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.Do("RPUSH", q.prefix+":dead", b)
+	return err
+}
+
+func (q *redisTaskQueue) DeadTasks() ([]*Task, error) {
+	list, err := redis.Strings(q.client.Do("LRANGE", q.prefix+":dead", 0, -1))
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+	tasks := make([]*Task, 0, len(list))
+	for _, m := range list {
+		t := new(Task)
+		if json.Unmarshal([]byte(m), t) == nil {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+func (q *redisTaskQueue) RemoveDead(id string) (*Task, error) {
+	list, err := redis.Strings(q.client.Do("LRANGE", q.prefix+":dead", 0, -1))
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+	for _, m := range list {
+		t := new(Task)
+		if json.Unmarshal([]byte(m), t) != nil || t.ID != id {
+			continue
+		}
+		n, err := redis.Int64(q.client.Do("LREM", q.prefix+":dead", 1, m))
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		return t, nil
+	}
+	return nil, nil
+}
+
+func (p *redisSessionProvider) SessionInit(maxlifetime int64, config string) error {
+	p.maxlifetime = maxlifetime
+	p.name = config
+	if p.name == "" {
+		p.name = "default"
+	}
+	// 客户端在首次读取session时获取，以便应用在启动服务前注册
+	return nil
+}
+
+func (p *redisSessionProvider) SessionRead(sid string) (session.Store, error) {
+	client, err := GetRedis(p.name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := redis.Bytes(client.Do("GET", "session:"+sid))
+	var kv map[interface{}]interface{}
+	if err == redis.ErrNil || len(b) == 0 {
+		kv = make(map[interface{}]interface{})
+	} else if err != nil {
+		return nil, err
+	} else if kv, err = session.DecodeGob(b); err != nil {
+		return nil, err
+	}
+	return &redisSessionStore{client: client, sid: sid, maxlifetime: p.maxlifetime, values: kv}, nil
+}
+
+func (p *redisSessionProvider) SessionExist(sid string) bool {
+	client, err := GetRedis(p.name)
+	if err != nil {
+		return false
+	}
+	n, err := redis.Int64(client.Do("EXISTS", "session:"+sid))
+	return err == nil && n > 0
+}
+
+func (p *redisSessionProvider) SessionRegenerate(oldsid, sid string) (session.Store, error) {
+	client, err := GetRedis(p.name)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := redis.Int64(client.Do("EXISTS", "session:"+oldsid)); n > 0 {
+		client.Do("RENAME", "session:"+oldsid, "session:"+sid)
+		client.Do("EXPIRE", "session:"+sid, p.maxlifetime)
+	}
+	return p.SessionRead(sid)
+}
+
+func (p *redisSessionProvider) SessionDestroy(sid string) error {
+	client, err := GetRedis(p.name)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do("DEL", "session:"+sid)
+	return err
+}
+
+// 由Redis的过期机制回收
+func (p *redisSessionProvider) SessionGC() {}
+
+func (p *redisSessionProvider) SessionAll() int { return 0 }
+
+func (s *redisSessionStore) Set(key, value interface{}) {
+	s.lock.Lock()
+	s.values[key] = value
+	s.lock.Unlock()
+}
+
+func (s *redisSessionStore) Get(key interface{}) interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.values[key]
+}
+
+func (s *redisSessionStore) Delete(key interface{}) {
+	s.lock.Lock()
+	delete(s.values, key)
+	s.lock.Unlock()
+}
+
+func (s *redisSessionStore) Flush() {
+	s.lock.Lock()
+	s.values = make(map[interface{}]interface{})
+	s.lock.Unlock()
+}
+
+func (s *redisSessionStore) SessionID() string {
+	return s.sid
+}
+
+func (s *redisSessionStore) SessionRelease(w http.ResponseWriter) {
+	s.lock.RLock()
+	b, err := session.EncodeGob(s.values)
+	s.lock.RUnlock()
+	if err != nil {
+		return
+	}
+	s.client.Do("SET", "session:"+s.sid, b, "EX", s.maxlifetime)
+}
+
+func init() {
+	session.Register(REDIS_SESSION_PROVIDER, &redisSessionProvider{})
+}
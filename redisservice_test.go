@@ -0,0 +1,254 @@
+package lessgo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lessgo/lessgo/cache"
+	"github.com/lessgo/lessgo/redis"
+)
+
+// 简易的Redis服务，支持SET(NX)、GET、DEL、SUBSCRIBE、PUBLISH及基本的列表命令，不处理过期与有序集合
+func fakeRedisServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	data := map[string]string{}
+	subs := map[string][]net.Conn{}
+	lists := map[string][]string{}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				br := bufio.NewReader(c)
+				for {
+					var n int
+					if _, err := fmt.Fscanf(br, "*%d\r\n", &n); err != nil {
+						return
+					}
+					args := make([]string, n)
+					for i := range args {
+						var size int
+						fmt.Fscanf(br, "$%d\r\n", &size)
+						b := make([]byte, size+2)
+						io.ReadFull(br, b)
+						args[i] = string(b[:size])
+					}
+					mu.Lock()
+					reply := "+OK\r\n"
+					switch args[0] {
+					case "SET":
+						if _, ok := data[args[1]]; ok && len(args) > 3 && args[3] == "NX" {
+							reply = "$-1\r\n"
+						} else {
+							data[args[1]] = args[2]
+						}
+					case "GET":
+						if v, ok := data[args[1]]; ok {
+							reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+						} else {
+							reply = "$-1\r\n"
+						}
+					case "DEL":
+						delete(data, args[1])
+						reply = ":1\r\n"
+					case "SUBSCRIBE":
+						reply = ""
+						for i, ch := range args[1:] {
+							subs[ch] = append(subs[ch], c)
+							reply += fmt.Sprintf("*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:%d\r\n", len(ch), ch, i+1)
+						}
+					case "RPUSH":
+						lists[args[1]] = append(lists[args[1]], args[2:]...)
+						reply = fmt.Sprintf(":%d\r\n", len(lists[args[1]]))
+					case "LPOP", "BLPOP":
+						reply = "$-1\r\n"
+						if args[0] == "BLPOP" {
+							reply = "*-1\r\n"
+						}
+						if l := lists[args[1]]; len(l) > 0 {
+							lists[args[1]] = l[1:]
+							reply = fmt.Sprintf("$%d\r\n%s\r\n", len(l[0]), l[0])
+							if args[0] == "BLPOP" {
+								reply = fmt.Sprintf("*2\r\n$%d\r\n%s\r\n", len(args[1]), args[1]) + reply
+							}
+						}
+					case "LLEN":
+						reply = fmt.Sprintf(":%d\r\n", len(lists[args[1]]))
+					case "LRANGE":
+						reply = fmt.Sprintf("*%d\r\n", len(lists[args[1]]))
+						for _, v := range lists[args[1]] {
+							reply += fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+						}
+					case "LREM":
+						reply = ":0\r\n"
+						for i, v := range lists[args[1]] {
+							if v == args[3] {
+								lists[args[1]] = append(lists[args[1]][:i:i], lists[args[1]][i+1:]...)
+								reply = ":1\r\n"
+								break
+							}
+						}
+					case "ZCARD":
+						reply = ":0\r\n"
+					case "ZRANGEBYSCORE":
+						reply = "*0\r\n"
+					case "PUBLISH":
+						msg := fmt.Sprintf("*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(args[2]), args[2])
+						for _, sub := range subs[args[1]] {
+							sub.Write([]byte(msg))
+						}
+						reply = fmt.Sprintf(":%d\r\n", len(subs[args[1]]))
+					}
+					c.Write([]byte(reply))
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestRedisService(t *testing.T) {
+	fname := CONFIG_DIR + "/app.yaml"
+	if _, err := os.Stat(fname); err == nil {
+		t.Skip(fname + " exists")
+	}
+	content := "redis.cache:\n  addrs: 10.0.0.1:26379;10.0.0.2:26379\n  master_name: mymaster\n  pool_size: 20\nredis.broken:\n  db: 1\n"
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+	opts, err := redisConfigs()
+	if err == nil || !strings.Contains(err.Error(), "[redis.broken]") {
+		t.Errorf("redisConfigs() error = %v", err)
+	}
+	opt := opts["cache"]
+	if len(opt.Addrs) != 2 || opt.MasterName != "mymaster" || opt.PoolSize != 20 || opt.ReadTimeout != 3*time.Second {
+		t.Errorf("redisConfigs() = %+v", opts)
+	}
+
+	ln := fakeRedisServer(t)
+	defer ln.Close()
+	client, err := redis.New(redis.Options{Addrs: []string{ln.Addr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterRedis("test", client)
+	if c, err := GetRedis("test"); err != nil || c != client {
+		t.Fatalf("GetRedis() = %v, %v", c, err)
+	}
+
+	nonces := NewRedisNonceStore(client, "nonce:")
+	if ok, err := nonces.Use("n1", time.Minute); !ok || err != nil {
+		t.Errorf("first Use() = %v, %v", ok, err)
+	}
+	if ok, err := nonces.Use("n1", time.Minute); ok || err != nil {
+		t.Errorf("second Use() = %v, %v", ok, err)
+	}
+
+	store := NewRedisIdempotencyStore(client, "idem:")
+	if resp, locked, err := store.Begin("k", time.Minute); resp != nil || locked || err != nil {
+		t.Fatalf("Begin() = %v, %v, %v", resp, locked, err)
+	}
+	if _, locked, _ := store.Begin("k", time.Minute); !locked {
+		t.Error("Begin() should be locked while processing")
+	}
+	store.Save("k", &IdempotentResponse{Fingerprint: "fp", Status: 201, Body: []byte("ok")}, time.Minute)
+	if resp, locked, err := store.Begin("k", time.Minute); err != nil || locked || resp == nil || resp.Status != 201 || string(resp.Body) != "ok" {
+		t.Errorf("Begin() after Save = %+v, %v, %v", resp, locked, err)
+	}
+}
+
+func TestSyncCacheTags(t *testing.T) {
+	ln := fakeRedisServer(t)
+	defer ln.Close()
+	client, err := redis.New(redis.Options{Addrs: []string{ln.Addr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err = SyncCacheTags(client, "test:tags"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cacheTagLock.Lock()
+		s := cacheTagSyncer
+		cacheTagSyncer = nil
+		cacheTagLock.Unlock()
+		s.close()
+	}()
+	ps, err := client.Subscribe("test:tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	// 本实例的失效被广播
+	InvalidateCacheTag("local")
+	if msg, err := ps.Receive(); err != nil || !strings.Contains(string(msg.Data), `"tags":["local"]`) {
+		t.Errorf("Receive() = %s, %v", msg.Data, err)
+	}
+
+	// 其它实例的失效作用于本实例
+	GetCache().Set("sync:1", 1, 1, 0, cache.Tags("remote"))
+	client.Do("PUBLISH", "test:tags", `{"node":"other","tags":["remote"]}`)
+	for i := 0; i < 100; i++ {
+		if _, ok := GetCache().Get("sync:1"); !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("cache entry of the remote tag is not invalidated")
+}
+
+func TestRedisTaskQueue(t *testing.T) {
+	ln := fakeRedisServer(t)
+	defer ln.Close()
+	client, err := redis.New(redis.Options{Addrs: []string{ln.Addr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	q := NewRedisTaskQueue(client, "{tasks}")
+	q.Push(&Task{ID: "1", Name: "a", Payload: []byte(`{"n":1}`)})
+	q.Push(&Task{ID: "2", Name: "b"})
+	if n, err := q.Len(); n != 2 || err != nil {
+		t.Errorf("Len() = %d, %v", n, err)
+	}
+	if task, err := q.Pop(time.Second); err != nil || task == nil || task.ID != "1" || string(task.Payload) != `{"n":1}` {
+		t.Errorf("Pop() = %+v, %v", task, err)
+	}
+	task, err := q.Pop(0)
+	if err != nil || task == nil || task.ID != "2" {
+		t.Fatalf("Pop(0) = %+v, %v", task, err)
+	}
+	if task, err := q.Pop(time.Second); task != nil || err != nil {
+		t.Errorf("Pop() on empty queue = %+v, %v", task, err)
+	}
+
+	q.Dead(task)
+	if dead, err := q.DeadTasks(); err != nil || len(dead) != 1 || dead[0].ID != "2" {
+		t.Errorf("DeadTasks() = %v, %v", dead, err)
+	}
+	if task, err := q.RemoveDead("2"); err != nil || task == nil {
+		t.Errorf("RemoveDead() = %v, %v", task, err)
+	}
+	if dead, _ := q.DeadTasks(); len(dead) != 0 {
+		t.Errorf("DeadTasks() after RemoveDead = %v", dead)
+	}
+}
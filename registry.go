@@ -0,0 +1,141 @@
+package lessgo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 操作与中间件的注册表，采用写时复制：
+// 注册时加锁复制并整体替换快照，查询时原子读取快照，无锁竞争，可在服务运行中动态注册。
+var (
+	apiHandlerRegistry    = newHandlerRegistry()
+	apiMiddlewareRegistry = newMiddlewareRegistry()
+)
+
+type (
+	handlerRegistry struct {
+		mu   sync.Mutex
+		snap atomic.Value // *handlerSnapshot
+	}
+	handlerSnapshot struct {
+		byId map[string]*ApiHandler
+		list []*ApiHandler // 按id排序，不含NilApiHandler
+	}
+
+	middlewareRegistry struct {
+		mu   sync.Mutex
+		snap atomic.Value // *middlewareSnapshot
+	}
+	middlewareSnapshot struct {
+		byName map[string]*ApiMiddleware
+		list   []*ApiMiddleware // 按名称排序
+	}
+)
+
+func newHandlerRegistry() *handlerRegistry {
+	r := new(handlerRegistry)
+	r.snap.Store(&handlerSnapshot{byId: map[string]*ApiHandler{}})
+	return r
+}
+
+func (r *handlerRegistry) load() *handlerSnapshot {
+	return r.snap.Load().(*handlerSnapshot)
+}
+
+func (r *handlerRegistry) get(id string) *ApiHandler {
+	return r.load().byId[id]
+}
+
+func (r *handlerRegistry) list() []*ApiHandler {
+	return r.load().list
+}
+
+// 添加操作，listed为false时不加入操作列表；id已存在时返回已注册的操作
+func (r *handlerRegistry) add(a *ApiHandler, listed bool) *ApiHandler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.load()
+	if h := old.byId[a.id]; h != nil {
+		return h
+	}
+	snap := &handlerSnapshot{byId: make(map[string]*ApiHandler, len(old.byId)+1), list: old.list}
+	for k, v := range old.byId {
+		snap.byId[k] = v
+	}
+	snap.byId[a.id] = a
+	if listed {
+		i := 0
+		for i < len(old.list) && old.list[i].id <= a.id {
+			i++
+		}
+		snap.list = make([]*ApiHandler, len(old.list)+1)
+		copy(snap.list, old.list[:i])
+		snap.list[i] = a
+		copy(snap.list[i+1:], old.list[i:])
+	}
+	r.snap.Store(snap)
+	return a
+}
+
+func newMiddlewareRegistry() *middlewareRegistry {
+	r := new(middlewareRegistry)
+	r.snap.Store(&middlewareSnapshot{byName: map[string]*ApiMiddleware{}})
+	return r
+}
+
+func (r *middlewareRegistry) load() *middlewareSnapshot {
+	return r.snap.Load().(*middlewareSnapshot)
+}
+
+func (r *middlewareRegistry) get(name string) *ApiMiddleware {
+	return r.load().byName[name]
+}
+
+func (r *middlewareRegistry) list() []*ApiMiddleware {
+	return r.load().list
+}
+
+// 添加或替换同名中间件
+func (r *middlewareRegistry) set(a *ApiMiddleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.load()
+	snap := &middlewareSnapshot{byName: make(map[string]*ApiMiddleware, len(old.byName)+1)}
+	for k, v := range old.byName {
+		snap.byName[k] = v
+	}
+	snap.byName[a.Name] = a
+	snap.list = make([]*ApiMiddleware, 0, len(old.list)+1)
+	added := false
+	for _, a2 := range old.list {
+		if a2.Name == a.Name {
+			continue
+		}
+		if !added && a.Name < a2.Name {
+			snap.list = append(snap.list, a)
+			added = true
+		}
+		snap.list = append(snap.list, a2)
+	}
+	if !added {
+		snap.list = append(snap.list, a)
+	}
+	r.snap.Store(snap)
+}
+
+// 从查询表中移除中间件，保留在列表中，直至同名中间件重新注册
+func (r *middlewareRegistry) del(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.load()
+	if _, ok := old.byName[name]; !ok {
+		return
+	}
+	snap := &middlewareSnapshot{byName: make(map[string]*ApiMiddleware, len(old.byName)), list: old.list}
+	for k, v := range old.byName {
+		if k != name {
+			snap.byName[k] = v
+		}
+	}
+	r.snap.Store(snap)
+}
@@ -0,0 +1,51 @@
+package lessgo
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRegistryConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ApiHandler{
+					Desc:    "registry test " + strconv.Itoa(i) + "-" + strconv.Itoa(j),
+					Method:  "GET",
+					Handler: func(c *Context) error { return nil },
+				}.Reg()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				for _, h := range Handlers() {
+					if getApiHandler(h.Id()) != h {
+						t.Errorf("handler %s not found by id", h.Id())
+						return
+					}
+				}
+				Middlewares()
+			}
+		}()
+	}
+	wg.Wait()
+
+	list := Handlers()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Id() >= list[i].Id() {
+			t.Fatalf("handlers not sorted by id at %d", i)
+		}
+	}
+
+	m := ApiMiddleware{Name: "registry test", Middleware: func(c *Context) error { return nil }}.Reg()
+	n := len(Middlewares())
+	m.SetConfig(nil)
+	if len(Middlewares()) != n || getApiMiddleware("registry test") != m {
+		t.Errorf("SetConfig changed the middleware list: %d -> %d", n, len(Middlewares()))
+	}
+}
@@ -1,10 +1,15 @@
 package lessgo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"github.com/lessgo/lessgo/pongo2"
 	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"strings"
 	"sync"
 	"time"
 )
@@ -17,12 +22,72 @@ type (
 	// Pongo2Render is a custom lessgo template renderer using Pongo2.
 	Pongo2Render struct {
 		set      *pongo2.TemplateSet
-		caching  bool // false=disable caching, true=enable caching
+		fsys     fs.FS // 不为nil时从虚拟文件系统读取模板
+		caching  bool  // false=disable caching, true=enable caching
 		tplCache map[string]*Tpl
+		gen      int // ClearCache的次数
 		sync.RWMutex
 	}
+
+	// 从虚拟文件系统加载模板的pongo2.TemplateLoader
+	fsTemplateLoader struct {
+		fsys fs.FS
+	}
+
+	// RendererConfig defines the config for UseRenderer middleware.
+	RendererConfig struct {
+		// 经RegisterRenderer注册的模板引擎名称
+		Engine string
+	}
 )
 
+var (
+	renderers     = map[string]Renderer{}
+	renderersLock sync.RWMutex
+)
+
+// 注册命名的模板引擎，供UseRenderer中间件按路由分组选用；
+// 内置"pongo2"(默认的模板引擎)与"html"(html/template，见HTMLRender)，其他引擎见render/jet、render/amber
+func RegisterRenderer(name string, r Renderer) {
+	renderersLock.Lock()
+	renderers[name] = r
+	renderersLock.Unlock()
+}
+
+// 返回已注册的模板引擎
+func GetRenderer(name string) (Renderer, bool) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	r, ok := renderers[name]
+	return r, ok
+}
+
+// 返回为路由分组选用模板引擎的中间件，c.Render()改用该引擎渲染，便于逐步迁移模板；
+// 每个引擎对应一个名为"模板引擎(<engine>)"的中间件，如：
+//
+//	lessgo.RegisterRenderer("jet", jet.New("views", !lessgo.Config.Debug))
+//	lessgo.Branch("/v2", "新版页面",
+//		lessgo.Leaf("/home", Home),
+//	).Use(lessgo.UseRenderer("jet"))
+func UseRenderer(engine string) *ApiMiddleware {
+	return ApiMiddleware{
+		Name:       "模板引擎(" + engine + ")",
+		Desc:       "为路由分组选用经RegisterRenderer注册的模板引擎",
+		Config:     RendererConfig{Engine: engine},
+		Middleware: useRenderer,
+	}.Reg()
+}
+
+func useRenderer(confObject interface{}) MiddlewareFunc {
+	config := confObject.(RendererConfig)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			c.renderer = config.Engine
+			return next(c)
+		}
+	}
+}
+
 // New creates a new Pongo2Render instance with custom Options.
 func NewPongo2Render(caching bool) *Pongo2Render {
 	return &Pongo2Render{
@@ -32,6 +97,17 @@ func NewPongo2Render(caching bool) *Pongo2Render {
 	}
 }
 
+// 创建从虚拟文件系统(如LayeredFS)读取模板的Pongo2Render，模板名为fsys中的路径；
+// 开启缓存时根模板在文件系统中的修改时间变化(如磁盘层新增了覆盖文件)后重新编译
+func NewPongo2RenderFS(fsys fs.FS, caching bool) *Pongo2Render {
+	return &Pongo2Render{
+		set:      pongo2.NewSet("lessgo", &fsTemplateLoader{fsys}),
+		fsys:     fsys,
+		caching:  caching,
+		tplCache: make(map[string]*Tpl),
+	}
+}
+
 // Render should render the template to the io.Writer.
 func (p *Pongo2Render) Render(w io.Writer, filename string, data interface{}, c *Context) error {
 	var (
@@ -39,27 +115,121 @@ func (p *Pongo2Render) Render(w io.Writer, filename string, data interface{}, c
 		data2    = pongo2.Context{}
 	)
 
+	// 复制一份数据，避免修改调用者的map
 	switch d := data.(type) {
 	case pongo2.Context:
-		data2 = d
+		for k, v := range d {
+			data2[k] = v
+		}
 	case map[string]interface{}:
-		data2 = pongo2.Context(d)
+		for k, v := range d {
+			data2[k] = v
+		}
+	case nil:
 	default:
 		b, _ := json.Marshal(data)
 		json.Unmarshal(b, &data2)
 	}
+	// 模板中可使用T()按请求语言翻译
+	if _, ok := data2["T"]; !ok && c != nil {
+		data2["T"] = c.T
+	}
+	// 内联脚本与样式可用{{ csp_nonce }}输出CSP随机数
+	if _, ok := data2["csp_nonce"]; !ok && c != nil {
+		data2["csp_nonce"] = c.CSPNonce()
+	}
 
+	var err error
 	if p.caching {
-		template = pongo2.Must(p.FromCache(filename))
+		template, err = p.FromCache(filename)
+	} else {
+		template, err = p.fromFile(filename)
+	}
+	if err == nil {
+		err = template.ExecuteWriter(data2, w)
+	}
+	if e, ok := err.(*pongo2.Error); ok {
+		return p.templateError(filename, e)
+	}
+	return err
+}
+
+// 不缓存时每次渲染均重新编译；调试模式下监视模板目录时，缓存编译结果直至目录变化
+func (p *Pongo2Render) fromFile(fname string) (*pongo2.Template, error) {
+	if p.fsys != nil || !watchingTemplate(fname) {
+		return p.set.FromFile(fname)
+	}
+	p.RLock()
+	tpl, has := p.tplCache[fname]
+	gen := p.gen
+	p.RUnlock()
+	if has {
+		return tpl.template, nil
+	}
+	newtpl, err := p.set.FromFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	p.Lock()
+	// 编译期间缓存被清空时不写入，避免缓存变化前的模板
+	if p.gen == gen {
+		p.tplCache[fname] = &Tpl{template: newtpl}
+	}
+	p.Unlock()
+	return newtpl, nil
+}
+
+// 清空模板编译缓存
+func (p *Pongo2Render) ClearCache() {
+	p.Lock()
+	p.tplCache = make(map[string]*Tpl)
+	p.gen++
+	p.Unlock()
+}
+
+// 转换为带模板源码的TemplateError
+func (p *Pongo2Render) templateError(filename string, e *pongo2.Error) *TemplateError {
+	te := &TemplateError{
+		Name:    e.Filename,
+		Line:    e.Line,
+		Column:  e.Column,
+		Message: e.ErrorMsg,
+	}
+	if te.Name == "" || te.Name == "<string>" {
+		te.Name = filename
+	}
+	if e.Sender != "" {
+		te.Message = e.Sender + ": " + te.Message
+	}
+	if e.Token != nil {
+		te.Message += " (near '" + e.Token.Val + "')"
+	}
+	if p.fsys != nil {
+		te.Source, _ = fs.ReadFile(p.fsys, fsPath(te.Name))
 	} else {
-		template = pongo2.Must(p.set.FromFile(filename))
+		te.Source, _ = ioutil.ReadFile(te.Name)
 	}
-	return template.ExecuteWriter(data2, w)
+	return te
 }
 
 func (p *Pongo2Render) FromCache(fname string) (*pongo2.Template, error) {
-	//从文件系统缓存中获取文件信息
-	fbytes, finfo, exist := lessgo.App.MemoryCache().GetCacheFile(fname)
+	var (
+		fbytes []byte
+		finfo  fs.FileInfo
+		exist  bool
+	)
+	if p.fsys != nil {
+		// 从虚拟文件系统中获取文件信息
+		fname = fsPath(fname)
+		var err error
+		if finfo, err = fs.Stat(p.fsys, fname); err == nil {
+			fbytes, err = fs.ReadFile(p.fsys, fname)
+		}
+		exist = err == nil
+	} else {
+		//从文件系统缓存中获取文件信息
+		fbytes, finfo, exist = lessgo.App.MemoryCache().GetCacheFile(fname)
+	}
 
 	// 文件已不存在
 	if !exist {
@@ -97,3 +267,19 @@ func (p *Pongo2Render) FromCache(fname string) (*pongo2.Template, error) {
 	p.tplCache[fname] = &Tpl{template: newtpl, modTime: finfo.ModTime()}
 	return newtpl, nil
 }
+
+// 解析模板路径，相对路径基于引用它的模板所在目录
+func (l *fsTemplateLoader) Abs(base, name string) string {
+	if base == "" || strings.HasPrefix(name, "/") {
+		return fsPath(name)
+	}
+	return fsPath(path.Join(path.Dir(base), name))
+}
+
+func (l *fsTemplateLoader) Get(name string) (io.Reader, error) {
+	b, err := fs.ReadFile(l.fsys, fsPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
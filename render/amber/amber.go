@@ -0,0 +1,111 @@
+// Package amber adapts github.com/eknkc/amber templates to lessgo's Renderer.
+//
+// Usage:
+//
+//	lessgo.RegisterRenderer("amber", amber.New("views", !lessgo.Config.Debug))
+//	lessgo.Branch("/legacy", "legacy pages",
+//		lessgo.Leaf("/home", Home),
+//	).Use(lessgo.UseRenderer("amber"))
+//
+// Template names are file paths below the template root. Amber compiles to
+// html/template, so map data gets an extra T field holding the request's
+// translate function, called as call .T "key".
+package amber
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo"
+
+	"github.com/eknkc/amber"
+)
+
+// Render renders amber templates.
+type Render struct {
+	dir     string
+	caching bool
+	options amber.Options
+	mu      sync.RWMutex
+	cache   map[string]*tpl
+}
+
+type tpl struct {
+	template *template.Template
+	modTime  time.Time
+}
+
+// New returns a Render loading templates from dir. With caching true compiled
+// templates are reused until the file's modification time changes.
+func New(dir string, caching bool) *Render {
+	return &Render{
+		dir:     dir,
+		caching: caching,
+		options: amber.DefaultOptions,
+		cache:   make(map[string]*tpl),
+	}
+}
+
+// SetOptions sets the amber compiler options, e.g. PrettyPrint.
+func (r *Render) SetOptions(options amber.Options) *Render {
+	r.mu.Lock()
+	r.options = options
+	r.cache = make(map[string]*tpl)
+	r.mu.Unlock()
+	return r
+}
+
+// Render implements lessgo.Renderer.
+func (r *Render) Render(w io.Writer, name string, data interface{}, c *lessgo.Context) error {
+	t, err := r.get(filepath.Join(r.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	if m, ok := data.(map[string]interface{}); ok && c != nil {
+		_, hasT := m["T"]
+		_, hasNonce := m["csp_nonce"]
+		if !hasT || !hasNonce {
+			// copy so the caller's map is not modified
+			m2 := make(map[string]interface{}, len(m)+2)
+			for k, v := range m {
+				m2[k] = v
+			}
+			if !hasT {
+				m2["T"] = c.T
+			}
+			if !hasNonce {
+				m2["csp_nonce"] = c.CSPNonce()
+			}
+			data = m2
+		}
+	}
+	return t.Execute(w, data)
+}
+
+func (r *Render) get(filename string) (*template.Template, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	t, ok := r.cache[filename]
+	options := r.options
+	r.mu.RUnlock()
+	if r.caching && ok && t.modTime.Equal(fi.ModTime()) {
+		return t.template, nil
+	}
+	compiled, err := amber.CompileFile(filename, options)
+	if err != nil {
+		return nil, err
+	}
+	if r.caching {
+		r.mu.Lock()
+		r.cache[filename] = &tpl{template: compiled, modTime: fi.ModTime()}
+		r.mu.Unlock()
+	}
+	return compiled, nil
+}
@@ -0,0 +1,88 @@
+// Package jet adapts github.com/CloudyKit/jet templates to lessgo's Renderer.
+//
+// Usage:
+//
+//	lessgo.RegisterRenderer("jet", jet.New("views", !lessgo.Config.Debug))
+//	lessgo.Branch("/v2", "new pages",
+//		lessgo.Leaf("/home", Home),
+//	).Use(lessgo.UseRenderer("jet"))
+//
+// Template names are slash-separated paths below the template root, e.g.
+// c.Render(200, "/home.jet", data). Map data is exposed both as template
+// variables ({{ name }}) and as the context ({{ .name }}); other data is the
+// context only. T translates with the request's language, as in pongo2.
+package jet
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/lessgo/lessgo"
+
+	"github.com/CloudyKit/jet/v6"
+)
+
+// Render renders jet templates.
+type Render struct {
+	set *jet.Set
+}
+
+// New returns a Render loading templates from dir. With caching false
+// (development) templates are reparsed on every render.
+func New(dir string, caching bool) *Render {
+	return newRender(jet.NewOSFileSystemLoader(dir), caching)
+}
+
+// NewFS returns a Render loading templates from fsys, e.g. a lessgo.LayeredFS.
+func NewFS(fsys fs.FS, caching bool) *Render {
+	return newRender(&fsLoader{fsys}, caching)
+}
+
+func newRender(loader jet.Loader, caching bool) *Render {
+	var opts []jet.Option
+	if !caching {
+		opts = append(opts, jet.InDevelopmentMode())
+	}
+	return &Render{set: jet.NewSet(loader, opts...)}
+}
+
+// Set returns the underlying template set, to add globals or custom loaders.
+func (r *Render) Set() *jet.Set {
+	return r.set
+}
+
+// Render implements lessgo.Renderer.
+func (r *Render) Render(w io.Writer, name string, data interface{}, c *lessgo.Context) error {
+	t, err := r.set.GetTemplate(name)
+	if err != nil {
+		return err
+	}
+	vars := make(jet.VarMap)
+	if m, ok := data.(map[string]interface{}); ok {
+		for k, v := range m {
+			vars.Set(k, v)
+		}
+	}
+	if _, ok := vars["T"]; !ok && c != nil {
+		vars.Set("T", c.T)
+	}
+	if _, ok := vars["csp_nonce"]; !ok && c != nil {
+		vars.Set("csp_nonce", c.CSPNonce())
+	}
+	return t.Execute(w, vars, data)
+}
+
+// fsLoader implements jet.Loader on an fs.FS.
+type fsLoader struct {
+	fsys fs.FS
+}
+
+func (l *fsLoader) Exists(name string) bool {
+	fi, err := fs.Stat(l.fsys, strings.TrimPrefix(name, "/"))
+	return err == nil && !fi.IsDir()
+}
+
+func (l *fsLoader) Open(name string) (io.ReadCloser, error) {
+	return l.fsys.Open(strings.TrimPrefix(name, "/"))
+}
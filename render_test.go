@@ -0,0 +1,88 @@
+package lessgo
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPongo2Render(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "index.tpl")
+	if err = ioutil.WriteFile(filename, []byte(`{{ T("hello") }} {{ name }}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	c, _ := testContext(req)
+	defer c.free()
+
+	r := NewPongo2Render(false)
+	var typedNil map[string]interface{}
+	for _, test := range []struct {
+		data interface{}
+		want string
+	}{
+		{nil, "hello "},
+		{typedNil, "hello "},
+		{map[string]interface{}{"name": "lessgo"}, "hello lessgo"},
+		{struct {
+			Name string `json:"name"`
+		}{"lessgo"}, "hello lessgo"},
+	} {
+		var buf bytes.Buffer
+		if err := r.Render(&buf, filename, test.data, c); err != nil {
+			t.Fatalf("Render(%#v): %v", test.data, err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("Render(%#v) = %q, want %q", test.data, buf.String(), test.want)
+		}
+		if m, ok := test.data.(map[string]interface{}); ok {
+			if _, ok := m["T"]; ok {
+				t.Errorf("Render(%#v) modified the caller's data", test.data)
+			}
+		}
+	}
+}
+
+type nameRenderer string
+
+func (r nameRenderer) Render(w io.Writer, name string, data interface{}, c *Context) error {
+	_, err := io.WriteString(w, string(r)+":"+name)
+	return err
+}
+
+func TestUseRenderer(t *testing.T) {
+	RegisterRenderer("test-jet", nameRenderer("jet"))
+	if _, ok := GetRenderer("test-jet"); !ok {
+		t.Fatal("renderer not registered")
+	}
+	m := UseRenderer("test-jet")
+	if m.Name != "模板引擎(test-jet)" || UseRenderer("test-jet") != m {
+		t.Errorf("UseRenderer = %q, not reused", m.Name)
+	}
+	h := func(c *Context) error { return c.Render(http.StatusOK, "home", nil) }
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	c, rec := testContext(req)
+	if err := m.Middleware.(ConfMiddlewareFunc)(RendererConfig{Engine: "test-jet"})(h)(c); err != nil {
+		t.Fatal(err)
+	}
+	c.free()
+	if rec.Body.String() != "jet:home" {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+
+	c, _ = testContext(req)
+	defer c.free()
+	if err := m.Middleware.(ConfMiddlewareFunc)(RendererConfig{Engine: "missing"})(h)(c); err != ErrRendererNotRegistered {
+		t.Errorf("unregistered engine: %v", err)
+	}
+}
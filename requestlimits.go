@@ -0,0 +1,77 @@
+package lessgo
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// 请求URI与请求头的限制，0为不限制，默认由listen::maxuribytes、listen::maxheadercount、listen::maxheaderbytes配置
+type RequestLimits struct {
+	MaxURIBytes    int // 请求URI(含查询参数)的最大字节数，超出时响应414
+	MaxHeaderCount int // 请求头的最大个数(同名的多个值分别计数)，超出时响应431
+	MaxHeaderBytes int // 单个请求头(名称与值)的最大字节数，超出时响应431
+}
+
+// 超出限制时返回的错误码，可用RegisterErrorCode替换消息与文档链接
+const (
+	ERR_URI_TOO_LONG     = "URI_TOO_LONG"
+	ERR_TOO_MANY_HEADERS = "TOO_MANY_HEADERS"
+	ERR_HEADER_TOO_LARGE = "HEADER_TOO_LARGE"
+)
+
+var requestLimits atomic.Value // RequestLimits
+
+func init() {
+	requestLimits.Store(RequestLimits{})
+	RegisterErrorCode(
+		ErrorCode{Code: ERR_URI_TOO_LONG, Status: http.StatusRequestURITooLong, Message: "request URI exceeds %d bytes"},
+		ErrorCode{Code: ERR_TOO_MANY_HEADERS, Status: http.StatusRequestHeaderFieldsTooLarge, Message: "request has more than %d headers"},
+		ErrorCode{Code: ERR_HEADER_TOO_LARGE, Status: http.StatusRequestHeaderFieldsTooLarge, Message: "request header %s exceeds %d bytes"},
+	)
+}
+
+// 设置请求URI与请求头的限制，在路由之前检查，超出时按错误码格式响应414或431
+func SetRequestLimits(l RequestLimits) {
+	requestLimits.Store(l)
+}
+
+// 返回当前的请求限制
+func GetRequestLimits() RequestLimits {
+	return requestLimits.Load().(RequestLimits)
+}
+
+// 按配置设置请求限制
+func setRequestLimits(c *config) {
+	SetRequestLimits(RequestLimits{
+		MaxURIBytes:    c.Listen.MaxURIBytes,
+		MaxHeaderCount: c.Listen.MaxHeaderCount,
+		MaxHeaderBytes: c.Listen.MaxHeaderBytes,
+	})
+}
+
+// 检查请求是否超出限制
+func checkRequestLimits(req *http.Request) error {
+	l := GetRequestLimits()
+	if l.MaxURIBytes > 0 && len(req.RequestURI) > l.MaxURIBytes {
+		return Err(ERR_URI_TOO_LONG, l.MaxURIBytes)
+	}
+	if l.MaxHeaderCount <= 0 && l.MaxHeaderBytes <= 0 {
+		return nil
+	}
+	count := 0
+	for k, vs := range req.Header {
+		count += len(vs)
+		if l.MaxHeaderBytes <= 0 {
+			continue
+		}
+		for _, v := range vs {
+			if len(k)+len(v) > l.MaxHeaderBytes {
+				return Err(ERR_HEADER_TOO_LARGE, k, l.MaxHeaderBytes)
+			}
+		}
+	}
+	if l.MaxHeaderCount > 0 && count > l.MaxHeaderCount {
+		return Err(ERR_TOO_MANY_HEADERS, l.MaxHeaderCount)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestLimits(t *testing.T) {
+	old := GetRequestLimits()
+	defer SetRequestLimits(old)
+	SetRequestLimits(RequestLimits{MaxURIBytes: 20, MaxHeaderCount: 3, MaxHeaderBytes: 32})
+
+	for _, test := range []struct {
+		uri    string
+		header http.Header
+		status int
+		code   string
+	}{
+		{"/limits?a=" + strings.Repeat("x", 10), http.Header{"X-A": {"1"}}, http.StatusNotFound, ""},
+		{"/limits?a=" + strings.Repeat("x", 11), nil, http.StatusRequestURITooLong, ERR_URI_TOO_LONG},
+		{"/limits", http.Header{"X-A": {"1", "2"}, "X-B": {"3", "4"}}, http.StatusRequestHeaderFieldsTooLarge, ERR_TOO_MANY_HEADERS},
+		{"/limits", http.Header{"Cookie": {strings.Repeat("c", 27)}}, http.StatusRequestHeaderFieldsTooLarge, ERR_HEADER_TOO_LARGE},
+	} {
+		req := httptest.NewRequest(GET, test.uri, nil)
+		for k, v := range test.header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("%s %v: status = %d, want %d", test.uri, test.header, rec.Code, test.status)
+			continue
+		}
+		if test.code == "" {
+			continue
+		}
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["code"] != test.code || body["message"] == "" {
+			t.Errorf("%s %v: body = %s", test.uri, test.header, rec.Body.String())
+		}
+	}
+}
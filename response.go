@@ -2,8 +2,12 @@ package lessgo
 
 import (
 	"bufio"
+	"bytes"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Response wraps an http.ResponseWriter and implements its interface to be used
@@ -18,6 +22,52 @@ type Response struct {
 
 var _ http.ResponseWriter = new(Response)
 
+// 中间件包装http.ResponseWriter时嵌入的基础类型，透传Flush、Hijack与CloseNotify
+type responseWriterWrapper struct {
+	http.ResponseWriter
+}
+
+func (w responseWriterWrapper) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w responseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// 底层不支持时返回nil通道(永不就绪)
+func (w responseWriterWrapper) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+// 复制完整响应体的ResponseWriter，超出上限时放弃复制
+type captureWriter struct {
+	responseWriterWrapper
+	buf      bytes.Buffer
+	max      int64
+	overflow bool
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if !w.overflow {
+		if w.max > 0 && int64(w.buf.Len()+len(b)) > w.max {
+			w.overflow = true
+			w.buf.Reset()
+		} else {
+			w.buf.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
 // NewResponse creates a new instance of Response.
 func NewResponse(w http.ResponseWriter) *Response {
 	return &Response{writer: w}
@@ -92,8 +142,13 @@ func (resp *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 // when the underlying connection has gone away.
 // This mechanism can be used to cancel long operations on the server if the
 // client has disconnected before the response is ready.
+// If the underlying writer does not support it, a nil channel that never
+// fires is returned.
 func (resp *Response) CloseNotify() <-chan bool {
-	return resp.writer.(http.CloseNotifier).CloseNotify()
+	if cn, ok := resp.writer.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
 }
 
 // Status returns the HTTP status code of the response.
@@ -131,3 +186,170 @@ func (resp *Response) init(rw http.ResponseWriter) {
 func (resp *Response) free() {
 	resp.writer = nil
 }
+
+// 返回设置Cache-Control响应头的构造器，以已有的响应头为基础，每次调用即更新响应头，如：
+//
+//	c.Response().CacheControl().Public().MaxAge(365 * 24 * time.Hour).Immutable()
+func (resp *Response) CacheControl() *CacheControl {
+	cc := &CacheControl{header: resp.Header()}
+	for _, d := range strings.Split(cc.header.Get(HeaderCacheControl), ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, value := d, ""
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			name, value = d[:i], d[i+1:]
+		}
+		cc.directives = append(cc.directives, cacheDirective{strings.ToLower(name), value})
+	}
+	return cc
+}
+
+// 将请求头追加到Vary响应头，忽略已存在的(不区分大小写)；
+// 响应内容取决于这些请求头(如Accept-Encoding、Origin、Accept-Language)时，中间件应调用以免共享缓存返回错误的版本
+func (resp *Response) Vary(headers ...string) {
+	header := resp.Header()
+	var vary []string
+	for _, v := range header[HeaderVary] {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				vary = append(vary, h)
+			}
+		}
+	}
+	changed := false
+next:
+	for _, h := range headers {
+		h = http.CanonicalHeaderKey(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		for _, v := range vary {
+			if v == "*" || strings.EqualFold(v, h) {
+				continue next
+			}
+		}
+		vary = append(vary, h)
+		changed = true
+	}
+	if changed {
+		header.Set(HeaderVary, strings.Join(vary, ", "))
+	}
+}
+
+type (
+	// Cache-Control响应头构造器，见Response.CacheControl
+	CacheControl struct {
+		header     http.Header
+		directives []cacheDirective
+	}
+
+	cacheDirective struct {
+		name, value string
+	}
+)
+
+// 允许共享缓存(如CDN)缓存，取消private
+func (cc *CacheControl) Public() *CacheControl {
+	cc.del("private")
+	return cc.set("public", "")
+}
+
+// 仅允许客户端缓存，取消public
+func (cc *CacheControl) Private() *CacheControl {
+	cc.del("public")
+	return cc.set("private", "")
+}
+
+// 缓存的有效时长，按秒取整
+func (cc *CacheControl) MaxAge(d time.Duration) *CacheControl {
+	return cc.set("max-age", cacheSeconds(d))
+}
+
+// 共享缓存的有效时长，覆盖max-age
+func (cc *CacheControl) SMaxAge(d time.Duration) *CacheControl {
+	return cc.set("s-maxage", cacheSeconds(d))
+}
+
+// 有效期内内容不会改变，客户端刷新时也无需重新验证，适用于带版本号的静态资源
+func (cc *CacheControl) Immutable() *CacheControl {
+	return cc.set("immutable", "")
+}
+
+// 每次使用缓存前须向服务器验证
+func (cc *CacheControl) NoCache() *CacheControl {
+	return cc.set("no-cache", "")
+}
+
+// 禁止缓存，取消其他全部指令
+func (cc *CacheControl) NoStore() *CacheControl {
+	cc.directives = cc.directives[:0]
+	return cc.set("no-store", "")
+}
+
+// 过期后须向服务器验证，不得使用过期的缓存
+func (cc *CacheControl) MustRevalidate() *CacheControl {
+	return cc.set("must-revalidate", "")
+}
+
+// 禁止中间代理转换内容(如压缩图片)
+func (cc *CacheControl) NoTransform() *CacheControl {
+	return cc.set("no-transform", "")
+}
+
+// 过期后的d时长内可先返回过期内容，同时在后台重新验证
+func (cc *CacheControl) StaleWhileRevalidate(d time.Duration) *CacheControl {
+	return cc.set("stale-while-revalidate", cacheSeconds(d))
+}
+
+// 验证出错时d时长内可返回过期内容
+func (cc *CacheControl) StaleIfError(d time.Duration) *CacheControl {
+	return cc.set("stale-if-error", cacheSeconds(d))
+}
+
+// 返回当前的Cache-Control响应头
+func (cc *CacheControl) String() string {
+	return cc.header.Get(HeaderCacheControl)
+}
+
+func (cc *CacheControl) set(name, value string) *CacheControl {
+	if name != "no-store" {
+		cc.del("no-store")
+	}
+	found := false
+	for i := range cc.directives {
+		if cc.directives[i].name == name {
+			cc.directives[i].value = value
+			found = true
+		}
+	}
+	if !found {
+		cc.directives = append(cc.directives, cacheDirective{name, value})
+	}
+	parts := make([]string, len(cc.directives))
+	for i, d := range cc.directives {
+		parts[i] = d.name
+		if d.value != "" {
+			parts[i] += "=" + d.value
+		}
+	}
+	cc.header.Set(HeaderCacheControl, strings.Join(parts, ", "))
+	return cc
+}
+
+func (cc *CacheControl) del(name string) {
+	for i, d := range cc.directives {
+		if d.name == name {
+			cc.directives = append(cc.directives[:i], cc.directives[i+1:]...)
+			return
+		}
+	}
+}
+
+func cacheSeconds(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
@@ -0,0 +1,61 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheControl(t *testing.T) {
+	resp := NewResponse(httptest.NewRecorder())
+	cc := resp.CacheControl().Private().MaxAge(90 * time.Second)
+	if got := resp.Header().Get(HeaderCacheControl); got != "private, max-age=90" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	cc.Public().MaxAge(365 * 24 * time.Hour).Immutable()
+	if got := cc.String(); got != "max-age=31536000, public, immutable" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	// 以已有的响应头为基础
+	resp.Header().Set(HeaderCacheControl, "no-cache, Max-Age=5")
+	if got := resp.CacheControl().MustRevalidate().SMaxAge(time.Minute).String(); got != "no-cache, max-age=5, must-revalidate, s-maxage=60" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := resp.CacheControl().NoStore().String(); got != "no-store" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if got := resp.CacheControl().StaleWhileRevalidate(time.Minute).String(); got != "stale-while-revalidate=60" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+}
+
+func TestVary(t *testing.T) {
+	resp := NewResponse(httptest.NewRecorder())
+	resp.Vary("accept-encoding")
+	resp.Vary("Origin", "Accept-Encoding", "")
+	resp.Header().Add(HeaderVary, "X-Custom")
+	resp.Vary("Accept-Language", "x-custom")
+	if got := resp.Header()[HeaderVary]; len(got) != 1 || got[0] != "Accept-Encoding, Origin, X-Custom, Accept-Language" {
+		t.Errorf("Vary = %q", got)
+	}
+
+	resp.Header().Set(HeaderVary, "*")
+	resp.Vary("Origin")
+	if got := resp.Header().Get(HeaderVary); got != "*" {
+		t.Errorf("Vary = %q", got)
+	}
+}
+
+func TestResponseWriterWrapper(t *testing.T) {
+	// httptest.ResponseRecorder不支持Hijack与CloseNotify
+	w := responseWriterWrapper{httptest.NewRecorder()}
+	if conn, rw, err := w.Hijack(); conn != nil || rw != nil || err != http.ErrNotSupported {
+		t.Errorf("Hijack() = %v, %v, %v", conn, rw, err)
+	}
+	if ch := w.CloseNotify(); ch != nil {
+		t.Errorf("CloseNotify() = %v", ch)
+	}
+	w.Flush()
+}
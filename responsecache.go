@@ -0,0 +1,119 @@
+package lessgo
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lessgo/lessgo/cache"
+)
+
+type (
+	// ResponseCacheConfig defines the config for response cache middleware.
+	ResponseCacheConfig struct {
+		// 缓存时长，单位秒
+		TTL int64
+
+		// 参与计算缓存键的请求头；携带Cookie或Authorization的请求仅当该头被列入时才缓存
+		VaryHeaders []string
+
+		// 允许缓存的最大响应体
+		MaxBytes int64
+
+		// 缓存响应的标签，可由InvalidateCacheTag清除；处理函数可用Context.CacheTags追加
+		Tags []string
+	}
+
+	// 缓存的响应
+	cachedResponse struct {
+		status int
+		header http.Header
+		body   []byte
+	}
+)
+
+const HeaderXCache = "X-Cache"
+
+// Context中存放缓存响应标签的键名
+const cacheTagsKey = "__cache_tags__"
+
+var ResponseCache = ApiMiddleware{
+	Name: "响应缓存",
+	Desc: "将GET请求的200响应缓存至全局缓存存储(见SetCache)，有效期内直接返回缓存",
+	Config: ResponseCacheConfig{
+		TTL:         60,
+		VaryHeaders: []string{HeaderAcceptEncoding},
+		MaxBytes:    1 * MB,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(ResponseCacheConfig)
+		ttl := time.Duration(config.TTL) * time.Second
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				if req.Method != GET || !coalescable(req, config.VaryHeaders) {
+					return next(c)
+				}
+				store := GetCache()
+				key := "response:" + coalesceKey(req, config.VaryHeaders)
+				if v, ok := store.Get(key); ok {
+					cached := v.(*cachedResponse)
+					header := c.response.Header()
+					for k, v := range cached.header {
+						header[k] = v
+					}
+					header.Set(HeaderXCache, "HIT")
+					c.WriteHeader(cached.status)
+					_, err := c.response.Write(cached.body)
+					return err
+				}
+
+				c.response.Vary(config.VaryHeaders...)
+				c.response.Header().Set(HeaderXCache, "MISS")
+				w := &captureWriter{
+					responseWriterWrapper: responseWriterWrapper{c.response.writer},
+					max:                   config.MaxBytes,
+				}
+				c.response.writer = w
+				err := next(c)
+				c.response.writer = w.ResponseWriter
+				if err != nil || w.overflow || c.response.Status() != http.StatusOK || !cacheableResponse(c.response.Header()) {
+					return err
+				}
+				header := make(http.Header, len(c.response.Header()))
+				cost := int64(w.buf.Len())
+				for k, v := range c.response.Header() {
+					if k == HeaderXCache {
+						continue
+					}
+					header[k] = v
+					for _, s := range v {
+						cost += int64(len(k) + len(s))
+					}
+				}
+				tags := config.Tags
+				if v, ok := c.Get(cacheTagsKey).([]string); ok {
+					tags = append(tags[:len(tags):len(tags)], v...)
+				}
+				store.Set(key, &cachedResponse{status: http.StatusOK, header: header, body: w.buf.Bytes()}, cost, ttl, cache.Tags(tags...))
+				return nil
+			}
+		}
+	},
+}.Reg()
+
+// 为当前请求的响应追加缓存标签，如"user:42"，供ResponseCache使用
+func (c *Context) CacheTags(tags ...string) {
+	v, _ := c.Get(cacheTagsKey).([]string)
+	c.Set(cacheTagsKey, append(v, tags...))
+}
+
+// 响应是否允许被共享缓存
+func cacheableResponse(header http.Header) bool {
+	if header.Get(HeaderSetCookie) != "" {
+		return false
+	}
+	cc := strings.ToLower(header.Get(HeaderCacheControl))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private") && !strings.Contains(cc, "no-cache")
+}
@@ -0,0 +1,65 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	h := ResponseCache.Middleware.(Middleware).getMiddlewareFunc(ResponseCacheConfig{
+		TTL:         60,
+		VaryHeaders: []string{HeaderAcceptEncoding},
+		MaxBytes:    MB,
+	})(func(c *Context) error {
+		calls++
+		if c.QueryParam("private") != "" {
+			c.response.Header().Set(HeaderCacheControl, "private")
+		}
+		if user := c.QueryParam("user"); user != "" {
+			c.CacheTags("user:" + user)
+		}
+		return c.String(http.StatusOK, "hello")
+	})
+
+	get := func(url string, header http.Header) (string, string) {
+		req, _ := http.NewRequest(GET, url, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		c, rec := testContext(req)
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		if header == nil && rec.Header().Get(HeaderVary) != HeaderAcceptEncoding {
+			t.Errorf("%s: Vary = %q", url, rec.Header().Get(HeaderVary))
+		}
+		return rec.Header().Get(HeaderXCache), rec.Body.String()
+	}
+	if x, body := get("/cached", nil); x != "MISS" || body != "hello" {
+		t.Errorf("first = %s %q", x, body)
+	}
+	if x, body := get("/cached", nil); x != "HIT" || body != "hello" {
+		t.Errorf("second = %s %q", x, body)
+	}
+	get("/cached", http.Header{HeaderCookie: {"sid=1"}})
+	get("/cached?private=1", nil)
+	get("/cached?private=1", nil)
+	if calls != 4 {
+		t.Errorf("handler calls = %d, want 4", calls)
+	}
+
+	get("/cached?user=42", nil)
+	if x, _ := get("/cached?user=42", nil); x != "HIT" {
+		t.Errorf("tagged = %s", x)
+	}
+	if n := InvalidateCacheTag("user:42"); n != 1 {
+		t.Errorf("InvalidateCacheTag() = %d, want 1", n)
+	}
+	if x, _ := get("/cached?user=42", nil); x != "MISS" {
+		t.Errorf("after invalidation = %s", x)
+	}
+	if _, ok := GetCache().(interface{ Len() int }); !ok {
+		t.Error("default cache store should be cache.Cache")
+	}
+}
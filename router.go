@@ -1,6 +1,9 @@
 package lessgo
 
 import (
+	"net/http"
+	"strings"
+
 	"github.com/lessgo/lessgo/utils"
 )
 
@@ -9,6 +12,9 @@ import (
 type Router struct {
 	trees map[string]*node
 
+	// 不含参数的静态路由，按方法、路径直接查找，未命中时再匹配trees
+	statics map[string]map[string]HandlerFunc
+
 	// Enables automatic redirection if the current route can't be matched but a
 	// handler for the path with (without) the trailing slash exists.
 	// For example if /foo/ is requested but a route only exists for /foo, the
@@ -69,6 +75,27 @@ func newRouter() *Router {
 	}
 }
 
+// NewRouter returns a Router that serves requests by itself, outside of the
+// app's middleware chain, with the default NotFound and MethodNotAllowed
+// handlers. It's meant to test the router in isolation, e.g. with
+// lessgotest.RouterConformance.
+func NewRouter() *Router {
+	r := newRouter()
+	r.NotFound = defaultNotFoundHandler
+	r.MethodNotAllowed = defaultMethodNotAllowedHandler
+	return r
+}
+
+// ServeHTTP dispatches req to the handle registered for its method and path,
+// errors returned by the handle are written as plain text.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c := NewContext(w, req)
+	if err := r.process(chainEndHandler)(c); err != nil && !c.response.Committed() {
+		code, msg := errorStatus(err, nil)
+		c.String(code, msg)
+	}
+}
+
 // Handle registers a new request handle with the given path and method.
 //
 // For GET, POST, PUT, PATCH and DELETE requests the respective shortcut
@@ -93,6 +120,16 @@ func (r *Router) Handle(method, path string, handle HandlerFunc) {
 	}
 
 	root.addRoute(path, handle)
+
+	if !strings.ContainsAny(path, ":*") {
+		if r.statics == nil {
+			r.statics = make(map[string]map[string]HandlerFunc)
+		}
+		if r.statics[method] == nil {
+			r.statics[method] = make(map[string]HandlerFunc)
+		}
+		r.statics[method][path] = handle
+	}
 }
 
 func (r *Router) allowed(path, reqMethod string, pkeys, pvalues []string) string {
@@ -140,9 +177,11 @@ func (r *Router) process(next HandlerFunc) HandlerFunc {
 		req := c.request
 		path := req.URL.Path
 		if root := r.trees[req.Method]; root != nil {
-			var handle HandlerFunc
 			var tsr bool
-			handle, c.pkeys, c.pvalues, tsr = root.getValue(path, c.pkeys, c.pvalues)
+			handle := r.statics[req.Method][path]
+			if handle == nil {
+				handle, c.pkeys, c.pvalues, tsr = root.getValue(path, c.pkeys, c.pvalues)
+			}
 			if handle != nil {
 				if err := handle(c); err != nil {
 					return err
@@ -195,7 +234,7 @@ func (r *Router) process(next HandlerFunc) HandlerFunc {
 			if r.HandleMethodNotAllowed {
 				if allow := r.allowed(path, req.Method, c.pkeys, c.pvalues); len(allow) > 0 {
 					c.response.Header().Set("Allow", allow)
-					if err := r.MethodNotAllowed(c); err != nil {
+					if err := handleMethodNotAllowed(c, r.MethodNotAllowed); err != nil {
 						return err
 					}
 					return next(c)
@@ -204,7 +243,7 @@ func (r *Router) process(next HandlerFunc) HandlerFunc {
 		}
 
 		// Handle 404
-		if err := r.NotFound(c); err != nil {
+		if err := handleNotFound(c, r.NotFound); err != nil {
 			return err
 		}
 		return next(c)
@@ -0,0 +1,55 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRouterStatics(t *testing.T) {
+	r := NewRouter()
+	var got string
+	r.Handle("GET", "/users", func(c *Context) error { got = "users"; return nil })
+	r.Handle("GET", "/users/:id", func(c *Context) error { got = "id:" + c.PathParam("id"); return nil })
+	r.Handle("GET", "/files/*path", func(c *Context) error { got = "files"; return nil })
+	if len(r.statics["GET"]) != 1 || r.statics["GET"]["/users"] == nil {
+		t.Fatalf("statics = %v", r.statics)
+	}
+	for path, want := range map[string]string{"/users": "users", "/users/7": "id:7", "/files/a": "files"} {
+		got = ""
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		if got != want {
+			t.Errorf("%s handled by %q, want %q", path, got, want)
+		}
+	}
+}
+
+// newBigRouter returns a router with n static and n parameterized routes.
+func newBigRouter(n int) *Router {
+	r := NewRouter()
+	h := func(c *Context) error { return nil }
+	for i := 0; i < n; i++ {
+		r.Handle("GET", "/api/v1/resource"+strconv.Itoa(i)+"/list", h)
+		r.Handle("GET", "/api/v1/item"+strconv.Itoa(i)+"/:id", h)
+	}
+	return r
+}
+
+func benchmarkRoute(b *testing.B, path string) {
+	h := newBigRouter(1000).process(chainEndHandler)
+	req, _ := http.NewRequest("GET", path, nil)
+	c := app.newContext(new(Response), req)
+	rw := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.init(rw, req)
+		h(c)
+		c.free()
+	}
+}
+
+func BenchmarkRouteStatic2000(b *testing.B) { benchmarkRoute(b, "/api/v1/resource999/list") }
+func BenchmarkRouteParam2000(b *testing.B)  { benchmarkRoute(b, "/api/v1/item999/42") }
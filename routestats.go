@@ -0,0 +1,163 @@
+package lessgo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// 路由的耗时与错误统计，分位数按最近ROUTE_STATS_WINDOW个请求计算
+	RouteStats struct {
+		Method    string  `json:"method"`
+		Path      string  `json:"path"`
+		InFlight  int64   `json:"in_flight"`
+		Count     uint64  `json:"count"`
+		Errors    uint64  `json:"errors"` // 返回错误、5xx或发生恐慌的请求数
+		ErrorRate float64 `json:"error_rate"`
+		AvgMs     float64 `json:"avg_ms"`
+		P50Ms     float64 `json:"p50_ms"`
+		P90Ms     float64 `json:"p90_ms"`
+		P99Ms     float64 `json:"p99_ms"`
+		MaxMs     float64 `json:"max_ms"`
+	}
+
+	// 路由的统计数据
+	routeMetrics struct {
+		inFlight      int64
+		count, errors uint64
+		total         time.Duration
+		max           time.Duration
+		window        []time.Duration // 最近请求耗时的环形缓冲区
+		next          int
+		lock          sync.Mutex
+	}
+
+	durations []time.Duration
+)
+
+// 计算耗时分位数的最近请求数
+const ROUTE_STATS_WINDOW = 1024
+
+var (
+	routeMetricsMap  = map[string]*routeMetrics{}
+	routeMetricsLock sync.RWMutex
+)
+
+// 返回所有路由的统计，按路径与方法排序
+func RouteStatsAll() []RouteStats {
+	routeMetricsLock.RLock()
+	metrics := make(map[string]*routeMetrics, len(routeMetricsMap))
+	for key, m := range routeMetricsMap {
+		metrics[key] = m
+	}
+	routeMetricsLock.RUnlock()
+	stats := make([]RouteStats, 0, len(metrics))
+	for key, m := range metrics {
+		s := m.stats()
+		i := strings.Index(key, " ")
+		s.Method, s.Path = key[:i], key[i+1:]
+		stats = append(stats, s)
+	}
+	sort.Sort(routeStatsSlice(stats))
+	return stats
+}
+
+// 返回指定路由的统计
+func readRouteStats(method, path string) (RouteStats, bool) {
+	routeMetricsLock.RLock()
+	m := routeMetricsMap[method+" "+path]
+	routeMetricsLock.RUnlock()
+	if m == nil {
+		return RouteStats{}, false
+	}
+	s := m.stats()
+	s.Method, s.Path = method, path
+	return s, true
+}
+
+// 返回路由的统计数据，重新注册路由时沿用
+func routeMetricsFor(method, path string) *routeMetrics {
+	key := method + " " + path
+	routeMetricsLock.Lock()
+	defer routeMetricsLock.Unlock()
+	m := routeMetricsMap[key]
+	if m == nil {
+		m = &routeMetrics{}
+		routeMetricsMap[key] = m
+	}
+	return m
+}
+
+func (m *routeMetrics) observe(d time.Duration, failed bool) {
+	m.lock.Lock()
+	m.count++
+	if failed {
+		m.errors++
+	}
+	m.total += d
+	if d > m.max {
+		m.max = d
+	}
+	if len(m.window) < ROUTE_STATS_WINDOW {
+		m.window = append(m.window, d)
+	} else {
+		m.window[m.next] = d
+		m.next = (m.next + 1) % ROUTE_STATS_WINDOW
+	}
+	m.lock.Unlock()
+}
+
+func (m *routeMetrics) stats() RouteStats {
+	m.lock.Lock()
+	s := RouteStats{
+		InFlight: atomic.LoadInt64(&m.inFlight),
+		Count:    m.count,
+		Errors:   m.errors,
+		MaxMs:    durationMs(m.max),
+	}
+	if m.count > 0 {
+		s.ErrorRate = float64(m.errors) / float64(m.count)
+		s.AvgMs = durationMs(m.total / time.Duration(m.count))
+	}
+	window := append(durations(nil), m.window...)
+	m.lock.Unlock()
+	if n := len(window); n > 0 {
+		sort.Sort(window)
+		s.P50Ms = durationMs(window[n*50/100])
+		s.P90Ms = durationMs(window[n*90/100])
+		s.P99Ms = durationMs(window[n*99/100])
+	}
+	return s
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+type routeStatsSlice []RouteStats
+
+func (l routeStatsSlice) Len() int { return len(l) }
+func (l routeStatsSlice) Less(i, j int) bool {
+	if l[i].Path != l[j].Path {
+		return l[i].Path < l[j].Path
+	}
+	return l[i].Method < l[j].Method
+}
+func (l routeStatsSlice) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+// 查询各路由耗时与错误统计的操作，供后台管理路由使用
+var RouteStatsHandler = ApiHandler{
+	Desc:   "查询各路由的耗时与错误统计",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, RouteStatsAll())
+	},
+}.Reg()
@@ -0,0 +1,40 @@
+package lessgo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRouteStats(t *testing.T) {
+	h := routePathHandler(POST, "/route/stats", nil, func(c *Context) error {
+		if c.QueryParam("fail") != "" {
+			return errors.New("fail")
+		}
+		if c.QueryParam("panic") != "" {
+			panic("boom")
+		}
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+	for _, url := range []string{"/route/stats", "/route/stats", "/route/stats?fail=1", "/route/stats?panic=1"} {
+		req, _ := http.NewRequest(POST, url, nil)
+		c, _ := testContext(req)
+		func() {
+			defer func() { recover() }()
+			h(c)
+		}()
+	}
+	s, ok := readRouteStats(POST, "/route/stats")
+	if !ok || s.Count != 4 || s.Errors != 2 || s.ErrorRate != 0.5 || s.InFlight != 0 || s.MaxMs < 2 || s.P90Ms < 2 {
+		t.Errorf("readRouteStats() = %+v, %v", s, ok)
+	}
+	found := false
+	for _, rs := range RouteStatsAll() {
+		found = found || rs.Method == POST && rs.Path == "/route/stats" && rs.Count == 4
+	}
+	if !found {
+		t.Error("RouteStatsAll() misses the route")
+	}
+}
@@ -0,0 +1,144 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 由lessgo routes设置的环境变量：非空时Run()构建路由后将路由表以JSON格式写入该文件并退出，不监听端口、不开启后台任务
+const ROUTES_ENV = "LESSGO_ROUTES"
+
+type (
+	// 路由表，Before与After为全部路由共用的系统中间件子链
+	RouteTable struct {
+		Before []string     `json:"before"`
+		After  []string     `json:"after"`
+		Routes []RouteEntry `json:"routes"`
+	}
+	// 路由表中的一条真实路由，Middlewares为从根节点起的虚拟路由中间件链
+	RouteEntry struct {
+		Method      string   `json:"method"`
+		Path        string   `json:"path"`
+		Handler     string   `json:"handler"`
+		Desc        string   `json:"desc,omitempty"`
+		Middlewares []string `json:"middlewares"`
+	}
+)
+
+// 返回当前虚拟路由对应的路由表，含单独注册的静态文件与静态目录路由，按路径与方法排序；
+// 未启用的节点及其子孙节点不在其中
+func GetRouteTable() *RouteTable {
+	t := &RouteTable{
+		Before: middlewareNames(lessgo.virtBefore),
+		After:  middlewareNames(lessgo.virtAfter),
+		Routes: routeEntries(lessgo.virtRouter),
+	}
+	for _, v := range lessgo.virtFiles {
+		t.Routes = append(t.Routes, RouteEntry{
+			Method:      GET,
+			Path:        v.Path,
+			Handler:     "file " + v.File,
+			Middlewares: middlewareNames(v.Middlewares),
+		})
+	}
+	for _, v := range lessgo.virtStatics {
+		root := v.Root
+		if v.FS != nil {
+			root = "fs"
+		}
+		t.Routes = append(t.Routes, RouteEntry{
+			Method:      GET,
+			Path:        v.Prefix + "/*filepath",
+			Handler:     "static " + root,
+			Middlewares: middlewareNames(v.Middlewares),
+		})
+	}
+	sort.SliceStable(t.Routes, func(i, j int) bool {
+		a, b := t.Routes[i], t.Routes[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	return t
+}
+
+// 返回虚拟路由子树中已启用操作的路由
+func routeEntries(vr *VirtRouter) []RouteEntry {
+	if vr == nil || !vr.Enable {
+		return nil
+	}
+	var routes []RouteEntry
+	if vr.Type == HANDLER && vr.apiHandler != nil {
+		paths := []string{vr.Path()}
+		// 与route()一致，"/index"操作同时注册在上级路径
+		if vr.suffix == "" && strings.HasSuffix(vr.Prefix, "/index") {
+			paths = append(paths, pathpkg.Join("/", strings.TrimSuffix(vr.Path(), "/index")))
+		}
+		mws := virtRouterMiddlewares(vr)
+		for _, p := range paths {
+			for _, m := range vr.Methods() {
+				routes = append(routes, RouteEntry{
+					Method:      m,
+					Path:        p,
+					Handler:     handlerName(vr.apiHandler.Handler),
+					Desc:        vr.apiHandler.Desc,
+					Middlewares: mws,
+				})
+			}
+		}
+	}
+	for _, child := range vr.Children {
+		routes = append(routes, routeEntries(child)...)
+	}
+	return routes
+}
+
+// 返回从根节点起到vr的完整中间件链的名称
+func virtRouterMiddlewares(vr *VirtRouter) []string {
+	var chain []*VirtRouter
+	for p := vr; p != nil; p = p.Parent {
+		chain = append(chain, p)
+	}
+	names := []string{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		names = append(names, middlewareNames(chain[i].Middlewares)...)
+	}
+	return names
+}
+
+var buildRouterOnce sync.Once
+
+// 注册系统预设的中间件与路由、读取虚拟路由配置并构建路由，仅执行一次；
+// 不开启配置热加载、模板监视等后台任务，也不连接数据库
+func buildRouter() {
+	buildRouterOnce.Do(func() {
+		registerDefaults()
+
+		// 从数据库初始化虚拟路由
+		initVirtRouterConfig()
+
+		// 重建路由
+		ReregisterRouter()
+	})
+}
+
+// 由lessgo routes启动时，构建路由后写出路由表并退出
+func dumpRoutesAndExit(file string) {
+	buildRouter()
+	b, err := json.MarshalIndent(GetRouteTable(), "", "  ")
+	if err == nil {
+		err = os.WriteFile(file, append(b, '\n'), 0644)
+	}
+	if err != nil {
+		Log.Error("Write the route table failed: %v", err)
+		Log.Flush()
+		os.Exit(1)
+	}
+	Log.Flush()
+	os.Exit(0)
+}
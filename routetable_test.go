@@ -0,0 +1,43 @@
+package lessgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRouteEntries(t *testing.T) {
+	index := ApiHandler{Desc: "route table index", Method: "GET", Handler: func(c *Context) error { return nil }}.Reg()
+	user := ApiHandler{
+		Desc:    "route table user",
+		Method:  "GET|POST",
+		Params:  []Param{{Name: "id", In: "path", Required: true, Model: ""}},
+		Handler: func(c *Context) error { return nil },
+	}.Reg()
+	off := Leaf("/off", index)
+	off.Enable = false
+	vr := Branch("/rt", "route table",
+		Leaf("/index", index),
+		Leaf("/users", user, SecureHeaders),
+		off,
+	).Use(CheckHome)
+	vr.reset() // 未挂载到根节点，自行计算路径
+
+	var got []string
+	for _, r := range routeEntries(vr) {
+		got = append(got, r.Method+" "+r.Path)
+		if r.Handler != handlerName(index.Handler) && r.Handler != handlerName(user.Handler) {
+			t.Errorf("%s %s: handler %q", r.Method, r.Path, r.Handler)
+		}
+		want := []string{CheckHome.Name}
+		if r.Path == "/rt/users/:id" {
+			want = append(want, SecureHeaders.Name)
+		}
+		if !reflect.DeepEqual(r.Middlewares, want) {
+			t.Errorf("%s %s: middlewares %q, want %q", r.Method, r.Path, r.Middlewares, want)
+		}
+	}
+	want := []string{"GET /rt/index", "GET /rt", "GET /rt/users/:id", "POST /rt/users/:id"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %q, want %q", got, want)
+	}
+}
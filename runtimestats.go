@@ -0,0 +1,109 @@
+package lessgo
+
+import (
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 运行时统计
+type RuntimeStats struct {
+	Time         time.Time                  `json:"time"`
+	UptimeSec    int64                      `json:"uptime_sec"`
+	Goroutines   int                        `json:"goroutines"`
+	CPUs         int                        `json:"cpus"`
+	Sys          uint64                     `json:"sys"` // 向系统申请的内存字节数
+	HeapAlloc    uint64                     `json:"heap_alloc"`
+	HeapInuse    uint64                     `json:"heap_inuse"`
+	HeapObjects  uint64                     `json:"heap_objects"`
+	StackInuse   uint64                     `json:"stack_inuse"`
+	NumGC        uint32                     `json:"num_gc"`
+	PauseTotalMs float64                    `json:"pause_total_ms"`
+	RecentPauses []float64                  `json:"recent_pauses_ms"` // 最近的GC暂停时长，新的在前，最多10个
+	LastGC       time.Time                  `json:"last_gc,omitempty"`
+	Connections  map[string]int64           `json:"connections"`  // 各监听地址打开的连接数
+	Listeners    map[string]ListenerStats   `json:"listeners"`    // 各监听地址的连接与请求数
+	InFlight     map[string]int64           `json:"in_flight"`    // 各路由("GET /path")进行中的请求数，不含为0的
+	BufferPool   BufferPoolStats            `json:"buffer_pool"`  // 响应缓冲池统计
+	WorkerPools  map[string]WorkerPoolStats `json:"worker_pools"` // 各工作池统计
+}
+
+var (
+	startTime = time.Now()
+
+	listenerCounters     = map[string]*listenerCounter{}
+	listenerCountersLock sync.Mutex
+
+	// 读取内存统计需暂停全部协程，结果缓存1秒
+	memStatsCache struct {
+		at    time.Time
+		stats runtime.MemStats
+		sync.Mutex
+	}
+)
+
+// 返回运行时统计
+func ReadRuntimeStats() RuntimeStats {
+	now := time.Now()
+	memStatsCache.Lock()
+	if now.Sub(memStatsCache.at) >= time.Second {
+		runtime.ReadMemStats(&memStatsCache.stats)
+		memStatsCache.at = now
+	}
+	m := &memStatsCache.stats
+	s := RuntimeStats{
+		Time:         now,
+		UptimeSec:    int64(now.Sub(startTime) / time.Second),
+		Goroutines:   runtime.NumGoroutine(),
+		CPUs:         runtime.NumCPU(),
+		Sys:          m.Sys,
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapObjects:  m.HeapObjects,
+		StackInuse:   m.StackInuse,
+		NumGC:        m.NumGC,
+		PauseTotalMs: float64(m.PauseTotalNs) / 1e6,
+	}
+	if m.LastGC > 0 {
+		s.LastGC = time.Unix(0, int64(m.LastGC))
+	}
+	for i := uint32(0); i < 10 && i < m.NumGC; i++ {
+		s.RecentPauses = append(s.RecentPauses, float64(m.PauseNs[(m.NumGC-i+255)%256])/1e6)
+	}
+	memStatsCache.Unlock()
+
+	s.Listeners = ReadListenerStats()
+	s.Connections = make(map[string]int64, len(s.Listeners))
+	for addr, l := range s.Listeners {
+		s.Connections[addr] = l.Connections
+	}
+
+	s.InFlight = map[string]int64{}
+	routeMetricsLock.RLock()
+	for key, m := range routeMetricsMap {
+		if v := atomic.LoadInt64(&m.inFlight); v != 0 {
+			s.InFlight[key] = v
+		}
+	}
+	routeMetricsLock.RUnlock()
+	s.BufferPool = ReadBufferPoolStats()
+	s.WorkerPools = ReadWorkerPoolStats()
+	return s
+}
+
+// 返回统计监听地址addr连接数的http.Server.ConnState回调
+func connStateCounter(addr string) func(net.Conn, http.ConnState) {
+	return getListenerCounter(addr).connState
+}
+
+// 查询运行时统计的操作，供后台管理路由使用
+var RuntimeStatsHandler = ApiHandler{
+	Desc:   "查询运行时统计",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, ReadRuntimeStats())
+	},
+}.Reg()
@@ -0,0 +1,32 @@
+package lessgo
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	var during RuntimeStats
+	h := routePathHandler(GET, "/stats/test", nil, func(c *Context) error {
+		during = ReadRuntimeStats()
+		return nil
+	})
+	req, _ := http.NewRequest(GET, "/stats/test", nil)
+	c, _ := testContext(req)
+	h(c)
+	if during.InFlight["GET /stats/test"] != 1 || during.Goroutines == 0 || during.HeapAlloc == 0 {
+		t.Errorf("stats during request = %+v", during)
+	}
+	if n, ok := ReadRuntimeStats().InFlight["GET /stats/test"]; ok {
+		t.Errorf("in flight after request = %d", n)
+	}
+
+	cs := connStateCounter("test:80")
+	cs(nil, http.StateNew)
+	cs(nil, http.StateNew)
+	cs(nil, http.StateActive)
+	cs(nil, http.StateClosed)
+	if n := ReadRuntimeStats().Connections["test:80"]; n != 1 {
+		t.Errorf("connections = %d, want 1", n)
+	}
+}
@@ -0,0 +1,96 @@
+package lessgo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// 按[runtime]配置设置GOMAXPROCS、GOGC与GC压舱物，并打印生效的运行时设置
+func applyRuntimeConfig(conf RuntimeConfig) {
+	procs, source := conf.MaxProcs, "config"
+	if procs <= 0 {
+		procs, source = runtime.NumCPU(), "CPUs"
+		if quota, ok := cgroupCPUQuota(cgroupRoot); ok {
+			procs, source = quotaProcs(quota), fmt.Sprintf("cgroup CPU quota %.2f", quota)
+		}
+	}
+	runtime.GOMAXPROCS(procs)
+
+	gc := "default"
+	if conf.GCPercent != 0 {
+		percent := conf.GCPercent
+		if percent < 0 {
+			percent = -1
+		}
+		debug.SetGCPercent(percent)
+		gc = strconv.Itoa(percent)
+	}
+
+	if conf.BallastMB > 0 {
+		gcBallast = make([]byte, conf.BallastMB*MB)
+	} else {
+		gcBallast = nil
+	}
+
+	Log.Sys("Runtime: GOMAXPROCS=%d (%s), GOGC=%s, GC ballast=%dMB", runtime.GOMAXPROCS(0), source, gc, conf.BallastMB)
+}
+
+// GC压舱物：一块从不读写的大内存，抬高触发GC的堆大小，以减少小堆服务的GC次数；
+// 未被访问的页不占用实际物理内存
+var gcBallast []byte
+
+// cgroup文件系统的挂载点
+var cgroupRoot = "/sys/fs/cgroup"
+
+// 读取cgroup的CPU配额(可用的CPU核数)，依次尝试cgroup v2的cpu.max与v1的cpu.cfs_quota_us，
+// 未限制配额或无法读取时返回false
+func cgroupCPUQuota(root string) (float64, bool) {
+	if b, err := ioutil.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) == 2 && fields[0] != "max" {
+			return parseCPUQuota(fields[0], fields[1])
+		}
+		return 0, false
+	}
+	for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+		quota, err := ioutil.ReadFile(filepath.Join(root, dir, "cpu.cfs_quota_us"))
+		if err != nil {
+			continue
+		}
+		period, err := ioutil.ReadFile(filepath.Join(root, dir, "cpu.cfs_period_us"))
+		if err != nil {
+			continue
+		}
+		return parseCPUQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+	return 0, false
+}
+
+func parseCPUQuota(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
+
+// 按配额取整得到GOMAXPROCS，至少为1，且不超过CPU核数
+func quotaProcs(quota float64) int {
+	procs := int(quota)
+	if procs < 1 {
+		procs = 1
+	}
+	if n := runtime.NumCPU(); procs > n {
+		procs = n
+	}
+	return procs
+}
@@ -0,0 +1,63 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestCgroupCPUQuota(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	if _, ok := cgroupCPUQuota(dir); ok {
+		t.Error("quota found without cgroup files")
+	}
+	write("cpu,cpuacct/cpu.cfs_quota_us", "-1\n")
+	write("cpu,cpuacct/cpu.cfs_period_us", "100000\n")
+	if _, ok := cgroupCPUQuota(dir); ok {
+		t.Error("v1 quota -1 means no limit")
+	}
+	write("cpu,cpuacct/cpu.cfs_quota_us", "150000\n")
+	if q, ok := cgroupCPUQuota(dir); !ok || q != 1.5 {
+		t.Errorf("v1 quota = %v, %v", q, ok)
+	}
+	write("cpu.max", "max 100000\n")
+	if _, ok := cgroupCPUQuota(dir); ok {
+		t.Error("v2 max means no limit")
+	}
+	write("cpu.max", "250000 100000\n")
+	if q, ok := cgroupCPUQuota(dir); !ok || q != 2.5 {
+		t.Errorf("v2 quota = %v, %v", q, ok)
+	}
+
+	if quotaProcs(0.5) != 1 || quotaProcs(1e6) != runtime.NumCPU() {
+		t.Errorf("quotaProcs = %d, %d", quotaProcs(0.5), quotaProcs(1e6))
+	}
+}
+
+func TestApplyRuntimeConfig(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	defer debug.SetGCPercent(debug.SetGCPercent(100))
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = os.TempDir() // 无cgroup文件
+
+	applyRuntimeConfig(RuntimeConfig{MaxProcs: 1, GCPercent: 300, BallastMB: 1})
+	if runtime.GOMAXPROCS(0) != 1 || debug.SetGCPercent(300) != 300 || len(gcBallast) != MB {
+		t.Errorf("GOMAXPROCS = %d, ballast = %d", runtime.GOMAXPROCS(0), len(gcBallast))
+	}
+	applyRuntimeConfig(RuntimeConfig{})
+	if runtime.GOMAXPROCS(0) != runtime.NumCPU() || gcBallast != nil {
+		t.Errorf("GOMAXPROCS = %d, ballast = %d", runtime.GOMAXPROCS(0), len(gcBallast))
+	}
+}
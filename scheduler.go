@@ -0,0 +1,326 @@
+package lessgo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/cron"
+)
+
+type (
+	// 后台任务的执行函数，cancel在超时或服务退出的等待期满时关闭，任务应尽快返回
+	JobFunc func(cancel <-chan struct{}) error
+
+	// 上次执行尚未完成时再次触发的处理方式
+	OverlapPolicy int
+
+	// 后台任务选项
+	JobOptions struct {
+		Timeout time.Duration // 单次执行超时，超时后关闭cancel并记为失败，0为不限
+		Overlap OverlapPolicy
+	}
+
+	// 后台任务的执行统计
+	JobStats struct {
+		Name      string    `json:"name"`
+		Spec      string    `json:"spec,omitempty"` // cron表达式，一次性任务为空
+		Running   int       `json:"running"`
+		Runs      uint64    `json:"runs"`
+		Failures  uint64    `json:"failures"` // 返回错误、超时或恐慌的次数
+		Timeouts  uint64    `json:"timeouts"`
+		Panics    uint64    `json:"panics"`
+		Skipped   uint64    `json:"skipped"` // 因重叠被跳过的次数
+		LastRun   time.Time `json:"last_run,omitempty"`
+		LastMs    int64     `json:"last_ms"` // 上次执行耗时，单位毫秒
+		LastError string    `json:"last_error,omitempty"`
+		NextRun   time.Time `json:"next_run,omitempty"`
+	}
+
+	// 后台任务调度器，任务在服务退出时停止触发，进行中的执行可在等待期内完成
+	JobScheduler struct {
+		jobs    map[string]*job
+		runs    map[*jobRun]struct{}
+		wg      sync.WaitGroup
+		started bool
+		stopped bool
+		lock    sync.Mutex
+	}
+
+	job struct {
+		name     string
+		spec     string
+		schedule cron.Schedule // 一次性任务为nil
+		fn       JobFunc
+		opts     JobOptions
+		timer    *time.Timer
+		queued   bool
+		removed  bool
+		stats    JobStats
+	}
+
+	// 一次执行
+	jobRun struct {
+		cancel chan struct{}
+		once   sync.Once
+	}
+)
+
+const (
+	OverlapSkip  OverlapPolicy = iota // 跳过本次触发(默认)
+	OverlapAllow                      // 并发执行
+	OverlapQueue                      // 上次完成后立即补执行一次，期间的多次触发合并
+)
+
+var ErrJobTimeout = errors.New("job timed out")
+
+// 全局后台任务调度器
+var Scheduler = NewJobScheduler()
+
+// 创建后台任务调度器
+func NewJobScheduler() *JobScheduler {
+	return &JobScheduler{
+		jobs: map[string]*job{},
+		runs: map[*jobRun]struct{}{},
+	}
+}
+
+// 按cron表达式(见cron子包，如"0 3 * * *"、"@every 5m")定时执行任务，同名任务将被替换
+func (s *JobScheduler) Cron(name, spec string, fn JobFunc, opts JobOptions) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return fmt.Errorf("cron %q never runs", spec)
+	}
+	return s.add(&job{name: name, spec: spec, schedule: schedule, fn: fn, opts: opts}, next.Sub(now))
+}
+
+// 延迟delay后执行一次任务，同名的未执行任务将被替换
+func (s *JobScheduler) After(name string, delay time.Duration, fn JobFunc, opts JobOptions) error {
+	return s.add(&job{name: name, fn: fn, opts: opts}, delay)
+}
+
+// 移除任务，不影响进行中的执行
+func (s *JobScheduler) Remove(name string) {
+	s.lock.Lock()
+	if j := s.jobs[name]; j != nil {
+		s.remove(j)
+	}
+	s.lock.Unlock()
+}
+
+// 返回所有任务的执行统计，按名称排序
+func (s *JobScheduler) Stats() []JobStats {
+	s.lock.Lock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]JobStats, len(names))
+	for i, name := range names {
+		stats[i] = s.jobs[name].stats
+	}
+	s.lock.Unlock()
+	return stats
+}
+
+// 停止触发任务并在timeout内等待进行中的执行完成，期满后关闭其cancel，
+// 返回仍未完成的执行数
+func (s *JobScheduler) Stop(timeout time.Duration) int {
+	s.lock.Lock()
+	if s.stopped {
+		s.lock.Unlock()
+		return 0
+	}
+	s.stopped = true
+	for _, j := range s.jobs {
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+		j.queued = false
+	}
+	s.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return 0
+	case <-time.After(timeout):
+	}
+	s.lock.Lock()
+	n := len(s.runs)
+	for r := range s.runs {
+		r.stop()
+	}
+	s.lock.Unlock()
+	return n
+}
+
+func (s *JobScheduler) add(j *job, delay time.Duration) error {
+	if j.name == "" || j.fn == nil {
+		return errors.New("job requires a name and a func")
+	}
+	j.stats.Name = j.name
+	j.stats.Spec = j.spec
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.stopped {
+		return errors.New("job scheduler is stopped")
+	}
+	if !s.started {
+		s.started = true
+		app.onShutdown(func() {
+			if n := s.Stop(30 * time.Second); n > 0 {
+				Log.Warn("Scheduler: %d jobs were not completed before shutdown", n)
+			}
+		})
+	}
+	if old := s.jobs[j.name]; old != nil {
+		s.remove(old)
+	}
+	s.jobs[j.name] = j
+	s.schedule(j, delay)
+	return nil
+}
+
+// 调用者需持有锁
+func (s *JobScheduler) remove(j *job) {
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	j.removed = true
+	j.queued = false
+	delete(s.jobs, j.name)
+}
+
+// 设置下次触发的定时器，调用者需持有锁
+func (s *JobScheduler) schedule(j *job, delay time.Duration) {
+	j.stats.NextRun = time.Now().Add(delay)
+	j.timer = time.AfterFunc(delay, func() {
+		s.trigger(j)
+	})
+}
+
+func (s *JobScheduler) trigger(j *job) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.stopped || j.removed {
+		return
+	}
+	j.stats.NextRun = time.Time{}
+	if j.schedule != nil {
+		now := time.Now()
+		if next := j.schedule.Next(now); !next.IsZero() {
+			s.schedule(j, next.Sub(now))
+		}
+	}
+	if j.stats.Running > 0 {
+		switch j.opts.Overlap {
+		case OverlapSkip:
+			j.stats.Skipped++
+			return
+		case OverlapQueue:
+			j.queued = true
+			return
+		}
+	}
+	s.run(j)
+}
+
+// 启动一次执行，调用者需持有锁
+func (s *JobScheduler) run(j *job) {
+	r := &jobRun{cancel: make(chan struct{})}
+	s.runs[r] = struct{}{}
+	j.stats.Running++
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		start := time.Now()
+		var timer *time.Timer
+		timedOut := make(chan struct{})
+		if j.opts.Timeout > 0 {
+			timer = time.AfterFunc(j.opts.Timeout, func() {
+				close(timedOut)
+				r.stop()
+			})
+		}
+		panicked, err := j.call(r.cancel)
+		timeout := timer != nil && !timer.Stop()
+		if timeout {
+			<-timedOut
+			if err == nil {
+				err = ErrJobTimeout
+			}
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.runs, r)
+		st := &j.stats
+		st.Running--
+		st.Runs++
+		st.LastRun = start
+		st.LastMs = int64(time.Since(start) / time.Millisecond)
+		st.LastError = ""
+		if panicked {
+			st.Panics++
+		}
+		if timeout {
+			st.Timeouts++
+		}
+		if err != nil {
+			st.Failures++
+			st.LastError = err.Error()
+			Log.Error("Scheduler: job %s failed: %v", j.name, err)
+		}
+		if j.schedule == nil && st.Running == 0 && s.jobs[j.name] == j {
+			// 一次性任务执行完毕
+			delete(s.jobs, j.name)
+		}
+		if j.queued && !s.stopped && !j.removed {
+			j.queued = false
+			s.run(j)
+		}
+	}()
+}
+
+// 执行任务并恢复恐慌
+func (j *job) call(cancel <-chan struct{}) (panicked bool, err error) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			buf := make([]byte, 4096)
+			buf = buf[:runtime.Stack(buf, false)]
+			Log.Error("Scheduler: job %s panic: %v\n%s", j.name, rcv, buf)
+			panicked, err = true, fmt.Errorf("panic: %v", rcv)
+		}
+	}()
+	return false, j.fn(cancel)
+}
+
+func (r *jobRun) stop() {
+	r.once.Do(func() {
+		close(r.cancel)
+	})
+}
+
+// 查询后台任务执行统计的操作，供后台管理路由使用
+var SchedulerStatsHandler = ApiHandler{
+	Desc:   "查询后台任务执行统计",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, Scheduler.Stats())
+	},
+}.Reg()
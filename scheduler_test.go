@@ -0,0 +1,90 @@
+package lessgo
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobScheduler(t *testing.T) {
+	s := NewJobScheduler()
+	var slow int32
+	release := make(chan struct{})
+	s.Cron("every", "@every 1h", func(cancel <-chan struct{}) error {
+		return nil
+	}, JobOptions{})
+	s.After("once", 10*time.Millisecond, func(cancel <-chan struct{}) error {
+		return errors.New("fail")
+	}, JobOptions{})
+	var guarded int32
+	s.Cron("guarded", "@every 1h", func(cancel <-chan struct{}) error {
+		if atomic.AddInt32(&guarded, 1) == 1 {
+			panic("boom")
+		}
+		<-cancel
+		return nil
+	}, JobOptions{Timeout: 20 * time.Millisecond})
+	s.After("slow", 0, func(cancel <-chan struct{}) error {
+		atomic.AddInt32(&slow, 1)
+		<-release
+		return nil
+	}, JobOptions{})
+	if err := s.Cron("bad", "* * *", nil, JobOptions{}); err == nil {
+		t.Error("Cron() with invalid spec should fail")
+	}
+	s.lock.Lock()
+	g := s.jobs["guarded"]
+	s.lock.Unlock()
+	s.trigger(g)
+	time.Sleep(10 * time.Millisecond)
+	s.trigger(g)
+	time.Sleep(100 * time.Millisecond)
+
+	stats := map[string]JobStats{}
+	for _, st := range s.Stats() {
+		stats[st.Name] = st
+	}
+	// 执行完的一次性任务被移除
+	if len(stats) != 3 || stats["every"].NextRun.IsZero() || stats["slow"].Running != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if st := stats["guarded"]; st.Runs != 2 || st.Failures != 2 || st.Panics != 1 || st.Timeouts != 1 || st.LastError != ErrJobTimeout.Error() {
+		t.Errorf("guarded stats = %+v", st)
+	}
+
+	// 重叠的触发按策略处理
+	s.lock.Lock()
+	j := s.jobs["slow"]
+	s.lock.Unlock()
+	s.trigger(j)
+	j.opts.Overlap = OverlapQueue
+	s.trigger(j)
+	s.trigger(j)
+	s.lock.Lock()
+	if j.stats.Skipped != 1 || !j.queued {
+		t.Errorf("skipped = %d, queued = %v", j.stats.Skipped, j.queued)
+	}
+	s.lock.Unlock()
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&slow); n != 2 {
+		t.Errorf("slow runs = %d, want 2", n)
+	}
+
+	// 退出时等待进行中的执行，期满后关闭cancel
+	stopped := make(chan struct{})
+	s.After("stuck", 0, func(cancel <-chan struct{}) error {
+		<-cancel
+		close(stopped)
+		return nil
+	}, JobOptions{})
+	time.Sleep(10 * time.Millisecond)
+	if n := s.Stop(20 * time.Millisecond); n != 1 {
+		t.Errorf("Stop() = %d, want 1", n)
+	}
+	<-stopped
+	if err := s.After("late", 0, func(cancel <-chan struct{}) error { return nil }, JobOptions{}); err == nil {
+		t.Error("After() should fail once stopped")
+	}
+}
@@ -0,0 +1,210 @@
+package lessgo
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	confpkg "github.com/lessgo/lessgo/config"
+)
+
+// 配置值中的密钥引用，如${secret:db_password}
+var secretRefRegexp = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+type secretEntry struct {
+	value   string
+	fetched time.Time
+}
+
+var (
+	secretLock     sync.Mutex
+	secretProvider confpkg.SecretProvider
+	secretTTL      time.Duration
+	secretCache    = map[string]*secretEntry{}
+	secretHooks    = map[string][]func(value string){}
+	secretStop     chan struct{}
+
+	// 系统配置中引用了密钥的配置项及其原始值，写入app.config时使用原始值
+	configSecretRefs = map[string]string{}
+	configSecretErr  error
+)
+
+// 设置密钥源(如Vault、AWS Secrets Manager或本地文件)，配置值中的${secret:name}将由其解析；
+// 密钥缓存ttl(<=0时为5分钟)后重新读取，值变化时调用OnSecretRotate注册的回调并热加载配置；
+// 设置后立即热加载配置以解析系统配置中的密钥引用，p为nil时取消
+func SetSecretProvider(p confpkg.SecretProvider, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	secretLock.Lock()
+	if secretStop != nil {
+		close(secretStop)
+		secretStop = nil
+	}
+	secretProvider = p
+	secretTTL = ttl
+	secretCache = map[string]*secretEntry{}
+	if p != nil {
+		secretStop = make(chan struct{})
+		go rotateSecrets(p, ttl, secretStop)
+	}
+	secretLock.Unlock()
+	return ReloadConfig()
+}
+
+// 读取密钥，优先使用缓存
+func Secret(name string) (string, error) {
+	secretLock.Lock()
+	p := secretProvider
+	e, ok := secretCache[name]
+	ttl := secretTTL
+	secretLock.Unlock()
+	if p == nil {
+		return "", confpkg.ErrNoSecretProvider
+	}
+	if ok && time.Since(e.fetched) < ttl {
+		return e.value, nil
+	}
+	value, err := p.GetSecret(name)
+	if err != nil {
+		return "", err
+	}
+	secretLock.Lock()
+	if secretProvider == p {
+		secretCache[name] = &secretEntry{value: value, fetched: time.Now()}
+	}
+	secretLock.Unlock()
+	return value, nil
+}
+
+// 注册密钥轮换的回调，缓存刷新时发现密钥值变化后调用
+func OnSecretRotate(name string, fn func(value string)) {
+	secretLock.Lock()
+	secretHooks[name] = append(secretHooks[name], fn)
+	secretLock.Unlock()
+}
+
+// 定期刷新已缓存的密钥
+func rotateSecrets(p confpkg.SecretProvider, ttl time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		secretLock.Lock()
+		names := make([]string, 0, len(secretCache))
+		for name := range secretCache {
+			names = append(names, name)
+		}
+		secretLock.Unlock()
+
+		rotated := false
+		for _, name := range names {
+			value, err := p.GetSecret(name)
+			if err != nil {
+				Log.Error("Refresh secret %s failed: %v", name, err)
+				continue
+			}
+			secretLock.Lock()
+			if secretProvider != p {
+				secretLock.Unlock()
+				return
+			}
+			e := secretCache[name]
+			changed := e == nil || e.value != value
+			secretCache[name] = &secretEntry{value: value, fetched: time.Now()}
+			hooks := secretHooks[name]
+			secretLock.Unlock()
+			if changed {
+				rotated = true
+				Log.Sys("Secret %s rotated.", name)
+				for _, fn := range hooks {
+					fn(value)
+				}
+			}
+		}
+		if rotated {
+			if err := ReloadConfig(); err != nil {
+				Log.Error("Reload config failed: %v", err)
+			}
+		}
+	}
+}
+
+// 判断字符串是否含有密钥引用
+func hasSecretRef(s string) bool {
+	return secretRefRegexp.MatchString(s)
+}
+
+// 将字符串中的密钥引用替换为密钥值
+func resolveSecrets(s string) (string, error) {
+	if !hasSecretRef(s) {
+		return s, nil
+	}
+	var err error
+	s = secretRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		var v string
+		v, err = Secret(secretRefRegexp.FindStringSubmatch(ref)[1])
+		return v
+	})
+	return s, err
+}
+
+// 开始读取系统配置前清空密钥引用的记录，返回原记录
+func resetConfigSecrets() map[string]string {
+	secretLock.Lock()
+	old := configSecretRefs
+	configSecretRefs = map[string]string{}
+	configSecretErr = nil
+	secretLock.Unlock()
+	return old
+}
+
+// 读取系统配置项时解析其中的密钥引用，并记录原始值；
+// 未设置密钥源时保留原始值，待SetSecretProvider后热加载时解析
+func readConfigSecret(fullname, str string) string {
+	secretLock.Lock()
+	delete(configSecretRefs, fullname)
+	if !hasSecretRef(str) {
+		secretLock.Unlock()
+		return str
+	}
+	configSecretRefs[fullname] = str
+	p := secretProvider
+	secretLock.Unlock()
+	if p == nil {
+		return str
+	}
+	v, err := resolveSecrets(str)
+	if err != nil {
+		secretLock.Lock()
+		if configSecretErr == nil {
+			configSecretErr = fmt.Errorf("Resolve secret of config %s failed: %v.", fullname, err)
+		}
+		secretLock.Unlock()
+		return str
+	}
+	return v
+}
+
+// 返回系统配置项的密钥引用
+func configSecretRef(fullname string) (string, bool) {
+	secretLock.Lock()
+	ref, ok := configSecretRefs[fullname]
+	secretLock.Unlock()
+	return ref, ok
+}
+
+// 返回读取系统配置时首个密钥解析错误
+func configSecretError() error {
+	secretLock.Lock()
+	defer secretLock.Unlock()
+	return configSecretErr
+}
@@ -0,0 +1,126 @@
+package lessgo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeSecretProvider struct {
+	lock    sync.Mutex
+	secrets map[string]string
+}
+
+func (p *fakeSecretProvider) GetSecret(name string) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	v, ok := p.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	return v, nil
+}
+
+func (p *fakeSecretProvider) set(name, value string) {
+	p.lock.Lock()
+	p.secrets[name] = value
+	p.lock.Unlock()
+}
+
+func TestSecretConfig(t *testing.T) {
+	os.Setenv("LESSGO_SESSION_SESSIONPROVIDERCONFIG", "${secret:redis}")
+	os.Setenv("LESSGO_DEMO_PASSWORD", "${secret:db}")
+	os.Setenv("LESSGO_DEMO_TOKEN", "${secret:missing}")
+	atomic.StoreInt32(&serving, 0)
+	defer func() {
+		os.Unsetenv("LESSGO_SESSION_SESSIONPROVIDERCONFIG")
+		os.Unsetenv("LESSGO_DEMO_PASSWORD")
+		os.Unsetenv("LESSGO_DEMO_TOKEN")
+		SetSecretProvider(nil, 0)
+		Config.Session.SessionProviderConfig = ""
+	}()
+
+	changed := make(chan []ConfigChange, 1)
+	OnConfigChange(func(changes []ConfigChange) {
+		select {
+		case changed <- changes:
+		default:
+		}
+	})
+	p := &fakeSecretProvider{secrets: map[string]string{"redis": "pw1", "db": "dbpw"}}
+	if err := SetSecretProvider(p, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if Config.Session.SessionProviderConfig != "pw1" {
+		t.Fatalf("SessionProviderConfig = %q", Config.Session.SessionProviderConfig)
+	}
+	for _, ch := range <-changed {
+		if strings.Contains(ch.Old+ch.New, "pw1") {
+			t.Errorf("secret leaked in change %+v", ch)
+		}
+	}
+
+	// 写入app.config时保留引用
+	dir, err := ioutil.TempDir("", "lessgo-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "app.config")
+	if err := Config.writeMainConfig(fname); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadFile(fname)
+	if strings.Contains(string(b), "pw1") || !strings.Contains(string(b), "${secret:redis}") {
+		t.Errorf("app.config = %s", b)
+	}
+
+	var conf struct {
+		Demo struct {
+			Password string
+			Token    string
+		}
+	}
+	err = Config.Bind(&conf)
+	if conf.Demo.Password != "dbpw" {
+		t.Errorf("Bind() = %+v", conf)
+	}
+	if err == nil || !strings.Contains(err.Error(), "demo::token: resolve secret failed") {
+		t.Errorf("Bind() error = %v", err)
+	}
+
+	rotated := make(chan string, 1)
+	OnSecretRotate("redis", func(value string) {
+		select {
+		case rotated <- value:
+		default:
+		}
+	})
+	p.set("redis", "pw2")
+	select {
+	case v := <-rotated:
+		if v != "pw2" {
+			t.Errorf("rotated value = %q", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("secret not rotated")
+	}
+	current := func() string {
+		configReloadLock.Lock()
+		defer configReloadLock.Unlock()
+		return Config.Session.SessionProviderConfig
+	}
+	deadline := time.Now().Add(time.Second)
+	for current() != "pw2" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := current(); v != "pw2" {
+		t.Errorf("SessionProviderConfig = %q after rotation", v)
+	}
+}
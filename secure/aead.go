@@ -0,0 +1,72 @@
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"time"
+)
+
+// Encrypt encrypts and authenticates payload with the primary key using
+// AES-256-GCM, in the URL and cookie safe form "keyid.timestamp.ciphertext".
+// The name and the key id are authenticated but not encrypted.
+func (r *KeyRing) Encrypt(name string, payload []byte) (string, error) {
+	k, err := r.primary()
+	if err != nil {
+		return "", err
+	}
+	aead, err := k.aead()
+	if err != nil {
+		return "", err
+	}
+	header := valueHeader(k.ID)
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, payload, additionalData(name, header))
+	return header + "." + encoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt with the same name. Values
+// older than maxAge are rejected with ErrExpired (maxAge <= 0 disables the
+// check).
+func (r *KeyRing) Decrypt(name, value string, maxAge time.Duration) ([]byte, error) {
+	id, header, rest, ts, err := splitValue(value)
+	if err != nil {
+		return nil, err
+	}
+	k, err := r.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := encoding.DecodeString(rest)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalid
+	}
+	n := aead.NonceSize()
+	payload, err := aead.Open(nil, sealed[:n], sealed[n:], additionalData(name, header))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if err = checkAge(ts, maxAge); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (k *ringKey) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.aeadKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(name, header string) []byte {
+	return []byte(name + "\x00" + header)
+}
@@ -0,0 +1,259 @@
+// Package secure signs and encrypts small values such as cookies and tokens
+// with a versioned key ring. New values are always produced with the primary
+// key; values produced with older keys still verify until those keys are
+// retired, so keys can be rotated without logging users out.
+//
+//	keys, err := secure.ParseKeys(os.Getenv("APP_KEYS")) // "2:<new secret>,1:<old secret>"
+//	ring, err := secure.NewKeyRing(keys...)
+//	token, err := ring.Sign("invite", []byte("user:42"))
+//	payload, err := ring.Verify("invite", token, 24*time.Hour)
+//
+// The name passed to every call binds a value to its purpose: a value signed
+// as "invite" does not verify as "csrf". Each key is expanded into separate
+// subkeys for signing and encryption, so one secret serves both.
+package secure
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned when a value cannot be verified or decrypted.
+var (
+	ErrInvalid    = errors.New("secure: invalid value")
+	ErrExpired    = errors.New("secure: expired value")
+	ErrUnknownKey = errors.New("secure: unknown key")
+	ErrNoKey      = errors.New("secure: key ring is empty")
+)
+
+// MinSecretSize is the minimum length of a key secret in bytes.
+const MinSecretSize = 16
+
+type (
+	// Key is a versioned secret. The ID is embedded in every value the key
+	// produces and must not contain '.', ':', ',' or spaces.
+	Key struct {
+		ID     string
+		Secret []byte
+	}
+
+	// KeyRing holds the primary key, used for new values, followed by older
+	// keys that are only used to verify and decrypt. It is safe for
+	// concurrent use.
+	KeyRing struct {
+		keys []*ringKey
+		mu   sync.RWMutex
+	}
+
+	ringKey struct {
+		Key
+		signKey []byte
+		aeadKey []byte
+	}
+)
+
+// NewKeyRing creates a key ring, the first key being the primary one. The
+// zero KeyRing is an empty ring ready to use.
+func NewKeyRing(keys ...Key) (*KeyRing, error) {
+	r := &KeyRing{}
+	if err := r.Reset(keys...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reset replaces all keys at once, the first key being the primary one. On
+// error the ring is left unchanged.
+func (r *KeyRing) Reset(keys ...Key) error {
+	ring := make([]*ringKey, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		rk, err := newRingKey(k)
+		if err != nil {
+			return err
+		}
+		if seen[k.ID] {
+			return fmt.Errorf("secure: duplicate key id %q", k.ID)
+		}
+		seen[k.ID] = true
+		ring = append(ring, rk)
+	}
+	r.mu.Lock()
+	r.keys = ring
+	r.mu.Unlock()
+	return nil
+}
+
+// ParseKeys parses a key list like "2:secret2,1:secret1", the first key
+// being the primary one. Secrets are used as is; surrounding spaces are
+// ignored. The keys are validated by NewKeyRing or Reset.
+func ParseKeys(s string) ([]Key, error) {
+	var keys []Key
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.Index(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("secure: key %q is not in the form id:secret", item)
+		}
+		keys = append(keys, Key{ID: item[:i], Secret: []byte(item[i+1:])})
+	}
+	return keys, nil
+}
+
+// GenerateKey creates a key with a random 32 byte secret. An empty id is
+// replaced by the current time in base 36, which keeps ids unique and
+// ordered across rotations.
+func GenerateKey(id string) (Key, error) {
+	if id == "" {
+		id = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+	return Key{ID: id, Secret: secret}, nil
+}
+
+// Rotate makes k the primary key. The previous keys are kept for
+// verification; a key with the same ID is replaced.
+func (r *KeyRing) Rotate(k Key) error {
+	rk, err := newRingKey(k)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	keys := []*ringKey{rk}
+	for _, old := range r.keys {
+		if old.ID != k.ID {
+			keys = append(keys, old)
+		}
+	}
+	r.keys = keys
+	r.mu.Unlock()
+	return nil
+}
+
+// RotateNew generates a random key, makes it the primary key and keeps at
+// most keep keys in total (keep <= 0 keeps all of them).
+func (r *KeyRing) RotateNew(keep int) (Key, error) {
+	k, err := GenerateKey("")
+	if err != nil {
+		return Key{}, err
+	}
+	if err = r.Rotate(k); err != nil {
+		return Key{}, err
+	}
+	if keep > 0 {
+		r.mu.Lock()
+		if len(r.keys) > keep {
+			r.keys = r.keys[:keep]
+		}
+		r.mu.Unlock()
+	}
+	return k, nil
+}
+
+// Retire removes the key with the given id, after which values produced
+// with it no longer verify. The primary key cannot be retired.
+func (r *KeyRing) Retire(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, k := range r.keys {
+		if k.ID == id && i > 0 {
+			r.keys = append(r.keys[:i:i], r.keys[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// IDs returns the key ids, primary first.
+func (r *KeyRing) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, len(r.keys))
+	for i, k := range r.keys {
+		ids[i] = k.ID
+	}
+	return ids
+}
+
+func (r *KeyRing) primary() (*ringKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.keys) == 0 {
+		return nil, ErrNoKey
+	}
+	return r.keys[0], nil
+}
+
+func (r *KeyRing) lookup(id string) (*ringKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if k.ID == id {
+			return k, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func newRingKey(k Key) (*ringKey, error) {
+	if k.ID == "" || strings.ContainsAny(k.ID, ".:, ") {
+		return nil, fmt.Errorf("secure: invalid key id %q", k.ID)
+	}
+	if len(k.Secret) < MinSecretSize {
+		return nil, fmt.Errorf("secure: secret of key %q is shorter than %d bytes", k.ID, MinSecretSize)
+	}
+	return &ringKey{
+		Key:     Key{ID: k.ID, Secret: append([]byte(nil), k.Secret...)},
+		signKey: deriveKey(k.Secret, "sign"),
+		aeadKey: deriveKey(k.Secret, "aead"),
+	}, nil
+}
+
+func deriveKey(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte("lessgo/secure " + purpose))
+	return h.Sum(nil)
+}
+
+var encoding = base64.RawURLEncoding
+
+// splitValue splits a value into the key id, the header "id.timestamp"
+// covered by its authentication and the remaining part.
+func splitValue(value string) (id, header, rest string, ts int64, err error) {
+	parts := strings.SplitN(value, ".", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return "", "", "", 0, ErrInvalid
+	}
+	ts, err = strconv.ParseInt(parts[1], 36, 64)
+	if err != nil {
+		return "", "", "", 0, ErrInvalid
+	}
+	return parts[0], parts[0] + "." + parts[1], parts[2], ts, nil
+}
+
+// checkAge reports ErrExpired for values older than maxAge (maxAge <= 0
+// disables the check).
+func checkAge(ts int64, maxAge time.Duration) error {
+	if maxAge > 0 && time.Since(time.Unix(ts, 0)) > maxAge {
+		return ErrExpired
+	}
+	return nil
+}
+
+func valueHeader(id string) string {
+	return id + "." + strconv.FormatInt(time.Now().Unix(), 36)
+}
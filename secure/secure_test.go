@@ -0,0 +1,127 @@
+package secure
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	keys, err := ParseKeys(" 1:0123456789abcdef, ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := NewKeyRing(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ring.Sign("invite", []byte("user:42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "1.") {
+		t.Errorf("token = %s", token)
+	}
+	if p, err := ring.Verify("invite", token, time.Hour); err != nil || string(p) != "user:42" {
+		t.Errorf("Verify = %q, %v", p, err)
+	}
+	if _, err := ring.Verify("csrf", token, time.Hour); err != ErrInvalid {
+		t.Errorf("other name: %v", err)
+	}
+	if _, err := ring.Verify("invite", token[:len(token)-2]+"AA", time.Hour); err != ErrInvalid {
+		t.Errorf("tampered mac: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	parts[2] = encoding.EncodeToString([]byte("user:43"))
+	if _, err := ring.Verify("invite", strings.Join(parts, "."), time.Hour); err != ErrInvalid {
+		t.Errorf("tampered payload: %v", err)
+	}
+
+	// expired; the timestamp is covered by the mac
+	old := "1.1." + encoding.EncodeToString([]byte("x"))
+	old += "." + encoding.EncodeToString(ring.keys[0].mac("invite", old))
+	if _, err := ring.Verify("invite", old, time.Hour); err != ErrExpired {
+		t.Errorf("expired: %v", err)
+	}
+	if p, err := ring.Verify("invite", old, 0); err != nil || string(p) != "x" {
+		t.Errorf("no max age = %q, %v", p, err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	ring, _ := NewKeyRing(Key{ID: "a", Secret: []byte("0123456789abcdef")})
+	v1, err := ring.Encrypt("session", []byte("secret data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, _ := ring.Encrypt("session", []byte("secret data"))
+	if v1 == v2 || strings.Contains(v1, encoding.EncodeToString([]byte("secret data"))) {
+		t.Errorf("values = %s, %s", v1, v2)
+	}
+	if p, err := ring.Decrypt("session", v1, time.Minute); err != nil || string(p) != "secret data" {
+		t.Errorf("Decrypt = %q, %v", p, err)
+	}
+	if _, err := ring.Decrypt("other", v1, time.Minute); err != ErrInvalid {
+		t.Errorf("other name: %v", err)
+	}
+	// the unencrypted timestamp is authenticated
+	parts := strings.SplitN(v1, ".", 3)
+	if _, err := ring.Decrypt("session", parts[0]+".1."+parts[2], 0); err != ErrInvalid {
+		t.Errorf("tampered header: %v", err)
+	}
+	if _, err := ring.Decrypt("session", "a.1.xx", 0); err != ErrInvalid {
+		t.Errorf("short value: %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ring, _ := NewKeyRing(Key{ID: "1", Secret: bytes.Repeat([]byte("1"), 16)})
+	signed, _ := ring.Sign("t", []byte("v"))
+	sealed, _ := ring.Encrypt("t", []byte("v"))
+
+	k, err := ring.RotateNew(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := ring.IDs(); len(ids) != 2 || ids[0] != k.ID || ids[1] != "1" {
+		t.Errorf("IDs = %v", ids)
+	}
+	if s, _ := ring.Sign("t", []byte("v")); !strings.HasPrefix(s, k.ID+".") {
+		t.Errorf("signed with %s", s)
+	}
+	if _, err := ring.Verify("t", signed, 0); err != nil {
+		t.Errorf("old signature: %v", err)
+	}
+	if _, err := ring.Decrypt("t", sealed, 0); err != nil {
+		t.Errorf("old ciphertext: %v", err)
+	}
+
+	if ring.Retire(k.ID) || !ring.Retire("1") {
+		t.Error("Retire")
+	}
+	if _, err := ring.Verify("t", signed, 0); err != ErrUnknownKey {
+		t.Errorf("retired key: %v", err)
+	}
+	ring.RotateNew(0)
+	ring.RotateNew(2)
+	if ids := ring.IDs(); len(ids) != 2 {
+		t.Errorf("IDs = %v", ids)
+	}
+
+	for _, s := range []string{"1:short", "a.b:0123456789abcdef", "nocolon", "1:0123456789abcdef,1:0123456789abcdef"} {
+		keys, err := ParseKeys(s)
+		if err == nil {
+			err = ring.Reset(keys...)
+		}
+		if err == nil {
+			t.Errorf("keys %q accepted", s)
+		}
+	}
+	if ids := ring.IDs(); len(ids) != 2 {
+		t.Errorf("failed Reset changed the ring: %v", ids)
+	}
+	if _, err := (&KeyRing{}).Sign("t", nil); err != ErrNoKey {
+		t.Errorf("empty ring: %v", err)
+	}
+}
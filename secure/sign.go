@@ -0,0 +1,58 @@
+package secure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"strings"
+	"time"
+)
+
+// Sign returns payload signed with the primary key using HMAC-SHA256, in
+// the URL and cookie safe form "keyid.timestamp.payload.mac". The payload
+// is readable by anyone; use Encrypt to keep it secret.
+func (r *KeyRing) Sign(name string, payload []byte) (string, error) {
+	k, err := r.primary()
+	if err != nil {
+		return "", err
+	}
+	msg := valueHeader(k.ID) + "." + encoding.EncodeToString(payload)
+	return msg + "." + encoding.EncodeToString(k.mac(name, msg)), nil
+}
+
+// Verify checks a value produced by Sign with the same name and returns its
+// payload. Values older than maxAge are rejected with ErrExpired (maxAge <= 0
+// disables the check).
+func (r *KeyRing) Verify(name, value string, maxAge time.Duration) ([]byte, error) {
+	id, _, rest, ts, err := splitValue(value)
+	if err != nil {
+		return nil, err
+	}
+	k, err := r.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndex(rest, ".")
+	if i < 0 {
+		return nil, ErrInvalid
+	}
+	mac, err := encoding.DecodeString(rest[i+1:])
+	if err != nil || !hmac.Equal(mac, k.mac(name, value[:len(value)-len(rest)+i])) {
+		return nil, ErrInvalid
+	}
+	if err = checkAge(ts, maxAge); err != nil {
+		return nil, err
+	}
+	payload, err := encoding.DecodeString(rest[:i])
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return payload, nil
+}
+
+func (k *ringKey) mac(name, msg string) []byte {
+	h := hmac.New(sha256.New, k.signKey)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
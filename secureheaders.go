@@ -0,0 +1,133 @@
+package lessgo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// SecureHeadersConfig defines the config for secure headers middleware.
+type SecureHeadersConfig struct {
+	// 为空时不设置对应的响应头
+	XSSProtection      string
+	ContentTypeNosniff string
+	XFrameOptions      string
+
+	// Strict-Transport-Security的max-age，单位秒，0为不设置；仅对HTTPS请求设置
+	HSTSMaxAge            int
+	HSTSExcludeSubdomains bool
+
+	// Content-Security-Policy，为空时不设置
+	ContentSecurityPolicy string
+
+	// 为每个请求生成CSP随机数并加入script-src与style-src指令，
+	// 处理函数中以c.CSPNonce()取得，模板中可用csp_nonce输出
+	CSPNonce bool
+}
+
+// 存放当前请求CSP随机数的请求上下文键
+const CSP_NONCE_KEY = "__csp_nonce__"
+
+var SecureHeaders = ApiMiddleware{
+	Name: "设置安全响应头",
+	Desc: "设置X-XSS-Protection、X-Content-Type-Options、X-Frame-Options、Strict-Transport-Security与Content-Security-Policy响应头；可为每个请求生成CSP随机数，以c.CSPNonce()或模板函数csp_nonce用于内联脚本与样式",
+	Config: SecureHeadersConfig{
+		XSSProtection:         "1; mode=block",
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         "SAMEORIGIN",
+		HSTSMaxAge:            0,
+		HSTSExcludeSubdomains: false,
+		ContentSecurityPolicy: "default-src 'self'",
+		CSPNonce:              true,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(SecureHeadersConfig)
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				header := c.response.Header()
+				if config.XSSProtection != "" {
+					header.Set(HeaderXXSSProtection, config.XSSProtection)
+				}
+				if config.ContentTypeNosniff != "" {
+					header.Set(HeaderXContentTypeOptions, config.ContentTypeNosniff)
+				}
+				if config.XFrameOptions != "" {
+					header.Set(HeaderXFrameOptions, config.XFrameOptions)
+				}
+				if config.HSTSMaxAge > 0 && c.IsTLS() {
+					subdomains := "; includeSubdomains"
+					if config.HSTSExcludeSubdomains {
+						subdomains = ""
+					}
+					header.Set(HeaderStrictTransportSecurity, fmt.Sprintf("max-age=%d%s", config.HSTSMaxAge, subdomains))
+				}
+				if config.ContentSecurityPolicy != "" {
+					policy := config.ContentSecurityPolicy
+					if config.CSPNonce {
+						nonce, err := newCSPNonce()
+						if err != nil {
+							return err
+						}
+						c.Set(CSP_NONCE_KEY, nonce)
+						policy = cspWithNonce(policy, nonce)
+					}
+					header.Set(HeaderContentSecurityPolicy, policy)
+				}
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 返回当前请求的CSP随机数，未启用SecureHeaders中间件的CSPNonce时返回空字符串；
+// 用于内联脚本与样式：<script nonce="{{csp_nonce}}">
+func (c *Context) CSPNonce() string {
+	nonce, _ := c.Get(CSP_NONCE_KEY).(string)
+	return nonce
+}
+
+func newCSPNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// 将随机数加入策略的script-src与style-src指令；策略无script-src时，
+// 以default-src的来源(若有)加随机数补充script-src，使内联脚本可凭随机数执行
+func cspWithNonce(policy, nonce string) string {
+	source := "'nonce-" + nonce + "'"
+	var (
+		directives = strings.Split(policy, ";")
+		hasScript  bool
+		defaultSrc string
+		out        = make([]string, 0, len(directives)+1)
+	)
+	for _, d := range directives {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name := strings.ToLower(strings.Fields(d)[0])
+		switch name {
+		case "script-src", "style-src":
+			if name == "script-src" {
+				hasScript = true
+			}
+			d += " " + source
+		case "default-src":
+			defaultSrc = strings.TrimSpace(d[len(name):])
+		}
+		out = append(out, d)
+	}
+	if !hasScript {
+		d := "script-src"
+		if defaultSrc != "" && defaultSrc != "'none'" {
+			d += " " + defaultSrc
+		}
+		out = append(out, d+" "+source)
+	}
+	return strings.Join(out, "; ")
+}
@@ -0,0 +1,53 @@
+package lessgo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	var nonce string
+	h := SecureHeaders.Middleware.(Middleware).getMiddlewareFunc(SecureHeaders.Config)(func(c *Context) error {
+		nonce = c.CSPNonce()
+		return c.NoContent(http.StatusOK)
+	})
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(GET, "/", nil)
+		c, rec := testContext(req)
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		c.free()
+		if nonce == "" {
+			t.Fatal("empty nonce")
+		}
+		want := "default-src 'self'; script-src 'self' 'nonce-" + nonce + "'"
+		if got := rec.Header().Get(HeaderContentSecurityPolicy); got != want {
+			t.Errorf("CSP = %q, want %q", got, want)
+		}
+		if rec.Header().Get(HeaderXFrameOptions) != "SAMEORIGIN" || rec.Header().Get(HeaderStrictTransportSecurity) != "" {
+			t.Errorf("headers = %v", rec.Header())
+		}
+		nonces = append(nonces, nonce)
+	}
+	if nonces[0] == nonces[1] {
+		t.Error("nonce reused across requests")
+	}
+}
+
+func TestCSPWithNonce(t *testing.T) {
+	for _, test := range []struct{ policy, want string }{
+		{"script-src 'self'; style-src 'self';", "script-src 'self' 'nonce-x'; style-src 'self' 'nonce-x'"},
+		{"default-src 'none'; img-src *", "default-src 'none'; img-src *; script-src 'nonce-x'"},
+		{"object-src 'none'", "object-src 'none'; script-src 'nonce-x'"},
+	} {
+		if got := cspWithNonce(test.policy, "x"); got != test.want {
+			t.Errorf("cspWithNonce(%q) = %q, want %q", test.policy, got, test.want)
+		}
+	}
+	if !strings.Contains(cspWithNonce("Script-Src 'self'", "x"), "Script-Src 'self' 'nonce-x'") {
+		t.Error("directive names are case-insensitive")
+	}
+}
@@ -0,0 +1,64 @@
+package lessgo
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lessgo/lessgo/secure"
+)
+
+// 应用密钥环，用于CSRF令牌、Cookie会话及应用自己的令牌，由system::securekeys配置
+var secureKeys = new(secure.KeyRing)
+
+// 返回应用密钥环，可用于签名与加密应用自己的令牌，如：
+//
+//	token, err := lessgo.SecureKeys().Sign("invite", []byte(userId))
+//	payload, err := lessgo.SecureKeys().Verify("invite", token, 24*time.Hour)
+//
+// 轮换密钥时在system::securekeys的开头加入新密钥并热加载配置，旧密钥签发的值在移除旧密钥前仍可验证；
+// 未配置时使用启动时生成的随机密钥，重启后此前签发的值均失效
+func SecureKeys() *secure.KeyRing {
+	return secureKeys
+}
+
+// 按配置设置应用密钥环，配置有误时保持当前密钥
+func setSecureKeys(c *config) {
+	keys, err := secure.ParseKeys(c.SecureKeys)
+	if err == nil && len(keys) == 0 {
+		if len(secureKeys.IDs()) > 0 {
+			return
+		}
+		Log.Warn("system::securekeys is not set, a random key is used and the signed values will be invalid after restart.")
+		var k secure.Key
+		k, err = secure.GenerateKey("")
+		keys = []secure.Key{k}
+	}
+	if err == nil {
+		err = secureKeys.Reset(keys...)
+	}
+	if err != nil {
+		Log.Error("Invalid system::securekeys: %v", err)
+	}
+}
+
+// 设置以应用密钥环加密的Cookie，Cookie名参与认证，值不可被读取或篡改
+func (c *Context) SetSecureCookie(cookie *http.Cookie) error {
+	value, err := secureKeys.Encrypt("cookie:"+cookie.Name, []byte(cookie.Value))
+	if err != nil {
+		return err
+	}
+	sc := *cookie
+	sc.Value = value
+	c.response.AddCookie(&sc)
+	return nil
+}
+
+// 读取由SetSecureCookie设置的Cookie值，超过maxAge(<=0时不检查)或验证失败时返回错误
+func (c *Context) SecureCookieParam(name string, maxAge time.Duration) (string, error) {
+	cookie, err := c.request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := secureKeys.Decrypt("cookie:"+name, cookie.Value, maxAge)
+	return string(value), err
+}
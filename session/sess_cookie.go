@@ -21,6 +21,9 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/secure"
 )
 
 var cookiepder = &CookieProvider{}
@@ -71,10 +74,7 @@ func (st *CookieSessionStore) SessionID() string {
 
 // SessionRelease Write cookie session to http response cookie
 func (st *CookieSessionStore) SessionRelease(w http.ResponseWriter) {
-	str, err := encodeCookie(cookiepder.block,
-		cookiepder.config.SecurityKey,
-		cookiepder.config.SecurityName,
-		st.values)
+	str, err := cookiepder.encode(st.values)
 	if err != nil {
 		return
 	}
@@ -89,6 +89,7 @@ func (st *CookieSessionStore) SessionRelease(w http.ResponseWriter) {
 }
 
 type cookieConfig struct {
+	Keys         string `json:"keys"`
 	SecurityKey  string `json:"securityKey"`
 	BlockKey     string `json:"blockKey"`
 	SecurityName string `json:"securityName"`
@@ -101,13 +102,29 @@ type CookieProvider struct {
 	maxlifetime int64
 	config      *cookieConfig
 	block       cipher.Block
+	keys        *secure.KeyRing
 }
 
 var CookieName string
 
+// secureCookieName binds the values encrypted by the key ring to cookie sessions.
+const secureCookieName = "lessgo-session"
+
+var cookieKeyRing *secure.KeyRing
+
+// SetCookieKeyRing sets the key ring used to encrypt cookie sessions whose
+// provider config has no keys. It must be called before the session manager
+// is created.
+func SetCookieKeyRing(r *secure.KeyRing) {
+	cookieKeyRing = r
+}
+
 // SessionInit Init cookie session provider with max lifetime and config json.
 // maxlifetime is ignored.
 // json config:
+// 	keys - versioned key ring like "2:secret2,1:secret1", see package secure.
+// 	       Values are encrypted with AES-GCM by the first key; cookies made by
+// 	       securityKey are still read. Defaults to the ring set by SetCookieKeyRing.
 // 	securityKey - hash string
 // 	blockKey - gob encode hash string. it's saved as aes crypto.
 // 	securityName - recognized name in encoded cookie string
@@ -129,17 +146,49 @@ func (pder *CookieProvider) SessionInit(maxlifetime int64, config string) error
 	if err != nil {
 		return err
 	}
+	pder.keys = cookieKeyRing
+	if pder.config.Keys != "" {
+		keys, err := secure.ParseKeys(pder.config.Keys)
+		if err != nil {
+			return err
+		}
+		if pder.keys, err = secure.NewKeyRing(keys...); err != nil {
+			return err
+		}
+	}
 	pder.maxlifetime = maxlifetime
 	return nil
 }
 
+func (pder *CookieProvider) encode(values map[interface{}]interface{}) (string, error) {
+	if pder.keys == nil {
+		return encodeCookie(pder.block, pder.config.SecurityKey, pder.config.SecurityName, values)
+	}
+	b, err := EncodeGob(values)
+	if err != nil {
+		return "", err
+	}
+	return pder.keys.Encrypt(secureCookieName, b)
+}
+
+func (pder *CookieProvider) decode(sid string) (map[interface{}]interface{}, error) {
+	if pder.keys != nil {
+		b, err := pder.keys.Decrypt(secureCookieName, sid, time.Duration(pder.maxlifetime)*time.Second)
+		if err == nil {
+			return DecodeGob(b)
+		}
+		if pder.config.SecurityKey == "" {
+			return nil, err
+		}
+		// cookies made by securityKey before switching to keys
+	}
+	return decodeCookie(pder.block, pder.config.SecurityKey, pder.config.SecurityName, sid, pder.maxlifetime)
+}
+
 // SessionRead Get SessionStore in cooke.
 // decode cooke string to map and put into SessionStore with sid.
 func (pder *CookieProvider) SessionRead(sid string) (Store, error) {
-	maps, _ := decodeCookie(pder.block,
-		pder.config.SecurityKey,
-		pder.config.SecurityName,
-		sid, pder.maxlifetime)
+	maps, _ := pder.decode(sid)
 	if maps == nil {
 		maps = make(map[interface{}]interface{})
 	}
@@ -91,3 +91,46 @@ func TestDestorySessionCookie(t *testing.T) {
 		t.Fatal("after destroy session and reqeust again ,get cookie session id is same.")
 	}
 }
+
+func TestCookieKeys(t *testing.T) {
+	legacy := `{"cookieName":"gosessionid","gclifetime":3600,"ProviderConfig":"{\"securityKey\":\"beegocookiehashkey\",\"blockKey\":\"0123456789abcdef\",\"securityName\":\"s\"}"}`
+	keys := `{"cookieName":"gosessionid","gclifetime":3600,"ProviderConfig":"{\"keys\":\"2:0123456789abcdef0123\",\"securityKey\":\"beegocookiehashkey\",\"blockKey\":\"0123456789abcdef\",\"securityName\":\"s\"}"}`
+	release := func(config string, set map[string]string, cookie string) *httptest.ResponseRecorder {
+		m, err := NewManager("cookie", config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _ := http.NewRequest("GET", "/", nil)
+		if cookie != "" {
+			r.Header.Set("Cookie", cookie)
+		}
+		w := httptest.NewRecorder()
+		sess, err := m.SessionStart(w, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set == nil {
+			if got := sess.Get("username"); got != "astaxie" {
+				t.Errorf("username = %v", got)
+			}
+			set = map[string]string{"username": "astaxie"}
+		}
+		for k, v := range set {
+			sess.Set(k, v)
+		}
+		sess.SessionRelease(w)
+		return w
+	}
+	cookieOf := func(w *httptest.ResponseRecorder) string {
+		cookies := w.Header()["Set-Cookie"]
+		return strings.Split(cookies[len(cookies)-1], ";")[0]
+	}
+
+	// a cookie made by securityKey is read after switching to keys and rewritten with them
+	w := release(legacy, map[string]string{"username": "astaxie"}, "")
+	w = release(keys, nil, cookieOf(w))
+	if c := cookieOf(w); !strings.HasPrefix(c, "gosessionid=2.") {
+		t.Errorf("cookie = %s", c)
+	}
+	release(keys, nil, cookieOf(w))
+}
@@ -0,0 +1,186 @@
+package lessgo
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// SignatureConfig defines the config for HMAC request signature middleware.
+	SignatureConfig struct {
+		// 存放客户端标识、签名与时间戳的请求头
+		KeyIdHeader     string
+		SignatureHeader string
+		TimestampHeader string
+
+		// 签名值的前缀，如GitHub风格的"sha256="
+		SignaturePrefix string
+
+		// 摘要算法：sha1、sha256、sha512
+		Algorithm string
+
+		// 签名编码：hex、base64
+		Encoding string
+
+		// 待签名内容：
+		// "body"时为 timestamp + "." + body；
+		// "request"时为 method + "\n" + uri + "\n" + timestamp + "\n" + body
+		Scheme string
+
+		// 允许的时间戳偏差，单位秒，同时作为防重放缓存的保留时长
+		SkewSeconds int64
+
+		// 是否拒绝重复的签名
+		RejectReplay bool
+
+		// 读取请求体的上限
+		MaxBodyBytes int64
+	}
+)
+
+var (
+	// 根据客户端标识查询签名密钥，返回nil表示未知客户端
+	signatureSecretFunc = func(keyId string) ([]byte, error) {
+		return nil, nil
+	}
+	signatureSecretFuncLock sync.RWMutex
+)
+
+var SignatureVerify = ApiMiddleware{
+	Name: "HMAC签名验证",
+	Desc: "使用按客户端分配的密钥验证请求签名，校验时间戳偏差并防止重放，适用于Webhook接收与合作方接口",
+	Config: SignatureConfig{
+		KeyIdHeader:     "X-Key-Id",
+		SignatureHeader: "X-Signature",
+		TimestampHeader: "X-Timestamp",
+		SignaturePrefix: "",
+		Algorithm:       "sha256",
+		Encoding:        "hex",
+		Scheme:          "request",
+		SkewSeconds:     300,
+		RejectReplay:    true,
+		MaxBodyBytes:    4 * MB,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(SignatureConfig)
+		newHash := signatureHash(config.Algorithm)
+		if newHash == nil {
+			Log.Error("SignatureVerify: unsupported algorithm %q, use sha256", config.Algorithm)
+			newHash = sha256.New
+		}
+		skew := time.Duration(config.SkewSeconds) * time.Second
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				keyId := req.Header.Get(config.KeyIdHeader)
+				sig := strings.TrimPrefix(req.Header.Get(config.SignatureHeader), config.SignaturePrefix)
+				ts := req.Header.Get(config.TimestampHeader)
+				if keyId == "" || sig == "" || ts == "" {
+					return NewHTTPError(http.StatusUnauthorized, "missing signature headers")
+				}
+
+				unix, err := strconv.ParseInt(ts, 10, 64)
+				if err != nil {
+					return NewHTTPError(http.StatusUnauthorized, "invalid timestamp")
+				}
+				if skew > 0 {
+					d := time.Since(time.Unix(unix, 0))
+					if d > skew || d < -skew {
+						return NewHTTPError(http.StatusUnauthorized, "timestamp out of range")
+					}
+				}
+
+				signatureSecretFuncLock.RLock()
+				fn := signatureSecretFunc
+				signatureSecretFuncLock.RUnlock()
+				secret, err := fn(keyId)
+				if err != nil {
+					return err
+				}
+				if secret == nil {
+					return NewHTTPError(http.StatusUnauthorized, "unknown key id")
+				}
+
+				got, err := decodeSignature(sig, config.Encoding)
+				if err != nil {
+					return NewHTTPError(http.StatusUnauthorized, "invalid signature encoding")
+				}
+
+				// 读取请求体，并还原供后续处理使用
+				var body []byte
+				if req.Body != nil {
+					body, err = ioutil.ReadAll(http.MaxBytesReader(c.response, req.Body, config.MaxBodyBytes))
+					if err != nil {
+						return NewHTTPError(http.StatusRequestEntityTooLarge)
+					}
+					req.Body = ioutil.NopCloser(bytes.NewReader(body))
+				}
+
+				mac := hmac.New(newHash, secret)
+				if config.Scheme == "body" {
+					fmt.Fprintf(mac, "%s.", ts)
+				} else {
+					fmt.Fprintf(mac, "%s\n%s\n%s\n", req.Method, req.URL.RequestURI(), ts)
+				}
+				mac.Write(body)
+				if !hmac.Equal(got, mac.Sum(nil)) {
+					return NewHTTPError(http.StatusUnauthorized, "signature mismatch")
+				}
+
+				if config.RejectReplay {
+					// 时间戳在[-skew, skew]内均有效，故保留两倍时长
+					// 以解码后的签名为键，避免同一签名换用不同编码形式(如大小写)重放
+					ok, err := useNonce("signature:"+keyId+":"+hex.EncodeToString(got), 2*skew)
+					if err != nil {
+						return err
+					}
+					if !ok {
+						return NewHTTPError(http.StatusUnauthorized, "replayed request")
+					}
+				}
+				c.Set("signatureKeyId", keyId)
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 设置根据客户端标识查询签名密钥的函数
+func SetSignatureSecretFunc(fn func(keyId string) ([]byte, error)) {
+	signatureSecretFuncLock.Lock()
+	signatureSecretFunc = fn
+	signatureSecretFuncLock.Unlock()
+}
+
+func signatureHash(algorithm string) func() hash.Hash {
+	switch strings.ToLower(algorithm) {
+	case "sha1":
+		return sha1.New
+	case "sha256", "":
+		return sha256.New
+	case "sha512":
+		return sha512.New
+	}
+	return nil
+}
+
+func decodeSignature(sig, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(sig)
+	}
+	return hex.DecodeString(sig)
+}
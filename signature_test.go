@@ -0,0 +1,86 @@
+package lessgo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeSignature(t *testing.T) {
+	for _, test := range []struct {
+		sig, encoding string
+		ok            bool
+	}{
+		{"0a1b", "hex", true},
+		{"zz", "hex", false},
+		{"Chs=", "base64", true},
+		{"Chs", "base64", false},
+	} {
+		b, err := decodeSignature(test.sig, test.encoding)
+		if (err == nil) != test.ok {
+			t.Errorf("decodeSignature(%q, %q) error = %v", test.sig, test.encoding, err)
+			continue
+		}
+		if test.ok && (len(b) != 2 || b[0] != 0x0a || b[1] != 0x1b) {
+			t.Errorf("decodeSignature(%q, %q) = %x", test.sig, test.encoding, b)
+		}
+	}
+}
+
+func TestSignatureVerify(t *testing.T) {
+	SetSignatureSecretFunc(func(keyId string) ([]byte, error) {
+		if keyId == "partner" {
+			return []byte("secret"), nil
+		}
+		return nil, nil
+	})
+	defer SetSignatureSecretFunc(func(string) ([]byte, error) { return nil, nil })
+	h := SignatureVerify.Middleware.(Middleware).getMiddlewareFunc(SignatureVerify.Config)(func(c *Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	sign := func(ts, body string) string {
+		mac := hmac.New(sha256.New, []byte("secret"))
+		fmt.Fprintf(mac, "POST\n/hook\n%s\n%s", ts, body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	do := func(ts, sig, body string) error {
+		req, _ := http.NewRequest("POST", "/hook", strings.NewReader(body))
+		req.Header.Set("X-Key-Id", "partner")
+		req.Header.Set("X-Timestamp", ts)
+		req.Header.Set("X-Signature", sig)
+		c, _ := testContext(req)
+		defer c.free()
+		return h(c)
+	}
+	code := func(err error) int {
+		if he, ok := err.(*HTTPError); ok {
+			return he.Code
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return http.StatusOK
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := sign(now, `{"id":1}`)
+	if err := do(now, sig, `{"id":1}`); code(err) != http.StatusOK {
+		t.Fatalf("valid signature: %v", err)
+	}
+	if err := do(now, strings.ToUpper(sig), `{"id":1}`); code(err) != http.StatusUnauthorized {
+		t.Fatalf("replayed signature with different case: got %v, want 401", err)
+	}
+	if err := do(now, sign(now, `{"id":2}`), `{"id":3}`); code(err) != http.StatusUnauthorized {
+		t.Fatalf("signature mismatch: got %v, want 401", err)
+	}
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	if err := do(old, sign(old, `{"id":4}`), `{"id":4}`); code(err) != http.StatusUnauthorized {
+		t.Fatalf("stale timestamp: got %v, want 401", err)
+	}
+}
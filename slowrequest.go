@@ -0,0 +1,246 @@
+package lessgo
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// SlowRequestConfig defines the config for slow request detection middleware.
+	SlowRequestConfig struct {
+		// 请求耗时阈值，单位毫秒，0表示不检测
+		LatencyMS int64
+
+		// 响应体大小阈值，单位字节，0表示不检测
+		ResponseBytes int64
+
+		// 打印日志时需隐藏值的参数名片段，参数名包含其一(不区分大小写)即隐藏，如token可匹配access_token
+		RedactParams []string
+	}
+
+	// 单个路由的慢请求统计
+	SlowRequestStat struct {
+		Route     string `json:"route"`
+		Slow      int64  `json:"slow"`
+		Large     int64  `json:"large"`
+		MaxMS     int64  `json:"max_ms"`
+		MaxBytes  int64  `json:"max_bytes"`
+		LastMS    int64  `json:"last_ms"`
+		LastBytes int64  `json:"last_bytes"`
+	}
+
+	slowRequestCounter struct {
+		slow, large, maxMS, maxBytes, lastMS, lastBytes int64
+	}
+
+	// 记录首字节写出时间的ResponseWriter
+	ttfbWriter struct {
+		responseWriterWrapper
+		first time.Time
+	}
+)
+
+// 未匹配路由的请求统一计入的统计键，避免按请求路径无限增长
+const SLOW_REQUEST_UNMATCHED = "<unmatched>"
+
+var (
+	slowRequestCounters    = map[string]*slowRequestCounter{}
+	slowRequestCountersMux sync.RWMutex
+)
+
+var SlowRequest = ApiMiddleware{
+	Name: "慢请求与大响应检测",
+	Desc: "标记耗时或响应体超过阈值的请求，打印路由、参数与耗时明细，并进行计数",
+	Config: SlowRequestConfig{
+		LatencyMS:     1000,
+		ResponseBytes: 4 * MB,
+		RedactParams:  []string{"password", "passwd", "token", "secret", "key"},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(SlowRequestConfig)
+		threshold := time.Duration(config.LatencyMS) * time.Millisecond
+		redact := make([]string, len(config.RedactParams))
+		for i, r := range config.RedactParams {
+			redact[i] = strings.ToLower(r)
+		}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				w := &ttfbWriter{responseWriterWrapper: responseWriterWrapper{c.response.writer}}
+				c.response.writer = w
+				start := time.Now()
+				err := next(c)
+				latency := time.Now().Sub(start)
+				c.response.writer = w.ResponseWriter
+
+				size := c.response.Size()
+				slow := threshold > 0 && latency >= threshold
+				large := config.ResponseBytes > 0 && size >= config.ResponseBytes
+				if !slow && !large {
+					return err
+				}
+
+				route := c.path
+				if route == "" {
+					route = SLOW_REQUEST_UNMATCHED
+				}
+				countSlowRequest(route, slow, large, int64(latency/time.Millisecond), size)
+
+				var ttfb time.Duration
+				if !w.first.IsZero() {
+					ttfb = w.first.Sub(start)
+				}
+				var kind string
+				switch {
+				case slow && large:
+					kind = "SLOW+LARGE"
+				case slow:
+					kind = "SLOW"
+				default:
+					kind = "LARGE"
+				}
+				Log.Warn("[%s] %s %s (route: %s) | params: %s | total: %v, ttfb: %v, write: %v | status: %d, bytes: %d",
+					kind, c.request.Method, c.request.URL.Path, route,
+					redactedParams(c, redact),
+					latency, ttfb, latency-ttfb,
+					c.response.Status(), size,
+				)
+				return err
+			}
+		}
+	},
+}.Reg()
+
+// 返回慢请求统计列表，按路由排序
+func SlowRequestStats() []SlowRequestStat {
+	slowRequestCountersMux.RLock()
+	defer slowRequestCountersMux.RUnlock()
+	stats := make([]SlowRequestStat, 0, len(slowRequestCounters))
+	for route, sc := range slowRequestCounters {
+		stats = append(stats, SlowRequestStat{
+			Route:     route,
+			Slow:      atomic.LoadInt64(&sc.slow),
+			Large:     atomic.LoadInt64(&sc.large),
+			MaxMS:     atomic.LoadInt64(&sc.maxMS),
+			MaxBytes:  atomic.LoadInt64(&sc.maxBytes),
+			LastMS:    atomic.LoadInt64(&sc.lastMS),
+			LastBytes: atomic.LoadInt64(&sc.lastBytes),
+		})
+	}
+	sort.Sort(slowRequestStatSlice(stats))
+	return stats
+}
+
+// 查询慢请求统计的操作，供后台管理路由使用
+var SlowRequestStatsHandler = ApiHandler{
+	Desc:   "查询各路由的慢请求与大响应统计",
+	Method: "GET",
+	Handler: func(c *Context) error {
+		return c.JSON(http.StatusOK, SlowRequestStats())
+	},
+}.Reg()
+
+// 清空慢请求统计
+func ResetSlowRequestStats() {
+	slowRequestCountersMux.Lock()
+	slowRequestCounters = map[string]*slowRequestCounter{}
+	slowRequestCountersMux.Unlock()
+}
+
+func countSlowRequest(route string, slow, large bool, ms, size int64) {
+	slowRequestCountersMux.RLock()
+	sc, ok := slowRequestCounters[route]
+	slowRequestCountersMux.RUnlock()
+	if !ok {
+		slowRequestCountersMux.Lock()
+		sc, ok = slowRequestCounters[route]
+		if !ok {
+			sc = new(slowRequestCounter)
+			slowRequestCounters[route] = sc
+		}
+		slowRequestCountersMux.Unlock()
+	}
+	if slow {
+		atomic.AddInt64(&sc.slow, 1)
+	}
+	if large {
+		atomic.AddInt64(&sc.large, 1)
+	}
+	atomic.StoreInt64(&sc.lastMS, ms)
+	atomic.StoreInt64(&sc.lastBytes, size)
+	storeMaxInt64(&sc.maxMS, ms)
+	storeMaxInt64(&sc.maxBytes, size)
+}
+
+func storeMaxInt64(addr *int64, v int64) {
+	for {
+		old := atomic.LoadInt64(addr)
+		if v <= old || atomic.CompareAndSwapInt64(addr, old, v) {
+			return
+		}
+	}
+}
+
+// 格式化path与query参数，隐藏敏感参数的值；redact为小写的参数名片段
+func redactedParams(c *Context, redact []string) string {
+	var buf bytes.Buffer
+	write := func(k, v string) {
+		if buf.Len() > 0 {
+			buf.WriteByte('&')
+		}
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		lk := strings.ToLower(k)
+		for _, r := range redact {
+			if r != "" && strings.Contains(lk, r) {
+				v = "***"
+				break
+			}
+		}
+		buf.WriteString(v)
+	}
+	for i, k := range c.pkeys {
+		if i < len(c.pvalues) {
+			write(k, c.pvalues[i])
+		}
+	}
+	for k, vs := range c.QueryValues() {
+		for _, v := range vs {
+			write(k, v)
+		}
+	}
+	return buf.String()
+}
+
+func (w *ttfbWriter) WriteHeader(code int) {
+	if w.first.IsZero() {
+		w.first = time.Now()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ttfbWriter) Write(b []byte) (int, error) {
+	if w.first.IsZero() {
+		w.first = time.Now()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+type slowRequestStatSlice []SlowRequestStat
+
+func (s slowRequestStatSlice) Len() int {
+	return len(s)
+}
+
+func (s slowRequestStatSlice) Less(i, j int) bool {
+	return s[i].Route < s[j].Route
+}
+
+func (s slowRequestStatSlice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
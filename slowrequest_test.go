@@ -0,0 +1,64 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSlowRequest(t *testing.T) {
+	ResetSlowRequestStats()
+	defer ResetSlowRequestStats()
+	h := SlowRequest.Middleware.(Middleware).getMiddlewareFunc(SlowRequestConfig{ResponseBytes: 4})(func(c *Context) error {
+		return c.String(http.StatusOK, "large body")
+	})
+	do := func(path, route string) {
+		req, _ := http.NewRequest(GET, path, nil)
+		c, rec := testContext(req)
+		defer c.free()
+		c.path = route
+		if err := h(c); err != nil || rec.Body.String() != "large body" {
+			t.Fatalf("%s = %q, %v", path, rec.Body.String(), err)
+		}
+	}
+	do("/orders/1", "/orders/:id")
+	do("/orders/2", "/orders/:id")
+	// 未匹配路由的请求不按路径分别计数
+	for _, path := range []string{"/scan/a", "/scan/b", "/scan/c"} {
+		do(path, "")
+	}
+	stats := SlowRequestStats()
+	if len(stats) != 2 || stats[0].Route != "/orders/:id" || stats[1].Route != SLOW_REQUEST_UNMATCHED {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if s := stats[1]; s.Large != 3 || s.Slow != 0 || s.MaxBytes != 10 {
+		t.Errorf("unmatched = %+v", s)
+	}
+
+	req, _ := http.NewRequest(GET, "/admin/api/slow_requests", nil)
+	c, rec := testContext(req)
+	defer c.free()
+	if err := SlowRequestStatsHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	var got []SlowRequestStat
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got) != 2 || got[0].Large != 2 {
+		t.Errorf("handler = %s, %v", rec.Body.String(), err)
+	}
+}
+
+func TestRedactedParams(t *testing.T) {
+	req, _ := http.NewRequest(GET, "/?access_token=a&X-Api-Token=b&Password=c&page=2", nil)
+	c, _ := testContext(req)
+	defer c.free()
+	got := redactedParams(c, []string{"token", "password"})
+	for _, want := range []string{"access_token=***", "X-Api-Token=***", "Password=***", "page=2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("redactedParams = %q, want %q", got, want)
+		}
+	}
+	if strings.Contains(got, "=a") || strings.Contains(got, "=b") || strings.Contains(got, "=c") {
+		t.Errorf("sensitive value leaked: %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+package lessgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestContextStore(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	c := NewContext(httptest.NewRecorder(), req)
+	for i := 0; i < inlineStoreSize+4; i++ {
+		c.Set("k"+strconv.Itoa(i), i)
+	}
+	c.Set("k1", "one")
+	c.Set("k10", "ten")
+	for i := 0; i < inlineStoreSize+4; i++ {
+		key := "k" + strconv.Itoa(i)
+		if !c.Contains(key) {
+			t.Errorf("%s missing", key)
+		}
+	}
+	if c.Get("k1") != "one" || c.Get("k10") != "ten" || c.Get("k2") != 2 {
+		t.Errorf("Get = %v, %v, %v", c.Get("k1"), c.Get("k10"), c.Get("k2"))
+	}
+	c.Del("k0")
+	c.Del("k11")
+	if c.Contains("k0") || c.Contains("k11") || c.Get("k7") != 7 || c.Get("k9") != 9 {
+		t.Error("Del removed the wrong keys")
+	}
+	c.Set("k0", 0) // takes the freed slot, k8 stays in the map
+	c.Set("k8", "eight")
+	if c.Get("k0") != 0 || c.Get("k8") != "eight" || c.store.n != inlineStoreSize {
+		t.Errorf("store = %+v", c.store)
+	}
+	c.store.reset()
+	if c.Contains("k1") || c.Contains("k10") {
+		t.Error("reset kept values")
+	}
+}
+
+func TestParamAllocs(t *testing.T) {
+	var (
+		user = new(struct{ id string })
+		post string
+	)
+	r := NewRouter()
+	r.Handle("GET", "/users/:id/posts/:post", func(c *Context) error {
+		user.id = c.PathParam("id")
+		c.Set("user", user) // boxing a pointer doesn't allocate
+		post = c.PathParam("post")
+		return nil
+	})
+	h := r.process(chainEndHandler)
+	req, _ := http.NewRequest("GET", "/users/42/posts/7", nil)
+	c := app.newContext(new(Response), req)
+	rw := httptest.NewRecorder()
+	allocs := testing.AllocsPerRun(100, func() {
+		c.init(rw, req)
+		if err := h(c); err != nil || c.Get("user") != user || user.id != "42" || post != "7" {
+			t.Fatalf("handler: %v, user = %v, post = %q", err, user, post)
+		}
+		c.free()
+	})
+	if allocs > 0 {
+		t.Errorf("routing with params allocates %v times per request", allocs)
+	}
+}
+
+func BenchmarkParams(b *testing.B) {
+	r := NewRouter()
+	user := new(struct{ id string })
+	r.Handle("GET", "/users/:id/posts/:post", func(c *Context) error {
+		user.id = c.PathParam("id")
+		c.Set("user", user)
+		return nil
+	})
+	h := r.process(chainEndHandler)
+	req, _ := http.NewRequest("GET", "/users/42/posts/7", nil)
+	c := app.newContext(new(Response), req)
+	rw := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.init(rw, req)
+		h(c)
+		c.free()
+	}
+}
+
+func TestBytesAccessors(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/users/42?a=1&name=go+pher&b&c=%E2%9C%93&a=2", nil)
+	req.Header.Set("X-Token", "secret")
+	c := NewContext(httptest.NewRecorder(), req)
+	c.SetPathParam("id", "42")
+
+	for key, want := range map[string]string{"a": "1", "name": "go pher", "b": "", "c": "✓", "missing": ""} {
+		if got := string(c.QueryBytes(key)); got != want {
+			t.Errorf("QueryBytes(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if string(c.HeaderBytes("x-token")) != "secret" || string(c.ParamBytes("id")) != "42" || c.ParamBytes("nope") != nil {
+		t.Errorf("HeaderBytes = %q, ParamBytes = %q", c.HeaderBytes("x-token"), c.ParamBytes("id"))
+	}
+	c.SetQueryParam("a", "3")
+	if string(c.QueryBytes("a")) != "3" {
+		t.Errorf("QueryBytes after SetQueryParam = %q", c.QueryBytes("a"))
+	}
+
+	c = NewContext(httptest.NewRecorder(), req)
+	c.SetPathParam("id", "42")
+	allocs := testing.AllocsPerRun(100, func() {
+		if len(c.QueryBytes("a")) != 1 || len(c.HeaderBytes("X-Token")) != 6 || len(c.ParamBytes("id")) != 2 {
+			t.Fatal("wrong values")
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("byte accessors allocate %v times", allocs)
+	}
+}
@@ -0,0 +1,304 @@
+package lessgo
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+/*
+ * 严格解析模式(listen::strictparsing)
+ * net/http在交给处理函数前已规范化请求(如同时存在Content-Length与Transfer-Encoding时丢弃前者、
+ * 合并折行的请求头)，处理函数无从判断原始请求是否可疑，因此在连接上检查原始的请求头：
+ * 发现可能用于请求走私的请求时，net/http在路由之前即响应400并关闭连接。
+ */
+
+// 严格解析模式下单个请求默认允许的最大请求头数，设置了RequestLimits.MaxHeaderCount时以其为准
+const strictMaxHeaderCount = 100
+
+// 单行超过此长度时停止检查，交由net/http按MaxHeaderBytes处理
+const strictMaxLineBytes = 1 << 20
+
+// 拒绝请求时代替其余数据交给net/http的畸形请求行，net/http据此响应400并关闭连接
+const strictBadRequest = "-\r\n\r\n"
+
+// 严格解析模式拒绝请求的原因
+type strictParseError string
+
+func (e strictParseError) Error() string {
+	return string(e)
+}
+
+var (
+	errStrictConflictLength = strictParseError("conflicting Content-Length and Transfer-Encoding")
+	errStrictLengths        = strictParseError("conflicting Content-Length")
+	errStrictObsFold        = strictParseError("obsolete line folding in header")
+	errStrictNUL            = strictParseError("NUL byte in header")
+	errStrictHeaderCount    = strictParseError("too many headers")
+)
+
+const (
+	strictHead = iota
+	strictBody
+	strictChunkSize
+	strictChunkData
+	strictTrailer
+	strictPass // 不再检查，如已升级为websocket的连接
+)
+
+type (
+	strictListener struct {
+		net.Listener
+	}
+
+	// 跟踪HTTP/1.x请求的分帧，逐个检查请求头，跳过请求体
+	strictConn struct {
+		net.Conn
+		state      int
+		line       []byte
+		start      int   // 当前请求在本次读取数据中的起始位置
+		lines      int   // 当前请求已读取的行数，含请求行
+		remain     int64 // 请求体或当前chunk未读的字节数
+		maxHeaders int
+		method     string
+		length     string // Content-Length
+		hasTE      bool
+		chunked    bool
+		upgrade    bool
+		err        error
+		rest       []byte // 拒绝请求后待返回的strictBadRequest
+	}
+
+	strictTLSKey struct{}
+)
+
+// 以严格解析模式包装监听器，TLS监听器须在TLS层之上包装
+func newStrictListener(ln net.Listener) net.Listener {
+	return &strictListener{ln}
+}
+
+func (l *strictListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	max := GetRequestLimits().MaxHeaderCount
+	if max <= 0 {
+		max = strictMaxHeaderCount
+	}
+	return &strictConn{Conn: c, maxHeaders: max}, nil
+}
+
+// 被拒绝的请求之前的数据照常返回，其后返回strictBadRequest与io.EOF
+func (c *strictConn) Read(b []byte) (int, error) {
+	if c.err != nil {
+		if len(c.rest) > 0 {
+			n := copy(b, c.rest)
+			c.rest = c.rest[n:]
+			return n, nil
+		}
+		return 0, io.EOF
+	}
+	n, err := c.Conn.Read(b)
+	if c.state != strictPass {
+		if i, e := c.inspect(b[:n]); e != nil {
+			Log.Debug("Strict parsing: rejected request from %v: %v", c.RemoteAddr(), e)
+			c.err = e
+			c.rest = []byte(strictBadRequest)
+			if i > 0 {
+				return i, nil
+			}
+			return c.Read(b)
+		}
+	}
+	return n, err
+}
+
+// 检查读取的数据，出错时返回被拒绝的请求在p中的起始位置
+func (c *strictConn) inspect(p []byte) (int, error) {
+	c.start = 0
+	for i := 0; i < len(p) && c.state != strictPass; {
+		switch c.state {
+		case strictBody, strictChunkData:
+			n := int64(len(p) - i)
+			if n > c.remain {
+				n = c.remain
+			}
+			c.remain -= n
+			i += int(n)
+			if c.remain == 0 {
+				if c.state == strictBody {
+					c.reset()
+				} else {
+					c.state = strictChunkSize
+				}
+			}
+		default:
+			if c.state == strictHead && c.lines == 0 && len(c.line) == 0 {
+				c.start = i
+			}
+			b := p[i]
+			i++
+			if b == 0 {
+				return c.start, errStrictNUL
+			}
+			c.line = append(c.line, b)
+			if b != '\n' {
+				if len(c.line) > strictMaxLineBytes {
+					c.state = strictPass
+				}
+				continue
+			}
+			line := bytes.TrimRight(c.line, "\r\n")
+			c.line = c.line[:0]
+			if err := c.endLine(line); err != nil {
+				return c.start, err
+			}
+		}
+	}
+	return 0, nil
+}
+
+func (c *strictConn) endLine(line []byte) error {
+	switch c.state {
+	case strictHead:
+		return c.headLine(line)
+	case strictChunkSize:
+		if i := bytes.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		size, err := strconv.ParseInt(string(bytes.TrimSpace(line)), 16, 64)
+		switch {
+		case err != nil || size < 0:
+			c.state = strictPass // 由net/http拒绝
+		case size == 0:
+			c.state = strictTrailer
+		default:
+			c.state = strictChunkData
+			c.remain = size + 2 // 含chunk末尾的CRLF
+		}
+	case strictTrailer:
+		if len(line) == 0 {
+			c.reset()
+		}
+	}
+	return nil
+}
+
+func (c *strictConn) headLine(line []byte) error {
+	if c.lines == 0 {
+		// 忽略请求行之前的空行
+		if len(line) == 0 {
+			return nil
+		}
+		c.lines++
+		if bytes.HasPrefix(line, []byte("PRI * HTTP/2")) {
+			c.state = strictPass
+			return nil
+		}
+		if i := bytes.IndexByte(line, ' '); i > 0 {
+			c.method = string(line[:i])
+		}
+		return nil
+	}
+	if len(line) == 0 {
+		return c.endHead()
+	}
+	if line[0] == ' ' || line[0] == '\t' {
+		return errStrictObsFold
+	}
+	c.lines++
+	if c.lines-1 > c.maxHeaders {
+		return errStrictHeaderCount
+	}
+	i := bytes.IndexByte(line, ':')
+	if i < 0 {
+		return nil // 由net/http拒绝
+	}
+	value := string(bytes.TrimSpace(line[i+1:]))
+	switch strings.ToLower(string(line[:i])) {
+	case "content-length":
+		if c.length != "" && c.length != value {
+			return errStrictLengths
+		}
+		c.length = value
+	case "transfer-encoding":
+		c.hasTE = true
+		c.chunked = strings.EqualFold(value, "chunked")
+	case "upgrade":
+		c.upgrade = true
+	}
+	return nil
+}
+
+// 请求头结束，按请求体的分帧方式转换状态
+func (c *strictConn) endHead() error {
+	switch {
+	case c.hasTE && c.length != "":
+		return errStrictConflictLength
+	case c.upgrade || c.method == "CONNECT":
+		c.state = strictPass
+	case c.hasTE:
+		if !c.chunked {
+			c.state = strictPass // 由net/http以501拒绝
+			return nil
+		}
+		c.state = strictChunkSize
+		c.lines = 0
+	case c.length != "":
+		n, err := strconv.ParseInt(c.length, 10, 64)
+		if err != nil || n < 0 {
+			c.state = strictPass
+			return nil
+		}
+		if n == 0 {
+			c.reset()
+			return nil
+		}
+		c.state = strictBody
+		c.remain = n
+		c.lines = 0
+	default:
+		c.reset()
+	}
+	return nil
+}
+
+// 准备读取下一个请求
+func (c *strictConn) reset() {
+	c.state = strictHead
+	c.lines = 0
+	c.method = ""
+	c.length = ""
+	c.hasTE = false
+	c.chunked = false
+	c.upgrade = false
+}
+
+// 包装后的连接不再是*tls.Conn，net/http无法设置req.TLS，
+// 因此在连接上下文中保存TLS连接，由strictTLSHandler恢复
+func strictConnContext(ctx context.Context, c net.Conn) context.Context {
+	if sc, ok := c.(*strictConn); ok {
+		if tc, ok := sc.Conn.(*tls.Conn); ok {
+			return context.WithValue(ctx, strictTLSKey{}, tc)
+		}
+	}
+	return ctx
+}
+
+func strictTLSHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.TLS == nil {
+			if tc, ok := req.Context().Value(strictTLSKey{}).(*tls.Conn); ok {
+				state := tc.ConnectionState()
+				req.TLS = &state
+			}
+		}
+		h.ServeHTTP(rw, req)
+	})
+}
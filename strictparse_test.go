@@ -0,0 +1,66 @@
+package lessgo
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrictParsing(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		io.WriteString(rw, req.URL.Path+":"+string(body))
+	}))
+	srv.Listener = newStrictListener(srv.Listener)
+	srv.Start()
+	defer srv.Close()
+
+	many := strings.Repeat("X-A: 1\r\n", strictMaxHeaderCount+1)
+	for _, test := range []struct {
+		raw  string
+		want []string // 依次响应的状态行与响应体，400只有状态码
+	}{
+		{"GET /a HTTP/1.1\r\nHost: x\r\n\r\n", []string{"200 /a:"}},
+		{"POST /a HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\n\r\nabcGET /b HTTP/1.1\r\nHost: x\r\n\r\n", []string{"200 /a:abc", "200 /b:"}},
+		{"POST /a HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n3;x=1\r\nabc\r\n0\r\nT: 1\r\n\r\nGET /b HTTP/1.1\r\nHost: x\r\n\r\n", []string{"200 /a:abc", "200 /b:"}},
+		{"POST /a HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", []string{"400"}},
+		{"POST /a HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\nPOST /b HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\nContent-Length: 5\r\n\r\n0\r\n\r\n", []string{"200 /a:", "400"}},
+		{"GET /a HTTP/1.1\r\nHost: x\r\nX-A: one\r\n two\r\n\r\n", []string{"400"}},
+		{"GET /a HTTP/1.1\r\nHost: x\r\nX-A: o\x00ne\r\n\r\n", []string{"400"}},
+		{"GET /a HTTP/1.1\r\nHost: x\r\n" + many + "\r\n", []string{"400"}},
+		{"POST /a HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\nContent-Length: 4\r\n\r\nabcd", []string{"400"}},
+		// 请求体中的内容不视为请求头
+		{"POST /a HTTP/1.1\r\nHost: x\r\nContent-Length: 7\r\n\r\n \x00a\r\n\r\n", []string{"200 /a: \x00a\r\n\r\n"}},
+	} {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, test.raw)
+		r := bufio.NewReader(conn)
+		for _, want := range test.want {
+			resp, err := http.ReadResponse(r, nil)
+			if err != nil {
+				t.Errorf("%q: %v", test.raw, err)
+				break
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			got := strings.TrimSuffix(resp.Status[:3]+" "+string(body), " ")
+			if resp.StatusCode == http.StatusBadRequest {
+				got = resp.Status[:3]
+			}
+			if got != want {
+				t.Errorf("%q: got %q, want %q", test.raw, got, want)
+			}
+		}
+		conn.Close()
+	}
+}
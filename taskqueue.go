@@ -0,0 +1,470 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+type (
+	// 后台任务
+	Task struct {
+		ID        string          `json:"id"`
+		Name      string          `json:"name"`
+		Payload   json.RawMessage `json:"payload"`
+		Attempts  int             `json:"attempts"`
+		LastError string          `json:"last_error,omitempty"`
+		RunAt     time.Time       `json:"run_at"` // 最早执行时间，用于重试退避
+		CreatedAt time.Time       `json:"created_at"`
+	}
+
+	// 任务处理函数，返回错误时按退避重试，超出最大尝试次数后转入死信
+	TaskFunc func(t *Task) error
+
+	// 任务队列接口，默认为进程内队列，多实例间共享见NewRedisTaskQueue
+	TaskQueue interface {
+		// 加入任务，RunAt未到时延迟至RunAt
+		Push(t *Task) error
+		// 取出一个到期的任务，timeout内没有任务时返回nil，timeout为0时不等待
+		Pop(timeout time.Duration) (*Task, error)
+		// 待执行(含延迟)的任务数
+		Len() (int, error)
+		// 加入死信
+		Dead(t *Task) error
+		// 返回所有死信
+		DeadTasks() ([]*Task, error)
+		// 移出一个死信，不存在时返回nil
+		RemoveDead(id string) (*Task, error)
+	}
+
+	// 任务队列选项
+	TaskOptions struct {
+		Workers     int           // 并发执行数
+		MaxAttempts int           // 最大尝试次数，超出后转入死信
+		BaseDelay   time.Duration // 首次重试间隔，之后按指数增长
+		MaxDelay    time.Duration // 重试间隔上限
+	}
+
+	// 任务队列的执行统计
+	TaskStats struct {
+		Pending   int    `json:"pending"`
+		InFlight  int64  `json:"in_flight"`
+		Enqueued  uint64 `json:"enqueued"`
+		Succeeded uint64 `json:"succeeded"`
+		Retried   uint64 `json:"retried"`
+		Dead      uint64 `json:"dead"`
+	}
+
+	// 任务管理器，服务退出时停止取新任务，并在等待期内继续执行队列中剩余的任务
+	TaskManager struct {
+		// 计数器，置于首部以保证原子操作的64位对齐
+		enqueued, succeeded, retried, dead uint64
+		inFlight                           int64
+
+		opts       TaskOptions
+		queue      TaskQueue
+		handlers   map[string]TaskFunc
+		stop       chan struct{}
+		drainUntil time.Time
+		wg         sync.WaitGroup
+		started    bool
+		stopped    bool
+		lock       sync.RWMutex
+	}
+
+	// 进程内任务队列
+	memoryTaskQueue struct {
+		ready   []*Task
+		delayed []*Task
+		dead    []*Task
+		notify  chan struct{}
+		lock    sync.Mutex
+	}
+)
+
+var ErrTaskNotRegistered = errors.New("task is not registered")
+
+// 全局任务管理器
+var Tasks = NewTaskManager(TaskOptions{})
+
+// 创建任务管理器，未设置的选项取默认值
+func NewTaskManager(opts TaskOptions) *TaskManager {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = time.Hour
+	}
+	return &TaskManager{
+		opts:     opts,
+		queue:    NewMemoryTaskQueue(),
+		handlers: map[string]TaskFunc{},
+		stop:     make(chan struct{}),
+	}
+}
+
+// 设置任务队列，须在注册处理函数之前调用
+func (m *TaskManager) SetQueue(q TaskQueue) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.started {
+		return errors.New("task manager is started, set the queue before Handle")
+	}
+	m.queue = q
+	return nil
+}
+
+// 注册任务处理函数，并启动执行协程
+func (m *TaskManager) Handle(name string, fn TaskFunc) {
+	m.lock.Lock()
+	m.handlers[name] = fn
+	m.start()
+	m.lock.Unlock()
+}
+
+// 加入任务，payload以JSON编码；任务名须已通过Handle注册
+func (m *TaskManager) Enqueue(name string, payload interface{}) error {
+	return m.EnqueueAfter(name, payload, 0)
+}
+
+// 加入延迟delay后执行的任务，payload以JSON编码
+func (m *TaskManager) EnqueueAfter(name string, payload interface{}, delay time.Duration) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	m.lock.RLock()
+	_, ok := m.handlers[name]
+	stopped, q := m.stopped, m.queue
+	m.lock.RUnlock()
+	if !ok {
+		return fmt.Errorf("%v: %s", ErrTaskNotRegistered, name)
+	}
+	if stopped {
+		return errors.New("task manager is stopped")
+	}
+	now := time.Now()
+	t := &Task{
+		ID:        string(utils.RandomCreateBytes(16)),
+		Name:      name,
+		Payload:   body,
+		RunAt:     now.Add(delay),
+		CreatedAt: now,
+	}
+	if err = q.Push(t); err != nil {
+		return err
+	}
+	atomic.AddUint64(&m.enqueued, 1)
+	return nil
+}
+
+// 返回执行统计
+func (m *TaskManager) Stats() TaskStats {
+	m.lock.RLock()
+	q := m.queue
+	m.lock.RUnlock()
+	s := TaskStats{
+		InFlight:  atomic.LoadInt64(&m.inFlight),
+		Enqueued:  atomic.LoadUint64(&m.enqueued),
+		Succeeded: atomic.LoadUint64(&m.succeeded),
+		Retried:   atomic.LoadUint64(&m.retried),
+		Dead:      atomic.LoadUint64(&m.dead),
+	}
+	s.Pending, _ = q.Len()
+	return s
+}
+
+// 返回所有死信
+func (m *TaskManager) DeadTasks() ([]*Task, error) {
+	m.lock.RLock()
+	q := m.queue
+	m.lock.RUnlock()
+	return q.DeadTasks()
+}
+
+// 重新执行一个死信
+func (m *TaskManager) Retry(id string) error {
+	m.lock.RLock()
+	q := m.queue
+	m.lock.RUnlock()
+	t, err := q.RemoveDead(id)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return fmt.Errorf("dead task %q not found", id)
+	}
+	t.Attempts = 0
+	t.RunAt = time.Now()
+	return q.Push(t)
+}
+
+// 停止取新任务，在timeout内继续执行已到期的任务并等待进行中的任务完成，
+// 返回仍在执行的任务数
+func (m *TaskManager) Stop(timeout time.Duration) int {
+	m.lock.Lock()
+	if m.stopped {
+		m.lock.Unlock()
+		return 0
+	}
+	m.stopped = true
+	m.drainUntil = time.Now().Add(timeout)
+	close(m.stop)
+	m.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	return int(atomic.LoadInt64(&m.inFlight))
+}
+
+// 启动执行协程，调用者需持有写锁
+func (m *TaskManager) start() {
+	if m.started || m.stopped {
+		return
+	}
+	m.started = true
+	for i := 0; i < m.opts.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	app.onShutdown(func() {
+		if n := m.Stop(30 * time.Second); n > 0 {
+			Log.Warn("Tasks: %d tasks were not completed before shutdown", n)
+		}
+		if n, _ := m.queue.Len(); n > 0 {
+			Log.Warn("Tasks: %d tasks are left in the queue", n)
+		}
+	})
+}
+
+func (m *TaskManager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.stop:
+			m.drain()
+			return
+		default:
+		}
+		t, err := m.queue.Pop(time.Second)
+		if err != nil {
+			Log.Error("Tasks: pop failed: %v", err)
+			select {
+			case <-m.stop:
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if t != nil {
+			m.process(t)
+		}
+	}
+}
+
+// 停止后在截止时间内执行队列中剩余的到期任务
+func (m *TaskManager) drain() {
+	for {
+		m.lock.RLock()
+		expired := time.Now().After(m.drainUntil)
+		m.lock.RUnlock()
+		if expired {
+			return
+		}
+		t, err := m.queue.Pop(0)
+		if err != nil || t == nil {
+			return
+		}
+		m.process(t)
+	}
+}
+
+func (m *TaskManager) process(t *Task) {
+	m.lock.RLock()
+	fn := m.handlers[t.Name]
+	m.lock.RUnlock()
+
+	atomic.AddInt64(&m.inFlight, 1)
+	err := ErrTaskNotRegistered
+	if fn != nil {
+		err = callTask(fn, t)
+	}
+	atomic.AddInt64(&m.inFlight, -1)
+	if err == nil {
+		atomic.AddUint64(&m.succeeded, 1)
+		return
+	}
+
+	t.Attempts++
+	t.LastError = err.Error()
+	if t.Attempts >= m.opts.MaxAttempts || fn == nil {
+		atomic.AddUint64(&m.dead, 1)
+		Log.Warn("Tasks: task %s(%s) is dead: %v", t.Name, t.ID, err)
+		if err = m.queue.Dead(t); err != nil {
+			Log.Error("Tasks: save dead task %s(%s) failed: %v", t.Name, t.ID, err)
+		}
+		return
+	}
+	delay := m.opts.BaseDelay << uint(t.Attempts-1)
+	if delay > m.opts.MaxDelay || delay <= 0 {
+		delay = m.opts.MaxDelay
+	}
+	t.RunAt = time.Now().Add(delay)
+	atomic.AddUint64(&m.retried, 1)
+	if err = m.queue.Push(t); err != nil {
+		Log.Error("Tasks: requeue task %s(%s) failed: %v", t.Name, t.ID, err)
+	}
+}
+
+// 执行任务并恢复恐慌
+func callTask(fn TaskFunc, t *Task) (err error) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			buf := make([]byte, 4096)
+			buf = buf[:runtime.Stack(buf, false)]
+			Log.Error("Tasks: task %s(%s) panic: %v\n%s", t.Name, t.ID, rcv, buf)
+			err = fmt.Errorf("panic: %v", rcv)
+		}
+	}()
+	return fn(t)
+}
+
+// 将任务参数解码至v
+func (t *Task) Bind(v interface{}) error {
+	return json.Unmarshal(t.Payload, v)
+}
+
+// 向全局任务管理器加入任务，如c.Enqueue("email.send", mail)
+func (c *Context) Enqueue(name string, payload interface{}) error {
+	return Tasks.Enqueue(name, payload)
+}
+
+// 创建进程内任务队列，服务退出时未执行的任务将丢失
+func NewMemoryTaskQueue() TaskQueue {
+	return &memoryTaskQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *memoryTaskQueue) Push(t *Task) error {
+	q.lock.Lock()
+	if t.RunAt.After(time.Now()) {
+		q.delayed = append(q.delayed, t)
+	} else {
+		q.ready = append(q.ready, t)
+	}
+	q.lock.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (q *memoryTaskQueue) Pop(timeout time.Duration) (*Task, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		q.lock.Lock()
+		now := time.Now()
+		wait := deadline.Sub(now)
+		delayed := q.delayed[:0]
+		for _, t := range q.delayed {
+			if d := t.RunAt.Sub(now); d > 0 {
+				delayed = append(delayed, t)
+				if d < wait {
+					wait = d
+				}
+			} else {
+				q.ready = append(q.ready, t)
+			}
+		}
+		q.delayed = delayed
+		if len(q.ready) > 0 {
+			t := q.ready[0]
+			q.ready[0] = nil
+			q.ready = q.ready[1:]
+			q.lock.Unlock()
+			return t, nil
+		}
+		q.lock.Unlock()
+		if wait <= 0 {
+			return nil, nil
+		}
+		select {
+		case <-q.notify:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (q *memoryTaskQueue) Len() (int, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.ready) + len(q.delayed), nil
+}
+
+func (q *memoryTaskQueue) Dead(t *Task) error {
+	q.lock.Lock()
+	q.dead = append(q.dead, t)
+	q.lock.Unlock()
+	return nil
+}
+
+func (q *memoryTaskQueue) DeadTasks() ([]*Task, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return append([]*Task(nil), q.dead...), nil
+}
+
+func (q *memoryTaskQueue) RemoveDead(id string) (*Task, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for i, t := range q.dead {
+		if t.ID == id {
+			q.dead = append(q.dead[:i], q.dead[i+1:]...)
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// 查询任务队列状态与死信或重新执行死信的操作，供后台管理路由使用
+var TaskQueueHandler = ApiHandler{
+	Desc:   "查询任务队列状态与死信或重新执行死信",
+	Method: "GET|POST",
+	Params: []Param{
+		{"id", "formData", false, "", "POST时需要重新执行的死信id"},
+	},
+	Handler: func(c *Context) error {
+		if c.request.Method == POST {
+			if err := Tasks.Retry(c.FormParam("id")); err != nil {
+				return NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+		dead, err := Tasks.DeadTasks()
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"stats": Tasks.Stats(),
+			"dead":  dead,
+		})
+	},
+}.Reg()
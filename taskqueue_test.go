@@ -0,0 +1,66 @@
+package lessgo
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskManager(t *testing.T) {
+	m := NewTaskManager(TaskOptions{Workers: 2, MaxAttempts: 2, BaseDelay: 10 * time.Millisecond})
+	var sent, flaky int32
+	m.Handle("email.send", func(task *Task) error {
+		var mail struct{ To string }
+		if err := task.Bind(&mail); err != nil || mail.To != "a@b.c" {
+			t.Errorf("Bind() = %+v, %v", mail, err)
+		}
+		atomic.AddInt32(&sent, 1)
+		return nil
+	})
+	m.Handle("flaky", func(task *Task) error {
+		if atomic.AddInt32(&flaky, 1) == 1 {
+			return errors.New("try again")
+		}
+		return nil
+	})
+	m.Handle("broken", func(task *Task) error {
+		panic("boom")
+	})
+	if err := m.Enqueue("unknown", nil); err == nil {
+		t.Error("Enqueue() of an unregistered task should fail")
+	}
+	m.Enqueue("email.send", map[string]string{"To": "a@b.c"})
+	m.Enqueue("flaky", nil)
+	m.Enqueue("broken", nil)
+	time.Sleep(100 * time.Millisecond)
+
+	s := m.Stats()
+	if atomic.LoadInt32(&sent) != 1 || atomic.LoadInt32(&flaky) != 2 || s.Succeeded != 2 || s.Retried != 2 || s.Dead != 1 || s.Pending != 0 {
+		t.Errorf("sent = %d, flaky = %d, Stats() = %+v", sent, flaky, s)
+	}
+	dead, _ := m.DeadTasks()
+	if len(dead) != 1 || dead[0].Name != "broken" || dead[0].Attempts != 2 || dead[0].LastError != "panic: boom" {
+		t.Fatalf("DeadTasks() = %+v", dead)
+	}
+	if err := m.Retry(dead[0].ID); err != nil {
+		t.Error(err)
+	}
+	if err := m.Retry(dead[0].ID); err == nil {
+		t.Error("Retry() of a requeued task should fail")
+	}
+
+	// 退出时执行队列中剩余的任务
+	for i := 0; i < 10; i++ {
+		m.Enqueue("email.send", map[string]string{"To": "a@b.c"})
+	}
+	if n := m.Stop(time.Second); n != 0 {
+		t.Errorf("Stop() = %d", n)
+	}
+	if sent != 11 {
+		t.Errorf("sent = %d after Stop, want 11", sent)
+	}
+	if err := m.Enqueue("email.send", nil); err == nil {
+		t.Error("Enqueue() should fail once stopped")
+	}
+}
@@ -0,0 +1,212 @@
+package lessgo
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 模板编译或执行错误，Renderer返回该错误时调试模式的错误页可显示出错位置附近的源码
+type TemplateError struct {
+	Name    string // 模板名
+	Line    int    // 出错的行，从1开始，未知时为0
+	Column  int
+	Message string
+	Source  []byte // 模板源码
+}
+
+func (e *TemplateError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("template %s:%d:%d: %s", e.Name, e.Line, e.Column, e.Message)
+	}
+	return "template " + e.Name + ": " + e.Message
+}
+
+var (
+	templateDirs      = []string{BIZ_VIEW_DIR, SYS_VIEW_DIR, TPL_DIR}
+	templateDirsAbs   []string
+	templateDirsState string
+	templateWatchLock sync.Mutex
+	templateWatching  int32 // 调试模式下正在监视模板目录
+	templateWatcher   int32 // 监视协程已启动
+
+	// 检查模板目录变化的间隔
+	templateWatchInterval = time.Second
+)
+
+// 追加调试模式下监视的模板目录，默认监视bizview、sysview与static/tpl；
+// 目录下的文件变化后清空各模板引擎的编译缓存，下次渲染时重新编译
+func WatchTemplateDirs(dirs ...string) {
+	templateWatchLock.Lock()
+	templateDirs = append(templateDirs, dirs...)
+	templateDirsAbs = nil
+	templateDirsState = ""
+	atomic.StoreInt32(&templateWatching, 0)
+	templateWatchLock.Unlock()
+}
+
+// 启动模板目录监视，仅在调试模式下检查，可随配置热加载切换
+func startTemplateWatcher() {
+	if !atomic.CompareAndSwapInt32(&templateWatcher, 0, 1) {
+		return
+	}
+	stop := make(chan struct{})
+	app.onShutdown(func() { close(stop) })
+	go func() {
+		ticker := time.NewTicker(templateWatchInterval)
+		defer ticker.Stop()
+		for {
+			if app.Debug() {
+				checkTemplateDirs()
+			} else {
+				atomic.StoreInt32(&templateWatching, 0)
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// 检查模板目录，有变化时清空模板编译缓存，返回是否有变化；
+// 开始监视时以当前状态为基准，并丢弃未监视期间的缓存
+func checkTemplateDirs() bool {
+	templateWatchLock.Lock()
+	defer templateWatchLock.Unlock()
+	if templateDirsAbs == nil {
+		for _, dir := range templateDirs {
+			if abs, err := filepath.Abs(dir); err == nil {
+				templateDirsAbs = append(templateDirsAbs, abs)
+			}
+		}
+	}
+	state := templateFilesState(templateDirsAbs)
+	if atomic.LoadInt32(&templateWatching) == 0 {
+		templateDirsState = state
+		clearTemplateCaches()
+		atomic.StoreInt32(&templateWatching, 1)
+		return false
+	}
+	if state == templateDirsState {
+		return false
+	}
+	templateDirsState = state
+	clearTemplateCaches()
+	atomic.AddInt32(&liveReloadVersion, 1)
+	Log.Sys("Templates changed, recompiling.")
+	return true
+}
+
+// 模板目录下全部文件的状态摘要
+func templateFilesState(dirs []string) string {
+	var buf bytes.Buffer
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(fname string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				fmt.Fprintf(&buf, "%s:%d:%d;", fname, info.Size(), info.ModTime().UnixNano())
+			}
+			return nil
+		})
+	}
+	return buf.String()
+}
+
+// 模板文件是否位于正在监视的模板目录中，是则可缓存其编译结果直至目录变化
+func watchingTemplate(fname string) bool {
+	if atomic.LoadInt32(&templateWatching) == 0 {
+		return false
+	}
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return false
+	}
+	templateWatchLock.Lock()
+	defer templateWatchLock.Unlock()
+	for _, dir := range templateDirsAbs {
+		if strings.HasPrefix(abs, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 清空默认及已注册模板引擎的编译缓存
+func clearTemplateCaches() {
+	type cacheClearer interface {
+		ClearCache()
+	}
+	if r, ok := app.renderer.(cacheClearer); ok {
+		r.ClearCache()
+	}
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	for _, r := range renderers {
+		if r, ok := r.(cacheClearer); ok {
+			r.ClearCache()
+		}
+	}
+}
+
+// 调试模式下代替空白的500响应，显示模板名、出错位置与附近源码
+func (c *Context) templateErrorPage(name string, err error) error {
+	te, ok := err.(*TemplateError)
+	if !ok {
+		te = &TemplateError{Name: name, Message: err.Error()}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(templateErrorHead)
+	fmt.Fprintf(&buf, "<h1>Template error</h1>\n<p class=\"name\">%s", html.EscapeString(te.Name))
+	if te.Line > 0 {
+		fmt.Fprintf(&buf, ":%d:%d", te.Line, te.Column)
+	}
+	fmt.Fprintf(&buf, "</p>\n<pre class=\"msg\">%s</pre>\n", html.EscapeString(te.Message))
+	if te.Line > 0 && len(te.Source) > 0 {
+		lines := strings.Split(string(te.Source), "\n")
+		from, to := te.Line-5, te.Line+5
+		if from < 1 {
+			from = 1
+		}
+		if to > len(lines) {
+			to = len(lines)
+		}
+		buf.WriteString("<pre class=\"src\">")
+		for i := from; i <= to; i++ {
+			class := ""
+			if i == te.Line {
+				class = ` class="err"`
+			}
+			fmt.Fprintf(&buf, "<span%s>%4d  %s</span>\n", class, i, html.EscapeString(strings.TrimRight(lines[i-1], "\r")))
+		}
+		buf.WriteString("</pre>\n")
+	}
+	buf.WriteString("</body>\n</html>\n")
+	return c.HTML(http.StatusInternalServerError, buf.String())
+}
+
+const templateErrorHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Template error</title>
+<style>
+body{font-family:-apple-system,Helvetica,Arial,sans-serif;margin:0;padding:24px 32px;color:#222;background:#fff5f5}
+h1{margin:0 0 8px;font-size:22px;color:#c62828}
+.name{margin:0 0 16px;font-family:Menlo,Consolas,monospace;color:#555}
+pre{margin:0 0 16px;padding:12px 16px;border-radius:4px;overflow:auto;font:13px/1.5 Menlo,Consolas,monospace}
+.msg{background:#ffebee;white-space:pre-wrap}
+.src{background:#263238;color:#cfd8dc}
+.src span{display:block}
+.src .err{background:#b71c1c;color:#fff}
+</style>
+</head>
+<body>
+`
@@ -0,0 +1,101 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTemplateErrorPage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tplerr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "broken.tpl")
+	if err = ioutil.WriteFile(filename, []byte("<p>one</p>\n<p>two</p>\n{% if %}<b>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	RegisterRenderer("test-pongo2", NewPongo2Render(false))
+	render := func() (int, string, error) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		c, rec := testContext(req)
+		defer c.free()
+		c.renderer = "test-pongo2"
+		err := c.Render(http.StatusOK, filename, nil)
+		return rec.Code, rec.Body.String(), err
+	}
+
+	debug := atomic.LoadInt32(&app.debug)
+	defer atomic.StoreInt32(&app.debug, debug)
+	atomic.StoreInt32(&app.debug, 0)
+	_, _, err = render()
+	te, ok := err.(*TemplateError)
+	if !ok || te.Name != filename || te.Line != 3 || !strings.Contains(string(te.Source), "<p>two</p>") {
+		t.Fatalf("err = %#v", err)
+	}
+
+	atomic.StoreInt32(&app.debug, 1)
+	code, body, err := render()
+	if err != nil || code != http.StatusInternalServerError {
+		t.Fatalf("debug render = %v, %d", err, code)
+	}
+	for _, want := range []string{"Template error", filename + ":3:", `<span class="err">   3  {% if %}&lt;b&gt;</span>`, "&lt;p&gt;two&lt;/p&gt;"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("error page lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestTemplateWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tplwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "page.tpl")
+	if err = ioutil.WriteFile(filename, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewPongo2Render(false)
+	RegisterRenderer("test-watch", r)
+	WatchTemplateDirs(dir)
+	if checkTemplateDirs() || !watchingTemplate(filename) {
+		t.Fatal("not watching")
+	}
+	if watchingTemplate(dir + "-other/page.tpl") {
+		t.Error("watching outside the template dirs")
+	}
+	if _, err = r.fromFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.tplCache) != 1 {
+		t.Fatalf("cached %d templates", len(r.tplCache))
+	}
+	if checkTemplateDirs() {
+		t.Error("changed without modification")
+	}
+
+	if err = ioutil.WriteFile(filename, []byte("v2 changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(filename, time.Now(), time.Now().Add(time.Second))
+	if !checkTemplateDirs() {
+		t.Fatal("change not detected")
+	}
+	if len(r.tplCache) != 0 {
+		t.Error("cache not cleared")
+	}
+	tpl, err := r.fromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := tpl.Execute(nil); out != "v2 changed" {
+		t.Errorf("rendered %q after change", out)
+	}
+}
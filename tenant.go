@@ -0,0 +1,160 @@
+package lessgo
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	// TenantConfig defines the config for multi-tenant resolution middleware.
+	TenantConfig struct {
+		// 依次尝试的租户标识来源："subdomain"、"header"、"path"；
+		// 请求头可由客户端任意设置，须先通过SetTenantResolver设置校验租户的函数，否则忽略"header"来源
+		Sources []string
+
+		// 携带租户标识的请求头
+		Header string
+
+		// 子域名方式的根域名，如"example.com"时从"acme.example.com"中取得"acme"
+		BaseDomain string
+
+		// 路径方式时是否去掉路径中的租户前缀，如"/acme/orders"转为"/orders"再进行路由
+		StripPrefix bool
+
+		// 是否要求必须解析出租户
+		Required bool
+	}
+
+	// 租户
+	Tenant struct {
+		ID   string            `json:"id"`
+		Name string            `json:"name"`
+		Meta map[string]string `json:"meta"`
+	}
+)
+
+// Context中存放Tenant的键名
+const tenantKey = "__tenant__"
+
+const HeaderXTenantID = "X-Tenant-ID"
+
+var (
+	// 根据租户标识查询租户，返回nil表示租户不存在；为nil时直接以标识创建租户
+	tenantResolver     func(c *Context, id string) (*Tenant, error)
+	tenantResolverLock sync.RWMutex
+	tenantHeaderWarn   sync.Once
+)
+
+var ResolveTenant = ApiMiddleware{
+	Name: "多租户",
+	Desc: "从请求头、子域名或路径前缀中解析租户，存入Context供数据库选择、限流与日志等使用",
+	Config: TenantConfig{
+		Sources:     []string{"subdomain", "header"},
+		Header:      HeaderXTenantID,
+		BaseDomain:  "",
+		StripPrefix: true,
+		Required:    false,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(TenantConfig)
+		baseDomain := "." + strings.TrimPrefix(strings.ToLower(config.BaseDomain), ".")
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				req := c.request
+				tenantResolverLock.RLock()
+				fn := tenantResolver
+				tenantResolverLock.RUnlock()
+
+				var id, source string
+				for _, source = range config.Sources {
+					switch source {
+					case "header":
+						if fn == nil {
+							tenantHeaderWarn.Do(func() {
+								Log.Warn("ResolveTenant: the \"header\" source is ignored until a tenant resolver is set")
+							})
+							continue
+						}
+						id = req.Header.Get(config.Header)
+					case "subdomain":
+						if config.BaseDomain == "" {
+							continue
+						}
+						host := req.Host
+						if h, _, err := net.SplitHostPort(host); err == nil {
+							host = h
+						}
+						host = strings.ToLower(host)
+						if strings.HasSuffix(host, baseDomain) {
+							id = strings.TrimSuffix(host, baseDomain)
+							// 只取最靠近根域名的一级
+							id = id[strings.LastIndex(id, ".")+1:]
+							if id == "www" {
+								id = ""
+							}
+						}
+					case "path":
+						p := strings.TrimPrefix(req.URL.Path, "/")
+						if i := strings.IndexByte(p, '/'); i >= 0 {
+							id = p[:i]
+						} else {
+							id = p
+						}
+					}
+					if id != "" {
+						break
+					}
+				}
+				if id == "" {
+					if config.Required {
+						return NewHTTPError(http.StatusBadRequest, "tenant is required")
+					}
+					return next(c)
+				}
+
+				tenant := &Tenant{ID: id}
+				if fn != nil {
+					var err error
+					if tenant, err = fn(c, id); err != nil {
+						return err
+					}
+				}
+				if tenant == nil {
+					return NewHTTPError(http.StatusNotFound, "unknown tenant")
+				}
+				if source == "path" && config.StripPrefix {
+					req.URL.Path = strings.TrimPrefix(req.URL.Path, "/"+id)
+					if req.URL.Path == "" {
+						req.URL.Path = "/"
+					}
+				}
+				c.Set(tenantKey, tenant)
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 设置根据租户标识查询并校验租户的函数(未设置时直接以标识创建租户，且不信任请求头中的租户标识)
+func SetTenantResolver(fn func(c *Context, id string) (*Tenant, error)) {
+	tenantResolverLock.Lock()
+	tenantResolver = fn
+	tenantResolverLock.Unlock()
+}
+
+// 获取当前请求的租户，未解析出租户时返回nil
+func (c *Context) Tenant() *Tenant {
+	t, _ := c.Get(tenantKey).(*Tenant)
+	return t
+}
+
+// 当前请求的租户标识，无租户时返回空
+func (c *Context) TenantID() string {
+	if t := c.Tenant(); t != nil {
+		return t.ID
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package lessgo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestResolveTenant(t *testing.T) {
+	do := func(config TenantConfig, url string, header string) (id, path string, err error) {
+		h := ResolveTenant.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+			id, path = c.TenantID(), c.Request().URL.Path
+			return nil
+		})
+		req, _ := http.NewRequest(GET, url, nil)
+		if header != "" {
+			req.Header.Set(HeaderXTenantID, header)
+		}
+		c, _ := testContext(req)
+		defer c.free()
+		err = h(c)
+		return
+	}
+	code := func(err error) int {
+		if he, ok := err.(*HTTPError); ok {
+			return he.Code
+		}
+		return 0
+	}
+	sub := TenantConfig{Sources: []string{"subdomain", "header"}, Header: HeaderXTenantID, BaseDomain: "example.com"}
+	path := TenantConfig{Sources: []string{"path"}, StripPrefix: true, Required: true}
+
+	for _, test := range []struct {
+		config         TenantConfig
+		url, header    string
+		wantID, wantTo string
+	}{
+		{sub, "http://acme.example.com:8080/orders", "", "acme", "/orders"},
+		{sub, "http://eu.acme.example.com/orders", "", "acme", "/orders"},
+		{sub, "http://www.example.com/orders", "", "", "/orders"},
+		// 未设置校验函数时不信任请求头
+		{sub, "http://example.com/orders", "evil", "", "/orders"},
+		{path, "http://example.com/acme/orders", "", "acme", "/orders"},
+		{path, "http://example.com/acme", "", "acme", "/"},
+	} {
+		id, to, err := do(test.config, test.url, test.header)
+		if err != nil || id != test.wantID || to != test.wantTo {
+			t.Errorf("%s (header %q) = %q %q, %v; want %q %q", test.url, test.header, id, to, err, test.wantID, test.wantTo)
+		}
+	}
+	if _, _, err := do(path, "http://example.com/", ""); code(err) != http.StatusBadRequest {
+		t.Errorf("missing required tenant = %v", err)
+	}
+
+	SetTenantResolver(func(c *Context, id string) (*Tenant, error) {
+		switch id {
+		case "acme", "beta":
+			return &Tenant{ID: id, Name: "Tenant " + id}, nil
+		case "broken":
+			return nil, errors.New("tenant store unavailable")
+		}
+		return nil, nil
+	})
+	defer SetTenantResolver(nil)
+	if id, _, err := do(sub, "http://example.com/orders", "beta"); err != nil || id != "beta" {
+		t.Errorf("header tenant = %q, %v", id, err)
+	}
+	// 子域名优先于请求头
+	if id, _, err := do(sub, "http://acme.example.com/orders", "beta"); err != nil || id != "acme" {
+		t.Errorf("subdomain tenant = %q, %v", id, err)
+	}
+	if _, _, err := do(sub, "http://nobody.example.com/orders", ""); code(err) != http.StatusNotFound {
+		t.Errorf("unknown tenant = %v", err)
+	}
+	if _, _, err := do(path, "http://example.com/broken/x", ""); err == nil || err.Error() != "tenant store unavailable" {
+		t.Errorf("resolver error = %v", err)
+	}
+}
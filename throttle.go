@@ -0,0 +1,197 @@
+package lessgo
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// ThrottleConfig defines the config for bandwidth throttling middleware.
+	ThrottleConfig struct {
+		// 每个连接的最大下行速率，单位字节/秒，为0时不限制
+		ConnBytesPerSec int64
+
+		// 每个路由(所有连接合计)的最大下行速率，单位字节/秒，为0时不限制；
+		// 按注册的路由区分，全局挂载时无法确定路由，所有请求共享同一限额
+		RouteBytesPerSec int64
+
+		// 允许突发的字节数，为0时取一秒的速率
+		BurstBytes int64
+	}
+
+	// 令牌桶
+	tokenBucket struct {
+		rate   float64 // 每秒产生的令牌数
+		burst  float64
+		tokens float64
+		last   time.Time
+		refs   int
+		sync.Mutex
+	}
+
+	// 按令牌桶限速的ResponseWriter
+	throttleWriter struct {
+		responseWriterWrapper
+		buckets []*tokenBucket
+		chunk   int
+	}
+)
+
+var (
+	// 按路由共享的令牌桶
+	routeBuckets = map[string]*tokenBucket{}
+	// 运行时设置的路由限速，优先于配置
+	routeRateOverrides = map[string]int64{}
+	// 按连接共享的令牌桶，连接上无请求时删除
+	connBuckets   = map[string]*tokenBucket{}
+	throttleMutex sync.Mutex
+)
+
+var Throttle = ApiMiddleware{
+	Name: "带宽限制",
+	Desc: "按连接与路由对响应进行令牌桶限速(支持突发与运行时调整)，防止大文件下载占满小实例的上行带宽",
+	Config: ThrottleConfig{
+		ConnBytesPerSec:  1 * MB,
+		RouteBytesPerSec: 0,
+		BurstBytes:       0,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(ThrottleConfig)
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				var buckets []*tokenBucket
+				// 只按注册的路由区分，避免以请求路径为键导致令牌桶无限增长
+				route := c.path
+				conn := c.request.RemoteAddr
+
+				throttleMutex.Lock()
+				routeRate := config.RouteBytesPerSec
+				if rate, ok := routeRateOverrides[route]; ok {
+					routeRate = rate
+				}
+				if routeRate > 0 {
+					b := routeBuckets[route]
+					if b == nil {
+						b = &tokenBucket{}
+						routeBuckets[route] = b
+					}
+					b.setRate(routeRate, config.BurstBytes)
+					buckets = append(buckets, b)
+				}
+				if config.ConnBytesPerSec > 0 {
+					b := connBuckets[conn]
+					if b == nil {
+						b = &tokenBucket{}
+						connBuckets[conn] = b
+					}
+					b.refs++
+					b.setRate(config.ConnBytesPerSec, config.BurstBytes)
+					buckets = append(buckets, b)
+				}
+				throttleMutex.Unlock()
+
+				if len(buckets) == 0 {
+					return next(c)
+				}
+				if config.ConnBytesPerSec > 0 {
+					defer func() {
+						throttleMutex.Lock()
+						if b := connBuckets[conn]; b != nil {
+							if b.refs--; b.refs <= 0 {
+								delete(connBuckets, conn)
+							}
+						}
+						throttleMutex.Unlock()
+					}()
+				}
+
+				// 每次写出的数据块，约为较小速率的1/10秒
+				chunk := 32 << 10
+				for _, b := range buckets {
+					if n := int(b.rate / 10); n > 0 && n < chunk {
+						chunk = n
+					}
+				}
+				w := &throttleWriter{
+					responseWriterWrapper: responseWriterWrapper{c.response.writer},
+					buckets:               buckets,
+					chunk:                 chunk,
+				}
+				c.response.writer = w
+				defer func() { c.response.writer = w.ResponseWriter }()
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 运行时调整路由的限速，bytesPerSec为0表示不限速，小于0表示恢复为配置值
+func SetRouteBandwidth(route string, bytesPerSec int64) {
+	throttleMutex.Lock()
+	if bytesPerSec < 0 {
+		delete(routeRateOverrides, route)
+	} else {
+		routeRateOverrides[route] = bytesPerSec
+	}
+	throttleMutex.Unlock()
+}
+
+func (b *tokenBucket) setRate(rate, burst int64) {
+	if burst <= 0 {
+		burst = rate
+	}
+	b.Lock()
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+		b.last = time.Now()
+	}
+	b.rate, b.burst = float64(rate), float64(burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.Unlock()
+}
+
+// 取出n个令牌，返回需要等待的时长
+func (b *tokenBucket) take(n int) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 || b.rate <= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (w *throttleWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n := len(p)
+		if n > w.chunk {
+			n = w.chunk
+		}
+		var wait time.Duration
+		for _, b := range w.buckets {
+			if d := b.take(n); d > wait {
+				wait = d
+			}
+		}
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		m, err := w.ResponseWriter.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,70 @@
+package lessgo
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := &tokenBucket{}
+	b.setRate(1000, 100)
+	// 突发额度内无需等待，超出部分按速率等待
+	if d := b.take(100); d != 0 {
+		t.Errorf("take within burst waits %v", d)
+	}
+	if d := b.take(100); d < 90*time.Millisecond || d > 110*time.Millisecond {
+		t.Errorf("take over burst waits %v, want ~100ms", d)
+	}
+	b.setRate(1000, 0)
+	if b.burst != 1000 {
+		t.Errorf("default burst = %v", b.burst)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	defer SetRouteBandwidth("/throttle/dl", -1)
+	body := bytes.Repeat([]byte("x"), 3000)
+	do := func(config ThrottleConfig) time.Duration {
+		h := Throttle.Middleware.(Middleware).getMiddlewareFunc(config)(func(c *Context) error {
+			c.WriteHeader(http.StatusOK)
+			_, err := c.Write(body)
+			return err
+		})
+		req, _ := http.NewRequest(GET, "/throttle/dl", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		c, rec := testContext(req)
+		defer c.free()
+		c.path = "/throttle/dl"
+		start := time.Now()
+		if err := h(c); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), body) {
+			t.Errorf("body of %d bytes altered to %d", len(body), rec.Body.Len())
+		}
+		return time.Since(start)
+	}
+
+	// 突发1000字节后按10000字节/秒写出其余2000字节
+	if d := do(ThrottleConfig{ConnBytesPerSec: 10000, BurstBytes: 1000}); d < 180*time.Millisecond {
+		t.Errorf("connection throttled download took %v, want >= 200ms", d)
+	}
+	throttleMutex.Lock()
+	n := len(connBuckets)
+	throttleMutex.Unlock()
+	if n != 0 {
+		t.Errorf("%d connection buckets left after the request", n)
+	}
+
+	// 运行时调整路由限速，恢复后不再限速
+	SetRouteBandwidth("/throttle/dl", 10000)
+	if d := do(ThrottleConfig{BurstBytes: 1000}); d < 180*time.Millisecond {
+		t.Errorf("route throttled download took %v, want >= 200ms", d)
+	}
+	SetRouteBandwidth("/throttle/dl", -1)
+	if d := do(ThrottleConfig{}); d > 100*time.Millisecond {
+		t.Errorf("unthrottled download took %v", d)
+	}
+}
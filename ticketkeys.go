@@ -0,0 +1,171 @@
+package lessgo
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type (
+	// TLS会话票据密钥
+	TicketKeys struct {
+		Keys    [][32]byte // 第一个用于加密新票据，其余仅用于解密此前签发的票据
+		Rotated time.Time  // 最近一次轮换的时间
+	}
+
+	// 会话票据密钥的存储；多个实例共用同一存储(如共享卷上的文件或Redis)时，
+	// 任一实例签发的票据均可在其他实例上恢复会话，无需会话保持
+	TicketKeyStore interface {
+		// 尚无密钥时返回nil
+		Load() (*TicketKeys, error)
+		Save(keys *TicketKeys) error
+	}
+
+	memoryTicketKeyStore struct {
+		keys *TicketKeys
+		sync.Mutex
+	}
+
+	fileTicketKeyStore struct {
+		path string
+	}
+)
+
+// 保留的会话票据密钥数，轮换后此前两个周期内签发的票据仍可使用
+const ticketKeysKeep = 3
+
+var (
+	ticketKeyStore     TicketKeyStore = NewMemoryTicketKeyStore()
+	ticketKeyStoreLock sync.RWMutex
+)
+
+// 设置会话票据密钥的存储(默认为内存存储，仅在本实例内轮换)，须在服务启动前设置
+func SetTicketKeyStore(store TicketKeyStore) {
+	ticketKeyStoreLock.Lock()
+	ticketKeyStore = store
+	ticketKeyStoreLock.Unlock()
+}
+
+func getTicketKeyStore() TicketKeyStore {
+	ticketKeyStoreLock.RLock()
+	defer ticketKeyStoreLock.RUnlock()
+	return ticketKeyStore
+}
+
+// 创建内存中的会话票据密钥存储
+func NewMemoryTicketKeyStore() TicketKeyStore {
+	return &memoryTicketKeyStore{}
+}
+
+func (s *memoryTicketKeyStore) Load() (*TicketKeys, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.keys, nil
+}
+
+func (s *memoryTicketKeyStore) Save(keys *TicketKeys) error {
+	s.Lock()
+	s.keys = keys
+	s.Unlock()
+	return nil
+}
+
+// 创建以JSON文件保存的会话票据密钥存储，文件可位于各实例共享的卷上
+func NewFileTicketKeyStore(path string) TicketKeyStore {
+	return &fileTicketKeyStore{path: path}
+}
+
+func (s *fileTicketKeyStore) Load() (*TicketKeys, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := new(TicketKeys)
+	if err = json.Unmarshal(b, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// 先写入临时文件再改名，其他实例不会读到写了一半的文件
+func (s *fileTicketKeyStore) Save(keys *TicketKeys) error {
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(s.path), ".ticketkeys")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// 从存储读取会话票据密钥，超过轮换间隔时生成新密钥写回存储，然后设置到conf；
+// 多个实例同时轮换时以最后写入的为准，其余实例在下次同步时采用
+func syncTicketKeys(conf *tls.Config, interval time.Duration) error {
+	store := getTicketKeyStore()
+	tk, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if tk == nil || len(tk.Keys) == 0 || time.Since(tk.Rotated) >= interval {
+		var key [32]byte
+		if _, err = rand.Read(key[:]); err != nil {
+			return err
+		}
+		keys := [][32]byte{key}
+		if tk != nil {
+			keys = append(keys, tk.Keys...)
+		}
+		if len(keys) > ticketKeysKeep {
+			keys = keys[:ticketKeysKeep]
+		}
+		tk = &TicketKeys{Keys: keys, Rotated: time.Now()}
+		if err = store.Save(tk); err != nil {
+			return err
+		}
+	}
+	conf.SetSessionTicketKeys(tk.Keys)
+	return nil
+}
+
+// 定期同步会话票据密钥，直至stop关闭
+func rotateTicketKeys(conf *tls.Config, interval time.Duration, stop <-chan struct{}) {
+	check := interval / 4
+	if check > time.Minute {
+		check = time.Minute
+	}
+	if check < time.Second {
+		check = time.Second
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := syncTicketKeys(conf, interval); err != nil {
+				Log.Error("TLS session ticket keys: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package lessgo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTicketKeyRotation(t *testing.T) {
+	defer SetTicketKeyStore(getTicketKeyStore())
+	store := NewFileTicketKeyStore(filepath.Join(t.TempDir(), "ticketkeys.json"))
+	SetTicketKeyStore(store)
+
+	conf := &tls.Config{}
+	if err := syncTicketKeys(conf, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := store.Load()
+	if first == nil || len(first.Keys) != 1 {
+		t.Fatalf("keys = %+v", first)
+	}
+	syncTicketKeys(conf, time.Hour)
+	if tk, _ := store.Load(); len(tk.Keys) != 1 {
+		t.Errorf("rotated before the interval: %d keys", len(tk.Keys))
+	}
+	for i := 0; i < 3; i++ {
+		tk, _ := store.Load()
+		tk.Rotated = tk.Rotated.Add(-time.Hour)
+		store.Save(tk)
+		syncTicketKeys(conf, time.Hour)
+	}
+	tk, _ := store.Load()
+	if len(tk.Keys) != ticketKeysKeep || tk.Keys[ticketKeysKeep-1] == first.Keys[0] || tk.Keys[0] == first.Keys[0] {
+		t.Errorf("keys after rotation = %d", len(tk.Keys))
+	}
+}
+
+// 共用密钥存储的实例可恢复彼此签发的会话
+func TestTicketKeysShared(t *testing.T) {
+	defer SetTicketKeyStore(getTicketKeyStore())
+	ca := newTestCA(t)
+	cert := ca.issue(t, "")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	clientConf := &tls.Config{RootCAs: pool, ServerName: "example.com", ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+
+	newServer := func(store TicketKeyStore) *tls.Config {
+		SetTicketKeyStore(store)
+		conf := &tls.Config{Certificates: []tls.Certificate{cert}}
+		if err := syncTicketKeys(conf, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		return conf
+	}
+	connect := func(conf *tls.Config) bool {
+		return tlsConnect(t, conf, clientConf).DidResume
+	}
+
+	shared := NewMemoryTicketKeyStore()
+	a, b := newServer(shared), newServer(shared)
+	if connect(a) {
+		t.Error("first connection resumed")
+	}
+	if !connect(b) {
+		t.Error("session not resumed on another instance")
+	}
+	if connect(newServer(NewMemoryTicketKeyStore())) {
+		t.Error("session resumed with other keys")
+	}
+}
+
+// 建立TLS连接并读取服务端写入的一个字节(客户端同时处理握手后发送的会话票据)，返回客户端的连接状态
+func tlsConnect(t *testing.T, serverConf, clientConf *tls.Config) tls.ConnectionState {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte{1})
+		conn.Close()
+	}()
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	return conn.ConnectionState()
+}
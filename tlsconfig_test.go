@@ -0,0 +1,74 @@
+package lessgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSConfigHTTP2(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	var certPEM []byte
+	for _, der := range cert.Certificate {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := app.newTLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与非平滑模式的serve()相同，由tls.NewListener提供HTTPS
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})}
+	go server.Serve(tls.NewListener(ln, config))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	for _, c := range []struct {
+		nextProtos []string
+		want       string
+	}{
+		{nil, "HTTP/2.0"},
+		{[]string{"http/1.1"}, "HTTP/1.1"},
+	} {
+		tr := &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, ServerName: "example.com", NextProtos: c.nextProtos},
+			ForceAttemptHTTP2: c.nextProtos == nil,
+		}
+		resp, err := (&http.Client{Transport: tr}).Get("https://" + ln.Addr().String() + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		tr.CloseIdleConnections()
+		if string(body) != c.want {
+			t.Errorf("client protocols %q: served over %s, want %s", c.nextProtos, body, c.want)
+		}
+	}
+}
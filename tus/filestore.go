@@ -0,0 +1,143 @@
+package tus
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore keeps the uploads in a directory: the data of upload <id> in
+// the file <id> and its Info in <id>.info.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore returns a FileStore in dir, created by the first upload.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// Path returns the path of the data of the upload, to move it once
+// completed.
+func (s *FileStore) Path(id string) string {
+	return filepath.Join(s.Dir, id)
+}
+
+// Create implements Store.
+func (s *FileStore) Create(info Info) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return s.save(info)
+}
+
+// Get implements Store.
+func (s *FileStore) Get(id string) (Info, error) {
+	var info Info
+	b, err := ioutil.ReadFile(s.Path(id) + ".info")
+	if os.IsNotExist(err) {
+		return info, ErrNotFound
+	}
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
+
+// Write implements Store.
+func (s *FileStore) Write(id string, offset int64, r io.Reader) (int64, error) {
+	info, err := s.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	// Bytes past offset are left by a write interrupted before its Info
+	// was saved.
+	if err = f.Truncate(offset); err == nil {
+		_, err = f.Seek(offset, os.SEEK_SET)
+	}
+	var n int64
+	if err == nil {
+		n, err = io.Copy(f, r)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if n > 0 {
+		info.Offset = offset + n
+		if serr := s.save(info); err == nil {
+			err = serr
+		}
+	}
+	return n, err
+}
+
+// SetSize implements Store.
+func (s *FileStore) SetSize(id string, size int64) error {
+	info, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	info.Size = size
+	return s.save(info)
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(id string) error {
+	err := os.Remove(s.Path(id) + ".info")
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(s.Path(id)); os.IsNotExist(err) {
+		err = nil
+	}
+	return err
+}
+
+// List implements Store.
+func (s *FileStore) List() ([]Info, error) {
+	names, err := filepath.Glob(filepath.Join(s.Dir, "*.info"))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]Info, 0, len(names))
+	for _, name := range names {
+		info, err := s.Get(strings.TrimSuffix(filepath.Base(name), ".info"))
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// save writes the Info atomically.
+func (s *FileStore) save(info Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.Path(info.ID) + ".info.tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path(info.ID)+".info")
+}
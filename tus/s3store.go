@@ -0,0 +1,498 @@
+package tus
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Store keeps the uploads in an S3 bucket, or a compatible one, signing
+// requests with Signature Version 4 so the AWS SDK is not required.
+//
+// Upload <id> is assembled by a multipart upload into the object
+// Prefix+<id>; its state is kept in Prefix+<id>.info and the received bytes
+// not filling a part yet in Prefix+<id>.part.
+type S3Store struct {
+	Bucket          string
+	Prefix          string // key prefix of the uploads, e.g. "uploads/"
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string       // for temporary credentials, optional
+	Endpoint        string       // https://s3.<region>.amazonaws.com by default, path-style requests are sent
+	PartSize        int64        // size of the parts, 5MB by default, the S3 minimum
+	Client          *http.Client // http.DefaultClient by default
+
+	now func() time.Time
+}
+
+// NewS3Store returns an S3Store with the credentials in the environment
+// variables AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func NewS3Store(bucket, region string) *S3Store {
+	return &S3Store{
+		Bucket:          bucket,
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Key returns the key of the object of the upload, complete once the
+// upload is.
+func (s *S3Store) Key(id string) string {
+	return s.Prefix + id
+}
+
+// s3Info is the state of an upload kept in the .info object.
+type s3Info struct {
+	Info
+	UploadID string   `json:"upload_id,omitempty"` // empty once completed
+	Parts    []s3Part `json:"parts,omitempty"`
+}
+
+type s3Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// partsSize is the size of the uploaded parts, the rest of Offset is in the
+// .part object.
+func (st *s3Info) partsSize() int64 {
+	var n int64
+	for _, p := range st.Parts {
+		n += p.Size
+	}
+	return n
+}
+
+// Create implements Store.
+func (s *S3Store) Create(info Info) error {
+	st := &s3Info{Info: info}
+	if info.Size == 0 {
+		return s.finish(st, nil)
+	}
+	_, body, err := s.do("POST", s.Key(info.ID), url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		UploadId string
+	}
+	if err = xml.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("tus: creating multipart upload: %v", err)
+	}
+	st.UploadID = res.UploadId
+	return s.save(st)
+}
+
+// Get implements Store.
+func (s *S3Store) Get(id string) (Info, error) {
+	st, err := s.load(id)
+	if err != nil {
+		return Info{}, err
+	}
+	return st.Info, nil
+}
+
+// Write implements Store. The bytes are buffered up to PartSize in memory.
+func (s *S3Store) Write(id string, offset int64, r io.Reader) (int64, error) {
+	st, err := s.load(id)
+	if err != nil {
+		return 0, err
+	}
+	partSize := s.partSize()
+	buf := make([]byte, 0, partSize)
+	if pending := st.Offset - st.partsSize(); pending > 0 {
+		_, b, err := s.do("GET", s.Key(id)+".part", nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		if int64(len(b)) < pending {
+			return 0, fmt.Errorf("tus: %s.part has %d bytes, want %d", id, len(b), pending)
+		}
+		buf = append(buf, b[:pending]...)
+	}
+
+	var readErr error
+	for {
+		m, err := io.ReadFull(r, buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+m]
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			readErr = err
+		}
+		// The last part is uploaded by finish.
+		if int64(len(buf)) < partSize || st.Size >= 0 && st.partsSize()+int64(len(buf)) == st.Size {
+			break
+		}
+		if err := s.uploadPart(st, buf); err != nil {
+			return st.Offset - offset, err
+		}
+		buf = buf[:0]
+	}
+
+	st.Offset = st.partsSize() + int64(len(buf))
+	if st.Complete() {
+		err = s.finish(st, buf)
+	} else {
+		if len(buf) > 0 {
+			_, _, err = s.do("PUT", s.Key(id)+".part", nil, buf)
+		}
+		if err == nil {
+			err = s.save(st)
+		}
+	}
+	if err != nil {
+		// The buffered bytes are lost, the saved offset is after the parts.
+		return st.partsSize() - offset, err
+	}
+	return st.Offset - offset, readErr
+}
+
+// SetSize implements Store.
+func (s *S3Store) SetSize(id string, size int64) error {
+	st, err := s.load(id)
+	if err != nil {
+		return err
+	}
+	st.Size = size
+	if !st.Complete() {
+		return s.save(st)
+	}
+	var buf []byte
+	if pending := st.Offset - st.partsSize(); pending > 0 {
+		if _, buf, err = s.do("GET", s.Key(id)+".part", nil, nil); err != nil {
+			return err
+		}
+		if int64(len(buf)) < pending {
+			return fmt.Errorf("tus: %s.part has %d bytes, want %d", id, len(buf), pending)
+		}
+		buf = buf[:pending]
+	}
+	return s.finish(st, buf)
+}
+
+// Delete implements Store.
+func (s *S3Store) Delete(id string) error {
+	st, err := s.load(id)
+	if err != nil {
+		return err
+	}
+	if st.UploadID != "" {
+		if _, _, err = s.do("DELETE", s.Key(id), url.Values{"uploadId": {st.UploadID}}, nil); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	for _, key := range []string{s.Key(id), s.Key(id) + ".part", s.Key(id) + ".info"} {
+		if _, _, err = s.do("DELETE", key, nil, nil); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List implements Store.
+func (s *S3Store) List() ([]Info, error) {
+	var infos []Info
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		_, body, err := s.do("GET", "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		var res struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err = xml.Unmarshal(body, &res); err != nil {
+			return nil, fmt.Errorf("tus: listing uploads: %v", err)
+		}
+		for _, obj := range res.Contents {
+			if !strings.HasSuffix(obj.Key, ".info") {
+				continue
+			}
+			id := strings.TrimSuffix(strings.TrimPrefix(obj.Key, s.Prefix), ".info")
+			info, err := s.Get(id)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return infos, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (s *S3Store) partSize() int64 {
+	if s.PartSize > 0 {
+		return s.PartSize
+	}
+	return 5 << 20
+}
+
+func (s *S3Store) uploadPart(st *s3Info, b []byte) error {
+	n := len(st.Parts) + 1
+	q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {st.UploadID}}
+	header, _, err := s.do("PUT", s.Key(st.ID), q, b)
+	if err != nil {
+		return err
+	}
+	st.Parts = append(st.Parts, s3Part{Number: n, ETag: header.Get("ETag"), Size: int64(len(b))})
+	st.Offset = st.partsSize()
+	return s.save(st)
+}
+
+// finish uploads the last part and completes the multipart upload.
+func (s *S3Store) finish(st *s3Info, last []byte) error {
+	if len(st.Parts) == 0 && len(last) == 0 {
+		// A multipart upload needs a part.
+		if st.UploadID != "" {
+			if _, _, err := s.do("DELETE", s.Key(st.ID), url.Values{"uploadId": {st.UploadID}}, nil); err != nil && !isNotFound(err) {
+				return err
+			}
+		}
+		if _, _, err := s.do("PUT", s.Key(st.ID), nil, []byte{}); err != nil {
+			return err
+		}
+	} else {
+		if len(last) > 0 {
+			if err := s.uploadPart(st, last); err != nil {
+				return err
+			}
+		}
+		var complete struct {
+			XMLName xml.Name `xml:"CompleteMultipartUpload"`
+			Parts   []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		for _, p := range st.Parts {
+			complete.Parts = append(complete.Parts, struct {
+				PartNumber int
+				ETag       string
+			}{p.Number, p.ETag})
+		}
+		body, _ := xml.Marshal(complete)
+		if _, _, err := s.do("POST", s.Key(st.ID), url.Values{"uploadId": {st.UploadID}}, body); err != nil {
+			return err
+		}
+		if _, _, err := s.do("DELETE", s.Key(st.ID)+".part", nil, nil); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	st.UploadID = ""
+	return s.save(st)
+}
+
+func (s *S3Store) load(id string) (*s3Info, error) {
+	_, body, err := s.do("GET", s.Key(id)+".info", nil, nil)
+	if isNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := &s3Info{}
+	if err = json.Unmarshal(body, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *S3Store) save(st *s3Info) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.do("PUT", s.Key(st.ID)+".info", nil, b)
+	return err
+}
+
+// s3Error is a response with an error status.
+type s3Error struct {
+	status int
+	method string
+	key    string
+	body   []byte
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("tus: s3 %s %s: %d %s", e.method, e.key, e.status, bytes.TrimSpace(e.body))
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(*s3Error)
+	return ok && e.status == http.StatusNotFound
+}
+
+// do sends a signed request for the object key, the bucket if empty, and
+// returns the response headers and body.
+func (s *S3Store) do(method, key string, query url.Values, body []byte) (http.Header, []byte, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	u := strings.TrimRight(endpoint, "/") + "/" + awsEscape(s.Bucket, false)
+	if key != "" {
+		u += "/" + awsEscape(key, true)
+	}
+	rawQuery := canonicalQuery(query)
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.sign(req, rawQuery, body)
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, nil, &s3Error{status: resp.StatusCode, method: method, key: key, body: b}
+	}
+	return resp.Header, b, nil
+}
+
+// sign adds the Signature Version 4 headers to req.
+func (s *S3Store) sign(req *http.Request, rawQuery string, body []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	// canonical headers: host and all x-amz-* headers, sorted
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		rawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalQuery encodes q sorted by key, as sent and signed.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := q[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything except the unreserved characters,
+// and the slashes if keepSlash.
+func awsEscape(s string, keepSlash bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && keepSlash {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
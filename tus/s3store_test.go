@@ -0,0 +1,175 @@
+package tus
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 implements the subset of the S3 API used by S3Store.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[string][]byte // upload id -> part number -> data
+	nextID  int
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AK/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/bucket")
+	key := strings.TrimPrefix(path, "/")
+	q := r.URL.Query()
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case key == "" && r.Method == "GET":
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, q.Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		fmt.Fprint(w, "<ListBucketResult>")
+		for _, k := range keys {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", k)
+		}
+		fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+	case r.Method == "POST" && q["uploads"] != nil:
+		f.nextID++
+		id := fmt.Sprint("u", f.nextID)
+		f.uploads[id] = map[string][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == "PUT" && q.Get("partNumber") != "":
+		parts := f.uploads[q.Get("uploadId")]
+		if parts == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		parts[q.Get("partNumber")] = body
+		w.Header().Set("ETag", `"etag`+q.Get("partNumber")+`"`)
+	case r.Method == "POST" && q.Get("uploadId") != "":
+		parts := f.uploads[q.Get("uploadId")]
+		var complete struct {
+			Part []struct {
+				PartNumber string
+				ETag       string
+			}
+		}
+		if parts == nil || xml.Unmarshal(body, &complete) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var data []byte
+		for _, p := range complete.Part {
+			if p.ETag != `"etag`+p.PartNumber+`"` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data = append(data, parts[p.PartNumber]...)
+		}
+		f.objects[key] = data
+		delete(f.uploads, q.Get("uploadId"))
+	case r.Method == "DELETE" && q.Get("uploadId") != "":
+		delete(f.uploads, q.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT":
+		f.objects[key] = body
+	case r.Method == "GET":
+		b, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	case r.Method == "DELETE":
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, uploads: map[string]map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	s := &S3Store{
+		Bucket:          "bucket",
+		Prefix:          "up/",
+		Region:          "us-east-1",
+		AccessKeyID:     "AK",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+		PartSize:        5,
+	}
+
+	id := strings.Repeat("a", 32)
+	if err := s.Create(Info{ID: id, Size: 12}); err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range []string{"hello", "worl", "d!!"} {
+		info, _ := s.Get(id)
+		if n, err := s.Write(id, info.Offset, strings.NewReader(chunk)); err != nil || n != int64(len(chunk)) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	info, err := s.Get(id)
+	if err != nil || !info.Complete() {
+		t.Fatalf("info = %+v, %v", info, err)
+	}
+	if got := string(fake.objects["up/"+id]); got != "helloworld!!" {
+		t.Errorf("object = %q", got)
+	}
+	if _, ok := fake.objects["up/"+id+".part"]; ok || len(fake.uploads) != 0 {
+		t.Errorf("leftovers: part %v, uploads %v", ok, fake.uploads)
+	}
+
+	// Deferred length, completed by SetSize with pending bytes.
+	deferred := strings.Repeat("b", 32)
+	if err = s.Create(Info{ID: deferred, Size: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Write(deferred, 0, bytes.NewReader([]byte("abc"))); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetSize(deferred, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(fake.objects["up/"+deferred]); got != "abc" {
+		t.Errorf("deferred object = %q", got)
+	}
+
+	empty := strings.Repeat("c", 32)
+	if err = s.Create(Info{ID: empty, Size: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := fake.objects["up/"+empty]; !ok || len(b) != 0 {
+		t.Errorf("empty object = %q, %v", b, ok)
+	}
+
+	infos, err := s.List()
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("List = %+v, %v", infos, err)
+	}
+	for _, info := range infos {
+		if err = s.Delete(info.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("objects left: %d", len(fake.objects))
+	}
+	if _, err = s.Get(id); err != ErrNotFound {
+		t.Errorf("Get after Delete: %v", err)
+	}
+}
@@ -0,0 +1,449 @@
+// Package tus implements the tus resumable upload protocol 1.0.0
+// (https://tus.io) with the creation, creation-with-upload,
+// creation-defer-length, expiration and termination extensions.
+//
+// A Handler is mounted as a route group, so the lessgo middlewares, e.g. an
+// authentication one, apply to the uploads:
+//
+//	var uploads = tus.New(tus.Options{
+//		Store:      tus.NewFileStore("uploads/tus"),
+//		MaxSize:    1 << 30,
+//		Expiration: 24 * time.Hour,
+//		OnComplete: func(c *lessgo.Context, info tus.Info) { ... },
+//	})
+//
+//	func init() {
+//		lessgo.Root(uploads.Routes("/files", auth))
+//		lessgo.Scheduler.Cron("tus-expire", "@hourly", uploads.RemoveExpired, lessgo.JobOptions{})
+//	}
+//
+// Uploads are created by POST /files, which responds with their location
+// /files/<id>, resumed by HEAD and PATCH /files/<id> and terminated by
+// DELETE /files/<id>.
+package tus
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo"
+)
+
+const (
+	// Version of the protocol implemented.
+	Version    = "1.0.0"
+	extensions = "creation,creation-with-upload,creation-defer-length,expiration,termination"
+
+	offsetContentType = "application/offset+octet-stream"
+)
+
+// ErrNotFound is returned by a Store for an unknown upload.
+var ErrNotFound = errors.New("tus: upload not found")
+
+// Info describes an upload.
+type Info struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"` // -1 while the length is deferred
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"` // zero if it never expires
+}
+
+// Complete reports whether all the bytes of the upload were received.
+func (i Info) Complete() bool {
+	return i.Size >= 0 && i.Offset == i.Size
+}
+
+// Expired reports whether the upload expired before being completed.
+func (i Info) Expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt) && !i.Complete()
+}
+
+// Store persists the uploads. Writes to an upload are serialized by the
+// Handler.
+type Store interface {
+	// Create creates an empty upload.
+	Create(info Info) error
+
+	// Get returns the upload, ErrNotFound if unknown.
+	Get(id string) (Info, error)
+
+	// Write appends the bytes read from r to the upload, at offset, its
+	// current offset. The bytes written must be kept even if reading r
+	// fails, so the client can resume after them.
+	Write(id string, offset int64, r io.Reader) (int64, error)
+
+	// SetSize sets the size of an upload created with a deferred length.
+	SetSize(id string, size int64) error
+
+	// Delete deletes the upload and its data.
+	Delete(id string) error
+
+	// List returns all the uploads, to remove the expired ones.
+	List() ([]Info, error)
+}
+
+// Options of a Handler.
+type Options struct {
+	Store Store
+
+	// Max size of an upload, 0 for no limit.
+	MaxSize int64
+
+	// Uploads not completed within Expiration after their creation expire,
+	// 0 for never. RemoveExpired deletes them.
+	Expiration time.Duration
+
+	// OnComplete is called when the last byte of an upload is received,
+	// by the request that wrote it.
+	OnComplete func(c *lessgo.Context, info Info)
+}
+
+// Handler serves the tus protocol.
+type Handler struct {
+	opt    Options
+	mu     sync.Mutex
+	locked map[string]bool
+}
+
+// New returns a Handler.
+func New(opt Options) *Handler {
+	if opt.Store == nil {
+		panic("tus: no store")
+	}
+	return &Handler{opt: opt, locked: map[string]bool{}}
+}
+
+// Routes returns the route group of the uploads at prefix, guarded by
+// middlewares.
+func (h *Handler) Routes(prefix string, middlewares ...*lessgo.ApiMiddleware) *lessgo.VirtRouter {
+	create := lessgo.ApiHandler{
+		Desc:    "tus: create an upload under " + prefix,
+		Method:  "POST|OPTIONS",
+		Handler: h.create,
+	}.Reg()
+	upload := lessgo.ApiHandler{
+		Desc:    "tus: resume, query or terminate an upload under " + prefix,
+		Method:  "HEAD|PATCH|DELETE|POST",
+		Params:  []lessgo.Param{{Name: "id", In: "path", Required: true, Model: "", Desc: "upload id"}},
+		Handler: h.upload,
+	}.Reg()
+	return lessgo.Branch(prefix, "tus resumable uploads",
+		lessgo.Leaf("/", create, middlewares...),
+		lessgo.Leaf("/", upload, middlewares...),
+	)
+}
+
+// RemoveExpired deletes the expired uploads. It is a lessgo.JobFunc to be
+// scheduled periodically.
+func (h *Handler) RemoveExpired(cancel <-chan struct{}) error {
+	infos, err := h.opt.Store.List()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var errs []string
+	for _, info := range infos {
+		select {
+		case <-cancel:
+			return errors.New("tus: removing expired uploads canceled")
+		default:
+		}
+		if !info.Expired(now) || !h.lock(info.ID) {
+			continue
+		}
+		if err := h.opt.Store.Delete(info.ID); err != nil && err != ErrNotFound {
+			errs = append(errs, err.Error())
+		}
+		h.unlock(info.ID)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// header sets the headers of every response and checks the protocol
+// version of the request, false if the response was written.
+func (h *Handler) header(c *lessgo.Context) bool {
+	header := c.Response().Header()
+	header.Set("Tus-Resumable", Version)
+	header.Set("Access-Control-Expose-Headers", "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Defer-Length, Upload-Metadata, Upload-Expires")
+	if c.Request().Method == lessgo.OPTIONS {
+		return true
+	}
+	if c.Request().Header.Get("Tus-Resumable") != Version {
+		header.Set("Tus-Version", Version)
+		c.NoContent(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) create(c *lessgo.Context) error {
+	if !h.header(c) {
+		return nil
+	}
+	req, header := c.Request(), c.Response().Header()
+	if req.Method == lessgo.OPTIONS {
+		header.Set("Tus-Version", Version)
+		header.Set("Tus-Extension", extensions)
+		if h.opt.MaxSize > 0 {
+			header.Set("Tus-Max-Size", strconv.FormatInt(h.opt.MaxSize, 10))
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	info := Info{Size: -1, CreatedAt: time.Now()}
+	if v := req.Header.Get("Upload-Length"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < 0 {
+			return c.String(http.StatusBadRequest, "invalid Upload-Length")
+		}
+		info.Size = size
+	} else if req.Header.Get("Upload-Defer-Length") != "1" {
+		return c.String(http.StatusBadRequest, "missing Upload-Length")
+	}
+	if h.opt.MaxSize > 0 && info.Size > h.opt.MaxSize {
+		return c.String(http.StatusRequestEntityTooLarge, "upload larger than Tus-Max-Size")
+	}
+	var err error
+	if info.Metadata, err = parseMetadata(req.Header.Get("Upload-Metadata")); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if info.ID, err = newID(); err != nil {
+		return err
+	}
+	if h.opt.Expiration > 0 {
+		info.ExpiresAt = info.CreatedAt.Add(h.opt.Expiration)
+	}
+	if err = h.opt.Store.Create(info); err != nil {
+		return err
+	}
+	header.Set(lessgo.HeaderLocation, strings.TrimRight(req.URL.Path, "/")+"/"+info.ID)
+	setExpires(header, info)
+
+	if req.Header.Get(lessgo.HeaderContentType) == offsetContentType && req.ContentLength != 0 {
+		h.lock(info.ID)
+		defer h.unlock(info.ID)
+		if info, err = h.write(c, info); err != nil {
+			return err
+		}
+		header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	}
+	if info.Complete() {
+		h.complete(c, info)
+	}
+	return c.NoContent(http.StatusCreated)
+}
+
+func (h *Handler) upload(c *lessgo.Context) error {
+	if !h.header(c) {
+		return nil
+	}
+	req, header := c.Request(), c.Response().Header()
+	method := req.Method
+	if method == lessgo.POST {
+		// For clients behind proxies that only pass GET and POST.
+		method = strings.ToUpper(req.Header.Get("X-HTTP-Method-Override"))
+		if method != lessgo.PATCH && method != lessgo.DELETE && method != lessgo.HEAD {
+			return c.NoContent(http.StatusMethodNotAllowed)
+		}
+	}
+
+	id := c.PathParam("id")
+	if !validID(id) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if method != lessgo.HEAD {
+		if !h.lock(id) {
+			return c.String(http.StatusConflict, "upload is being written by another request")
+		}
+		defer h.unlock(id)
+	}
+	info, err := h.opt.Store.Get(id)
+	if err == ErrNotFound {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if info.Expired(time.Now()) {
+		if method != lessgo.HEAD {
+			h.opt.Store.Delete(id)
+		}
+		return c.NoContent(http.StatusGone)
+	}
+
+	switch method {
+	case lessgo.HEAD:
+		header.Set(lessgo.HeaderCacheControl, "no-store")
+		header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+		if info.Size < 0 {
+			header.Set("Upload-Defer-Length", "1")
+		} else {
+			header.Set("Upload-Length", strconv.FormatInt(info.Size, 10))
+		}
+		if len(info.Metadata) > 0 {
+			header.Set("Upload-Metadata", formatMetadata(info.Metadata))
+		}
+		setExpires(header, info)
+		return c.NoContent(http.StatusOK)
+
+	case lessgo.DELETE:
+		if err = h.opt.Store.Delete(id); err != nil && err != ErrNotFound {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	// PATCH
+	if req.Header.Get(lessgo.HeaderContentType) != offsetContentType {
+		return c.String(http.StatusUnsupportedMediaType, "Content-Type must be "+offsetContentType)
+	}
+	offset, err := strconv.ParseInt(req.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return c.String(http.StatusBadRequest, "invalid Upload-Offset")
+	}
+	if offset != info.Offset {
+		return c.String(http.StatusConflict, fmt.Sprintf("Upload-Offset %d doesn't match the upload offset %d", offset, info.Offset))
+	}
+	wasComplete := info.Complete()
+	if v := req.Header.Get("Upload-Length"); v != "" && info.Size < 0 {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < info.Offset {
+			return c.String(http.StatusBadRequest, "invalid Upload-Length")
+		}
+		if h.opt.MaxSize > 0 && size > h.opt.MaxSize {
+			return c.String(http.StatusRequestEntityTooLarge, "upload larger than Tus-Max-Size")
+		}
+		if err = h.opt.Store.SetSize(id, size); err != nil {
+			return err
+		}
+		info.Size = size
+	}
+	if info, err = h.write(c, info); err != nil {
+		return err
+	}
+	if !wasComplete && info.Complete() {
+		h.complete(c, info)
+	}
+	header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	setExpires(header, info)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// write appends the request body to the upload, which must be locked.
+func (h *Handler) write(c *lessgo.Context, info Info) (Info, error) {
+	var r io.Reader = c.Request().Body
+	switch {
+	case info.Size >= 0:
+		r = io.LimitReader(r, info.Size-info.Offset)
+	case h.opt.MaxSize > 0:
+		r = io.LimitReader(r, h.opt.MaxSize-info.Offset)
+	}
+	n, err := h.opt.Store.Write(info.ID, info.Offset, r)
+	info.Offset += n
+	if err != nil {
+		// The bytes written are kept, the client resumes after them.
+		return info, fmt.Errorf("tus: writing upload %s at %d: %v", info.ID, info.Offset, err)
+	}
+	return info, nil
+}
+
+func (h *Handler) complete(c *lessgo.Context, info Info) {
+	if h.opt.OnComplete != nil {
+		h.opt.OnComplete(c, info)
+	}
+}
+
+func (h *Handler) lock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.locked[id] {
+		return false
+	}
+	h.locked[id] = true
+	return true
+}
+
+func (h *Handler) unlock(id string) {
+	h.mu.Lock()
+	delete(h.locked, id)
+	h.mu.Unlock()
+}
+
+func setExpires(header http.Header, info Info) {
+	if !info.ExpiresAt.IsZero() && !info.Complete() {
+		header.Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id was returned by newID, ids are used in file
+// names and object keys.
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && strings.ToLower(id) == id
+}
+
+// parseMetadata parses an Upload-Metadata header: comma separated keys,
+// each followed by a space and its base64 encoded value if it has one.
+func parseMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid Upload-Metadata %q", pair)
+		}
+		var v []byte
+		if len(fields) == 2 {
+			var err error
+			if v, err = base64.StdEncoding.DecodeString(fields[1]); err != nil {
+				return nil, fmt.Errorf("invalid Upload-Metadata value of %s", fields[0])
+			}
+		}
+		m[fields[0]] = string(v)
+	}
+	return m, nil
+}
+
+func formatMetadata(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k
+		if m[k] != "" {
+			pairs[i] += " " + base64.StdEncoding.EncodeToString([]byte(m[k]))
+		}
+	}
+	return strings.Join(pairs, ",")
+}
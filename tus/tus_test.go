@@ -0,0 +1,184 @@
+package tus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lessgo/lessgo"
+	"github.com/lessgo/lessgo/lessgotest"
+)
+
+var (
+	testDir        string
+	testStore      *FileStore
+	completed      = map[string]Info{}
+	completedLock  sync.Mutex
+	testHandler    *Handler
+	expiringUpload *Handler
+)
+
+func init() {
+	testDir, _ = ioutil.TempDir("", "tus")
+	testStore = NewFileStore(testDir)
+	testHandler = New(Options{
+		Store:   testStore,
+		MaxSize: 100,
+		OnComplete: func(c *lessgo.Context, info Info) {
+			completedLock.Lock()
+			completed[info.ID] = info
+			completedLock.Unlock()
+		},
+	})
+	expiringUpload = New(Options{Store: testStore, Expiration: time.Millisecond})
+	lessgo.Root(
+		testHandler.Routes("/files"),
+		expiringUpload.Routes("/expiring"),
+	)
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	os.RemoveAll(testDir)
+	os.Exit(code)
+}
+
+func tusRequest(r *lessgotest.Request) *lessgotest.Request {
+	return r.WithHeader("Tus-Resumable", Version)
+}
+
+func patch(client *lessgotest.Client, location, offset, body string) *lessgotest.Request {
+	return tusRequest(client.PATCH(location)).
+		WithHeader("Upload-Offset", offset).
+		WithBody([]byte(body), offsetContentType)
+}
+
+func TestUpload(t *testing.T) {
+	client := lessgotest.New(nil)
+
+	client.OPTIONS("/files").Expect(t).
+		Status(http.StatusNoContent).
+		Header("Tus-Version", Version).
+		Header("Tus-Extension", extensions).
+		Header("Tus-Max-Size", "100")
+	client.POST("/files").WithHeader("Upload-Length", "11").Expect(t).
+		Status(http.StatusPreconditionFailed)
+	tusRequest(client.POST("/files")).WithHeader("Upload-Length", "101").Expect(t).
+		Status(http.StatusRequestEntityTooLarge)
+
+	resp := tusRequest(client.POST("/files")).
+		WithHeader("Upload-Length", "11").
+		WithHeader("Upload-Metadata", "filename aGVsbG8udHh0,private").
+		Expect(t).
+		Status(http.StatusCreated)
+	location := resp.Recorder.Header().Get(lessgo.HeaderLocation)
+	if !strings.HasPrefix(location, "/files/") {
+		t.Fatalf("Location = %q", location)
+	}
+	id := strings.TrimPrefix(location, "/files/")
+
+	tusRequest(client.HEAD(location)).Expect(t).
+		Status(http.StatusOK).
+		Header("Upload-Offset", "0").
+		Header("Upload-Length", "11").
+		Header("Upload-Metadata", "filename aGVsbG8udHh0,private").
+		Header(lessgo.HeaderCacheControl, "no-store")
+
+	patch(client, location, "0", "hello ").Expect(t).
+		Status(http.StatusNoContent).
+		Header("Upload-Offset", "6")
+	patch(client, location, "0", "hello ").Expect(t).Status(http.StatusConflict)
+	tusRequest(client.PATCH(location)).WithHeader("Upload-Offset", "6").
+		WithBody([]byte("world"), "text/plain").Expect(t).
+		Status(http.StatusUnsupportedMediaType)
+	// Bytes past the upload length are ignored.
+	patch(client, location, "6", "world and more").Expect(t).
+		Status(http.StatusNoContent).
+		Header("Upload-Offset", "11")
+
+	completedLock.Lock()
+	info, ok := completed[id]
+	completedLock.Unlock()
+	if !ok || info.Metadata["filename"] != "hello.txt" || info.Size != 11 {
+		t.Errorf("completed = %+v, %v", info, ok)
+	}
+	if b, _ := ioutil.ReadFile(testStore.Path(id)); string(b) != "hello world" {
+		t.Errorf("data = %q", b)
+	}
+
+	tusRequest(client.DELETE(location)).Expect(t).Status(http.StatusNoContent)
+	tusRequest(client.HEAD(location)).Expect(t).Status(http.StatusNotFound)
+	tusRequest(client.HEAD("/files/../../etc")).Expect(t).Status(http.StatusNotFound)
+}
+
+func TestDeferredLengthAndOverride(t *testing.T) {
+	client := lessgotest.New(nil)
+	resp := tusRequest(client.POST("/files")).
+		WithHeader("Upload-Defer-Length", "1").
+		WithBody([]byte("abc"), offsetContentType).
+		Expect(t).
+		Status(http.StatusCreated).
+		Header("Upload-Offset", "3")
+	location := resp.Recorder.Header().Get(lessgo.HeaderLocation)
+
+	tusRequest(client.HEAD(location)).Expect(t).
+		Header("Upload-Defer-Length", "1").
+		Header("Upload-Length", "")
+
+	patch(client, location, "3", "de").
+		WithHeader("Upload-Length", "5").
+		Expect(t).
+		Status(http.StatusNoContent).
+		Header("Upload-Offset", "5")
+	id := strings.TrimPrefix(location, "/files/")
+	completedLock.Lock()
+	_, ok := completed[id]
+	completedLock.Unlock()
+	if !ok {
+		t.Error("deferred upload not completed")
+	}
+
+	tusRequest(client.POST(location)).WithHeader("X-HTTP-Method-Override", "DELETE").Expect(t).
+		Status(http.StatusNoContent)
+	if _, err := testStore.Get(id); err != ErrNotFound {
+		t.Errorf("Get after DELETE override: %v", err)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	client := lessgotest.New(nil)
+	first := tusRequest(client.POST("/expiring")).WithHeader("Upload-Length", "5").Expect(t).
+		Status(http.StatusCreated).
+		Recorder.Header()
+	if first.Get("Upload-Expires") == "" {
+		t.Error("no Upload-Expires")
+	}
+	second := tusRequest(client.POST("/expiring")).WithHeader("Upload-Length", "5").Expect(t).
+		Recorder.Header().Get(lessgo.HeaderLocation)
+	time.Sleep(5 * time.Millisecond)
+
+	patch(client, first.Get(lessgo.HeaderLocation), "0", "hello").Expect(t).Status(http.StatusGone)
+	if err := expiringUpload.RemoveExpired(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testStore.Get(strings.TrimPrefix(second, "/expiring/")); err != ErrNotFound {
+		t.Errorf("expired upload not removed: %v", err)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	m, err := parseMetadata("a YQ==, b ,c Yw==")
+	if err != nil || len(m) != 3 || m["a"] != "a" || m["b"] != "" || m["c"] != "c" {
+		t.Errorf("metadata = %v, %v", m, err)
+	}
+	if formatMetadata(m) != "a YQ==,b,c Yw==" {
+		t.Errorf("formatted = %q", formatMetadata(m))
+	}
+	if _, err = parseMetadata("a !!"); err == nil {
+		t.Error("invalid base64 accepted")
+	}
+}
@@ -0,0 +1,81 @@
+package lessgo
+
+import (
+	"database/sql"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+// TransactionConfig defines the config for transaction-per-request middleware.
+type TransactionConfig struct {
+	// 使用的数据库连接池名称，见RegisterDB
+	DB string
+
+	// 需要开启事务的请求方法，为空时所有请求均开启
+	Methods []string
+}
+
+// Context中存放事务的键名
+const txKey = "__tx__"
+
+var Transaction = ApiMiddleware{
+	Name: "数据库事务",
+	Desc: "为每个请求开启数据库事务，响应状态为2xx时提交，返回错误、非2xx或发生恐慌时回滚",
+	Config: TransactionConfig{
+		DB:      "default",
+		Methods: []string{POST, PUT, PATCH, DELETE},
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(TransactionConfig)
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) (err error) {
+				if len(config.Methods) > 0 && !utils.InSlice(c.request.Method, config.Methods) {
+					return next(c)
+				}
+				db, err := GetDB(config.DB)
+				if err != nil {
+					return err
+				}
+				tx, err := db.Begin()
+				if err != nil {
+					return err
+				}
+				c.Set(txKey, tx)
+				done := false
+				defer func() {
+					c.Del(txKey)
+					if done {
+						return
+					}
+					// 发生恐慌时回滚
+					tx.Rollback()
+					if rcv := recover(); rcv != nil {
+						panic(rcv)
+					}
+				}()
+
+				err = next(c)
+				done = true
+				status := c.response.Status()
+				if err != nil || c.response.Committed() && (status < 200 || status > 299) {
+					if rerr := tx.Rollback(); rerr != nil {
+						Log.Error("Transaction: rollback failed: %v", rerr)
+					}
+					return err
+				}
+				// 响应已发出时提交失败无法再改变响应状态，只能记录错误
+				if err = tx.Commit(); err != nil && c.response.Committed() {
+					Log.Error("Transaction: commit failed after response %d: %v", status, err)
+				}
+				return err
+			}
+		}
+	},
+}.Reg()
+
+// 获取当前请求的数据库事务，未使用Transaction中间件时返回nil
+func (c *Context) Tx() *sql.Tx {
+	tx, _ := c.Get(txKey).(*sql.Tx)
+	return tx
+}
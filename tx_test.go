@@ -0,0 +1,49 @@
+package lessgo
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	if err := RegisterDB("txtest", DBConfig{Driver: "lessgofake", DSN: "txtest"}); err != nil {
+		t.Fatal(err)
+	}
+	mw := Transaction.Middleware.(Middleware).getMiddlewareFunc(TransactionConfig{DB: "txtest"})
+
+	tests := []struct {
+		handler            HandlerFunc
+		commits, rollbacks int32
+	}{
+		{func(c *Context) error { return c.String(http.StatusCreated, "ok") }, 1, 0},
+		{func(c *Context) error { return nil }, 1, 0},
+		{func(c *Context) error { return c.String(http.StatusConflict, "conflict") }, 0, 1},
+		{func(c *Context) error { return errors.New("failed") }, 0, 1},
+		{func(c *Context) error { panic("boom") }, 0, 1},
+	}
+	for i, test := range tests {
+		commits, rollbacks := atomic.LoadInt32(&fakeCommits), atomic.LoadInt32(&fakeRollbacks)
+		req, _ := http.NewRequest(POST, "/orders", nil)
+		c, _ := testContext(req)
+		func() {
+			defer func() { recover() }()
+			mw(func(c *Context) error {
+				if c.Tx() == nil {
+					t.Errorf("%d: no transaction in context", i)
+				}
+				return test.handler(c)
+			})(c)
+		}()
+		if c.Tx() != nil {
+			t.Errorf("%d: transaction left in context", i)
+		}
+		if n := atomic.LoadInt32(&fakeCommits) - commits; n != test.commits {
+			t.Errorf("%d: commits = %d, want %d", i, n, test.commits)
+		}
+		if n := atomic.LoadInt32(&fakeRollbacks) - rollbacks; n != test.rollbacks {
+			t.Errorf("%d: rollbacks = %d, want %d", i, n, test.rollbacks)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package lessgo
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// UploadProgressConfig defines the config for upload progress middleware.
+	UploadProgressConfig struct {
+		// 携带上传ID的请求头，请求头缺失时从同名的URL参数读取
+		Header string
+
+		// 上传结束后进度保留的时长，单位秒
+		KeepSecond int64
+	}
+
+	// 上传进度
+	UploadProgress struct {
+		ID       string    `json:"id"`
+		State    string    `json:"state"` // uploading、done、error
+		Received int64     `json:"received"`
+		Size     int64     `json:"size"` // 请求体总长度，未知时为-1
+		Updated  time.Time `json:"updated"`
+	}
+
+	// 统计读取进度的请求体
+	progressBody struct {
+		io.ReadCloser
+		read, total int64
+		fns         []func(read, total int64)
+	}
+)
+
+const (
+	UPLOAD_UPLOADING = "uploading"
+	UPLOAD_DONE      = "done"
+	UPLOAD_ERROR     = "error"
+)
+
+var (
+	uploadProgresses    = map[string]*UploadProgress{}
+	uploadProgressMutex sync.Mutex
+)
+
+// 注册请求体的读取进度回调，每次读取后以已读取与总字节数(未知时为-1)调用fn；
+// 须在读取请求体(如Bind、FormFile)之前调用，可多次调用注册多个回调
+func (c *Context) OnUploadProgress(fn func(read, total int64)) {
+	if pb, ok := c.request.Body.(*progressBody); ok {
+		pb.fns = append(pb.fns, fn)
+		return
+	}
+	if c.request.Body == nil {
+		return
+	}
+	total := c.request.ContentLength
+	if total < 0 {
+		total = -1
+	}
+	c.request.Body = &progressBody{ReadCloser: c.request.Body, total: total, fns: []func(read, total int64){fn}}
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.read += int64(n)
+		for _, fn := range b.fns {
+			fn(b.read, b.total)
+		}
+	}
+	return n, err
+}
+
+// 返回上传ID对应的上传进度
+func GetUploadProgress(id string) (UploadProgress, bool) {
+	uploadProgressMutex.Lock()
+	defer uploadProgressMutex.Unlock()
+	p, ok := uploadProgresses[id]
+	if !ok {
+		return UploadProgress{}, false
+	}
+	return *p, true
+}
+
+// 记录携带上传ID(由客户端生成的随机串)的请求的上传进度，供UploadProgressHandler查询，
+// 以便页面在上传大文件的同时轮询进度
+var TrackUploadProgress = ApiMiddleware{
+	Name: "上传进度",
+	Desc: "记录携带上传ID的请求的请求体读取进度，通过UploadProgressHandler按ID查询",
+	Config: UploadProgressConfig{
+		Header:     "X-Progress-ID",
+		KeepSecond: 60,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(UploadProgressConfig)
+		keep := time.Duration(config.KeepSecond) * time.Second
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				id := c.request.Header.Get(config.Header)
+				if id == "" {
+					id = c.QueryParam(config.Header)
+				}
+				if id == "" {
+					return next(c)
+				}
+				p := &UploadProgress{ID: id, State: UPLOAD_UPLOADING, Size: c.request.ContentLength, Updated: time.Now()}
+				if p.Size < 0 {
+					p.Size = -1
+				}
+				uploadProgressMutex.Lock()
+				uploadProgresses[id] = p
+				uploadProgressMutex.Unlock()
+
+				c.OnUploadProgress(func(read, total int64) {
+					uploadProgressMutex.Lock()
+					p.Received = read
+					p.Updated = time.Now()
+					uploadProgressMutex.Unlock()
+				})
+				err := next(c)
+
+				uploadProgressMutex.Lock()
+				p.State = UPLOAD_DONE
+				if err != nil || c.response.Status() >= 400 {
+					p.State = UPLOAD_ERROR
+				}
+				p.Updated = time.Now()
+				uploadProgressMutex.Unlock()
+				time.AfterFunc(keep, func() {
+					uploadProgressMutex.Lock()
+					// 同一ID可能已被新的上传使用
+					if uploadProgresses[id] == p {
+						delete(uploadProgresses, id)
+					}
+					uploadProgressMutex.Unlock()
+				})
+				return err
+			}
+		}
+	},
+}.Reg()
+
+// 按上传ID查询上传进度的操作，与TrackUploadProgress中间件配合使用，如：
+//
+//	lessgo.Root(
+//		lessgo.Leaf("/upload", UploadHandler, lessgo.TrackUploadProgress),
+//		lessgo.Leaf("/upload/progress", lessgo.UploadProgressHandler),
+//	)
+var UploadProgressHandler = ApiHandler{
+	Desc:   "查询上传进度",
+	Method: "GET",
+	Params: []Param{
+		{Name: "id", In: "query", Required: true, Model: "", Desc: "上传ID"},
+	},
+	Handler: func(c *Context) error {
+		p, ok := GetUploadProgress(c.QueryParam("id"))
+		if !ok {
+			return c.NoContent(http.StatusNotFound)
+		}
+		c.response.CacheControl().NoStore()
+		return c.JSON(http.StatusOK, p)
+	},
+}.Reg()
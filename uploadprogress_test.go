@@ -0,0 +1,58 @@
+package lessgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestUploadProgress(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 100<<10)
+	req, _ := http.NewRequest("POST", "/upload?X-Progress-ID=p1", bytes.NewReader(body))
+	c, _ := testContext(req)
+	defer c.free()
+
+	var during UploadProgress
+	var calls int
+	var last [2]int64
+	h := TrackUploadProgress.Middleware.(Middleware).getMiddlewareFunc(UploadProgressConfig{
+		Header:     "X-Progress-ID",
+		KeepSecond: 60,
+	})(func(c *Context) error {
+		c.OnUploadProgress(func(read, total int64) {
+			calls++
+			last = [2]int64{read, total}
+		})
+		buf := make([]byte, 10<<10)
+		c.Request().Body.Read(buf)
+		during, _ = GetUploadProgress("p1")
+		ioutil.ReadAll(c.Request().Body)
+		return c.NoContent(http.StatusCreated)
+	})
+	if err := h(c); err != nil {
+		t.Fatal(err)
+	}
+	if during.State != UPLOAD_UPLOADING || during.Received != 10<<10 || during.Size != int64(len(body)) {
+		t.Errorf("progress during the upload = %+v", during)
+	}
+	if calls == 0 || last != [2]int64{int64(len(body)), int64(len(body))} {
+		t.Errorf("%d calls, last = %v", calls, last)
+	}
+
+	req, _ = http.NewRequest("GET", "/upload/progress?id=p1", nil)
+	c2, rec := testContext(req)
+	defer c2.free()
+	if err := UploadProgressHandler.Handler(c2); err != nil {
+		t.Fatal(err)
+	}
+	var p UploadProgress
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if rec.Code != http.StatusOK || p.State != UPLOAD_DONE || p.Received != int64(len(body)) {
+		t.Errorf("progress = %d %+v", rec.Code, p)
+	}
+	if _, ok := GetUploadProgress("missing"); ok {
+		t.Error("unknown upload id found")
+	}
+}
@@ -0,0 +1,220 @@
+package lessgo
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// UserAgentConfig defines the config for User-Agent parsing middleware.
+	UserAgentConfig struct {
+		// 禁止访问的爬虫名称(不区分大小写)，"*"表示全部爬虫
+		BlockBots []string
+
+		// 允许访问的爬虫名称，优先于BlockBots，如"Googlebot"
+		AllowBots []string
+
+		// 每个爬虫每分钟在当前路由上的最大请求数，为0时不限制
+		BotRatePerMinute int
+
+		// 是否拒绝未携带User-Agent的请求
+		BlockEmpty bool
+	}
+
+	// User-Agent解析结果
+	UserAgent struct {
+		Raw            string `json:"raw"`
+		Browser        string `json:"browser"`
+		BrowserVersion string `json:"browser_version"`
+		OS             string `json:"os"`
+		Device         string `json:"device"` // desktop、mobile、tablet、bot
+		Bot            bool   `json:"bot"`
+		BotName        string `json:"bot_name"`
+	}
+
+	// 按爬虫与路由计数的固定窗口限流
+	botRateLimiter struct {
+		limit  int
+		window int64
+		counts map[string]int
+		sync.Mutex
+	}
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Context中存放UserAgent的键名
+const userAgentKey = "__useragent__"
+
+var (
+	// 已知爬虫，按顺序匹配
+	knownBots = []string{
+		"Googlebot", "Bingbot", "Baiduspider", "YandexBot", "DuckDuckBot", "Slurp",
+		"Sogou", "360Spider", "Bytespider", "PetalBot", "YisouSpider", "AhrefsBot",
+		"SemrushBot", "MJ12bot", "DotBot", "facebookexternalhit", "Twitterbot",
+		"LinkedInBot", "Applebot", "GPTBot", "CCBot", "ClaudeBot", "curl", "Wget",
+		"python-requests", "Go-http-client", "Java", "okhttp", "HeadlessChrome",
+	}
+	// 通用爬虫特征
+	botPattern = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|scrap|fetch|monitor|http-?client`)
+
+	// 浏览器，按顺序匹配(Edge、Opera等需先于Chrome)
+	browserPatterns = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+		{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+		{"WeChat", regexp.MustCompile(`MicroMessenger/([\d.]+)`)},
+		{"UCBrowser", regexp.MustCompile(`UCBrowser/([\d.]+)`)},
+		{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+		{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+		{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+		{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+		{"IE", regexp.MustCompile(`(?:MSIE |Trident/.*rv:)([\d.]+)`)},
+	}
+
+	osPatterns = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"Windows Phone", regexp.MustCompile(`Windows Phone`)},
+		{"Windows", regexp.MustCompile(`Windows`)},
+		{"iOS", regexp.MustCompile(`iPhone|iPad|iPod`)},
+		{"Mac OS X", regexp.MustCompile(`Mac OS X|Macintosh`)},
+		{"Android", regexp.MustCompile(`Android`)},
+		{"Chrome OS", regexp.MustCompile(`CrOS`)},
+		{"Linux", regexp.MustCompile(`Linux`)},
+	}
+)
+
+var DetectUserAgent = ApiMiddleware{
+	Name: "User-Agent解析",
+	Desc: "解析User-Agent得到设备、浏览器与爬虫信息，并可按路由禁止或限流已知爬虫",
+	Config: UserAgentConfig{
+		BlockBots:        []string{},
+		AllowBots:        []string{"Googlebot", "Bingbot", "Baiduspider"},
+		BotRatePerMinute: 0,
+		BlockEmpty:       false,
+	},
+	Middleware: func(confObject interface{}) MiddlewareFunc {
+		config := confObject.(UserAgentConfig)
+		limiter := &botRateLimiter{limit: config.BotRatePerMinute, counts: map[string]int{}}
+
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Context) error {
+				ua := c.UserAgent()
+				if ua.Raw == "" && config.BlockEmpty {
+					return NewHTTPError(http.StatusForbidden)
+				}
+				if !ua.Bot || containsFold(config.AllowBots, ua.BotName) {
+					return next(c)
+				}
+				if containsFold(config.BlockBots, "*") || containsFold(config.BlockBots, ua.BotName) {
+					return NewHTTPError(http.StatusForbidden)
+				}
+				if !limiter.allow(ua.BotName + " " + c.path) {
+					c.response.Header().Set("Retry-After", "60")
+					return NewHTTPError(http.StatusTooManyRequests)
+				}
+				return next(c)
+			}
+		}
+	},
+}.Reg()
+
+// 获取客户端User-Agent解析结果
+func (c *Context) UserAgent() *UserAgent {
+	if ua, ok := c.Get(userAgentKey).(*UserAgent); ok {
+		return ua
+	}
+	ua := ParseUserAgent(c.request.UserAgent())
+	c.Set(userAgentKey, ua)
+	return ua
+}
+
+// 解析User-Agent
+func ParseUserAgent(raw string) *UserAgent {
+	ua := &UserAgent{Raw: raw}
+	if raw == "" {
+		return ua
+	}
+	for _, name := range knownBots {
+		if strings.Contains(strings.ToLower(raw), strings.ToLower(name)) {
+			ua.Bot, ua.BotName = true, name
+			break
+		}
+	}
+	if !ua.Bot {
+		if loc := botPattern.FindStringIndex(raw); loc != nil {
+			ua.Bot = true
+			// 取匹配处所在的产品名，如"ExampleBot/1.0"中的"ExampleBot"
+			start := strings.LastIndexAny(raw[:loc[0]], " ;(") + 1
+			end := strings.IndexAny(raw[loc[1]:], "/ ;)")
+			if end < 0 {
+				end = len(raw)
+			} else {
+				end += loc[1]
+			}
+			ua.BotName = raw[start:end]
+		}
+	}
+
+	for _, p := range browserPatterns {
+		if m := p.re.FindStringSubmatch(raw); m != nil {
+			ua.Browser, ua.BrowserVersion = p.name, m[1]
+			break
+		}
+	}
+	for _, p := range osPatterns {
+		if p.re.MatchString(raw) {
+			ua.OS = p.name
+			break
+		}
+	}
+
+	switch {
+	case ua.Bot:
+		ua.Device = DeviceBot
+	case strings.Contains(raw, "iPad") || strings.Contains(raw, "Tablet") ||
+		(strings.Contains(raw, "Android") && !strings.Contains(raw, "Mobile")):
+		ua.Device = DeviceTablet
+	case strings.Contains(raw, "Mobi") || strings.Contains(raw, "iPhone") || strings.Contains(raw, "Windows Phone"):
+		ua.Device = DeviceMobile
+	default:
+		ua.Device = DeviceDesktop
+	}
+	return ua
+}
+
+func (l *botRateLimiter) allow(key string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	window := time.Now().Unix() / 60
+	l.Lock()
+	defer l.Unlock()
+	if window != l.window {
+		l.window = window
+		l.counts = map[string]int{}
+	}
+	l.counts[key]++
+	return l.counts[key] <= l.limit
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package lessgo
+
+import (
+	"testing"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	for _, test := range []struct {
+		raw     string
+		browser string
+		version string
+		os      string
+		device  string
+		botName string
+	}{
+		{"", "", "", "", "", ""},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			"Chrome", "120.0.0.0", "Windows", DeviceDesktop, ""},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			"Edge", "120.0.2210.91", "Windows", DeviceDesktop, ""},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			"Safari", "17.1", "Mac OS X", DeviceDesktop, ""},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			"Firefox", "121.0", "Linux", DeviceDesktop, ""},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			"Safari", "17.1", "iOS", DeviceMobile, ""},
+		{"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.169 Mobile/15E148 Safari/604.1",
+			"Chrome", "119.0.6045.169", "iOS", DeviceTablet, ""},
+		{"Mozilla/5.0 (Linux; Android 13; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			"Samsung Internet", "23.0", "Android", DeviceMobile, ""},
+		{"Mozilla/5.0 (Linux; Android 10; SM-T510) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+			"Chrome", "119.0.0.0", "Android", DeviceTablet, ""},
+		{"Mozilla/5.0 (Linux; Android 12; V2148A) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.99 Mobile Safari/537.36 MicroMessenger/8.0.40.2420",
+			"WeChat", "8.0.40.2420", "Android", DeviceMobile, ""},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			"", "", "", DeviceBot, "Googlebot"},
+		{"Mozilla/5.0 (compatible; Baiduspider/2.0; +http://www.baidu.com/search/spider.html)",
+			"", "", "", DeviceBot, "Baiduspider"},
+		{"curl/8.4.0", "", "", "", DeviceBot, "curl"},
+		{"Mozilla/5.0 (compatible; ExampleCrawler/1.0)", "", "", "", DeviceBot, "ExampleCrawler"},
+	} {
+		ua := ParseUserAgent(test.raw)
+		if ua.Browser != test.browser || ua.BrowserVersion != test.version || ua.OS != test.os ||
+			ua.Device != test.device || ua.BotName != test.botName || ua.Bot != (test.botName != "") {
+			t.Errorf("ParseUserAgent(%q) = %+v", test.raw, ua)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package lessgo
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lessgo/lessgo/secure"
+)
+
+// 启动前检查的命令行参数，如./app -check或./app --check：
+// Run()构建路由并执行Validate()，打印检查报告后退出，通过时退出码为0，否则为1；不监听端口、不开启后台任务
+const CHECK_FLAG = "check"
+
+// 启动时读取主配置的错误
+var configLoadErr error
+
+// Validate发现的问题列表
+type ValidationErrors []string
+
+func (e ValidationErrors) Error() string {
+	return fmt.Sprintf("%d problems found:\n  %s", len(e), strings.Join(e, "\n  "))
+}
+
+// 构建完整路由并检查配置、中间件引用及操作，有问题时返回ValidationErrors；
+// 不监听端口、不开启配置热加载等后台任务，也不连接数据库，可用作部署前的检查
+func Validate() error {
+	var errs ValidationErrors
+	func() {
+		defer func() {
+			// 路由冲突等错误在注册路由时panic
+			if r := recover(); r != nil {
+				errs = append(errs, fmt.Sprintf("router: %v", r))
+			}
+		}()
+		buildRouter()
+	}()
+	errs = append(errs, validateConfig()...)
+	errs = append(errs, validateRouter()...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// 构建完整路由并检查配置、中间件引用及操作，见Validate()
+func (this *App) Validate() error {
+	return Validate()
+}
+
+// 检查主配置及数据库、Redis、工作池等配置段
+func validateConfig() []string {
+	var errs []string
+	add := func(format string, a ...interface{}) {
+		errs = append(errs, "config: "+fmt.Sprintf(format, a...))
+	}
+	if configLoadErr != nil {
+		add("%v", strings.TrimSpace(configLoadErr.Error()))
+	}
+	if err := configSecretError(); err != nil {
+		add("%v", err)
+	}
+	_, serrs := bindValues()
+	for _, fe := range serrs {
+		// 数据库与Redis配置段的错误由dbConfigs()与redisConfigs()报告
+		if !strings.HasPrefix(fe.Key, DB_SECTION_PREFIX) && !strings.HasPrefix(fe.Key, REDIS_SECTION_PREFIX) {
+			add("%v", fe)
+		}
+	}
+
+	if _, port, err := net.SplitHostPort(Config.Listen.Address); err != nil {
+		add("listen::address: %v", err)
+	} else if _, err = net.LookupPort("tcp", port); err != nil {
+		add("listen::address: %v", err)
+	}
+	if Config.Listen.EnableHTTPS {
+		if Config.Listen.HTTPSCertFile == "" || Config.Listen.HTTPSKeyFile == "" {
+			add("listen::enablehttps is on, but listen::httpscertfile or listen::httpskeyfile is not set")
+		} else if _, err := tls.LoadX509KeyPair(Config.Listen.HTTPSCertFile, Config.Listen.HTTPSKeyFile); err != nil {
+			add("listen::httpscertfile and listen::httpskeyfile: %v", err)
+		}
+	}
+	if _, err := secure.ParseKeys(Config.SecureKeys); err != nil {
+		add("system::securekeys: %v", err)
+	}
+	checkFormat := func(key, format string, formats ...string) {
+		if format == "" {
+			return
+		}
+		for _, f := range formats {
+			if f == format {
+				return
+			}
+		}
+		add("%s: unknown format %q, expected one of %s", key, format, strings.Join(formats, ", "))
+	}
+	checkFormat("log::consoleformat", Config.Log.ConsoleFormat, "text", "json", "logfmt", "pretty")
+	checkFormat("log::fileformat", Config.Log.FileFormat, "text", "json", "logfmt")
+
+	if _, err := dbConfigs(); err != nil {
+		add("%v", err)
+	}
+	if _, err := redisConfigs(); err != nil {
+		add("%v", err)
+	}
+	if _, err := workerPoolConfigs(); err != nil {
+		add("%v", err)
+	}
+	return errs
+}
+
+// 检查虚拟路由引用的中间件及操作
+func validateRouter() []string {
+	var errs []string
+	middlewares := func(where string, configs []*MiddlewareConfig) {
+		for _, m := range configs {
+			if m == nil {
+				continue
+			}
+			if getApiMiddleware(m.Name) == nil {
+				errs = append(errs, fmt.Sprintf("middleware: %s: %q does not exist", where, m.Name))
+			} else if !m.CheckValid() {
+				errs = append(errs, fmt.Sprintf("middleware: %s: %q was re-registered", where, m.Name))
+			} else if pool, ok := bulkheadPoolOf(m); ok && getWorkerPool(pool) == nil {
+				errs = append(errs, fmt.Sprintf("middleware: %s: worker pool %q of %q is not registered", where, pool, m.Name))
+			}
+		}
+	}
+	middlewares("before", lessgo.virtBefore)
+	middlewares("after", lessgo.virtAfter)
+	for _, v := range lessgo.virtFiles {
+		middlewares("file "+v.Path, v.Middlewares)
+	}
+	for _, v := range lessgo.virtStatics {
+		middlewares("static "+v.Prefix, v.Middlewares)
+	}
+	if lessgo.virtRouter != nil {
+		for _, vr := range lessgo.virtRouter.Progeny() {
+			middlewares(vr.Path(), vr.Middlewares)
+			if vr.Type != HANDLER {
+				continue
+			}
+			switch {
+			case vr.apiHandler == nil:
+				errs = append(errs, fmt.Sprintf("handler: %s: hid %q has no handler", vr.Path(), vr.Hid))
+			case vr.apiHandler.id == "" || vr.apiHandler.id != vr.Hid:
+				errs = append(errs, fmt.Sprintf("handler: %s: hid %q does not match the handler id %q", vr.Path(), vr.Hid, vr.apiHandler.id))
+			case getApiHandler(vr.Hid) != vr.apiHandler:
+				errs = append(errs, fmt.Sprintf("handler: %s: hid %q is not registered", vr.Path(), vr.Hid))
+			case vr.apiHandler.Handler == nil:
+				errs = append(errs, fmt.Sprintf("handler: %s: %q has no handler function", vr.Path(), vr.apiHandler.Desc))
+			}
+		}
+	}
+	for _, s := range staleVirtRouters {
+		errs = append(errs, "handler: "+s+" in the virtual router config does not exist")
+	}
+	return errs
+}
+
+func init() {
+	// 在标准命令行参数中注册，以免应用调用flag.Parse()时报错
+	if flag.Lookup(CHECK_FLAG) == nil {
+		flag.Bool(CHECK_FLAG, false, "validate the config and the router, then exit without serving")
+	}
+}
+
+// 命令行参数中是否含-check或--check，"--"之后的参数不做解析
+func checkFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if n := len(arg) - len(name); n == 0 || n > 2 {
+			continue
+		}
+		value := "true"
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = name[:i], name[i+1:]
+		}
+		if name == CHECK_FLAG {
+			ok, _ := strconv.ParseBool(value)
+			return ok
+		}
+	}
+	return false
+}
+
+// 以-check启动时，执行Validate()并打印报告后退出
+func checkAndExit() {
+	err := Validate()
+	Log.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s check failed, %v\n", Config.AppName, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s check passed: %d routes.\n", Config.AppName, len(GetRouteTable().Routes))
+	os.Exit(0)
+}
@@ -0,0 +1,64 @@
+package lessgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFlag(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"-check"}, true},
+		{[]string{"-lessgo.debug=false", "--check"}, true},
+		{[]string{"--check=false"}, false},
+		{[]string{"---check"}, false},
+		{[]string{"check"}, false},
+		{[]string{"--", "-check"}, false},
+	} {
+		if got := checkFlag(c.args); got != c.want {
+			t.Errorf("checkFlag(%q) = %v, want %v", c.args, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	before := lessgo.virtBefore
+	defer func() { lessgo.virtBefore = before }()
+	lessgo.virtBefore = append(before[:len(before):len(before)], &MiddlewareConfig{Name: "validate missing"}, BulkheadPool("validate:missing").NewMiddlewareConfig())
+
+	// 虚拟路由配置中的操作已不存在
+	h := ApiHandler{Desc: "validate kept", Method: "GET", Handler: func(c *Context) error { return nil }}.Reg()
+	vr := Branch("/validate", "validate", Leaf("/kept", h))
+	vr.Children = append(vr.Children, &VirtRouter{Type: HANDLER, Prefix: "/gone", Hid: "no such handler"})
+	stale := staleVirtRouters
+	defer func() { staleVirtRouters = stale }()
+	vr.initFromConfig()
+	if len(vr.Children) != 1 {
+		t.Fatalf("stale node not removed: %d children", len(vr.Children))
+	}
+
+	err := Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("Validate() = %v", err)
+	}
+	for i, want := range []string{
+		`middleware: before: "validate missing" does not exist`,
+		`middleware: before: worker pool "validate:missing" of "工作池隔离:validate:missing" is not registered`,
+		`handler: /validate/gone (hid no such handler) in the virtual router config does not exist`,
+	} {
+		if errs[i] != want {
+			t.Errorf("problem %d = %q, want %q", i, errs[i], want)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "3 problems found:\n  middleware: ") {
+		t.Errorf("report: %q", err.Error())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	pathpkg "path"
@@ -31,11 +32,16 @@ func (this *VirtFile) route() {
 type VirtStatic struct {
 	Prefix      string
 	Root        string
+	FS          fs.FS // 不为nil时从虚拟文件系统读取，忽略Root
 	Middlewares []*MiddlewareConfig
 }
 
 // 从单独静态目录虚拟路由注册真实路由
 func (this *VirtStatic) route() {
+	if this.FS != nil {
+		app.staticFS(this.Prefix, this.FS, getMiddlewareFuncs(this.Middlewares)...)
+		return
+	}
 	app.static(this.Prefix, this.Root, getMiddlewareFuncs(this.Middlewares)...)
 }
 
@@ -298,6 +304,9 @@ func (vr *VirtRouter) delChild(virtRouter *VirtRouter) (err error) {
 	return fmt.Errorf("node %v does not have child node: %v.", vr.Description(), virtRouter.Description())
 }
 
+// 读取虚拟路由配置时，因操作已不存在而被移除的操作节点
+var staleVirtRouters []string
+
 // 对从配置文件读来的路由进行部分字段的初始化
 func (vr *VirtRouter) initFromConfig() {
 	// 获取操作
@@ -315,6 +324,7 @@ func (vr *VirtRouter) initFromConfig() {
 			if parent == nil {
 				return
 			}
+			staleVirtRouters = append(staleVirtRouters, pathpkg.Join("/", parent.path, vr.Prefix)+" (hid "+vr.Hid+")")
 
 			for i, child := range parent.Children {
 				if child == vr {
@@ -450,9 +460,12 @@ func (vr *VirtRouter) route(g *Group) {
 			child.route(childGroup)
 		}
 	case HANDLER:
+		// 先绑定操作，注册路由时据此记录请求匹配到的操作
 		if omitIndex {
+			g.bindApiHandler(vr.Methods(), prefix2, vr.apiHandler)
 			g.match(vr.Methods(), prefix2, vr.apiHandler.Handler, mws...)
 		}
+		g.bindApiHandler(vr.Methods(), prefix, vr.apiHandler)
 		g.match(vr.Methods(), prefix, vr.apiHandler.Handler, mws...)
 	}
 }
@@ -467,7 +480,18 @@ type virtRouterConfig struct {
 var canSaveVirtRouterConfig bool
 
 // 读取虚拟路由配置
+// 存在结构化路由配置(virtrouter.yaml/.yml/.toml/.json)时，其由部署工具维护，
+// 总是以其为准，运行中的修改仍保存至config/virtrouter.config
 func readVirtRouterConfig() (md5 string, vr *VirtRouter, err error) {
+	sfname, err := findStructuredConfig(CONFIG_DIR + "/virtrouter")
+	if err != nil {
+		Log.Warn("%v", err)
+	}
+	if sfname != "" {
+		vr, err = readStructuredVirtRouterConfig(sfname)
+		return Md5, vr, err
+	}
+
 	f, err := os.OpenFile(ROUTERCONFIG_FILE, os.O_CREATE|os.O_RDONLY, 0777)
 	if err != nil {
 		return
@@ -488,6 +512,21 @@ func readVirtRouterConfig() (md5 string, vr *VirtRouter, err error) {
 	return vrc.Md5, vrc.VirtRouter, err
 }
 
+// 读取结构化路由配置，忽略其中的md5
+func readStructuredVirtRouterConfig(fname string) (*VirtRouter, error) {
+	data, err := readStructuredConfig(fname)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	vrc := virtRouterConfig{}
+	err = json.Unmarshal(b, &vrc)
+	return vrc.VirtRouter, err
+}
+
 // 保存虚拟路由配置到配置文件
 func saveVirtRouterConfig() error {
 	if !canSaveVirtRouterConfig {
@@ -587,7 +626,7 @@ func newRootVirtRouter() *VirtRouter {
 func initVirtRouterConfig() {
 	md5, vr, err := readVirtRouterConfig()
 	if err != nil {
-		Log.Error("Read the config/virtrouter.config failed: %v.", err)
+		Log.Error("Read the virtual router config failed: %v.", err)
 		return
 	}
 
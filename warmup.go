@@ -0,0 +1,131 @@
+package lessgo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 预热钩子
+type WarmupHook struct {
+	Func    func(ctx context.Context) error
+	Timeout time.Duration // 超时，默认30秒；超时后ctx被取消，不再等待该钩子
+}
+
+type warmupHook struct {
+	name string
+	hook WarmupHook
+}
+
+var (
+	warmupHooks     []warmupHook
+	warmupHooksLock sync.Mutex
+	warming         int32
+	warmupOnce      sync.Once
+)
+
+// 注册预热钩子，同名的将被替换；服务绑定监听端口后按注册顺序依次执行，全部完成前就绪检查失败，
+// 以便负载均衡在缓存填充、连接池建立等完成后再转入流量；钩子失败或超时仅记录日志，不影响启动，如：
+//
+//	lessgo.RegisterWarmup("db:default", lessgo.WarmupHook{Func: func(ctx context.Context) error {
+//		db, err := lessgo.GetDB("default")
+//		if err != nil {
+//			return err
+//		}
+//		return db.PingContext(ctx)
+//	}})
+func RegisterWarmup(name string, hook WarmupHook) {
+	if hook.Timeout <= 0 {
+		hook.Timeout = 30 * time.Second
+	}
+	warmupHooksLock.Lock()
+	defer warmupHooksLock.Unlock()
+	for i := range warmupHooks {
+		if warmupHooks[i].name == name {
+			warmupHooks[i].hook = hook
+			return
+		}
+	}
+	warmupHooks = append(warmupHooks, warmupHook{name: name, hook: hook})
+}
+
+// 移除预热钩子
+func RemoveWarmup(name string) {
+	warmupHooksLock.Lock()
+	defer warmupHooksLock.Unlock()
+	for i := range warmupHooks {
+		if warmupHooks[i].name == name {
+			warmupHooks = append(warmupHooks[:i:i], warmupHooks[i+1:]...)
+			return
+		}
+	}
+}
+
+// 服务是否正在预热，预热时就绪检查失败
+func Warming() bool {
+	return atomic.LoadInt32(&warming) == 1
+}
+
+// 标记服务正在预热，并返回包装监听器的函数：监听套接字绑定后开始执行预热钩子，完成后就绪；
+// wrap不为nil时继续用于包装监听器
+func warmupOnListen(wrap func(net.Listener) net.Listener) func(net.Listener) net.Listener {
+	atomic.StoreInt32(&warming, 1)
+	return func(ln net.Listener) net.Listener {
+		warmupOnce.Do(func() {
+			go runWarmups()
+		})
+		if wrap != nil {
+			ln = wrap(ln)
+		}
+		return ln
+	}
+}
+
+// 依次执行预热钩子，然后标记就绪
+func runWarmups() {
+	warmupHooksLock.Lock()
+	hooks := append([]warmupHook(nil), warmupHooks...)
+	warmupHooksLock.Unlock()
+	if len(hooks) > 0 {
+		start := time.Now()
+		Log.Sys("> Warming up with %d hooks, readiness check fails until done", len(hooks))
+		var failed int
+		for _, h := range hooks {
+			if runWarmup(h) != nil {
+				failed++
+			}
+		}
+		Log.Sys("> Warmup done in %v (%d failed)", time.Since(start), failed)
+	}
+	atomic.StoreInt32(&warming, 0)
+}
+
+// 执行单个预热钩子，超时后不再等待
+func runWarmup(h warmupHook) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.hook.Timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				done <- fmt.Errorf("panic: %v", rcv)
+			}
+		}()
+		done <- h.hook.Func(ctx)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %v", h.hook.Timeout)
+	}
+	if err != nil {
+		Log.Error("Warmup %s failed in %v: %v", h.name, time.Since(start), err)
+	} else {
+		Log.Sys("| warmup %-20s | %v", h.name, time.Since(start))
+	}
+	return err
+}
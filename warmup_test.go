@@ -0,0 +1,83 @@
+package lessgo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWarmup(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	hook := func(name string, err error) WarmupHook {
+		return WarmupHook{Func: func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return err
+		}}
+	}
+	RegisterWarmup("test:cache", hook("old", nil))
+	RegisterWarmup("test:slow", WarmupHook{Func: func(ctx context.Context) error {
+		time.Sleep(time.Second) // 忽略ctx的钩子在超时后不再等待
+		return nil
+	}, Timeout: 20 * time.Millisecond})
+	RegisterWarmup("test:panic", WarmupHook{Func: func(ctx context.Context) error { panic("boom") }})
+	RegisterWarmup("test:pool", hook("pool", errors.New("refused")))
+	RegisterWarmup("test:cache", hook("cache", nil))
+	RemoveWarmup("test:panic")
+	defer func() {
+		RemoveWarmup("test:cache")
+		RemoveWarmup("test:slow")
+		RemoveWarmup("test:pool")
+	}()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var wrapped bool
+	wrap := warmupOnListen(func(ln net.Listener) net.Listener {
+		wrapped = true
+		return ln
+	})
+	if !Warming() {
+		t.Fatal("not warming before the listener is bound")
+	}
+	req, _ := http.NewRequest(GET, "/", nil)
+	c, rec := testContext(req)
+	if err = ReadyzHandler.Handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"warming"`) {
+		t.Errorf("readyz while warming = %d %s", rec.Code, rec.Body.String())
+	}
+
+	start := time.Now()
+	if wrap(ln) != ln || !wrapped {
+		t.Error("listener not wrapped")
+	}
+	for Warming() {
+		if time.Since(start) > 500*time.Millisecond {
+			t.Fatal("warmup did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(order, ",") != "cache,pool" {
+		t.Errorf("hooks run: %q", order)
+	}
+
+	if err = runWarmup(warmupHook{"test:panic", WarmupHook{Func: func(ctx context.Context) error { panic("boom") }, Timeout: time.Second}}); err == nil || err.Error() != "panic: boom" {
+		t.Errorf("panicking hook: %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+package lessgo
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lessgo/lessgo/utils"
+)
+
+type (
+	// Webhook投递选项
+	WebhookOptions struct {
+		Workers     int           // 并发投递数
+		QueueSize   int           // 待投递队列长度，队列满时Emit返回错误
+		Timeout     time.Duration // 单次投递超时
+		MaxAttempts int           // 最大尝试次数，超出后转入死信
+		BaseDelay   time.Duration // 首次重试间隔，之后按指数增长
+		MaxDelay    time.Duration // 重试间隔上限
+		History     int           // 保留的已完成投递记录数
+		// Stop时仍未完成的投递(待投递或等待重试)会被丢弃，可通过该回调持久化后重新Emit
+		OnStop func(unfinished []WebhookDelivery)
+	}
+
+	// Webhook订阅端点
+	WebhookEndpoint struct {
+		ID     string   `json:"id"`
+		URL    string   `json:"url"`
+		Secret string   `json:"-"`
+		Events []string `json:"events"` // 订阅的事件，"*"表示全部
+	}
+
+	// 一次Webhook投递
+	WebhookDelivery struct {
+		ID          string          `json:"id"`
+		Event       string          `json:"event"`
+		EndpointID  string          `json:"endpoint_id"`
+		URL         string          `json:"url"`
+		Payload     json.RawMessage `json:"payload"`
+		Status      string          `json:"status"`
+		Attempts    int             `json:"attempts"`
+		LastStatus  int             `json:"last_status,omitempty"`
+		LastError   string          `json:"last_error,omitempty"`
+		NextAttempt time.Time       `json:"next_attempt,omitempty"`
+		CreatedAt   time.Time       `json:"created_at"`
+		UpdatedAt   time.Time       `json:"updated_at"`
+
+		secret string
+		timer  *time.Timer // 等待重试的定时器
+	}
+
+	// Webhook投递器
+	WebhookDispatcher struct {
+		opts       WebhookOptions
+		client     *http.Client
+		events     map[string]string // 事件名 -> 描述
+		endpoints  map[string]*WebhookEndpoint
+		deliveries map[string]*WebhookDelivery
+		finished   []string // 已完成投递的id，按完成先后
+		queue      chan *WebhookDelivery
+		stop       chan struct{}
+		drainUntil time.Time // Stop后继续投递队列中剩余投递的截止时间
+		wg         sync.WaitGroup
+		started    bool
+		stopped    bool
+		lock       sync.RWMutex
+	}
+
+	webhookDeliveries []*WebhookDelivery
+)
+
+// Webhook投递状态
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookRetrying  = "retrying"
+	WebhookDead      = "dead"
+)
+
+// Webhook请求头，签名为 "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))，
+// 可直接由HMAC签名验证中间件的"body"方案校验
+const (
+	HeaderXWebhookID        = "X-Webhook-Id"
+	HeaderXWebhookEvent     = "X-Webhook-Event"
+	HeaderXWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderXWebhookSignature = "X-Webhook-Signature"
+)
+
+var ErrWebhookQueueFull = errors.New("webhook queue is full")
+
+// 全局Webhook投递器
+var Webhooks = NewWebhookDispatcher(WebhookOptions{})
+
+// 创建Webhook投递器，未设置的选项取默认值
+func NewWebhookDispatcher(opts WebhookOptions) *WebhookDispatcher {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = time.Hour
+	}
+	if opts.History <= 0 {
+		opts.History = 1000
+	}
+	return &WebhookDispatcher{
+		opts:       opts,
+		client:     &http.Client{Timeout: opts.Timeout},
+		events:     map[string]string{},
+		endpoints:  map[string]*WebhookEndpoint{},
+		deliveries: map[string]*WebhookDelivery{},
+		queue:      make(chan *WebhookDelivery, opts.QueueSize),
+		stop:       make(chan struct{}),
+	}
+}
+
+// 注册事件类型
+func (d *WebhookDispatcher) RegisterEvent(event, desc string) {
+	d.lock.Lock()
+	d.events[event] = desc
+	d.lock.Unlock()
+}
+
+// 返回已注册的事件类型及描述
+func (d *WebhookDispatcher) Events() map[string]string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	events := make(map[string]string, len(d.events))
+	for k, v := range d.events {
+		events[k] = v
+	}
+	return events
+}
+
+// 添加或替换订阅端点
+func (d *WebhookDispatcher) AddEndpoint(ep WebhookEndpoint) error {
+	if ep.ID == "" || ep.URL == "" || ep.Secret == "" {
+		return errors.New("webhook endpoint requires ID, URL and Secret")
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, e := range ep.Events {
+		if _, ok := d.events[e]; !ok && e != "*" {
+			return fmt.Errorf("webhook event %q is not registered", e)
+		}
+	}
+	d.endpoints[ep.ID] = &ep
+	return nil
+}
+
+// 移除订阅端点，已入队的投递不受影响
+func (d *WebhookDispatcher) RemoveEndpoint(id string) {
+	d.lock.Lock()
+	delete(d.endpoints, id)
+	d.lock.Unlock()
+}
+
+// 触发事件，为每个订阅该事件的端点创建一次投递
+func (d *WebhookDispatcher) Emit(event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	if _, ok := d.events[event]; !ok {
+		d.lock.Unlock()
+		return fmt.Errorf("webhook event %q is not registered", event)
+	}
+	if d.stopped {
+		d.lock.Unlock()
+		return errors.New("webhook dispatcher is stopped")
+	}
+	d.start()
+	var list []*WebhookDelivery
+	now := time.Now()
+	for _, ep := range d.endpoints {
+		if !webhookSubscribed(ep, event) {
+			continue
+		}
+		dl := &WebhookDelivery{
+			ID:         string(utils.RandomCreateBytes(16)),
+			Event:      event,
+			EndpointID: ep.ID,
+			URL:        ep.URL,
+			Payload:    body,
+			Status:     WebhookPending,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			secret:     ep.Secret,
+		}
+		d.deliveries[dl.ID] = dl
+		list = append(list, dl)
+	}
+	d.lock.Unlock()
+
+	for _, dl := range list {
+		if !d.enqueue(dl) {
+			err = ErrWebhookQueueFull
+		}
+	}
+	return err
+}
+
+// 查询投递记录，status为空时返回全部，按创建时间倒序
+func (d *WebhookDispatcher) Deliveries(status string) []WebhookDelivery {
+	d.lock.RLock()
+	list := make(webhookDeliveries, 0, len(d.deliveries))
+	for _, dl := range d.deliveries {
+		if status == "" || dl.Status == status {
+			list = append(list, dl)
+		}
+	}
+	sort.Sort(list)
+	result := make([]WebhookDelivery, len(list))
+	for i, dl := range list {
+		result[i] = *dl
+	}
+	d.lock.RUnlock()
+	return result
+}
+
+// 重新投递一条死信
+func (d *WebhookDispatcher) Retry(id string) error {
+	d.lock.Lock()
+	dl := d.deliveries[id]
+	if dl == nil {
+		d.lock.Unlock()
+		return fmt.Errorf("webhook delivery %q not found", id)
+	}
+	if dl.Status != WebhookDead {
+		d.lock.Unlock()
+		return fmt.Errorf("webhook delivery %q is %s, only dead deliveries can be retried", id, dl.Status)
+	}
+	if d.stopped {
+		d.lock.Unlock()
+		return errors.New("webhook dispatcher is stopped")
+	}
+	d.start()
+	dl.Status = WebhookPending
+	dl.Attempts = 0
+	dl.NextAttempt = time.Time{}
+	dl.UpdatedAt = time.Now()
+	d.lock.Unlock()
+	if !d.enqueue(dl) {
+		return ErrWebhookQueueFull
+	}
+	return nil
+}
+
+// 停止投递，在timeout内继续投递队列中剩余的投递并取消等待中的重试，
+// 返回尚未完成的投递数，这些投递会传给OnStop回调
+func (d *WebhookDispatcher) Stop(timeout time.Duration) int {
+	d.lock.Lock()
+	if d.stopped {
+		d.lock.Unlock()
+		return 0
+	}
+	d.stopped = true
+	d.drainUntil = time.Now().Add(timeout)
+	for _, dl := range d.deliveries {
+		if dl.timer != nil {
+			dl.timer.Stop()
+			dl.timer = nil
+		}
+	}
+	close(d.stop)
+	d.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	d.lock.RLock()
+	var unfinished webhookDeliveries
+	for _, dl := range d.deliveries {
+		if dl.Status == WebhookPending || dl.Status == WebhookRetrying {
+			unfinished = append(unfinished, dl)
+		}
+	}
+	sort.Sort(unfinished)
+	result := make([]WebhookDelivery, len(unfinished))
+	for i, dl := range unfinished {
+		result[i] = *dl
+		result[i].timer = nil
+	}
+	d.lock.RUnlock()
+	if len(result) > 0 && d.opts.OnStop != nil {
+		d.opts.OnStop(result)
+	}
+	return len(result)
+}
+
+// 首次使用时启动投递协程，调用者需持有写锁
+func (d *WebhookDispatcher) start() {
+	if d.started {
+		return
+	}
+	d.started = true
+	for i := 0; i < d.opts.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	app.onShutdown(func() {
+		if n := d.Stop(5 * time.Second); n > 0 {
+			Log.Warn("Webhook: %d deliveries were not completed before shutdown", n)
+		}
+	})
+}
+
+func (d *WebhookDispatcher) enqueue(dl *WebhookDelivery) bool {
+	select {
+	case d.queue <- dl:
+		return true
+	default:
+		d.lock.Lock()
+		dl.Status = WebhookDead
+		dl.LastError = ErrWebhookQueueFull.Error()
+		dl.UpdatedAt = time.Now()
+		d.finish(dl)
+		d.lock.Unlock()
+		return false
+	}
+}
+
+func (d *WebhookDispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.stop:
+			d.drain()
+			return
+		case dl := <-d.queue:
+			d.deliver(dl)
+		}
+	}
+}
+
+// 停止后在截止时间内投递队列中剩余的投递
+func (d *WebhookDispatcher) drain() {
+	for {
+		d.lock.RLock()
+		expired := time.Now().After(d.drainUntil)
+		d.lock.RUnlock()
+		if expired {
+			return
+		}
+		select {
+		case dl := <-d.queue:
+			d.deliver(dl)
+		default:
+			return
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliver(dl *WebhookDelivery) {
+	d.lock.RLock()
+	event, url, secret, payload := dl.Event, dl.URL, dl.secret, dl.Payload
+	d.lock.RUnlock()
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(POST, url, bytes.NewReader(payload))
+	var code int
+	if err == nil {
+		mac := hmac.New(sha256.New, []byte(secret))
+		io.WriteString(mac, ts+".")
+		mac.Write(payload)
+		req.Header.Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+		req.Header.Set(HeaderXWebhookID, dl.ID)
+		req.Header.Set(HeaderXWebhookEvent, event)
+		req.Header.Set(HeaderXWebhookTimestamp, ts)
+		req.Header.Set(HeaderXWebhookSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		var resp *http.Response
+		if resp, err = d.client.Do(req); err == nil {
+			code = resp.StatusCode
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			if code < 200 || code > 299 {
+				err = fmt.Errorf("unexpected status %d", code)
+			}
+		}
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	dl.Attempts++
+	dl.LastStatus = code
+	dl.NextAttempt = time.Time{}
+	dl.UpdatedAt = time.Now()
+	if err == nil {
+		dl.Status = WebhookDelivered
+		dl.LastError = ""
+		d.finish(dl)
+		return
+	}
+	dl.LastError = err.Error()
+	if dl.Attempts >= d.opts.MaxAttempts {
+		dl.Status = WebhookDead
+		Log.Warn("Webhook: delivery %s of %s to %s is dead: %v", dl.ID, event, url, err)
+		d.finish(dl)
+		return
+	}
+	delay := d.opts.BaseDelay << uint(dl.Attempts-1)
+	if delay > d.opts.MaxDelay || delay <= 0 {
+		delay = d.opts.MaxDelay
+	}
+	dl.Status = WebhookRetrying
+	dl.NextAttempt = dl.UpdatedAt.Add(delay)
+	if d.stopped {
+		return
+	}
+	dl.timer = time.AfterFunc(delay, func() {
+		d.lock.Lock()
+		if d.stopped || dl.timer == nil {
+			d.lock.Unlock()
+			return
+		}
+		dl.timer = nil
+		d.lock.Unlock()
+		d.enqueue(dl)
+	})
+}
+
+// 记录已完成的投递并清理超出保留数量的记录，死信不会被清理，调用者需持有写锁
+func (d *WebhookDispatcher) finish(dl *WebhookDelivery) {
+	if dl.Status == WebhookDead {
+		return
+	}
+	d.finished = append(d.finished, dl.ID)
+	for len(d.finished) > d.opts.History {
+		delete(d.deliveries, d.finished[0])
+		d.finished = d.finished[1:]
+	}
+}
+
+func webhookSubscribed(ep *WebhookEndpoint, event string) bool {
+	for _, e := range ep.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (l webhookDeliveries) Len() int           { return len(l) }
+func (l webhookDeliveries) Less(i, j int) bool { return l[i].CreatedAt.After(l[j].CreatedAt) }
+func (l webhookDeliveries) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// 查询Webhook投递状态或重新投递死信的操作，供后台管理路由使用
+var WebhookHandler = ApiHandler{
+	Desc:   "查询Webhook投递状态或重新投递死信",
+	Method: "GET|POST",
+	Params: []Param{
+		{"status", "query", false, "", "GET时按状态过滤：pending、retrying、delivered、dead"},
+		{"id", "formData", false, "", "POST时需要重新投递的投递id"},
+	},
+	Handler: func(c *Context) error {
+		if c.request.Method == POST {
+			if err := Webhooks.Retry(c.FormParam("id")); err != nil {
+				return NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"events":     Webhooks.Events(),
+			"deliveries": Webhooks.Deliveries(c.QueryParam("status")),
+		})
+	},
+}.Reg()
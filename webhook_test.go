@@ -0,0 +1,120 @@
+package lessgo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		fail   = true
+		bodies = make(chan string, 16)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(r.Header.Get(HeaderXWebhookTimestamp) + "."))
+		mac.Write(body)
+		if r.Header.Get(HeaderXWebhookSignature) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("bad signature %q", r.Header.Get(HeaderXWebhookSignature))
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		bodies <- string(body)
+	}))
+	defer srv.Close()
+
+	d := NewWebhookDispatcher(WebhookOptions{Workers: 1, MaxAttempts: 3, BaseDelay: 20 * time.Millisecond})
+	defer d.Stop(time.Second)
+	d.RegisterEvent("order.paid", "订单已支付")
+	if err := d.AddEndpoint(WebhookEndpoint{ID: "a", URL: srv.URL, Events: []string{"*"}}); err == nil {
+		t.Fatal("endpoint without secret should be rejected")
+	}
+	if err := d.AddEndpoint(WebhookEndpoint{ID: "a", URL: srv.URL, Secret: "s3cret", Events: []string{"*"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Emit("order.paid", map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次失败后按BaseDelay退避
+	var dl WebhookDelivery
+	waitWebhook(t, func() bool {
+		list := d.Deliveries("")
+		dl = list[0]
+		return dl.Attempts >= 1
+	})
+	if dl.Status == WebhookRetrying {
+		if delay := dl.NextAttempt.Sub(dl.UpdatedAt); delay != 20*time.Millisecond {
+			t.Errorf("first retry delay = %v, want 20ms", delay)
+		}
+		if err := d.Retry(dl.ID); err == nil {
+			t.Error("retrying a delivery that is waiting for its retry timer should fail")
+		}
+	}
+
+	// 达到MaxAttempts后转入死信
+	waitWebhook(t, func() bool { return len(d.Deliveries(WebhookDead)) == 1 })
+	dl = d.Deliveries(WebhookDead)[0]
+	if dl.Attempts != 3 || dl.LastStatus != http.StatusInternalServerError {
+		t.Errorf("dead delivery attempts = %d, last status = %d", dl.Attempts, dl.LastStatus)
+	}
+
+	// 重新投递死信
+	lock.Lock()
+	fail = false
+	lock.Unlock()
+	if err := d.Retry(dl.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case body := <-bodies:
+		if body != `{"id":1}` {
+			t.Errorf("delivered body = %s", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dead delivery was not redelivered")
+	}
+	waitWebhook(t, func() bool { return len(d.Deliveries(WebhookDelivered)) == 1 })
+	if err := d.Retry(dl.ID); err == nil {
+		t.Error("retrying a delivered delivery should fail")
+	}
+}
+
+func TestWebhookStop(t *testing.T) {
+	var unfinished []WebhookDelivery
+	d := NewWebhookDispatcher(WebhookOptions{
+		Workers:   1,
+		BaseDelay: time.Hour,
+		OnStop:    func(list []WebhookDelivery) { unfinished = list },
+	})
+	d.RegisterEvent("ping", "")
+	d.AddEndpoint(WebhookEndpoint{ID: "a", URL: "http://127.0.0.1:1/", Secret: "x", Events: []string{"ping"}})
+	d.Emit("ping", nil)
+	waitWebhook(t, func() bool { return len(d.Deliveries(WebhookRetrying)) == 1 })
+	if n := d.Stop(time.Second); n != 1 || len(unfinished) != 1 || unfinished[0].Status != WebhookRetrying {
+		t.Errorf("Stop() = %d, unfinished = %v", n, unfinished)
+	}
+}
+
+func waitWebhook(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for webhook delivery")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,372 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what happens when a client's send queue is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the message being queued.
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest
+	// CloseSlow closes the connection of the slow client.
+	CloseSlow
+)
+
+var (
+	// ErrClientNotFound is returned by Hub.Send when no client has the given id
+	// and there is no broker to forward the message to.
+	ErrClientNotFound = errors.New("websocket: client not found")
+	// ErrHubClosed is returned by Hub.Register after the hub has been closed.
+	ErrHubClosed = errors.New("websocket: hub closed")
+)
+
+// Broker fans hub messages out to other instances, e.g. over Redis pub/sub.
+type Broker interface {
+	// Publish sends data to every subscribed instance, including this one.
+	Publish(data []byte) error
+	// Subscribe calls fn for every published message until Close.
+	Subscribe(fn func(data []byte)) error
+	Close() error
+}
+
+// Hub keeps track of connected clients and the rooms they joined.
+type Hub struct {
+	queueSize int
+	policy    OverflowPolicy
+
+	node    string
+	broker  Broker
+	clients map[string]map[*Client]struct{}
+	rooms   map[string]map[*Client]struct{}
+	closed  bool
+	mu      sync.RWMutex
+
+	dropped uint64
+}
+
+// Client is a connection registered in a Hub.
+type Client struct {
+	ID   string
+	Conn *Conn
+
+	hub   *Hub
+	send  chan []byte
+	rooms map[string]struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	// closed by Hub.Close to make writeLoop flush the queue and exit
+	flush     chan struct{}
+	flushOnce sync.Once
+}
+
+// hubMessage is the envelope published through the broker.
+type hubMessage struct {
+	Node string `json:"node"`
+	Room string `json:"room,omitempty"`
+	To   string `json:"to,omitempty"`
+	Data []byte `json:"data"`
+}
+
+// NewHub returns a Hub whose clients each have a send queue of queueSize
+// messages handled by policy when full.
+func NewHub(queueSize int, policy OverflowPolicy) *Hub {
+	if queueSize <= 0 {
+		queueSize = 256
+	}
+	node := make([]byte, 8)
+	rand.Read(node)
+	return &Hub{
+		queueSize: queueSize,
+		policy:    policy,
+		node:      hex.EncodeToString(node),
+		clients:   map[string]map[*Client]struct{}{},
+		rooms:     map[string]map[*Client]struct{}{},
+	}
+}
+
+// SetBroker enables multi-instance fan-out through b.
+func (h *Hub) SetBroker(b Broker) error {
+	if err := b.Subscribe(h.receive); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	old := h.broker
+	h.broker = b
+	h.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Register adds ws to the hub under id and starts its writer goroutine.
+// Several connections may share one id, e.g. a user with multiple tabs.
+// It returns ErrHubClosed, leaving ws open, once the hub has been closed.
+func (h *Hub) Register(id string, ws *Conn) (*Client, error) {
+	c := &Client{
+		ID:    id,
+		Conn:  ws,
+		hub:   h,
+		send:  make(chan []byte, h.queueSize),
+		rooms: map[string]struct{}{},
+		done:  make(chan struct{}),
+		flush: make(chan struct{}),
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrHubClosed
+	}
+	if h.clients[id] == nil {
+		h.clients[id] = map[*Client]struct{}{}
+	}
+	h.clients[id][c] = struct{}{}
+	h.mu.Unlock()
+	go c.writeLoop()
+	return c, nil
+}
+
+// Broadcast delivers msg to every member of room on all instances.
+func (h *Hub) Broadcast(room string, msg []byte) error {
+	return h.dispatch(&hubMessage{Room: room, Data: msg})
+}
+
+// Send delivers msg to every connection registered under id on all instances.
+func (h *Hub) Send(id string, msg []byte) error {
+	return h.dispatch(&hubMessage{To: id, Data: msg})
+}
+
+// Rooms returns the names of the rooms that have members on this instance.
+func (h *Hub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for r := range h.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+// Members returns the clients of room on this instance.
+func (h *Hub) Members(room string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	members := make([]*Client, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		members = append(members, c)
+	}
+	return members
+}
+
+// Dropped returns the number of messages discarded by the overflow policy.
+func (h *Hub) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Close stops accepting new clients, writes out the messages already queued
+// for every client, then closes the clients and the broker.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	var all []*Client
+	for _, set := range h.clients {
+		for c := range set {
+			all = append(all, c)
+		}
+	}
+	b := h.broker
+	h.broker = nil
+	h.mu.Unlock()
+	for _, c := range all {
+		c.flushOnce.Do(func() { close(c.flush) })
+	}
+	for _, c := range all {
+		<-c.done
+	}
+	if b != nil {
+		return b.Close()
+	}
+	return nil
+}
+
+func (h *Hub) dispatch(m *hubMessage) error {
+	h.mu.RLock()
+	b := h.broker
+	h.mu.RUnlock()
+	if b == nil {
+		if !h.deliver(m) && m.To != "" {
+			return ErrClientNotFound
+		}
+		return nil
+	}
+	m.Node = h.node
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	// local delivery happens here; the broker echo from this node is ignored
+	h.deliver(m)
+	return b.Publish(data)
+}
+
+func (h *Hub) receive(data []byte) {
+	var m hubMessage
+	if err := json.Unmarshal(data, &m); err != nil || m.Node == h.node {
+		return
+	}
+	h.deliver(&m)
+}
+
+// deliver queues m on the matching local clients and reports whether any matched.
+func (h *Hub) deliver(m *hubMessage) bool {
+	h.mu.RLock()
+	var targets []*Client
+	set := h.rooms[m.Room]
+	if m.To != "" {
+		set = h.clients[m.To]
+	}
+	for c := range set {
+		targets = append(targets, c)
+	}
+	h.mu.RUnlock()
+	for _, c := range targets {
+		c.enqueue(m.Data)
+	}
+	return len(targets) > 0
+}
+
+// Join adds the client to room.
+func (c *Client) Join(room string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = map[*Client]struct{}{}
+	}
+	h.rooms[room][c] = struct{}{}
+	c.rooms[room] = struct{}{}
+}
+
+// Leave removes the client from room.
+func (c *Client) Leave(room string) {
+	h := c.hub
+	h.mu.Lock()
+	c.leave(room)
+	h.mu.Unlock()
+}
+
+func (c *Client) leave(room string) {
+	h := c.hub
+	delete(c.rooms, room)
+	if set := h.rooms[room]; set != nil {
+		delete(set, c)
+		if len(set) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Send queues msg for this connection only.
+func (c *Client) Send(msg []byte) {
+	c.enqueue(msg)
+}
+
+// Done is closed when the client has been closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close unregisters the client from the hub and closes its connection.
+func (c *Client) Close() error {
+	var err error
+	c.once.Do(func() {
+		h := c.hub
+		h.mu.Lock()
+		close(c.done)
+		for room := range c.rooms {
+			c.leave(room)
+		}
+		if set := h.clients[c.ID]; set != nil {
+			delete(set, c)
+			if len(set) == 0 {
+				delete(h.clients, c.ID)
+			}
+		}
+		h.mu.Unlock()
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+func (c *Client) enqueue(msg []byte) {
+	select {
+	case <-c.done:
+		return
+	case c.send <- msg:
+		return
+	default:
+	}
+	switch c.hub.policy {
+	case DropOldest:
+		for {
+			select {
+			case <-c.send:
+				atomic.AddUint64(&c.hub.dropped, 1)
+			default:
+			}
+			select {
+			case c.send <- msg:
+				return
+			default:
+			}
+		}
+	case CloseSlow:
+		atomic.AddUint64(&c.hub.dropped, 1)
+		go c.Close()
+	default:
+		atomic.AddUint64(&c.hub.dropped, 1)
+	}
+}
+
+func (c *Client) writeLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.flush:
+			// write out what is queued, bounded by the connection's write timeout
+			for {
+				select {
+				case msg := <-c.send:
+					if _, err := c.Conn.Write(msg); err != nil {
+						c.Close()
+						return
+					}
+				default:
+					c.Close()
+					return
+				}
+			}
+		case msg := <-c.send:
+			if _, err := c.Conn.Write(msg); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 返回一对已连接的服务端与客户端连接
+func testConnPair(t *testing.T) (server, client *Conn, cleanup func()) {
+	conns := make(chan *Conn)
+	release := make(chan struct{})
+	ts := httptest.NewServer(Handler(func(ws *Conn) {
+		conns <- ws
+		<-release
+	}))
+	client, err := Dial(strings.Replace(ts.URL, "http://", "ws://", 1), "", "http://localhost/")
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	server = <-conns
+	return server, client, func() {
+		client.Close()
+		close(release)
+		ts.Close()
+	}
+}
+
+// 不启动写协程的客户端，便于检查发送队列
+func testClient(h *Hub, id string, ws *Conn) *Client {
+	c := &Client{
+		ID:    id,
+		Conn:  ws,
+		hub:   h,
+		send:  make(chan []byte, h.queueSize),
+		rooms: map[string]struct{}{},
+		done:  make(chan struct{}),
+		flush: make(chan struct{}),
+	}
+	h.clients[id] = map[*Client]struct{}{c: {}}
+	return c
+}
+
+func TestHubDropOldest(t *testing.T) {
+	h := NewHub(2, DropOldest)
+	c := testClient(h, "u1", nil)
+	for _, msg := range []string{"1", "2", "3"} {
+		c.Send([]byte(msg))
+	}
+	if got := string(<-c.send) + string(<-c.send); got != "23" {
+		t.Errorf("queued %q, want %q", got, "23")
+	}
+	if h.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", h.Dropped())
+	}
+}
+
+func TestHubCloseSlow(t *testing.T) {
+	server, _, cleanup := testConnPair(t)
+	defer cleanup()
+	h := NewHub(1, CloseSlow)
+	c := testClient(h, "u1", server)
+	c.Send([]byte("1"))
+	c.Send([]byte("2"))
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("slow client was not closed")
+	}
+	if h.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", h.Dropped())
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.clients) != 0 {
+		t.Errorf("closed client is still registered")
+	}
+}
+
+type testBroker struct {
+	published [][]byte
+	fn        func(data []byte)
+}
+
+func (b *testBroker) Publish(data []byte) error {
+	b.published = append(b.published, data)
+	return nil
+}
+
+func (b *testBroker) Subscribe(fn func(data []byte)) error {
+	b.fn = fn
+	return nil
+}
+
+func (b *testBroker) Close() error { return nil }
+
+func TestHubReceive(t *testing.T) {
+	h := NewHub(8, DropNewest)
+	b := &testBroker{}
+	if err := h.SetBroker(b); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient(h, "u1", nil)
+	c.Join("room")
+	if err := h.Broadcast("room", []byte("local")); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.published) != 1 || len(c.send) != 1 {
+		t.Fatalf("published %d, queued %d; want 1, 1", len(b.published), len(c.send))
+	}
+	// 本节点发布的消息经broker回传时忽略
+	b.fn(b.published[0])
+	if len(c.send) != 1 {
+		t.Fatalf("echo of a local message was delivered again")
+	}
+	remote, _ := json.Marshal(&hubMessage{Node: "other", Room: "room", Data: []byte("remote")})
+	b.fn(remote)
+	if got := string(<-c.send) + "," + string(<-c.send); got != "local,remote" {
+		t.Errorf("delivered %q, want %q", got, "local,remote")
+	}
+}
+
+func TestHubCloseFlush(t *testing.T) {
+	server, client, cleanup := testConnPair(t)
+	defer cleanup()
+	h := NewHub(8, DropNewest)
+	c, err := h.Register("u1", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 写协程可能尚未开始处理，Close须写出已排队的消息
+	c.Send([]byte("a"))
+	c.Send([]byte("b"))
+	h.Close()
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"a", "b"} {
+		var got string
+		if err := Message.Receive(client, &got); err != nil || got != want {
+			t.Fatalf("Receive() = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := h.Register("u2", server); err != ErrHubClosed {
+		t.Errorf("Register after Close: err = %v, want ErrHubClosed", err)
+	}
+}
@@ -291,7 +291,7 @@ func (handler *hybiFrameHandler) HandleFrame(frame frameReader) (frameReader, er
 	case PingFrame:
 		pingMsg := make([]byte, maxControlFramePayloadLength)
 		n, err := io.ReadFull(frame, pingMsg)
-		if err != nil && err != io.ErrUnexpectedEOF {
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			return nil, err
 		}
 		io.Copy(ioutil.Discard, frame)
@@ -301,7 +301,8 @@ func (handler *hybiFrameHandler) HandleFrame(frame frameReader) (frameReader, er
 		}
 		return nil, nil
 	case PongFrame:
-		return nil, ErrNotImplemented
+		io.Copy(ioutil.Discard, frame)
+		return nil, nil
 	}
 	return frame, nil
 }
@@ -344,7 +345,8 @@ func newHybiConn(config *Config, buf *bufio.ReadWriter, rwc io.ReadWriteCloser,
 		frameWriterFactory: hybiFrameWriterFactory{
 			buf.Writer, request == nil},
 		PayloadType:        TextFrame,
-		defaultCloseStatus: closeStatusNormal}
+		defaultCloseStatus: closeStatusNormal,
+		closed:             make(chan struct{})}
 	ws.frameHandler = &hybiFrameHandler{conn: ws}
 	return ws
 }
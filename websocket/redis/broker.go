@@ -0,0 +1,125 @@
+// Package redis implements a websocket.Broker over Redis pub/sub, so that a
+// websocket.Hub can broadcast across several instances.
+//
+// depend on github.com/garyburd/redigo/redis
+//
+// Usage:
+//
+//	hub := websocket.NewHub(256, websocket.DropOldest)
+//	broker, err := redis.NewBroker("127.0.0.1:6379", "", "lessgo:ws")
+//	if err == nil {
+//		err = hub.SetBroker(broker)
+//	}
+package redis
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Broker publishes and receives hub messages on one Redis channel.
+type Broker struct {
+	pool    *redis.Pool
+	channel string
+	psc     *redis.PubSubConn
+	closed  bool
+	mu      sync.Mutex
+}
+
+// NewBroker connects to the Redis server at addr and uses channel for fan-out.
+func NewBroker(addr, password, channel string) (*Broker, error) {
+	pool := &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 180 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("tcp", addr)
+			if err != nil {
+				return nil, err
+			}
+			if password != "" {
+				if _, err := c.Do("AUTH", password); err != nil {
+					c.Close()
+					return nil, err
+				}
+			}
+			return c, nil
+		},
+	}
+	c := pool.Get()
+	defer c.Close()
+	if _, err := c.Do("PING"); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &Broker{pool: pool, channel: channel}, nil
+}
+
+// Publish implements websocket.Broker.
+func (b *Broker) Publish(data []byte) error {
+	c := b.pool.Get()
+	defer c.Close()
+	_, err := c.Do("PUBLISH", b.channel, data)
+	return err
+}
+
+// Subscribe implements websocket.Broker. The subscription is re-established
+// after connection errors until Close is called.
+func (b *Broker) Subscribe(fn func(data []byte)) error {
+	psc, err := b.subscribe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				fn(v.Data)
+			case error:
+				psc.Close()
+				for {
+					b.mu.Lock()
+					closed := b.closed
+					b.mu.Unlock()
+					if closed {
+						return
+					}
+					if psc, err = b.subscribe(); err == nil {
+						break
+					}
+					time.Sleep(time.Second)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *Broker) subscribe() (*redis.PubSubConn, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errors.New("redis broker: closed")
+	}
+	psc := &redis.PubSubConn{Conn: b.pool.Get()}
+	if err := psc.Subscribe(b.channel); err != nil {
+		psc.Close()
+		return nil, err
+	}
+	b.psc = psc
+	return psc, nil
+}
+
+// Close implements websocket.Broker.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	if b.psc != nil {
+		b.psc.Unsubscribe()
+		b.psc.Close()
+	}
+	b.mu.Unlock()
+	return b.pool.Close()
+}
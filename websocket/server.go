@@ -86,6 +86,8 @@ func (s Server) serveWebSocket(w http.ResponseWriter, req *http.Request) {
 	if conn == nil {
 		panic("unexpected nil conn")
 	}
+	track(conn)
+	defer untrack(conn)
 	s.Handler(conn)
 }
 
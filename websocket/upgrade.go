@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Upgrader holds the options used by Upgrade.
+type Upgrader struct {
+	// Handshake is an optional function in WebSocket handshake.
+	// If nil, the Origin header is checked as Handler does.
+	Handshake func(*Config, *http.Request) error
+
+	// ReadTimeout limits the wait for the next frame, including pongs.
+	// It should be longer than PingInterval. Zero means no timeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout limits each frame write. Zero means no timeout.
+	WriteTimeout time.Duration
+
+	// PingInterval is the interval between server pings. Zero disables pings.
+	PingInterval time.Duration
+}
+
+var conns = struct {
+	m map[*Conn]struct{}
+	sync.Mutex
+}{m: map[*Conn]struct{}{}}
+
+// Upgrade hijacks the HTTP connection and performs the WebSocket handshake.
+// Unlike Handler, the caller owns the returned connection and must Close it.
+func Upgrade(w http.ResponseWriter, req *http.Request, u *Upgrader) (*Conn, error) {
+	if u == nil {
+		u = &Upgrader{}
+	}
+	handshake := u.Handshake
+	if handshake == nil {
+		handshake = checkOrigin
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	rwc, buf, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := newServerConn(rwc, buf, req, &Config{}, handshake)
+	if err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	conn.readTimeout = u.ReadTimeout
+	conn.writeTimeout = u.WriteTimeout
+	track(conn)
+	if u.PingInterval > 0 {
+		go conn.keepAlive(u.PingInterval)
+	}
+	return conn, nil
+}
+
+// keepAlive pings the peer until the connection is closed or a ping fails.
+func (ws *Conn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.closed:
+			return
+		case <-ticker.C:
+			if err := ws.Ping(nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CloseAll sends a "going away" close frame to every open server connection
+// and closes it. It is meant to be called on server shutdown.
+func CloseAll() int {
+	conns.Lock()
+	list := make([]*Conn, 0, len(conns.m))
+	for c := range conns.m {
+		list = append(list, c)
+	}
+	conns.Unlock()
+	for _, c := range list {
+		c.closeWithStatus(closeStatusGoingAway)
+	}
+	return len(list)
+}
+
+func track(ws *Conn) {
+	conns.Lock()
+	conns.m[ws] = struct{}{}
+	conns.Unlock()
+}
+
+func untrack(ws *Conn) {
+	conns.Lock()
+	delete(conns.m, ws)
+	conns.Unlock()
+}
@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 以Upgrade升级的服务端连接及其客户端连接
+func testUpgrade(t *testing.T, u *Upgrader) (server, client *Conn, cleanup func()) {
+	conns := make(chan *Conn, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := Upgrade(w, r, u)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- ws
+	}))
+	client, err := Dial(strings.Replace(ts.URL, "http://", "ws://", 1), "", "http://localhost/")
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	server = <-conns
+	return server, client, func() {
+		client.Close()
+		server.Close()
+		ts.Close()
+	}
+}
+
+func readString(ws *Conn) (string, error) {
+	buf := make([]byte, 64)
+	n, err := ws.Read(buf)
+	return string(buf[:n]), err
+}
+
+func TestUpgradeKeepAlive(t *testing.T) {
+	// 对端在Read中回复ping，pong使服务端的读取时限不断延后
+	server, client, cleanup := testUpgrade(t, &Upgrader{PingInterval: 20 * time.Millisecond, ReadTimeout: 100 * time.Millisecond})
+	defer cleanup()
+
+	got := make(chan string, 1)
+	go func() {
+		msg, err := readString(client)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- msg
+	}()
+	time.AfterFunc(300*time.Millisecond, func() { client.Write([]byte("hi")) })
+	msg, err := readString(server)
+	if err != nil || msg != "hi" {
+		t.Fatalf("server read %q, %v after pings", msg, err)
+	}
+	if _, err = server.Write([]byte("bye")); err != nil {
+		t.Fatal(err)
+	}
+	if msg = <-got; msg != "bye" {
+		t.Errorf("client read %q through pings", msg)
+	}
+}
+
+func TestUpgradeReadTimeout(t *testing.T) {
+	server, _, cleanup := testUpgrade(t, &Upgrader{ReadTimeout: 50 * time.Millisecond})
+	defer cleanup()
+	start := time.Now()
+	_, err := readString(server)
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("read from an idle peer = %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("read timed out after %v", d)
+	}
+}
+
+func TestUpgradeWriteTimeout(t *testing.T) {
+	server, _, cleanup := testUpgrade(t, &Upgrader{WriteTimeout: 50 * time.Millisecond})
+	defer cleanup()
+	// 对端不读取，写满套接字缓冲区后写入超时
+	chunk := make([]byte, 1<<20)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := server.Write(chunk); err != nil {
+			if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+				t.Fatalf("write error = %v, want a timeout", err)
+			}
+			return
+		}
+	}
+	t.Fatal("write to a stalled peer did not time out")
+}
+
+func TestUpgradePong(t *testing.T) {
+	server, client, cleanup := testUpgrade(t, nil)
+	defer cleanup()
+	// 未经请求的pong应被忽略
+	w, err := client.frameWriterFactory.NewFrameWriter(PongFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("unsolicited"))
+	w.Close()
+	client.Write([]byte("after pong"))
+	if msg, err := readString(server); err != nil || msg != "after pong" {
+		t.Errorf("server read %q, %v", msg, err)
+	}
+}
+
+func TestCloseAll(t *testing.T) {
+	server, client, cleanup := testUpgrade(t, nil)
+	defer cleanup()
+	conns.Lock()
+	_, tracked := conns.m[server]
+	conns.Unlock()
+	if !tracked {
+		t.Fatal("upgraded connection not tracked")
+	}
+	if n := CloseAll(); n < 1 {
+		t.Errorf("CloseAll() = %d", n)
+	}
+	if _, err := readString(client); err != io.EOF {
+		t.Errorf("client read after CloseAll = %v, want EOF", err)
+	}
+	conns.Lock()
+	_, tracked = conns.m[server]
+	conns.Unlock()
+	if tracked {
+		t.Error("closed connection still tracked")
+	}
+	// 再次关闭不应出错或阻塞
+	server.Close()
+}
+
+func TestUpgradeNotHijackable(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := Upgrade(struct{ http.ResponseWriter }{httptest.NewRecorder()}, req, nil); err != ErrNotSupported {
+		t.Errorf("Upgrade on a writer without Hijack = %v", err)
+	}
+}
@@ -161,6 +161,13 @@ type Conn struct {
 	frameHandler
 	PayloadType        byte
 	defaultCloseStatus int
+
+	// read/write timeouts applied before each frame, see Upgrader.
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	closeOnce sync.Once
+	closed    chan struct{}
 }
 
 // Read implements the io.Reader interface:
@@ -173,6 +180,9 @@ func (ws *Conn) Read(msg []byte) (n int, err error) {
 	defer ws.rio.Unlock()
 again:
 	if ws.frameReader == nil {
+		if ws.readTimeout > 0 {
+			ws.SetReadDeadline(time.Now().Add(ws.readTimeout))
+		}
 		frame, err := ws.frameReaderFactory.NewFrameReader()
 		if err != nil {
 			return 0, err
@@ -201,6 +211,9 @@ again:
 func (ws *Conn) Write(msg []byte) (int, error) {
 	ws.wio.Lock()
 	defer ws.wio.Unlock()
+	if ws.writeTimeout > 0 {
+		ws.SetWriteDeadline(time.Now().Add(ws.writeTimeout))
+	}
 	w, err := ws.frameWriterFactory.NewFrameWriter(ws.PayloadType)
 	if err != nil {
 		return 0, err
@@ -215,11 +228,40 @@ func (ws *Conn) Write(msg []byte) (int, error) {
 
 // Close implements the io.Closer interface.
 func (ws *Conn) Close() error {
-	err := ws.frameHandler.WriteClose(ws.defaultCloseStatus)
+	return ws.closeWithStatus(ws.defaultCloseStatus)
+}
+
+// closeWithStatus sends the close frame and closes the underlying connection
+// only once; later calls are no-ops.
+func (ws *Conn) closeWithStatus(status int) (err error) {
+	ws.closeOnce.Do(func() {
+		close(ws.closed)
+		untrack(ws)
+		err = ws.frameHandler.WriteClose(status)
+		if err != nil {
+			ws.rwc.Close()
+			return
+		}
+		err = ws.rwc.Close()
+	})
+	return
+}
+
+// Ping sends a ping frame with the given payload. The peer's pong is
+// consumed by Read transparently.
+func (ws *Conn) Ping(msg []byte) error {
+	ws.wio.Lock()
+	defer ws.wio.Unlock()
+	if ws.writeTimeout > 0 {
+		ws.SetWriteDeadline(time.Now().Add(ws.writeTimeout))
+	}
+	w, err := ws.frameWriterFactory.NewFrameWriter(PingFrame)
 	if err != nil {
 		return err
 	}
-	return ws.rwc.Close()
+	_, err = w.Write(msg)
+	w.Close()
+	return err
 }
 
 func (ws *Conn) IsClientConn() bool { return ws.request == nil }
@@ -0,0 +1,253 @@
+package lessgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	confpkg "github.com/lessgo/lessgo/config"
+)
+
+type (
+	// 工作池配置，对应配置中的[workerpool.<name>]段，如：
+	//
+	//	[workerpool.reports]
+	//	workers = 4
+	//	queue = 20
+	//	queue_timeout = 5s
+	//
+	// 由Bulkhead中间件将路由分配到工作池，池中的路由共享并发额度，与其他路由相互隔离
+	WorkerPoolConfig struct {
+		Workers      int           `default:"10" validate:"min=1"`         // 同时处理的最大请求数
+		Queue        int           `default:"100" validate:"min=0"`        // 等待队列长度，为0时超出并发数立即拒绝
+		QueueTimeout time.Duration `config:"queue_timeout" default:"1s"`   // 排队的最长等待时间，0为不限
+		RejectStatus int           `config:"reject_status" default:"503"`  // 拒绝时返回的状态码，429或503
+		RetryAfter   int           `config:"retry_after" validate:"min=0"` // 拒绝时建议客户端重试的等待秒数，0为不设置
+	}
+
+	// 工作池统计
+	WorkerPoolStats struct {
+		Workers   int   `json:"workers"`
+		Busy      int   `json:"busy"`      // 处理中的请求数
+		Queued    int32 `json:"queued"`    // 排队中的请求数
+		Completed int64 `json:"completed"` // 已处理的请求数
+		Rejected  int64 `json:"rejected"`  // 因队列已满或等待超时被拒绝的请求数
+	}
+
+	workerPool struct {
+		name      string
+		conf      WorkerPoolConfig
+		bulkhead  *bulkhead
+		completed int64
+		rejected  int64
+	}
+
+	// Bulkhead中间件的配置
+	BulkheadConfig struct {
+		Pool string // 工作池名称，见RegisterWorkerPool
+	}
+)
+
+const (
+	// 配置中工作池段名的前缀
+	WORKER_POOL_SECTION_PREFIX = "workerpool."
+
+	// 默认工作池，Bulkhead中间件未指定Pool时使用；
+	// 未以[workerpool.default]配置时按WorkerPoolConfig的默认值注册
+	DEFAULT_WORKER_POOL = "default"
+)
+
+var (
+	workerPools          = map[string]*workerPool{}
+	workerPoolsLock      sync.RWMutex
+	workerPoolConfigOnce sync.Once
+)
+
+// 注册名为name的工作池，同名的工作池将被替换(替换前已进入旧池的请求不受影响)；
+// 配置中[workerpool.<name>]段定义的工作池在首次使用或启动服务时自动注册
+func RegisterWorkerPool(name string, conf WorkerPoolConfig) error {
+	if conf.Workers <= 0 {
+		return fmt.Errorf("Worker pool %s: workers must be positive.", name)
+	}
+	if conf.RejectStatus != http.StatusTooManyRequests {
+		conf.RejectStatus = http.StatusServiceUnavailable
+	}
+	workerPoolsLock.Lock()
+	workerPools[name] = &workerPool{name: name, conf: conf, bulkhead: newBulkhead(conf.Workers)}
+	workerPoolsLock.Unlock()
+	return nil
+}
+
+// 返回各工作池的统计
+func ReadWorkerPoolStats() map[string]WorkerPoolStats {
+	workerPoolsLock.RLock()
+	defer workerPoolsLock.RUnlock()
+	stats := make(map[string]WorkerPoolStats, len(workerPools))
+	for name, p := range workerPools {
+		stats[name] = WorkerPoolStats{
+			Workers:   p.conf.Workers,
+			Busy:      len(p.bulkhead.sem),
+			Queued:    atomic.LoadInt32(&p.bulkhead.waiting),
+			Completed: atomic.LoadInt64(&p.completed),
+			Rejected:  atomic.LoadInt64(&p.rejected),
+		}
+	}
+	return stats
+}
+
+func getWorkerPool(name string) *workerPool {
+	loadWorkerPoolConfigs()
+	workerPoolsLock.RLock()
+	defer workerPoolsLock.RUnlock()
+	return workerPools[name]
+}
+
+// 注册配置中定义的工作池，仅执行一次
+func loadWorkerPoolConfigs() {
+	workerPoolConfigOnce.Do(func() {
+		confs, err := workerPoolConfigs()
+		if err != nil {
+			Log.Error("%v", err)
+		}
+		names := make([]string, 0, len(confs))
+		for name := range confs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := RegisterWorkerPool(name, confs[name]); err != nil {
+				Log.Error("%v", err)
+				continue
+			}
+			Log.Sys("> Registered worker pool %s (%d workers)", name, confs[name].Workers)
+		}
+		workerPoolsLock.RLock()
+		_, ok := workerPools[DEFAULT_WORKER_POOL]
+		workerPoolsLock.RUnlock()
+		if !ok {
+			var conf WorkerPoolConfig
+			confpkg.Bind(confpkg.NewFakeConfig(), &conf)
+			RegisterWorkerPool(DEFAULT_WORKER_POOL, conf)
+		}
+	})
+}
+
+// 读取配置中[workerpool.<name>]段定义的工作池
+func workerPoolConfigs() (map[string]WorkerPoolConfig, error) {
+	values, serrs := bindValues()
+	sections := map[string]confpkg.Configer{}
+	for k, v := range values {
+		i := strings.Index(k, "::")
+		if i < 0 || !strings.HasPrefix(k, WORKER_POOL_SECTION_PREFIX) {
+			continue
+		}
+		name := k[len(WORKER_POOL_SECTION_PREFIX):i]
+		if sections[name] == nil {
+			sections[name] = confpkg.NewFakeConfig()
+		}
+		sections[name].Set(k[i+2:], v)
+	}
+
+	var errs []string
+	for _, fe := range serrs {
+		if strings.HasPrefix(fe.Key, WORKER_POOL_SECTION_PREFIX) {
+			errs = append(errs, fe.Error())
+		}
+	}
+	confs := make(map[string]WorkerPoolConfig, len(sections))
+	for name, section := range sections {
+		var conf WorkerPoolConfig
+		if err := confpkg.Bind(section, &conf); err != nil {
+			errs = append(errs, fmt.Sprintf("[%s%s] %v", WORKER_POOL_SECTION_PREFIX, name, err))
+			continue
+		}
+		confs[name] = conf
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return confs, fmt.Errorf("Invalid worker pool config: %s", strings.Join(errs, "\n"))
+	}
+	return confs, nil
+}
+
+// 在工作池中处理请求，池已满且排队已满或超时时拒绝
+func (p *workerPool) serve(c *Context, next HandlerFunc) error {
+	if !p.bulkhead.acquire(p.conf.Queue, p.conf.QueueTimeout) {
+		atomic.AddInt64(&p.rejected, 1)
+		if p.conf.RetryAfter > 0 {
+			c.response.Header().Set("Retry-After", strconv.Itoa(p.conf.RetryAfter))
+		}
+		return NewHTTPError(p.conf.RejectStatus)
+	}
+	defer func() {
+		p.bulkhead.release()
+		atomic.AddInt64(&p.completed, 1)
+	}()
+	return next(c)
+}
+
+// 工作池隔离中间件：多个挂载点的路由可共享同一命名工作池，池由配置定义并计入运行时统计；
+// 仅需限制单个路由或路由组的并发时使用ConcurrencyLimit，其额度属于挂载的中间件实例
+var Bulkhead = ApiMiddleware{
+	Name: "工作池隔离",
+	Desc: "将路由分配到有界的命名工作池(见RegisterWorkerPool与[workerpool.<name>]配置段)，池中路由的慢请求或请求激增只占用本池额度，不影响其他路由",
+	Config: BulkheadConfig{
+		Pool: DEFAULT_WORKER_POOL,
+	},
+	Middleware: bulkheadMiddleware,
+}.Reg()
+
+// 返回将路由分配到名为pool的工作池的中间件，如：
+//
+//	lessgo.Leaf("/reports", ReportHandler, lessgo.BulkheadPool("reports"))
+func BulkheadPool(pool string) *ApiMiddleware {
+	return ApiMiddleware{
+		Name:       Bulkhead.Name + ":" + pool,
+		Desc:       "将路由分配到工作池" + pool,
+		Config:     BulkheadConfig{Pool: pool},
+		Middleware: bulkheadMiddleware,
+	}.Reg()
+}
+
+func bulkheadMiddleware(confObject interface{}) MiddlewareFunc {
+	config := confObject.(BulkheadConfig)
+	if config.Pool == "" {
+		config.Pool = DEFAULT_WORKER_POOL
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			// 每次请求时查找，以便使用替换后的工作池
+			p := getWorkerPool(config.Pool)
+			if p == nil {
+				return NewHTTPError(http.StatusInternalServerError, "Bulkhead: worker pool "+config.Pool+" is not registered")
+			}
+			return p.serve(c, next)
+		}
+	}
+}
+
+// 返回中间件配置所引用的工作池名称，不是Bulkhead中间件时ok为false
+func bulkheadPoolOf(m *MiddlewareConfig) (pool string, ok bool) {
+	a := getApiMiddleware(m.Name)
+	if a == nil || a.Name != Bulkhead.Name && !strings.HasPrefix(a.Name, Bulkhead.Name+":") {
+		return "", false
+	}
+	js := m.Config
+	if js == "" {
+		js = a.ConfigJSON()
+	}
+	var config BulkheadConfig
+	if json.Unmarshal([]byte(js), &config) != nil {
+		return "", false
+	}
+	if config.Pool == "" {
+		config.Pool = DEFAULT_WORKER_POOL
+	}
+	return config.Pool, true
+}
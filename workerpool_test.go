@@ -0,0 +1,107 @@
+package lessgo
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolConfigs(t *testing.T) {
+	fname := CONFIG_DIR + "/app.yaml"
+	if _, err := os.Stat(fname); err == nil {
+		t.Skip(fname + " exists")
+	}
+	content := "workerpool.reports:\n  workers: 2\n  queue_timeout: 5s\nworkerpool.broken:\n  workers: 0\n"
+	if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+
+	confs, err := workerPoolConfigs()
+	if err == nil {
+		t.Error("workerPoolConfigs() should report the invalid pool")
+	}
+	if _, ok := confs["broken"]; ok {
+		t.Errorf("invalid pool bound: %+v", confs["broken"])
+	}
+	if c := confs["reports"]; c.Workers != 2 || c.Queue != 100 || c.QueueTimeout != 5*time.Second || c.RejectStatus != 503 {
+		t.Errorf("reports = %+v", c)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	if err := RegisterWorkerPool("test:reports", WorkerPoolConfig{Workers: 1, RetryAfter: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if RegisterWorkerPool("test:empty", WorkerPoolConfig{}) == nil {
+		t.Error("pool without workers registered")
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := bulkheadMiddleware(BulkheadConfig{Pool: "test:reports"})(func(c *Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	get := func(h HandlerFunc) (*http.Response, error) {
+		req, _ := http.NewRequest(GET, "/reports", nil)
+		c, rec := testContext(req)
+		err := h(c)
+		return rec.Result(), err
+	}
+	done := make(chan error)
+	go func() {
+		_, err := get(h)
+		done <- err
+	}()
+	<-started
+
+	// 池已满且不排队，其他路由不受影响
+	resp, err := get(h)
+	if he, ok := err.(*HTTPError); !ok || he.Code != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("request over the pool = %v, Retry-After %q", err, resp.Header.Get("Retry-After"))
+	}
+	if _, err = get(func(c *Context) error { return nil }); err != nil {
+		t.Errorf("request outside the pool = %v", err)
+	}
+	if s := ReadWorkerPoolStats()["test:reports"]; s.Workers != 1 || s.Busy != 1 || s.Rejected != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	if s := ReadRuntimeStats().WorkerPools["test:reports"]; s.Busy != 0 || s.Completed != 1 {
+		t.Errorf("stats after request = %+v", s)
+	}
+
+	if _, err = get(bulkheadMiddleware(BulkheadConfig{Pool: "test:missing"})(nil)); err == nil {
+		t.Error("unregistered pool should fail")
+	}
+	// 未配置时默认池按默认值注册，默认配置与未指定池均使用默认池
+	if s, ok := ReadWorkerPoolStats()[DEFAULT_WORKER_POOL]; !ok || s.Workers != 10 {
+		t.Errorf("default pool = %+v, %v", s, ok)
+	}
+	for _, conf := range []interface{}{Bulkhead.Config, BulkheadConfig{}} {
+		if _, err = get(bulkheadMiddleware(conf)(func(c *Context) error { return nil })); err != nil {
+			t.Errorf("default pool with %+v = %v", conf, err)
+		}
+	}
+	if pool, ok := bulkheadPoolOf(Bulkhead.NewMiddlewareConfig()); !ok || pool != DEFAULT_WORKER_POOL {
+		t.Errorf("bulkheadPoolOf(Bulkhead) = %q, %v", pool, ok)
+	}
+
+	m := BulkheadPool("test:reports")
+	if m != BulkheadPool("test:reports") || m.Name != Bulkhead.Name+":test:reports" {
+		t.Errorf("BulkheadPool registered %q twice", m.Name)
+	}
+	if pool, ok := bulkheadPoolOf(m.NewMiddlewareConfig()); !ok || pool != "test:reports" {
+		t.Errorf("bulkheadPoolOf = %q, %v", pool, ok)
+	}
+	if _, ok := bulkheadPoolOf(SecureHeaders.NewMiddlewareConfig()); ok {
+		t.Error("bulkheadPoolOf matched another middleware")
+	}
+}
@@ -0,0 +1,54 @@
+package lessgo
+
+import (
+	"context"
+	"net/http"
+)
+
+// 将net/http的处理器包装为操作函数，可用作ApiHandler.Handler，以挂载net/http生态中的处理器
+func WrapHTTPHandler(h http.Handler) HandlerFunc {
+	return func(c *Context) error {
+		h.ServeHTTP(c.response, c.request)
+		return nil
+	}
+}
+
+// 将net/http的中间件(func(http.Handler) http.Handler)包装为中间件函数；
+// 被包装的中间件在构建处理链时只创建一次，替换的*http.Request与http.ResponseWriter对后续处理链生效，
+// 未调用后续处理器时(如鉴权失败)后续处理链不再执行
+func WrapHTTPMiddleware(m func(http.Handler) http.Handler) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		h := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			call := r.Context().Value(httpMiddlewareKey{}).(*httpMiddlewareCall)
+			c := call.c
+			c.request = r
+			if resp := c.response; w != http.ResponseWriter(resp) {
+				// 后续处理链经中间件替换的ResponseWriter写入；
+				// 替换的ResponseWriter通常仍写入原Response，未写入时同步响应状态
+				c.response = &Response{writer: w, status: resp.status, size: resp.size, committed: resp.committed}
+				defer func() {
+					if wrapped := c.response; !resp.committed && wrapped.committed {
+						resp.status, resp.size, resp.committed = wrapped.status, wrapped.size, true
+					}
+					c.response = resp
+				}()
+			}
+			call.err = next(c)
+		}))
+		return func(c *Context) error {
+			req := c.request
+			call := &httpMiddlewareCall{c: c}
+			h.ServeHTTP(c.response, req.WithContext(context.WithValue(req.Context(), httpMiddlewareKey{}, call)))
+			c.request = req
+			return call.err
+		}
+	}
+}
+
+type (
+	httpMiddlewareKey  struct{}
+	httpMiddlewareCall struct {
+		c   *Context
+		err error
+	}
+)
@@ -0,0 +1,68 @@
+package lessgo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// 将响应体转为大写的net/http中间件
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (w upperWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.Write([]byte(strings.ToUpper(string(b))))
+}
+
+func TestWrapHTTPMiddleware(t *testing.T) {
+	var builds int
+	auth := func(next http.Handler) http.Handler {
+		builds++
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			r.Header.Set("X-User", "gopher")
+			next.ServeHTTP(upperWriter{w}, r)
+		})
+	}
+	h := WrapMiddleware(auth)(func(c *Context) error {
+		return c.String(http.StatusOK, "hello "+c.HeaderParam("X-User"))
+	})
+	if builds != 1 {
+		t.Errorf("middleware built %d times", builds)
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	c, rec := testContext(req)
+	if err := h(c); err != nil || rec.Code != http.StatusUnauthorized || c.Response().Status() != http.StatusUnauthorized {
+		t.Errorf("unauthorized: %v %d %q", err, rec.Code, rec.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "token")
+	c, rec = testContext(req)
+	if err := h(c); err != nil || rec.Body.String() != "HELLO GOPHER" || c.Response().Status() != http.StatusOK {
+		t.Errorf("authorized: %v %d %q", err, rec.Code, rec.Body.String())
+	}
+	if c.Request() != req || c.Response().Size() != int64(len("HELLO GOPHER")) {
+		t.Errorf("request or response not restored")
+	}
+	if builds != 1 {
+		t.Errorf("middleware built %d times", builds)
+	}
+}
+
+func TestWrapHTTPHandler(t *testing.T) {
+	h := WrapHTTPHandler(http.StripPrefix("/files", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(r.URL.Path))
+	})))
+	req, _ := http.NewRequest("GET", "/files/a.txt", nil)
+	c, rec := testContext(req)
+	if err := h(c); err != nil || rec.Code != http.StatusAccepted || rec.Body.String() != "/a.txt" || c.Response().Status() != http.StatusAccepted {
+		t.Errorf("WrapHTTPHandler: %v %d %q", err, rec.Code, rec.Body.String())
+	}
+}